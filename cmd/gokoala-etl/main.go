@@ -1,11 +1,18 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"log"
 	"net"
 	"os"
+	"os/signal"
+	"runtime"
+	"slices"
 	"strconv"
+	"syscall"
+	"text/tabwriter"
 
 	"github.com/PDOK/gokoala/internal/ogc/features_search/etl"
 	"github.com/PDOK/gokoala/internal/ogc/features_search/etl/config"
@@ -33,6 +40,12 @@ const (
 	pageSizeFlag     = "page-size"
 	skipOptimizeFlag = "skip-optimize"
 	languageFlag     = "lang"
+	operationColFlag = "operation-column"
+	workersFlag      = "workers"
+	queryFlag        = "query"
+	limitFlag        = "limit"
+	versionFlag      = "collection-version"
+	jsonFlag         = "json"
 )
 
 var (
@@ -167,6 +180,13 @@ var (
 			Required: false,
 			Value:    10000,
 		},
+		&cli.IntFlag{
+			Name:     workersFlag,
+			EnvVars:  []string{strcase.ToScreamingSnake(workersFlag)},
+			Usage:    "Number of concurrent workers to use when transforming records (defaults to the number of CPUs)",
+			Required: false,
+			Value:    runtime.NumCPU(),
+		},
 		&cli.BoolFlag{
 			Name:     skipOptimizeFlag,
 			EnvVars:  []string{strcase.ToScreamingSnake(skipOptimizeFlag)},
@@ -175,6 +195,73 @@ var (
 			Value:    false,
 		},
 	}
+
+	inspectFlags = []cli.Flag{
+		commonDBFlags[dbHostFlag],
+		commonDBFlags[dbPortFlag],
+		commonDBFlags[dbNameFlag],
+		commonDBFlags[dbUsernameFlag],
+		commonDBFlags[dbPasswordFlag],
+		commonDBFlags[dbSslModeFlag],
+		&cli.PathFlag{
+			Name:     searchIndexFlag,
+			EnvVars:  []string{strcase.ToScreamingSnake(searchIndexFlag)},
+			Usage:    "Name of search index",
+			Required: false,
+			Value:    "search_index",
+		},
+		&cli.BoolFlag{
+			Name:     jsonFlag,
+			Usage:    "Output as JSON instead of a table",
+			Required: false,
+			Value:    false,
+		},
+	}
+
+	sampleRecordsFlags = append(slices.Clone(inspectFlags),
+		&cli.StringFlag{
+			Name:     collectionIDFlag,
+			Usage:    "ID/name of the collection in the search index to sample records from (default: all collections)",
+			Required: false,
+			EnvVars:  []string{strcase.ToScreamingSnake(collectionIDFlag)},
+		},
+		&cli.StringFlag{
+			Name:     queryFlag,
+			Usage:    "Only sample records matching these search terms (default: all records)",
+			Required: false,
+		},
+		&cli.IntFlag{
+			Name:     limitFlag,
+			Usage:    "Maximum number of records to sample",
+			Required: false,
+			Value:    10,
+		},
+	)
+
+	dropCollectionVersionFlags = append(slices.Clone(inspectFlags[:len(inspectFlags)-1]),
+		&cli.StringFlag{
+			Name:     collectionIDFlag,
+			Usage:    "ID/name of the collection in the search index to drop",
+			Required: true,
+			EnvVars:  []string{strcase.ToScreamingSnake(collectionIDFlag)},
+		},
+		&cli.IntFlag{
+			Name:     versionFlag,
+			Usage:    "Version of the collection in the search index to drop",
+			Required: true,
+			EnvVars:  []string{strcase.ToScreamingSnake(versionFlag)},
+		},
+	)
+
+	importChangesetFlags = append(slices.Clone(importFileFlags),
+		&cli.StringFlag{
+			Name:     operationColFlag,
+			EnvVars:  []string{strcase.ToScreamingSnake(operationColFlag)},
+			Usage:    "Name of the column in the changeset which holds the operation per feature: 'insert', 'update' or 'delete'",
+			Required: false,
+			Value:    "operation",
+		},
+	)
 )
 
 func main() {
@@ -224,8 +311,8 @@ func main() {
 				}
 				file := c.Path(fileFlag)
 				for _, coll := range cfg.Collections {
-					err = etl.ImportFile(coll, c.String(searchIndexFlag), revision,
-						file, c.Int(pageSizeFlag), c.Bool(skipOptimizeFlag), dbConn)
+					err = etl.ImportFile(c.Context, coll, c.String(searchIndexFlag), revision,
+						file, c.Int(pageSizeFlag), c.Int(workersFlag), c.Bool(skipOptimizeFlag), dbConn)
 
 					if err != nil {
 						return fmt.Errorf("failed to import collection %s from file %s, error: %w",
@@ -235,9 +322,100 @@ func main() {
 				return nil
 			},
 		},
+		{
+			Name: "import-changeset",
+			Usage: "Apply a changeset file (e.g. GeoPackage) to an existing collection in the search index. " +
+				"Only inserts, updates or deletes the records in the changeset instead of rebuilding the whole collection",
+			Flags: importChangesetFlags,
+			Action: func(c *cli.Context) error {
+				dbConn := flagsToDBConnStr(c)
+				cfg, err := config.NewConfig(c.Path(configFileFlag))
+				if err != nil {
+					return err
+				}
+				revision := c.String(revisionFlag)
+				_, err = uuid.Parse(revision)
+				if err != nil {
+					return fmt.Errorf("invalid revision %s, must be a UUID", revision)
+				}
+				file := c.Path(fileFlag)
+				for _, coll := range cfg.Collections {
+					err = etl.ImportChangeset(coll, c.String(searchIndexFlag), revision, file,
+						c.String(operationColFlag), c.Int(pageSizeFlag), c.Bool(skipOptimizeFlag), dbConn)
+
+					if err != nil {
+						return fmt.Errorf("failed to apply changeset %s to collection %s, error: %w",
+							file, coll.ID, err)
+					}
+				}
+				return nil
+			},
+		},
+		{
+			Name:  "list-collections",
+			Usage: "List collections and versions in the search index, including record counts per partition and revisions",
+			Flags: inspectFlags,
+			Action: func(c *cli.Context) error {
+				collections, err := etl.ListCollections(flagsToDBConnStr(c), c.String(searchIndexFlag))
+				if err != nil {
+					return err
+				}
+				if c.Bool(jsonFlag) {
+					return printJSON(collections)
+				}
+				w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+				_, _ = fmt.Fprintln(w, "COLLECTION\tVERSION\tPARTITION\tRECORDS\tREVISION\tREVISION DATE")
+				for _, coll := range collections {
+					_, _ = fmt.Fprintf(w, "%s\t%d\t%s\t%d\t%s\t%s\n", coll.CollectionID, coll.CollectionVersion,
+						coll.Partition, coll.RecordCount, valueOrEmpty(coll.Revision), valueOrEmpty(coll.RevisionDate))
+				}
+				return w.Flush()
+			},
+		},
+		{
+			Name:  "sample-records",
+			Usage: "Show a sample of records in the search index, including the suggest and ts (full-text search) tokens",
+			Flags: sampleRecordsFlags,
+			Action: func(c *cli.Context) error {
+				records, err := etl.SampleRecords(flagsToDBConnStr(c), c.String(searchIndexFlag),
+					c.String(collectionIDFlag), c.String(queryFlag), c.Int(limitFlag))
+				if err != nil {
+					return err
+				}
+				if c.Bool(jsonFlag) {
+					return printJSON(records)
+				}
+				w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+				_, _ = fmt.Fprintln(w, "COLLECTION\tVERSION\tFEATURE ID\tEXTERNAL FID\tDISPLAY NAME\tSUGGEST\tTS")
+				for _, r := range records {
+					_, _ = fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%s\t%s\t%s\n", r.CollectionID, r.CollectionVersion,
+						r.FeatureID, valueOrEmpty(r.ExternalFid), r.DisplayName, r.Suggest, r.Tokens)
+				}
+				return w.Flush()
+			},
+		},
+		{
+			Name:  "drop-collection-version",
+			Usage: "Remove all records of a specific version of a collection from the search index",
+			Flags: dropCollectionVersionFlags,
+			Action: func(c *cli.Context) error {
+				deleted, err := etl.DropCollectionVersion(flagsToDBConnStr(c), c.String(searchIndexFlag),
+					c.String(collectionIDFlag), c.Int(versionFlag))
+				if err != nil {
+					return err
+				}
+				log.Printf("removed %d records of collection %s version %d from search index %s",
+					deleted, c.String(collectionIDFlag), c.Int(versionFlag), c.String(searchIndexFlag))
+				return nil
+			},
+		},
 	}
 
-	err := app.Run(os.Args)
+	// cancel (long-running) imports on interrupt
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	err := app.RunContext(ctx, os.Args)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -248,3 +426,16 @@ func flagsToDBConnStr(c *cli.Context) string {
 		c.String(dbUsernameFlag), c.String(dbPasswordFlag), net.JoinHostPort(c.String(dbHostFlag),
 			strconv.Itoa(c.Int(dbPortFlag))), c.String(dbNameFlag), c.String(dbSslModeFlag), appName)
 }
+
+func printJSON(v any) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+func valueOrEmpty[T any](v *T) string {
+	if v == nil {
+		return ""
+	}
+	return fmt.Sprint(*v)
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"path/filepath"
+	"strings"
+
+	"github.com/PDOK/gokoala/config"
+	"github.com/PDOK/gokoala/internal/ogc/features"
+	"github.com/creasty/defaults"
+	"github.com/iancoleman/strcase"
+	"gopkg.in/yaml.v3"
+)
+
+// starterConfigOptions input to generate a starter config, either for a GeoPackage or a Postgres schema.
+type starterConfigOptions struct {
+	title       string
+	baseURL     string
+	fid         string
+	externalFid string
+
+	geoPackage string
+	postgres   *config.Postgres
+}
+
+// generateStarterConfig inspects the GeoPackage or Postgres schema and writes a config
+// to serve all tables found as OGC API Features.
+func generateStarterConfig(opts starterConfigOptions, w io.Writer) error {
+	baseURL, err := url.Parse(strings.TrimSuffix(opts.baseURL, "/"))
+	if err != nil {
+		return fmt.Errorf("invalid base URL %s: %w", opts.baseURL, err)
+	}
+
+	// datasource as written to the config file, with only the relevant settings
+	var datasource config.Datasource
+	switch {
+	case opts.geoPackage != "":
+		datasource.GeoPackage = &config.GeoPackage{Local: &config.GeoPackageLocal{File: opts.geoPackage}}
+		datasource.GeoPackage.Local.Fid = opts.fid
+		if opts.title == "" {
+			opts.title = humanizeFileName(opts.geoPackage)
+		}
+	case opts.postgres != nil:
+		datasource.Postgres = opts.postgres
+		datasource.Postgres.Fid = opts.fid
+		if opts.title == "" {
+			opts.title = opts.postgres.DatabaseName
+		}
+	default:
+		return fmt.Errorf("specify either --%s or --%s", geoPackageFlag, dbHostFlag)
+	}
+
+	// datasource used for inspection, with defaults for all other settings
+	inspect := *datasource.DeepCopy()
+	if err = defaults.Set(&inspect); err != nil {
+		return err
+	}
+	tables, err := features.InspectDatasource(inspect, opts.externalFid)
+	if err != nil {
+		return err
+	}
+	if datasource.Postgres != nil {
+		// don't write password to config file, reference environment variable instead
+		datasource.Postgres.Pass = config.Secret("${env:" + strcase.ToScreamingSnake(dbPasswordFlag) + "}")
+	}
+
+	cfg := features.NewStarterConfig(opts.title, config.URL{URL: baseURL}, datasource, tables)
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if _, err = fmt.Fprintln(w, "---"); err != nil {
+		return err
+	}
+	if err = encoder.Encode(cfg); err != nil {
+		return err
+	}
+
+	return encoder.Close()
+}
+
+// humanizeFileName turns a file name like '/data/my_dataset.gpkg' into a title like 'My dataset'.
+func humanizeFileName(file string) string {
+	name := strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+	name = strings.Join(strings.FieldsFunc(name, func(r rune) bool { return r == '_' || r == '-' }), " ")
+	if name == "" {
+		return file
+	}
+
+	return strings.ToUpper(name[:1]) + name[1:]
+}
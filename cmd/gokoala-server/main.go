@@ -1,10 +1,12 @@
 package main
 
 import (
+	"fmt"
 	"log"
 	"net"
 	"os"
 	"strconv"
+	"strings"
 
 	"github.com/PDOK/gokoala/config"
 	eng "github.com/PDOK/gokoala/internal/engine"
@@ -25,6 +27,19 @@ const (
 	themeFileFlag           = "theme-file"
 	rewritesFileFlag        = "rewrites-file"
 	synonymsFileFlag        = "synonyms-file"
+	offlineFlag             = "offline"
+	geoPackageFlag          = "geopackage"
+	dbHostFlag              = "db-host"
+	dbPortFlag              = "db-port"
+	dbNameFlag              = "db-name"
+	dbSchemaFlag            = "db-schema"
+	dbSslModeFlag           = "db-ssl-mode"
+	dbUsernameFlag          = "db-username"
+	dbPasswordFlag          = "db-password"
+	fidFlag                 = "fid"
+	externalFidFlag         = "external-fid"
+	titleFlag               = "title"
+	baseURLFlag             = "base-url"
 )
 
 var (
@@ -57,10 +72,11 @@ var (
 			Required: false,
 			EnvVars:  []string{strcase.ToScreamingSnake(shutdownDelayFlag)},
 		},
-		&cli.StringFlag{
+		&cli.StringSliceFlag{
 			Name:     configFileFlag,
-			Usage:    "reference to YAML configuration file",
-			Required: true,
+			Aliases:  []string{"c"},
+			Usage:    "reference to YAML configuration file. Repeat to merge overlays (e.g. per environment) on top of the first file, in order",
+			Required: false, // required, but checked in action since the 'validate' command has its own flag
 			EnvVars:  []string{strcase.ToScreamingSnake(configFileFlag)},
 		},
 		&cli.StringFlag{
@@ -102,6 +118,96 @@ var (
 			Required: false,
 		},
 	}
+
+	validateFlags = []cli.Flag{
+		&cli.StringSliceFlag{
+			Name:     configFileFlag,
+			Aliases:  []string{"c"},
+			Usage:    "reference to YAML configuration file to validate. Repeat to merge overlays on top of the first file, in order",
+			Required: true,
+			EnvVars:  []string{strcase.ToScreamingSnake(configFileFlag)},
+		},
+		&cli.BoolFlag{
+			Name:     offlineFlag,
+			Usage:    "only validate the config file itself, don't connect to the configured datasources",
+			Value:    false,
+			Required: false,
+		},
+	}
+
+	generateConfigFlags = []cli.Flag{
+		&cli.PathFlag{
+			Name:     geoPackageFlag,
+			Usage:    "path to GeoPackage to generate config for. Either specify this or a Postgres database (db-host, etc)",
+			Required: false,
+		},
+		&cli.StringFlag{
+			Name:     dbHostFlag,
+			Usage:    "host of Postgres database to generate config for",
+			Required: false,
+			EnvVars:  []string{strcase.ToScreamingSnake(dbHostFlag)},
+		},
+		&cli.UintFlag{
+			Name:     dbPortFlag,
+			Value:    5432,
+			Required: false,
+			EnvVars:  []string{strcase.ToScreamingSnake(dbPortFlag)},
+		},
+		&cli.StringFlag{
+			Name:     dbNameFlag,
+			Value:    "postgres",
+			Required: false,
+			EnvVars:  []string{strcase.ToScreamingSnake(dbNameFlag)},
+		},
+		&cli.StringFlag{
+			Name:     dbSchemaFlag,
+			Usage:    "generate config for the feature tables in this schema",
+			Value:    "public",
+			Required: false,
+			EnvVars:  []string{strcase.ToScreamingSnake(dbSchemaFlag)},
+		},
+		&cli.StringFlag{
+			Name:     dbSslModeFlag,
+			Value:    "disable",
+			Required: false,
+			EnvVars:  []string{strcase.ToScreamingSnake(dbSslModeFlag)},
+		},
+		&cli.StringFlag{
+			Name:     dbUsernameFlag,
+			Value:    "postgres",
+			Required: false,
+			EnvVars:  []string{strcase.ToScreamingSnake(dbUsernameFlag)},
+		},
+		&cli.StringFlag{
+			Name:     dbPasswordFlag,
+			Value:    "postgres",
+			Required: false,
+			EnvVars:  []string{strcase.ToScreamingSnake(dbPasswordFlag)},
+		},
+		&cli.StringFlag{
+			Name:     fidFlag,
+			Usage:    "feature id column name",
+			Value:    "fid",
+			Required: false,
+		},
+		&cli.StringFlag{
+			Name:     externalFidFlag,
+			Usage:    "external feature id column name, only used when present in all tables. Relations are derived from columns named '<collection>_<external-fid>'",
+			Value:    "external_fid",
+			Required: false,
+		},
+		&cli.StringFlag{
+			Name:     titleFlag,
+			Usage:    "title of the API (default: derived from GeoPackage file name or database name)",
+			Required: false,
+		},
+		&cli.StringFlag{
+			Name:     baseURLFlag,
+			Usage:    "base URL of the API",
+			Value:    "http://localhost:8080",
+			Required: false,
+		},
+	}
 )
 
 func main() {
@@ -109,23 +215,85 @@ func main() {
 	app.Name = config.AppName
 	app.Usage = "Cloud Native OGC APIs server, written in Go"
 	app.Flags = cliFlags
+	app.Commands = []*cli.Command{
+		{
+			Name: "validate",
+			Usage: "Validate the config file, including references between collections and styles. Unless offline, " +
+				"also connect to the configured datasources to check tables, columns, queryables and indexes. " +
+				"Prints a JSON report",
+			Flags: validateFlags,
+			Action: func(c *cli.Context) error {
+				report := validateConfig(c.StringSlice(configFileFlag), c.Bool(offlineFlag))
+				if err := report.write(os.Stdout); err != nil {
+					return err
+				}
+				if !report.Valid {
+					return cli.Exit("config file "+strings.Join(report.ConfigFiles, ", ")+" is invalid", 1)
+				}
+				return nil
+			},
+		},
+		{
+			Name:  "jsonschema",
+			Usage: "Print the JSON Schema of the config file, to validate config files in your editor/IDE or CI pipeline",
+			Action: func(_ *cli.Context) error {
+				_, err := os.Stdout.Write(config.JSONSchema())
+				return err
+			},
+		},
+		{
+			Name: "generate-config",
+			Usage: "Generate a starter config to serve all tables in a GeoPackage or Postgres schema as OGC API Features. " +
+				"Prints the config as YAML, review it before use",
+			Flags: generateConfigFlags,
+			Action: func(c *cli.Context) error {
+				opts := starterConfigOptions{
+					title:       c.String(titleFlag),
+					baseURL:     c.String(baseURLFlag),
+					fid:         c.String(fidFlag),
+					externalFid: c.String(externalFidFlag),
+					geoPackage:  c.Path(geoPackageFlag),
+				}
+				if opts.geoPackage == "" && c.IsSet(dbHostFlag) {
+					opts.postgres = &config.Postgres{
+						Host:         c.String(dbHostFlag),
+						Port:         c.Uint(dbPortFlag),
+						DatabaseName: c.String(dbNameFlag),
+						Schema:       c.String(dbSchemaFlag),
+						SSLMode:      c.String(dbSslModeFlag),
+						User:         c.String(dbUsernameFlag),
+						Pass:         config.Secret(c.String(dbPasswordFlag)),
+					}
+				}
+				return generateStarterConfig(opts, os.Stdout)
+			},
+		},
+	}
 	app.Action = func(c *cli.Context) error {
+		if !c.IsSet(configFileFlag) {
+			return fmt.Errorf("required flag %q not set", configFileFlag)
+		}
 		log.Printf("%s - %s\n", app.Name, app.Usage)
 
 		address := net.JoinHostPort(c.String(hostFlag), strconv.Itoa(c.Int(portFlag)))
 		debugPort := c.Int(debugPortFlag)
 		shutdownDelay := c.Int(shutdownDelayFlag)
-		configFile := c.String(configFileFlag)
+		configFiles := c.StringSlice(configFileFlag)
 		themeFile := c.String(themeFileFlag)
 		openAPIFile := c.String(openAPIFileFlag)
 		trailingSlash := c.Bool(enableTrailingSlashFlag)
 		cors := c.Bool(enableCorsFlag)
 
-		// Engine encapsulates shared non-OGCAPI specific logic
-		engine, err := eng.NewEngine(configFile, themeFile, openAPIFile, trailingSlash, cors)
+		cfg, err := config.NewConfig(configFiles...)
 		if err != nil {
 			return err
 		}
+		theme, err := config.NewTheme(themeFile)
+		if err != nil {
+			return err
+		}
+		// Engine encapsulates shared non-OGCAPI specific logic
+		engine := eng.NewEngineWithConfig(cfg, theme, openAPIFile, trailingSlash, cors)
 		// Each OGC API building block makes use of said Engine
 		err = ogc.SetupBuildingBlocks(engine, c.String(rewritesFileFlag), c.String(synonymsFileFlag))
 		if err != nil {
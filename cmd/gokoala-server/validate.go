@@ -0,0 +1,89 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"maps"
+	"slices"
+
+	"github.com/PDOK/gokoala/config"
+	"github.com/PDOK/gokoala/internal/ogc/features"
+)
+
+const (
+	apiFeatures       = "features"
+	apiFeaturesSearch = "search"
+)
+
+// validationReport machine-readable result of validating a config file.
+type validationReport struct {
+	ConfigFiles []string               `json:"configFiles"`
+	Valid       bool                   `json:"valid"`
+	Offline     bool                   `json:"offline"`
+	Errors      []string               `json:"errors,omitempty"`
+	Collections []collectionValidation `json:"collections,omitempty"`
+}
+
+// collectionValidation result of validating the datasource(s) of a single collection.
+type collectionValidation struct {
+	ID     string   `json:"id"`
+	API    string   `json:"api"`
+	Valid  bool     `json:"valid"`
+	Errors []string `json:"errors,omitempty"`
+}
+
+// validateConfig loads the given config file(s), which runs all validators (including cross-references
+// between collections and styles). Unless offline, the configured datasources are validated as well.
+func validateConfig(configFiles []string, offline bool) validationReport {
+	report := validationReport{ConfigFiles: configFiles, Offline: offline}
+	cfg, err := config.NewConfig(configFiles...)
+	if err != nil {
+		report.Errors = errorMessages(err)
+		return report
+	}
+	report.Valid = true
+	if offline {
+		return report
+	}
+	if cfg.OgcAPI.Features != nil {
+		report.addCollections(apiFeatures, features.ValidateDatasources(config.NewFeaturesConfig(cfg.OgcAPI.Features)))
+	}
+	if cfg.OgcAPI.FeaturesSearch != nil {
+		report.addCollections(apiFeaturesSearch, features.ValidateDatasources(config.NewSearchConfig(cfg.OgcAPI.FeaturesSearch)))
+	}
+
+	return report
+}
+
+func (r *validationReport) addCollections(api string, errsByCollection map[string]error) {
+	for _, id := range slices.Sorted(maps.Keys(errsByCollection)) {
+		collection := collectionValidation{ID: id, API: api, Valid: errsByCollection[id] == nil}
+		if !collection.Valid {
+			collection.Errors = errorMessages(errsByCollection[id])
+			r.Valid = false
+		}
+		r.Collections = append(r.Collections, collection)
+	}
+}
+
+func (r *validationReport) write(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r)
+}
+
+// errorMessages flattens joined errors into separate messages.
+func errorMessages(err error) []string {
+	var joined interface{ Unwrap() []error }
+	if errors.As(err, &joined) {
+		var result []string
+		for _, e := range joined.Unwrap() {
+			if e != nil {
+				result = append(result, errorMessages(e)...)
+			}
+		}
+		return result
+	}
+	return []string{err.Error()}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateConfig(t *testing.T) {
+	tests := []struct {
+		name        string
+		configFile  string
+		offline     bool
+		wantValid   bool
+		wantErrMsgs []string
+	}{
+		{
+			name:       "valid config offline",
+			configFile: "examples/config_all.yaml",
+			offline:    true,
+			wantValid:  true,
+		},
+		{
+			name:       "valid config without datasources",
+			configFile: "internal/engine/testdata/config_translations.yaml",
+			offline:    false,
+			wantValid:  true,
+		},
+		{
+			name:       "invalid style references",
+			configFile: "internal/engine/testdata/config_invalid_styles_duplicate.yaml",
+			offline:    true,
+			wantValid:  false,
+			wantErrMsgs: []string{
+				"validation failed for style 'default'; style ID isn't unique per projection",
+			},
+		},
+		{
+			name:       "unknown config file",
+			configFile: "internal/engine/testdata/does_not_exist.yaml",
+			offline:    true,
+			wantValid:  false,
+			wantErrMsgs: []string{
+				"failed to read config file",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := validateConfig([]string{tt.configFile}, tt.offline)
+			assert.Equal(t, tt.wantValid, report.Valid)
+			assert.Equal(t, tt.offline, report.Offline)
+			require.Len(t, report.Errors, len(tt.wantErrMsgs))
+			for i, msg := range tt.wantErrMsgs {
+				assert.Contains(t, report.Errors[i], msg)
+			}
+
+			var buf bytes.Buffer
+			require.NoError(t, report.write(&buf))
+			assert.Contains(t, buf.String(), "\"configFiles\": [\n    \""+tt.configFile+"\"\n  ]")
+		})
+	}
+}
@@ -1,5 +1,15 @@
 package config
 
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// layout of the (start and end) datetime of a temporal extent, in UTC
+const intervalLayout = "2006-01-02T15:04:05Z"
+
 // +kubebuilder:object:generate=true
 type GeoSpatialCollectionMetadata struct {
 	// Human-friendly title of this collection. When no title is specified the collection ID is used.
@@ -44,6 +54,10 @@ type GeoSpatialCollectionMetadata struct {
 	// +kubebuilder:validation:Pattern=`^http:\/\/www\.opengis\.net\/def\/crs\/.*$`
 	// +optional
 	StorageCrs *string `yaml:"storageCrs,omitempty" json:"storageCrs,omitempty" default:"http://www.opengis.net/def/crs/OGC/1.3/CRS84" validate:"startswith=http://www.opengis.net/def/crs"`
+
+	// Title, description and keywords of this collection in other languages, by language (e.g. 'en').
+	// +optional
+	Translations map[string]MetadataTranslation `yaml:"translations,omitempty" json:"translations,omitempty"`
 }
 
 // +kubebuilder:object:generate=true
@@ -56,13 +70,67 @@ type Extent struct {
 	// Geospatial extent
 	Bbox []string `yaml:"bbox" json:"bbox"`
 
-	// Temporal extent
+	// Temporal extent, start and end as JSON values: a quoted UTC datetime
+	// (e.g. "\"2020-01-01T00:00:00Z\"") or null for an open-ended interval.
 	// +optional
 	// +kubebuilder:validation:MinItems=2
 	// +kubebuilder:validation:MaxItems=2
 	Interval []string `yaml:"interval,omitempty" json:"interval,omitempty" validate:"omitempty,len=2"`
 }
 
+// ParseInterval parses the start and end of the temporal extent, nil for an open end.
+func (e *Extent) ParseInterval() (start *time.Time, end *time.Time, err error) {
+	if len(e.Interval) != 2 {
+		return nil, nil, errors.New("interval should contain exactly 2 values")
+	}
+	if start, err = parseIntervalValue(e.Interval[0]); err != nil {
+		return nil, nil, err
+	}
+	if end, err = parseIntervalValue(e.Interval[1]); err != nil {
+		return nil, nil, err
+	}
+	if start != nil && end != nil && start.After(*end) {
+		return nil, nil, errors.New("start of interval should be before the end")
+	}
+
+	return start, end, nil
+}
+
+func parseIntervalValue(value string) (*time.Time, error) {
+	var datetime *string
+	if err := json.Unmarshal([]byte(value), &datetime); err != nil {
+		return nil, fmt.Errorf("%s should be a quoted datetime or null", value)
+	}
+	if datetime == nil {
+		return nil, nil //nolint:nilnil
+	}
+	t, err := time.Parse(intervalLayout, *datetime)
+	if err != nil {
+		return nil, fmt.Errorf("%s should be a UTC datetime like %s", value, intervalLayout)
+	}
+
+	return &t, nil
+}
+
+func validateExtents(collections GeoSpatialCollections) error {
+	var errMessages []string
+	for _, coll := range collections {
+		metadata := coll.GetMetadata()
+		if metadata == nil || metadata.Extent == nil || metadata.Extent.Interval == nil {
+			continue
+		}
+		if _, _, err := metadata.Extent.ParseInterval(); err != nil {
+			errMessages = append(errMessages, fmt.Sprintf("validation failed for collection '%s'; "+
+				"field 'Extent.Interval' is invalid: %v\n", coll.GetID(), err))
+		}
+	}
+	if len(errMessages) > 0 {
+		return fmt.Errorf("invalid config provided:\n%v", errMessages)
+	}
+
+	return nil
+}
+
 // +kubebuilder:object:generate=true
 type CollectionLinks struct {
 	// Links to downloads of an entire collection. These will be rendered as rel=enclosure links
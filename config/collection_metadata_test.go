@@ -0,0 +1,67 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExtent_ParseInterval(t *testing.T) {
+	date := func(year int) *time.Time {
+		d := time.Date(year, 1, 1, 0, 0, 0, 0, time.UTC)
+
+		return &d
+	}
+	tests := []struct {
+		interval  []string
+		wantStart *time.Time
+		wantEnd   *time.Time
+		wantErr   string
+	}{
+		{
+			interval:  []string{`"2020-01-01T00:00:00Z"`, `"2021-01-01T00:00:00Z"`},
+			wantStart: date(2020),
+			wantEnd:   date(2021),
+		},
+		{
+			interval:  []string{`"2020-01-01T00:00:00Z"`, "null"},
+			wantStart: date(2020),
+		},
+		{
+			interval: []string{"null", `"2021-01-01T00:00:00Z"`},
+			wantEnd:  date(2021),
+		},
+		{
+			interval: []string{"2020-01-01T00:00:00Z", "null"},
+			wantErr:  "2020-01-01T00:00:00Z should be a quoted datetime or null",
+		},
+		{
+			interval: []string{`"2020-01-01T00:00:00+02:00"`, "null"},
+			wantErr:  `"2020-01-01T00:00:00+02:00" should be a UTC datetime like 2006-01-02T15:04:05Z`,
+		},
+		{
+			interval: []string{`"2021-01-01T00:00:00Z"`, `"2020-01-01T00:00:00Z"`},
+			wantErr:  "start of interval should be before the end",
+		},
+		{
+			interval: []string{"null"},
+			wantErr:  "interval should contain exactly 2 values",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.interval[0], func(t *testing.T) {
+			extent := Extent{Interval: tt.interval}
+			start, end, err := extent.ParseInterval()
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantStart, start)
+			assert.Equal(t, tt.wantEnd, end)
+		})
+	}
+}
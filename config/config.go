@@ -88,20 +88,39 @@ type Config struct {
 	// Location where resources (e.g. thumbnails) specific to the given dataset are hosted
 	// +optional
 	Resources *Resources `yaml:"resources,omitempty" json:"resources,omitempty"`
+
+	// Title, abstract, keywords and dataset details in other languages, by language (e.g. 'en').
+	// The language should be one of the AvailableLanguages. When a value isn't translated
+	// the value in the default language (the first of the AvailableLanguages) is used.
+	// +optional
+	Translations map[string]Translation `yaml:"translations,omitempty" json:"translations,omitempty"`
+
+	// Options regarding the OpenAPI specification of this API
+	// +optional
+	OpenAPI *OpenAPI `yaml:"openApi,omitempty" json:"openApi,omitempty"`
 }
 
-// NewConfig read YAML config file, required to start GoKoala.
-func NewConfig(configFile string) (*Config, error) {
-	yamlData, err := os.ReadFile(configFile)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read config file %w", err)
+// NewConfig read YAML config file(s), required to start GoKoala. When multiple config files
+// are given, each file is an overlay merged on top of the previous file(s), see mergeYAML.
+func NewConfig(configFiles ...string) (*Config, error) {
+	if len(configFiles) == 0 {
+		return nil, errors.New("no config file given")
+	}
+	var merged *yaml.Node
+	for _, configFile := range configFiles {
+		node, err := readConfigFile(configFile)
+		if err != nil {
+			return nil, err
+		}
+		if merged == nil {
+			merged = node
+		} else {
+			merged = mergeYAML(merged, node)
+		}
 	}
-
-	// expand environment variables
-	yamlData = []byte(os.ExpandEnv(string(yamlData)))
 
 	var config *Config
-	err = yaml.Unmarshal(yamlData, &config)
+	err := merged.Decode(&config)
 	if err != nil {
 		return nil, fmt.Errorf("failed to unmarshal config file, error: %w", err)
 	}
@@ -114,6 +133,33 @@ func NewConfig(configFile string) (*Config, error) {
 	return config, nil
 }
 
+// readConfigFile reads a single config file, including environment variables and secret references.
+func readConfigFile(configFile string) (*yaml.Node, error) {
+	yamlData, err := os.ReadFile(configFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file %w", err)
+	}
+
+	// expand environment variables
+	yamlData = expandEnv(yamlData)
+
+	var document yaml.Node
+	err = yaml.Unmarshal(yamlData, &document)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal config file %s, error: %w", configFile, err)
+	}
+	if len(document.Content) == 0 {
+		return nil, fmt.Errorf("config file %s is empty", configFile)
+	}
+	root := document.Content[0]
+	err = resolveSecretReferences(root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve secret in config file %s, error: %w", configFile, err)
+	}
+
+	return root, nil
+}
+
 // UnmarshalYAML hooks into unmarshalling to set defaults and validate config.
 func (c *Config) UnmarshalYAML(unmarshal func(any) error) error {
 	type cfg Config
@@ -215,6 +261,17 @@ type Resources struct {
 	Directory *string `yaml:"directory,omitempty" json:"directory,omitempty" validate:"required_without=URL,omitempty,dirpath|filepath"`
 }
 
+// +kubebuilder:object:generate=true
+type OpenAPI struct {
+	// Split the collection specific paths (e.g. /collections/foo/items) into separate OpenAPI documents,
+	// served at /api/collections/{collectionId} and referenced from the main OpenAPI spec. These
+	// documents are generated on first use. Recommended for APIs with many collections since
+	// it considerably reduces the size of the main spec and the startup time.
+	// +kubebuilder:default=false
+	// +optional
+	SplitPerCollection bool `yaml:"splitPerCollection,omitempty" json:"splitPerCollection,omitempty"`
+}
+
 // +kubebuilder:object:generate=true
 type License struct {
 	// Name of the license, e.g. MIT, CC0, etc
@@ -258,9 +315,24 @@ func validate(config *Config) error {
 	if config.OgcAPI.Features != nil {
 		errs = append(errs, validateFeatureCollections(config.OgcAPI.Features.Collections))
 	}
+	if config.OgcAPI.FeaturesSearch != nil {
+		errs = append(errs, validateCollectionRefs(config.OgcAPI.FeaturesSearch.Collections, config.OgcAPI.Features))
+	}
+	if config.OgcAPI.GeoVolumes != nil {
+		errs = append(errs, validateGeoVolumes(config.OgcAPI.GeoVolumes))
+	}
+	if config.OgcAPI.Processes != nil {
+		errs = append(errs, validateProcesses(config.OgcAPI.Processes, config.OgcAPI.Features))
+	}
+	if config.OgcAPI.Styles != nil {
+		errs = append(errs, validateStyles(config.OgcAPI.Styles))
+	}
 	if config.OgcAPI.Tiles != nil {
 		errs = append(errs, validateTileProjections(config.OgcAPI.Tiles))
+		errs = append(errs, validateTileSources(config.OgcAPI.Tiles))
 	}
+	errs = append(errs, validateExtents(config.AllCollections()))
+	errs = append(errs, validateTranslations(config))
 	err = errors.Join(errs...)
 	if err != nil {
 		return err
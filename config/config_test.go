@@ -8,6 +8,7 @@ import (
 	"path"
 	"runtime"
 	"testing"
+	"time"
 
 	"github.com/PDOK/gokoala/internal/engine/types"
 	"github.com/stretchr/testify/assert"
@@ -80,6 +81,146 @@ func TestNewConfig(t *testing.T) {
 			wantErr:    true,
 			wantErrMsg: "validation failed for srs 'EPSG:99999'; srs is not supported",
 		},
+		{
+			name: "fail on invalid config with custom tileMatrixSet but no custom tileMatrixSets",
+			args: args{
+				configFile: "internal/engine/testdata/config_invalid_tiles_custom_tms.yaml",
+			},
+			wantErr:    true,
+			wantErrMsg: "tileMatrixSet 'NetherlandsUTM31Quad' is not built-in, configure customTileMatrixSets to use it",
+		},
+		{
+			name: "fail on invalid config with 3D collection referring to unknown child",
+			args: args{
+				configFile: "internal/engine/testdata/config_invalid_3d_children.yaml",
+			},
+			wantErr:    true,
+			wantErrMsg: "validation failed for 3D collection 'city'; child 'trees' doesn't refer to a 3D collection",
+		},
+		{
+			name: "fail on invalid config with 3D collection lacking both tile server and local path",
+			args: args{
+				configFile: "internal/engine/testdata/config_invalid_3d_source.yaml",
+			},
+			wantErr:    true,
+			wantErrMsg: "validation failed for 3D collection 'trees'; either configure a tileServer or a localPath for this collection",
+		},
+		{
+			name: "fail on invalid config with built-in processes lacking OGC API Features",
+			args: args{
+				configFile: "internal/engine/testdata/config_invalid_processes.yaml",
+			},
+			wantErr:    true,
+			wantErrMsg: "validation failed for processes; either configure a processesServer or configure OGC API Features to offer the built-in processes",
+		},
+		{
+			name: "fail on invalid config with translations to languages that aren't available",
+			args: args{
+				configFile: "internal/engine/testdata/config_invalid_translations.yaml",
+			},
+			wantErr:    true,
+			wantErrMsg: "validation failed for translations of collection 'buildings'; language 'de' isn't one of the availableLanguages",
+		},
+		{
+			name: "fail on invalid config with a temporal extent that isn't a UTC datetime",
+			args: args{
+				configFile: "internal/engine/testdata/config_invalid_interval.yaml",
+			},
+			wantErr:    true,
+			wantErrMsg: `validation failed for collection 'buildings'; field 'Extent.Interval' is invalid: "2020-01-01" should be a UTC datetime like 2006-01-02T15:04:05Z`,
+		},
+		{
+			name: "read config file with translations",
+			args: args{
+				configFile: "internal/engine/testdata/config_translations.yaml",
+			},
+			wantErr: false,
+		},
+		{
+			name: "read config file with tiles served from archives",
+			args: args{
+				configFile: "internal/engine/testdata/config_tiles_archive.yaml",
+			},
+			wantErr: false,
+		},
+		{
+			name: "read config file with tile cache",
+			args: args{
+				configFile: "internal/engine/testdata/config_tiles_cache.yaml",
+			},
+			wantErr: false,
+		},
+		{
+			name: "fail on invalid config with tiles without tile server or archive",
+			args: args{
+				configFile: "internal/engine/testdata/config_invalid_tiles_archive.yaml",
+			},
+			wantErr:    true,
+			wantErrMsg: "validation failed for srs 'EPSG:3857' in top-level tiles; either configure a tileServer or an archive for this srs",
+		},
+		{
+			name: "fail on invalid config with archive in custom tileMatrixSet",
+			args: args{
+				configFile: "internal/engine/testdata/config_invalid_tiles_archive_custom_tms.yaml",
+			},
+			wantErr:    true,
+			wantErrMsg: "archives can only be used with built-in (quadtree) tileMatrixSets, not with 'NetherlandsUTM31Quad'",
+		},
+		{
+			name: "fail on invalid config with TileJSON vector layers but no tile server",
+			args: args{
+				configFile: "internal/engine/testdata/config_invalid_tiles_tilejson.yaml",
+			},
+			wantErr:    true,
+			wantErrMsg: "validation failed for top-level tiles; vectorLayers.uriTemplateTileJSON requires a tileServer",
+		},
+		{
+			name: "read config file with derived tiles",
+			args: args{
+				configFile: "internal/engine/testdata/config_tiles_derived.yaml",
+			},
+			wantErr: false,
+		},
+		{
+			name: "fail on invalid config with tiles derived from tileMatrixSet which isn't offered",
+			args: args{
+				configFile: "internal/engine/testdata/config_invalid_tiles_derived.yaml",
+			},
+			wantErr:    true,
+			wantErrMsg: "validation failed for srs 'EPSG:3857' in top-level tiles; derivedFrom 'EuropeanETRS89_LAEAQuad' should be the tileMatrixSet of another supported srs",
+		},
+		{
+			name: "fail on invalid config with unsupported styles storage",
+			args: args{
+				configFile: "internal/engine/testdata/config_invalid_styles_storage.yaml",
+			},
+			wantErr:    true,
+			wantErrMsg: "Field validation for 'Storage' failed on the 'oneof' tag",
+		},
+		{
+			name: "fail on invalid config with relation to unknown collection",
+			args: args{
+				configFile: "internal/engine/testdata/config_invalid_relations.yaml",
+			},
+			wantErr:    true,
+			wantErrMsg: "validation failed for relation 'standplaatsen' of collection 'verblijfsobjecten'; related collection 'standplaatsen' doesn't exist",
+		},
+		{
+			name: "fail on invalid config with search collection referencing unknown local collection",
+			args: args{
+				configFile: "internal/engine/testdata/config_invalid_collection_refs.yaml",
+			},
+			wantErr:    true,
+			wantErrMsg: "validation failed for search collection 'addresses'; collectionRef 'addresses' doesn't exist in OGC API Features",
+		},
+		{
+			name: "fail on invalid config with duplicate style IDs",
+			args: args{
+				configFile: "internal/engine/testdata/config_invalid_styles_duplicate.yaml",
+			},
+			wantErr:    true,
+			wantErrMsg: "validation failed for style 'default'; style ID isn't unique per projection",
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -342,6 +483,28 @@ func TestCollectionsSRS(t *testing.T) {
 	assert.Equal(t, expected, oaf.CollectionsSRS())
 }
 
+func TestTilesCache_Defaults(t *testing.T) {
+	config, err := NewConfig("internal/engine/testdata/config_tiles_cache.yaml")
+	require.NoError(t, err)
+
+	cache := config.OgcAPI.Tiles.Cache
+	require.NotNil(t, cache)
+	assert.Equal(t, 10000, cache.MaxEntries)
+	assert.Equal(t, time.Hour, cache.DefaultMaxAge.Duration)
+	maxSize, err := cache.MaxSizeAsBytes()
+	require.NoError(t, err)
+	assert.Equal(t, int64(256_000_000), maxSize)
+
+	require.NotNil(t, cache.Disk)
+	maxDiskSize, err := cache.Disk.MaxSizeAsBytes()
+	require.NoError(t, err)
+	assert.Equal(t, int64(1_000_000_000), maxDiskSize)
+
+	require.NotNil(t, cache.Seed)
+	assert.Equal(t, 4, cache.Seed.Concurrency)
+	assert.Empty(t, cache.Seed.Srs)
+}
+
 func TestCacheDir(t *testing.T) {
 	tests := []struct {
 		name    string
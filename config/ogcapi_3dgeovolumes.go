@@ -1,9 +1,13 @@
 package config
 
+import "fmt"
+
 // +kubebuilder:object:generate=true
 type OgcAPI3dGeoVolumes struct {
-	// Reference to the server (or object storage) hosting the 3D Tiles
-	TileServer URL `yaml:"tileServer" json:"tileServer" validate:"required"`
+	// Reference to the server (or object storage) hosting the 3D Tiles.
+	// Not required when all collections are served from local disk (see localPath).
+	// +optional
+	TileServer URL `yaml:"tileServer" json:"tileServer"`
 
 	// Collections to be served as 3D GeoVolumes
 	Collections GeoVolumesCollections `yaml:"collections" json:"collections"`
@@ -48,6 +52,12 @@ type GeoVolumesCollection struct {
 	// +optional
 	TileServerPath *string `yaml:"tileServerPath,omitempty" json:"tileServerPath,omitempty"`
 
+	// Optional path to a directory or 3D Tiles archive (*.3tz) on local disk containing the 3D tiles (or
+	// quantized mesh) of this collection. When set, tiles are served from local disk instead of the tileserver.
+	// The tileset.json (or layer.json in case of a DTM) should be located in the root of the directory/archive.
+	// +optional
+	LocalPath *string `yaml:"localPath,omitempty" json:"localPath,omitempty"`
+
 	// Is a digital terrain model (DTM) in Quantized Mesh format, REQUIRED when you want to serve a DTM.
 	// +kubebuilder:default=false
 	// +optional
@@ -60,6 +70,36 @@ type GeoVolumesCollection struct {
 	// Optional URL to 3D viewer to visualize the given collection of 3D Tiles.
 	// +optional
 	URL3DViewer *URL `yaml:"3dViewerUrl,omitempty" json:"3dViewerUrl,omitempty"`
+
+	// Optional IDs of other 3D GeoVolumes collections nested in this collection (e.g. the
+	// buildings in a city), these are advertised as children of this collection.
+	// +optional
+	Children []string `yaml:"children,omitempty" json:"children,omitempty"`
+}
+
+func validateGeoVolumes(geoVolumes *OgcAPI3dGeoVolumes) error {
+	var errMessages []string
+	collections := geoVolumes.Collections
+	for _, collection := range collections {
+		if collection.LocalPath == nil && geoVolumes.TileServer.URL == nil {
+			errMessages = append(errMessages, fmt.Sprintf("validation failed for 3D collection '%s'; "+
+				"either configure a tileServer or a localPath for this collection\n", collection.ID))
+		}
+		for _, child := range collection.Children {
+			if child == collection.ID {
+				errMessages = append(errMessages, fmt.Sprintf("validation failed for 3D collection '%s'; "+
+					"collection can't be a child of itself\n", collection.ID))
+			} else if !collections.ContainsID(child) {
+				errMessages = append(errMessages, fmt.Sprintf("validation failed for 3D collection '%s'; "+
+					"child '%s' doesn't refer to a 3D collection\n", collection.ID, child))
+			}
+		}
+	}
+	if len(errMessages) > 0 {
+		return fmt.Errorf("invalid config provided:\n%v", errMessages)
+	}
+
+	return nil
 }
 
 func (cgv GeoVolumesCollection) GetID() string {
@@ -209,6 +209,12 @@ func validateFeatureCollections(collections []FeaturesCollection) error {
 			errMessages = append(errMessages, fmt.Sprintf("validation failed for collection '%s'; "+
 				"field 'Extent.Interval' is required with field 'TemporalProperties'\n", collection.ID))
 		}
+		for _, relation := range collection.Relations {
+			if !FeaturesCollections(collections).ContainsID(relation.RelatedCollection) {
+				errMessages = append(errMessages, fmt.Sprintf("validation failed for relation '%s' of collection '%s'; "+
+					"related collection '%s' doesn't exist\n", relation.Name(), collection.ID, relation.RelatedCollection))
+			}
+		}
 		if collection.Filters.Properties != nil {
 			for _, pf := range collection.Filters.Properties {
 				if pf.AllowedValues != nil && *pf.DeriveAllowedValuesFromDatasource {
@@ -130,10 +130,11 @@ type Postgres struct {
 	// +optional
 	User string `yaml:"user" json:"user" validate:"required" default:"postgres"`
 
-	// Password when connecting to the PostgreSQL server.
+	// Password when connecting to the PostgreSQL server. Preferably reference a secret instead
+	// of inlining the password, e.g. ${env:PG_PASS} or ${file:/run/secrets/pg}.
 	// +kubebuilder:default="postgres"
 	// +optional
-	Pass string `yaml:"pass" json:"pass" validate:"required" default:"postgres"`
+	Pass Secret `yaml:"pass" json:"pass" validate:"required" default:"postgres"`
 
 	// When true the geometry column in the feature table needs to be indexed. Initialization will fail
 	// when no index is present, when false the index check is skipped. For large tables an index is recommended!
@@ -148,7 +149,7 @@ func (p *Postgres) ConnectionString() string {
 	defaultSearchPath := "public, postgis, topology" // otherwise postgis extension isn't found
 
 	return fmt.Sprintf("postgres://%s:%s@%s/%s?sslmode=%s&search_path=%s,%s&application_name=%s",
-		p.User, p.Pass, net.JoinHostPort(p.Host, port), p.DatabaseName, p.SSLMode,
+		p.User, p.Pass.Value(), net.JoinHostPort(p.Host, port), p.DatabaseName, p.SSLMode,
 		p.Schema, defaultSearchPath, AppName)
 }
 
@@ -250,7 +251,8 @@ type GeoPackageCloud struct {
 
 	// Some kind of credential like a password or key to authenticate with the storage backend, e.g:
 	// 'Eby8vdM02xNOcqFlqUwJPLlmEtlCDXJ1OUzFT50uSRZ6IFsuFq2UVErCz4I6tq/K1SZFPTOtr/KBHBeksoGMGw==' when using Azurite.
-	Auth string `yaml:"auth" json:"auth" validate:"required"`
+	// Preferably reference a secret instead of inlining the credential, e.g. ${file:/run/secrets/storage}.
+	Auth Secret `yaml:"auth" json:"auth" validate:"required"`
 
 	// Container/bucket on the storage account
 	Container string `yaml:"container" json:"container" validate:"required"`
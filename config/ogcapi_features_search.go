@@ -1,6 +1,7 @@
 package config
 
 import (
+	"fmt"
 	"net/url"
 	"slices"
 
@@ -286,3 +287,26 @@ func (fas FeaturesAndSearchConfig) ForceUTC() bool {
 	}
 	return fas.features.ForceUTC
 }
+
+// validateCollectionRefs validates that references to feature collections hosted on this server
+// (so without an 'api' base URL) point to an existing feature collection.
+func validateCollectionRefs(collections FeaturesSearchCollections, features *OgcAPIFeatures) error {
+	var errMessages []string
+	for _, collection := range collections {
+		for _, ref := range collection.CollectionRefs {
+			if ref.APIBaseURL.URL != nil {
+				continue // remote collection, can't be validated here
+			}
+			if features == nil || !features.Collections.ContainsID(ref.CollectionID) {
+				errMessages = append(errMessages, fmt.Sprintf("validation failed for search collection '%s'; "+
+					"collectionRef '%s' doesn't exist in OGC API Features, specify 'api' when referencing a "+
+					"remote collection\n", collection.ID, ref.CollectionID))
+			}
+		}
+	}
+	if len(errMessages) > 0 {
+		return fmt.Errorf("invalid config provided:\n%v", errMessages)
+	}
+
+	return nil
+}
@@ -1,13 +1,81 @@
 package config
 
+import (
+	"errors"
+)
+
 // +kubebuilder:object:generate=true
 type OgcAPIProcesses struct {
 	// Enable to advertise dismiss operations on the conformance page
 	SupportsDismiss bool `yaml:"supportsDismiss" json:"supportsDismiss"`
 
-	// Enable to advertise callback operations on the conformance page
+	// Enable to advertise callback operations on the conformance page. For built-in processes, callbacks
+	// to subscribers on internal (loopback, private or link-local) addresses are refused.
 	SupportsCallback bool `yaml:"supportsCallback" json:"supportsCallback"`
 
-	// Reference to an external service implementing the process API. GoKoala acts only as a proxy for OGC API Processes.
-	ProcessesServer URL `yaml:"processesServer" json:"processesServer" validate:"required"`
+	// Reference to an external service implementing the process API. When configured GoKoala acts only as a
+	// proxy for OGC API Processes. Otherwise, GoKoala executes the built-in processes itself.
+	// +optional
+	ProcessesServer URL `yaml:"processesServer,omitempty" json:"processesServer,omitempty"`
+
+	// ADVANCED SETTING. Maximum number of retries when retrieving the OpenAPI spec and process descriptions
+	// from the processesServer on startup. These are merged into the OpenAPI spec and landing page of GoKoala.
+	// +kubebuilder:default=3
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	ProcessesServerRetries int `yaml:"processesServerRetries,omitempty" json:"processesServerRetries,omitempty" validate:"required,gte=1" default:"3"`
+
+	// Settings regarding the execution of built-in processes. Not applicable when a processesServer is configured.
+	// +optional
+	Jobs ProcessesJobs `yaml:"jobs,omitempty" json:"jobs,omitempty"`
+}
+
+// +kubebuilder:object:generate=true
+type ProcessesJobs struct {
+	// Where to keep track of jobs (status and results of process executions). Jobs kept in memory are lost on restart.
+	// +kubebuilder:validation:Enum=memory;sqlite
+	// +kubebuilder:default="memory"
+	// +optional
+	Store string `yaml:"store,omitempty" json:"store,omitempty" default:"memory" validate:"oneof=memory sqlite"`
+
+	// Path to the SQLite database on local disk in which jobs are kept. Created when it doesn't exist.
+	// Required when store is 'sqlite'.
+	// +optional
+	SQLitePath string `yaml:"sqlitePath,omitempty" json:"sqlitePath,omitempty" validate:"required_if=Store sqlite"`
+
+	// Maximum number of jobs executed concurrently, additional jobs are queued.
+	// +kubebuilder:default=4
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	MaxConcurrent int `yaml:"maxConcurrent,omitempty" json:"maxConcurrent,omitempty" default:"4" validate:"gte=1"`
+
+	// Maximum number of jobs waiting for execution. When the queue is full new jobs are refused (503 Service Unavailable).
+	// +kubebuilder:default=100
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MaxQueued int `yaml:"maxQueued,omitempty" json:"maxQueued,omitempty" default:"100" validate:"gte=0"`
+
+	// Maximum duration of a single job execution (excluding time spent in the queue), after which the job fails.
+	// +kubebuilder:default="10m"
+	// +optional
+	Timeout Duration `yaml:"timeout,omitempty" json:"timeout,omitempty" validate:"required" default:"10m"`
+
+	// Duration after which finished jobs (including their results) are removed.
+	// +kubebuilder:default="24h"
+	// +optional
+	Retention Duration `yaml:"retention,omitempty" json:"retention,omitempty" validate:"required" default:"24h"`
+}
+
+// IsProxy whether an external service implements the process API, as opposed to the built-in processes.
+func (p *OgcAPIProcesses) IsProxy() bool {
+	return p.ProcessesServer.URL != nil
+}
+
+func validateProcesses(processes *OgcAPIProcesses, features *OgcAPIFeatures) error {
+	if !processes.IsProxy() && features == nil {
+		return errors.New("validation failed for processes; either configure a processesServer or " +
+			"configure OGC API Features to offer the built-in processes\n")
+	}
+
+	return nil
 }
@@ -1,5 +1,13 @@
 package config
 
+import (
+	"fmt"
+	"strings"
+)
+
+// styleProjectionDelimiter separates the style ID from the projection in the ID of a style instance, e.g. 'default__netherlandsrdnewquad'.
+const styleProjectionDelimiter = "__"
+
 // +kubebuilder:object:generate=true
 type OgcAPIStyles struct {
 	// ID of the style to use a default
@@ -10,6 +18,21 @@ type OgcAPIStyles struct {
 
 	// Styles exposed though this API
 	SupportedStyles []Style `yaml:"supportedStyles" json:"supportedStyles" validate:"required,dive"`
+
+	// Enables creating, updating and deleting styles through the API (OGC API Styles "manage-styles").
+	// Styles configured in this file are read-only, only styles created through the API can be changed.
+	// GoKoala doesn't offer authentication, so make sure to protect these endpoints (e.g. in an API gateway).
+	// +optional
+	Manage *StylesManagement `yaml:"manage,omitempty" json:"manage,omitempty"`
+}
+
+// +kubebuilder:object:generate=true
+type StylesManagement struct {
+	// Backend to persist styles created through the API. Currently only 'local' is
+	// supported, which stores the styles (and their metadata) in the stylesDir.
+	// +kubebuilder:default="local"
+	// +optional
+	Storage string `yaml:"storage,omitempty" json:"storage,omitempty" default:"local" validate:"required,oneof=local"`
 }
 
 // +kubebuilder:object:generate=true
@@ -51,6 +74,10 @@ type Style struct {
 
 	// This style is offered in the following formats
 	Formats []StyleFormat `yaml:"formats" json:"formats" validate:"required,dive"`
+
+	// Title, description and keywords of this style in other languages, by language (e.g. 'en').
+	// +optional
+	Translations map[string]MetadataTranslation `yaml:"translations,omitempty" json:"translations,omitempty"`
 }
 
 // +kubebuilder:object:generate=true
@@ -60,3 +87,31 @@ type StyleFormat struct {
 	// +optional
 	Format string `yaml:"format,omitempty" json:"format,omitempty" default:"mapbox" validate:"required,oneof=mapbox sld10"`
 }
+
+// validateStyles validates that style IDs are unique, since each style is offered per projection
+// (as '<style id>__<projection>') the ID may also not contain the projection delimiter.
+func validateStyles(styles *OgcAPIStyles) error {
+	var errMessages []string
+	if len(styles.SupportedStyles) > 0 && styles.SupportedStyles[0].ID != styles.Default {
+		errMessages = append(errMessages, fmt.Sprintf("validation failed for styles; default style '%s' "+
+			"must be the first entry in supportedStyles\n", styles.Default))
+	}
+	seen := make(map[string]bool, len(styles.SupportedStyles))
+	for _, style := range styles.SupportedStyles {
+		if seen[style.ID] {
+			errMessages = append(errMessages, fmt.Sprintf("validation failed for style '%s'; "+
+				"style ID isn't unique per projection\n", style.ID))
+		}
+		seen[style.ID] = true
+		if strings.Contains(style.ID, styleProjectionDelimiter) {
+			errMessages = append(errMessages, fmt.Sprintf("validation failed for style '%s'; "+
+				"style ID may not contain '%s' since it's used to separate style and projection\n",
+				style.ID, styleProjectionDelimiter))
+		}
+	}
+	if len(errMessages) > 0 {
+		return fmt.Errorf("invalid config provided:\n%v", errMessages)
+	}
+
+	return nil
+}
@@ -3,10 +3,12 @@ package config
 import (
 	"encoding/json"
 	"fmt"
+	"path/filepath"
 	"slices"
 	"sort"
 
 	"github.com/PDOK/gokoala/internal/engine/util"
+	"github.com/docker/go-units"
 	"gopkg.in/yaml.v3"
 )
 
@@ -21,6 +23,89 @@ type OgcAPITiles struct {
 	// Tiles per collection. When no collections are specified tiles should be hosted at the root of the API (/tiles endpoint).
 	// +optional
 	Collections TilesCollections `yaml:"collections,omitempty" json:"collections,omitempty"`
+
+	// Custom TileMatrixSets (OGC 2D-TMS JSON definitions) in addition to the built-in ones.
+	// Reference a custom TileMatrixSet by its ID in the 'tileMatrixSet' of a supported SRS.
+	// +optional
+	CustomTileMatrixSets *CustomTileMatrixSets `yaml:"customTileMatrixSets,omitempty" json:"customTileMatrixSets,omitempty"`
+
+	// Optional cache for tiles retrieved from the tile server. Applies to both dataset and collection tiles.
+	// +optional
+	Cache *TilesCache `yaml:"cache,omitempty" json:"cache,omitempty"`
+}
+
+// +kubebuilder:object:generate=true
+type TilesCache struct {
+	// Max number of tiles kept in memory.
+	// +kubebuilder:default=10000
+	// +optional
+	MaxEntries int `yaml:"maxEntries,omitempty" json:"maxEntries,omitempty" validate:"gt=0" default:"10000"`
+
+	// Max size of the in-memory cache. Accepts human-readable size such as 64Mb, 1Gb, etc. When omitted 256Mb is used.
+	// +kubebuilder:default="256Mb"
+	// +optional
+	MaxSize string `yaml:"maxSize,omitempty" json:"maxSize,omitempty" default:"256Mb"`
+
+	// How long tiles are cached when the tile server doesn't specify a max-age (in the Cache-Control header).
+	// +kubebuilder:default="1h"
+	// +optional
+	DefaultMaxAge Duration `yaml:"defaultMaxAge,omitempty" json:"defaultMaxAge,omitempty" default:"1h"`
+
+	// Optional second tier on local disk, for tiles evicted from memory.
+	// +optional
+	Disk *TilesDiskCache `yaml:"disk,omitempty" json:"disk,omitempty"`
+
+	// Optional pre-seeding of the cache during startup.
+	// +optional
+	Seed *TilesCacheSeed `yaml:"seed,omitempty" json:"seed,omitempty"`
+}
+
+// MaxSizeAsBytes max size of the in-memory cache in bytes.
+func (cache *TilesCache) MaxSizeAsBytes() (int64, error) {
+	return units.FromHumanSize(cache.MaxSize)
+}
+
+// +kubebuilder:object:generate=true
+type TilesDiskCache struct {
+	// Directory to store cached tiles. Note: existing cached tiles in this directory are removed on startup.
+	Path string `yaml:"path" json:"path" validate:"required,dirpath|filepath"`
+
+	// Max size of the disk cache. Accepts human-readable size such as 100Mb, 4Gb, 1Tb, etc. When omitted 1Gb is used.
+	// +kubebuilder:default="1Gb"
+	// +optional
+	MaxSize string `yaml:"maxSize,omitempty" json:"maxSize,omitempty" default:"1Gb"`
+}
+
+// MaxSizeAsBytes max size of the disk cache in bytes.
+func (cache *TilesDiskCache) MaxSizeAsBytes() (int64, error) {
+	return units.FromHumanSize(cache.MaxSize)
+}
+
+// +kubebuilder:object:generate=true
+type TilesCacheSeed struct {
+	// Zoom levels to seed, within the tile matrix set limits of each tileset. Be aware that the number
+	// of tiles grows exponentially with each zoom level, so usually only the lowest zoom levels are seeded.
+	ZoomLevelRange ZoomLevelRange `yaml:"zoomLevelRange" json:"zoomLevelRange" validate:"required"`
+
+	// Projections (SRS/CRS) to seed. When omitted all supported projections are seeded.
+	// +optional
+	Srs []string `yaml:"srs,omitempty" json:"srs,omitempty" validate:"dive,startswith=EPSG:"`
+
+	// Number of tiles requested in parallel from the tile server while seeding.
+	// +kubebuilder:default=4
+	// +optional
+	Concurrency int `yaml:"concurrency,omitempty" json:"concurrency,omitempty" validate:"gt=0" default:"4"`
+}
+
+// +kubebuilder:object:generate=true
+type CustomTileMatrixSets struct {
+	// Directory containing TileMatrixSet definitions, all *.json files in this directory are loaded.
+	// +optional
+	Dir *string `yaml:"dir,omitempty" json:"dir,omitempty" validate:"omitempty,dir"`
+
+	// Individual TileMatrixSet definitions (*.json files)
+	// +optional
+	Files []string `yaml:"files,omitempty" json:"files,omitempty" validate:"dive,file"`
 }
 
 type TilesCollections []TilesCollection
@@ -37,16 +122,20 @@ func (cst TilesCollections) ContainsID(id string) bool {
 
 // Keep in sync with OgcAPITiles
 type OgcAPITilesJSON struct {
-	*Tiles      `json:",inline"`
-	Collections []TilesCollection `json:"collections,omitempty"`
+	*Tiles               `json:",inline"`
+	Collections          []TilesCollection     `json:"collections,omitempty"`
+	CustomTileMatrixSets *CustomTileMatrixSets `json:"customTileMatrixSets,omitempty"`
+	Cache                *TilesCache           `json:"cache,omitempty"`
 }
 
 // MarshalJSON custom because inlining only works on embedded structs.
 // Value instead of pointer receiver because only that way it can be used for both.
 func (o OgcAPITiles) MarshalJSON() ([]byte, error) {
 	return json.Marshal(OgcAPITilesJSON{
-		Tiles:       o.DatasetTiles,
-		Collections: o.Collections,
+		Tiles:                o.DatasetTiles,
+		Collections:          o.Collections,
+		CustomTileMatrixSets: o.CustomTileMatrixSets,
+		Cache:                o.Cache,
 	})
 }
 
@@ -140,6 +229,15 @@ const (
 	TilesTypeVector TilesType = "vector"
 )
 
+// +kubebuilder:validation:Enum=png;jpeg;webp
+type RasterTilesFormat string
+
+const (
+	RasterTilesFormatPNG  RasterTilesFormat = "png"
+	RasterTilesFormatJPEG RasterTilesFormat = "jpeg"
+	RasterTilesFormatWebP RasterTilesFormat = "webp"
+)
+
 func (o *OgcAPITiles) HasType(t TilesType) bool {
 	if o.DatasetTiles != nil && slices.Contains(o.DatasetTiles.Types, t) {
 		return true
@@ -154,7 +252,8 @@ func (o *OgcAPITiles) HasType(t TilesType) bool {
 }
 
 // AllTileProjections projections supported by GoKoala for serving (vector) tiles, regardless of the dataset.
-// When adding a new projection also add corresponding HTML/JSON templates.
+// Each projection maps to a built-in TileMatrixSet. When adding a new projection also add
+// a corresponding TileMatrixSet definition (internal/ogc/tiles/tileMatrixSets).
 var AllTileProjections = map[string]string{
 	"EPSG:28992": "NetherlandsRDNewQuad",
 	"EPSG:3035":  "EuropeanETRS89_LAEAQuad",
@@ -193,12 +292,43 @@ func (o *OgcAPITiles) GetProjections() []SupportedSrs {
 	return result
 }
 
+// GetCustomTileMatrixSetIDs IDs of the custom (not built-in) TileMatrixSets used by this dataset.
+func (o *OgcAPITiles) GetCustomTileMatrixSetIDs() []string {
+	result := make([]string, 0)
+	for _, projection := range o.GetProjections() {
+		id := projection.GetTileMatrixSetID()
+		if !IsBuiltInTileMatrixSet(id) && !slices.Contains(result, id) {
+			result = append(result, id)
+		}
+	}
+	sort.Strings(result)
+
+	return result
+}
+
+// IsBuiltInTileMatrixSet true when the given TileMatrixSet ID is one of AllTileProjections.
+func IsBuiltInTileMatrixSet(id string) bool {
+	for _, tileMatrixSetID := range AllTileProjections {
+		if tileMatrixSetID == id {
+			return true
+		}
+	}
+
+	return false
+}
+
 // +kubebuilder:object:generate=true
 type Tiles struct {
 	// Reference to the server (or object storage) hosting the tiles.
+	// Not required when tiles in all supported projections are served from archives.
 	// Note: Only marked as optional in CRD to support top-level OR collection-level tiles
 	// +optional
-	TileServer URL `yaml:"tileServer" json:"tileServer" validate:"required"`
+	TileServer URL `yaml:"tileServer" json:"tileServer"`
+
+	// Serve tiles directly from local MBTiles or PMTiles archives instead of the tile server.
+	// At most one archive per supported projection (SRS/CRS).
+	// +optional
+	Archives []TilesArchive `yaml:"archives,omitempty" json:"archives,omitempty" validate:"dive"`
 
 	// Could be 'vector' and/or 'raster' to indicate the types of tiles offered
 	// Note: Only marked as optional in CRD to support top-level OR collection-level tiles
@@ -214,9 +344,59 @@ type Tiles struct {
 	// +optional
 	URITemplateTiles *string `yaml:"uriTemplateTiles,omitempty" json:"uriTemplateTiles,omitempty"`
 
+	// Formats in which raster tiles are offered, only applicable when 'raster' is one of the types. Defaults to png.
+	// +optional
+	RasterFormats []RasterTilesFormat `yaml:"rasterFormats,omitempty" json:"rasterFormats,omitempty" validate:"dive,oneof=png jpeg webp"`
+
+	// Optional templates to the raster tiles on the tileserver, per raster format. Defaults to {tms}/{z}/{x}/{y}.<format>,
+	// for example {tms}/{z}/{x}/{y}.png. A template may contain a {style} placeholder, which is replaced by the
+	// requested style when serving styled raster tiles (OGC API Styles) or by the default style otherwise.
+	// +optional
+	URITemplateRasterTiles map[RasterTilesFormat]string `yaml:"uriTemplateRasterTiles,omitempty" json:"uriTemplateRasterTiles,omitempty"`
+
 	// Optional health check configuration
 	// +optional
 	HealthCheck HealthCheck `yaml:"healthCheck" json:"healthCheck"`
+
+	// Discover the vector layers (layer IDs, fields and zoom levels) in the tiles at startup. These are advertised
+	// in TileJSON and tileset metadata, allowing style editors (like Maputnik) to discover the schema of the tiles.
+	// Only applicable when 'vector' is one of the types.
+	// +optional
+	VectorLayers *TilesVectorLayers `yaml:"vectorLayers,omitempty" json:"vectorLayers,omitempty"`
+}
+
+// ArchiveBySrs returns the archive holding tiles in the given projection, nil when tiles
+// in this projection aren't served from an archive.
+func (t *Tiles) ArchiveBySrs(srs string) *TilesArchive {
+	for i := range t.Archives {
+		if t.Archives[i].Srs == srs {
+			return &t.Archives[i]
+		}
+	}
+
+	return nil
+}
+
+// HasType true when tiles of the given type are offered.
+func (t *Tiles) HasType(tilesType TilesType) bool {
+	return slices.Contains(t.Types, tilesType)
+}
+
+// GetRasterFormats formats in which raster tiles are offered, empty when no raster tiles are offered.
+func (t *Tiles) GetRasterFormats() []RasterTilesFormat {
+	if !t.HasType(TilesTypeRaster) {
+		return nil
+	}
+	if len(t.RasterFormats) == 0 {
+		return []RasterTilesFormat{RasterTilesFormatPNG}
+	}
+
+	return t.RasterFormats
+}
+
+// HasTileServer true when a tile server is configured.
+func (t *Tiles) HasTileServer() bool {
+	return t.TileServer.URL != nil
 }
 
 func (t *Tiles) deriveHealthCheckTilePath() {
@@ -232,6 +412,33 @@ func (t *Tiles) deriveHealthCheckTilePath() {
 	t.HealthCheck.TilePath = &tilePath
 }
 
+// +kubebuilder:object:generate=true
+type TilesVectorLayers struct {
+	// Optional template to a TileJSON document on the tileserver to read the vector layers from, for
+	// example {tms}/tiles.json. Requires a tileServer. When omitted a representative tile per zoom level is inspected instead.
+	// +optional
+	URITemplateTileJSON *string `yaml:"uriTemplateTileJSON,omitempty" json:"uriTemplateTileJSON,omitempty"`
+}
+
+// +kubebuilder:object:generate=true
+type TilesArchive struct {
+	// Projection (SRS/CRS) of the tiles in the archive. Tiles are addressed
+	// according to the corresponding TileMatrixSet.
+	// +kubebuilder:validation:Pattern=`^EPSG:\d+$`
+	Srs string `yaml:"srs" json:"srs" validate:"required,startswith=EPSG:"`
+
+	// Location of the MBTiles (*.mbtiles) or PMTiles (*.pmtiles) archive on disk.
+	// You can place the archive here manually (out-of-band) or you can specify Download
+	// and let the application download the archive for you and store it at this location.
+	// +kubebuilder:validation:Pattern=`^.+\.(mbtiles|pmtiles)$`
+	File string `yaml:"file" json:"file" validate:"required,filepath"`
+
+	// Optional initialization task to download the archive during startup. The archive will be
+	// downloaded to local disk and stored at the location specified in File.
+	// +optional
+	Download *GeoPackageDownload `yaml:"download,omitempty" json:"download,omitempty"`
+}
+
 // +kubebuilder:object:generate=true
 type SupportedSrs struct {
 	// Projection (SRS/CRS) used
@@ -240,6 +447,42 @@ type SupportedSrs struct {
 
 	// Available zoom levels
 	ZoomLevelRange ZoomLevelRange `yaml:"zoomLevelRange" json:"zoomLevelRange" validate:"required"`
+
+	// ID of the TileMatrixSet used for this projection. Defaults to the built-in TileMatrixSet
+	// of the projection. Set this to use a custom TileMatrixSet (see customTileMatrixSets).
+	// +optional
+	TileMatrixSet string `yaml:"tileMatrixSet,omitempty" json:"tileMatrixSet,omitempty"`
+
+	// ID of the TileMatrixSet of another supported SRS from which tiles in this SRS are derived. Use this when
+	// the tile server doesn't offer tiles in this SRS: tiles are then reprojected on the fly from the tiles in
+	// the other TileMatrixSet. Works for both vector and raster (png/jpeg) tiles, but comes at a (CPU) cost.
+	// +optional
+	DerivedFrom string `yaml:"derivedFrom,omitempty" json:"derivedFrom,omitempty"`
+}
+
+// GetTileMatrixSetID ID of the TileMatrixSet used for this projection.
+func (s SupportedSrs) GetTileMatrixSetID() string {
+	if s.TileMatrixSet != "" {
+		return s.TileMatrixSet
+	}
+
+	return AllTileProjections[s.Srs]
+}
+
+// IsDerived true when tiles in this projection are derived (reprojected) from tiles in another projection.
+func (s SupportedSrs) IsDerived() bool {
+	return s.DerivedFrom != ""
+}
+
+// SupportedSrsByTileMatrixSetID returns the supported SRS using the given TileMatrixSet, nil when not supported.
+func (t *Tiles) SupportedSrsByTileMatrixSetID(tileMatrixSetID string) *SupportedSrs {
+	for i := range t.SupportedSrs {
+		if t.SupportedSrs[i].GetTileMatrixSetID() == tileMatrixSetID {
+			return &t.SupportedSrs[i]
+		}
+	}
+
+	return nil
 }
 
 // +kubebuilder:object:generate=true
@@ -296,20 +539,97 @@ type HealthCheck struct {
 	TilePath *string `yaml:"tilePath,omitempty" json:"tilePath,omitempty" validate:"required_unless=Srs EPSG:28992"`
 }
 
-func validateTileProjections(tiles *OgcAPITiles) error {
+func validateTileSources(tiles *OgcAPITiles) error {
 	var errMessages []string
+	validate := func(t Tiles, location string) {
+		for _, archive := range t.Archives {
+			i := slices.IndexFunc(t.SupportedSrs, func(s SupportedSrs) bool { return s.Srs == archive.Srs })
+			if i < 0 {
+				errMessages = append(errMessages, fmt.Sprintf("validation failed for archive '%s' in %s; "+
+					"srs '%s' is not one of the supported srs", archive.File, location, archive.Srs))
+			} else if tms := t.SupportedSrs[i].GetTileMatrixSetID(); !IsBuiltInTileMatrixSet(tms) {
+				errMessages = append(errMessages, fmt.Sprintf("validation failed for archive '%s' in %s; "+
+					"archives can only be used with built-in (quadtree) tileMatrixSets, not with '%s'", archive.File, location, tms))
+			}
+			if ext := filepath.Ext(archive.File); ext != ".mbtiles" && ext != ".pmtiles" {
+				errMessages = append(errMessages, fmt.Sprintf("validation failed for archive '%s' in %s; "+
+					"only MBTiles (*.mbtiles) and PMTiles (*.pmtiles) archives are supported", archive.File, location))
+			}
+		}
+		for _, srs := range t.SupportedSrs {
+			if !srs.IsDerived() {
+				continue
+			}
+			source := t.SupportedSrsByTileMatrixSetID(srs.DerivedFrom)
+			switch {
+			case source == nil || source.Srs == srs.Srs:
+				errMessages = append(errMessages, fmt.Sprintf("validation failed for srs '%s' in %s; derivedFrom "+
+					"'%s' should be the tileMatrixSet of another supported srs", srs.Srs, location, srs.DerivedFrom))
+			case source.IsDerived():
+				errMessages = append(errMessages, fmt.Sprintf("validation failed for srs '%s' in %s; derivedFrom "+
+					"'%s' is itself derived, tiles can only be derived from tiles which aren't derived", srs.Srs, location, srs.DerivedFrom))
+			}
+			if t.ArchiveBySrs(srs.Srs) != nil {
+				errMessages = append(errMessages, fmt.Sprintf("validation failed for srs '%s' in %s; "+
+					"tiles are either derived or served from an archive, not both", srs.Srs, location))
+			}
+			if slices.Contains(t.GetRasterFormats(), RasterTilesFormatWebP) {
+				errMessages = append(errMessages, fmt.Sprintf("validation failed for srs '%s' in %s; "+
+					"derived raster tiles can't be encoded as webp", srs.Srs, location))
+			}
+		}
+		if t.VectorLayers != nil && t.VectorLayers.URITemplateTileJSON != nil && !t.HasTileServer() {
+			errMessages = append(errMessages, fmt.Sprintf("validation failed for %s; "+
+				"vectorLayers.uriTemplateTileJSON requires a tileServer", location))
+		}
+		if t.HasTileServer() {
+			return
+		}
+		if t.HasType(TilesTypeRaster) {
+			errMessages = append(errMessages, fmt.Sprintf("validation failed for %s; "+
+				"raster tiles can only be served from a tileServer", location))
+		}
+		for _, srs := range t.SupportedSrs {
+			if t.ArchiveBySrs(srs.Srs) == nil && !srs.IsDerived() {
+				errMessages = append(errMessages, fmt.Sprintf("validation failed for srs '%s' in %s; "+
+					"either configure a tileServer or an archive for this srs", srs.Srs, location))
+			}
+		}
+	}
 	if tiles.DatasetTiles != nil {
-		for _, srs := range tiles.DatasetTiles.SupportedSrs {
+		validate(*tiles.DatasetTiles, "top-level tiles")
+	}
+	for _, collection := range tiles.Collections {
+		validate(collection.GeoDataTiles, "tiles of collection "+collection.ID)
+	}
+	if len(errMessages) > 0 {
+		return fmt.Errorf("invalid config provided:\n%v", errMessages)
+	}
+
+	return nil
+}
+
+func validateTileProjections(tiles *OgcAPITiles) error {
+	var errMessages []string
+	validate := func(srs SupportedSrs) {
+		switch {
+		case srs.TileMatrixSet == "":
 			if _, ok := AllTileProjections[srs.Srs]; !ok {
 				errMessages = append(errMessages, fmt.Sprintf("validation failed for srs '%s'; srs is not supported", srs.Srs))
 			}
+		case !IsBuiltInTileMatrixSet(srs.TileMatrixSet) && tiles.CustomTileMatrixSets == nil:
+			errMessages = append(errMessages, fmt.Sprintf("validation failed for srs '%s'; tileMatrixSet '%s' "+
+				"is not built-in, configure customTileMatrixSets to use it", srs.Srs, srs.TileMatrixSet))
+		}
+	}
+	if tiles.DatasetTiles != nil {
+		for _, srs := range tiles.DatasetTiles.SupportedSrs {
+			validate(srs)
 		}
 	}
 	for _, collection := range tiles.Collections {
 		for _, srs := range collection.GeoDataTiles.SupportedSrs {
-			if _, ok := AllTileProjections[srs.Srs]; !ok {
-				errMessages = append(errMessages, fmt.Sprintf("validation failed for srs '%s'; srs is not supported", srs.Srs))
-			}
+			validate(srs)
 		}
 	}
 	if len(errMessages) > 0 {
@@ -0,0 +1,50 @@
+package config
+
+import (
+	"slices"
+
+	"gopkg.in/yaml.v3"
+)
+
+// mergeYAML merges the overlay into the base YAML node, without modifying either node. Mappings
+// are merged recursively, other values (including sequences) in the overlay replace those in the
+// base. This way an overlay (e.g. per environment) only needs to contain the settings that differ.
+// Use null in the overlay to remove a setting.
+func mergeYAML(base *yaml.Node, overlay *yaml.Node) *yaml.Node {
+	base, overlay = resolveAlias(base), resolveAlias(overlay)
+	if base.Kind != yaml.MappingNode || overlay.Kind != yaml.MappingNode {
+		return overlay
+	}
+	merged := *base
+	merged.Anchor = "" // a copy, aliases still refer to the original (anchored) node
+	merged.Content = slices.Clone(base.Content)
+	for i := 0; i+1 < len(overlay.Content); i += 2 {
+		key, value := overlay.Content[i], overlay.Content[i+1]
+		if j := indexOfKey(merged.Content, key.Value); j >= 0 {
+			merged.Content[j+1] = mergeYAML(merged.Content[j+1], value)
+		} else {
+			merged.Content = append(merged.Content, key, value)
+		}
+	}
+
+	return &merged
+}
+
+// indexOfKey returns the index of the given key in the content of a mapping node, or -1 when absent.
+func indexOfKey(mappingContent []*yaml.Node, key string) int {
+	for i := 0; i+1 < len(mappingContent); i += 2 {
+		if mappingContent[i].Value == key {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func resolveAlias(node *yaml.Node) *yaml.Node {
+	if node.Kind == yaml.AliasNode && node.Alias != nil {
+		return node.Alias
+	}
+
+	return node
+}
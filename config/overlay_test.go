@@ -0,0 +1,94 @@
+package config
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestMergeYAML(t *testing.T) {
+	tests := []struct {
+		name    string
+		base    string
+		overlay string
+		want    string
+	}{
+		{
+			name:    "merge mappings recursively",
+			base:    "a: 1\nb:\n  c: 2\n  d: 3\n",
+			overlay: "b:\n  d: 4\n  e: 5\nf: 6\n",
+			want:    "a: 1\nb:\n  c: 2\n  d: 4\n  e: 5\nf: 6\n",
+		},
+		{
+			name:    "replace sequences",
+			base:    "a: [1, 2, 3]\n",
+			overlay: "a: [4]\n",
+			want:    "a: [4]\n",
+		},
+		{
+			name:    "remove using null",
+			base:    "a: 1\nb:\n  c: 2\n",
+			overlay: "b: null\n",
+			want:    "a: 1\nb: null\n",
+		},
+		{
+			name:    "only merge into aliased mapping at given path",
+			base:    "a: &anchor\n  b: 1\nc: *anchor\n",
+			overlay: "c:\n  b: 2\n",
+			want:    "a: &anchor\n  b: 1\nc:\n  b: 2\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var base, overlay yaml.Node
+			require.NoError(t, yaml.Unmarshal([]byte(tt.base), &base))
+			require.NoError(t, yaml.Unmarshal([]byte(tt.overlay), &overlay))
+
+			merged := mergeYAML(base.Content[0], overlay.Content[0])
+
+			assert.Equal(t, tt.want, marshalYAML(t, merged))
+			assert.Equal(t, tt.base, marshalYAML(t, &base), "base should be unmodified")
+		})
+	}
+}
+
+func TestNewConfig_Overlays(t *testing.T) {
+	dir := t.TempDir()
+	overlay := writeConfig(t, dir, "overlay.yaml", `---
+title: Minimal OGC API (test)
+baseUrl: https://test.example.com
+license:
+  name: CC0
+`)
+	secondOverlay := writeConfig(t, dir, "second-overlay.yaml", `---
+baseUrl: https://acceptance.example.com
+`)
+
+	config, err := NewConfig("internal/engine/testdata/config_minimal.yaml", overlay, secondOverlay)
+	require.NoError(t, err)
+	assert.Equal(t, "Minimal OGC API (test)", config.Title)
+	assert.Equal(t, "https://acceptance.example.com", config.BaseURL.String())
+	assert.Equal(t, "CC0", config.License.Name)
+	assert.Equal(t, "https://www.tldrlegal.com/license/mit-license", config.License.URL.String())
+	assert.Equal(t, "1.0.2", config.Version)
+
+	invalidOverlay := writeConfig(t, dir, "invalid-overlay.yaml", "version: foo\n")
+	_, err = NewConfig("internal/engine/testdata/config_minimal.yaml", invalidOverlay)
+	require.ErrorContains(t, err, "validation for 'Version' failed on the 'semver' tag")
+
+	_, err = NewConfig()
+	require.Error(t, err)
+}
+
+func marshalYAML(t *testing.T, node *yaml.Node) string {
+	t.Helper()
+	var result bytes.Buffer
+	encoder := yaml.NewEncoder(&result)
+	encoder.SetIndent(2)
+	require.NoError(t, encoder.Encode(node))
+
+	return result.String()
+}
@@ -0,0 +1,19 @@
+//go:generate go run ../hack/jsonschema -config-dir . -output gokoala.schema.json
+package config
+
+import (
+	_ "embed"
+)
+
+// jsonSchema JSON Schema of the config file, generated from the structs in this package.
+//
+//go:embed gokoala.schema.json
+var jsonSchema []byte
+
+// JSONSchema returns the JSON Schema of the GoKoala config file. Editors/IDEs and CI pipelines
+// can use this schema to validate config files (offline). Note that the schema covers the structure
+// of the config file and simple constraints (e.g. enums and patterns), cross-field validations are
+// only performed when the config is loaded.
+func JSONSchema() []byte {
+	return jsonSchema
+}
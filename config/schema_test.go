@@ -0,0 +1,107 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestJSONSchema(t *testing.T) {
+	schema := compileJSONSchema(t)
+
+	files, err := filepath.Glob("examples/config_*.yaml")
+	require.NoError(t, err)
+	files = slices.DeleteFunc(files, func(file string) bool {
+		return strings.HasSuffix(file, "_etl.yaml") // not a GoKoala config, but config of the search ETL
+	})
+	files = append(files, "internal/engine/testdata/config_minimal.yaml")
+
+	for _, file := range files {
+		t.Run(file, func(t *testing.T) {
+			assert.NoError(t, schema.Validate(readYAMLAsJSON(t, file)))
+		})
+	}
+}
+
+func TestJSONSchema_Invalid(t *testing.T) {
+	schema := compileJSONSchema(t)
+
+	tests := []struct {
+		name       string
+		config     string
+		wantErrMsg string
+	}{
+		{
+			name:       "missing required property",
+			config:     "title: Test\nserviceIdentifier: test\nabstract: test\nlicense: {name: MIT, url: 'https://example.com'}\nogcApi: {}",
+			wantErrMsg: "missing property 'baseUrl'",
+		},
+		{
+			name:       "unknown property",
+			config:     "title: Test\nserviceIdentifier: test\nabstract: test\nlicense: {name: MIT, url: 'https://example.com', foo: bar}\nbaseUrl: https://example.com\nogcApi: {}",
+			wantErrMsg: "additional properties 'foo' not allowed",
+		},
+		{
+			name:       "invalid enum",
+			config:     "title: Test\nserviceIdentifier: test\nabstract: test\nlicense: {name: MIT, url: 'https://example.com'}\nbaseUrl: https://example.com\nogcApi: {features: {basemap: foo, collections: []}}",
+			wantErrMsg: "value must be one of 'OSM', 'BRT'",
+		},
+		{
+			name:       "invalid pattern",
+			config:     "title: Test\nserviceIdentifier: test\nabstract: test\nlicense: {name: MIT, url: 'https://example.com'}\nbaseUrl: ftp://example.com\nogcApi: {}",
+			wantErrMsg: "does not match pattern",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var doc any
+			require.NoError(t, yaml.Unmarshal([]byte(tt.config), &doc))
+			err := schema.Validate(toJSON(t, doc))
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErrMsg)
+		})
+	}
+}
+
+func compileJSONSchema(t *testing.T) *jsonschema.Schema {
+	t.Helper()
+	doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(JSONSchema()))
+	require.NoError(t, err)
+	compiler := jsonschema.NewCompiler()
+	compiler.AssertFormat()
+	require.NoError(t, compiler.AddResource("gokoala.schema.json", doc))
+	schema, err := compiler.Compile("gokoala.schema.json")
+	require.NoError(t, err)
+
+	return schema
+}
+
+func readYAMLAsJSON(t *testing.T, file string) any {
+	t.Helper()
+	yamlData, err := os.ReadFile(file)
+	require.NoError(t, err)
+	var doc any
+	require.NoError(t, yaml.Unmarshal(yamlData, &doc))
+
+	return toJSON(t, doc)
+}
+
+// toJSON converts a YAML document to JSON types, as expected by the JSON Schema validator
+func toJSON(t *testing.T, doc any) any {
+	t.Helper()
+	jsonData, err := json.Marshal(doc)
+	require.NoError(t, err)
+	result, err := jsonschema.UnmarshalJSON(bytes.NewReader(jsonData))
+	require.NoError(t, err)
+
+	return result
+}
@@ -0,0 +1,115 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	redactedSecret = "******"
+
+	envReferencePrefix  = "env:"
+	fileReferencePrefix = "file:"
+)
+
+var secretReferenceRegex = regexp.MustCompile(`\$\{(` + envReferencePrefix + `|` + fileReferencePrefix + `)[^}]+}`)
+
+// Secret sensitive value like a password or key. The value is redacted when printed, e.g.
+// when the config is logged or rendered in a template. Use Value to get the actual secret.
+// The value isn't redacted when (un)marshaling JSON or YAML, so the config can be encoded without loss.
+//
+// Instead of inlining secrets in the config file, reference them using ${env:NAME}
+// for an environment variable or ${file:/path/to/secret} for a file (e.g. a mounted secret).
+type Secret string
+
+// Value returns the actual secret.
+func (s Secret) Value() string {
+	return string(s)
+}
+
+// String returns the redacted secret.
+func (s Secret) String() string {
+	if s == "" {
+		return ""
+	}
+
+	return redactedSecret
+}
+
+// GoString returns the redacted secret, also when printed using the %#v verb.
+func (s Secret) GoString() string {
+	return fmt.Sprintf("config.Secret(%q)", s.String())
+}
+
+// expandEnv expands plain ${NAME} or $NAME references to environment variables in the
+// config file. Secret references like ${env:NAME} and ${file:/path} are left untouched,
+// these are resolved after parsing, see resolveSecretReferences.
+func expandEnv(yamlData []byte) []byte {
+	return []byte(os.Expand(string(yamlData), func(name string) string {
+		if strings.HasPrefix(name, envReferencePrefix) || strings.HasPrefix(name, fileReferencePrefix) {
+			return "${" + name + "}"
+		}
+
+		return os.Getenv(name)
+	}))
+}
+
+// resolveSecretReferences resolves ${env:NAME} and ${file:/path} references in all scalar
+// values of the given YAML node. This happens after parsing (instead of on the raw file like
+// expandEnv) so secrets containing YAML syntax, like '#' or ': ', can't corrupt the config.
+func resolveSecretReferences(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		if !secretReferenceRegex.MatchString(node.Value) {
+			return nil
+		}
+		var errs []error
+		node.Value = secretReferenceRegex.ReplaceAllStringFunc(node.Value, func(reference string) string {
+			resolved, err := resolveSecretReference(reference)
+			if err != nil {
+				errs = append(errs, err)
+			}
+
+			return resolved
+		})
+		if len(errs) > 0 {
+			return fmt.Errorf("line %d: %w", node.Line, errors.Join(errs...))
+		}
+		if node.Style&(yaml.SingleQuotedStyle|yaml.DoubleQuotedStyle) == 0 {
+			// resolve the tag (e.g. !!int) based on the actual value, like environment variables
+			node.Tag = ""
+		}
+
+		return nil
+	}
+	for _, child := range node.Content {
+		if err := resolveSecretReferences(child); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func resolveSecretReference(reference string) (string, error) {
+	name := strings.TrimSuffix(strings.TrimPrefix(reference, "${"), "}")
+	if envVar, ok := strings.CutPrefix(name, envReferencePrefix); ok {
+		value, exists := os.LookupEnv(envVar)
+		if !exists {
+			return "", fmt.Errorf("environment variable %s referenced in config file is not set", envVar)
+		}
+
+		return value, nil
+	}
+	file := strings.TrimPrefix(name, fileReferencePrefix)
+	value, err := os.ReadFile(file)
+	if err != nil {
+		return "", fmt.Errorf("failed to read secret referenced in config file: %w", err)
+	}
+	// files (like docker/k8s secrets) commonly end with a newline, which isn't part of the secret
+	return strings.TrimRight(string(value), "\r\n"), nil
+}
@@ -0,0 +1,147 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"text/template"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestSecret_Redacted(t *testing.T) {
+	pg := Postgres{User: "postgres", Pass: "s3cr3t"}
+
+	assert.Equal(t, "s3cr3t", pg.Pass.Value())
+	assert.Equal(t, "******", pg.Pass.String())
+	assert.NotContains(t, fmt.Sprintf("%v", pg), "s3cr3t")
+	assert.NotContains(t, fmt.Sprintf("%+v", pg), "s3cr3t")
+	assert.NotContains(t, fmt.Sprintf("%#v", pg), "s3cr3t")
+	assert.Contains(t, pg.ConnectionString(), ":s3cr3t@")
+
+	var rendered bytes.Buffer
+	tmpl := template.Must(template.New("test").Parse("{{ .Pass }}"))
+	require.NoError(t, tmpl.Execute(&rendered, pg))
+	assert.Equal(t, "******", rendered.String())
+
+	assert.Empty(t, Secret("").String())
+}
+
+func TestSecret_RoundTrip(t *testing.T) {
+	for _, pass := range []Secret{"${env:PG_PASS}", "${file:/run/secrets/pg}", "s3cr3t"} {
+		pg := Postgres{User: "postgres", Pass: pass}
+
+		pgJSON, err := json.Marshal(pg)
+		require.NoError(t, err)
+		var fromJSON Postgres
+		require.NoError(t, json.Unmarshal(pgJSON, &fromJSON))
+		assert.Equal(t, pass, fromJSON.Pass)
+
+		pgYAML, err := yaml.Marshal(pg)
+		require.NoError(t, err)
+		var fromYAML Postgres
+		require.NoError(t, yaml.Unmarshal(pgYAML, &fromYAML))
+		assert.Equal(t, pass, fromYAML.Pass)
+	}
+}
+
+func TestNewConfig_SecretReferences(t *testing.T) {
+	dir := t.TempDir()
+	secretFile := filepath.Join(dir, "pg")
+	require.NoError(t, os.WriteFile(secretFile, []byte("p@ss: #word\n"), 0o600))
+	t.Setenv("TEST_PG_PORT", "6543")
+	t.Setenv("TEST_TITLE", "My API")
+
+	configFile := writeConfig(t, dir, "config.yaml", `---
+version: 1.0.0
+title: ${TEST_TITLE}
+abstract: ${env:TEST_TITLE} with secrets
+baseUrl: http://localhost:8080
+serviceIdentifier: Secrets
+license:
+  name: MIT
+  url: https://www.tldrlegal.com/license/mit-license
+ogcApi:
+  features:
+    datasources:
+      defaultWGS84:
+        postgres:
+          port: ${env:TEST_PG_PORT}
+          pass: ${file:`+secretFile+`}
+    collections:
+      - id: foo
+`)
+	config, err := NewConfig(configFile)
+	require.NoError(t, err)
+
+	assert.Equal(t, "My API", config.Title)
+	assert.Equal(t, "My API with secrets", config.Abstract)
+	pg := config.OgcAPI.Features.Datasources.DefaultWGS84.Postgres
+	assert.Equal(t, uint(6543), pg.Port)
+	assert.Equal(t, "p@ss: #word", pg.Pass.Value())
+}
+
+func TestResolveSecretReferences(t *testing.T) {
+	tests := []struct {
+		name       string
+		yaml       string
+		want       string
+		wantErrMsg string
+	}{
+		{
+			name: "no references",
+			yaml: "value: $foo ${bar}",
+			want: "value: $foo ${bar}\n",
+		},
+		{
+			name: "env reference",
+			yaml: "value: ${env:TEST_SECRET}",
+			want: "value: s3cr3t\n",
+		},
+		{
+			name: "quoted env reference",
+			yaml: `value: "pre-${env:TEST_SECRET}"`,
+			want: "value: \"pre-s3cr3t\"\n",
+		},
+		{
+			name:       "unset env reference",
+			yaml:       "value: ${env:TEST_SECRET_DOES_NOT_EXIST}",
+			wantErrMsg: "line 1: environment variable TEST_SECRET_DOES_NOT_EXIST referenced in config file is not set",
+		},
+		{
+			name:       "missing file reference",
+			yaml:       "nested:\n  value: ${file:/does/not/exist}",
+			wantErrMsg: "line 2: failed to read secret referenced in config file",
+		},
+	}
+	t.Setenv("TEST_SECRET", "s3cr3t")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var node yaml.Node
+			require.NoError(t, yaml.Unmarshal([]byte(tt.yaml), &node))
+
+			err := resolveSecretReferences(&node)
+			if tt.wantErrMsg != "" {
+				require.ErrorContains(t, err, tt.wantErrMsg)
+				return
+			}
+			require.NoError(t, err)
+			result, err := yaml.Marshal(&node)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, string(result))
+		})
+	}
+}
+
+func writeConfig(t *testing.T, dir string, name string, content string) string {
+	t.Helper()
+	configFile := filepath.Join(dir, name)
+	require.NoError(t, os.WriteFile(configFile, []byte(content), 0o600))
+
+	return configFile
+}
@@ -0,0 +1,216 @@
+package config
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+
+	"golang.org/x/text/language"
+)
+
+// +kubebuilder:object:generate=true
+type Translation struct {
+	// Human-friendly title of the API in this language
+	// +optional
+	Title *string `yaml:"title,omitempty" json:"title,omitempty"`
+
+	// Human-friendly description of the API and dataset in this language
+	// +optional
+	Abstract *string `yaml:"abstract,omitempty" json:"abstract,omitempty"`
+
+	// Keywords in this language
+	// +optional
+	Keywords []string `yaml:"keywords,omitempty" json:"keywords,omitempty"`
+
+	// Key/value pairs to add extra information to the landing page, in this language
+	// +optional
+	DatasetDetails []DatasetDetail `yaml:"datasetDetails,omitempty" json:"datasetDetails,omitempty"`
+}
+
+// +kubebuilder:object:generate=true
+type MetadataTranslation struct {
+	// Human-friendly title in this language
+	// +optional
+	Title *string `yaml:"title,omitempty" json:"title,omitempty"`
+
+	// Description in this language
+	// +optional
+	Description *string `yaml:"description,omitempty" json:"description,omitempty"`
+
+	// Keywords in this language
+	// +optional
+	Keywords []string `yaml:"keywords,omitempty" json:"keywords,omitempty"`
+}
+
+// DefaultLanguage the language of the (untranslated) titles, descriptions, etc. in this config,
+// which is the first of the AvailableLanguages.
+func (c *Config) DefaultLanguage() language.Tag {
+	if len(c.AvailableLanguages) == 0 {
+		return language.Dutch
+	}
+
+	return c.AvailableLanguages[0].Tag
+}
+
+// HasTranslations whether the title, abstract, etc. of the API or the metadata of
+// collections/styles is translated to the given language.
+func (c *Config) HasTranslations(lang language.Tag) bool {
+	key := lang.String()
+	if _, ok := c.Translations[key]; ok {
+		return true
+	}
+	for _, metadata := range c.allCollectionMetadata() {
+		if _, ok := metadata.Translations[key]; ok {
+			return true
+		}
+	}
+	if c.OgcAPI.Styles != nil {
+		for _, style := range c.OgcAPI.Styles.SupportedStyles {
+			if _, ok := style.Translations[key]; ok {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// Localize returns this config with the title, abstract, etc. of the API and the metadata of
+// collections/styles in the given language. Values without translation keep their value in the
+// default language. Returns the config itself (no copy) when nothing is translated to the given language.
+func (c *Config) Localize(lang language.Tag) *Config {
+	if !c.HasTranslations(lang) {
+		return c
+	}
+	result := c.DeepCopy()
+	if translation, ok := result.Translations[lang.String()]; ok {
+		if translation.Title != nil {
+			result.Title = *translation.Title
+		}
+		if translation.Abstract != nil {
+			result.Abstract = *translation.Abstract
+		}
+		if translation.Keywords != nil {
+			result.Keywords = translation.Keywords
+		}
+		if translation.DatasetDetails != nil {
+			result.DatasetDetails = translation.DatasetDetails
+		}
+	}
+	for _, metadata := range result.allCollectionMetadata() {
+		*metadata = *metadata.Localize(lang)
+	}
+	if result.OgcAPI.Styles != nil {
+		for i, style := range result.OgcAPI.Styles.SupportedStyles {
+			result.OgcAPI.Styles.SupportedStyles[i] = style.Localize(lang)
+		}
+	}
+
+	return result
+}
+
+// Localize returns this metadata with the title, description and keywords in the given language.
+// Values without translation keep their value in the default language.
+func (m *GeoSpatialCollectionMetadata) Localize(lang language.Tag) *GeoSpatialCollectionMetadata {
+	if m == nil {
+		return nil
+	}
+	translation, ok := m.Translations[lang.String()]
+	if !ok {
+		return m
+	}
+	result := *m
+	if translation.Title != nil {
+		result.Title = translation.Title
+	}
+	if translation.Description != nil {
+		result.Description = translation.Description
+	}
+	if translation.Keywords != nil {
+		result.Keywords = translation.Keywords
+	}
+
+	return &result
+}
+
+// Localize returns this style with the title, description and keywords in the given language.
+// Values without translation keep their value in the default language.
+// Value instead of pointer receiver because only that way it can be used for both.
+func (s Style) Localize(lang language.Tag) Style {
+	translation, ok := s.Translations[lang.String()]
+	if !ok {
+		return s
+	}
+	if translation.Title != nil {
+		s.Title = *translation.Title
+	}
+	if translation.Description != nil {
+		s.Description = translation.Description
+	}
+	if translation.Keywords != nil {
+		s.Keywords = translation.Keywords
+	}
+
+	return s
+}
+
+// allCollectionMetadata metadata of all collections (of all OGC APIs), in order of configuration.
+func (c *Config) allCollectionMetadata() []*GeoSpatialCollectionMetadata {
+	var result []*GeoSpatialCollectionMetadata
+	add := func(metadata *GeoSpatialCollectionMetadata) {
+		if metadata != nil {
+			result = append(result, metadata)
+		}
+	}
+	if c.OgcAPI.GeoVolumes != nil {
+		for _, coll := range c.OgcAPI.GeoVolumes.Collections {
+			add(coll.Metadata)
+		}
+	}
+	if c.OgcAPI.Tiles != nil {
+		for _, coll := range c.OgcAPI.Tiles.Collections {
+			add(coll.Metadata)
+		}
+	}
+	if c.OgcAPI.Features != nil {
+		for _, coll := range c.OgcAPI.Features.Collections {
+			add(coll.Metadata)
+		}
+	}
+	if c.OgcAPI.FeaturesSearch != nil {
+		for _, coll := range c.OgcAPI.FeaturesSearch.Collections {
+			add(coll.Metadata)
+		}
+	}
+
+	return result
+}
+
+// validateTranslations validates that translations are only provided for available languages.
+func validateTranslations(config *Config) error {
+	var errMessages []string
+	validateLanguages := func(subject string, languages []string) {
+		for _, lang := range languages {
+			if !slices.ContainsFunc(config.AvailableLanguages, func(l Language) bool { return l.String() == lang }) {
+				errMessages = append(errMessages, fmt.Sprintf("validation failed for translations of %s; "+
+					"language '%s' isn't one of the availableLanguages\n", subject, lang))
+			}
+		}
+	}
+	validateLanguages("the API", slices.Sorted(maps.Keys(config.Translations)))
+	for _, coll := range config.AllCollections() {
+		if coll.GetMetadata() != nil {
+			validateLanguages("collection '"+coll.GetID()+"'", slices.Sorted(maps.Keys(coll.GetMetadata().Translations)))
+		}
+	}
+	if config.OgcAPI.Styles != nil {
+		for _, style := range config.OgcAPI.Styles.SupportedStyles {
+			validateLanguages("style '"+style.ID+"'", slices.Sorted(maps.Keys(style.Translations)))
+		}
+	}
+	if len(errMessages) > 0 {
+		return fmt.Errorf("invalid config provided:\n%v", errMessages)
+	}
+
+	return nil
+}
@@ -0,0 +1,52 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/text/language"
+)
+
+func TestConfig_Localize(t *testing.T) {
+	config, err := NewConfig("internal/engine/testdata/config_translations.yaml")
+	require.NoError(t, err)
+	assert.Equal(t, language.Dutch, config.DefaultLanguage())
+	assert.True(t, config.HasTranslations(language.English))
+	assert.False(t, config.HasTranslations(language.Dutch))
+
+	// nothing translated to dutch (the default language)
+	assert.Same(t, config, config.Localize(language.Dutch))
+
+	localized := config.Localize(language.English)
+	assert.Equal(t, "Example", localized.Title)
+	assert.Equal(t, "This is an example API", localized.Abstract)
+	assert.Equal(t, []string{"example"}, localized.Keywords)
+
+	metadata := localized.AllCollections()[0].GetMetadata()
+	assert.Equal(t, "Buildings", *metadata.Title)
+	assert.Equal(t, "Gebouwen in 3D", *metadata.Description, "untranslated value should fall back to default language")
+
+	// original config should be left untouched
+	assert.Equal(t, "Voorbeeld", config.Title)
+	assert.Equal(t, "Gebouwen", *config.AllCollections()[0].GetMetadata().Title)
+}
+
+func TestStyle_Localize(t *testing.T) {
+	description := "Standaard stijl"
+	title := "Default style"
+	style := Style{
+		ID:          "default",
+		Title:       "Standaard",
+		Description: &description,
+		Translations: map[string]MetadataTranslation{
+			"en": {Title: &title},
+		},
+	}
+
+	localized := style.Localize(language.English)
+	assert.Equal(t, "Default style", localized.Title)
+	assert.Equal(t, "Standaard stijl", *localized.Description)
+	assert.Equal(t, "Standaard", style.Localize(language.Dutch).Title)
+	assert.Equal(t, "Standaard", style.Title)
+}
@@ -135,6 +135,18 @@ func (in *Config) DeepCopyInto(out *Config) {
 		*out = new(Resources)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.Translations != nil {
+		in, out := &in.Translations, &out.Translations
+		*out = make(map[string]Translation, len(*in))
+		for key, val := range *in {
+			(*out)[key] = *val.DeepCopy()
+		}
+	}
+	if in.OpenAPI != nil {
+		in, out := &in.OpenAPI, &out.OpenAPI
+		*out = new(OpenAPI)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Config.
@@ -147,6 +159,31 @@ func (in *Config) DeepCopy() *Config {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CustomTileMatrixSets) DeepCopyInto(out *CustomTileMatrixSets) {
+	*out = *in
+	if in.Dir != nil {
+		in, out := &in.Dir, &out.Dir
+		*out = new(string)
+		**out = **in
+	}
+	if in.Files != nil {
+		in, out := &in.Files, &out.Files
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CustomTileMatrixSets.
+func (in *CustomTileMatrixSets) DeepCopy() *CustomTileMatrixSets {
+	if in == nil {
+		return nil
+	}
+	out := new(CustomTileMatrixSets)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *DatasetDetail) DeepCopyInto(out *DatasetDetail) {
 	*out = *in
@@ -614,6 +651,13 @@ func (in *GeoSpatialCollectionMetadata) DeepCopyInto(out *GeoSpatialCollectionMe
 		*out = new(string)
 		**out = **in
 	}
+	if in.Translations != nil {
+		in, out := &in.Translations, &out.Translations
+		*out = make(map[string]MetadataTranslation, len(*in))
+		for key, val := range *in {
+			(*out)[key] = *val.DeepCopy()
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GeoSpatialCollectionMetadata.
@@ -644,10 +688,20 @@ func (in *GeoVolumesCollection) DeepCopyInto(out *GeoVolumesCollection) {
 		*out = new(string)
 		**out = **in
 	}
+	if in.LocalPath != nil {
+		in, out := &in.LocalPath, &out.LocalPath
+		*out = new(string)
+		**out = **in
+	}
 	if in.URL3DViewer != nil {
 		in, out := &in.URL3DViewer, &out.URL3DViewer
 		*out = (*in).DeepCopy()
 	}
+	if in.Children != nil {
+		in, out := &in.Children, &out.Children
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GeoVolumesCollection.
@@ -780,6 +834,36 @@ func (in *MetadataLink) DeepCopy() *MetadataLink {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MetadataTranslation) DeepCopyInto(out *MetadataTranslation) {
+	*out = *in
+	if in.Title != nil {
+		in, out := &in.Title, &out.Title
+		*out = new(string)
+		**out = **in
+	}
+	if in.Description != nil {
+		in, out := &in.Description, &out.Description
+		*out = new(string)
+		**out = **in
+	}
+	if in.Keywords != nil {
+		in, out := &in.Keywords, &out.Keywords
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MetadataTranslation.
+func (in *MetadataTranslation) DeepCopy() *MetadataTranslation {
+	if in == nil {
+		return nil
+	}
+	out := new(MetadataTranslation)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *OgcAPI) DeepCopyInto(out *OgcAPI) {
 	*out = *in
@@ -923,6 +1007,7 @@ func (in *OgcAPIFeaturesSearch) DeepCopy() *OgcAPIFeaturesSearch {
 func (in *OgcAPIProcesses) DeepCopyInto(out *OgcAPIProcesses) {
 	*out = *in
 	in.ProcessesServer.DeepCopyInto(&out.ProcessesServer)
+	in.Jobs.DeepCopyInto(&out.Jobs)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OgcAPIProcesses.
@@ -945,6 +1030,11 @@ func (in *OgcAPIStyles) DeepCopyInto(out *OgcAPIStyles) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.Manage != nil {
+		in, out := &in.Manage, &out.Manage
+		*out = new(StylesManagement)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OgcAPIStyles.
@@ -972,6 +1062,16 @@ func (in *OgcAPITiles) DeepCopyInto(out *OgcAPITiles) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.CustomTileMatrixSets != nil {
+		in, out := &in.CustomTileMatrixSets, &out.CustomTileMatrixSets
+		*out = new(CustomTileMatrixSets)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Cache != nil {
+		in, out := &in.Cache, &out.Cache
+		*out = new(TilesCache)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OgcAPITiles.
@@ -1020,6 +1120,21 @@ func (in *OnTheFlySupportedSrs) DeepCopy() *OnTheFlySupportedSrs {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OpenAPI) DeepCopyInto(out *OpenAPI) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OpenAPI.
+func (in *OpenAPI) DeepCopy() *OpenAPI {
+	if in == nil {
+		return nil
+	}
+	out := new(OpenAPI)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Postgres) DeepCopyInto(out *Postgres) {
 	*out = *in
@@ -1041,6 +1156,23 @@ func (in *Postgres) DeepCopy() *Postgres {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProcessesJobs) DeepCopyInto(out *ProcessesJobs) {
+	*out = *in
+	in.Retention.DeepCopyInto(&out.Retention)
+	in.Timeout.DeepCopyInto(&out.Timeout)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProcessesJobs.
+func (in *ProcessesJobs) DeepCopy() *ProcessesJobs {
+	if in == nil {
+		return nil
+	}
+	out := new(ProcessesJobs)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Queryable) DeepCopyInto(out *Queryable) {
 	*out = *in
@@ -1181,6 +1313,13 @@ func (in *Style) DeepCopyInto(out *Style) {
 		*out = make([]StyleFormat, len(*in))
 		copy(*out, *in)
 	}
+	if in.Translations != nil {
+		in, out := &in.Translations, &out.Translations
+		*out = make(map[string]MetadataTranslation, len(*in))
+		for key, val := range *in {
+			(*out)[key] = *val.DeepCopy()
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Style.
@@ -1208,6 +1347,21 @@ func (in *StyleFormat) DeepCopy() *StyleFormat {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StylesManagement) DeepCopyInto(out *StylesManagement) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StylesManagement.
+func (in *StylesManagement) DeepCopy() *StylesManagement {
+	if in == nil {
+		return nil
+	}
+	out := new(StylesManagement)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Support) DeepCopyInto(out *Support) {
 	*out = *in
@@ -1259,6 +1413,13 @@ func (in *TemporalProperties) DeepCopy() *TemporalProperties {
 func (in *Tiles) DeepCopyInto(out *Tiles) {
 	*out = *in
 	in.TileServer.DeepCopyInto(&out.TileServer)
+	if in.Archives != nil {
+		in, out := &in.Archives, &out.Archives
+		*out = make([]TilesArchive, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	if in.Types != nil {
 		in, out := &in.Types, &out.Types
 		*out = make([]TilesType, len(*in))
@@ -1274,7 +1435,24 @@ func (in *Tiles) DeepCopyInto(out *Tiles) {
 		*out = new(string)
 		**out = **in
 	}
+	if in.RasterFormats != nil {
+		in, out := &in.RasterFormats, &out.RasterFormats
+		*out = make([]RasterTilesFormat, len(*in))
+		copy(*out, *in)
+	}
+	if in.URITemplateRasterTiles != nil {
+		in, out := &in.URITemplateRasterTiles, &out.URITemplateRasterTiles
+		*out = make(map[RasterTilesFormat]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
 	in.HealthCheck.DeepCopyInto(&out.HealthCheck)
+	if in.VectorLayers != nil {
+		in, out := &in.VectorLayers, &out.VectorLayers
+		*out = new(TilesVectorLayers)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Tiles.
@@ -1287,6 +1465,73 @@ func (in *Tiles) DeepCopy() *Tiles {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TilesArchive) DeepCopyInto(out *TilesArchive) {
+	*out = *in
+	if in.Download != nil {
+		in, out := &in.Download, &out.Download
+		*out = new(GeoPackageDownload)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TilesArchive.
+func (in *TilesArchive) DeepCopy() *TilesArchive {
+	if in == nil {
+		return nil
+	}
+	out := new(TilesArchive)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TilesCache) DeepCopyInto(out *TilesCache) {
+	*out = *in
+	out.DefaultMaxAge = in.DefaultMaxAge
+	if in.Disk != nil {
+		in, out := &in.Disk, &out.Disk
+		*out = new(TilesDiskCache)
+		**out = **in
+	}
+	if in.Seed != nil {
+		in, out := &in.Seed, &out.Seed
+		*out = new(TilesCacheSeed)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TilesCache.
+func (in *TilesCache) DeepCopy() *TilesCache {
+	if in == nil {
+		return nil
+	}
+	out := new(TilesCache)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TilesCacheSeed) DeepCopyInto(out *TilesCacheSeed) {
+	*out = *in
+	out.ZoomLevelRange = in.ZoomLevelRange
+	if in.Srs != nil {
+		in, out := &in.Srs, &out.Srs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TilesCacheSeed.
+func (in *TilesCacheSeed) DeepCopy() *TilesCacheSeed {
+	if in == nil {
+		return nil
+	}
+	out := new(TilesCacheSeed)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *TilesCollection) DeepCopyInto(out *TilesCollection) {
 	*out = *in
@@ -1313,6 +1558,76 @@ func (in *TilesCollection) DeepCopy() *TilesCollection {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TilesDiskCache) DeepCopyInto(out *TilesDiskCache) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TilesDiskCache.
+func (in *TilesDiskCache) DeepCopy() *TilesDiskCache {
+	if in == nil {
+		return nil
+	}
+	out := new(TilesDiskCache)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TilesVectorLayers) DeepCopyInto(out *TilesVectorLayers) {
+	*out = *in
+	if in.URITemplateTileJSON != nil {
+		in, out := &in.URITemplateTileJSON, &out.URITemplateTileJSON
+		*out = new(string)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TilesVectorLayers.
+func (in *TilesVectorLayers) DeepCopy() *TilesVectorLayers {
+	if in == nil {
+		return nil
+	}
+	out := new(TilesVectorLayers)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Translation) DeepCopyInto(out *Translation) {
+	*out = *in
+	if in.Title != nil {
+		in, out := &in.Title, &out.Title
+		*out = new(string)
+		**out = **in
+	}
+	if in.Abstract != nil {
+		in, out := &in.Abstract, &out.Abstract
+		*out = new(string)
+		**out = **in
+	}
+	if in.Keywords != nil {
+		in, out := &in.Keywords, &out.Keywords
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.DatasetDetails != nil {
+		in, out := &in.DatasetDetails, &out.DatasetDetails
+		*out = make([]DatasetDetail, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Translation.
+func (in *Translation) DeepCopy() *Translation {
+	if in == nil {
+		return nil
+	}
+	out := new(Translation)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *WebConfig) DeepCopyInto(out *WebConfig) {
 	*out = *in
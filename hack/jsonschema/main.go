@@ -0,0 +1,485 @@
+// Command jsonschema generates a JSON Schema of the GoKoala YAML config file, so editors/IDEs and
+// CI pipelines can validate config files offline. The schema is derived from the config.Config type
+// tree (using the YAML tags), the doc comments of the config structs and their Kubebuilder markers
+// (defaults, enums, patterns, etc).
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/PDOK/gokoala/config"
+)
+
+const (
+	jsonSchemaDraft = "https://json-schema.org/draft/2020-12/schema"
+	markerPrefix    = "+kubebuilder:"
+
+	// Kubebuilder's duration format is a Go duration (e.g. 1h30m), while in JSON Schema
+	// this format means an ISO 8601 duration (e.g. PT1H30M). So use a pattern instead.
+	goDurationFormat  = "duration"
+	goDurationPattern = `^(0|(\d+(\.\d+)?(ns|us|µs|ms|s|m|h))+)$`
+)
+
+func main() {
+	configDir := flag.String("config-dir", "config", "directory containing the source code of the config package")
+	output := flag.String("output", "config/gokoala.schema.json", "file to write the JSON Schema to")
+	flag.Parse()
+
+	schema, err := generate(*configDir)
+	if err != nil {
+		log.Fatalf("failed to generate JSON Schema: %v", err)
+	}
+	if err = os.WriteFile(*output, schema, 0o600); err != nil {
+		log.Fatalf("failed to write JSON Schema: %v", err)
+	}
+}
+
+// generate returns the JSON Schema of config.Config, using the doc comments and markers
+// found in the source code of the config package in the given directory.
+func generate(configDir string) ([]byte, error) {
+	docs, err := parseDocs(configDir)
+	if err != nil {
+		return nil, err
+	}
+	g := &generator{
+		docs:    docs,
+		pkgPath: reflect.TypeFor[config.Config]().PkgPath(),
+		defs:    make(map[string]map[string]any),
+	}
+	root, err := g.schemaFor(reflect.TypeFor[config.Config]())
+	if err != nil {
+		return nil, err
+	}
+	root["$schema"] = jsonSchemaDraft
+	root["title"] = config.AppName + " config"
+	root["description"] = "Configuration file of " + config.AppName + ", a Cloud Native OGC APIs server"
+	root["$defs"] = g.defs
+
+	// allow top-level keys which only serve as YAML anchors, e.g. to share metadata between collections
+	delete(g.defs["Config"], "additionalProperties")
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err = encoder.Encode(root); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// doc comment of a type or field, split in a description and Kubebuilder markers.
+type doc struct {
+	description string
+	markers     map[string]string
+	optional    bool
+}
+
+// typeDocs docs of a type and its fields (by Go field name).
+type typeDocs struct {
+	doc
+	fields map[string]doc
+}
+
+// parseDocs reads the doc comments of all types and struct fields in the given Go package directory.
+func parseDocs(dir string) (map[string]typeDocs, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]typeDocs)
+	fset := token.NewFileSet()
+	for _, file := range files {
+		if strings.HasSuffix(file, "_test.go") || strings.HasPrefix(filepath.Base(file), "zz_generated") {
+			continue
+		}
+		f, err := parser.ParseFile(fset, file, nil, parser.ParseComments)
+		if err != nil {
+			return nil, err
+		}
+		for _, decl := range f.Decls {
+			genDecl, ok := decl.(*ast.GenDecl)
+			if !ok || genDecl.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range genDecl.Specs {
+				typeSpec := spec.(*ast.TypeSpec)
+				commentGroup := typeSpec.Doc
+				if commentGroup == nil && len(genDecl.Specs) == 1 {
+					commentGroup = genDecl.Doc
+				}
+				td := typeDocs{doc: parseDoc(commentGroup), fields: make(map[string]doc)}
+				if structType, ok := typeSpec.Type.(*ast.StructType); ok {
+					for _, field := range structType.Fields.List {
+						for _, name := range fieldNames(field) {
+							td.fields[name] = parseDoc(field.Doc)
+						}
+					}
+				}
+				result[typeSpec.Name.Name] = td
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// fieldNames returns the names of the given struct field, for embedded fields this is the type name.
+func fieldNames(field *ast.Field) []string {
+	if len(field.Names) > 0 {
+		names := make([]string, 0, len(field.Names))
+		for _, name := range field.Names {
+			names = append(names, name.Name)
+		}
+		return names
+	}
+	expr := field.Type
+	if star, ok := expr.(*ast.StarExpr); ok {
+		expr = star.X
+	}
+	switch t := expr.(type) {
+	case *ast.Ident:
+		return []string{t.Name}
+	case *ast.SelectorExpr:
+		return []string{t.Sel.Name}
+	}
+
+	return nil
+}
+
+func parseDoc(commentGroup *ast.CommentGroup) doc {
+	result := doc{markers: make(map[string]string)}
+	if commentGroup == nil {
+		return result
+	}
+	var paragraphs []string
+	var lines []string
+	for _, comment := range commentGroup.List {
+		line := strings.TrimSpace(strings.TrimPrefix(comment.Text, "//"))
+		switch {
+		case strings.HasPrefix(line, markerPrefix):
+			key, value, _ := strings.Cut(strings.TrimPrefix(line, markerPrefix), "=")
+			result.markers[key] = unquoteMarkerValue(value)
+		case line == "+optional":
+			result.optional = true
+		case strings.HasPrefix(line, "+"), strings.HasPrefix(line, "nolint"):
+			// other markers or linter directives, not part of the description
+		case line == "":
+			if len(lines) > 0 {
+				paragraphs = append(paragraphs, strings.Join(lines, " "))
+				lines = nil
+			}
+		default:
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) > 0 {
+		paragraphs = append(paragraphs, strings.Join(lines, " "))
+	}
+	result.description = strings.Join(paragraphs, "\n")
+
+	return result
+}
+
+func unquoteMarkerValue(value string) string {
+	if len(value) >= 2 && strings.HasPrefix(value, "`") && strings.HasSuffix(value, "`") {
+		return value[1 : len(value)-1]
+	}
+	if unquoted, err := strconv.Unquote(value); err == nil {
+		return unquoted
+	}
+
+	return value
+}
+
+type generator struct {
+	docs    map[string]typeDocs
+	pkgPath string
+
+	// definitions of all (struct) types in the config package, by type name
+	defs map[string]map[string]any
+}
+
+// schemaFor returns the schema for the given type. Structs in the config package are added
+// to the definitions and referenced.
+func (g *generator) schemaFor(t reflect.Type) (map[string]any, error) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	var typeDoc typeDocs
+	if t.PkgPath() == g.pkgPath {
+		typeDoc = g.docs[t.Name()]
+	}
+
+	// types with custom (un)marshalling, like URL and Duration, are represented as a scalar
+	if scalarType, ok := typeDoc.markers["validation:Type"]; ok {
+		schema := map[string]any{"type": scalarType}
+		if err := applyMarkers(schema, typeDoc.doc); err != nil {
+			return nil, fmt.Errorf("type %s: %w", t.Name(), err)
+		}
+		return schema, nil
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		if t.PkgPath() != g.pkgPath {
+			return nil, fmt.Errorf("unsupported struct type %s", t)
+		}
+		if _, ok := g.defs[t.Name()]; !ok {
+			g.defs[t.Name()] = nil // placeholder, to support recursive types
+			def, err := g.objectSchema(t)
+			if err != nil {
+				return nil, err
+			}
+			if typeDoc.description != "" {
+				def["description"] = typeDoc.description
+			}
+			g.defs[t.Name()] = def
+		}
+		return map[string]any{"$ref": "#/$defs/" + t.Name()}, nil
+	case reflect.Slice, reflect.Array:
+		items, err := g.schemaFor(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return map[string]any{"type": "array", "items": items}, nil
+	case reflect.Map:
+		values, err := g.schemaFor(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		schema := map[string]any{"type": "object", "additionalProperties": values}
+		if keys, err := g.schemaFor(t.Key()); err == nil && keys["enum"] != nil {
+			schema["propertyNames"] = keys
+		}
+		return schema, nil
+	case reflect.String:
+		schema := map[string]any{"type": "string"}
+		if err := applyMarkers(schema, typeDoc.doc); err != nil {
+			return nil, fmt.Errorf("type %s: %w", t.Name(), err)
+		}
+		return schema, nil
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return map[string]any{"type": "integer"}, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer", "minimum": 0}, nil
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}, nil
+	default:
+		return nil, fmt.Errorf("unsupported type %s", t)
+	}
+}
+
+// objectSchema returns the schema of a struct, properties are named after the YAML tags.
+func (g *generator) objectSchema(t reflect.Type) (map[string]any, error) {
+	properties := make(map[string]any)
+	var required []string
+	if err := g.addProperties(t, properties, &required, true); err != nil {
+		return nil, err
+	}
+	schema := map[string]any{
+		"type":                 "object",
+		"properties":           properties,
+		"additionalProperties": false,
+	}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+
+	return schema, nil
+}
+
+func (g *generator) addProperties(t reflect.Type, properties map[string]any, required *[]string, propagateRequired bool) error {
+	fieldDocs := g.docs[t.Name()].fields
+	for i := range t.NumField() {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, opts, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		if name == "-" {
+			continue
+		}
+		if opts == "inline" || strings.Contains(opts, ",inline") {
+			// properties of an inlined pointer are optional as a whole
+			inlineType := field.Type
+			isPointer := inlineType.Kind() == reflect.Pointer
+			if isPointer {
+				inlineType = inlineType.Elem()
+			}
+			if err := g.addProperties(inlineType, properties, required, propagateRequired && !isPointer); err != nil {
+				return err
+			}
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+
+		fieldDoc := fieldDocs[field.Name]
+		schema, err := g.schemaFor(field.Type)
+		if err != nil {
+			return fmt.Errorf("field %s.%s: %w", t.Name(), field.Name, err)
+		}
+		if fieldDoc.description != "" {
+			schema["description"] = fieldDoc.description
+		}
+		if err = applyMarkers(schema, fieldDoc); err != nil {
+			return fmt.Errorf("field %s.%s: %w", t.Name(), field.Name, err)
+		}
+		if err = applyTags(schema, field.Tag); err != nil {
+			return fmt.Errorf("field %s.%s: %w", t.Name(), field.Name, err)
+		}
+		allowNumbers(schema)
+		if items, ok := schema["items"].(map[string]any); ok {
+			allowNumbers(items)
+		}
+		properties[name] = schema
+
+		if propagateRequired && isRequired(field, fieldDoc, schema) {
+			*required = append(*required, name)
+		}
+	}
+
+	return nil
+}
+
+// isRequired true when the field is required in the config file, thus when the field is validated
+// as required and there's no default value to fall back on.
+func isRequired(field reflect.StructField, fieldDoc doc, schema map[string]any) bool {
+	if fieldDoc.optional || fieldDoc.markers["default"] != "" {
+		return false
+	}
+	if _, isObject := schema["$ref"]; isObject && field.Type.Kind() == reflect.Struct {
+		// the validator ignores 'required' on (non-pointer) structs
+		return false
+	}
+	tag := field.Tag
+	if _, hasDefault := tag.Lookup("default"); hasDefault {
+		return false
+	}
+
+	return slices.Contains(strings.Split(tag.Get("validate"), ","), "required")
+}
+
+// applyMarkers adds Kubebuilder validation markers and defaults to the schema.
+func applyMarkers(schema map[string]any, d doc) error {
+	target := schema
+	if items, ok := schema["items"].(map[string]any); ok && schema["type"] == "array" {
+		// Kubebuilder applies markers like pattern and enum to the items of an array
+		target = items
+	}
+	for key, value := range d.markers {
+		var err error
+		switch key {
+		case "validation:Pattern":
+			target["pattern"] = value
+		case "validation:Format":
+			if value == goDurationFormat {
+				target["pattern"] = goDurationPattern
+			} else {
+				target["format"] = value
+			}
+		case "validation:Enum":
+			err = setEnum(target, strings.Split(value, ";"))
+		case "validation:Minimum":
+			schema["minimum"], err = strconv.ParseFloat(value, 64)
+		case "validation:Maximum":
+			schema["maximum"], err = strconv.ParseFloat(value, 64)
+		case "validation:MinItems":
+			schema["minItems"], err = strconv.Atoi(value)
+		case "validation:MaxItems":
+			schema["maxItems"], err = strconv.Atoi(value)
+		case "default":
+			schema["default"], err = scalarValue(schema, value)
+		}
+		if err != nil {
+			return fmt.Errorf("invalid marker %s=%s: %w", key, value, err)
+		}
+	}
+
+	return nil
+}
+
+// applyTags adds defaults and enums based on the 'default' and 'validate' struct tags,
+// for fields lacking the equivalent Kubebuilder markers.
+func applyTags(schema map[string]any, tag reflect.StructTag) error {
+	if value, ok := tag.Lookup("default"); ok && schema["default"] == nil && isScalar(schema) {
+		defaultValue, err := scalarValue(schema, value)
+		if err != nil {
+			return fmt.Errorf("invalid default %s: %w", value, err)
+		}
+		schema["default"] = defaultValue
+	}
+	for _, validation := range strings.Split(tag.Get("validate"), ",") {
+		if values, ok := strings.CutPrefix(validation, "oneof="); ok && schema["enum"] == nil && isScalar(schema) {
+			if err := setEnum(schema, strings.Fields(values)); err != nil {
+				return fmt.Errorf("invalid enum %s: %w", values, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// allowNumbers accepts numbers for plain string fields, since YAML numbers are converted to strings
+// when unmarshalling the config (e.g. coordinates in a bbox).
+func allowNumbers(schema map[string]any) {
+	if schema["type"] != "string" || schema["pattern"] != nil || schema["enum"] != nil || schema["format"] != nil {
+		return
+	}
+	schema["type"] = []string{"string", "number"}
+}
+
+func setEnum(schema map[string]any, values []string) error {
+	enum := make([]any, 0, len(values))
+	for _, value := range values {
+		v, err := scalarValue(schema, value)
+		if err != nil {
+			return err
+		}
+		enum = append(enum, v)
+	}
+	schema["enum"] = enum
+
+	return nil
+}
+
+func isScalar(schema map[string]any) bool {
+	switch schema["type"] {
+	case "string", "boolean", "integer", "number":
+		return true
+	}
+
+	return false
+}
+
+// scalarValue converts the given value (from a marker or tag) to the type of the schema.
+func scalarValue(schema map[string]any, value string) (any, error) {
+	switch schema["type"] {
+	case "boolean":
+		return strconv.ParseBool(value)
+	case "integer":
+		return strconv.ParseInt(value, 10, 64)
+	case "number":
+		return strconv.ParseFloat(value, 64)
+	default:
+		return value, nil
+	}
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerate_UpToDate(t *testing.T) {
+	expected, err := os.ReadFile("../../config/gokoala.schema.json")
+	require.NoError(t, err)
+
+	actual, err := generate("../../config")
+	require.NoError(t, err)
+
+	assert.Equal(t, string(expected), string(actual),
+		"JSON Schema of config is outdated, run 'go generate ./config/...' to regenerate it")
+}
+
+func TestParseDoc(t *testing.T) {
+	docs, err := parseDocs("../../config")
+	require.NoError(t, err)
+
+	field := docs["Postgres"].fields["SSLMode"]
+	assert.Equal(t, "The SSL mode to use, e.g. 'disable', 'allow', 'prefer', 'require', 'verify-ca' or 'verify-full'.", field.description)
+	assert.Equal(t, "disable", field.markers["default"])
+	assert.Equal(t, "disable;allow;prefer;require;verify-ca;verify-full", field.markers["validation:Enum"])
+	assert.True(t, field.optional)
+
+	assert.Equal(t, `^(https?://.+)|(\$\{.+\}.*)`, docs["URL"].markers["validation:Pattern"])
+	assert.Equal(t, "string", docs["URL"].markers["validation:Type"])
+}
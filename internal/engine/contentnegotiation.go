@@ -3,6 +3,9 @@ package engine
 import (
 	"log"
 	"net/http"
+	"slices"
+	"strconv"
+	"strings"
 
 	"github.com/PDOK/gokoala/config"
 	"github.com/PDOK/gokoala/internal/engine/util"
@@ -22,10 +25,14 @@ const (
 	MediaTypeMapboxStyle   = "application/vnd.mapbox.style+json"
 	MediaTypeSLD           = "application/vnd.ogc.sld+xml;version=1.0"
 	MediaTypeOpenAPI       = "application/vnd.oai.openapi+json;version=3.0"
+	MediaTypeOpenAPI31     = "application/vnd.oai.openapi+json;version=3.1"
 	MediaTypeGeoJSON       = "application/geo+json"
 	MediaTypeJSONFG        = "application/vnd.ogc.fg+json" // https://docs.ogc.org/per/21-017r1.html#toc17
 	MediaTypeJSONSchema    = "application/schema+json"
 	MediaTypeQuantizedMesh = "application/vnd.quantized-mesh"
+	MediaTypePNG           = "image/png"
+	MediaTypeJPEG          = "image/jpeg"
+	MediaTypeWebP          = "image/webp"
 
 	FormatHTML           = "html"
 	FormatXML            = "xml"
@@ -37,7 +44,11 @@ const (
 	FormatSLD            = "sld10"
 	FormatGeoJSON        = "geojson" // ?=json should also work for geojson
 	FormatJSONFG         = "jsonfg"
+	FormatOpenAPI31      = "openapi31"
 	FormatGzip           = "gzip"
+	FormatPNG            = "png"
+	FormatJPEG           = "jpeg"
+	FormatWebP           = "webp"
 )
 
 var (
@@ -104,6 +115,10 @@ func newContentNegotiation(availableLanguages []config.Language) *ContentNegotia
 		contenttype.NewMediaType(MediaTypeMapboxStyle),
 		contenttype.NewMediaType(MediaTypeSLD),
 		contenttype.NewMediaType(MediaTypeOpenAPI),
+		contenttype.NewMediaType(MediaTypeOpenAPI31),
+		contenttype.NewMediaType(MediaTypePNG),
+		contenttype.NewMediaType(MediaTypeJPEG),
+		contenttype.NewMediaType(MediaTypeWebP),
 	}
 
 	formatsByMediaType := map[string]string{
@@ -116,6 +131,10 @@ func newContentNegotiation(availableLanguages []config.Language) *ContentNegotia
 		MediaTypeMVT:         FormatMVT,
 		MediaTypeMapboxStyle: FormatMapboxStyle,
 		MediaTypeSLD:         FormatSLD,
+		MediaTypeOpenAPI31:   FormatOpenAPI31,
+		MediaTypePNG:         FormatPNG,
+		MediaTypeJPEG:        FormatJPEG,
+		MediaTypeWebP:        FormatWebP,
 	}
 
 	mediaTypesByFormat := util.Inverse(formatsByMediaType)
@@ -145,6 +164,15 @@ func (cn *ContentNegotiation) GetStyleFormatExtension(format string) string {
 	return ""
 }
 
+// GetStyleFormatMediaType returns the media type of the given style format
+func (cn *ContentNegotiation) GetStyleFormatMediaType(format string) string {
+	if slices.Contains(cn.GetSupportedStyleFormats(), format) {
+		return cn.mediaTypesByFormat[format]
+	}
+
+	return ""
+}
+
 // NegotiateFormat performs content negotiation, not idempotent (since it removes the ?f= param).
 func (cn *ContentNegotiation) NegotiateFormat(req *http.Request) string {
 	requestedFormat := cn.getFormatFromQueryParam(req)
@@ -279,3 +307,35 @@ func (cn *ContentNegotiation) getLanguageFromHeader(req *http.Request) language.
 
 	return requestedLanguage
 }
+
+// AcceptsEncoding whether the client accepts the given content coding (e.g. gzip) according
+// to the Accept-Encoding header(s) of the request. Codings with quality 0 (q=0) aren't acceptable.
+func AcceptsEncoding(req *http.Request, encoding string) bool {
+	wildcard := false
+	for _, header := range req.Header.Values(HeaderAcceptEncoding) {
+		for _, part := range strings.Split(header, ",") {
+			coding, params, _ := strings.Cut(part, ";")
+			coding = strings.TrimSpace(coding)
+			switch {
+			case strings.EqualFold(coding, encoding):
+				return hasNonZeroQuality(params)
+			case coding == "*":
+				wildcard = hasNonZeroQuality(params)
+			}
+		}
+	}
+
+	return wildcard
+}
+
+func hasNonZeroQuality(params string) bool {
+	for _, param := range strings.Split(params, ";") {
+		if value, ok := strings.CutPrefix(strings.TrimSpace(param), "q="); ok {
+			quality, err := strconv.ParseFloat(value, 64)
+
+			return err != nil || quality > 0
+		}
+	}
+
+	return true
+}
@@ -24,6 +24,9 @@ func TestContentNegotiation_NegotiateFormat(t *testing.T) {
 	testFormat(t, cn, "", "http://pdok.example/ogc/api?f=json", "json")
 	testFormat(t, cn, "application/xml, application/json, text/css, text/html", "http://pdok.example/ogc/api/", "xml")
 	testFormat(t, cn, "application/json, application/xml, text/css, text/html", "http://pdok.example/ogc/api/", "json")
+	testFormat(t, cn, "image/png", "http://pdok.example/ogc/api/tiles/WebMercatorQuad/0/0/0", "png")
+	testFormat(t, cn, "image/webp,image/png;q=0.8", "http://pdok.example/ogc/api/tiles/WebMercatorQuad/0/0/0", "webp")
+	testFormat(t, cn, "", "http://pdok.example/ogc/api/tiles/WebMercatorQuad/0/0/0?f=jpeg", "jpeg")
 	testLanguage(t, cn, "nl;q=1", "http://pdok.example/ogc/api", language.Dutch)
 	testLanguage(t, cn, "fr;q=0.8, de;q=0.5", "http://pdok.example/ogc/api", language.Dutch)
 	testLanguage(t, cn, "en;q=1", "http://pdok.example/ogc/api", language.English)
@@ -78,3 +81,34 @@ func testLanguageWithCookie(t *testing.T, cn *ContentNegotiation, cookieLanguage
 		t.Fatalf("Expected %v for input %s, got %v", expectedLanguage, givenURL, lang)
 	}
 }
+
+func TestAcceptsEncoding(t *testing.T) {
+	tests := []struct {
+		acceptEncoding string
+		want           bool
+	}{
+		{acceptEncoding: "", want: false},
+		{acceptEncoding: "gzip", want: true},
+		{acceptEncoding: "gzip, deflate, br", want: true},
+		{acceptEncoding: "deflate, GZIP;q=0.5", want: true},
+		{acceptEncoding: "gzip;q=0", want: false},
+		{acceptEncoding: "gzip; q=0.0, deflate", want: false},
+		{acceptEncoding: "deflate, br", want: false},
+		{acceptEncoding: "x-gzip", want: false},
+		{acceptEncoding: "*", want: true},
+		{acceptEncoding: "*;q=0", want: false},
+		{acceptEncoding: "gzip;q=0, *", want: false},
+		{acceptEncoding: "identity, *;q=0.1", want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.acceptEncoding, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "http://pdok.example/ogc/api", nil)
+			if tt.acceptEncoding != "" {
+				req.Header.Set(HeaderAcceptEncoding, tt.acceptEncoding)
+			}
+			if got := AcceptsEncoding(req, FormatGzip); got != tt.want {
+				t.Errorf("AcceptsEncoding(%q) = %v, want %v", tt.acceptEncoding, got, tt.want)
+			}
+		})
+	}
+}
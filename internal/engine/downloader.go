@@ -14,7 +14,13 @@ import (
 	"golang.org/x/sync/errgroup"
 )
 
-const bufferSize = 1 * 1024 * 1024 // 1MiB
+const (
+	bufferSize = 1 * 1024 * 1024 // 1MiB
+
+	fetchTimeout       = 15 * time.Second
+	fetchRetryDelay    = 1 * time.Second
+	fetchRetryMaxDelay = 10 * time.Second
+)
 
 // Part piece of the file to download when HTTP Range Requests are supported.
 type Part struct {
@@ -109,6 +115,27 @@ func downloadWithMultipleConnections(url url.URL, outputFile *os.File, contentLe
 	return wg.Wait()
 }
 
+// Fetch retrieves the document at the given URL in the given media type, e.g. during bootstrap to
+// retrieve metadata from a backing server. Failed requests will be retried at most maxRetries times.
+func Fetch(target url.URL, mediaType string, maxRetries int) ([]byte, error) {
+	client := createHTTPClient(false, fetchTimeout, fetchRetryDelay, fetchRetryMaxDelay, maxRetries)
+	req, err := http.NewRequest(http.MethodGet, target.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set(HeaderAccept, mediaType)
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to retrieve %s, status code: %d", target.String(), res.StatusCode)
+	}
+
+	return io.ReadAll(res.Body)
+}
+
 func downloadPart(client *http.Client, url url.URL, outputFilepath string, part Part) error {
 	outputFile, err := os.OpenFile(outputFilepath, os.O_RDWR, 0664)
 	if err != nil {
@@ -22,7 +22,7 @@ import (
 	"github.com/PDOK/gokoala/internal/engine/util"
 
 	"github.com/go-chi/chi/v5"
-	"github.com/go-chi/chi/v5/middleware"
+	"golang.org/x/text/language"
 )
 
 const (
@@ -38,7 +38,12 @@ type Engine struct {
 	CN        *ContentNegotiation
 	Router    *chi.Mux
 
-	shutdownHooks []func()
+	// DebugRouter for endpoints that should only be exposed on the debug server (e.g. diagnostics),
+	// never on the main server.
+	DebugRouter *chi.Mux
+
+	shutdownHooks   []func()
+	readinessChecks []readinessCheck
 }
 
 // NewEngine builds a new Engine.
@@ -61,13 +66,15 @@ func NewEngineWithConfig(config *config.Config, theme *config.Theme, openAPIFile
 	templates := newTemplates(config, theme)
 	openAPI := newOpenAPI(config, []string{openAPIFile}, nil)
 	router := newRouter(config.Version, enableTrailingSlash, enableCORS)
+	debugRouter := newDebugRouter()
 
 	engine := &Engine{
-		Config:    config,
-		OpenAPI:   openAPI,
-		Templates: templates,
-		CN:        contentNegotiation,
-		Router:    router,
+		Config:      config,
+		OpenAPI:     openAPI,
+		Templates:   templates,
+		CN:          contentNegotiation,
+		Router:      router,
+		DebugRouter: debugRouter,
 	}
 
 	// Default (non-OGC) endpoints
@@ -85,16 +92,18 @@ func (e *Engine) Start(address string, debugPort int, shutdownDelay int) error {
 	if debugPort > 0 {
 		go func() {
 			debugAddress := fmt.Sprintf("localhost:%d", debugPort)
-			debugRouter := chi.NewRouter()
-			debugRouter.Use(middleware.Logger)
-			debugRouter.Mount("/debug", middleware.Profiler())
-			err := e.startServer("debug server", debugAddress, 0, debugRouter)
+			err := e.startServer("debug server", debugAddress, 0, e.DebugRouter)
 			if err != nil {
 				log.Fatalf("debug server failed %v", err)
 			}
 		}()
 	}
 
+	// specs per collection are generated on first use, validate these in the background to catch issues early on
+	if e.OpenAPI != nil {
+		go e.OpenAPI.validateCollectionSpecs()
+	}
+
 	// main server
 	return e.startServer("main server", address, shutdownDelay, e.Router)
 }
@@ -108,7 +117,7 @@ func (e *Engine) RegisterShutdownHook(fn func()) {
 // Use only once during bootstrap for specific use cases! For example: when you want to expand a
 // specific part of the OpenAPI spec with data outside the configuration file (e.g. from a database).
 func (e *Engine) RebuildOpenAPI(openAPIParams any) {
-	e.OpenAPI = newOpenAPI(e.Config, e.OpenAPI.extraOpenAPIFiles, openAPIParams)
+	e.OpenAPI = buildOpenAPI(e.Config, e.OpenAPI.extraOpenAPIFiles, e.OpenAPI.processesServerSpec, openAPIParams)
 }
 
 // ParseTemplate parses both HTML and non-HTML templates depending on the format given in the TemplateKey and
@@ -129,6 +138,14 @@ func (e *Engine) RenderTemplatesWithParams(urlPath string, params any, breadcrum
 	e.renderTemplates(urlPath, params, breadcrumbs, true, keys...)
 }
 
+// RerenderTemplatesWithParams renders templates just like RenderTemplatesWithParams, but after startup. Use this when
+// the resources backing the templates changed at runtime (e.g. through the API). The OpenAPI validation of the
+// rendered templates is skipped, since failing validation would terminate the server. Callers should validate
+// the resources backing the templates themselves beforehand.
+func (e *Engine) RerenderTemplatesWithParams(params any, breadcrumbs []Breadcrumb, keys ...TemplateKey) {
+	e.renderTemplates("", params, breadcrumbs, false, keys...)
+}
+
 // RenderAndServe renders an already parsed HTML or non-HTML template on-the-fly depending
 // on the format in the given TemplateKey. The result isn't stored in engine, it's served directly to the client.
 //
@@ -156,10 +173,10 @@ func (e *Engine) RenderAndServe(w http.ResponseWriter, r *http.Request, key Temp
 	var output []byte
 	if key.Format == FormatHTML {
 		htmlTmpl := parsedTemplate.(*htmltemplate.Template)
-		output = e.Templates.renderHTMLTemplate(htmlTmpl, r.URL, params, breadcrumbs, "", availableFormats)
+		output = e.Templates.renderHTMLTemplate(htmlTmpl, r.URL, key.Language, params, breadcrumbs, "", availableFormats)
 	} else {
 		jsonTmpl := parsedTemplate.(*texttemplate.Template)
-		output = e.Templates.renderNonHTMLTemplate(jsonTmpl, params, key, "")
+		output = e.Templates.renderNonHTMLTemplate(jsonTmpl, key.Language, params, key, "")
 	}
 	contentType := e.CN.formatToMediaType(key)
 
@@ -170,6 +187,7 @@ func (e *Engine) RenderAndServe(w http.ResponseWriter, r *http.Request, key Temp
 
 		return
 	}
+	w.Header().Set(HeaderContentLanguage, key.Language.String())
 	writeResponse(w, contentType, output)
 }
 
@@ -209,6 +227,9 @@ func (e *Engine) Serve(w http.ResponseWriter, r *http.Request, opt ...ServeOptio
 		if s.contentType == "" {
 			s.contentType = e.CN.formatToMediaType(*s.templateKey)
 		}
+		if s.contentLanguage == nil {
+			s.contentLanguage = &s.templateKey.Language
+		}
 	case s.json != nil:
 		if !s.validateResponse {
 			// shortcut for max performance: serve JSON *WITHOUT* OpenAPI validation
@@ -231,6 +252,9 @@ func (e *Engine) Serve(w http.ResponseWriter, r *http.Request, opt ...ServeOptio
 			return
 		}
 	}
+	if s.contentLanguage != nil {
+		w.Header().Set(HeaderContentLanguage, s.contentLanguage.String())
+	}
 	writeResponse(w, s.contentType, s.output)
 }
 
@@ -241,8 +265,9 @@ type serve struct {
 	json        any
 
 	// output
-	output      []byte
-	contentType string
+	output          []byte
+	contentType     string
+	contentLanguage *language.Tag
 
 	// validation
 	validateRequest  bool
@@ -282,6 +307,12 @@ func ServeContentType(contentType string) ServeOption {
 	}
 }
 
+func ServeContentLanguage(contentLanguage language.Tag) ServeOption {
+	return func(s *serve) {
+		s.contentLanguage = &contentLanguage
+	}
+}
+
 // ReverseProxy forwards given HTTP request to given target server, and optionally tweaks response.
 func (e *Engine) ReverseProxy(w http.ResponseWriter, r *http.Request, target *url.URL,
 	prefer204 bool, contentTypeOverwrite string) {
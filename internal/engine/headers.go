@@ -4,12 +4,21 @@ const (
 	HeaderLink            = "Link"
 	HeaderAccept          = "Accept"
 	HeaderAcceptLanguage  = "Accept-Language"
+	HeaderAcceptEncoding  = "Accept-Encoding"
 	HeaderAcceptRanges    = "Accept-Ranges"
 	HeaderRange           = "Range"
 	HeaderContentType     = "Content-Type"
 	HeaderContentLength   = "Content-Length"
 	HeaderContentCrs      = "Content-Crs"
+	HeaderContentLanguage = "Content-Language"
 	HeaderContentEncoding = "Content-Encoding"
+	HeaderCacheControl    = "Cache-Control"
+	HeaderVary            = "Vary"
+	HeaderETag            = "ETag"
+	HeaderLastModified    = "Last-Modified"
+	HeaderIfNoneMatch     = "If-None-Match"
+	HeaderIfModifiedSince = "If-Modified-Since"
+	HeaderCache           = "X-Cache"
 	HeaderBaseURL         = "X-BaseUrl"
 	HeaderRequestedWith   = "X-Requested-With"
 	HeaderAPIVersion      = "API-Version"
@@ -1,20 +1,102 @@
 package engine
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
 	"log"
 	"net/http"
 	"net/url"
+	"sync"
 	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	healthPath    = "/health"
+	readinessPath = "/health/ready"
+
+	// readiness including the reason components aren't available, only on the debug server
+	debugReadinessPath = "/debug" + readinessPath
+
+	// max time for all readiness checks together
+	readinessTimeout = 5 * time.Second
+
+	// the public readiness endpoint reuses the outcome of the checks for this long, to protect backing components
+	readinessCacheTTL = 5 * time.Second
+
+	readinessStatusUp   = "up"
+	readinessStatusDown = "down"
 )
 
+// ReadinessCheck checks whether a backing component (e.g. a database or tile server) is available.
+// Returns an error when the component isn't available.
+type ReadinessCheck func(ctx context.Context) error
+
+type readinessCheck struct {
+	component string
+	name      string
+	check     ReadinessCheck
+}
+
+type componentStatus struct {
+	Component string `json:"component"`
+	Name      string `json:"name"`
+	Status    string `json:"status"`
+	Error     string `json:"error,omitempty"`
+}
+
+type readinessStatus struct {
+	Ready      bool              `json:"ready"`
+	Components []componentStatus `json:"components"`
+}
+
+// RegisterReadinessCheck register a check of a backing component to perform on the readiness endpoint. The
+// component is the part of the API depending on it (e.g. 'tiles'), the name identifies the specific backend.
+func (e *Engine) RegisterReadinessCheck(component string, name string, check ReadinessCheck) {
+	e.readinessChecks = append(e.readinessChecks, readinessCheck{component, name, check})
+}
+
+// CheckURL checks whether the server at the given URL is available. A server
+// responding with a server error (5xx) is considered unavailable.
+func CheckURL(ctx context.Context, target *url.URL) error {
+	return checkURL(ctx, target, http.StatusInternalServerError)
+}
+
+// CheckURLFound checks whether the resource at the given URL is available, meaning
+// the server responds with a success (2xx) status code.
+func CheckURLFound(ctx context.Context, target *url.URL) error {
+	return checkURL(ctx, target, http.StatusMultipleChoices)
+}
+
+func checkURL(ctx context.Context, target *url.URL, unavailableFrom int) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, target.String(), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= unavailableFrom {
+		return fmt.Errorf("%s responded with status code %d", target, resp.StatusCode)
+	}
+
+	return nil
+}
+
 func newHealthEndpoint(e *Engine) {
 	var target *url.URL
 	if tilesConfig := e.Config.OgcAPI.Tiles; tilesConfig != nil {
 		var err error
 		switch {
-		case tilesConfig.DatasetTiles != nil && *tilesConfig.DatasetTiles.HealthCheck.Enabled:
+		case tilesConfig.DatasetTiles != nil && tilesConfig.DatasetTiles.HasTileServer() &&
+			*tilesConfig.DatasetTiles.HealthCheck.Enabled:
 			target, err = url.Parse(tilesConfig.DatasetTiles.TileServer.String() + *tilesConfig.DatasetTiles.HealthCheck.TilePath)
-		case len(tilesConfig.Collections) > 0 && *tilesConfig.Collections[0].GeoDataTiles.HealthCheck.Enabled:
+		case len(tilesConfig.Collections) > 0 && tilesConfig.Collections[0].GeoDataTiles.HasTileServer() &&
+			*tilesConfig.Collections[0].GeoDataTiles.HealthCheck.Enabled:
 			target, err = url.Parse(tilesConfig.Collections[0].GeoDataTiles.TileServer.String() + *tilesConfig.Collections[0].GeoDataTiles.HealthCheck.TilePath)
 		default:
 			log.Println("cannot determine health check tilepath or tiles health check is disabled, falling back to basic check")
@@ -25,7 +107,7 @@ func newHealthEndpoint(e *Engine) {
 	}
 	if target != nil {
 		client := &http.Client{Timeout: time.Duration(500) * time.Millisecond}
-		e.Router.Get("/health", func(w http.ResponseWriter, _ *http.Request) {
+		e.Router.Get(healthPath, func(w http.ResponseWriter, _ *http.Request) {
 			resp, err := client.Head(target.String())
 			if err != nil {
 				// the exact error is irrelevant for health monitoring, but log it for insight
@@ -37,8 +119,103 @@ func newHealthEndpoint(e *Engine) {
 			}
 		})
 	} else {
-		e.Router.Get("/health", func(w http.ResponseWriter, _ *http.Request) {
+		e.Router.Get(healthPath, func(w http.ResponseWriter, _ *http.Request) {
 			SafeWrite(w.Write, []byte("OK"))
 		})
 	}
+	e.Router.Get(readinessPath, e.readiness(false, readinessCacheTTL))
+	e.DebugRouter.Get(debugReadinessPath, e.readiness(true, 0))
+}
+
+// readiness performs all registered readiness checks concurrently and reports the status per component.
+// Responds with 503 when one or more components aren't available, in contrast to /health (which is a
+// cheap liveness probe) this endpoint checks all backing components. To prevent each request from hitting
+// all backing components, the outcome is reused for the given TTL and concurrent requests share the checks.
+// Errors may reveal internals (e.g. hostnames) so these are only reported when detailed.
+func (e *Engine) readiness(detailed bool, ttl time.Duration) http.HandlerFunc {
+	var (
+		mu      sync.Mutex
+		cached  readinessStatus
+		checked time.Time
+		group   singleflight.Group
+	)
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		if ttl <= 0 {
+			writeReadiness(w, e.checkReadiness(r.Context()), detailed)
+
+			return
+		}
+		mu.Lock()
+		status, fresh := cached, Now().Sub(checked) < ttl
+		mu.Unlock()
+		if !fresh {
+			result, _, _ := group.Do("readiness", func() (any, error) {
+				// the outcome is shared between requests, so don't tie it to the context of this request
+				s := e.checkReadiness(context.Background())
+				mu.Lock()
+				cached, checked = s, Now()
+				mu.Unlock()
+
+				return s, nil
+			})
+			status = result.(readinessStatus)
+		}
+		writeReadiness(w, status, detailed)
+	}
+}
+
+// checkReadiness performs all registered readiness checks concurrently, including the errors of the checks.
+func (e *Engine) checkReadiness(ctx context.Context) readinessStatus {
+	ctx, cancel := context.WithTimeout(ctx, readinessTimeout)
+	defer cancel()
+
+	status := readinessStatus{Ready: true, Components: make([]componentStatus, len(e.readinessChecks))}
+	var wg sync.WaitGroup
+	for i, rc := range e.readinessChecks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cs := componentStatus{Component: rc.component, Name: rc.name, Status: readinessStatusUp}
+			if err := rc.check(ctx); err != nil {
+				log.Printf("readiness check of %s '%s' failed: %v", rc.component, rc.name, err)
+				cs.Status = readinessStatusDown
+				cs.Error = err.Error()
+			}
+			status.Components[i] = cs
+		}()
+	}
+	wg.Wait()
+	for _, cs := range status.Components {
+		if cs.Status != readinessStatusUp {
+			status.Ready = false
+		}
+	}
+
+	return status
+}
+
+func writeReadiness(w http.ResponseWriter, status readinessStatus, detailed bool) {
+	statusCode := http.StatusOK
+	if !status.Ready {
+		statusCode = http.StatusServiceUnavailable
+	}
+	if !detailed {
+		// copy, since the status may be shared with other requests
+		components := make([]componentStatus, len(status.Components))
+		for i, cs := range status.Components {
+			cs.Error = ""
+			components[i] = cs
+		}
+		status.Components = components
+	}
+	body, err := json.MarshalIndent(status, "", "  ")
+	if err != nil {
+		RenderProblemAndLog(ProblemServerError, w, err)
+
+		return
+	}
+	w.Header().Set(HeaderContentType, MediaTypeJSON)
+	w.WriteHeader(statusCode)
+	SafeWrite(w.Write, body)
 }
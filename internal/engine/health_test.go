@@ -0,0 +1,156 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEngine_Readiness(t *testing.T) {
+	tests := []struct {
+		name           string
+		checks         map[string]ReadinessCheck
+		wantStatusCode int
+		wantBody       string
+		wantDebugBody  string
+	}{
+		{
+			name:           "no backing components",
+			wantStatusCode: http.StatusOK,
+			wantBody:       `{"ready": true, "components": []}`,
+			wantDebugBody:  `{"ready": true, "components": []}`,
+		},
+		{
+			name: "all components up",
+			checks: map[string]ReadinessCheck{
+				"db": func(_ context.Context) error { return nil },
+			},
+			wantStatusCode: http.StatusOK,
+			wantBody:       `{"ready": true, "components": [{"component": "features", "name": "db", "status": "up"}]}`,
+			wantDebugBody:  `{"ready": true, "components": [{"component": "features", "name": "db", "status": "up"}]}`,
+		},
+		{
+			name: "component down",
+			checks: map[string]ReadinessCheck{
+				"db": func(_ context.Context) error { return errors.New("connection refused") },
+			},
+			wantStatusCode: http.StatusServiceUnavailable,
+			wantBody:       `{"ready": false, "components": [{"component": "features", "name": "db", "status": "down"}]}`,
+			wantDebugBody:  `{"ready": false, "components": [{"component": "features", "name": "db", "status": "down", "error": "connection refused"}]}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine, err := NewEngine("internal/engine/testdata/config_minimal.yaml", "internal/engine/testdata/test_theme.yaml", "", false, true)
+			require.NoError(t, err)
+			for name, check := range tt.checks {
+				engine.RegisterReadinessCheck("features", name, check)
+			}
+
+			rr := httptest.NewRecorder()
+			engine.Router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "http://localhost:8080/health/ready", nil))
+
+			assert.Equal(t, tt.wantStatusCode, rr.Code)
+			assert.Equal(t, MediaTypeJSON, rr.Header().Get(HeaderContentType))
+			assert.JSONEq(t, tt.wantBody, rr.Body.String())
+
+			// errors are only reported on the debug server
+			rr = httptest.NewRecorder()
+			engine.DebugRouter.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "http://localhost:9001/debug/health/ready", nil))
+			assert.Equal(t, tt.wantStatusCode, rr.Code)
+			assert.JSONEq(t, tt.wantDebugBody, rr.Body.String())
+
+			// liveness probe isn't affected by backing components
+			rr = httptest.NewRecorder()
+			engine.Router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "http://localhost:8080/health", nil))
+			assert.Equal(t, http.StatusOK, rr.Code)
+		})
+	}
+}
+
+func TestEngine_ReadinessCached(t *testing.T) {
+	engine, err := NewEngine("internal/engine/testdata/config_minimal.yaml", "internal/engine/testdata/test_theme.yaml", "", false, true)
+	require.NoError(t, err)
+	var calls atomic.Int32
+	var down atomic.Bool
+	engine.RegisterReadinessCheck("features", "db", func(_ context.Context) error {
+		calls.Add(1)
+		time.Sleep(10 * time.Millisecond)
+		if down.Load() {
+			return errors.New("connection refused")
+		}
+
+		return nil
+	})
+	clock := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	Now = func() time.Time { return clock }
+	defer func() { Now = time.Now }()
+	get := func(router http.Handler, url string) int {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, url, nil))
+
+		return rr.Code
+	}
+
+	// concurrent requests share the checks
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Go(func() {
+			assert.Equal(t, http.StatusOK, get(engine.Router, "http://localhost:8080/health/ready"))
+		})
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), calls.Load())
+
+	// outcome is reused within the TTL
+	down.Store(true)
+	clock = clock.Add(readinessCacheTTL - time.Second)
+	assert.Equal(t, http.StatusOK, get(engine.Router, "http://localhost:8080/health/ready"))
+	assert.Equal(t, int32(1), calls.Load())
+
+	// debug server always performs the checks
+	assert.Equal(t, http.StatusServiceUnavailable, get(engine.DebugRouter, "http://localhost:9001/debug/health/ready"))
+	assert.Equal(t, int32(2), calls.Load())
+
+	// outcome is refreshed after the TTL
+	clock = clock.Add(2 * time.Second)
+	assert.Equal(t, http.StatusServiceUnavailable, get(engine.Router, "http://localhost:8080/health/ready"))
+	assert.Equal(t, int32(3), calls.Load())
+}
+
+func TestCheckURL(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ok":
+			w.WriteHeader(http.StatusOK)
+		case "/error":
+			w.WriteHeader(http.StatusBadGateway)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+	target := func(path string) *url.URL {
+		u, err := url.Parse(ts.URL + path)
+		require.NoError(t, err)
+		return u
+	}
+	ctx := context.Background()
+
+	require.NoError(t, CheckURL(ctx, target("/ok")))
+	require.NoError(t, CheckURL(ctx, target("/missing")))
+	require.Error(t, CheckURL(ctx, target("/error")))
+
+	require.NoError(t, CheckURLFound(ctx, target("/ok")))
+	require.Error(t, CheckURLFound(ctx, target("/missing")))
+	require.Error(t, CheckURLFound(ctx, target("/error")))
+}
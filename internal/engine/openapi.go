@@ -12,7 +12,9 @@ import (
 	"net/url"
 	"path/filepath"
 	"regexp"
+	"slices"
 	"strings"
+	"sync"
 	texttemplate "text/template"
 
 	gokoalaconfig "github.com/PDOK/gokoala/config"
@@ -20,9 +22,12 @@ import (
 
 	"github.com/PDOK/gokoala/internal/engine/util"
 	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3conv"
 	"github.com/getkin/kin-openapi/openapi3filter"
 	"github.com/getkin/kin-openapi/routers"
 	"github.com/getkin/kin-openapi/routers/gorillamux"
+	"golang.org/x/sync/singleflight"
+	"golang.org/x/text/language"
 )
 
 const (
@@ -36,17 +41,81 @@ const (
 	tilesSpec          = specPath + "tiles.go.json"
 	stylesSpec         = specPath + "styles.go.json"
 	geoVolumesSpec     = specPath + "3dgeovolumes.go.json"
+	processesSpec      = specPath + "processes.go.json"
 	commonSpec         = specPath + "common.go.json"
 	HTMLRegex          = `<[/]?([a-zA-Z]+).*?>`
+
+	// location of the OpenAPI spec on an external processes server
+	processesServerSpecPath = "/api"
+
+	// prefix of the names of components in the OpenAPI spec of an external processes server, to
+	// prevent these from overwriting components with the same name in GoKoala's own specs
+	processesServerComponentPrefix = "processesServer."
+	componentRefPrefix             = "#/components/"
+)
+
+// paths in the OpenAPI spec of an external processes server to include in the OpenAPI spec of GoKoala,
+// other paths (landing page, conformance, etc.) are served by GoKoala itself.
+var processesServerPaths = []string{"/processes", "/jobs"}
+
+// OpenAPIVersion version of the OpenAPI specification in which an OpenAPI spec is offered.
+type OpenAPIVersion string
+
+const (
+	OpenAPI30 OpenAPIVersion = "3.0"
+	OpenAPI31 OpenAPIVersion = "3.1"
 )
 
+// ErrCollectionSpecNotFound is returned when no separate OpenAPI spec is available for a collection.
+var ErrCollectionSpecNotFound = errors.New("no separate OpenAPI spec available for collection")
+
 type OpenAPI struct {
 	spec     *openapi3.T
 	SpecJSON []byte
 
-	config            *gokoalaconfig.Config
-	router            routers.Router
-	extraOpenAPIFiles []string
+	// spec in the languages to which the title, abstract, etc. are translated
+	localizedSpecJSON map[language.Tag][]byte
+
+	// source of the specs generated on first use, by language (language.Und is the default language)
+	sourceSpecs map[language.Tag]*sourceSpec
+	// collections with a separate spec, only set when splitting per collection
+	collectionIDs map[string]bool
+	// specs generated on first use: OpenAPI 3.1 specs and per-collection specs. Only successfully
+	// generated specs are kept, concurrent generation of the same spec is deduplicated.
+	generatedSpecs map[generatedSpecKey]*generatedSpec
+	generatedMutex sync.RWMutex
+	generating     singleflight.Group
+
+	config              *gokoalaconfig.Config
+	router              routers.Router
+	extraOpenAPIFiles   []string
+	processesServerSpec []byte
+}
+
+type sourceSpec struct {
+	// main spec, without references to the per-collection specs
+	main []byte
+	// full spec including collection specific paths, only set when splitting per collection
+	full map[string]any
+	// collection specific paths that have been split off from the main spec, by collection ID
+	collectionPaths map[string][]string
+	// language to request referenced per-collection specs in, empty for the default language
+	lang string
+}
+
+type generatedSpecKey struct {
+	collectionID string
+	lang         language.Tag
+	version      OpenAPIVersion
+}
+
+func (k generatedSpecKey) String() string {
+	return k.collectionID + "|" + k.lang.String() + "|" + string(k.version)
+}
+
+type generatedSpec struct {
+	specJSON []byte
+	router   routers.Router
 }
 
 // init once.
@@ -85,6 +154,10 @@ func init() {
 }
 
 func newOpenAPI(config *gokoalaconfig.Config, extraOpenAPIFiles []string, openAPIParams any) *OpenAPI {
+	return buildOpenAPI(config, extraOpenAPIFiles, fetchProcessesServerSpec(config), openAPIParams)
+}
+
+func buildOpenAPI(config *gokoalaconfig.Config, extraOpenAPIFiles []string, processesServerSpec []byte, openAPIParams any) *OpenAPI {
 	ctx := context.Background()
 
 	// order matters, see mergeSpecs for details.
@@ -107,6 +180,9 @@ func newOpenAPI(config *gokoalaconfig.Config, extraOpenAPIFiles []string, openAP
 	if config.OgcAPI.GeoVolumes != nil {
 		defaultOpenAPIFiles = append(defaultOpenAPIFiles, geoVolumesSpec)
 	}
+	if config.OgcAPI.Processes != nil && !config.OgcAPI.Processes.IsProxy() {
+		defaultOpenAPIFiles = append(defaultOpenAPIFiles, processesSpec)
+	}
 
 	// add preamble first
 	openAPIFiles := []string{preamble} //nolint:prealloc
@@ -114,22 +190,68 @@ func newOpenAPI(config *gokoalaconfig.Config, extraOpenAPIFiles []string, openAP
 	openAPIFiles = append(openAPIFiles, extraOpenAPIFiles...)
 	openAPIFiles = append(openAPIFiles, defaultOpenAPIFiles...)
 
-	resultSpec, resultSpecJSON := mergeSpecs(ctx, config, openAPIFiles, openAPIParams)
-	validateSpec(ctx, resultSpec, resultSpecJSON)
+	// add spec of external processes server last, since GoKoala's own specs take precedence
+	var externalSpecs [][]byte
+	if processesServerSpec != nil {
+		externalSpecs = append(externalSpecs, processesServerSpec)
+	}
+
+	collectionIDs := collectionIDsToSplit(config)
+
+	source := newSourceSpec(mergeSpecs(config, openAPIFiles, openAPIParams, externalSpecs...), collectionIDs)
+	resultSpec, err := loadAndValidateSpec(ctx, source.main)
+	if err != nil && externalSpecs != nil {
+		log.Printf("WARNING: OpenAPI spec of processes server can't be merged into OpenAPI spec, "+
+			"processes won't be documented in OpenAPI spec: %v", err)
+		processesServerSpec, externalSpecs = nil, nil
+		source = newSourceSpec(mergeSpecs(config, openAPIFiles, openAPIParams), collectionIDs)
+		resultSpec, err = loadAndValidateSpec(ctx, source.main)
+	}
+	if err != nil {
+		log.Print(string(source.main))
+		log.Fatalf("invalid OpenAPI spec: %v", err)
+	}
 
 	for _, server := range resultSpec.Servers {
 		server.URL = normalizeBaseURL(server.URL)
 	}
 
+	sourceSpecs := map[language.Tag]*sourceSpec{language.Und: source}
+	localizedSpecJSON := make(map[language.Tag][]byte)
+	for _, lang := range config.AvailableLanguages {
+		if config.HasTranslations(lang.Tag) {
+			localizedSource := newSourceSpec(mergeSpecs(config.Localize(lang.Tag), openAPIFiles,
+				openAPIParams, externalSpecs...), collectionIDs)
+			localizedSource.lang = lang.String()
+			sourceSpecs[lang.Tag] = localizedSource
+			localizedSpecJSON[lang.Tag] = servedSpecJSON(config, localizedSource)
+		}
+	}
+
 	return &OpenAPI{
-		config:            config,
-		spec:              resultSpec,
-		SpecJSON:          util.PrettyPrintJSON(resultSpecJSON, ""),
-		router:            newOpenAPIRouter(resultSpec),
-		extraOpenAPIFiles: extraOpenAPIFiles,
+		config:              config,
+		spec:                resultSpec,
+		SpecJSON:            servedSpecJSON(config, source),
+		localizedSpecJSON:   localizedSpecJSON,
+		sourceSpecs:         sourceSpecs,
+		collectionIDs:       collectionIDs,
+		generatedSpecs:      make(map[generatedSpecKey]*generatedSpec),
+		router:              newOpenAPIRouter(resultSpec),
+		extraOpenAPIFiles:   extraOpenAPIFiles,
+		processesServerSpec: processesServerSpec,
 	}
 }
 
+// servedSpecJSON returns the (OpenAPI 3.0) main spec as served to clients.
+func servedSpecJSON(config *gokoalaconfig.Config, source *sourceSpec) []byte {
+	specJSON, err := source.withCollectionRefs(source.main, config.BaseURL.String(), OpenAPI30)
+	if err != nil {
+		log.Fatalf("failed to reference per-collection OpenAPI specs: %v", err)
+	}
+
+	return util.PrettyPrintJSON(specJSON, "")
+}
+
 // mergeSpecs merges the given OpenAPI specs.
 //
 // Order matters! We start with the preamble, it is highest in rank and there's no way to override it.
@@ -137,37 +259,38 @@ func newOpenAPI(config *gokoalaconfig.Config, extraOpenAPIFiles []string, openAP
 // have a higher change of getting their changes in the final spec than files that follow later.
 //
 // The OpenAPI spec optionally provided through the CLI should be the second (after preamble) item in the
-// `files` slice since it allows the user to override other/default specs.
-func mergeSpecs(ctx context.Context, config *gokoalaconfig.Config, files []string, params any) (*openapi3.T, []byte) {
-	loader := &openapi3.Loader{Context: ctx, IsExternalRefsAllowed: false}
-
+// `files` slice since it allows the user to override other/default specs. Specs retrieved from external
+// servers (externalSpecs) aren't templates and are merged last, after all files. The result is only
+// loaded and validated afterward (see loadSpec and validateSpec) since that's costly for large specs.
+func mergeSpecs(config *gokoalaconfig.Config, files []string, params any, externalSpecs ...[]byte) []byte {
 	if len(files) < 1 {
 		log.Fatalf("files can't be empty, at least OGC Common is expected")
 	}
 	var resultSpecJSON []byte
-	var resultSpec *openapi3.T
 
+	specs := make([][]byte, 0, len(files)+len(externalSpecs))
 	for _, file := range files {
 		if file == "" {
 			continue
 		}
-		specJSON := renderOpenAPITemplate(config, file, params)
-		var mergedJSON []byte
+		specs = append(specs, renderOpenAPITemplate(config, file, params))
+	}
+	specs = append(specs, externalSpecs...)
+
+	for _, specJSON := range specs {
 		if resultSpecJSON == nil {
-			mergedJSON = specJSON
-		} else {
-			var err error
-			mergedJSON, err = util.MergeJSON(resultSpecJSON, specJSON, orderByOpenAPIConvention)
-			if err != nil {
-				log.Print(string(mergedJSON))
-				log.Fatalf("failed to merge OpenAPI specs: %v", err)
-			}
+			resultSpecJSON = specJSON
+			continue
+		}
+		mergedJSON, err := util.MergeJSON(resultSpecJSON, specJSON, orderByOpenAPIConvention)
+		if err != nil {
+			log.Print(string(mergedJSON))
+			log.Fatalf("failed to merge OpenAPI specs: %v", err)
 		}
 		resultSpecJSON = mergedJSON
-		resultSpec = loadSpec(loader, mergedJSON)
 	}
 
-	return resultSpec, resultSpecJSON
+	return resultSpecJSON
 }
 
 func orderByOpenAPIConvention(output map[string]any) any {
@@ -189,23 +312,145 @@ func orderByOpenAPIConvention(output map[string]any) any {
 	return result
 }
 
-func loadSpec(loader *openapi3.Loader, mergedJSON []byte, fileName ...string) *openapi3.T {
-	resultSpec, err := loader.LoadFromData(mergedJSON)
+func marshalByOpenAPIConvention(spec *openapi3.T) ([]byte, error) {
+	specJSON, err := json.Marshal(spec)
 	if err != nil {
-		log.Print(string(mergedJSON))
-		log.Fatalf("failed to load merged OpenAPI spec %s, due to %v", fileName, err)
+		return nil, err
+	}
+	var output map[string]any
+	if err = json.Unmarshal(specJSON, &output); err != nil {
+		return nil, err
 	}
 
-	return resultSpec
+	return json.Marshal(orderByOpenAPIConvention(output))
 }
 
-func validateSpec(ctx context.Context, finalSpec *openapi3.T, finalSpecRaw []byte) {
+func loadAndValidateSpec(ctx context.Context, specJSON []byte) (*openapi3.T, error) {
+	loader := &openapi3.Loader{Context: ctx, IsExternalRefsAllowed: false}
+	spec, err := loader.LoadFromData(specJSON)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load OpenAPI spec: %w", err)
+	}
 	// Validate OGC OpenAPI spec. Note: the examples provided in the official spec aren't valid.
-	err := finalSpec.Validate(ctx, openapi3.DisableExamplesValidation())
+	if err = spec.Validate(ctx, openapi3.DisableExamplesValidation()); err != nil {
+		return nil, err
+	}
+
+	return spec, nil
+}
+
+// fetchProcessesServerSpec retrieves the OpenAPI spec of the external processes server, when configured.
+// Only the processes and jobs paths of this spec are retained. Returns nil when the spec can't be
+// retrieved or is unusable, in that case the processes server is still proxied but not documented.
+func fetchProcessesServerSpec(config *gokoalaconfig.Config) []byte {
+	processes := config.OgcAPI.Processes
+	if processes == nil || !processes.IsProxy() {
+		return nil
+	}
+	target := *processes.ProcessesServer.URL
+	target.Path = processes.ProcessesServer.Path + processesServerSpecPath
+	specJSON, err := Fetch(target, MediaTypeOpenAPI+", "+MediaTypeJSON, processes.ProcessesServerRetries)
 	if err != nil {
-		log.Print(string(finalSpecRaw))
-		log.Fatalf("invalid OpenAPI spec: %v", err)
+		log.Printf("WARNING: failed to retrieve OpenAPI spec of processes server, "+
+			"processes won't be documented in OpenAPI spec: %v", err)
+		return nil
+	}
+	filtered, err := filterProcessesServerSpec(specJSON)
+	if err != nil {
+		log.Printf("WARNING: unusable OpenAPI spec of processes server %s, "+
+			"processes won't be documented in OpenAPI spec: %v", target.String(), err)
+		return nil
 	}
+
+	return filtered
+}
+
+// filterProcessesServerSpec retains only the processes and jobs paths (and all components) of the given
+// OpenAPI spec. Components are prefixed, see processesServerComponentPrefix. Returns an error when the
+// result isn't a valid OpenAPI spec on its own.
+func filterProcessesServerSpec(specJSON []byte) ([]byte, error) {
+	var spec map[string]any
+	if err := json.Unmarshal(specJSON, &spec); err != nil {
+		return nil, err
+	}
+	paths, _ := spec["paths"].(map[string]any)
+	filteredPaths := make(map[string]any)
+	for path, item := range paths {
+		for _, prefix := range processesServerPaths {
+			if path == prefix || strings.HasPrefix(path, prefix+"/") {
+				filteredPaths[path] = item
+			}
+		}
+	}
+	if len(filteredPaths) == 0 {
+		return nil, errors.New("spec contains no processes or jobs paths")
+	}
+	filtered := map[string]any{
+		"openapi": spec["openapi"],
+		"info":    spec["info"],
+		"paths":   filteredPaths,
+	}
+	if components, ok := spec["components"].(map[string]any); ok {
+		prefixedComponents := make(map[string]any, len(components))
+		for componentType, named := range components {
+			if named, ok := named.(map[string]any); ok {
+				prefixed := make(map[string]any, len(named))
+				for name, component := range named {
+					prefixed[processesServerComponentPrefix+name] = component
+				}
+				prefixedComponents[componentType] = prefixed
+			}
+		}
+		filtered["components"] = prefixedComponents
+	}
+	filteredJSON, err := json.Marshal(prefixComponentRefs(filtered))
+	if err != nil {
+		return nil, err
+	}
+	if _, err = loadAndValidateSpec(context.Background(), filteredJSON); err != nil {
+		return nil, err
+	}
+
+	return filteredJSON, nil
+}
+
+// prefixComponentRefs adds processesServerComponentPrefix to all references to components
+// (including names of security schemes in security requirements) in the given part of an OpenAPI spec.
+func prefixComponentRefs(node any) any {
+	switch n := node.(type) {
+	case map[string]any:
+		for key, value := range n {
+			if key == "security" {
+				if requirements, ok := value.([]any); ok {
+					for i, requirement := range requirements {
+						if schemes, ok := requirement.(map[string]any); ok {
+							prefixed := make(map[string]any, len(schemes))
+							for name, scopes := range schemes {
+								prefixed[processesServerComponentPrefix+name] = scopes
+							}
+							requirements[i] = prefixed
+						}
+					}
+
+					continue
+				}
+			}
+			n[key] = prefixComponentRefs(value)
+		}
+	case []any:
+		for i, value := range n {
+			n[i] = prefixComponentRefs(value)
+		}
+	case string:
+		// references, including those in discriminator mappings
+		if ref, ok := strings.CutPrefix(n, componentRefPrefix); ok {
+			if componentType, name, ok := strings.Cut(ref, "/"); ok {
+				return componentRefPrefix + componentType + "/" + processesServerComponentPrefix + name
+			}
+		}
+	}
+
+	return node
 }
 
 func newOpenAPIRouter(doc *openapi3.T) routers.Router {
@@ -230,6 +475,152 @@ func renderOpenAPITemplate(config *gokoalaconfig.Config, fileName string, params
 	return rendered.Bytes()
 }
 
+// LocalizedSpecJSON returns the OpenAPI spec (as JSON) with the title, abstract, etc. in the given language.
+// Falls back to SpecJSON when nothing is translated to the given language.
+func (o *OpenAPI) LocalizedSpecJSON(lang language.Tag) []byte {
+	if specJSON, ok := o.localizedSpecJSON[lang]; ok {
+		return specJSON
+	}
+
+	return o.SpecJSON
+}
+
+// LocalizedSpecJSONInVersion returns the OpenAPI spec (as JSON) in the given language and OpenAPI version.
+// The OpenAPI 3.1 spec is converted from the OpenAPI 3.0 spec on first use.
+func (o *OpenAPI) LocalizedSpecJSONInVersion(lang language.Tag, version OpenAPIVersion) ([]byte, error) {
+	if version != OpenAPI31 {
+		return o.LocalizedSpecJSON(lang), nil
+	}
+	spec, err := o.generate(generatedSpecKey{lang: o.sourceLanguage(lang), version: version})
+	if err != nil {
+		return nil, err
+	}
+
+	return spec.specJSON, nil
+}
+
+// CollectionSpecJSON returns the OpenAPI spec (as JSON) of a single collection in the given language
+// and OpenAPI version. Only available when splitting per collection is enabled, the spec is
+// generated on first use.
+func (o *OpenAPI) CollectionSpecJSON(collectionID string, lang language.Tag, version OpenAPIVersion) ([]byte, error) {
+	if !o.collectionIDs[collectionID] {
+		return nil, ErrCollectionSpecNotFound
+	}
+	spec, err := o.generate(generatedSpecKey{collectionID: collectionID, lang: o.sourceLanguage(lang), version: version})
+	if err != nil {
+		return nil, err
+	}
+
+	return spec.specJSON, nil
+}
+
+func (o *OpenAPI) sourceLanguage(lang language.Tag) language.Tag {
+	if _, ok := o.sourceSpecs[lang]; ok {
+		return lang
+	}
+
+	return language.Und
+}
+
+// generate generates the requested spec on first use, subsequent calls return the cached result.
+// Failures aren't cached, so generation is retried on the next call.
+func (o *OpenAPI) generate(key generatedSpecKey) (*generatedSpec, error) {
+	if spec, ok := o.getGeneratedSpec(key); ok {
+		return spec, nil
+	}
+	result, err, _ := o.generating.Do(key.String(), func() (any, error) {
+		if spec, ok := o.getGeneratedSpec(key); ok {
+			return spec, nil
+		}
+		spec, err := o.generateSpec(key)
+		if err != nil {
+			log.Printf("failed to generate OpenAPI %s spec (collection: '%s', language: %s): %v",
+				key.version, key.collectionID, key.lang, err)
+			return nil, err
+		}
+		o.generatedMutex.Lock()
+		o.generatedSpecs[key] = spec
+		o.generatedMutex.Unlock()
+
+		return spec, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result.(*generatedSpec), nil
+}
+
+func (o *OpenAPI) getGeneratedSpec(key generatedSpecKey) (*generatedSpec, bool) {
+	o.generatedMutex.RLock()
+	defer o.generatedMutex.RUnlock()
+	spec, ok := o.generatedSpecs[key]
+
+	return spec, ok
+}
+
+// validateCollectionSpecs generates the spec of each collection (used for request/response validation)
+// once, to report invalid specs early on instead of on first use.
+func (o *OpenAPI) validateCollectionSpecs() {
+	collectionIDs := make([]string, 0, len(o.collectionIDs))
+	for collectionID := range o.collectionIDs {
+		collectionIDs = append(collectionIDs, collectionID)
+	}
+	slices.Sort(collectionIDs)
+	for _, collectionID := range collectionIDs {
+		// errors are logged by generate
+		_, _ = o.generate(generatedSpecKey{collectionID: collectionID, lang: language.Und, version: OpenAPI30})
+	}
+}
+
+func (o *OpenAPI) generateSpec(key generatedSpecKey) (*generatedSpec, error) {
+	ctx := context.Background()
+	source := o.sourceSpecs[key.lang]
+
+	var err error
+	specJSON := source.main
+	if key.collectionID != "" {
+		if specJSON, err = source.collectionSpec(key.collectionID); err != nil {
+			return nil, err
+		}
+	}
+	loader := &openapi3.Loader{Context: ctx, IsExternalRefsAllowed: false}
+	spec, err := loader.LoadFromData(specJSON)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load OpenAPI spec: %w", err)
+	}
+	if err = spec.Validate(ctx, openapi3.DisableExamplesValidation()); err != nil {
+		return nil, fmt.Errorf("invalid OpenAPI spec: %w", err)
+	}
+
+	result := &generatedSpec{}
+	if key.version == OpenAPI31 {
+		openapi3conv.Upgrade(spec)
+		// upgrade results in the latest OpenAPI 3.x version, which only adds to 3.1.
+		// We advertise 3.1 since this version is more widely supported by tools.
+		spec.OpenAPI = "3.1.0"
+		if specJSON, err = marshalByOpenAPIConvention(spec); err != nil {
+			return nil, err
+		}
+	} else if key.collectionID != "" && key.lang == language.Und {
+		// router is used to validate requests/responses of this collection against the OpenAPI spec
+		for _, server := range spec.Servers {
+			server.URL = normalizeBaseURL(server.URL)
+		}
+		if result.router, err = gorillamux.NewRouter(spec); err != nil {
+			return nil, err
+		}
+	}
+	if key.collectionID == "" {
+		if specJSON, err = source.withCollectionRefs(specJSON, o.config.BaseURL.String(), key.version); err != nil {
+			return nil, err
+		}
+	}
+	result.specJSON = util.PrettyPrintJSON(specJSON, "")
+
+	return result, nil
+}
+
 func (o *OpenAPI) ValidateRequest(r *http.Request) error {
 	requestValidationInput, _ := o.getRequestValidationInput(r)
 	if requestValidationInput != nil {
@@ -275,7 +666,17 @@ func (o *OpenAPI) ValidateResponse(contentType string, body []byte, r *http.Requ
 }
 
 func (o *OpenAPI) getRequestValidationInput(r *http.Request) (*openapi3filter.RequestValidationInput, error) {
-	route, pathParams, err := o.router.FindRoute(r)
+	router := o.router
+	if collectionID := collectionIDOfPath(o.collectionIDs, r.URL.Path); collectionID != "" {
+		spec, err := o.generate(generatedSpecKey{collectionID: collectionID, lang: language.Und, version: OpenAPI30})
+		if err != nil {
+			log.Printf("OpenAPI spec of collection %s unavailable, skipping OpenAPI validation for url %s", collectionID, r.URL)
+
+			return nil, err
+		}
+		router = spec.router
+	}
+	route, pathParams, err := router.FindRoute(r)
 	if err != nil {
 		log.Printf("route not found in OpenAPI spec for url %s (host: %s), "+
 			"skipping OpenAPI validation", r.URL, r.Host)
@@ -0,0 +1,210 @@
+package engine
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"maps"
+	"net/url"
+	"strings"
+
+	gokoalaconfig "github.com/PDOK/gokoala/config"
+)
+
+const (
+	collectionsPathPrefix = "/collections/"
+
+	// location of the per-collection OpenAPI specs, relative to the base URL
+	collectionSpecPath = "/api/collections/"
+)
+
+var jsonPointerEscaper = strings.NewReplacer("~", "~0", "/", "~1")
+
+// collectionIDsToSplit returns the IDs of the collections for which the collection specific
+// paths should be split off from the main OpenAPI spec, or nil when splitting is disabled.
+func collectionIDsToSplit(config *gokoalaconfig.Config) map[string]bool {
+	if config.OpenAPI == nil || !config.OpenAPI.SplitPerCollection {
+		return nil
+	}
+	collectionIDs := make(map[string]bool)
+	for _, collection := range config.AllCollections() {
+		collectionIDs[collection.GetID()] = true
+	}
+
+	return collectionIDs
+}
+
+// collectionIDOfPath returns the ID of the collection to which the given path belongs (e.g. 'foo'
+// for '/collections/foo/items'). Returns an empty string when the path isn't collection specific.
+func collectionIDOfPath(collectionIDs map[string]bool, path string) string {
+	remainder, ok := strings.CutPrefix(path, collectionsPathPrefix)
+	if !ok {
+		return ""
+	}
+	collectionID, _, _ := strings.Cut(remainder, "/")
+	if !collectionIDs[collectionID] {
+		return ""
+	}
+
+	return collectionID
+}
+
+// newSourceSpec splits off the collection specific paths from the given (merged) spec,
+// when collection IDs are given. Otherwise, the spec is used as-is.
+func newSourceSpec(specJSON []byte, collectionIDs map[string]bool) *sourceSpec {
+	if collectionIDs == nil {
+		return &sourceSpec{main: specJSON}
+	}
+	var spec map[string]any
+	if err := json.Unmarshal(specJSON, &spec); err != nil {
+		log.Fatalf("failed to split OpenAPI spec per collection: %v", err)
+	}
+	paths, _ := spec["paths"].(map[string]any)
+	mainPaths := make(map[string]any)
+	collectionPaths := make(map[string][]string)
+	for path, item := range paths {
+		if collectionID := collectionIDOfPath(collectionIDs, path); collectionID != "" {
+			collectionPaths[collectionID] = append(collectionPaths[collectionID], path)
+		} else {
+			mainPaths[path] = item
+		}
+	}
+	mainSpec := maps.Clone(spec)
+	mainSpec["paths"] = mainPaths
+	pruneComponents(mainSpec)
+	mainJSON, err := json.Marshal(orderByOpenAPIConvention(mainSpec))
+	if err != nil {
+		log.Fatalf("failed to split OpenAPI spec per collection: %v", err)
+	}
+
+	return &sourceSpec{main: mainJSON, full: spec, collectionPaths: collectionPaths}
+}
+
+// collectionSpec returns a standalone spec containing only the paths of the given collection.
+func (s *sourceSpec) collectionSpec(collectionID string) ([]byte, error) {
+	paths, _ := s.full["paths"].(map[string]any)
+	collectionPaths := make(map[string]any)
+	for _, path := range s.collectionPaths[collectionID] {
+		collectionPaths[path] = paths[path]
+	}
+	if len(collectionPaths) == 0 {
+		return nil, ErrCollectionSpecNotFound
+	}
+	spec := maps.Clone(s.full)
+	spec["paths"] = collectionPaths
+	pruneComponents(spec)
+
+	return json.Marshal(orderByOpenAPIConvention(spec))
+}
+
+// withCollectionRefs adds references to the per-collection specs to the given main spec,
+// in place of the paths that have been split off.
+func (s *sourceSpec) withCollectionRefs(specJSON []byte, baseURL string, version OpenAPIVersion) ([]byte, error) {
+	if len(s.collectionPaths) == 0 {
+		return specJSON, nil
+	}
+	var spec map[string]any
+	if err := json.Unmarshal(specJSON, &spec); err != nil {
+		return nil, err
+	}
+	paths, ok := spec["paths"].(map[string]any)
+	if !ok {
+		return nil, errors.New("no paths in OpenAPI spec")
+	}
+	for collectionID, collectionPaths := range s.collectionPaths {
+		specURL := s.collectionSpecURL(baseURL, collectionID, version)
+		for _, path := range collectionPaths {
+			paths[path] = map[string]any{
+				"$ref": specURL + "#/paths/" + url.PathEscape(jsonPointerEscaper.Replace(path)),
+			}
+		}
+	}
+
+	return json.Marshal(orderByOpenAPIConvention(spec))
+}
+
+func (s *sourceSpec) collectionSpecURL(baseURL string, collectionID string, version OpenAPIVersion) string {
+	query := url.Values{}
+	if version == OpenAPI31 {
+		query.Set(FormatParam, FormatOpenAPI31)
+	}
+	if s.lang != "" {
+		query.Set(languageParam, s.lang)
+	}
+	specURL := baseURL + collectionSpecPath + url.PathEscape(collectionID)
+	if len(query) > 0 {
+		specURL += "?" + query.Encode()
+	}
+
+	return specURL
+}
+
+// pruneComponents removes all components which aren't (transitively) referenced from the rest of the spec.
+func pruneComponents(spec map[string]any) {
+	components, ok := spec["components"].(map[string]any)
+	if !ok {
+		return
+	}
+	referenced := make(map[string]map[string]any)
+	var queue []any
+	for key, value := range spec {
+		if key != "components" {
+			queue = append(queue, value)
+		}
+	}
+	for len(queue) > 0 {
+		node := queue[len(queue)-1]
+		queue = queue[:len(queue)-1]
+
+		switch n := node.(type) {
+		case map[string]any:
+			for key, value := range n {
+				ref, isRef := value.(string)
+				if key != "$ref" || !isRef {
+					queue = append(queue, value)
+					continue
+				}
+				componentType, name, ok := parseComponentRef(ref)
+				if !ok || referenced[componentType][name] != nil {
+					continue
+				}
+				componentsOfType, _ := components[componentType].(map[string]any)
+				if component, exists := componentsOfType[name]; exists {
+					if referenced[componentType] == nil {
+						referenced[componentType] = make(map[string]any)
+					}
+					referenced[componentType][name] = component
+					queue = append(queue, component)
+				}
+			}
+		case []any:
+			queue = append(queue, n...)
+		}
+	}
+
+	pruned := make(map[string]any)
+	for componentType, componentsOfType := range components {
+		if componentType == "securitySchemes" {
+			// security schemes are referenced by name, not by $ref
+			pruned[componentType] = componentsOfType
+		} else if len(referenced[componentType]) > 0 {
+			pruned[componentType] = referenced[componentType]
+		}
+	}
+	spec["components"] = pruned
+}
+
+// parseComponentRef parses a local reference like '#/components/schemas/foo' into its type and name.
+func parseComponentRef(ref string) (componentType string, name string, ok bool) {
+	remainder, ok := strings.CutPrefix(ref, "#/components/")
+	if !ok {
+		return "", "", false
+	}
+	componentType, name, ok = strings.Cut(remainder, "/")
+	if !ok {
+		return "", "", false
+	}
+	name = strings.NewReplacer("~1", "/", "~0", "~").Replace(name)
+
+	return componentType, name, true
+}
@@ -1,13 +1,18 @@
 package engine
 
 import (
+	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"path/filepath"
+	"sync"
 	"testing"
 
 	gokoalaconfig "github.com/PDOK/gokoala/config"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/text/language"
 )
 
 func Test_newOpenAPI(t *testing.T) {
@@ -215,3 +220,219 @@ func Test_newOpenAPI(t *testing.T) {
 		})
 	}
 }
+
+func Test_newOpenAPI_ProcessesServer(t *testing.T) {
+	tests := []struct {
+		name            string
+		upstreamSpec    string
+		wantContains    []string
+		wantNotContains []string
+	}{
+		{
+			name: "merge processes and jobs paths of processes server",
+			upstreamSpec: `{
+  "openapi": "3.0.2",
+  "info": {"title": "Upstream", "version": "1.0.0"},
+  "paths": {
+    "/": {"get": {"operationId": "upstreamLandingPage", "responses": {"200": {"description": "landing page"}}}},
+    "/processes": {"get": {"operationId": "upstreamProcesses", "responses": {"200": {"description": "processes"}}}},
+    "/processes/{processId}": {"get": {"operationId": "upstreamProcess",
+      "parameters": [{"$ref": "#/components/parameters/processId"}],
+      "responses": {"200": {"description": "process"}}}},
+    "/jobs": {"get": {"operationId": "upstreamJobs", "responses": {"200": {"description": "jobs"}}}}
+  },
+  "components": {
+    "parameters": {"processId": {"name": "processId", "in": "path", "required": true, "schema": {"type": "string"}}}
+  }
+}`,
+			wantContains: []string{"upstreamProcesses", "upstreamProcess", "upstreamJobs", "Landing page",
+				`"$ref": "#/components/parameters/processesServer.processId"`},
+			wantNotContains: []string{"upstreamLandingPage", "Upstream"},
+		},
+		{
+			name: "prefix components of processes server",
+			upstreamSpec: `{
+  "openapi": "3.0.2",
+  "info": {"title": "Upstream", "version": "1.0.0"},
+  "paths": {
+    "/processes": {"get": {"operationId": "upstreamProcesses",
+      "parameters": [{"$ref": "#/components/parameters/f"}],
+      "security": [{"apiKey": []}],
+      "responses": {"200": {"description": "processes"}}}}
+  },
+  "components": {
+    "parameters": {"f": {"name": "format", "in": "query", "description": "Upstream format", "schema": {"type": "string"}}},
+    "securitySchemes": {"apiKey": {"type": "apiKey", "name": "key", "in": "header"}}
+  }
+}`,
+			wantContains: []string{"upstreamProcesses", `"processesServer.f"`, "Upstream format",
+				`"processesServer.apiKey": []`, "The optional f parameter indicates the output format"},
+		},
+		{
+			name: "skip spec of processes server which conflicts with own spec",
+			upstreamSpec: `{
+  "openapi": "3.0.2",
+  "info": {"title": "Upstream", "version": "1.0.0"},
+  "paths": {
+    "/processes": {"get": {"operationId": "getLandingPage", "responses": {"200": {"description": "processes"}}}}
+  }
+}`,
+			wantContains:    []string{"Landing page"},
+			wantNotContains: []string{"/processes"},
+		},
+		{
+			name: "skip spec of processes server with external references",
+			upstreamSpec: `{
+  "openapi": "3.0.2",
+  "info": {"title": "Upstream", "version": "1.0.0"},
+  "paths": {
+    "/processes": {"get": {"operationId": "upstreamProcesses",
+      "responses": {"200": {"$ref": "https://schemas.opengis.net/ogcapi/processes/part1/1.0/openapi/responses/ProcessList.yaml"}}}}
+  }
+}`,
+			wantContains:    []string{"Landing page"},
+			wantNotContains: []string{"upstreamProcesses"},
+		},
+		{
+			name:            "skip invalid spec of processes server",
+			upstreamSpec:    `not json`,
+			wantContains:    []string{"Landing page"},
+			wantNotContains: []string{"/processes"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/ogcapi/api" {
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+				w.Header().Set(HeaderContentType, MediaTypeJSON)
+				_, _ = w.Write([]byte(tt.upstreamSpec))
+			}))
+			defer upstream.Close()
+			processesServer, err := url.Parse(upstream.URL + "/ogcapi")
+			require.NoError(t, err)
+
+			cfg := &gokoalaconfig.Config{
+				Version:  "2.3.0",
+				Title:    "Test API",
+				Abstract: "Test API description",
+				BaseURL:  gokoalaconfig.URL{URL: &url.URL{Scheme: "https", Host: "api.foobar.example", Path: "/"}},
+				OgcAPI: gokoalaconfig.OgcAPI{
+					Processes: &gokoalaconfig.OgcAPIProcesses{
+						ProcessesServer:        gokoalaconfig.URL{URL: processesServer},
+						ProcessesServerRetries: 1,
+					},
+				},
+			}
+			openAPI := newOpenAPI(cfg, []string{""}, nil)
+			require.NotNil(t, openAPI)
+
+			for _, want := range tt.wantContains {
+				assert.Contains(t, string(openAPI.SpecJSON), want)
+			}
+			for _, notWant := range tt.wantNotContains {
+				assert.NotContains(t, string(openAPI.SpecJSON), notWant)
+			}
+		})
+	}
+}
+
+func Test_newOpenAPI_SplitPerCollection(t *testing.T) {
+	config, err := gokoalaconfig.NewConfig("internal/engine/testdata/config_openapi_split.yaml")
+	require.NoError(t, err)
+
+	openAPI := newOpenAPI(config, []string{""}, nil)
+	require.NotNil(t, openAPI)
+
+	// collection specific paths and components are split off from the main spec
+	assert.Contains(t, string(openAPI.SpecJSON), `"$ref": "http://localhost:8180/api/collections/newyork#/paths/~1collections~1newyork~13dtiles"`)
+	assert.NotContains(t, string(openAPI.SpecJSON), "get3dTileset.newyork")
+	assert.Empty(t, openAPI.generatedSpecs)
+
+	// per-collection specs are generated on first use and cached thereafter
+	specJSON, err := openAPI.CollectionSpecJSON("newyork", language.Dutch, OpenAPI30)
+	require.NoError(t, err)
+	assert.Contains(t, string(specJSON), "get3dTileset.newyork")
+	assert.NotContains(t, string(specJSON), "/collections/newyork-dtm")
+	assert.Len(t, openAPI.generatedSpecs, 1)
+	cachedSpecJSON, err := openAPI.CollectionSpecJSON("newyork", language.English, OpenAPI30)
+	require.NoError(t, err)
+	assert.Equal(t, specJSON, cachedSpecJSON)
+	assert.Len(t, openAPI.generatedSpecs, 1)
+
+	specJSON, err = openAPI.CollectionSpecJSON("newyork", language.Dutch, OpenAPI31)
+	require.NoError(t, err)
+	assert.Contains(t, string(specJSON), `"openapi": "3.1.0"`)
+
+	_, err = openAPI.CollectionSpecJSON("foo", language.Dutch, OpenAPI30)
+	require.ErrorIs(t, err, ErrCollectionSpecNotFound)
+
+	// requests for a collection are validated against the spec of that collection
+	req := httptest.NewRequest(http.MethodGet, "http://localhost:8180/collections/newyork-dtm/quantized-mesh/layer.json", nil)
+	input, err := openAPI.getRequestValidationInput(req)
+	require.NoError(t, err)
+	assert.Equal(t, "getDTM.newyork-dtm", input.Route.Operation.OperationID)
+	require.NoError(t, openAPI.ValidateRequest(req))
+}
+
+func TestOpenAPI_Generate(t *testing.T) {
+	config, err := gokoalaconfig.NewConfig("internal/engine/testdata/config_openapi_split.yaml")
+	require.NoError(t, err)
+	openAPI := newOpenAPI(config, []string{""}, nil)
+	key := generatedSpecKey{lang: language.Und, version: OpenAPI31}
+
+	// failures aren't cached
+	source := openAPI.sourceSpecs[language.Und]
+	mainSpec := source.main
+	source.main = []byte(`{"openapi": "3.0.2"}`)
+	_, err = openAPI.generate(key)
+	require.Error(t, err)
+	assert.Empty(t, openAPI.generatedSpecs)
+	source.main = mainSpec
+
+	// concurrent generation of the same spec results in a single spec
+	specs := make([]*generatedSpec, 10)
+	var wg sync.WaitGroup
+	for i := range specs {
+		wg.Go(func() {
+			specs[i], _ = openAPI.generate(key)
+		})
+	}
+	wg.Wait()
+	require.NotNil(t, specs[0])
+	for _, spec := range specs {
+		assert.Same(t, specs[0], spec)
+	}
+
+	// specs of all collections are generated (and thereby validated) in one go
+	openAPI.validateCollectionSpecs()
+	assert.Len(t, openAPI.generatedSpecs, 1+len(openAPI.collectionIDs))
+	for collectionID := range openAPI.collectionIDs {
+		spec, err := openAPI.generate(generatedSpecKey{collectionID: collectionID, lang: language.Und, version: OpenAPI30})
+		require.NoError(t, err)
+		assert.NotNil(t, spec.router)
+	}
+}
+
+func TestOpenAPI_LocalizedSpecJSONInVersion(t *testing.T) {
+	config, err := gokoalaconfig.NewConfig("internal/engine/testdata/config_translations.yaml")
+	require.NoError(t, err)
+	openAPI := newOpenAPI(config, []string{""}, nil)
+
+	specJSON, err := openAPI.LocalizedSpecJSONInVersion(language.English, OpenAPI30)
+	require.NoError(t, err)
+	assert.Contains(t, string(specJSON), `"openapi": "3.0`)
+	assert.Contains(t, string(specJSON), `"title": "Example"`)
+
+	specJSON, err = openAPI.LocalizedSpecJSONInVersion(language.English, OpenAPI31)
+	require.NoError(t, err)
+	assert.Contains(t, string(specJSON), `"openapi": "3.1.0"`)
+	assert.Contains(t, string(specJSON), `"title": "Example"`)
+	assert.NotContains(t, string(specJSON), `"nullable"`)
+
+	specJSON, err = openAPI.LocalizedSpecJSONInVersion(language.Dutch, OpenAPI31)
+	require.NoError(t, err)
+	assert.Contains(t, string(specJSON), `"title": "Voorbeeld"`)
+}
@@ -27,6 +27,19 @@ var (
 	ProblemBadGateway    = ProblemKind(http.StatusBadGateway)
 )
 
+// The following problems only apply to operations that queue work, these
+// should be added to the specific operations in the OpenAPI spec.
+var (
+	ProblemServiceUnavailable = ProblemKind(http.StatusServiceUnavailable)
+)
+
+// The following problems only apply to operations that modify resources, these
+// should be added to the specific operations in the OpenAPI spec.
+var (
+	ProblemConflict             = ProblemKind(http.StatusConflict)
+	ProblemUnsupportedMediaType = ProblemKind(http.StatusUnsupportedMediaType)
+)
+
 // RenderProblem writes RFC 7807 (https://tools.ietf.org/html/rfc7807) problem to the client.
 // Only the listed problem kinds are supported since they should be advertised in the OpenAPI spec.
 // Optionally, a caller may add details (single string) about the problem. Warning: Be sure to not
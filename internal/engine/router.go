@@ -5,6 +5,7 @@ import (
 	"runtime/debug"
 	"time"
 
+	"github.com/PDOK/gokoala/config"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/cors"
@@ -38,6 +39,22 @@ func newRouter(version string, enableTrailingSlash bool, enableCORS bool) *chi.M
 	return router
 }
 
+// debugConfigSchemaPath serves the JSON Schema of the config file on the debug server
+const debugConfigSchemaPath = "/debug/config/schema.json"
+
+// newDebugRouter router for the debug server, which only binds to localhost
+func newDebugRouter() *chi.Mux {
+	router := chi.NewRouter()
+	router.Use(middleware.Logger)
+	router.Mount("/debug", middleware.Profiler())
+	router.Get(debugConfigSchemaPath, func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set(HeaderContentType, MediaTypeJSONSchema)
+		SafeWrite(w.Write, config.JSONSchema())
+	})
+
+	return router
+}
+
 func optionsFallback(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.Method == http.MethodOptions {
@@ -92,3 +92,22 @@ func TestAbortRecoverer(t *testing.T) {
 	// when
 	r.ServeHTTP(w, req)
 }
+
+func TestDebugRouter_ConfigSchema(t *testing.T) {
+	// given
+	w := httptest.NewRecorder()
+	r := newDebugRouter()
+
+	req, err := http.NewRequest(http.MethodGet, debugConfigSchemaPath, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// when
+	r.ServeHTTP(w, req)
+
+	// then
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, MediaTypeJSONSchema, w.Header().Get(HeaderContentType))
+	assert.Contains(t, w.Body.String(), "\"$schema\": \"https://json-schema.org/draft/2020-12/schema\"")
+}
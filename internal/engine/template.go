@@ -9,6 +9,7 @@ import (
 	"net/url"
 	"path/filepath"
 	"strings"
+	"sync"
 	texttemplate "text/template"
 
 	"github.com/PDOK/gokoala/config"
@@ -67,6 +68,9 @@ type TemplateData struct {
 	// AvailableFormats returns the output formats available for the current page
 	AvailableFormats []OutputFormat
 
+	// Language of the contents of the current page
+	Language language.Tag
+
 	// Request URL
 	url *url.URL
 }
@@ -85,6 +89,18 @@ func (td *TemplateData) QueryString(format string) string {
 	return fmt.Sprintf("?%s=%s", FormatParam, format)
 }
 
+// AlternateLanguages returns the available languages, other than the language of the current page.
+func (td *TemplateData) AlternateLanguages() []language.Tag {
+	var result []language.Tag
+	for _, lang := range td.Config.AvailableLanguages {
+		if lang.Tag != td.Language {
+			result = append(result, lang.Tag)
+		}
+	}
+
+	return result
+}
+
 type Breadcrumb struct {
 	Name string
 	Path string
@@ -158,6 +174,12 @@ type Templates struct {
 
 	config     *config.Config
 	localizers map[language.Tag]i18n.Localizer
+
+	// config per language, localized once on startup since this involves a deep copy
+	localizedConfigs map[language.Tag]*config.Config
+
+	// guards the templates, since these may be (re)rendered after startup
+	mu sync.RWMutex
 }
 
 func newTemplates(config *config.Config, theme *config.Theme) *Templates {
@@ -168,11 +190,32 @@ func newTemplates(config *config.Config, theme *config.Theme) *Templates {
 		Theme:             theme,
 		localizers:        newLocalizers(config.AvailableLanguages),
 	}
+	templates.localizedConfigs = newLocalizedConfigs(config, templates.localizers)
 
 	return templates
 }
 
+func newLocalizedConfigs(cfg *config.Config, localizers map[language.Tag]i18n.Localizer) map[language.Tag]*config.Config {
+	result := make(map[language.Tag]*config.Config, len(localizers))
+	for lang := range localizers {
+		result[lang] = cfg.Localize(lang)
+	}
+
+	return result
+}
+
+// localizedConfig returns the config in the given language, falls back to the config in the default language.
+func (t *Templates) localizedConfig(lang language.Tag) *config.Config {
+	if localized, ok := t.localizedConfigs[lang]; ok {
+		return localized
+	}
+
+	return t.config
+}
+
 func (t *Templates) getParsedTemplate(key TemplateKey) (any, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
 	if parsedTemplate, ok := t.ParsedTemplates[key]; ok {
 		return parsedTemplate, nil
 	}
@@ -181,6 +224,8 @@ func (t *Templates) getParsedTemplate(key TemplateKey) (any, error) {
 }
 
 func (t *Templates) getRenderedTemplate(key TemplateKey) ([]byte, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
 	if RenderedTemplate, ok := t.RenderedTemplates[key]; ok {
 		return RenderedTemplate, nil
 	}
@@ -193,10 +238,10 @@ func (t *Templates) parseAndSaveTemplate(key TemplateKey) {
 		keyWithLang := ExpandTemplateKey(key, lang)
 		if key.Format == FormatHTML {
 			_, parsed := t.parseHTMLTemplate(keyWithLang, lang)
-			t.ParsedTemplates[keyWithLang] = parsed
+			t.saveParsedTemplate(keyWithLang, parsed)
 		} else {
 			_, parsed := t.parseNonHTMLTemplate(keyWithLang, lang)
-			t.ParsedTemplates[keyWithLang] = parsed
+			t.saveParsedTemplate(keyWithLang, parsed)
 		}
 	}
 }
@@ -206,18 +251,26 @@ func (t *Templates) renderAndSaveTemplate(key TemplateKey, breadcrumbs []Breadcr
 		var result []byte
 		if key.Format == FormatHTML {
 			file, parsed := t.parseHTMLTemplate(key, lang)
-			result = t.renderHTMLTemplate(parsed, nil, params, breadcrumbs, file, OutputFormatDefault)
+			result = t.renderHTMLTemplate(parsed, nil, lang, params, breadcrumbs, file, OutputFormatDefault)
 		} else {
 			file, parsed := t.parseNonHTMLTemplate(key, lang)
-			result = t.renderNonHTMLTemplate(parsed, params, key, file)
+			result = t.renderNonHTMLTemplate(parsed, lang, params, key, file)
 		}
 
 		// Store rendered template per language
 		key.Language = lang
+		t.mu.Lock()
 		t.RenderedTemplates[key] = result
+		t.mu.Unlock()
 	}
 }
 
+func (t *Templates) saveParsedTemplate(key TemplateKey, parsed any) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.ParsedTemplates[key] = parsed
+}
+
 func (t *Templates) parseHTMLTemplate(key TemplateKey, lang language.Tag) (string, *htmltemplate.Template) {
 	templateFile := filepath.Clean(filepath.Join(key.Directory, key.Name))
 	templateFuncs := t.createTemplateFuncs(lang)
@@ -233,16 +286,17 @@ func (t *Templates) parseHTMLTemplate(key TemplateKey, lang language.Tag) (strin
 
 	return templateFile, parsed
 }
-func (t *Templates) renderHTMLTemplate(parsed *htmltemplate.Template, url *url.URL,
+func (t *Templates) renderHTMLTemplate(parsed *htmltemplate.Template, url *url.URL, lang language.Tag,
 	params any, breadcrumbs []Breadcrumb, file string, availableFormats []OutputFormat) []byte {
 
 	var rendered bytes.Buffer
 	if err := parsed.Execute(&rendered, &TemplateData{
-		Config:           t.config,
+		Config:           t.localizedConfig(lang),
 		Theme:            t.Theme,
 		Params:           params,
 		Breadcrumbs:      breadcrumbs,
 		AvailableFormats: availableFormats,
+		Language:         lang,
 		url:              url,
 	}); err != nil {
 		log.Fatalf("failed to execute HTML template %s, error: %v", file, err)
@@ -272,11 +326,14 @@ func (t *Templates) parseNonHTMLTemplate(key TemplateKey, lang language.Tag) (st
 	return templateFile, parsed
 }
 
-func (t *Templates) renderNonHTMLTemplate(parsed *texttemplate.Template, params any, key TemplateKey, file string) []byte {
+func (t *Templates) renderNonHTMLTemplate(parsed *texttemplate.Template, lang language.Tag,
+	params any, key TemplateKey, file string) []byte {
+
 	var rendered bytes.Buffer
 	if err := parsed.Execute(&rendered, &TemplateData{
-		Config: t.config,
-		Params: params,
+		Config:   t.localizedConfig(lang),
+		Params:   params,
+		Language: lang,
 	}); err != nil {
 		log.Fatalf("failed to execute template %s, error: %v", file, err)
 	}
@@ -299,5 +356,17 @@ func (t *Templates) createTemplateFuncs(lang language.Tag) map[string]any {
 
 			return htmltemplate.HTML(translated) //nolint:gosec // since we trust our language files
 		},
+		// translate metadata (of collections, styles) provided through params. Metadata accessed
+		// through the config is already translated, since the config is localized per language.
+		"localize": func(metadata any) any {
+			switch m := metadata.(type) {
+			case *config.GeoSpatialCollectionMetadata:
+				return m.Localize(lang)
+			case config.Style:
+				return m.Localize(lang)
+			}
+
+			return metadata
+		},
 	})
 }
@@ -3,7 +3,9 @@ package engine
 import (
 	"testing"
 
+	"github.com/PDOK/gokoala/config"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"golang.org/x/text/language"
 )
 
@@ -91,3 +93,16 @@ func TestTemplateKeyWithOptions(t *testing.T) {
 		})
 	}
 }
+
+func TestTemplates_LocalizedConfig(t *testing.T) {
+	cfg, err := config.NewConfig("internal/engine/testdata/config_translations.yaml")
+	require.NoError(t, err)
+	templates := newTemplates(cfg, nil)
+
+	assert.Same(t, cfg, templates.localizedConfig(language.Dutch))
+	assert.Same(t, cfg, templates.localizedConfig(language.German), "fall back to default language")
+	english := templates.localizedConfig(language.English)
+	assert.Equal(t, "Example", english.Title)
+	assert.Same(t, english, templates.localizedConfig(language.English), "localized once")
+	assert.Equal(t, "Voorbeeld", cfg.Title)
+}
@@ -0,0 +1,24 @@
+package util
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+)
+
+// IsGzipped whether the given data is gzip compressed, based on the magic number.
+func IsGzipped(data []byte) bool {
+	return len(data) > 2 && data[0] == 0x1f && data[1] == 0x8b
+}
+
+// Gunzip decompresses the given gzip compressed data.
+func Gunzip(data []byte) ([]byte, error) {
+	reader, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress: %w", err)
+	}
+	defer reader.Close()
+
+	return io.ReadAll(reader)
+}
@@ -0,0 +1,29 @@
+package util
+
+import (
+	"bytes"
+	"compress/gzip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGzip(t *testing.T) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	_, err := gz.Write([]byte("foo"))
+	require.NoError(t, err)
+	require.NoError(t, gz.Close())
+
+	assert.True(t, IsGzipped(buf.Bytes()))
+	assert.False(t, IsGzipped([]byte("foo")))
+	assert.False(t, IsGzipped(nil))
+
+	data, err := Gunzip(buf.Bytes())
+	require.NoError(t, err)
+	assert.Equal(t, "foo", string(data))
+
+	_, err = Gunzip([]byte("foo"))
+	require.ErrorContains(t, err, "failed to decompress")
+}
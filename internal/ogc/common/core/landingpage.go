@@ -0,0 +1,17 @@
+package core
+
+// LandingPage content of the landing page provided by other building blocks.
+type LandingPage struct {
+	// Processes offered through OGC API Processes
+	Processes []Process
+}
+
+// Process summary of a process, as listed on the landing page.
+type Process struct {
+	ID          string
+	Title       string
+	Description string
+
+	// IDs of the collections of this API referred to by the inputs of the process
+	Collections []string
+}
@@ -1,15 +1,19 @@
 package core
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/PDOK/gokoala/internal/engine"
+	"github.com/go-chi/chi/v5"
+	"golang.org/x/text/language"
 )
 
 const (
 	templatesDir       = "internal/ogc/common/core/templates/"
 	rootPath           = "/"
 	apiPath            = "/api"
+	collectionAPIPath  = apiPath + "/collections/{collectionId}"
 	alternativeAPIPath = "/openapi.json"
 	conformancePath    = "/conformance"
 )
@@ -22,7 +26,7 @@ type CommonCore struct {
 	engine *engine.Engine
 }
 
-func NewCommonCore(e *engine.Engine, extraConformanceClasses ExtraConformanceClasses) *CommonCore {
+func NewCommonCore(e *engine.Engine, extraConformanceClasses ExtraConformanceClasses, landingPage LandingPage) *CommonCore {
 	conformanceBreadcrumbs := []engine.Breadcrumb{
 		{
 			Name: "Conformance",
@@ -36,7 +40,8 @@ func NewCommonCore(e *engine.Engine, extraConformanceClasses ExtraConformanceCla
 		},
 	}
 
-	e.RenderTemplates(rootPath,
+	e.RenderTemplatesWithParams(rootPath,
+		landingPage,
 		nil,
 		engine.NewTemplateKey(templatesDir+"landing-page.go.json"),
 		engine.NewTemplateKey(templatesDir+"landing-page.go.html"))
@@ -55,7 +60,8 @@ func NewCommonCore(e *engine.Engine, extraConformanceClasses ExtraConformanceCla
 	e.Router.Get(rootPath, core.LandingPage())
 	e.Router.Get(apiPath, core.API())
 	// implements https://gitdocumentatie.logius.nl/publicatie/api/adr/#api-17
-	e.Router.Get(alternativeAPIPath, func(w http.ResponseWriter, r *http.Request) { core.apiAsJSON(w, r) })
+	e.Router.Get(alternativeAPIPath, func(w http.ResponseWriter, r *http.Request) { core.apiAsJSON(w, r, engine.OpenAPI30) })
+	e.Router.Get(collectionAPIPath, core.CollectionAPI())
 	e.Router.Get(conformancePath, core.Conformance())
 	e.Router.Handle("/*", http.FileServer(http.Dir("assets")))
 
@@ -86,18 +92,60 @@ func (c *CommonCore) API() http.HandlerFunc {
 			c.apiAsHTML(w, r)
 			return
 		case engine.FormatJSON:
-			c.apiAsJSON(w, r)
+			c.apiAsJSON(w, r, engine.OpenAPI30)
+			return
+		case engine.FormatOpenAPI31:
+			c.apiAsJSON(w, r, engine.OpenAPI31)
 			return
 		}
 		engine.RenderProblem(engine.ProblemNotFound, w)
 	}
 }
 
+// CollectionAPI serves the OpenAPI spec of a single collection, only available
+// when the OpenAPI spec is split per collection.
+func (c *CommonCore) CollectionAPI() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		version := engine.OpenAPI30
+		format := c.engine.CN.NegotiateFormat(r)
+		if format == engine.FormatOpenAPI31 {
+			version = engine.OpenAPI31
+		} else if format != engine.FormatJSON {
+			engine.RenderProblem(engine.ProblemNotFound, w)
+			return
+		}
+		lang := c.engine.CN.NegotiateLanguage(w, r)
+		specJSON, err := c.engine.OpenAPI.CollectionSpecJSON(chi.URLParam(r, "collectionId"), lang, version)
+		c.serveSpec(w, r, lang, version, specJSON, err)
+	}
+}
+
 func (c *CommonCore) apiAsHTML(w http.ResponseWriter, r *http.Request) {
 	key := engine.NewTemplateKey(templatesDir+"api.go.html", c.engine.WithNegotiatedLanguage(w, r))
 	c.engine.Serve(w, r, engine.ServeTemplate(key))
 }
 
-func (c *CommonCore) apiAsJSON(w http.ResponseWriter, r *http.Request) {
-	c.engine.Serve(w, r, engine.ServeContentType(engine.MediaTypeOpenAPI), engine.ServePreRenderedOutput(c.engine.OpenAPI.SpecJSON))
+func (c *CommonCore) apiAsJSON(w http.ResponseWriter, r *http.Request, version engine.OpenAPIVersion) {
+	lang := c.engine.CN.NegotiateLanguage(w, r)
+	specJSON, err := c.engine.OpenAPI.LocalizedSpecJSONInVersion(lang, version)
+	c.serveSpec(w, r, lang, version, specJSON, err)
+}
+
+func (c *CommonCore) serveSpec(w http.ResponseWriter, r *http.Request, lang language.Tag,
+	version engine.OpenAPIVersion, specJSON []byte, err error) {
+
+	if err != nil {
+		if errors.Is(err, engine.ErrCollectionSpecNotFound) {
+			engine.RenderProblem(engine.ProblemNotFound, w)
+		} else {
+			engine.RenderProblem(engine.ProblemServerError, w)
+		}
+		return
+	}
+	contentType := engine.MediaTypeOpenAPI
+	if version == engine.OpenAPI31 {
+		contentType = engine.MediaTypeOpenAPI31
+	}
+	c.engine.Serve(w, r, engine.ServeContentType(contentType), engine.ServeContentLanguage(lang),
+		engine.ServePreRenderedOutput(specJSON))
 }
@@ -98,7 +98,7 @@ func TestCommonCore_LandingPage(t *testing.T) {
 
 			newEngine, err := engine.NewEngine(tt.fields.configFile, "internal/engine/testdata/test_theme.yaml", "", false, true)
 			require.NoError(t, err)
-			core := NewCommonCore(newEngine, ExtraConformanceClasses{})
+			core := NewCommonCore(newEngine, ExtraConformanceClasses{}, LandingPage{})
 			handler := core.LandingPage()
 			handler.ServeHTTP(rr, req)
 
@@ -159,7 +159,7 @@ func TestCommonCore_Conformance(t *testing.T) {
 
 			newEngine, err := engine.NewEngine(tt.fields.configFile, "internal/engine/testdata/test_theme.yaml", "", false, true)
 			require.NoError(t, err)
-			core := NewCommonCore(newEngine, ExtraConformanceClasses{})
+			core := NewCommonCore(newEngine, ExtraConformanceClasses{}, LandingPage{})
 			handler := core.Conformance()
 			handler.ServeHTTP(rr, req)
 
@@ -194,6 +194,17 @@ func TestCommonCore_API(t *testing.T) {
 				statusCode: http.StatusOK,
 			},
 		},
+		{
+			name: "OpenAPI 3.1 as JSON",
+			fields: fields{
+				configFile: "internal/engine/testdata/config_multiple_ogc_apis_single_collection.yaml",
+				url:        "http://localhost:8080/api?f=openapi31",
+			},
+			want: want{
+				body:       "\"openapi\": \"3.1.0\"",
+				statusCode: http.StatusOK,
+			},
+		},
 		{
 			name: "OpenAPI as HTML",
 			fields: fields{
@@ -217,7 +228,7 @@ func TestCommonCore_API(t *testing.T) {
 
 			newEngine, err := engine.NewEngine(tt.fields.configFile, "internal/engine/testdata/test_theme.yaml", "", false, true)
 			require.NoError(t, err)
-			core := NewCommonCore(newEngine, ExtraConformanceClasses{})
+			core := NewCommonCore(newEngine, ExtraConformanceClasses{}, LandingPage{})
 			handler := core.API()
 			handler.ServeHTTP(rr, req)
 
@@ -229,6 +240,140 @@ func TestCommonCore_API(t *testing.T) {
 	}
 }
 
+func TestCommonCore_Translations(t *testing.T) {
+	tests := []struct {
+		name         string
+		url          string
+		wantLanguage string
+		wantBody     []string
+	}{
+		{
+			name:         "landing page as JSON in default language",
+			url:          "http://localhost:8080/?f=json",
+			wantLanguage: "nl",
+			wantBody:     []string{`"title": "Voorbeeld"`, `"hreflang": "nl"`, `"href": "http://localhost:8080?f=json&lang=en"`},
+		},
+		{
+			name:         "landing page as JSON in english",
+			url:          "http://localhost:8080/?f=json&lang=en",
+			wantLanguage: "en",
+			wantBody:     []string{`"title": "Example"`, `"description": "This is an example API"`, `"hreflang": "en"`, `"hreflang": "nl"`},
+		},
+		{
+			name:         "landing page as HTML in english",
+			url:          "http://localhost:8080/?f=html&lang=en",
+			wantLanguage: "en",
+			wantBody:     []string{`<html lang="en"`, "<title>Example (OGC API)</title>"},
+		},
+		{
+			name:         "OpenAPI as JSON in english",
+			url:          "http://localhost:8080/api?f=json&lang=en",
+			wantLanguage: "en",
+			wantBody:     []string{`"title": "Example"`},
+		},
+	}
+	newEngine, err := engine.NewEngine("internal/engine/testdata/config_translations.yaml", "internal/engine/testdata/test_theme.yaml", "", false, true)
+	require.NoError(t, err)
+	NewCommonCore(newEngine, ExtraConformanceClasses{}, LandingPage{})
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, tt.url, nil)
+			require.NoError(t, err)
+			rr := httptest.NewRecorder()
+			newEngine.Router.ServeHTTP(rr, req)
+
+			assert.Equal(t, http.StatusOK, rr.Code)
+			assert.Equal(t, tt.wantLanguage, rr.Header().Get(engine.HeaderContentLanguage))
+			for _, body := range tt.wantBody {
+				assert.Contains(t, rr.Body.String(), body)
+			}
+		})
+	}
+}
+
+func TestCommonCore_CollectionAPI(t *testing.T) {
+	tests := []struct {
+		name            string
+		url             string
+		accept          string
+		wantStatusCode  int
+		wantContentType string
+		wantBody        []string
+		wantNotInBody   []string
+	}{
+		{
+			name:            "main OpenAPI spec references per-collection specs",
+			url:             "http://localhost:8180/api?f=json",
+			wantStatusCode:  http.StatusOK,
+			wantContentType: engine.MediaTypeOpenAPI,
+			wantBody: []string{
+				`"/conformance": {`,
+				`"$ref": "http://localhost:8180/api/collections/newyork#/paths/~1collections~1newyork~13dtiles"`,
+				`"$ref": "http://localhost:8180/api/collections/newyork-dtm#/paths/~1collections~1newyork-dtm~1quantized-mesh~1%7Bpath%7D"`,
+			},
+			wantNotInBody: []string{`"operationId": "get3dTileset.newyork"`},
+		},
+		{
+			name:            "main OpenAPI 3.1 spec references per-collection 3.1 specs",
+			url:             "http://localhost:8180/api",
+			accept:          engine.MediaTypeOpenAPI31,
+			wantStatusCode:  http.StatusOK,
+			wantContentType: engine.MediaTypeOpenAPI31,
+			wantBody: []string{
+				`"openapi": "3.1.0"`,
+				`"$ref": "http://localhost:8180/api/collections/newyork?f=openapi31#/paths/~1collections~1newyork~13dtiles"`,
+			},
+		},
+		{
+			name:            "OpenAPI spec of single collection",
+			url:             "http://localhost:8180/api/collections/newyork?f=json",
+			wantStatusCode:  http.StatusOK,
+			wantContentType: engine.MediaTypeOpenAPI,
+			wantBody:        []string{`"/collections/newyork/3dtiles": {`, `"operationId": "get3dTileset.newyork"`},
+			wantNotInBody:   []string{`"/conformance"`, `"/collections/newyork-dtm`},
+		},
+		{
+			name:            "OpenAPI 3.1 spec of single collection",
+			url:             "http://localhost:8180/api/collections/newyork-dtm?f=openapi31",
+			wantStatusCode:  http.StatusOK,
+			wantContentType: engine.MediaTypeOpenAPI31,
+			wantBody:        []string{`"openapi": "3.1.0"`, `"/collections/newyork-dtm/quantized-mesh/{path}": {`},
+		},
+		{
+			name:           "OpenAPI spec of unknown collection",
+			url:            "http://localhost:8180/api/collections/foo",
+			wantStatusCode: http.StatusNotFound,
+		},
+	}
+	newEngine, err := engine.NewEngine("internal/engine/testdata/config_openapi_split.yaml", "internal/engine/testdata/test_theme.yaml", "", false, true)
+	require.NoError(t, err)
+	NewCommonCore(newEngine, ExtraConformanceClasses{}, LandingPage{})
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, tt.url, nil)
+			require.NoError(t, err)
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			rr := httptest.NewRecorder()
+			newEngine.Router.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.wantStatusCode, rr.Code)
+			if tt.wantContentType != "" {
+				assert.Equal(t, tt.wantContentType, rr.Header().Get(engine.HeaderContentType))
+			}
+			for _, body := range tt.wantBody {
+				assert.Contains(t, rr.Body.String(), body)
+			}
+			for _, body := range tt.wantNotInBody {
+				assert.NotContains(t, rr.Body.String(), body)
+			}
+		})
+	}
+}
+
 func createMockServer() (*httptest.ResponseRecorder, *httptest.Server) {
 	rr := httptest.NewRecorder()
 	l, err := net.Listen("tcp", "localhost:0")
@@ -0,0 +1,122 @@
+package geospatial
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PDOK/gokoala/config"
+)
+
+const (
+	bboxParam     = "bbox"
+	dateTimeParam = "datetime"
+
+	intervalSeparator   = "/"
+	intervalHalfBounded = ".."
+)
+
+// collectionsFilter spatial and/or temporal filter on the 3D containers in the list of collections,
+// see https://docs.ogc.org/DRAFTS/22-029.html (OGC API 3D GeoVolumes).
+type collectionsFilter struct {
+	// bbox in CRS84 (4 values) or CRS84h (6 values), crosses the antimeridian when min lon > max lon
+	bbox []float64
+
+	// start and end of datetime interval (inclusive), nil when open-ended
+	start *time.Time
+	end   *time.Time
+}
+
+// parseCollectionsFilter parses the bbox and datetime params, returns nil when neither is present.
+func parseCollectionsFilter(params url.Values) (*collectionsFilter, error) {
+	bbox := params.Get(bboxParam)
+	datetime := params.Get(dateTimeParam)
+	if bbox == "" && datetime == "" {
+		return nil, nil //nolint:nilnil
+	}
+
+	filter := &collectionsFilter{}
+	if bbox != "" {
+		parts := strings.Split(bbox, ",")
+		if len(parts) != 4 && len(parts) != 6 {
+			return nil, errors.New("bbox should contain exactly 4 or 6 values separated by commas")
+		}
+		for _, part := range parts {
+			value, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid value in bbox: %w", err)
+			}
+			filter.bbox = append(filter.bbox, value)
+		}
+		// min lon may exceed max lon, for a bbox crossing the antimeridian
+		_, minY, _, maxY := bounds2D(filter.bbox)
+		if minY > maxY || (len(filter.bbox) == 6 && filter.bbox[2] > filter.bbox[5]) {
+			return nil, errors.New("bbox lower corner should be below the upper corner")
+		}
+	}
+	if datetime != "" {
+		var err error
+		if strings.Contains(datetime, intervalSeparator) {
+			parts := strings.SplitN(datetime, intervalSeparator, 2)
+			if filter.start, err = parseIntervalPart(parts[0]); err != nil {
+				return nil, err
+			}
+			if filter.end, err = parseIntervalPart(parts[1]); err != nil {
+				return nil, err
+			}
+		} else {
+			instant, err := time.Parse(time.RFC3339, datetime)
+			if err != nil {
+				return nil, fmt.Errorf("invalid datetime %q: %w", datetime, err)
+			}
+			filter.start, filter.end = &instant, &instant
+		}
+	}
+
+	return filter, nil
+}
+
+func parseIntervalPart(part string) (*time.Time, error) {
+	if part == "" || part == intervalHalfBounded {
+		return nil, nil //nolint:nilnil
+	}
+	t, err := time.Parse(time.RFC3339, part)
+	if err != nil {
+		return nil, fmt.Errorf("invalid datetime interval %q: %w", part, err)
+	}
+
+	return &t, nil
+}
+
+// matches whether the given collection should be listed. Only 3D containers are filtered, when the
+// (spatial or temporal) extent of a 3D container is unknown it's listed regardless of the filter.
+func (f *collectionsFilter) matches(coll config.GeoSpatialCollection, volume *GeoVolume) bool {
+	if volume == nil {
+		return true
+	}
+	if !volume.intersects(f.bbox) {
+		return false
+	}
+	if f.start == nil && f.end == nil {
+		return true
+	}
+	metadata := coll.GetMetadata()
+	if metadata == nil || metadata.Extent == nil || metadata.Extent.Interval == nil {
+		return true
+	}
+	intervalStart, intervalEnd, err := metadata.Extent.ParseInterval()
+	if err != nil {
+		return true // can't happen, since the interval is validated on startup
+	}
+	if f.end != nil && intervalStart != nil && intervalStart.After(*f.end) {
+		return false
+	}
+	if f.start != nil && intervalEnd != nil && intervalEnd.Before(*f.start) {
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,84 @@
+package geospatial
+
+// GeoVolume describes the 3D space occupied by a 3D container (a collection in OGC API 3D GeoVolumes).
+type GeoVolume struct {
+	// Region bounding volume as defined in 3D Tiles: west, south, east, north (in radians) and
+	// min and max height (in meters). Nil when the bounding volume isn't a region.
+	Region []float64
+
+	// Box bounding volume as defined in 3D Tiles: center followed by the x, y and z half-axes
+	// in earth-centered, earth-fixed coordinates. Nil when the bounding volume isn't a box.
+	Box []float64
+
+	// Bbox extent in CRS84 (4 values) or, when the heights are known, CRS84h (6 values: min lon,
+	// min lat, min height, max lon, max lat, max height). Nil when unknown.
+	Bbox []float64
+
+	// Children IDs of 3D containers nested in this 3D container
+	Children []string
+}
+
+// Is3D whether the bbox includes heights.
+func (g GeoVolume) Is3D() bool {
+	return len(g.Bbox) == 6
+}
+
+// intersects whether the extent of this 3D container intersects with the given bbox (in CRS84
+// or CRS84h). When the extent is unknown the 3D container is considered to intersect.
+func (g GeoVolume) intersects(bbox []float64) bool {
+	if len(g.Bbox) == 0 || len(bbox) == 0 {
+		return true
+	}
+	minX, minY, maxX, maxY := bounds2D(g.Bbox)
+	bboxMinX, bboxMinY, bboxMaxX, bboxMaxY := bounds2D(bbox)
+	if minY > bboxMaxY || maxY < bboxMinY || !overlapsX(minX, maxX, bboxMinX, bboxMaxX) {
+		return false
+	}
+	if g.Is3D() && len(bbox) == 6 {
+		return g.Bbox[2] <= bbox[5] && g.Bbox[5] >= bbox[2]
+	}
+
+	return true
+}
+
+// overlapsX whether the longitude ranges of two bboxes overlap. A bbox crossing the antimeridian
+// has a minX larger than its maxX (see OGC API Common), this range is split at the antimeridian.
+func overlapsX(minX, maxX, otherMinX, otherMaxX float64) bool {
+	for _, r := range rangesX(minX, maxX) {
+		for _, other := range rangesX(otherMinX, otherMaxX) {
+			if r[0] <= other[1] && r[1] >= other[0] {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func rangesX(minX, maxX float64) [][2]float64 {
+	if minX > maxX {
+		return [][2]float64{{minX, 180}, {-180, maxX}}
+	}
+
+	return [][2]float64{{minX, maxX}}
+}
+
+func bounds2D(bbox []float64) (minX, minY, maxX, maxY float64) {
+	if len(bbox) == 6 {
+		return bbox[0], bbox[1], bbox[3], bbox[4]
+	}
+
+	return bbox[0], bbox[1], bbox[2], bbox[3]
+}
+
+// GeoVolumes 3D containers by collection ID.
+type GeoVolumes map[string]GeoVolume
+
+// Get the GeoVolume of the given collection, nil when the collection isn't a 3D container.
+func (g GeoVolumes) Get(collectionID string) *GeoVolume {
+	if volume, ok := g[collectionID]; ok {
+		return &volume
+	}
+
+	return nil
+}
@@ -13,8 +13,24 @@ const (
 	templatesDir    = "internal/ogc/common/geospatial/templates/"
 )
 
+var collectionsBreadcrumbs = []engine.Breadcrumb{
+	{
+		Name: "Collections",
+		Path: "collections",
+	},
+}
+
 type Collections struct {
-	engine *engine.Engine
+	engine     *engine.Engine
+	types      CollectionTypes
+	geoVolumes GeoVolumes
+}
+
+// Wrapper around collections+types to make it easier to access in the "collections" template.
+type collectionsWithTypes struct {
+	Collections []config.GeoSpatialCollection
+	Types       CollectionTypes
+	GeoVolumes  GeoVolumes
 }
 
 // Wrapper around collection+type to make it easier to access in the "collection" template.
@@ -22,25 +38,26 @@ type collectionWithType struct {
 	Collection any
 	Type       CollectionType
 	GeomType   string
+	GeoVolume  *GeoVolume
 
 	CQLEnabled bool
 }
 
 // NewCollections enables support for OGC APIs that organize data in the concept of collections.
 // A collection, also known as a geospatial data resource, is a common way to organize data in various OGC APIs.
-func NewCollections(e *engine.Engine, types CollectionTypes) *Collections {
+// The given GeoVolumes describe the 3D containers among the collections, when OGC API 3D GeoVolumes is enabled.
+func NewCollections(e *engine.Engine, types CollectionTypes, geoVolumes GeoVolumes) *Collections {
 	if e.Config.HasCollections() {
-		collectionsBreadcrumbs := []engine.Breadcrumb{
-			{
-				Name: "Collections",
-				Path: "collections",
-			},
-		}
 		e.RenderTemplatesWithParams(CollectionsPath,
-			types,
+			collectionsWithTypes{e.Config.AllCollections().Unique(), types, geoVolumes},
 			collectionsBreadcrumbs,
 			engine.NewTemplateKey(templatesDir+"collections.go.json"),
 			engine.NewTemplateKey(templatesDir+"collections.go.html"))
+		if geoVolumes != nil {
+			// 3D containers can be filtered, in which case the list of collections is rendered on-the-fly
+			e.ParseTemplate(engine.NewTemplateKey(templatesDir + "collections.go.json"))
+			e.ParseTemplate(engine.NewTemplateKey(templatesDir + "collections.go.html"))
+		}
 
 		for _, coll := range e.Config.AllCollections().Unique() {
 			title := coll.GetID()
@@ -61,6 +78,7 @@ func NewCollections(e *engine.Engine, types CollectionTypes) *Collections {
 				coll,
 				types.GetCollectionType(coll.GetID()),
 				types.GetGeometryType(coll.GetID()),
+				geoVolumes.Get(coll.GetID()),
 				cqlEnabled,
 			}
 
@@ -72,7 +90,9 @@ func NewCollections(e *engine.Engine, types CollectionTypes) *Collections {
 	}
 
 	instance := &Collections{
-		engine: e,
+		engine:     e,
+		types:      types,
+		geoVolumes: geoVolumes,
 	}
 
 	e.Router.Get(CollectionsPath, instance.Collections())
@@ -81,11 +101,31 @@ func NewCollections(e *engine.Engine, types CollectionTypes) *Collections {
 	return instance
 }
 
-// Collections returns list of collections.
+// Collections returns list of collections. The 3D containers in this list can be filtered by bbox and/or datetime.
 func (c *Collections) Collections() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		key := engine.NewTemplateKey(templatesDir+"collections.go."+c.engine.CN.NegotiateFormat(r), c.engine.WithNegotiatedLanguage(w, r))
-		c.engine.Serve(w, r, engine.ServeTemplate(key))
+		if c.geoVolumes == nil {
+			c.engine.Serve(w, r, engine.ServeTemplate(key))
+			return
+		}
+		filter, err := parseCollectionsFilter(r.URL.Query())
+		if err != nil {
+			engine.RenderProblem(engine.ProblemBadRequest, w, err.Error())
+			return
+		}
+		if filter == nil {
+			c.engine.Serve(w, r, engine.ServeTemplate(key))
+			return
+		}
+		var collections []config.GeoSpatialCollection
+		for _, coll := range c.engine.Config.AllCollections().Unique() {
+			if filter.matches(coll, c.geoVolumes.Get(coll.GetID())) {
+				collections = append(collections, coll)
+			}
+		}
+		c.engine.RenderAndServe(w, r, key, collectionsWithTypes{collections, c.types, c.geoVolumes},
+			collectionsBreadcrumbs, engine.OutputFormatDefault)
 	}
 }
 
@@ -10,6 +10,7 @@ import (
 	"os"
 	"path"
 	"runtime"
+	"strings"
 	"testing"
 
 	"github.com/PDOK/gokoala/config"
@@ -66,7 +67,7 @@ func TestNewCollections(t *testing.T) {
 	}
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			collections := NewCollections(test.args.e, NewCollectionTypes(nil, nil))
+			collections := NewCollections(test.args.e, NewCollectionTypes(nil, nil), nil)
 			assert.NotEmpty(t, collections.engine.Templates.RenderedTemplates)
 		})
 	}
@@ -179,7 +180,7 @@ func TestNewCollections_Collections(t *testing.T) {
 
 			newEngine, err := engine.NewEngine(tt.fields.configFile, "internal/engine/testdata/test_theme.yaml", "", false, true)
 			require.NoError(t, err)
-			collections := NewCollections(newEngine, NewCollectionTypes(nil, nil))
+			collections := NewCollections(newEngine, NewCollectionTypes(nil, nil), nil)
 			handler := collections.Collections()
 			handler.ServeHTTP(rr, req)
 
@@ -255,7 +256,7 @@ func TestNewCollections_Collection(t *testing.T) {
 
 			newEngine, err := engine.NewEngine(tt.fields.configFile, "internal/engine/testdata/test_theme.yaml", "", false, true)
 			require.NoError(t, err)
-			collections := NewCollections(newEngine, NewCollectionTypes(nil, nil))
+			collections := NewCollections(newEngine, NewCollectionTypes(nil, nil), nil)
 			handler := collections.Collection()
 			handler.ServeHTTP(rr, req)
 
@@ -265,6 +266,151 @@ func TestNewCollections_Collection(t *testing.T) {
 	}
 }
 
+func TestNewCollections_GeoVolumes(t *testing.T) {
+	geoVolumes := GeoVolumes{
+		"container_1": GeoVolume{
+			Region:   []float64{0.0872, 0.9075, 0.0873, 0.9076, -5, 120},
+			Bbox:     []float64{4.996, 51.996, -5, 5.002, 52.002, 120},
+			Children: []string{"container_2"},
+		},
+		"container_2": GeoVolume{
+			Bbox: []float64{5.5, 52.5, 6, 53},
+		},
+	}
+	tests := []struct {
+		name            string
+		url             string
+		containerID     string
+		wantStatusCode  int
+		wantContains    []string
+		wantNotContains []string
+	}{
+		{
+			name:           "all 3D containers without filter",
+			url:            "http://localhost:8080/collections?f=json",
+			wantStatusCode: http.StatusOK,
+			wantContains:   []string{`"title": "container_1"`, `"title": "container_2"`},
+		},
+		{
+			name:            "filter by bbox",
+			url:             "http://localhost:8080/collections?f=json&bbox=4.9,51.9,5.1,52.1",
+			wantStatusCode:  http.StatusOK,
+			wantContains:    []string{`"title": "container_1"`},
+			wantNotContains: []string{`"title": "container_2"`},
+		},
+		{
+			name:            "filter by 3D bbox",
+			url:             "http://localhost:8080/collections?f=json&bbox=4.9,51.9,200,5.1,52.1,300",
+			wantStatusCode:  http.StatusOK,
+			wantNotContains: []string{`"title": "container_1"`, `"title": "container_2"`},
+		},
+		{
+			name:            "filter by bbox in HTML",
+			url:             "http://localhost:8080/collections?f=html&bbox=5.6,52.6,5.7,52.7",
+			wantStatusCode:  http.StatusOK,
+			wantContains:    []string{`href="http://localhost:8080/collections/container_2"`},
+			wantNotContains: []string{`href="http://localhost:8080/collections/container_1"`},
+		},
+		{
+			name:           "invalid bbox",
+			url:            "http://localhost:8080/collections?f=json&bbox=5.6,52.6,5.7",
+			wantStatusCode: http.StatusBadRequest,
+			wantContains:   []string{"bbox should contain exactly 4 or 6 values"},
+		},
+		{
+			name:           "invalid bbox with lower corner above upper corner",
+			url:            "http://localhost:8080/collections?f=json&bbox=5.6,52.7,5.7,52.6",
+			wantStatusCode: http.StatusBadRequest,
+			wantContains:   []string{"bbox lower corner should be below the upper corner"},
+		},
+		{
+			name:            "filter by bbox crossing the antimeridian",
+			url:             "http://localhost:8080/collections?f=json&bbox=170,51.9,5.1,52.1",
+			wantStatusCode:  http.StatusOK,
+			wantContains:    []string{`"title": "container_1"`},
+			wantNotContains: []string{`"title": "container_2"`},
+		},
+		{
+			name:           "invalid datetime",
+			url:            "http://localhost:8080/collections?f=json&datetime=yesterday",
+			wantStatusCode: http.StatusBadRequest,
+			wantContains:   []string{"invalid datetime"},
+		},
+		{
+			name:           "bounding volume and children of collection",
+			url:            "http://localhost:8080/collections/:collectionId?f=json",
+			containerID:    "container_1",
+			wantStatusCode: http.StatusOK,
+			wantContains: []string{
+				`"crs": "http://www.opengis.net/def/crs/OGC/0/CRS84h"`,
+				`"boundingVolume": {"region": [0.0872, 0.9075, 0.0873, 0.9076, -5, 120]}`,
+				`"children": [{"id": "container_2", "collectionType": "3d-container"`,
+				`"href": "http://localhost:8080/collections/container_2?f=json"`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			newEngine, err := engine.NewEngine("internal/ogc/geovolumes/testdata/config_minimal_3d.yaml",
+				"internal/engine/testdata/test_theme.yaml", "", false, true)
+			require.NoError(t, err)
+			collections := NewCollections(newEngine, NewCollectionTypes(nil, nil), geoVolumes)
+
+			rr := httptest.NewRecorder()
+			if tt.containerID != "" {
+				req, err := createCollectionRequest(tt.url, tt.containerID)
+				require.NoError(t, err)
+				collections.Collection().ServeHTTP(rr, req)
+			} else {
+				req, err := createCollectionsRequest(tt.url)
+				require.NoError(t, err)
+				collections.Collections().ServeHTTP(rr, req)
+			}
+
+			assert.Equal(t, tt.wantStatusCode, rr.Code)
+			body := withoutWhitespace(rr.Body.String())
+			for _, want := range tt.wantContains {
+				assert.Contains(t, body, withoutWhitespace(want))
+			}
+			for _, notWant := range tt.wantNotContains {
+				assert.NotContains(t, body, withoutWhitespace(notWant))
+			}
+		})
+	}
+}
+
+func TestCollectionsFilter_Matches(t *testing.T) {
+	coll := config.GeoVolumesCollection{ID: "container_1", Metadata: &config.GeoSpatialCollectionMetadata{
+		Extent: &config.Extent{Interval: []string{`"2020-01-01T00:00:00Z"`, "null"}},
+	}}
+	volume := &GeoVolume{Bbox: []float64{4, 51, 6, 53}}
+	tests := []struct {
+		query string
+		want  bool
+	}{
+		{query: "bbox=5,52,5.5,52.5", want: true},
+		{query: "bbox=7,52,8,52.5", want: false},
+		{query: "bbox=170,52,5,52.5", want: true},  // crosses the antimeridian
+		{query: "bbox=170,52,3,52.5", want: false}, // crosses the antimeridian
+		{query: "datetime=2021-06-01T00:00:00Z", want: true},
+		{query: "datetime=2019-06-01T00:00:00Z", want: false},
+		{query: "datetime=../2019-06-01T00:00:00Z", want: false},
+		{query: "datetime=2019-06-01T00:00:00Z/..", want: true},
+		{query: "bbox=5,52,5.5,52.5&datetime=2019-01-01T00:00:00Z/2019-12-31T00:00:00Z", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			params, err := url.ParseQuery(tt.query)
+			require.NoError(t, err)
+			filter, err := parseCollectionsFilter(params)
+			require.NoError(t, err)
+			require.NotNil(t, filter)
+			assert.Equal(t, tt.want, filter.matches(coll, volume))
+			assert.True(t, filter.matches(coll, nil), "collections other than 3D containers aren't filtered")
+		})
+	}
+}
+
 func createMockServer() (*httptest.ResponseRecorder, *httptest.Server) {
 	rr := httptest.NewRecorder()
 	l, err := net.Listen("tcp", "localhost:0")
@@ -300,3 +446,7 @@ func createCollectionRequest(url string, containerID string) (*http.Request, err
 
 	return req, err
 }
+
+func withoutWhitespace(s string) string {
+	return strings.Join(strings.Fields(s), "")
+}
@@ -0,0 +1,69 @@
+package common
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/PDOK/gokoala/config"
+)
+
+var invalidCollectionIDChars = regexp.MustCompile(`[^a-z0-9_-]+`)
+
+// TableInspection result of inspecting a table containing features or attributes in a data source,
+// without any collections being configured. Used to generate a starter config.
+type TableInspection struct {
+	*Table
+
+	// ID of the collection derived from the table name
+	CollectionID string
+
+	// SRID of the geometry column, 0 when the table has no geometry
+	SRID int
+
+	// Extent (minx, miny, maxx, maxy) of all geometries in the table, nil when the table is empty or has no geometry
+	Bbox []float64
+
+	// Date and date-time columns, in order of occurrence in the table
+	TemporalColumns []string
+
+	// Temporal extent (earliest and latest value) of the first and last temporal column, nil without temporal columns
+	Interval []string
+
+	// Columns which are the first (or only) column of an index, thus suitable as queryable
+	IndexedColumns []string
+}
+
+// CollectionsForTables creates a collection for each of the given tables, this allows one to read the
+// tables (including schema) from a data source without any collections being configured.
+func CollectionsForTables(tables []string) config.FeaturesCollections {
+	result := make(config.FeaturesCollections, 0, len(tables))
+	for _, table := range tables {
+		collection := config.FeaturesCollection{ID: CollectionIDForTable(table)}
+		if collection.ID != table {
+			tableName := table
+			collection.TableName = &tableName
+		}
+		result = append(result, collection)
+	}
+
+	return result
+}
+
+// CollectionIDForTable derives a valid collection ID (lowercase, no special characters) from the given table name.
+func CollectionIDForTable(table string) string {
+	id := invalidCollectionIDChars.ReplaceAllString(strings.ToLower(table), "_")
+
+	return strings.Trim(id, "_-")
+}
+
+// TemporalColumns returns the date and date-time columns of the given table.
+func TemporalColumns(table *Table) []string {
+	var result []string
+	for _, field := range table.Schema.Fields {
+		if field.IsTemporal() {
+			result = append(result, field.Name)
+		}
+	}
+
+	return result
+}
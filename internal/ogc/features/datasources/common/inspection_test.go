@@ -0,0 +1,23 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCollectionIDForTable(t *testing.T) {
+	assert.Equal(t, "road_segments", CollectionIDForTable("Road Segments"))
+	assert.Equal(t, "foo-bar", CollectionIDForTable("foo-bar"))
+	assert.Equal(t, "a_b", CollectionIDForTable("__A.b!"))
+}
+
+func TestCollectionsForTables(t *testing.T) {
+	collections := CollectionsForTables([]string{"addresses", "Road Segments"})
+
+	assert.Len(t, collections, 2)
+	assert.Equal(t, "addresses", collections[0].ID)
+	assert.Nil(t, collections[0].TableName)
+	assert.Equal(t, "road_segments", collections[1].ID)
+	assert.Equal(t, "Road Segments", *collections[1].TableName)
+}
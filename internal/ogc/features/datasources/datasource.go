@@ -41,6 +41,9 @@ type Datasource interface {
 	// SupportsOnTheFlyTransformation returns whether the datasource supports coordinate transformation/reprojection on-the-fly
 	SupportsOnTheFlyTransformation() bool
 
+	// Ping checks whether the datasource is available, e.g. whether the database can be reached
+	Ping(ctx context.Context) error
+
 	// Close closes (connections to) the datasource gracefully
 	Close()
 }
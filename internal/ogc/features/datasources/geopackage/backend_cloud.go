@@ -19,14 +19,14 @@ type cloudGeoPackage struct {
 	cloudVFS *cloudsqlitevfs.VFS
 }
 
-func newCloudBackedGeoPackage(gpkg *config.GeoPackageCloud) geoPackageBackend {
+func newCloudBackedGeoPackage(gpkg *config.GeoPackageCloud) (geoPackageBackend, error) {
 	cacheDir, err := gpkg.CacheDir()
 	if err != nil {
-		log.Fatalf("invalid cache dir, error: %v", err)
+		return nil, fmt.Errorf("invalid cache dir, error: %w", err)
 	}
 	cacheSize, err := gpkg.Cache.MaxSizeAsBytes()
 	if err != nil {
-		log.Fatalf("invalid cache size provided, error: %v", err)
+		return nil, fmt.Errorf("invalid cache size provided, error: %w", err)
 	}
 
 	msg := fmt.Sprintf("Cloud-Backed GeoPackage '%s' in container '%s' on '%s'",
@@ -34,20 +34,21 @@ func newCloudBackedGeoPackage(gpkg *config.GeoPackageCloud) geoPackageBackend {
 
 	log.Printf("connecting to %s\n", msg)
 	vfsName := uuid.New().String() // important: each geopackage must use a unique VFS name
-	vfs, err := cloudsqlitevfs.NewVFS(vfsName, gpkg.Connection, gpkg.User, gpkg.Auth,
+	vfs, err := cloudsqlitevfs.NewVFS(vfsName, gpkg.Connection, gpkg.User, gpkg.Auth.Value(),
 		gpkg.Container, cacheDir, cacheSize, gpkg.LogHTTPRequests)
 	if err != nil {
-		log.Fatalf("failed to connect with %s, error: %v", msg, err)
+		return nil, fmt.Errorf("failed to connect with %s, error: %w", msg, err)
 	}
 	log.Printf("connected to %s\n", msg)
 
 	conn := fmt.Sprintf("/%s/%s?vfs=%s&mode=ro&_cache_size=%d", gpkg.Container, gpkg.File, vfsName, gpkg.InMemoryCacheSize)
 	db, err := sqlx.Open(SqliteDriverName, conn)
 	if err != nil {
-		log.Fatalf("failed to open %s, error: %v", msg, err)
+		_ = vfs.Close()
+		return nil, fmt.Errorf("failed to open %s, error: %w", msg, err)
 	}
 
-	return &cloudGeoPackage{db, &vfs}
+	return &cloudGeoPackage{db, &vfs}, nil
 }
 
 func (g *cloudGeoPackage) getDB() *sqlx.DB {
@@ -3,7 +3,7 @@
 package geopackage
 
 import (
-	"log"
+	"errors"
 
 	"github.com/PDOK/gokoala/config"
 )
@@ -13,8 +13,6 @@ import (
 // '--allow-multiple-definition' flag. This flag is required since both the 'mattn' sqlite
 // driver and 'go-cloud-sqlite-vfs' contain a copy of the sqlite C-code, which causes
 // duplicate symbols (aka multiple definitions).
-func newCloudBackedGeoPackage(_ *config.GeoPackageCloud) geoPackageBackend {
-	log.Fatalf("Cloud backed GeoPackage isn't supported on darwin/macos")
-
-	return nil
+func newCloudBackedGeoPackage(_ *config.GeoPackageCloud) (geoPackageBackend, error) {
+	return nil, errors.New("Cloud backed GeoPackage isn't supported on darwin/macos")
 }
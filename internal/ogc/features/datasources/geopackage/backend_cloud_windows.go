@@ -3,13 +3,12 @@
 package geopackage
 
 import (
-	"log"
+	"errors"
 
 	"github.com/PDOK/gokoala/config"
 )
 
 // Dummy implementation to make compilation on window work.
-func newCloudBackedGeoPackage(_ *config.GeoPackageCloud) geoPackageBackend {
-	log.Fatalf("Cloud backed GeoPackage isn't supported on windows")
-	return nil
+func newCloudBackedGeoPackage(_ *config.GeoPackageCloud) (geoPackageBackend, error) {
+	return nil, errors.New("Cloud backed GeoPackage isn't supported on windows")
 }
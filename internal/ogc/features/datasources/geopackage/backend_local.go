@@ -15,21 +15,23 @@ type localGeoPackage struct {
 	db *sqlx.DB
 }
 
-func newLocalGeoPackage(gpkg *config.GeoPackageLocal) geoPackageBackend {
+func newLocalGeoPackage(gpkg *config.GeoPackageLocal) (geoPackageBackend, error) {
 	if gpkg.Download != nil {
-		downloadGeoPackage(gpkg)
+		if err := downloadGeoPackage(gpkg); err != nil {
+			return nil, err
+		}
 	}
 	conn := fmt.Sprintf("file:%s?mode=ro&_cache_size=%d", gpkg.File, gpkg.InMemoryCacheSize)
 	db, err := sqlx.Open(SqliteDriverName, conn)
 	if err != nil {
-		log.Fatalf("failed to open GeoPackage: %v", err)
+		return nil, fmt.Errorf("failed to open GeoPackage: %w", err)
 	}
 	log.Printf("connected to local GeoPackage: %s", gpkg.File)
 
-	return &localGeoPackage{db}
+	return &localGeoPackage{db}, nil
 }
 
-func downloadGeoPackage(gpkg *config.GeoPackageLocal) {
+func downloadGeoPackage(gpkg *config.GeoPackageLocal) error {
 	url := *gpkg.Download.From.URL
 	log.Printf("start download of GeoPackage: %s", url.String())
 
@@ -41,9 +43,11 @@ func downloadGeoPackage(gpkg *config.GeoPackageLocal) {
 	downloadTime, err := engine.Download(url, gpkg.File, gpkg.Download.Parallelism, tlsSkipVerify,
 		gpkg.Download.Timeout.Duration, gpkg.Download.RetryDelay.Duration, gpkg.Download.RetryMaxDelay.Duration, gpkg.Download.MaxRetries)
 	if err != nil {
-		log.Fatalf("failed to download GeoPackage: %v", err)
+		return fmt.Errorf("failed to download GeoPackage: %w", err)
 	}
 	log.Printf("successfully downloaded GeoPackage to %s in %s", gpkg.File, downloadTime.Round(time.Second))
+
+	return nil
 }
 
 func (g *localGeoPackage) getDB() *sqlx.DB {
@@ -3,7 +3,6 @@ package geopackage
 import (
 	"database/sql"
 	"fmt"
-	"log"
 	"os"
 	"path"
 	"sync"
@@ -36,7 +35,7 @@ func LoadDriver() {
 				query := fmt.Sprintf("select icu_load_collation('und-u-ks-level1-kc-true', '%s');", common.IgnoreAccentCollation)
 				_, err := conn.Exec(query, nil)
 				if err != nil {
-					log.Fatalf(errICUNotEnabled+" - %v", err)
+					return fmt.Errorf(errICUNotEnabled+" - %w", err)
 				}
 
 				// Unicode collation allows accent/diacritics AND casing to be ignored.
@@ -44,9 +43,9 @@ func LoadDriver() {
 				query = fmt.Sprintf("select icu_load_collation('und-u-ks-level1', '%s');", common.IgnoreAccentAndCaseCollation)
 				_, err = conn.Exec(query, nil)
 				if err != nil {
-					log.Fatalf(errICUNotEnabled+" - %v", err)
+					return fmt.Errorf(errICUNotEnabled+" - %w", err)
 				}
-				return err
+				return nil
 			},
 		}
 
@@ -60,16 +60,21 @@ func NewGeoPackage(collections config.FeaturesCollections, gpkgConfig config.Geo
 		preparedStmtCache: NewCache(),
 	}
 
+	var err error
 	warmUp := false
 	switch {
 	case gpkgConfig.Local != nil:
-		g.backend = newLocalGeoPackage(gpkgConfig.Local)
+		if g.backend, err = newLocalGeoPackage(gpkgConfig.Local); err != nil {
+			return nil, err
+		}
 		g.FidColumn = gpkgConfig.Local.Fid
 		g.ExternalFidColumn = gpkgConfig.Local.ExternalFid
 		g.QueryTimeout = gpkgConfig.Local.QueryTimeout.Duration
 		g.maxBBoxSizeToUseWithRTree = gpkgConfig.Local.MaxBBoxSizeToUseWithRTree
 	case gpkgConfig.Cloud != nil:
-		g.backend = newCloudBackedGeoPackage(gpkgConfig.Cloud)
+		if g.backend, err = newCloudBackedGeoPackage(gpkgConfig.Cloud); err != nil {
+			return nil, err
+		}
 		g.FidColumn = gpkgConfig.Cloud.Fid
 		g.ExternalFidColumn = gpkgConfig.Cloud.ExternalFid
 		g.QueryTimeout = gpkgConfig.Cloud.QueryTimeout.Duration
@@ -79,17 +84,21 @@ func NewGeoPackage(collections config.FeaturesCollections, gpkgConfig config.Geo
 		return nil, errors.New("unknown GeoPackage config encountered")
 	}
 
-	g.TableByCollectionID, g.QueryablesByCollectionID = readMetadata(
+	g.TableByCollectionID, g.QueryablesByCollectionID, err = readMetadata(
 		g.backend.getDB(), collections, g.FidColumn, g.ExternalFidColumn)
-
-	if err := assertIndexesExist(collections, g.TableByCollectionID, g.backend.getDB(), g.FidColumn); err != nil {
+	if err != nil {
+		g.backend.close()
+		return nil, err
+	}
+	if err = assertIndexesExist(collections, g.TableByCollectionID, g.backend.getDB(), g.FidColumn); err != nil {
+		g.backend.close()
 		return nil, err
 	}
 	if warmUp {
 		// perform warmup async since it can take a long time
 		go func() {
 			if err := warmUpFeatureTables(collections, g.TableByCollectionID, g.backend.getDB()); err != nil {
-				log.Fatal(err)
+				log.Printf("WARNING: failed to warm up GeoPackage cache, queries may be slow at first: %v", err)
 			}
 		}()
 	}
@@ -97,6 +106,13 @@ func NewGeoPackage(collections config.FeaturesCollections, gpkgConfig config.Geo
 	return g, nil
 }
 
+// Ping performs a cheap query on the GeoPackage, since for cloud-backed GeoPackages we
+// also want to know whether the (remote) storage is reachable through the VFS.
+func (g *GeoPackage) Ping(ctx context.Context) error {
+	var count int
+	return g.backend.getDB().GetContext(ctx, &count, "select count(*) from gpkg_contents")
+}
+
 func (g *GeoPackage) Close() {
 	g.preparedStmtCache.Close()
 	g.backend.close()
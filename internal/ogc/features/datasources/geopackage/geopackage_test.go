@@ -2,8 +2,11 @@ package geopackage
 
 import (
 	"context"
+	"net/http"
+	"net/http/httptest"
 	neturl "net/url"
 	"path"
+	"path/filepath"
 	"runtime"
 	"testing"
 	"time"
@@ -27,7 +30,7 @@ func init() {
 func newTestGeoPackage(file string) geoPackageBackend {
 	LoadDriver()
 
-	return newLocalGeoPackage(&config.GeoPackageLocal{
+	backend, err := newLocalGeoPackage(&config.GeoPackageLocal{
 		GeoPackageCommon: config.GeoPackageCommon{
 			DatasourceCommon: config.DatasourceCommon{
 				Fid:          "feature_id",
@@ -38,6 +41,11 @@ func newTestGeoPackage(file string) geoPackageBackend {
 		},
 		File: pwd + file,
 	})
+	if err != nil {
+		panic(err)
+	}
+
+	return backend
 }
 
 func TestNewGeoPackage(t *testing.T) {
@@ -82,6 +90,27 @@ func TestNewGeoPackage(t *testing.T) {
 	}
 }
 
+func TestNewGeoPackage_FailedDownload(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close() // unreachable
+	from, err := neturl.Parse(server.URL + "/bag.gpkg")
+	require.NoError(t, err)
+
+	_, err = NewGeoPackage([]config.FeaturesCollection{{ID: "ligplaatsen"}}, config.GeoPackage{
+		Local: &config.GeoPackageLocal{
+			File: filepath.Join(t.TempDir(), "bag.gpkg"),
+			Download: &config.GeoPackageDownload{
+				From:          config.URL{URL: from},
+				Parallelism:   1,
+				Timeout:       config.Duration{Duration: time.Second},
+				RetryDelay:    config.Duration{Duration: time.Millisecond},
+				RetryMaxDelay: config.Duration{Duration: time.Millisecond},
+			},
+		},
+	}, false, 0, false)
+	require.ErrorContains(t, err, "failed to download GeoPackage")
+}
+
 func TestGeoPackage_GetFeatures(t *testing.T) {
 	type fields struct {
 		backend          geoPackageBackend
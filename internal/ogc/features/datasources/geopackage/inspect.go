@@ -0,0 +1,137 @@
+package geopackage
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/PDOK/gokoala/config"
+	"github.com/PDOK/gokoala/internal/ogc/common/geospatial"
+	"github.com/PDOK/gokoala/internal/ogc/features/datasources/common"
+	"github.com/jmoiron/sqlx"
+)
+
+// Inspect reads all feature and attributes tables from the given GeoPackage, including their schema, extent
+// and indexes. In contrast to NewGeoPackage no collections need to be configured, since the goal is to
+// generate a (starter) config. The external FID column is only used when present in all tables.
+func Inspect(gpkgConfig config.GeoPackage, externalFidColumn string) ([]common.TableInspection, error) {
+	LoadDriver()
+
+	var backend geoPackageBackend
+	var fidColumn string
+	var err error
+	switch {
+	case gpkgConfig.Local != nil:
+		backend, err = newLocalGeoPackage(gpkgConfig.Local)
+		fidColumn = gpkgConfig.Local.Fid
+	case gpkgConfig.Cloud != nil:
+		backend, err = newCloudBackedGeoPackage(gpkgConfig.Cloud)
+		fidColumn = gpkgConfig.Cloud.Fid
+	default:
+		return nil, errors.New("unknown GeoPackage config encountered")
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer backend.close()
+	db := backend.getDB()
+
+	var tableNames []string
+	query := fmt.Sprintf(`select table_name from gpkg_contents where data_type = '%s' or data_type = '%s' order by table_name`,
+		geospatial.Features, geospatial.Attributes)
+	if err := db.Select(&tableNames, query); err != nil {
+		return nil, fmt.Errorf("failed to retrieve gpkg_contents using query: %v\n, error: %w", query, err)
+	}
+	if externalFidColumn != "" {
+		for _, tableName := range tableNames {
+			var count int
+			if err := db.Get(&count, fmt.Sprintf(`select count(*) from pragma_table_info('%s') where name = ?`, tableName),
+				externalFidColumn); err != nil {
+				return nil, err
+			}
+			if count == 0 {
+				log.Printf("Warning: table %s has no external FID column %s, not using external FIDs", tableName, externalFidColumn)
+				externalFidColumn = ""
+				break
+			}
+		}
+	}
+
+	collections := common.CollectionsForTables(tableNames)
+	tableByCollectionID, err := readGeoPackageTables(collections, db, fidColumn, externalFidColumn)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]common.TableInspection, 0, len(collections))
+	for _, collection := range collections {
+		table := tableByCollectionID[collection.ID]
+		inspection := common.TableInspection{
+			Table:           table,
+			CollectionID:    collection.ID,
+			TemporalColumns: common.TemporalColumns(table),
+		}
+		if table.Type == geospatial.Features {
+			if inspection.SRID, inspection.Bbox, err = readExtent(db, table); err != nil {
+				return nil, fmt.Errorf("failed to read extent of table %s, error: %w", table.Name, err)
+			}
+		}
+		if len(inspection.TemporalColumns) > 0 {
+			start := inspection.TemporalColumns[0]
+			end := inspection.TemporalColumns[len(inspection.TemporalColumns)-1]
+			if inspection.Interval, err = readInterval(db, table, start, end); err != nil {
+				return nil, fmt.Errorf("failed to read temporal extent of table %s, error: %w", table.Name, err)
+			}
+		}
+		query = fmt.Sprintf(`select distinct info.name from pragma_index_list('%s') as list, pragma_index_info(list.name) as info
+where info.seqno = 0 order by info.name`, table.Name)
+		if err = db.Select(&inspection.IndexedColumns, query); err != nil {
+			return nil, fmt.Errorf("failed to read indexes of table %s, error: %w", table.Name, err)
+		}
+		result = append(result, inspection)
+	}
+
+	return result, nil
+}
+
+// readExtent reads the SRID and computes the bbox of the geometries in the given table, using the
+// RTree spatial index. Falls back to the (optional) bbox in gpkg_contents when there's no RTree.
+func readExtent(db *sqlx.DB, table *common.Table) (int, []float64, error) {
+	var srid int
+	if err := db.Get(&srid, `select srs_id from gpkg_geometry_columns where table_name = ?`, table.Name); err != nil {
+		return 0, nil, err
+	}
+
+	var hasRTree bool
+	rtree := fmt.Sprintf("rtree_%s_%s", table.Name, table.GeometryColumnName)
+	if err := db.Get(&hasRTree, `select exists (select 1 from sqlite_master where type = 'table' and name = ?)`, rtree); err != nil {
+		return 0, nil, err
+	}
+	var query string
+	if hasRTree {
+		query = fmt.Sprintf(`select min(minx), min(miny), max(maxx), max(maxy) from "%s"`, rtree)
+	} else {
+		query = fmt.Sprintf(`select min_x, min_y, max_x, max_y from gpkg_contents where table_name = '%s'`, table.Name)
+	}
+	var minx, miny, maxx, maxy sql.NullFloat64
+	if err := db.QueryRowx(query).Scan(&minx, &miny, &maxx, &maxy); err != nil {
+		return 0, nil, err
+	}
+	if !minx.Valid || !miny.Valid || !maxx.Valid || !maxy.Valid {
+		return srid, nil, nil // empty table
+	}
+
+	return srid, []float64{minx.Float64, miny.Float64, maxx.Float64, maxy.Float64}, nil
+}
+
+// readInterval reads the earliest start date and latest end date in the given table.
+func readInterval(db *sqlx.DB, table *common.Table, start, end string) ([]string, error) {
+	var minStart, maxEnd sql.NullString
+	query := fmt.Sprintf(`select min("%s"), max("%s") from "%s"`, start, end, table.Name)
+	if err := db.QueryRowx(query).Scan(&minStart, &maxEnd); err != nil {
+		return nil, err
+	}
+
+	return []string{minStart.String, maxEnd.String}, nil
+}
@@ -22,24 +22,25 @@ const (
 var newlineRegex = regexp.MustCompile(`[\r\n]+`)
 
 // readMetadata reads metadata such as available feature tables, the schema of each table,
-// available filters, etc. from the GeoPackage. Terminates on failure.
+// available filters, etc. from the GeoPackage.
 func readMetadata(db *sqlx.DB, collections config.FeaturesCollections, fidColumn, externalFidColumn string) (
 	tableByCollectionID map[string]*common.Table,
-	queryablesByCollectionID map[string]d.Queryables) {
+	queryablesByCollectionID map[string]d.Queryables,
+	err error) {
 
 	metadata, err := readDriverMetadata(db)
 	if err != nil {
-		log.Fatal(err)
+		return nil, nil, err
 	}
 	log.Println(metadata)
 
 	tableByCollectionID, err = readGeoPackageTables(collections, db, fidColumn, externalFidColumn)
 	if err != nil {
-		log.Fatal(err)
+		return nil, nil, err
 	}
 	queryablesByCollectionID, err = readQueryables(tableByCollectionID, collections, db)
 	if err != nil {
-		log.Fatal(err)
+		return nil, nil, err
 	}
 
 	return
@@ -77,10 +78,10 @@ select compile_options
 from pragma_compile_options 
 where compile_options like 'ENABLE_%'`)
 	if !slices.Contains(p.CompileOptions, "ENABLE_ICU") {
-		log.Fatal(errICUNotEnabled)
+		return "", errors.New(errICUNotEnabled)
 	}
 	if !slices.Contains(p.CompileOptions, "ENABLE_MATH_FUNCTIONS") {
-		log.Fatal(errMathNotEnabled)
+		return "", errors.New(errMathNotEnabled)
 	}
 
 	return fmt.Sprintf("geopackage version: %s, sqlite version: %s, spatialite version: %s on %s",
@@ -0,0 +1,138 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/PDOK/gokoala/config"
+	"github.com/PDOK/gokoala/internal/ogc/features/datasources/common"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Inspect reads all feature tables from the given Postgres schema, including their schema, extent
+// and indexes. In contrast to NewPostgres no collections need to be configured, since the goal is to
+// generate a (starter) config. The external FID column is only used when present in all tables.
+func Inspect(pgConfig config.Postgres, externalFidColumn string) ([]common.TableInspection, error) {
+	ctx := context.Background()
+	db, err := InitConnectionPool(ctx, pgConfig.ConnectionString())
+	if err != nil {
+		return nil, fmt.Errorf("unable to create connection pool: %w", err)
+	}
+	defer db.Close()
+
+	rows, err := db.Query(ctx, `select f_table_name::text from geometry_columns where f_table_schema = $1 order by f_table_name`,
+		pgConfig.Schema)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve geometry_columns, error: %w", err)
+	}
+	tableNames, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, err
+	}
+	if externalFidColumn != "" {
+		for _, tableName := range tableNames {
+			var exists bool
+			if err = db.QueryRow(ctx, `select exists (select 1 from information_schema.columns
+where table_schema = $1 and table_name = $2 and column_name = $3)`, pgConfig.Schema, tableName, externalFidColumn).Scan(&exists); err != nil {
+				return nil, err
+			}
+			if !exists {
+				log.Printf("Warning: table %s has no external FID column %s, not using external FIDs", tableName, externalFidColumn)
+				externalFidColumn = ""
+				break
+			}
+		}
+	}
+
+	collections := common.CollectionsForTables(tableNames)
+	tableByCollectionID, err := readFeatureTables(collections, db, pgConfig.Fid, externalFidColumn, pgConfig.Schema)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]common.TableInspection, 0, len(collections))
+	for _, collection := range collections {
+		table := tableByCollectionID[collection.ID]
+		inspection := common.TableInspection{
+			Table:           table,
+			CollectionID:    collection.ID,
+			TemporalColumns: common.TemporalColumns(table),
+		}
+		if inspection.SRID, inspection.Bbox, err = readExtent(db, pgConfig.Schema, table); err != nil {
+			return nil, fmt.Errorf("failed to read extent of table %s, error: %w", table.Name, err)
+		}
+		if len(inspection.TemporalColumns) > 0 {
+			start := inspection.TemporalColumns[0]
+			end := inspection.TemporalColumns[len(inspection.TemporalColumns)-1]
+			if inspection.Interval, err = readInterval(db, pgConfig.Schema, table, start, end); err != nil {
+				return nil, fmt.Errorf("failed to read temporal extent of table %s, error: %w", table.Name, err)
+			}
+		}
+		if inspection.IndexedColumns, err = readIndexedColumns(db, pgConfig.Schema, table); err != nil {
+			return nil, fmt.Errorf("failed to read indexes of table %s, error: %w", table.Name, err)
+		}
+		result = append(result, inspection)
+	}
+
+	return result, nil
+}
+
+// readExtent reads the SRID and computes the bbox of the geometries in the given table.
+func readExtent(db *pgxpool.Pool, schemaName string, table *common.Table) (int, []float64, error) {
+	ctx := context.Background()
+	var srid int
+	if err := db.QueryRow(ctx, `select srid from geometry_columns where f_table_schema = $1 and f_table_name = $2 and f_geometry_column = $3`,
+		schemaName, table.Name, table.GeometryColumnName).Scan(&srid); err != nil {
+		return 0, nil, err
+	}
+
+	var minx, miny, maxx, maxy *float64
+	query := fmt.Sprintf(`select st_xmin(e), st_ymin(e), st_xmax(e), st_ymax(e) from (select st_extent("%s") as e from "%s"."%s") as extent`,
+		table.GeometryColumnName, schemaName, table.Name)
+	if err := db.QueryRow(ctx, query).Scan(&minx, &miny, &maxx, &maxy); err != nil {
+		return 0, nil, err
+	}
+	if minx == nil || miny == nil || maxx == nil || maxy == nil {
+		return srid, nil, nil // empty table
+	}
+
+	return srid, []float64{*minx, *miny, *maxx, *maxy}, nil
+}
+
+// readInterval reads the earliest start date and latest end date in the given table.
+func readInterval(db *pgxpool.Pool, schemaName string, table *common.Table, start, end string) ([]string, error) {
+	var minStart, maxEnd *time.Time
+	query := fmt.Sprintf(`select min("%s")::timestamptz, max("%s")::timestamptz from "%s"."%s"`, start, end, schemaName, table.Name)
+	if err := db.QueryRow(context.Background(), query).Scan(&minStart, &maxEnd); err != nil {
+		return nil, err
+	}
+	result := make([]string, 2)
+	if minStart != nil {
+		result[0] = minStart.UTC().Format(time.RFC3339)
+	}
+	if maxEnd != nil {
+		result[1] = maxEnd.UTC().Format(time.RFC3339)
+	}
+
+	return result, nil
+}
+
+// readIndexedColumns reads the columns which are the first (or only) column of an index.
+func readIndexedColumns(db *pgxpool.Pool, schemaName string, table *common.Table) ([]string, error) {
+	rows, err := db.Query(context.Background(), `
+select distinct a.attname::text
+from pg_index idx
+join pg_class tbl on tbl.oid = idx.indrelid
+join pg_namespace ns on ns.oid = tbl.relnamespace
+join pg_attribute a on a.attrelid = tbl.oid and a.attnum = idx.indkey[0]
+where ns.nspname = $1 and tbl.relname = $2
+order by 1`, schemaName, table.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows(rows, pgx.RowTo[string])
+}
@@ -17,14 +17,15 @@ import (
 var newlineRegex = regexp.MustCompile(`[\r\n]+`)
 
 // readMetadata reads metadata such as available feature tables, the schema of each table,
-// available filters, etc. from the Postgres database. Terminates on failure.
+// available filters, etc. from the Postgres database.
 func readMetadata(db *pgxpool.Pool, collections config.FeaturesCollections, fidColumn, externalFidColumn, schemaName string) (
 	tableByCollectionID map[string]*common.Table,
-	queryablesByCollectionID map[string]d.Queryables) {
+	queryablesByCollectionID map[string]d.Queryables,
+	err error) {
 
 	metadata, err := readDriverMetadata(db)
 	if err != nil {
-		log.Fatal(err)
+		return nil, nil, err
 	}
 	log.Println(metadata)
 
@@ -33,11 +34,11 @@ func readMetadata(db *pgxpool.Pool, collections config.FeaturesCollections, fidC
 	}
 	tableByCollectionID, err = readFeatureTables(collections, db, fidColumn, externalFidColumn, schemaName)
 	if err != nil {
-		log.Fatal(err)
+		return nil, nil, err
 	}
 	queryablesByCollectionID, err = readQueryables(tableByCollectionID, collections, db)
 	if err != nil {
-		log.Fatal(err)
+		return nil, nil, err
 	}
 
 	return
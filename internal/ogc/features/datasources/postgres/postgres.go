@@ -69,9 +69,11 @@ func NewPostgres(collections config.FeaturesCollections, pgConfig config.Postgre
 		schemaName: pgConfig.Schema,
 	}
 
-	pg.TableByCollectionID, pg.QueryablesByCollectionID = readMetadata(
+	pg.TableByCollectionID, pg.QueryablesByCollectionID, err = readMetadata(
 		db, collections, pg.FidColumn, pg.ExternalFidColumn, pg.schemaName)
-
+	if err != nil {
+		return nil, err
+	}
 	if err = assertIndexesExist(collections, pg.TableByCollectionID, db, *pgConfig.SpatialIndexRequired); err != nil {
 		return nil, err
 	}
@@ -79,6 +81,10 @@ func NewPostgres(collections config.FeaturesCollections, pgConfig config.Postgre
 	return pg, nil
 }
 
+func (pg *Postgres) Ping(ctx context.Context) error {
+	return pg.db.Ping(ctx)
+}
+
 func (pg *Postgres) Close() {
 	pg.db.Close()
 }
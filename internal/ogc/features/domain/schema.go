@@ -222,6 +222,12 @@ func (f Field) ToTypeFormat() TypeFormat {
 	}
 }
 
+// IsTemporal returns true when the field is a date or date-time, false otherwise.
+func (f Field) IsTemporal() bool {
+	format := f.ToTypeFormat().Format
+	return format == formatDateOnly || format == formatDateTime
+}
+
 // IsNumeric returns true when the field is numeric (integer, double, etc), false otherwise.
 func (f Field) IsNumeric() bool {
 	t := f.normalizeType()
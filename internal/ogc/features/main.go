@@ -1,9 +1,12 @@
 package features
 
 import (
+	"errors"
 	"fmt"
 	"log"
+	"maps"
 	"net/http"
+	"slices"
 	"strings"
 	"sync"
 
@@ -61,6 +64,8 @@ func NewFeatures(e *engine.Engine) *Features {
 		json:                  newJSONFeatures(e),
 	}
 
+	RegisterReadinessChecks(e, "features", datasources)
+
 	e.Router.Get(geospatial.CollectionsPath+"/{collectionId}/items", f.Features())
 	e.Router.Get(geospatial.CollectionsPath+"/{collectionId}/items/{featureId}", f.Feature())
 	e.Router.Get(geospatial.CollectionsPath+"/{collectionId}/schema", f.Schema())
@@ -73,6 +78,11 @@ func (f *Features) GetCollectionTypes() geospatial.CollectionTypes {
 	return f.collectionTypes
 }
 
+// GetDatasource returns the datasource serving the given collection in WGS84, nil when the collection is unknown.
+func (f *Features) GetDatasource(collectionID string) ds.Datasource {
+	return f.datasources[DatasourceKey{srid: domain.WGS84SRID, collectionID: collectionID}]
+}
+
 type DatasourceKey struct {
 	srid         int
 	collectionID string
@@ -123,6 +133,34 @@ func CreateDatasources(cfg config.FeaturesAndSearchConfig, shutdownHook func(fn
 	return result
 }
 
+// RegisterReadinessChecks registers a readiness check for each (unique) datasource, the
+// name of the check contains the collections and projections served by the datasource.
+func RegisterReadinessChecks(e *engine.Engine, component string, datasources map[DatasourceKey]ds.Datasource) {
+	collectionsByDatasource := make(map[ds.Datasource][]string)
+	sridsByDatasource := make(map[ds.Datasource][]string)
+	for key, datasource := range datasources {
+		if !slices.Contains(collectionsByDatasource[datasource], key.collectionID) {
+			collectionsByDatasource[datasource] = append(collectionsByDatasource[datasource], key.collectionID)
+		}
+		srid := fmt.Sprintf("%s%d", domain.EPSGPrefix, key.srid)
+		if key.srid == domain.WGS84SRID {
+			srid = domain.OGCPrefix + domain.WGS84CodeOGC
+		}
+		if !slices.Contains(sridsByDatasource[datasource], srid) {
+			sridsByDatasource[datasource] = append(sridsByDatasource[datasource], srid)
+		}
+	}
+	names := make(map[string]ds.Datasource, len(collectionsByDatasource))
+	for datasource, collections := range collectionsByDatasource {
+		slices.Sort(collections)
+		slices.Sort(sridsByDatasource[datasource])
+		names[fmt.Sprintf("%s (%s)", strings.Join(collections, ", "), strings.Join(sridsByDatasource[datasource], ", "))] = datasource
+	}
+	for _, name := range slices.Sorted(maps.Keys(names)) {
+		e.RegisterReadinessCheck(component, name, names[name].Ping)
+	}
+}
+
 func GetProjJSONBySRID(datasources map[DatasourceKey]ds.Datasource) map[int]string {
 	log.Println("start determining axis order for all configured CRSs")
 	infoMap := make(map[int]string)
@@ -368,25 +406,29 @@ func configureCollectionDatasources(cfg config.FeaturesAndSearchConfig, result m
 func newDatasource(shutdownHook func(fn func()), cfg config.FeaturesAndSearchConfig,
 	dsConfig config.Datasource, transformOnTheFly bool) ds.Datasource {
 
+	datasource, err := createDatasource(cfg, dsConfig, transformOnTheFly)
+	if err != nil {
+		log.Fatal(err)
+	}
+	shutdownHook(datasource.Close)
+
+	return datasource
+}
+
+func createDatasource(cfg config.FeaturesAndSearchConfig, dsConfig config.Datasource,
+	transformOnTheFly bool) (ds.Datasource, error) {
+
 	maxDecimals := cfg.MaxDecimals()
 	forceUTC := cfg.ForceUTC()
 
-	var datasource ds.Datasource
-	var err error
 	switch {
 	case dsConfig.GeoPackage != nil:
-		datasource, err = geopackage.NewGeoPackage(cfg.FeatureCollections(), *dsConfig.GeoPackage, transformOnTheFly, maxDecimals, forceUTC)
+		return geopackage.NewGeoPackage(cfg.FeatureCollections(), *dsConfig.GeoPackage, transformOnTheFly, maxDecimals, forceUTC)
 	case dsConfig.Postgres != nil:
-		datasource, err = postgres.NewPostgres(cfg.FeatureCollections(), *dsConfig.Postgres, transformOnTheFly, maxDecimals, forceUTC)
+		return postgres.NewPostgres(cfg.FeatureCollections(), *dsConfig.Postgres, transformOnTheFly, maxDecimals, forceUTC)
 	default:
-		log.Fatal("got unknown datasource type")
+		return nil, errors.New("got unknown datasource type")
 	}
-	if err != nil {
-		log.Fatal(err)
-	}
-	shutdownHook(datasource.Close)
-
-	return datasource
 }
 
 func handleCollectionNotFound(w http.ResponseWriter, collectionID string) {
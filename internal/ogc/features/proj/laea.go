@@ -0,0 +1,64 @@
+package proj
+
+import "math"
+
+// lambertAzimuthalEqualArea ellipsoidal Lambert Azimuthal Equal Area projection (EPSG method 9820),
+// for example used by EPSG:3035. See IOGP Guidance Note 7-2, section 3.2.1.
+type lambertAzimuthalEqualArea struct {
+	ellipsoid
+	lon0, falseEasting, falseNorthing float64
+	qp, rq, d, sinBeta0, cosBeta0     float64
+}
+
+func newLambertAzimuthalEqualArea(lat0, lon0, falseEasting, falseNorthing float64, el ellipsoid) lambertAzimuthalEqualArea {
+	p := lambertAzimuthalEqualArea{
+		ellipsoid:     el,
+		lon0:          radians(lon0),
+		falseEasting:  falseEasting,
+		falseNorthing: falseNorthing,
+	}
+	phi0 := radians(lat0)
+	p.qp = p.q(math.Pi / 2)
+	p.rq = el.a * math.Sqrt(p.qp/2)
+	beta0 := math.Asin(p.q(phi0) / p.qp)
+	p.sinBeta0, p.cosBeta0 = math.Sin(beta0), math.Cos(beta0)
+	p.d = el.a * (math.Cos(phi0) / math.Sqrt(1-el.e2*math.Sin(phi0)*math.Sin(phi0))) / (p.rq * p.cosBeta0)
+
+	return p
+}
+
+func (p lambertAzimuthalEqualArea) q(phi float64) float64 {
+	sinPhi := math.Sin(phi)
+
+	return (1 - p.e2) * (sinPhi/(1-p.e2*sinPhi*sinPhi) - (1/(2*p.e))*math.Log((1-p.e*sinPhi)/(1+p.e*sinPhi)))
+}
+
+func (p lambertAzimuthalEqualArea) Forward(lon, lat float64) (float64, float64) {
+	lambda := radians(lon) - p.lon0
+	beta := math.Asin(math.Max(-1, math.Min(1, p.q(radians(lat))/p.qp)))
+	sinBeta, cosBeta := math.Sin(beta), math.Cos(beta)
+	b := p.rq * math.Sqrt(2/(1+p.sinBeta0*sinBeta+p.cosBeta0*cosBeta*math.Cos(lambda)))
+
+	return p.falseEasting + b*p.d*cosBeta*math.Sin(lambda),
+		p.falseNorthing + (b/p.d)*(p.cosBeta0*sinBeta-p.sinBeta0*cosBeta*math.Cos(lambda))
+}
+
+func (p lambertAzimuthalEqualArea) Inverse(x, y float64) (float64, float64) {
+	dx, dy := x-p.falseEasting, y-p.falseNorthing
+	rho := math.Hypot(dx/p.d, p.d*dy)
+	if rho == 0 {
+		return degrees(p.lon0), degrees(math.Asin(p.sinBeta0))
+	}
+	c := 2 * math.Asin(math.Min(1, rho/(2*p.rq)))
+	sinC, cosC := math.Sin(c), math.Cos(c)
+	beta := math.Asin(cosC*p.sinBeta0 + (p.d*dy*sinC*p.cosBeta0)/rho)
+	lambda := p.lon0 + math.Atan2(dx*sinC, p.d*rho*p.cosBeta0*cosC-p.d*p.d*dy*p.sinBeta0*sinC)
+
+	e4, e6 := p.e2*p.e2, p.e2*p.e2*p.e2
+	phi := beta +
+		(p.e2/3+31*e4/180+517*e6/5040)*math.Sin(2*beta) +
+		(23*e4/360+251*e6/3780)*math.Sin(4*beta) +
+		(761*e6/45360)*math.Sin(6*beta)
+
+	return degrees(lambda), degrees(phi)
+}
@@ -1,3 +1,5 @@
+// Package proj determines the axis order of projections (using PROJ) and converts coordinates
+// between the projections supported for (derived) tiles.
 package proj
 
 import (
@@ -0,0 +1,138 @@
+package proj
+
+import "math"
+
+// arc-seconds to radians
+const arcSecond = math.Pi / (180 * 3600)
+
+// rdNew Amersfoort / RD New (EPSG:28992): oblique stereographic projection (EPSG method 9809)
+// on the Bessel 1841 ellipsoid, combined with a 7-parameter datum transformation to WGS84.
+type rdNew struct {
+	stereo  obliqueStereographic
+	bessel  ellipsoid
+	wgs84   ellipsoid
+	toWGS84 helmert
+}
+
+func newRDNew() rdNew {
+	bessel := newEllipsoid(6377397.155, 299.1528128)
+
+	return rdNew{
+		stereo: newObliqueStereographic(52.15616055555555, 5.38763888888889, 0.9999079, 155000, 463000, bessel),
+		bessel: bessel,
+		wgs84:  newEllipsoid(wgs84SemiMajorAxis, wgs84InverseFlattening),
+		// Amersfoort to WGS 84 (position vector convention)
+		toWGS84: helmert{
+			tx: 565.417, ty: 50.3319, tz: 465.552,
+			rx: -0.398957 * arcSecond, ry: 0.343988 * arcSecond, rz: -1.8774 * arcSecond,
+			scale: 4.0725e-6,
+		},
+	}
+}
+
+func (p rdNew) Forward(lon, lat float64) (float64, float64) {
+	x, y, z := p.wgs84.toGeocentric(radians(lon), radians(lat))
+	lonBessel, latBessel := p.bessel.fromGeocentric(p.toWGS84.inverse(x, y, z))
+
+	return p.stereo.forward(lonBessel, latBessel)
+}
+
+func (p rdNew) Inverse(x, y float64) (float64, float64) {
+	lonBessel, latBessel := p.stereo.inverse(x, y)
+	lon, lat := p.wgs84.fromGeocentric(p.toWGS84.forward(p.bessel.toGeocentric(lonBessel, latBessel)))
+
+	return degrees(lon), degrees(lat)
+}
+
+// helmert 7-parameter (position vector) transformation between geocentric coordinates, rotations in radians.
+type helmert struct {
+	tx, ty, tz, rx, ry, rz, scale float64
+}
+
+func (h helmert) forward(x, y, z float64) (float64, float64, float64) {
+	m := 1 + h.scale
+
+	return h.tx + m*(x-h.rz*y+h.ry*z),
+		h.ty + m*(h.rz*x+y-h.rx*z),
+		h.tz + m*(-h.ry*x+h.rx*y+z)
+}
+
+// inverse approximation by reversing the parameters, sufficient given the small rotations.
+func (h helmert) inverse(x, y, z float64) (float64, float64, float64) {
+	return helmert{-h.tx, -h.ty, -h.tz, -h.rx, -h.ry, -h.rz, -h.scale}.forward(x, y, z)
+}
+
+// obliqueStereographic EPSG method 9809, see IOGP Guidance Note 7-2, section 3.5.2.
+// Geographic coordinates in radians.
+type obliqueStereographic struct {
+	ellipsoid
+	lon0, k0, falseEasting, falseNorthing float64
+	r, n, c, chi0                         float64
+}
+
+func newObliqueStereographic(lat0, lon0, k0, falseEasting, falseNorthing float64, el ellipsoid) obliqueStereographic {
+	p := obliqueStereographic{
+		ellipsoid:     el,
+		lon0:          radians(lon0),
+		k0:            k0,
+		falseEasting:  falseEasting,
+		falseNorthing: falseNorthing,
+	}
+	phi0 := radians(lat0)
+	sinPhi0 := math.Sin(phi0)
+	rho0 := el.a * (1 - el.e2) / math.Pow(1-el.e2*sinPhi0*sinPhi0, 1.5)
+	nu0 := el.a / math.Sqrt(1-el.e2*sinPhi0*sinPhi0)
+	p.r = math.Sqrt(rho0 * nu0)
+	p.n = math.Sqrt(1 + el.e2*math.Pow(math.Cos(phi0), 4)/(1-el.e2))
+	s1 := (1 + sinPhi0) / (1 - sinPhi0)
+	s2 := (1 - el.e*sinPhi0) / (1 + el.e*sinPhi0)
+	w1 := math.Pow(s1*math.Pow(s2, el.e), p.n)
+	sinChi0 := (w1 - 1) / (w1 + 1)
+	p.c = (p.n + sinPhi0) * (1 - sinChi0) / ((p.n - sinPhi0) * (1 + sinChi0))
+	w2 := p.c * w1
+	p.chi0 = math.Asin((w2 - 1) / (w2 + 1))
+
+	return p
+}
+
+func (p obliqueStereographic) forward(lon, lat float64) (float64, float64) {
+	lambda := p.n*(lon-p.lon0) + p.lon0
+	sinPhi := math.Sin(lat)
+	sa := (1 + sinPhi) / (1 - sinPhi)
+	sb := (1 - p.e*sinPhi) / (1 + p.e*sinPhi)
+	w := p.c * math.Pow(sa*math.Pow(sb, p.e), p.n)
+	chi := math.Asin((w - 1) / (w + 1))
+	sinChi, cosChi := math.Sin(chi), math.Cos(chi)
+	sinChi0, cosChi0 := math.Sin(p.chi0), math.Cos(p.chi0)
+	b := 1 + sinChi*sinChi0 + cosChi*cosChi0*math.Cos(lambda-p.lon0)
+
+	return p.falseEasting + 2*p.r*p.k0*cosChi*math.Sin(lambda-p.lon0)/b,
+		p.falseNorthing + 2*p.r*p.k0*(sinChi*cosChi0-cosChi*sinChi0*math.Cos(lambda-p.lon0))/b
+}
+
+func (p obliqueStereographic) inverse(x, y float64) (float64, float64) {
+	dx, dy := x-p.falseEasting, y-p.falseNorthing
+	g := 2 * p.r * p.k0 * math.Tan(math.Pi/4-p.chi0/2)
+	h := 4*p.r*p.k0*math.Tan(p.chi0) + g
+	i := math.Atan(dx / (h + dy))
+	j := math.Atan(dx/(g-dy)) - i
+	chi := p.chi0 + 2*math.Atan((dy-dx*math.Tan(j/2))/(2*p.r*p.k0))
+	lambda := j + 2*i + p.lon0
+	lon := (lambda-p.lon0)/p.n + p.lon0
+
+	sinChi := math.Sin(chi)
+	psi := 0.5 * math.Log((1+sinChi)/(p.c*(1-sinChi))) / p.n
+	lat := 2*math.Atan(math.Exp(psi)) - math.Pi/2
+	for range 10 {
+		sinLat := p.e * math.Sin(lat)
+		psiI := math.Log(math.Tan(lat/2+math.Pi/4) * math.Pow((1-sinLat)/(1+sinLat), p.e/2))
+		next := lat - (psiI-psi)*math.Cos(lat)*(1-p.e2*math.Sin(lat)*math.Sin(lat))/(1-p.e2)
+		if math.Abs(next-lat) < 1e-12 {
+			lat = next
+			break
+		}
+		lat = next
+	}
+
+	return lon, lat
+}
@@ -0,0 +1,135 @@
+package proj
+
+import (
+	"fmt"
+	"math"
+	"slices"
+
+	"github.com/PDOK/gokoala/internal/engine/util"
+)
+
+const (
+	wgs84SemiMajorAxis     = 6378137.0
+	wgs84InverseFlattening = 298.257223563
+)
+
+// Projection converts between projected coordinates (x/y or easting/northing) and
+// geographic WGS84/ETRS89 coordinates (longitude/latitude in degrees). Note: only the handful
+// of projections used by the built-in TileMatrixSets are implemented, with an accuracy suitable
+// for rendering tiles (about 1 meter).
+type Projection interface {
+	Forward(lon, lat float64) (x, y float64)
+	Inverse(x, y float64) (lon, lat float64)
+}
+
+// Transformer converts a coordinate from one projection to another.
+type Transformer func(x, y float64) (float64, float64)
+
+var projections = map[string]Projection{
+	"EPSG:4326":  geographic{},
+	"EPSG:4258":  geographic{}, // ETRS89 is considered equal to WGS84 at this level of accuracy
+	"EPSG:3857":  webMercator{},
+	"EPSG:3035":  newLambertAzimuthalEqualArea(52, 10, 4321000, 3210000, newEllipsoid(6378137, 298.257222101)),
+	"EPSG:28992": newRDNew(),
+}
+
+// SupportedSrs projections supported for transformation.
+func SupportedSrs() []string {
+	result := util.Keys(projections)
+	slices.Sort(result)
+
+	return result
+}
+
+// NewTransformer creates a Transformer converting coordinates in the given source projection to the target projection.
+func NewTransformer(sourceSrs, targetSrs string) (Transformer, error) {
+	source, ok := projections[sourceSrs]
+	if !ok {
+		return nil, fmt.Errorf("transformation from srs '%s' isn't supported, supported are: %v", sourceSrs, SupportedSrs())
+	}
+	target, ok := projections[targetSrs]
+	if !ok {
+		return nil, fmt.Errorf("transformation to srs '%s' isn't supported, supported are: %v", targetSrs, SupportedSrs())
+	}
+	if sourceSrs == targetSrs {
+		return func(x, y float64) (float64, float64) { return x, y }, nil
+	}
+
+	return func(x, y float64) (float64, float64) {
+		return target.Forward(source.Inverse(x, y))
+	}, nil
+}
+
+// geographic longitude/latitude in degrees, x = longitude and y = latitude.
+type geographic struct{}
+
+func (geographic) Forward(lon, lat float64) (float64, float64) {
+	return lon, lat
+}
+
+func (geographic) Inverse(x, y float64) (float64, float64) {
+	return x, y
+}
+
+// webMercator spherical (pseudo) Mercator, EPSG:3857.
+type webMercator struct{}
+
+// latitude beyond which web mercator isn't defined
+const webMercatorMaxLat = 85.06
+
+func (webMercator) Forward(lon, lat float64) (float64, float64) {
+	lat = math.Max(-webMercatorMaxLat, math.Min(webMercatorMaxLat, lat))
+
+	return wgs84SemiMajorAxis * radians(lon), wgs84SemiMajorAxis * math.Log(math.Tan(math.Pi/4+radians(lat)/2))
+}
+
+func (webMercator) Inverse(x, y float64) (float64, float64) {
+	return degrees(x / wgs84SemiMajorAxis), degrees(2*math.Atan(math.Exp(y/wgs84SemiMajorAxis)) - math.Pi/2)
+}
+
+type ellipsoid struct {
+	a  float64 // semi-major axis
+	e2 float64 // eccentricity squared
+	e  float64 // eccentricity
+}
+
+func newEllipsoid(semiMajorAxis, inverseFlattening float64) ellipsoid {
+	f := 1 / inverseFlattening
+	e2 := 2*f - f*f
+
+	return ellipsoid{a: semiMajorAxis, e2: e2, e: math.Sqrt(e2)}
+}
+
+// toGeocentric converts geographic coordinates (radians) on this ellipsoid to geocentric (cartesian) coordinates.
+func (el ellipsoid) toGeocentric(lon, lat float64) (float64, float64, float64) {
+	sinLat := math.Sin(lat)
+	nu := el.a / math.Sqrt(1-el.e2*sinLat*sinLat)
+
+	return nu * math.Cos(lat) * math.Cos(lon), nu * math.Cos(lat) * math.Sin(lon), nu * (1 - el.e2) * sinLat
+}
+
+// fromGeocentric converts geocentric (cartesian) coordinates to geographic coordinates (radians) on this ellipsoid.
+func (el ellipsoid) fromGeocentric(x, y, z float64) (float64, float64) {
+	p := math.Hypot(x, y)
+	lat := math.Atan2(z, p*(1-el.e2))
+	for range 10 {
+		sinLat := math.Sin(lat)
+		nu := el.a / math.Sqrt(1-el.e2*sinLat*sinLat)
+		next := math.Atan2(z+el.e2*nu*sinLat, p)
+		if math.Abs(next-lat) < 1e-12 {
+			lat = next
+			break
+		}
+		lat = next
+	}
+
+	return math.Atan2(y, x), lat
+}
+
+func radians(deg float64) float64 {
+	return deg * math.Pi / 180
+}
+
+func degrees(rad float64) float64 {
+	return rad * 180 / math.Pi
+}
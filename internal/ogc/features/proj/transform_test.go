@@ -0,0 +1,106 @@
+package proj
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestObliqueStereographic(t *testing.T) {
+	// example from IOGP Guidance Note 7-2 (Amersfoort / RD New, without datum transformation)
+	p := newRDNew().stereo
+	x, y := p.forward(radians(6), radians(53))
+	assert.InDelta(t, 196105.283, x, 0.01)
+	assert.InDelta(t, 557057.739, y, 0.01)
+
+	lon, lat := p.inverse(196105.283, 557057.739)
+	assert.InDelta(t, 6, degrees(lon), 1e-8)
+	assert.InDelta(t, 53, degrees(lat), 1e-8)
+}
+
+func TestLambertAzimuthalEqualArea(t *testing.T) {
+	// example from IOGP Guidance Note 7-2 (ETRS89 / LAEA Europe)
+	p := projections["EPSG:3035"]
+	x, y := p.Forward(5, 50)
+	assert.InDelta(t, 3962799.45, x, 0.01)
+	assert.InDelta(t, 2999718.85, y, 0.01)
+
+	lon, lat := p.Inverse(3962799.45, 2999718.85)
+	assert.InDelta(t, 5, lon, 1e-7)
+	assert.InDelta(t, 50, lat, 1e-7)
+}
+
+func TestNewTransformer(t *testing.T) {
+	tests := []struct {
+		name             string
+		source, target   string
+		x, y             float64
+		wantX, wantY     float64
+		delta            float64
+		wantInverseDelta float64
+	}{
+		{
+			name:   "RD to WGS84, Amersfoort",
+			source: "EPSG:28992", target: "EPSG:4326",
+			x: 155000, y: 463000,
+			wantX: 5.387206, wantY: 52.155174,
+			delta: 1e-5, wantInverseDelta: 0.01,
+		},
+		{
+			name:   "WGS84 to web mercator",
+			source: "EPSG:4326", target: "EPSG:3857",
+			x: 5.387206, y: 52.155174,
+			wantX: 599701.03, wantY: 6828231.68,
+			delta: 0.01, wantInverseDelta: 1e-9,
+		},
+		{
+			name:   "RD to web mercator",
+			source: "EPSG:28992", target: "EPSG:3857",
+			x: 155000, y: 463000,
+			wantX: 599701.03, wantY: 6828231.68,
+			delta: 1, wantInverseDelta: 0.01,
+		},
+		{
+			name:   "RD to ETRS89 LAEA",
+			source: "EPSG:28992", target: "EPSG:3035",
+			x: 155000, y: 463000,
+			wantX: 4005541.5, wantY: 3237257.6,
+			delta: 1, wantInverseDelta: 0.01,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transform, err := NewTransformer(tt.source, tt.target)
+			require.NoError(t, err)
+			x, y := transform(tt.x, tt.y)
+			assert.InDelta(t, tt.wantX, x, tt.delta)
+			assert.InDelta(t, tt.wantY, y, tt.delta)
+
+			inverse, err := NewTransformer(tt.target, tt.source)
+			require.NoError(t, err)
+			origX, origY := inverse(x, y)
+			assert.InDelta(t, tt.x, origX, tt.wantInverseDelta)
+			assert.InDelta(t, tt.y, origY, tt.wantInverseDelta)
+		})
+	}
+}
+
+func TestNewTransformer_Unsupported(t *testing.T) {
+	_, err := NewTransformer("EPSG:28992", "EPSG:25831")
+	require.ErrorContains(t, err, "transformation to srs 'EPSG:25831' isn't supported")
+	_, err = NewTransformer("EPSG:25831", "EPSG:28992")
+	require.ErrorContains(t, err, "transformation from srs 'EPSG:25831' isn't supported")
+
+	same, err := NewTransformer("EPSG:28992", "EPSG:28992")
+	require.NoError(t, err)
+	x, y := same(1, 2)
+	assert.InDelta(t, 1.0, x, 0)
+	assert.InDelta(t, 2.0, y, 0)
+}
+
+func TestWebMercator_Clamped(t *testing.T) {
+	_, y := webMercator{}.Forward(0, 90)
+	assert.False(t, math.IsInf(y, 0))
+}
@@ -0,0 +1,190 @@
+package features
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/PDOK/gokoala/config"
+	"github.com/PDOK/gokoala/internal/ogc/common/geospatial"
+	"github.com/PDOK/gokoala/internal/ogc/features/datasources/common"
+	"github.com/PDOK/gokoala/internal/ogc/features/datasources/geopackage"
+	"github.com/PDOK/gokoala/internal/ogc/features/datasources/postgres"
+	"github.com/PDOK/gokoala/internal/ogc/features/domain"
+)
+
+// InspectDatasource reads all tables in the given datasource (GeoPackage or Postgres), including schema,
+// extent and indexes. Used as input for NewStarterConfig.
+func InspectDatasource(datasource config.Datasource, externalFidColumn string) ([]common.TableInspection, error) {
+	switch {
+	case datasource.GeoPackage != nil:
+		return geopackage.Inspect(*datasource.GeoPackage, externalFidColumn)
+	case datasource.Postgres != nil:
+		return postgres.Inspect(*datasource.Postgres, externalFidColumn)
+	default:
+		return nil, errors.New("got unknown datasource type")
+	}
+}
+
+// NewStarterConfig generates a config to serve the given (inspected) tables as OGC API Features. Titles
+// and descriptions are derived from the tables, extents and temporal properties from the data and
+// queryables from the indexed columns. Meant as a starting point, so review the result before use.
+func NewStarterConfig(title string, baseURL config.URL, datasource config.Datasource,
+	tables []common.TableInspection) *config.Config {
+
+	features := &config.OgcAPIFeatures{Datasources: &config.Datasources{}}
+	if len(tables) > 0 && tables[0].Schema.HasExternalFid() {
+		setExternalFid(&datasource, tables[0].Schema)
+	}
+	if datasource.Postgres != nil {
+		// Postgres always transforms on-the-fly, offer features in the SRS of each table next to WGS84
+		otf := config.OnTheFlyDatasource{Datasource: datasource}
+		for _, table := range tables {
+			srs := sridToSrs(table.SRID)
+			if srs != "" && !slices.ContainsFunc(otf.SupportedSrs, func(s config.OnTheFlySupportedSrs) bool { return s.Srs == srs }) {
+				otf.SupportedSrs = append(otf.SupportedSrs, config.OnTheFlySupportedSrs{Srs: srs})
+			}
+		}
+		features.Datasources.OnTheFly = []config.OnTheFlyDatasource{otf}
+	} else {
+		for _, table := range tables {
+			if sridToSrs(table.SRID) != "" {
+				log.Printf("Warning: table %s uses SRID %d, features in the default datasource should be in WGS84. "+
+					"Configure this GeoPackage as additional datasource instead", table.Name, table.SRID)
+			}
+		}
+		features.Datasources.DefaultWGS84 = &datasource
+	}
+
+	for _, table := range tables {
+		features.Collections = append(features.Collections, newStarterCollection(table))
+	}
+
+	licenseURL, _ := url.Parse("https://creativecommons.org/publicdomain/zero/1.0/deed.nl")
+	return &config.Config{
+		Version:           "1.0.0",
+		Title:             title,
+		ServiceIdentifier: strings.Join(strings.Fields(title), ""),
+		Abstract:          "Contains " + title,
+		License:           config.License{Name: "CC0 1.0", URL: config.URL{URL: licenseURL}},
+		BaseURL:           baseURL,
+		OgcAPI:            config.OgcAPI{Features: features},
+	}
+}
+
+func newStarterCollection(table common.TableInspection) config.FeaturesCollection {
+	title := humanize(table.Name)
+	description := fmt.Sprintf("Attributes from table '%s'", table.Name)
+	if table.Type == geospatial.Features {
+		description = fmt.Sprintf("Features of type %s from table '%s'", table.GeometryType, table.Name)
+	}
+	collection := config.FeaturesCollection{
+		ID: table.CollectionID,
+		Metadata: &config.GeoSpatialCollectionMetadata{
+			Title:       &title,
+			Description: &description,
+		},
+	}
+	if table.CollectionID != table.Name {
+		collection.TableName = &table.Name
+	}
+
+	if table.Bbox != nil {
+		collection.Metadata.Extent = &config.Extent{Srs: sridToSrs(table.SRID)}
+		for _, coord := range table.Bbox {
+			collection.Metadata.Extent.Bbox = append(collection.Metadata.Extent.Bbox, strconv.FormatFloat(coord, 'f', -1, 64))
+		}
+	}
+	if len(table.TemporalColumns) > 0 && table.Interval != nil {
+		collection.Metadata.TemporalProperties = &config.TemporalProperties{
+			StartDate: table.TemporalColumns[0],
+			EndDate:   table.TemporalColumns[len(table.TemporalColumns)-1],
+		}
+		if collection.Metadata.Extent == nil {
+			collection.Metadata.Extent = &config.Extent{}
+		}
+		for _, value := range table.Interval {
+			collection.Metadata.Extent.Interval = append(collection.Metadata.Extent.Interval, intervalValue(table.Name, value))
+		}
+	}
+
+	for _, field := range table.Schema.Fields {
+		if field.FeatureRelation != nil {
+			if field.FeatureRelation.CollectionID == "" {
+				log.Printf("Warning: column %s in table %s looks like a relation, but no collection found "+
+					"for '%s'", field.Name, table.Name, field.FeatureRelation.Name)
+			} else {
+				log.Printf("derived relation '%s' from collection %s to collection %s",
+					field.FeatureRelation.Name, table.CollectionID, field.FeatureRelation.CollectionID)
+			}
+			continue
+		}
+		if !slices.Contains(table.IndexedColumns, field.Name) || field.IsFid || field.IsExternalFid ||
+			field.IsPrimaryGeometry {
+			continue
+		}
+		collection.Filters.Properties = append(collection.Filters.Properties, config.Queryable{Name: field.Name})
+	}
+
+	return collection
+}
+
+// intervalValue formats the given date(time) as a value of a temporal extent, this is a quoted UTC
+// datetime or null. Dates and datetimes are read as-is from GeoPackages, so these come in various formats.
+func intervalValue(tableName string, value string) string {
+	if value == "" {
+		return "null"
+	}
+	for _, layout := range []string{time.RFC3339Nano, time.DateTime, time.DateOnly, "2006-01-02T15:04:05"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return strconv.Quote(t.UTC().Format("2006-01-02T15:04:05Z"))
+		}
+	}
+	log.Printf("Warning: temporal extent of table %s contains an unknown datetime '%s', "+
+		"leaving the interval open-ended", tableName, value)
+
+	return "null"
+}
+
+func setExternalFid(datasource *config.Datasource, schema *domain.Schema) {
+	for _, field := range schema.Fields {
+		if !field.IsExternalFid {
+			continue
+		}
+		switch {
+		case datasource.GeoPackage != nil && datasource.GeoPackage.Local != nil:
+			datasource.GeoPackage.Local.ExternalFid = field.Name
+		case datasource.GeoPackage != nil && datasource.GeoPackage.Cloud != nil:
+			datasource.GeoPackage.Cloud.ExternalFid = field.Name
+		case datasource.Postgres != nil:
+			datasource.Postgres.ExternalFid = field.Name
+		}
+	}
+}
+
+// sridToSrs returns the SRS (e.g. EPSG:28992) for the given SRID, empty for WGS84 since that's the default.
+func sridToSrs(srid int) string {
+	if srid <= 0 || srid == domain.WGS84SRID || srid == domain.WGS84SRIDPostgis {
+		return ""
+	}
+
+	return domain.EPSGPrefix + strconv.Itoa(srid)
+}
+
+// humanize turns a table name like 'my_table' into a title like 'My table'.
+func humanize(name string) string {
+	words := strings.FieldsFunc(name, func(r rune) bool { return r == '_' || r == '-' })
+	if len(words) == 0 {
+		return name
+	}
+	result := []rune(strings.Join(words, " "))
+	result[0] = unicode.ToUpper(result[0])
+
+	return string(result)
+}
@@ -0,0 +1,169 @@
+package features
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/PDOK/gokoala/config"
+	"github.com/PDOK/gokoala/internal/ogc/common/geospatial"
+	"github.com/PDOK/gokoala/internal/ogc/features/datasources/common"
+	"github.com/PDOK/gokoala/internal/ogc/features/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestNewStarterConfig(t *testing.T) {
+	baseURL, _ := url.Parse("http://localhost:8080")
+	tables := starterTables()
+
+	cfg := NewStarterConfig("My Dataset", config.URL{URL: baseURL},
+		config.Datasource{Postgres: &config.Postgres{Host: "localhost"}}, tables)
+
+	assert.Equal(t, "MyDataset", cfg.ServiceIdentifier)
+	require.NotNil(t, cfg.OgcAPI.Features)
+	assert.Nil(t, cfg.OgcAPI.Features.Datasources.DefaultWGS84)
+	require.Len(t, cfg.OgcAPI.Features.Datasources.OnTheFly, 1)
+	otf := cfg.OgcAPI.Features.Datasources.OnTheFly[0]
+	assert.Equal(t, "external_fid", otf.Datasource.Postgres.ExternalFid)
+	assert.Equal(t, []config.OnTheFlySupportedSrs{{Srs: "EPSG:28992"}}, otf.SupportedSrs)
+
+	require.Len(t, cfg.OgcAPI.Features.Collections, 2)
+	roads := cfg.OgcAPI.Features.Collections[0]
+	assert.Equal(t, "road_segments", roads.ID)
+	assert.Equal(t, "Road_Segments", *roads.TableName)
+	assert.Equal(t, "Road Segments", *roads.Metadata.Title)
+	assert.Equal(t, "Features of type LINESTRING from table 'Road_Segments'", *roads.Metadata.Description)
+	assert.Equal(t, "EPSG:28992", roads.Metadata.Extent.Srs)
+	assert.Equal(t, []string{"10.5", "20", "30", "40.25"}, roads.Metadata.Extent.Bbox)
+	assert.Equal(t, []string{`"2020-01-01T00:00:00Z"`, "null"}, roads.Metadata.Extent.Interval)
+	assert.Equal(t, "valid_from", roads.Metadata.TemporalProperties.StartDate)
+	assert.Equal(t, "valid_to", roads.Metadata.TemporalProperties.EndDate)
+	assert.Equal(t, []config.Queryable{{Name: "name"}}, roads.Filters.Properties)
+
+	city := cfg.OgcAPI.Features.Collections[1]
+	assert.Equal(t, "city", city.ID)
+	assert.Nil(t, city.TableName)
+	assert.Equal(t, "Attributes from table 'city'", *city.Metadata.Description)
+	assert.Nil(t, city.Metadata.Extent)
+	assert.Empty(t, city.Filters.Properties)
+}
+
+func TestNewStarterConfig_GeoPackage(t *testing.T) {
+	baseURL, _ := url.Parse("http://localhost:8080")
+	tables := []common.TableInspection{
+		{
+			Table: &common.Table{
+				Name:         "addresses",
+				Type:         geospatial.Features,
+				GeometryType: "POINT",
+				Schema: &domain.Schema{Fields: []domain.Field{
+					{Name: "fid", Type: "INTEGER", IsFid: true},
+					{Name: "geom", Type: "POINT", IsPrimaryGeometry: true},
+				}},
+			},
+			CollectionID: "addresses",
+			SRID:         4326,
+			Bbox:         []float64{5, 52, 6, 53},
+		},
+	}
+	datasource := config.Datasource{GeoPackage: &config.GeoPackage{Local: &config.GeoPackageLocal{File: "addresses.gpkg"}}}
+
+	cfg := NewStarterConfig("Addresses", config.URL{URL: baseURL}, datasource, tables)
+
+	require.NotNil(t, cfg.OgcAPI.Features.Datasources.DefaultWGS84)
+	assert.Empty(t, cfg.OgcAPI.Features.Datasources.OnTheFly)
+	assert.Empty(t, cfg.OgcAPI.Features.Datasources.DefaultWGS84.GeoPackage.Local.ExternalFid)
+	require.Len(t, cfg.OgcAPI.Features.Collections, 1)
+	assert.Empty(t, cfg.OgcAPI.Features.Collections[0].Metadata.Extent.Srs)
+	assert.Nil(t, cfg.OgcAPI.Features.Collections[0].Metadata.TemporalProperties)
+}
+
+// Generated starter configs should be accepted as-is by GoKoala
+func TestNewStarterConfig_RoundTrip(t *testing.T) {
+	t.Setenv("DB_PASSWORD", "secret") // the generated config references the password
+	baseURL, _ := url.Parse("http://localhost:8080")
+	datasources := map[string]config.Datasource{
+		"postgres":   {Postgres: &config.Postgres{Host: "localhost", Pass: "${env:DB_PASSWORD}"}},
+		"geopackage": {GeoPackage: &config.GeoPackage{Local: &config.GeoPackageLocal{File: "addresses.gpkg"}}},
+	}
+	for name, datasource := range datasources {
+		t.Run(name, func(t *testing.T) {
+			cfg := NewStarterConfig("My Dataset", config.URL{URL: baseURL}, datasource, starterTables())
+
+			file := filepath.Join(t.TempDir(), "config.yaml")
+			out, err := yaml.Marshal(cfg)
+			require.NoError(t, err)
+			require.NoError(t, os.WriteFile(file, out, 0o600))
+
+			loaded, err := config.NewConfig(file)
+			require.NoError(t, err)
+			require.NotNil(t, loaded.OgcAPI.Features)
+			assert.Len(t, loaded.OgcAPI.Features.Collections, 2)
+		})
+	}
+}
+
+func TestIntervalValue(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{value: "", want: "null"},
+		{value: "2020-01-01", want: `"2020-01-01T00:00:00Z"`},
+		{value: "2020-01-01 12:30:00", want: `"2020-01-01T12:30:00Z"`},
+		{value: "2020-01-01T12:30:00", want: `"2020-01-01T12:30:00Z"`},
+		{value: "2020-01-01T12:30:00.123Z", want: `"2020-01-01T12:30:00Z"`},
+		{value: "2020-01-01T12:30:00+02:00", want: `"2020-01-01T10:30:00Z"`},
+		{value: "yesterday", want: "null"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			assert.Equal(t, tt.want, intervalValue("table", tt.value))
+		})
+	}
+}
+
+func starterTables() []common.TableInspection {
+	return []common.TableInspection{
+		{
+			Table: &common.Table{
+				Name:               "Road_Segments",
+				Type:               geospatial.Features,
+				GeometryColumnName: "geom",
+				GeometryType:       "LINESTRING",
+				Schema: &domain.Schema{Fields: []domain.Field{
+					{Name: "fid", Type: "INTEGER", IsFid: true},
+					{Name: "geom", Type: "LINESTRING", IsPrimaryGeometry: true},
+					{Name: "external_fid", Type: "TEXT", IsExternalFid: true},
+					{Name: "name", Type: "TEXT"},
+					{Name: "type", Type: "TEXT"},
+					{Name: "valid_from", Type: "DATE"},
+					{Name: "valid_to", Type: "DATE"},
+					{Name: "city_external_fid", Type: "TEXT",
+						FeatureRelation: &domain.FeatureRelation{Name: "city", CollectionID: "city"}},
+				}},
+			},
+			CollectionID:    "road_segments",
+			SRID:            28992,
+			Bbox:            []float64{10.5, 20, 30, 40.25},
+			TemporalColumns: []string{"valid_from", "valid_to"},
+			Interval:        []string{"2020-01-01", ""},
+			IndexedColumns:  []string{"fid", "name", "city_external_fid"},
+		},
+		{
+			Table: &common.Table{
+				Name: "city",
+				Type: geospatial.Attributes,
+				Schema: &domain.Schema{Fields: []domain.Field{
+					{Name: "fid", Type: "INTEGER", IsFid: true},
+					{Name: "external_fid", Type: "TEXT", IsExternalFid: true},
+					{Name: "name", Type: "TEXT"},
+				}},
+			},
+			CollectionID: "city",
+		},
+	}
+}
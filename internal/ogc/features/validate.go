@@ -0,0 +1,78 @@
+package features
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/PDOK/gokoala/config"
+	ds "github.com/PDOK/gokoala/internal/ogc/features/datasources"
+	"github.com/PDOK/gokoala/internal/ogc/features/domain"
+)
+
+// ValidateDatasources connects to each datasource configured for the given (features or search)
+// collections without serving them. Creating a datasource asserts the configured tables, columns
+// and indexes exist. Additionally, the schema and queryables of each collection are read.
+// Returns the validation error per collection ID, or nil when the collection is valid.
+func ValidateDatasources(cfg config.FeaturesAndSearchConfig) map[string]error {
+	configured := make(map[DatasourceKey]*datasourceConfig)
+	configureCollectionDatasources(cfg, configured)
+	configureTopLevelDatasources(cfg, configured)
+
+	result := make(map[string]error)
+	for _, coll := range cfg.Collections() {
+		result[coll.GetID()] = nil
+	}
+
+	created := make(map[config.Datasource]ds.Datasource)
+	failed := make(map[config.Datasource]error)
+	defer func() {
+		for _, datasource := range created {
+			datasource.Close()
+		}
+	}()
+
+	// sort keys for predictable order of errors
+	keys := slices.SortedFunc(maps.Keys(configured), func(a, b DatasourceKey) int {
+		return cmp.Or(cmp.Compare(a.collectionID, b.collectionID), cmp.Compare(a.srid, b.srid))
+	})
+	for _, key := range keys {
+		dsCfg := configured[key]
+		if dsCfg == nil {
+			continue
+		}
+		datasource, ok := created[dsCfg.ds]
+		if !ok {
+			err, alreadyFailed := failed[dsCfg.ds]
+			if !alreadyFailed {
+				datasource, err = createDatasource(cfg, dsCfg.ds, dsCfg.transformOnTheFly)
+				if err != nil {
+					failed[dsCfg.ds] = err
+				} else {
+					created[dsCfg.ds] = datasource
+				}
+			}
+			if err != nil {
+				result[key.collectionID] = errors.Join(result[key.collectionID],
+					fmt.Errorf("failed to connect to datasource for SRID %d: %w", key.srid, err))
+				continue
+			}
+		}
+		// the schema should be the same regardless of CRS, so only check it in WGS84
+		if key.srid == domain.WGS84SRID {
+			if _, _, err := datasource.GetSchema(key.collectionID); err != nil {
+				result[key.collectionID] = errors.Join(result[key.collectionID],
+					fmt.Errorf("failed to read schema and queryables: %w", err))
+			}
+		}
+	}
+	for collectionID := range result {
+		if !slices.ContainsFunc(keys, func(k DatasourceKey) bool { return k.collectionID == collectionID }) {
+			result[collectionID] = errors.New("no datasource configured for this collection")
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,377 @@
+package features_search
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/PDOK/gokoala/internal/engine"
+	"github.com/PDOK/gokoala/internal/ogc/features"
+	ds "github.com/PDOK/gokoala/internal/ogc/features/datasources"
+	fd "github.com/PDOK/gokoala/internal/ogc/features/domain"
+	d "github.com/PDOK/gokoala/internal/ogc/features_search/domain"
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	batchPath = searchPath + "/batch"
+
+	batchMaxQueries  = 10000
+	batchMaxBodySize = 10 << 20 // 10 MiB
+
+	// number of search queries executed in parallel for a single batch request,
+	// kept low to prevent a batch from claiming the whole connection pool
+	batchConcurrency = 4
+
+	// a match is considered ambiguous when the runner-up scores at least this fraction of the best match
+	ambiguousScoreRatio = 0.95
+
+	mediaTypeCSV = "text/csv"
+
+	csvColumnID          = "id"
+	csvColumnQuery       = "q"
+	csvColumnCollections = "collections"
+
+	propScore = "score"
+)
+
+type batchStatus string
+
+const (
+	batchStatusMatch     batchStatus = "match"
+	batchStatusAmbiguous batchStatus = "ambiguous"
+	batchStatusNoMatch   batchStatus = "no_match"
+	batchStatusError     batchStatus = "error"
+)
+
+var batchKnownParams = map[string]struct{}{
+	features.CrsParam: {},
+}
+
+// batchQuery a single search query in a batch request
+type batchQuery struct {
+	// optional identifier supplied by the client to correlate queries and results
+	ID string `json:"id,omitempty"`
+
+	// the search term(s)
+	Query string `json:"q"`
+
+	// optional collections (and their params) to search in, overrides the collections in the query string
+	Collections d.CollectionsWithParams `json:"-"`
+}
+
+func (bq *batchQuery) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		ID          any                       `json:"id"`
+		Query       string                    `json:"q"`
+		Collections map[string]map[string]any `json:"collections"`
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber() // allow both numeric and textual identifiers and versions
+	if err := decoder.Decode(&raw); err != nil {
+		return err
+	}
+	if raw.ID != nil {
+		bq.ID = fmt.Sprint(raw.ID)
+	}
+	bq.Query = raw.Query
+	if len(raw.Collections) > 0 {
+		bq.Collections = make(d.CollectionsWithParams, len(raw.Collections))
+		for name, params := range raw.Collections {
+			bq.Collections[name] = make(d.CollectionParams, len(params))
+			for key, value := range params {
+				bq.Collections[name][key] = fmt.Sprint(value)
+			}
+		}
+	}
+	return nil
+}
+
+// batchResult best match for a single search query in a batch request
+type batchResult struct {
+	Index   int         `json:"index"`
+	ID      string      `json:"id,omitempty"`
+	Query   string      `json:"q"`
+	Status  batchStatus `json:"status"`
+	Score   *float64    `json:"score,omitempty"`
+	Feature *fd.Feature `json:"feature,omitempty"`
+	Error   string      `json:"error,omitempty"`
+}
+
+type batchResults struct {
+	Timestamp       string        `json:"timeStamp"`
+	NumberOfQueries int           `json:"numberOfQueries"`
+	NumberMatched   int           `json:"numberMatched"`
+	Results         []batchResult `json:"results"`
+}
+
+// SearchBatch geocode many search queries at once, handle requests like "POST /search/batch?mycollection[version]=1"
+// with a JSON array or CSV document of search queries in the request body. Returns the best match per query.
+func (s *Search) SearchBatch() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		r.Body = http.MaxBytesReader(w, r.Body, batchMaxBodySize)
+
+		// Validate
+		if err := s.engine.OpenAPI.ValidateRequest(r); err != nil {
+			engine.RenderProblem(engine.ProblemBadRequest, w, err.Error())
+			return
+		}
+		defaultCollections, outputSRID, contentCrs, err := parseBatchQueryParams(r.URL.Query())
+		if err != nil {
+			engine.RenderProblem(engine.ProblemBadRequest, w, err.Error())
+			return
+		}
+		queries, err := parseBatchQueries(r)
+		if err != nil {
+			engine.RenderProblem(engine.ProblemBadRequest, w, err.Error())
+			return
+		}
+		w.Header().Add(engine.HeaderContentCrs, contentCrs.ToLink())
+
+		// Perform actual search, with bounded concurrency
+		results := make([]batchResult, len(queries))
+		g, ctx := errgroup.WithContext(r.Context())
+		g.SetLimit(batchConcurrency)
+		for i, query := range queries {
+			g.Go(func() error {
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+				results[i] = s.searchBestMatch(ctx, i, query, defaultCollections, outputSRID, contentCrs)
+				return nil
+			})
+		}
+		if err = g.Wait(); err != nil {
+			handleQueryError(w, err)
+			return
+		}
+
+		// Output
+		matched := 0
+		for _, result := range results {
+			if result.Status == batchStatusMatch || result.Status == batchStatusAmbiguous {
+				matched++
+			}
+		}
+		s.engine.Serve(w, r,
+			engine.ServeJSON(batchResults{
+				Timestamp:       now().Format(time.RFC3339),
+				NumberOfQueries: len(results),
+				NumberMatched:   matched,
+				Results:         results,
+			}),
+			engine.ServeValidation(false /* performed earlier */, s.json.validateResponse),
+			engine.ServeContentType(engine.MediaTypeJSON))
+	}
+}
+
+func (s *Search) searchBestMatch(ctx context.Context, index int, query batchQuery, defaultCollections d.CollectionsWithParams,
+	outputSRID fd.SRID, contentCrs fd.ContentCrs) batchResult {
+
+	result := batchResult{Index: index, ID: query.ID, Query: query.Query}
+
+	collections := query.Collections
+	if len(collections) == 0 {
+		collections = defaultCollections
+	}
+	searchTerms, err := parseSearchTerms(url.Values{queryParam: []string{query.Query}})
+	if err == nil {
+		err = validateCollections(collections)
+	}
+	if err != nil {
+		result.Status = batchStatusError
+		result.Error = err.Error()
+		return result
+	}
+
+	searchQuery, err := s.queryExpansion.Expand(ctx, searchTerms)
+	if err != nil {
+		log.Printf("failed to expand search query %d in batch, error: %v\n", index, err)
+		result.Status = batchStatusError
+		result.Error = "failed to expand search query"
+		return result
+	}
+	fc, err := s.datasource.SearchFeaturesAcrossCollections(ctx, ds.FeaturesSearchCriteria{
+		SearchQuery: *searchQuery,
+		Settings:    s.engine.Config.OgcAPI.FeaturesSearch.SearchSettings,
+		Limit:       2, // best match + runner-up to detect ambiguity
+		OutputSRID:  outputSRID,
+	}, s.axisOrderBySRID[outputSRID.GetOrDefault()], collections)
+	if err != nil {
+		// log error but don't include sensitive information from the datasource in the result
+		log.Printf("failed to fulfill search query %d in batch, error: %v\n", index, err)
+		result.Status = batchStatusError
+		result.Error = "failed to fulfill search query"
+		return result
+	}
+	if err = s.enrichFeaturesWithHref(fc, contentCrs); err != nil {
+		result.Status = batchStatusError
+		result.Error = err.Error()
+		return result
+	}
+
+	result.Status, result.Feature, result.Score = bestMatch(fc.Features)
+	return result
+}
+
+// bestMatch returns the highest scoring feature, and whether it's a clear match or an ambiguous one.
+// Features are expected to be sorted by score (descending), as returned by the datasource.
+func bestMatch(feats []*fd.Feature) (batchStatus, *fd.Feature, *float64) {
+	if len(feats) == 0 {
+		return batchStatusNoMatch, nil, nil
+	}
+	best := feats[0]
+	bestScore := score(best)
+	status := batchStatusMatch
+	if len(feats) > 1 && bestScore != nil {
+		runnerUpScore := score(feats[1])
+		if runnerUpScore != nil && *runnerUpScore >= *bestScore*ambiguousScoreRatio {
+			status = batchStatusAmbiguous
+		}
+	}
+	return status, best, bestScore
+}
+
+func score(feat *fd.Feature) *float64 {
+	var result float64
+	switch v := feat.Properties.Value(propScore).(type) {
+	case float64:
+		result = v
+	case float32:
+		result = float64(v)
+	case int64:
+		result = float64(v)
+	case int:
+		result = float64(v)
+	default:
+		return nil
+	}
+	return &result
+}
+
+func parseBatchQueryParams(query url.Values) (defaultCollections d.CollectionsWithParams, outputSRID fd.SRID,
+	contentCrs fd.ContentCrs, err error) {
+
+	for param := range query {
+		if deepObjectParamRegex.MatchString(param) {
+			continue
+		}
+		if _, ok := batchKnownParams[param]; !ok {
+			return nil, outputSRID, contentCrs, fmt.Errorf("unknown query parameter(s) found: %s", param)
+		}
+	}
+	// collections in the query string are optional, since these can also be specified per search query
+	defaultCollections, _ = parseCollections(query)
+	outputSRID, err = features.ParseCrsToSRID(query, features.CrsParam)
+	contentCrs = features.ParseCrsToContentCrs(query)
+	return
+}
+
+// parseBatchQueries parses search queries from the request body, either as a JSON array or as CSV.
+func parseBatchQueries(r *http.Request) ([]batchQuery, error) {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get(engine.HeaderContentType))
+	if err != nil {
+		mediaType = engine.MediaTypeJSON
+	}
+	var queries []batchQuery
+	switch mediaType {
+	case engine.MediaTypeJSON:
+		queries, err = parseBatchQueriesJSON(r.Body)
+	case mediaTypeCSV:
+		queries, err = parseBatchQueriesCSV(r.Body)
+	default:
+		return nil, fmt.Errorf("unsupported content type '%s', use '%s' or '%s'",
+			mediaType, engine.MediaTypeJSON, mediaTypeCSV)
+	}
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return nil, fmt.Errorf("request body too large, maximum is %d bytes", maxBytesErr.Limit)
+		}
+		return nil, err
+	}
+	if len(queries) == 0 {
+		return nil, errors.New("no search queries provided in request body")
+	}
+	if len(queries) > batchMaxQueries {
+		return nil, fmt.Errorf("too many search queries in request body (%d), maximum is %d", len(queries), batchMaxQueries)
+	}
+	return queries, nil
+}
+
+func parseBatchQueriesJSON(body io.Reader) ([]batchQuery, error) {
+	var queries []batchQuery
+	if err := json.NewDecoder(body).Decode(&queries); err != nil {
+		return nil, fmt.Errorf("failed to parse search queries, expected a JSON array: %w", err)
+	}
+	return queries, nil
+}
+
+// parseBatchQueriesCSV parses CSV with a header row containing at least a 'q' column. Optionally, an 'id' column
+// and a 'collections' column containing deep object params (e.g. 'foo[version]=1&bar[version]=2') may be present.
+func parseBatchQueriesCSV(body io.Reader) ([]batchQuery, error) {
+	reader := csv.NewReader(body)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, column := range header {
+		columns[strings.ToLower(strings.TrimSpace(column))] = i
+	}
+	queryColumn, ok := columns[csvColumnQuery]
+	if !ok {
+		return nil, fmt.Errorf("CSV header should contain a '%s' column", csvColumnQuery)
+	}
+
+	var queries []batchQuery
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CSV: %w", err)
+		}
+		query := batchQuery{Query: record[queryColumn]}
+		if i, ok := columns[csvColumnID]; ok {
+			query.ID = record[i]
+		}
+		if i, ok := columns[csvColumnCollections]; ok && record[i] != "" {
+			params, err := url.ParseQuery(record[i])
+			if err != nil {
+				return nil, fmt.Errorf("invalid collections on line %d of CSV: %w", line, err)
+			}
+			if query.Collections, err = parseCollections(params); err != nil {
+				return nil, fmt.Errorf("invalid collections on line %d of CSV: %w", line, err)
+			}
+		}
+		queries = append(queries, query)
+	}
+	return queries, nil
+}
+
+func validateCollections(collections d.CollectionsWithParams) error {
+	if len(collections) == 0 {
+		return errors.New("no collection(s) specified for search query, specify these " +
+			"per search query or in the query string. For example: 'foo[version]=1'")
+	}
+	for name, params := range collections {
+		if version, ok := params[d.VersionParam]; !ok || version == "" {
+			return fmt.Errorf("no version specified for collection %s", name)
+		}
+	}
+	return nil
+}
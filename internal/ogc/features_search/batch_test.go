@@ -0,0 +1,166 @@
+package features_search
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	fd "github.com/PDOK/gokoala/internal/ogc/features/domain"
+	d "github.com/PDOK/gokoala/internal/ogc/features_search/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseBatchQueries(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		want        []batchQuery
+		wantErr     string
+	}{
+		{
+			name:        "JSON array",
+			contentType: "application/json",
+			body: `[
+				{"id": 1, "q": "Abbewier 1 Oudeschild"},
+				{"id": "two", "q": "Achterom 10", "collections": {"addresses": {"version": 1, "relevance": 0.8}}}
+			]`,
+			want: []batchQuery{
+				{ID: "1", Query: "Abbewier 1 Oudeschild"},
+				{ID: "two", Query: "Achterom 10", Collections: d.CollectionsWithParams{
+					"addresses": {"version": "1", "relevance": "0.8"},
+				}},
+			},
+		},
+		{
+			name:        "CSV with all columns",
+			contentType: "text/csv; charset=utf-8",
+			body: "id,q,collections\n" +
+				"1,Abbewier 1 Oudeschild,addresses[version]=1&buildings[version]=2\n" +
+				"2,\"Achterom 10, Den Hoorn\",\n",
+			want: []batchQuery{
+				{ID: "1", Query: "Abbewier 1 Oudeschild", Collections: d.CollectionsWithParams{
+					"addresses": {"version": "1"},
+					"buildings": {"version": "2"},
+				}},
+				{ID: "2", Query: "Achterom 10, Den Hoorn"},
+			},
+		},
+		{
+			name:        "CSV with only query column",
+			contentType: "text/csv",
+			body:        "Q\nOudeschild\n",
+			want:        []batchQuery{{Query: "Oudeschild"}},
+		},
+		{
+			name:        "CSV without query column",
+			contentType: "text/csv",
+			body:        "id,address\n1,Oudeschild\n",
+			wantErr:     "CSV header should contain a 'q' column",
+		},
+		{
+			name:        "CSV with collection without version",
+			contentType: "text/csv",
+			body:        "q,collections\nOudeschild,addresses[relevance]=1\n",
+			wantErr:     "invalid collections on line 2 of CSV",
+		},
+		{
+			name:        "Empty JSON array",
+			contentType: "application/json",
+			body:        "[]",
+			wantErr:     "no search queries provided",
+		},
+		{
+			name:        "Invalid JSON",
+			contentType: "application/json",
+			body:        `{"q": "Oudeschild"}`,
+			wantErr:     "expected a JSON array",
+		},
+		{
+			name:        "Unsupported content type",
+			contentType: "application/xml",
+			body:        "<q>Oudeschild</q>",
+			wantErr:     "unsupported content type 'application/xml'",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/search/batch", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+
+			got, err := parseBatchQueries(req)
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestParseBatchQueriesTooMany(t *testing.T) {
+	body := "q\n" + strings.Repeat("Oudeschild\n", batchMaxQueries+1)
+	req := httptest.NewRequest(http.MethodPost, "/search/batch", strings.NewReader(body))
+	req.Header.Set("Content-Type", "text/csv")
+
+	_, err := parseBatchQueries(req)
+	require.ErrorContains(t, err, "too many search queries")
+}
+
+func TestBestMatch(t *testing.T) {
+	feature := func(id string, score float64) *fd.Feature {
+		f := &fd.Feature{ID: id, Properties: fd.NewFeatureProperties(false)}
+		f.Properties.Set(propScore, score)
+		return f
+	}
+	tests := []struct {
+		name       string
+		feats      []*fd.Feature
+		wantStatus batchStatus
+		wantID     string
+		wantScore  float64
+	}{
+		{
+			name:       "no results",
+			feats:      []*fd.Feature{},
+			wantStatus: batchStatusNoMatch,
+		},
+		{
+			name:       "single result",
+			feats:      []*fd.Feature{feature("1", 0.8)},
+			wantStatus: batchStatusMatch,
+			wantID:     "1",
+			wantScore:  0.8,
+		},
+		{
+			name:       "clear best match",
+			feats:      []*fd.Feature{feature("1", 0.8), feature("2", 0.4)},
+			wantStatus: batchStatusMatch,
+			wantID:     "1",
+			wantScore:  0.8,
+		},
+		{
+			name:       "ambiguous match",
+			feats:      []*fd.Feature{feature("1", 0.8), feature("2", 0.79)},
+			wantStatus: batchStatusAmbiguous,
+			wantID:     "1",
+			wantScore:  0.8,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, feat, score := bestMatch(tt.feats)
+			assert.Equal(t, tt.wantStatus, status)
+			if tt.wantID == "" {
+				assert.Nil(t, feat)
+				assert.Nil(t, score)
+				return
+			}
+			assert.Equal(t, tt.wantID, feat.ID)
+			assert.InDelta(t, tt.wantScore, *score, 0.0001)
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package features_search
+
+import (
+	"net/http"
+
+	"github.com/PDOK/gokoala/internal/engine"
+)
+
+const (
+	debugQueryExpansionPath = "/debug/search/query-expansion"
+	debugReloadPath         = debugQueryExpansionPath + "/reload"
+)
+
+// queryExpansionDiagnostics shows how a search query is expanded before it's sent to the search index
+type queryExpansionDiagnostics struct {
+	Query                       string `json:"q"`
+	WildcardQuery               string `json:"wildcardQuery"`
+	ExactMatchQuery             string `json:"exactMatchQuery"`
+	ExactMatchQueryWithSynonyms string `json:"exactMatchQueryWithSynonyms"`
+	UntokenizedQuery            string `json:"untokenizedQuery"`
+}
+
+// registerDebugEndpoints adds endpoints to the debug server (not exposed publicly) to
+// inspect and reload the query expansion (rewrites and synonyms)
+func (s *Search) registerDebugEndpoints() {
+	s.engine.DebugRouter.Get(debugQueryExpansionPath, s.queryExpansionDiagnostics())
+	s.engine.DebugRouter.Post(debugReloadPath, s.reloadQueryExpansion())
+}
+
+func (s *Search) queryExpansionDiagnostics() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query().Get(queryParam)
+		if q == "" {
+			engine.RenderProblem(engine.ProblemBadRequest, w, "query parameter 'q' is required")
+			return
+		}
+		searchQuery, err := s.queryExpansion.Expand(r.Context(), q)
+		if err != nil {
+			engine.RenderProblem(engine.ProblemServerError, w, err.Error())
+			return
+		}
+		s.engine.Serve(w, r,
+			engine.ServeJSON(queryExpansionDiagnostics{
+				Query:                       q,
+				WildcardQuery:               searchQuery.ToWildcardQuery(),
+				ExactMatchQuery:             searchQuery.ToExactMatchQuery(false),
+				ExactMatchQueryWithSynonyms: searchQuery.ToExactMatchQuery(true),
+				UntokenizedQuery:            searchQuery.ToUntokenizedQuery(),
+			}),
+			engine.ServeValidation(false, false),
+			engine.ServeContentType(engine.MediaTypeJSON))
+	}
+}
+
+func (s *Search) reloadQueryExpansion() http.HandlerFunc {
+	return func(w http.ResponseWriter, _ *http.Request) {
+		if err := s.queryExpansion.Reload(); err != nil {
+			engine.RenderProblem(engine.ProblemBadRequest, w, err.Error())
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,65 @@
+package features_search
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/PDOK/gokoala/internal/engine"
+	"github.com/PDOK/gokoala/internal/ogc/features_search/query_expansion"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSearch_DebugEndpoints(t *testing.T) {
+	dir := t.TempDir()
+	rewritesFile := filepath.Join(dir, "rewrites.csv")
+	synonymsFile := filepath.Join(dir, "synonyms.csv")
+	require.NoError(t, os.WriteFile(rewritesFile, []byte("laan,ln\n"), 0o600))
+	require.NoError(t, os.WriteFile(synonymsFile, []byte("eerste,1ste\n"), 0o600))
+	queryExpansion, err := query_expansion.NewQueryExpansion(rewritesFile, synonymsFile, 10)
+	require.NoError(t, err)
+
+	newEngine, err := engine.NewEngine("internal/engine/testdata/config_minimal.yaml",
+		"internal/engine/testdata/test_theme.yaml", "", false, true)
+	require.NoError(t, err)
+	search := &Search{engine: newEngine, queryExpansion: queryExpansion}
+	search.registerDebugEndpoints()
+
+	doRequest := func(method, url string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		newEngine.DebugRouter.ServeHTTP(rr, httptest.NewRequest(method, url, nil))
+
+		return rr
+	}
+	expand := "http://localhost:9001" + debugQueryExpansionPath + "?q=1ste%20ln"
+	reload := "http://localhost:9001" + debugReloadPath
+
+	rr := doRequest(http.MethodGet, "http://localhost:9001"+debugQueryExpansionPath)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Contains(t, rr.Body.String(), "query parameter 'q' is required")
+
+	rr = doRequest(http.MethodGet, expand)
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, `{"q": "1ste ln", "wildcardQuery": "(1ste:* | eerste:*) & laan:*", "exactMatchQuery": "(1ste) & laan",
+		"exactMatchQueryWithSynonyms": "(1ste | eerste) & laan", "untokenizedQuery": "1ste laan"}`, rr.Body.String())
+
+	// invalid rewrites (cyclic) are refused, the previous rules remain in use
+	require.NoError(t, os.WriteFile(rewritesFile, []byte("laan,ln\nln,laan\n"), 0o600))
+	rr = doRequest(http.MethodPost, reload)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Contains(t, rr.Body.String(), "cyclic rewrite detected")
+	rr = doRequest(http.MethodGet, expand)
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), `"untokenizedQuery":"1ste laan"`)
+
+	// valid rewrites are applied
+	require.NoError(t, os.WriteFile(rewritesFile, []byte("lane,ln\n"), 0o600))
+	rr = doRequest(http.MethodPost, reload)
+	assert.Equal(t, http.StatusNoContent, rr.Code)
+	rr = doRequest(http.MethodGet, expand)
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), `"untokenizedQuery":"1ste lane"`)
+}
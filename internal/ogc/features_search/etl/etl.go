@@ -3,23 +3,45 @@
 package etl
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/PDOK/gokoala/internal/ogc/features_search/etl/config"
 	"github.com/PDOK/gokoala/internal/ogc/features_search/etl/extract"
 	"github.com/PDOK/gokoala/internal/ogc/features_search/etl/load"
 	t "github.com/PDOK/gokoala/internal/ogc/features_search/etl/transform"
+	"golang.org/x/sync/errgroup"
 	"golang.org/x/text/language"
 )
 
+const progressInterval = 30 * time.Second
+
+// Operation values expected in the operation column of a changeset, see ImportChangeset
+const (
+	OperationInsert = "insert"
+	OperationUpdate = "update"
+	OperationDelete = "delete"
+)
+
 // Extract - the 'E' in ETL. Datasource agnostic interface to extract source data.
 type Extract interface {
 
-	// Extract raw records from the source database to be transformed and loaded into the target search index
+	// Extract raw records from the source database to be transformed and loaded into the target search index.
+	// Returns at most limit records with a feature ID greater than afterFID, ordered by feature ID (keyset pagination).
 	Extract(table config.FeatureTable, fields []string, externaFidFields []string,
-		where string, limit int, offset int) ([]t.RawRecord, error)
+		where string, limit int, afterFID int64) ([]t.RawRecord, error)
+
+	// ExtractKeys extracts only the keys (feature ID and external FID values) of raw records.
+	// Used to delete records from the target search index. Paginated like Extract.
+	ExtractKeys(table config.FeatureTable, externalFidFields []string,
+		where string, limit int, afterFID int64) ([]t.RawRecord, error)
+
+	// Count the number of raw records in the source database
+	Count(table config.FeatureTable, where string) (int64, error)
 
 	// Close connection to the source database
 	Close()
@@ -30,6 +52,9 @@ type Transform interface {
 
 	// Transform each raw record in one or more search records depending on the given configuration
 	Transform(records []t.RawRecord, collection config.Collection) ([]t.SearchIndexRecord, error)
+
+	// TransformKeys transforms raw records in keys which identify existing records in the search index
+	TransformKeys(records []t.RawRecord, collection config.Collection) ([]t.SearchIndexKey, error)
 }
 
 // Load - the 'L' in ETL. Datasource agnostic interface to load data into target database.
@@ -47,12 +72,28 @@ type Load interface {
 
 	// Load records into the search index. Returns the number of records loaded.
 	// Assumes the index is already initialized.
-	Load(records []t.SearchIndexRecord) (int64, error)
+	Load(ctx context.Context, records []t.SearchIndexRecord) (int64, error)
+
+	// AbortLoad hook to clean up after a failed or cancelled load.
+	// For example, by dropping tables or partitions created in PreLoad.
+	AbortLoad() error
 
 	// PostLoad hook to execute logic after loading records into the search index.
 	// For example, by switching partitions or rebuilding indexes.
 	PostLoad(collectionID string, index string, revision string) error
 
+	// PreApply hook to execute logic before applying a changeset (incremental ETL) to the search index.
+	// For example, by starting a transaction.
+	PreApply(collectionID string, index string) error
+
+	// Apply a changeset to the search index: delete records matching the given keys and
+	// insert or replace the given records. Returns the number of records deleted and loaded.
+	Apply(deletes []t.SearchIndexKey, upserts []t.SearchIndexRecord) (int64, int64, error)
+
+	// PostApply hook to execute logic after applying a changeset to the search index.
+	// For example, by updating the revision and committing a transaction.
+	PostApply(collectionID string, index string, revision string) error
+
 	// Optimize once ETL is completed (optional)
 	Optimize(index string) error
 
@@ -60,6 +101,23 @@ type Load interface {
 	Close()
 }
 
+// Inspect datasource agnostic interface to look inside the target search index
+type Inspect interface {
+
+	// Collections lists collections and versions in the search index, including record counts and revisions
+	Collections(index string) ([]load.IndexedCollection, error)
+
+	// Sample returns (at most) limit records matching the given collection and search query, both optional
+	Sample(index string, collectionID string, query string, limit int) ([]load.IndexedRecord, error)
+
+	// DropCollectionVersion removes a specific version of a collection from the search index.
+	// Returns the number of records removed.
+	DropCollectionVersion(index string, collectionID string, version int) (int64, error)
+
+	// Close connection to the target database
+	Close()
+}
+
 // CreateSearchIndex creates an empty search index in the target database
 func CreateSearchIndex(dbConn string, searchIndex string, srid int, lang language.Tag) error {
 	db, err := newTargetToLoad(dbConn)
@@ -80,11 +138,44 @@ func GetRevision(dbConn string, collectionID string, searchIndex string) (string
 	return db.GetRevision(collectionID, searchIndex)
 }
 
-// ImportFile import source data into the target search index using extract-transform-load principle
+// ListCollections returns all collections and versions in the target search index
+func ListCollections(dbConn string, searchIndex string) ([]load.IndexedCollection, error) {
+	db, err := newTargetToInspect(dbConn)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	return db.Collections(searchIndex)
+}
+
+// SampleRecords returns a sample of records from the target search index, optionally
+// filtered by collection and/or search query
+func SampleRecords(dbConn string, searchIndex string, collectionID string, query string, limit int) ([]load.IndexedRecord, error) {
+	db, err := newTargetToInspect(dbConn)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	return db.Sample(searchIndex, collectionID, query, limit)
+}
+
+// DropCollectionVersion removes a specific version of a collection from the target search index
+func DropCollectionVersion(dbConn string, searchIndex string, collectionID string, version int) (int64, error) {
+	db, err := newTargetToInspect(dbConn)
+	if err != nil {
+		return -1, err
+	}
+	defer db.Close()
+	return db.DropCollectionVersion(searchIndex, collectionID, version)
+}
+
+// ImportFile import source data into the target search index using extract-transform-load principle.
 //
-//nolint:funlen
-func ImportFile(collection config.Collection, searchIndex string, revision string, filePath string,
-	pageSize int, skipOptimize bool, dbConn string) error {
+// Extract, transform and load are executed concurrently as stages of a streaming pipeline, connected by
+// bounded channels. Transformation is performed by the given number of workers. The import can be
+// cancelled through the given context, in which case the data loaded so far is discarded.
+func ImportFile(ctx context.Context, collection config.Collection, searchIndex string, revision string, filePath string,
+	pageSize int, workers int, skipOptimize bool, dbConn string) error {
 
 	source, err := newSourceToExtract(filePath)
 	if err != nil {
@@ -96,7 +187,6 @@ func ImportFile(collection config.Collection, searchIndex string, revision strin
 		return err
 	}
 	defer target.Close()
-	transformer := t.NewTransformer()
 
 	// pre-load
 	if err = target.PreLoad(collection.ID, searchIndex); err != nil {
@@ -106,51 +196,222 @@ func ImportFile(collection config.Collection, searchIndex string, revision strin
 	// import each feature table
 	for _, table := range collection.Tables {
 		details := fmt.Sprintf("file %s (feature table '%s', collection '%s') into search index %s", filePath, table.Table, collection.ID, searchIndex)
-		log.Printf("start import of %s", details)
-
-		// import records in batches depending on page size
-		offset := 0
-		for {
-			log.Println("---")
-			log.Printf("extracting source records from offset %d", offset)
-			var externalFidFields []string
-			if collection.ExternalFid != nil {
-				externalFidFields = collection.ExternalFid.Fields
+		if err = importTable(ctx, source, target, collection, table, pageSize, workers, details); err != nil {
+			log.Printf("import of %s failed, discarding records loaded so far", details)
+			if abortErr := target.AbortLoad(); abortErr != nil {
+				log.Printf("failed to discard records loaded so far: %v", abortErr)
 			}
+			return err
+		}
+	}
+
+	// post-load
+	if err = target.PostLoad(collection.ID, searchIndex, revision); err != nil {
+		return err
+	}
+
+	if !skipOptimize {
+		log.Println("start optimizing")
+		if err = target.Optimize(searchIndex); err != nil {
+			return err
+		}
+		log.Println("completed optimizing")
+	}
+	return nil
+}
+
+// batch of search index records, transformed from the given number of source records
+type batch struct {
+	sourceCount int
+	records     []t.SearchIndexRecord
+}
+
+//nolint:funlen
+func importTable(ctx context.Context, source Extract, target Load, collection config.Collection,
+	table config.FeatureTable, pageSize int, workers int, details string) error {
+
+	var externalFidFields []string
+	if collection.ExternalFid != nil {
+		externalFidFields = collection.ExternalFid.Fields
+	}
+	workers = max(workers, 1)
+
+	total, err := source.Count(table, collection.Filter)
+	if err != nil {
+		return fmt.Errorf("failed counting source records: %w", err)
+	}
+	log.Printf("start import of %d source records from %s using %d transform workers", total, details, workers)
+	progress := newProgress(details, total)
+	reportCtx, stopReport := context.WithCancel(ctx)
+	defer stopReport()
+	go progress.reportPeriodically(reportCtx, progressInterval)
 
-			// Extract
-			sourceRecords, err := source.Extract(table, collection.Fields, externalFidFields, collection.Filter, pageSize, offset)
+	g, ctx := errgroup.WithContext(ctx)
+	extracted := make(chan []t.RawRecord, workers)
+	transformed := make(chan batch, workers)
+
+	// Extract
+	g.Go(func() error {
+		defer close(extracted)
+		for afterFID := int64(-1); ; {
+			sourceRecords, err := source.Extract(table, collection.Fields, externalFidFields, collection.Filter, pageSize, afterFID)
 			if err != nil {
 				return fmt.Errorf("failed extracting source records: %w", err)
 			}
-			sourceRecordCount := len(sourceRecords)
-			if sourceRecordCount == 0 {
-				break // no more batches of records to extract
+			if len(sourceRecords) == 0 {
+				return nil // no more batches of records to extract
+			}
+			afterFID = sourceRecords[len(sourceRecords)-1].FeatureID
+			select {
+			case extracted <- sourceRecords:
+			case <-ctx.Done():
+				return ctx.Err()
 			}
+		}
+	})
+
+	// Transform
+	var transformers sync.WaitGroup
+	for range workers {
+		transformers.Add(1)
+		g.Go(func() error {
+			defer transformers.Done()
+			transformer := t.NewTransformer() // not safe for concurrent use, so one per worker
+			for sourceRecords := range extracted {
+				targetRecords, err := transformer.Transform(sourceRecords, collection)
+				if err != nil {
+					return fmt.Errorf("failed to transform raw records to search index records: %w", err)
+				}
+				select {
+				case transformed <- batch{len(sourceRecords), targetRecords}:
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			}
+			return nil
+		})
+	}
+	g.Go(func() error {
+		transformers.Wait()
+		close(transformed)
+		return nil
+	})
+
+	// Load
+	g.Go(func() error {
+		for b := range transformed {
+			loaded, err := target.Load(ctx, b.records)
+			if err != nil {
+				return fmt.Errorf("failed loading records into target: %w", err)
+			}
+			progress.add(b.sourceCount, loaded)
+		}
+		return ctx.Err()
+	})
+
+	if err = g.Wait(); err != nil {
+		return err
+	}
+	stopReport()
+	progress.summary()
+	return nil
+}
+
+// ImportChangeset applies a changeset to an existing collection in the target search index (incremental/delta ETL).
+// Contrary to ImportFile only the records affected by the changeset are touched. The changeset is a file
+// (e.g. GeoPackage) with the same structure as the source file used by ImportFile plus an operation column,
+// which indicates per feature whether it should be inserted, updated or deleted. See Operation.
+//
+//nolint:funlen
+func ImportChangeset(collection config.Collection, searchIndex string, revision string, filePath string,
+	operationColumn string, pageSize int, skipOptimize bool, dbConn string) error {
+
+	source, err := newSourceToExtract(filePath)
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+	target, err := newTargetToLoad(dbConn)
+	if err != nil {
+		return err
+	}
+	defer target.Close()
+	transformer := t.NewTransformer()
+
+	var externalFidFields []string
+	if collection.ExternalFid != nil {
+		externalFidFields = collection.ExternalFid.Fields
+	}
+	upsert := fmt.Sprintf("%s in ('%s', '%s')", operationColumn, OperationInsert, OperationUpdate)
+	upsertFilter := combineFilters(collection.Filter, upsert)
+	deleteFilter := fmt.Sprintf("%s = '%s'", operationColumn, OperationDelete)
+	if collection.Filter != "" {
+		// features which no longer match the filter after an update should be removed from the index
+		deleteFilter = fmt.Sprintf("(%s) or (%s and not (%s))", deleteFilter, upsert, collection.Filter)
+	}
 
-			// Transform
-			log.Printf("extracted %d source records, starting transform", sourceRecordCount)
+	// pre-apply
+	if err = target.PreApply(collection.ID, searchIndex); err != nil {
+		return err
+	}
+
+	// apply changes of each feature table
+	var totalDeleted, totalLoaded int64
+	for _, table := range collection.Tables {
+		details := fmt.Sprintf("changeset %s (feature table '%s', collection '%s') into search index %s", filePath, table.Table, collection.ID, searchIndex)
+		log.Printf("start applying %s", details)
+
+		// deletes
+		for afterFID := int64(-1); ; {
+			sourceRecords, err := source.ExtractKeys(table, externalFidFields, deleteFilter, pageSize, afterFID)
+			if err != nil {
+				return fmt.Errorf("failed extracting source records to delete: %w", err)
+			}
+			if len(sourceRecords) == 0 {
+				break
+			}
+			afterFID = sourceRecords[len(sourceRecords)-1].FeatureID
+			keys, err := transformer.TransformKeys(sourceRecords, collection)
+			if err != nil {
+				return fmt.Errorf("failed to transform raw records to search index keys: %w", err)
+			}
+			deleted, _, err := target.Apply(keys, nil)
+			if err != nil {
+				return fmt.Errorf("failed deleting records from target: %w", err)
+			}
+			totalDeleted += deleted
+		}
+
+		// inserts and updates
+		for afterFID := int64(-1); ; {
+			sourceRecords, err := source.Extract(table, collection.Fields, externalFidFields, upsertFilter, pageSize, afterFID)
+			if err != nil {
+				return fmt.Errorf("failed extracting source records to upsert: %w", err)
+			}
+			if len(sourceRecords) == 0 {
+				break
+			}
+			afterFID = sourceRecords[len(sourceRecords)-1].FeatureID
 			targetRecords, err := transformer.Transform(sourceRecords, collection)
 			if err != nil {
 				return fmt.Errorf("failed to transform raw records to search index records: %w", err)
 			}
-			log.Printf("transform completed, %d source records transformed into %d target records", sourceRecordCount, len(targetRecords))
-
-			// Load
-			loaded, err := target.Load(targetRecords)
+			deleted, loaded, err := target.Apply(nil, targetRecords)
 			if err != nil {
-				return fmt.Errorf("failed loading records into target: %w", err)
+				return fmt.Errorf("failed upserting records into target: %w", err)
 			}
-			log.Printf("loaded %d records into target search index: '%s'", loaded, searchIndex)
-			offset += pageSize
+			totalDeleted += deleted
+			totalLoaded += loaded
 		}
-		log.Printf("completed import of %s", details)
+		log.Printf("completed applying %s", details)
 	}
 
-	// post-load
-	if err = target.PostLoad(collection.ID, searchIndex, revision); err != nil {
+	// post-apply
+	if err = target.PostApply(collection.ID, searchIndex, revision); err != nil {
 		return err
 	}
+	log.Printf("changeset applied to search index '%s': %d records deleted (including replaced records), %d records loaded",
+		searchIndex, totalDeleted, totalLoaded)
 
 	if !skipOptimize {
 		log.Println("start optimizing")
@@ -162,6 +423,16 @@ func ImportFile(collection config.Collection, searchIndex string, revision strin
 	return nil
 }
 
+func combineFilters(filters ...string) string {
+	var nonEmpty []string
+	for _, filter := range filters {
+		if filter != "" {
+			nonEmpty = append(nonEmpty, "("+filter+")")
+		}
+	}
+	return strings.Join(nonEmpty, " and ")
+}
+
 func newSourceToExtract(filePath string) (Extract, error) {
 	if strings.HasSuffix(filePath, ".gpkg") {
 		return extract.NewGeoPackage(filePath)
@@ -177,3 +448,10 @@ func newTargetToLoad(dbConn string) (Load, error) {
 	// add new targets here (elasticsearch, solr, etc)
 	return nil, fmt.Errorf("unsupported target database connection: %s", dbConn)
 }
+
+func newTargetToInspect(dbConn string) (Inspect, error) {
+	if strings.HasPrefix(dbConn, "postgres:") {
+		return load.NewPostgres(dbConn)
+	}
+	return nil, fmt.Errorf("unsupported target database connection: %s", dbConn)
+}
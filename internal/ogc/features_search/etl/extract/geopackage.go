@@ -57,13 +57,14 @@ func (g *GeoPackage) Close() {
 	_ = g.db.Close()
 }
 
-func (g *GeoPackage) Extract(table config.FeatureTable, fields []string, externalFidFields []string, where string, limit int, offset int) ([]t.RawRecord, error) {
+// Extract reads at most limit records with a feature ID greater than afterFID, ordered by feature ID.
+// Uses keyset pagination (instead of LIMIT/OFFSET) so each page is a cheap range scan on the fid, regardless
+// of how far the extraction has progressed.
+func (g *GeoPackage) Extract(table config.FeatureTable, fields []string, externalFidFields []string, where string, limit int, afterFID int64) ([]t.RawRecord, error) {
 	if len(fields) == 0 {
 		return nil, errors.New("no fields provided to read from GeoPackage")
 	}
-	if where != "" {
-		where = "where " + where
-	}
+	where = keysetWhere(table, where)
 
 	// combine field and externalFidFields
 	extraFields := fields
@@ -80,10 +81,10 @@ func (g *GeoPackage) Extract(table config.FeatureTable, fields []string, externa
 		    %[1]s -- all feature specific fields and any fields for external_fid
 		from %[2]s
 		%[5]s
-		limit :limit
-		offset :offset`, strings.Join(extraFields, ","), table.Table, table.FID, table.Geom, where)
+		order by %[3]s asc
+		limit :limit`, strings.Join(extraFields, ","), table.Table, table.FID, table.Geom, where)
 
-	rows, err := g.db.NamedQuery(query, map[string]any{"limit": limit, "offset": offset})
+	rows, err := g.db.NamedQuery(query, map[string]any{"limit": limit, "after": afterFID})
 	if err != nil {
 		return nil, err
 	}
@@ -107,6 +108,71 @@ func (g *GeoPackage) Extract(table config.FeatureTable, fields []string, externa
 	return result, nil
 }
 
+// Count the number of records in the given table, optionally filtered by the given where clause
+func (g *GeoPackage) Count(table config.FeatureTable, where string) (int64, error) {
+	if where != "" {
+		where = "where " + where
+	}
+	var count int64
+	err := g.db.QueryRowx(fmt.Sprintf(`select count(*) from %s %s`, table.Table, where)).Scan(&count)
+	return count, err
+}
+
+// ExtractKeys extracts only the keys (feature ID and external FID values) of records, e.g. to delete
+// these records from the search index. Unlike Extract this doesn't read geometries, since a record
+// marked for deletion may no longer have a (valid) geometry. Paginated like Extract.
+func (g *GeoPackage) ExtractKeys(table config.FeatureTable, externalFidFields []string, where string, limit int, afterFID int64) ([]t.RawRecord, error) {
+	where = keysetWhere(table, where)
+	extraFields := ""
+	if len(externalFidFields) > 0 {
+		extraFields = ", " + strings.Join(externalFidFields, ",")
+	}
+
+	query := fmt.Sprintf(`
+		select %[3]s as fid %[1]s
+		from %[2]s
+		%[4]s
+		order by %[3]s asc
+		limit :limit`, extraFields, table.Table, table.FID, where)
+
+	rows, err := g.db.NamedQuery(query, map[string]any{"limit": limit, "after": afterFID})
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []t.RawRecord
+	for rows.Next() {
+		var row []any
+		if row, err = rows.SliceScan(); err != nil {
+			return nil, err
+		}
+		if len(row) != len(externalFidFields)+1 {
+			return nil, fmt.Errorf("unexpected row length (%v)", len(row))
+		}
+		fid := row[0].(int64)
+		if fid < 0 {
+			return nil, errors.New("encountered negative fid")
+		}
+		result = append(result, t.RawRecord{
+			FeatureID:         fid,
+			SourceTable:       table.Table,
+			ExternalFidValues: row[1:],
+			ExternalFidBase:   table.Table,
+		})
+	}
+	return result, nil
+}
+
+// keysetWhere combines the given (optional) where clause with the keyset pagination condition
+func keysetWhere(table config.FeatureTable, where string) string {
+	keyset := fmt.Sprintf("%s > :after", table.FID)
+	if where == "" {
+		return "where " + keyset
+	}
+	return fmt.Sprintf("where (%s) and %s", where, keyset)
+}
+
 func mapRowToRawRecord(row []any, fields []string, externalFidFields []string, tableName string) (t.RawRecord, error) {
 	bbox := row[1:5]
 
@@ -123,7 +189,8 @@ func mapRowToRawRecord(row []any, fields []string, externalFidFields []string, t
 		return t.RawRecord{}, err
 	}
 	return t.RawRecord{
-		FeatureID: fid,
+		FeatureID:   fid,
+		SourceTable: tableName,
 		Bbox: geom.NewBounds(geom.XY).Set(
 			bbox[0].(float64),
 			bbox[1].(float64),
@@ -10,6 +10,7 @@ import (
 
 	t "github.com/PDOK/gokoala/internal/ogc/features_search/etl/transform"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	pgxgeom "github.com/twpayne/pgx-geom"
 )
 
@@ -20,11 +21,17 @@ const (
 	postgresDetachErr = "already pending detach in partitioned table"
 )
 
+var searchIndexColumns = []string{"feature_id", "source_table", "external_fid", "collection_id", "collection_version",
+	"display_name", "suggest", "geometry_type", "bbox", "geometry"}
+
 type Postgres struct {
 	db *pgx.Conn
 
 	partitionToLoad   string
 	partitionToDetach string
+
+	// transaction used while applying a changeset (incremental ETL)
+	changesetTx pgx.Tx
 }
 
 func NewPostgres(dbConn string) (*Postgres, error) {
@@ -45,6 +52,10 @@ func NewPostgres(dbConn string) (*Postgres, error) {
 }
 
 func (p *Postgres) Close() {
+	if p.changesetTx != nil {
+		// changeset wasn't committed (e.g. due to an error), discard all changes
+		_ = p.changesetTx.Rollback(context.Background())
+	}
 	_ = p.db.Close(context.Background())
 }
 
@@ -87,15 +98,12 @@ func (p *Postgres) PreLoad(collectionID string, index string) error {
 	return nil
 }
 
-func (p *Postgres) Load(records []t.SearchIndexRecord) (int64, error) {
+func (p *Postgres) Load(ctx context.Context, records []t.SearchIndexRecord) (int64, error) {
 	loaded, err := p.db.CopyFrom(
-		context.Background(),
+		ctx,
 		pgx.Identifier{p.partitionToLoad},
-		[]string{"feature_id", "external_fid", "collection_id", "collection_version", "display_name", "suggest", "geometry_type", "bbox", "geometry"},
-		pgx.CopyFromSlice(len(records), func(i int) ([]any, error) {
-			r := records[i]
-			return []any{r.FeatureID, r.ExternalFid, r.CollectionID, r.CollectionVersion, r.DisplayName, r.Suggest, r.GeometryType, r.Bbox, r.Geometry}, nil
-		}),
+		searchIndexColumns,
+		copyFromRecords(records),
 	)
 	if err != nil {
 		return -1, fmt.Errorf("unable to copy records: %w", err)
@@ -103,6 +111,27 @@ func (p *Postgres) Load(records []t.SearchIndexRecord) (int64, error) {
 	return loaded, nil
 }
 
+// AbortLoad drops the table created in PreLoad. Since this table isn't attached
+// as a partition (yet) the search index itself is unaffected.
+func (p *Postgres) AbortLoad() error {
+	if p.partitionToLoad == "" {
+		return nil
+	}
+	if p.db.IsClosed() {
+		// connection is closed when a load is cancelled halfway, reconnect
+		db, err := pgx.Connect(context.Background(), p.db.Config().ConnString())
+		if err != nil {
+			return fmt.Errorf("unable to reconnect to database: %w", err)
+		}
+		p.db = db
+	}
+	_, err := p.db.Exec(context.Background(), fmt.Sprintf(`drop table if exists %s;`, p.partitionToLoad))
+	if err != nil {
+		return fmt.Errorf("error dropping table %s: %w", p.partitionToLoad, err)
+	}
+	return nil
+}
+
 func (p *Postgres) PostLoad(collectionID string, index string, revision string) error {
 	if p.partitionToDetach != "" {
 	RETRY:
@@ -133,18 +162,151 @@ func (p *Postgres) PostLoad(collectionID string, index string, revision string)
 		}
 	}
 
+	return updateRevision(p.db, collectionID, index, revision)
+}
+
+// PreApply starts a transaction on the partition currently attached to the search index
+// for the given collection, in order to apply a changeset (incremental ETL). Since the
+// changes are applied in a transaction the search index keeps serving the previous
+// state of the collection until PostApply is called.
+func (p *Postgres) PreApply(collectionID string, index string) error {
+	tablePrefix := index + "_" + collectionID
+	for _, table := range []string{tablePrefix + alphaPartition, tablePrefix + betaPartition} {
+		tableIsPartition, err := p.isPartition(table, index)
+		if err != nil {
+			return fmt.Errorf("error querying partition status for collection: %s Error: %w", collectionID, err)
+		}
+		if tableIsPartition {
+			p.partitionToLoad = table
+			break
+		}
+	}
+	if p.partitionToLoad == "" {
+		return fmt.Errorf("collection %s isn't loaded in search index %s yet, "+
+			"a full import is required before changesets can be applied", collectionID, index)
+	}
+	var loadedWithoutSourceTable bool
+	err := p.db.QueryRow(context.Background(), fmt.Sprintf(
+		`select exists(select 1 from %s where source_table is null);`, p.partitionToLoad)).Scan(&loadedWithoutSourceTable)
+	if err != nil {
+		return fmt.Errorf("error querying source tables of collection: %s Error: %w", collectionID, err)
+	}
+	if loadedWithoutSourceTable {
+		return fmt.Errorf("collection %s was loaded in search index %s without source tables (by an older version), "+
+			"a full import is required before changesets can be applied", collectionID, index)
+	}
+
+	tx, err := p.db.Begin(context.Background())
+	if err != nil {
+		return fmt.Errorf("error starting transaction to apply changeset: %w", err)
+	}
+	p.changesetTx = tx
+	return nil
+}
+
+// Apply deletes records matching the given keys and upserts the given records into the
+// partition selected in PreApply. Upserts replace all existing records of the same feature,
+// since a single feature may result in multiple search records (one per suggestion).
+// Records are matched on external_fid when available, otherwise on source table and feature_id
+// (feature IDs of different tables in the same collection may collide).
+// Returns the number of deleted and loaded records.
+func (p *Postgres) Apply(deletes []t.SearchIndexKey, upserts []t.SearchIndexRecord) (int64, int64, error) {
+	if p.changesetTx == nil {
+		return -1, -1, errors.New("no changeset in progress, call PreApply first")
+	}
+	keys := deletes
+	for _, r := range upserts {
+		keys = append(keys, t.SearchIndexKey{FeatureID: r.FeatureID, SourceTable: r.SourceTable, ExternalFid: r.ExternalFid})
+	}
+	deleted, err := p.deleteByKeys(keys)
+	if err != nil {
+		return -1, -1, err
+	}
+	loaded, err := p.changesetTx.CopyFrom(
+		context.Background(),
+		pgx.Identifier{p.partitionToLoad},
+		searchIndexColumns,
+		copyFromRecords(upserts),
+	)
+	if err != nil {
+		return -1, -1, fmt.Errorf("unable to copy records: %w", err)
+	}
+	return deleted, loaded, nil
+}
+
+// PostApply updates the revision of the collection and commits the changeset
+func (p *Postgres) PostApply(collectionID string, index string, revision string) error {
+	if p.changesetTx == nil {
+		return errors.New("no changeset in progress, call PreApply first")
+	}
+	if err := updateRevision(p.changesetTx, collectionID, index, revision); err != nil {
+		return err
+	}
+	err := p.changesetTx.Commit(context.Background())
+	p.changesetTx = nil
+	if err != nil {
+		return fmt.Errorf("error committing changeset: %w", err)
+	}
+	return nil
+}
+
+func (p *Postgres) deleteByKeys(keys []t.SearchIndexKey) (int64, error) {
+	var sourceTables, featureIDs, externalFids []string
+	for _, key := range keys {
+		if key.ExternalFid != nil {
+			externalFids = append(externalFids, *key.ExternalFid)
+		} else {
+			sourceTables = append(sourceTables, key.SourceTable)
+			featureIDs = append(featureIDs, key.FeatureID)
+		}
+	}
+	var deleted int64
+	if len(externalFids) > 0 {
+		result, err := p.changesetTx.Exec(context.Background(),
+			fmt.Sprintf(`delete from %s where external_fid = any($1);`, p.partitionToLoad), externalFids)
+		if err != nil {
+			return -1, fmt.Errorf("error deleting records by external_fid: %w", err)
+		}
+		deleted += result.RowsAffected()
+	}
+	if len(featureIDs) > 0 {
+		result, err := p.changesetTx.Exec(context.Background(),
+			fmt.Sprintf(`delete from %s
+				where (source_table, feature_id) in (select * from unnest($1::text[], $2::text[]))
+				and external_fid is null;`, p.partitionToLoad), sourceTables, featureIDs)
+		if err != nil {
+			return -1, fmt.Errorf("error deleting records by feature_id: %w", err)
+		}
+		deleted += result.RowsAffected()
+	}
+	return deleted, nil
+}
+
+// executor is satisfied by both a connection and a transaction
+type executor interface {
+	Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error)
+}
+
+func updateRevision(db executor, collectionID string, index string, revision string) error {
 	metadata := fmt.Sprintf(`
         insert into %[1]s_metadata (collection_id, revision)
         values ('%[2]s', '%[3]s')
         on conflict (collection_id)
         do update set revision = '%[3]s', revision_date = now();`, index, collectionID, revision)
-	_, err = p.db.Exec(context.Background(), metadata)
+	_, err := db.Exec(context.Background(), metadata)
 	if err != nil {
 		return fmt.Errorf("error updating metadata table of index %s. Error: %w", index, err)
 	}
 	return nil
 }
 
+func copyFromRecords(records []t.SearchIndexRecord) pgx.CopyFromSource {
+	return pgx.CopyFromSlice(len(records), func(i int) ([]any, error) {
+		r := records[i]
+		return []any{r.FeatureID, r.SourceTable, r.ExternalFid, r.CollectionID, r.CollectionVersion, r.DisplayName, r.Suggest, r.GeometryType, r.Bbox, r.Geometry}, nil
+	})
+}
+
 func (p *Postgres) Optimize(index string) error {
 	log.Println("perform targeted VACUUM + ANALYZE on loaded partition")
 	_, err := p.db.Exec(context.Background(), fmt.Sprintf(`vacuum analyze %s;`, p.partitionToLoad))
@@ -25,6 +25,7 @@ var (
 	create table if not exists %[1]s (
 		id 					serial,
 		feature_id 			text 					 not null,
+		source_table        text                     null,
 		external_fid        text                     null,
 		collection_id 		text					 not null,
 		collection_version 	int 					 not null,
@@ -124,6 +125,12 @@ func (p *Postgres) createTables(index string, srid int) error {
 		return fmt.Errorf("error creating search index table: %w", err)
 	}
 
+	// add columns introduced after the search index table was (initially) created
+	_, err = p.db.Exec(context.Background(), fmt.Sprintf(`alter table %s add column if not exists source_table text null;`, index))
+	if err != nil {
+		return fmt.Errorf("error adding source_table column to search index table: %w", err)
+	}
+
 	// create primary key when it doesn't exist yet
 	primaryKey := fmt.Sprintf(`
 		do $$
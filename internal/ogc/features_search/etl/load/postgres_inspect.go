@@ -0,0 +1,104 @@
+package load
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// IndexedCollection summary of a collection version in the search index
+type IndexedCollection struct {
+	CollectionID      string     `json:"collectionId"`
+	CollectionVersion int        `json:"collectionVersion"`
+	Partition         string     `json:"partition"`
+	RecordCount       int64      `json:"recordCount"`
+	Revision          *string    `json:"revision,omitempty"`
+	RevisionDate      *time.Time `json:"revisionDate,omitempty"`
+}
+
+// IndexedRecord record in the search index, including the tokens used for full-text search
+type IndexedRecord struct {
+	FeatureID         string  `json:"featureId"`
+	ExternalFid       *string `json:"externalFid,omitempty"`
+	CollectionID      string  `json:"collectionId"`
+	CollectionVersion int     `json:"collectionVersion"`
+	DisplayName       string  `json:"displayName"`
+	Suggest           string  `json:"suggest"`
+	Tokens            string  `json:"ts"`
+}
+
+// Collections lists the collections and versions in the search index, including
+// record counts per partition and the revision of each collection
+func (p *Postgres) Collections(index string) ([]IndexedCollection, error) {
+	rows, err := p.db.Query(context.Background(), fmt.Sprintf(`
+		select i.collection_id, i.collection_version, i.tableoid::regclass::text, count(*), m.revision::text, m.revision_date
+		from %[1]s i
+		left join %[1]s_metadata m on m.collection_id = i.collection_id
+		group by i.collection_id, i.collection_version, i.tableoid, m.revision, m.revision_date
+		order by i.collection_id, i.collection_version;`, index))
+	if err != nil {
+		return nil, fmt.Errorf("error listing collections in search index %s: %w", index, err)
+	}
+	result, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (IndexedCollection, error) {
+		var c IndexedCollection
+		err := row.Scan(&c.CollectionID, &c.CollectionVersion, &c.Partition, &c.RecordCount, &c.Revision, &c.RevisionDate)
+		return c, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error listing collections in search index %s: %w", index, err)
+	}
+	return result, nil
+}
+
+// Sample returns (at most) limit records from the search index matching the given
+// search query. Both the collection ID and the query are optional.
+func (p *Postgres) Sample(index string, collectionID string, query string, limit int) ([]IndexedRecord, error) {
+	rows, err := p.db.Query(context.Background(), fmt.Sprintf(`
+		select feature_id, external_fid, collection_id, collection_version, display_name, suggest, ts::text
+		from %[1]s
+		where ($1 = '' or collection_id = $1)
+		  and ($2 = '' or ts @@ plainto_tsquery('custom_dict', $2))
+		limit $3;`, index), collectionID, query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("error sampling records from search index %s: %w", index, err)
+	}
+	result, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (IndexedRecord, error) {
+		var r IndexedRecord
+		err := row.Scan(&r.FeatureID, &r.ExternalFid, &r.CollectionID, &r.CollectionVersion, &r.DisplayName, &r.Suggest, &r.Tokens)
+		return r, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error sampling records from search index %s: %w", index, err)
+	}
+	return result, nil
+}
+
+// DropCollectionVersion deletes all records of the given collection version from the search index.
+// Returns the number of deleted records. The revision of the collection is removed when no
+// records of the collection remain.
+func (p *Postgres) DropCollectionVersion(index string, collectionID string, version int) (int64, error) {
+	tx, err := p.db.Begin(context.Background())
+	if err != nil {
+		return -1, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback(context.Background()) }()
+
+	result, err := tx.Exec(context.Background(), fmt.Sprintf(`
+		delete from %[1]s where collection_id = $1 and collection_version = $2;`, index), collectionID, version)
+	if err != nil {
+		return -1, fmt.Errorf("error deleting version %d of collection %s: %w", version, collectionID, err)
+	}
+	_, err = tx.Exec(context.Background(), fmt.Sprintf(`
+		delete from %[1]s_metadata m
+		where m.collection_id = $1
+		  and not exists (select 1 from %[1]s i where i.collection_id = m.collection_id);`, index), collectionID)
+	if err != nil {
+		return -1, fmt.Errorf("error updating metadata table of index %s: %w", index, err)
+	}
+	if err = tx.Commit(context.Background()); err != nil {
+		return -1, fmt.Errorf("error committing transaction: %w", err)
+	}
+	return result.RowsAffected(), nil
+}
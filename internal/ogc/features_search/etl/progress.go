@@ -0,0 +1,75 @@
+package etl
+
+import (
+	"context"
+	"log"
+	"sync/atomic"
+	"time"
+)
+
+// progress keeps track of - and periodically reports on - the progress of an import
+type progress struct {
+	details string
+	total   int64
+	start   time.Time
+
+	processed atomic.Int64 // number of source records transformed and loaded
+	loaded    atomic.Int64 // number of target records loaded into the search index
+}
+
+func newProgress(details string, total int64) *progress {
+	return &progress{
+		details: details,
+		total:   total,
+		start:   time.Now(),
+	}
+}
+
+func (p *progress) add(processed int, loaded int64) {
+	p.processed.Add(int64(processed))
+	p.loaded.Add(loaded)
+}
+
+// reportPeriodically logs progress every interval until the given context is done
+func (p *progress) reportPeriodically(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			p.report()
+		}
+	}
+}
+
+func (p *progress) report() {
+	processed := p.processed.Load()
+	rate := p.rate(processed)
+	percentage := 100.0
+	if p.total > 0 {
+		percentage = float64(processed) / float64(p.total) * 100
+	}
+	eta := "unknown"
+	if rate > 0 && p.total >= processed {
+		eta = (time.Duration(float64(p.total-processed)/rate) * time.Second).Round(time.Second).String()
+	}
+	log.Printf("progress: %d/%d source records processed (%.1f%%), %d records loaded, %.0f records/sec, ETA %s",
+		processed, p.total, percentage, p.loaded.Load(), rate, eta)
+}
+
+func (p *progress) summary() {
+	processed := p.processed.Load()
+	log.Printf("completed import of %s: %d source records processed, %d records loaded in %s (%.0f records/sec)",
+		p.details, processed, p.loaded.Load(), time.Since(p.start).Round(time.Second), p.rate(processed))
+}
+
+// rate in source records per second
+func (p *progress) rate(processed int64) float64 {
+	elapsed := time.Since(p.start).Seconds()
+	if elapsed <= 0 {
+		return 0
+	}
+	return float64(processed) / elapsed
+}
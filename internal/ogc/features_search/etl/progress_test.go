@@ -0,0 +1,30 @@
+package etl
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProgress(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	p := newProgress("test file", 100)
+	p.add(25, 50)
+	p.add(25, 50)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	p.reportPeriodically(ctx, 10*time.Millisecond)
+	p.summary()
+
+	assert.Contains(t, buf.String(), "progress: 50/100 source records processed (50.0%), 100 records loaded")
+	assert.Contains(t, buf.String(), "completed import of test file: 50 source records processed, 100 records loaded")
+}
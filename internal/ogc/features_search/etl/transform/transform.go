@@ -19,6 +19,7 @@ import (
 
 type RawRecord struct {
 	FeatureID         int64
+	SourceTable       string
 	FieldValues       []any
 	ExternalFidValues []any
 	ExternalFidBase   string
@@ -29,6 +30,7 @@ type RawRecord struct {
 
 type SearchIndexRecord struct {
 	FeatureID         string
+	SourceTable       string
 	ExternalFid       *string
 	CollectionID      string
 	CollectionVersion int
@@ -39,6 +41,15 @@ type SearchIndexRecord struct {
 	Geometry          *geom.Point
 }
 
+// SearchIndexKey uniquely identifies the search records of a single feature in a collection.
+// Used for incremental updates (delta ETL) of the search index. Since feature IDs are only
+// unique within a feature table, the source table is part of the key.
+type SearchIndexKey struct {
+	FeatureID   string
+	SourceTable string
+	ExternalFid *string
+}
+
 type Transformer struct {
 	parsedTemplates map[string]*template.Template
 }
@@ -90,6 +101,7 @@ func (t Transformer) Transform(records []RawRecord, collection config.Collection
 		for _, suggestion := range suggestions {
 			resultRecord := SearchIndexRecord{
 				FeatureID:         strconv.FormatInt(r.FeatureID, 10),
+				SourceTable:       r.SourceTable,
 				ExternalFid:       externalFid,
 				CollectionID:      collection.ID,
 				CollectionVersion: collection.Version,
@@ -105,6 +117,23 @@ func (t Transformer) Transform(records []RawRecord, collection config.Collection
 	return result, nil
 }
 
+// TransformKeys transforms raw records into keys identifying existing records in the search index
+func (t Transformer) TransformKeys(records []RawRecord, collection config.Collection) ([]SearchIndexKey, error) {
+	result := make([]SearchIndexKey, 0, len(records))
+	for _, r := range records {
+		externalFid, err := generateExternalFid(r.ExternalFidBase, collection.ExternalFid, r.ExternalFidValues)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, SearchIndexKey{
+			FeatureID:   strconv.FormatInt(r.FeatureID, 10),
+			SourceTable: r.SourceTable,
+			ExternalFid: externalFid,
+		})
+	}
+	return result, nil
+}
+
 func (t Transformer) renderTemplate(templateFromConfig string, fieldValuesByName map[string]string) (string, error) {
 	parsedTemplate, ok := t.parsedTemplates[templateFromConfig]
 	if !ok {
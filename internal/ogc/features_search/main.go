@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"net/url"
 	"strings"
+	"time"
 
 	"github.com/PDOK/gokoala/config"
 	"github.com/PDOK/gokoala/internal/engine"
@@ -25,6 +26,8 @@ const (
 	templatesDir = "internal/ogc/features_search/templates/"
 	searchHTML   = "search.go.html"
 	searchPath   = "/search"
+
+	queryExpansionReloadInterval = 1 * time.Minute
 )
 
 type Search struct {
@@ -68,6 +71,13 @@ func NewSearch(e *engine.Engine, datasources map[features.DatasourceKey]ds.Datas
 		queryExpansion:  queryExpansion,
 	}
 	e.Router.Get(searchPath, s.Search())
+	e.Router.Post(batchPath, s.SearchBatch())
+	s.registerDebugEndpoints()
+
+	// reload rewrites/synonyms when changed on disk, without restarting the server
+	watchCtx, stopWatch := context.WithCancel(context.Background())
+	go queryExpansion.Watch(watchCtx, queryExpansionReloadInterval)
+	e.RegisterShutdownHook(stopWatch)
 
 	e.RenderTemplatesWithParams(searchPath,
 		searchPage{
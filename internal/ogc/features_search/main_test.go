@@ -495,7 +495,7 @@ func importGpkg(collectionName string, dbConn string) error {
 		return fmt.Errorf("collection %s not found in config", collectionName)
 	}
 	collectionVersion := uuid.NewString()
-	return etl.ImportFile(*collection, testSearchIndex, collectionVersion, "internal/ogc/features_search/testdata/fake-addresses-crs84.gpkg", 5000, false, dbConn)
+	return etl.ImportFile(context.Background(), *collection, testSearchIndex, collectionVersion, "internal/ogc/features_search/testdata/fake-addresses-crs84.gpkg", 5000, 4, false, dbConn)
 }
 
 func setupPostgis(ctx context.Context, t *testing.T) (network.Port, testcontainers.Container, error) {
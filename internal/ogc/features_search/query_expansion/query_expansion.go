@@ -11,48 +11,124 @@ import (
 	"slices"
 	"sort"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/PDOK/gokoala/internal/ogc/features_search/domain"
 )
 
-const regexPrefix = "regex:"
+const (
+	regexPrefix = "regex:"
+
+	minSynonymLength = 2
+	maxSynonymLength = 100
+)
 
 // QueryExpansion query expansion involves evaluating a user's input (what words were typed into the search query area)
 // and expanding the search query to match additional results, see https://en.wikipedia.org/wiki/Query_expansion
 type QueryExpansion struct {
-	rewrites    []rewrite
-	synonyms    map[string][]string
-	maxSynonyms int
+	rewritesFile string
+	synonymsFile string
+	maxSynonyms  int
+
+	// rewrites and synonyms, swapped atomically on reload
+	rules atomic.Pointer[rules]
+}
+
+type rules struct {
+	rewrites []rewrite
+	synonyms map[string][]string
+	modTime  time.Time // latest modification time of the files the rules are read from
 }
 
 func NewQueryExpansion(rewritesFile, synonymsFile string, maxSynonyms int) (*QueryExpansion, error) {
-	rewrites, rewErr := readRewrites(rewritesFile)
-	synonyms, synErr := readSynonyms(synonymsFile)
+	qe := &QueryExpansion{
+		rewritesFile: rewritesFile,
+		synonymsFile: synonymsFile,
+		maxSynonyms:  maxSynonyms,
+	}
+	r, err := readRules(rewritesFile, synonymsFile)
+	qe.rules.Store(r)
+	return qe, err
+}
+
+// Reload rewrites and synonyms from file. When the files are invalid the current
+// rewrites and synonyms remain in use and an error is returned.
+func (s *QueryExpansion) Reload() error {
+	r, err := readRules(s.rewritesFile, s.synonymsFile)
+	if err != nil {
+		return fmt.Errorf("failed to reload rewrites/synonyms, keeping current set: %w", err)
+	}
+	s.rules.Store(r)
+	log.Printf("reloaded rewrites from %s and synonyms from %s", s.rewritesFile, s.synonymsFile)
+	return nil
+}
 
-	return &QueryExpansion{
-		rewrites:    rewrites,
-		synonyms:    synonyms,
-		maxSynonyms: maxSynonyms,
-	}, errors.Join(rewErr, synErr)
+// Watch the rewrites and synonyms files for changes (by polling every interval) and reload
+// them when modified, until the given context is done.
+func (s *QueryExpansion) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			modTime, err := latestModTime(s.rewritesFile, s.synonymsFile)
+			if err != nil {
+				log.Printf("failed to check rewrites/synonyms for changes: %v", err)
+				continue
+			}
+			if modTime.After(s.rules.Load().modTime) {
+				if err = s.Reload(); err != nil {
+					log.Println(err)
+				}
+			}
+		}
+	}
 }
 
 // Expand Perform query expansion, see https://en.wikipedia.org/wiki/Query_expansion
-func (s QueryExpansion) Expand(ctx context.Context, searchTerms string) (*domain.SearchQuery, error) {
+func (s *QueryExpansion) Expand(ctx context.Context, searchTerms string) (*domain.SearchQuery, error) {
 	expandCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
 	defer cancel()
 
-	rewritten, err := performRewrites(expandCtx, strings.ToLower(searchTerms), s.rewrites)
+	r := s.rules.Load()
+	rewritten, err := performRewrites(expandCtx, strings.ToLower(searchTerms), r.rewrites)
 	if err != nil {
 		return nil, err
 	}
-	words, wordsWithoutSynonyms, wordsWithSynonyms, err := expandSynonyms(expandCtx, rewritten, s.synonyms, s.maxSynonyms)
+	words, wordsWithoutSynonyms, wordsWithSynonyms, err := expandSynonyms(expandCtx, rewritten, r.synonyms, s.maxSynonyms)
 	if err != nil {
 		return nil, err
 	}
 	return domain.NewSearchQuery(words, wordsWithoutSynonyms, wordsWithSynonyms), expandCtx.Err()
 }
 
+func readRules(rewritesFile, synonymsFile string) (*rules, error) {
+	modTime, modErr := latestModTime(rewritesFile, synonymsFile)
+	rewrites, rewErr := readRewrites(rewritesFile)
+	synonyms, synErr := readSynonyms(synonymsFile)
+	if err := errors.Join(modErr, rewErr, synErr); err != nil {
+		return &rules{rewrites: rewrites, synonyms: synonyms}, err
+	}
+	return &rules{rewrites: rewrites, synonyms: synonyms, modTime: modTime}, nil
+}
+
+func latestModTime(files ...string) (time.Time, error) {
+	var result time.Time
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			return result, err
+		}
+		if info.ModTime().After(result) {
+			result = info.ModTime()
+		}
+	}
+	return result, nil
+}
+
 func performRewrites(ctx context.Context, input string, rewrites []rewrite) (string, error) {
 	for _, r := range rewrites {
 		if r.regex != nil {
@@ -226,9 +302,53 @@ func readRewrites(filepath string) ([]rewrite, error) {
 			rewrites = append(rewrites, r)
 		}
 	}
+	if err = assertNoCyclicRewrites(rewrites); err != nil {
+		return nil, err
+	}
 	return rewrites, nil
 }
 
+// assertNoCyclicRewrites checks that (non-regex) rewrites don't undo each other, e.g. 'a' rewritten
+// to 'b' and 'b' rewritten (directly or through other rewrites) back to 'a'.
+func assertNoCyclicRewrites(rewrites []rewrite) error {
+	rewrittenTo := make(map[string][]string)
+	for _, r := range rewrites {
+		if r.regex == nil {
+			rewrittenTo[r.alternative] = append(rewrittenTo[r.alternative], r.original)
+		}
+	}
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make(map[string]int)
+	var visit func(word string, path []string) error
+	visit = func(word string, path []string) error {
+		switch state[word] {
+		case visiting:
+			return fmt.Errorf("failed to parse CSV file: cyclic rewrite detected: %s -> %s",
+				strings.Join(path, " -> "), word)
+		case visited:
+			return nil
+		}
+		state[word] = visiting
+		for _, next := range rewrittenTo[word] {
+			if err := visit(next, append(slices.Clone(path), word)); err != nil {
+				return err
+			}
+		}
+		state[word] = visited
+		return nil
+	}
+	for _, r := range rewrites {
+		if err := visit(r.alternative, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func readSynonyms(filepath string) (map[string][]string, error) {
 	records, err := readCsvFile(filepath)
 	if err != nil {
@@ -242,7 +362,11 @@ func readSynonyms(filepath string) (map[string][]string, error) {
 		// add all alternatives
 		result[key] = make([]string, 0)
 		for i := 1; i < len(row); i++ {
-			result[key] = append(result[key], strings.ToLower(row[i]))
+			alt := strings.ToLower(row[i])
+			if alt == key {
+				return nil, fmt.Errorf("failed to parse CSV file: synonym '%s' is cyclic, it refers to itself", key)
+			}
+			result[key] = append(result[key], alt)
 		}
 
 		// make result map bidirectional, so:
@@ -296,8 +420,13 @@ func readCsvFile(filepath string) ([][]string, error) {
 }
 
 func assertSynonymLength(syn string) error {
-	if len(syn) < 2 {
-		return fmt.Errorf("failed to parse CSV file: synonym '%s' is too short, should be at least 2 chars long", syn)
+	if len(syn) < minSynonymLength {
+		return fmt.Errorf("failed to parse CSV file: synonym '%s' is too short, should be at least %d chars long",
+			syn, minSynonymLength)
+	}
+	if len(syn) > maxSynonymLength {
+		return fmt.Errorf("failed to parse CSV file: synonym '%s' is too long, should be at most %d chars long",
+			syn, maxSynonymLength)
 	}
 	return nil
 }
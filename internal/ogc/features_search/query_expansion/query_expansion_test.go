@@ -7,6 +7,7 @@ import (
 	"runtime"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -292,3 +293,112 @@ westgoeverneurstraat | westgoevstraat | westgouvstraat) & 1800
 		})
 	}
 }
+
+func TestReload(t *testing.T) {
+	// given
+	dir := t.TempDir()
+	rewritesFile := path.Join(dir, "rewrites.csv")
+	synonymsFile := path.Join(dir, "synonyms.csv")
+	require.NoError(t, os.WriteFile(rewritesFile, []byte("hertogenbosch,den bosch\n"), 0600))
+	require.NoError(t, os.WriteFile(synonymsFile, []byte("eerste,1ste\n"), 0600))
+	queryExpansion, err := NewQueryExpansion(rewritesFile, synonymsFile, 10)
+	require.NoError(t, err)
+	assertExpandsTo(t, queryExpansion, "tweede", "tweede")
+
+	// when: reload with invalid synonyms
+	require.NoError(t, os.WriteFile(synonymsFile, []byte("tweede,2de\na,b\n"), 0600))
+	err = queryExpansion.Reload()
+
+	// then: error and current synonyms remain in use
+	require.ErrorContains(t, err, "keeping current set")
+	assertExpandsTo(t, queryExpansion, "tweede", "tweede")
+	assertExpandsTo(t, queryExpansion, "1ste", "(1ste | eerste)")
+
+	// when: reload with valid synonyms
+	require.NoError(t, os.WriteFile(synonymsFile, []byte("tweede,2de\n"), 0600))
+	err = queryExpansion.Reload()
+
+	// then
+	require.NoError(t, err)
+	assertExpandsTo(t, queryExpansion, "tweede", "(tweede | 2de)")
+	assertExpandsTo(t, queryExpansion, "1ste", "1ste")
+}
+
+func TestWatch(t *testing.T) {
+	// given
+	dir := t.TempDir()
+	rewritesFile := path.Join(dir, "rewrites.csv")
+	synonymsFile := path.Join(dir, "synonyms.csv")
+	require.NoError(t, os.WriteFile(rewritesFile, []byte("hertogenbosch,den bosch\n"), 0600))
+	require.NoError(t, os.WriteFile(synonymsFile, []byte("eerste,1ste\n"), 0600))
+	queryExpansion, err := NewQueryExpansion(rewritesFile, synonymsFile, 10)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go queryExpansion.Watch(ctx, 10*time.Millisecond)
+
+	// when
+	require.NoError(t, os.WriteFile(synonymsFile, []byte("tweede,2de\n"), 0600))
+	require.NoError(t, os.Chtimes(synonymsFile, time.Now().Add(time.Hour), time.Now().Add(time.Hour)))
+
+	// then
+	assert.EventuallyWithT(t, func(c *assert.CollectT) {
+		actual, err := queryExpansion.Expand(context.Background(), "tweede")
+		require.NoError(c, err)
+		assert.Equal(c, "(tweede | 2de)", actual.ToExactMatchQuery(true))
+	}, 2*time.Second, 10*time.Millisecond)
+}
+
+func TestInvalidRewritesAndSynonyms(t *testing.T) {
+	tests := []struct {
+		name     string
+		rewrites string
+		synonyms string
+		wantErr  string
+	}{
+		{
+			name:     "too short synonym",
+			rewrites: "foo,bar\n",
+			synonyms: "a,abc\n",
+			wantErr:  "synonym 'a' is too short",
+		},
+		{
+			name:     "too long synonym",
+			rewrites: "foo,bar\n",
+			synonyms: "abc," + strings.Repeat("x", maxSynonymLength+1) + "\n",
+			wantErr:  "is too long",
+		},
+		{
+			name:     "synonym referring to itself",
+			rewrites: "foo,bar\n",
+			synonyms: "abc,def,abc\n",
+			wantErr:  "synonym 'abc' is cyclic",
+		},
+		{
+			name:     "cyclic rewrites",
+			rewrites: "foo,bar\nbaz,foo\nbar,baz\n",
+			synonyms: "abc,def\n",
+			wantErr:  "cyclic rewrite detected",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			rewritesFile := path.Join(dir, "rewrites.csv")
+			synonymsFile := path.Join(dir, "synonyms.csv")
+			require.NoError(t, os.WriteFile(rewritesFile, []byte(tt.rewrites), 0600))
+			require.NoError(t, os.WriteFile(synonymsFile, []byte(tt.synonyms), 0600))
+
+			_, err := NewQueryExpansion(rewritesFile, synonymsFile, 10)
+			require.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
+
+func assertExpandsTo(t *testing.T, queryExpansion *QueryExpansion, searchQuery string, want string) {
+	t.Helper()
+	actual, err := queryExpansion.Expand(context.Background(), searchQuery)
+	require.NoError(t, err)
+	assert.Equal(t, want, actual.ToExactMatchQuery(true))
+}
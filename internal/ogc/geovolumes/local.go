@@ -0,0 +1,142 @@
+package geovolumes
+
+import (
+	"archive/zip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/PDOK/gokoala/config"
+	"github.com/PDOK/gokoala/internal/engine"
+	"github.com/PDOK/gokoala/internal/engine/util"
+)
+
+const (
+	archiveExtension = ".3tz"
+
+	mediaTypeGLTF        = "model/gltf+json"
+	mediaTypeGLTFBinary  = "model/gltf-binary"
+	mediaTypeOctetStream = "application/octet-stream"
+)
+
+// contentTypes media types of the files in 3D Tiles and quantized mesh tilesets, by file extension.
+var contentTypes = map[string]string{
+	".json":    engine.MediaTypeJSON,
+	".gltf":    mediaTypeGLTF,
+	".glb":     mediaTypeGLTFBinary,
+	".b3dm":    mediaTypeOctetStream,
+	".i3dm":    mediaTypeOctetStream,
+	".pnts":    mediaTypeOctetStream,
+	".cmpt":    mediaTypeOctetStream,
+	".subtree": mediaTypeOctetStream,
+	".terrain": engine.MediaTypeQuantizedMesh,
+}
+
+// localTiles 3D tiles (or quantized mesh) of a collection on local disk, in a directory or 3D Tiles archive.
+type localTiles struct {
+	fs.FS
+	io.Closer
+}
+
+// openLocalTiles opens the directory or 3D Tiles archive (3TZ, a zip file) in the given collection.
+func openLocalTiles(collection config.GeoVolumesCollection) (*localTiles, error) {
+	localPath := *collection.LocalPath
+	if strings.EqualFold(filepath.Ext(localPath), archiveExtension) {
+		archive, err := zip.OpenReader(localPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open 3D Tiles archive %s: %w", localPath, err)
+		}
+
+		return &localTiles{archive, archive}, nil
+	}
+	info, err := os.Stat(localPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open 3D Tiles directory %s: %w", localPath, err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("3D Tiles location %s should be a directory or 3D Tiles archive (*%s)", localPath, archiveExtension)
+	}
+
+	return &localTiles{os.DirFS(localPath), io.NopCloser(nil)}, nil
+}
+
+// readFile reads the file at the given path (relative to the root of the tileset).
+// Returns nil without error when the file doesn't exist.
+func (l *localTiles) readFile(filePath string) ([]byte, error) {
+	filePath = strings.TrimPrefix(path.Clean("/"+filePath), "/")
+	data, err := fs.ReadFile(l, filePath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+
+	return data, err
+}
+
+// serveLocalFile serves a file from a directory or 3D Tiles archive on local disk. JSON files are
+// validated against the OpenAPI spec when configured, other (binary) files are served as-is.
+func (t *ThreeDimensionalGeoVolumes) serveLocalFile(w http.ResponseWriter, r *http.Request, tiles *localTiles,
+	filePath string, prefer204 bool, contentTypeOverwrite string) {
+
+	data, err := tiles.readFile(filePath)
+	if err != nil {
+		engine.RenderProblemAndLog(engine.ProblemServerError, w, err)
+
+		return
+	}
+	if data == nil {
+		if prefer204 {
+			// OGC spec: tile without content within the tileset, respond with 204 (similar to a tile server)
+			w.WriteHeader(http.StatusNoContent)
+		} else {
+			engine.RenderProblem(engine.ProblemNotFound, w)
+		}
+
+		return
+	}
+	contentType, ok := contentTypes[strings.ToLower(path.Ext(filePath))]
+	if !ok {
+		contentType = mediaTypeOctetStream
+	}
+	if contentTypeOverwrite != "" {
+		contentType = contentTypeOverwrite
+	}
+
+	if contentType == engine.MediaTypeJSON {
+		if util.IsGzipped(data) {
+			if data, err = util.Gunzip(data); err != nil {
+				engine.RenderProblemAndLog(engine.ProblemServerError, w, err)
+
+				return
+			}
+		}
+		t.engine.Serve(w, r,
+			engine.ServeValidation(false, t.validateResponse),
+			engine.ServeContentType(contentType),
+			engine.ServePreRenderedOutput(data))
+
+		return
+	}
+
+	w.Header().Set(engine.HeaderContentType, contentType)
+	if util.IsGzipped(data) {
+		// tiles (notably quantized mesh terrain) are often stored gzipped, pass as-is when client supports it.
+		// Since the response depends on the client, let caches know.
+		w.Header().Add(engine.HeaderVary, engine.HeaderAcceptEncoding)
+		if engine.AcceptsEncoding(r, engine.FormatGzip) {
+			w.Header().Set(engine.HeaderContentEncoding, engine.FormatGzip)
+		} else if data, err = util.Gunzip(data); err != nil {
+			engine.RenderProblemAndLog(engine.ProblemServerError, w, err)
+
+			return
+		}
+	}
+	w.Header().Set(engine.HeaderContentLength, strconv.Itoa(len(data)))
+	engine.SafeWrite(w.Write, data)
+}
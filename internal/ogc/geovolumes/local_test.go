@@ -0,0 +1,183 @@
+package geovolumes
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/PDOK/gokoala/internal/engine"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestThreeDimensionalGeoVolumes_LocalTiles(t *testing.T) {
+	tests := []struct {
+		name                string
+		url                 string
+		containerID         string
+		tilePath            string
+		explicitTileSet     string
+		acceptEncoding      string
+		wantStatusCode      int
+		wantContentType     string
+		wantContentEncoding string
+		wantVary            string
+		wantBodyContains    string
+	}{
+		{
+			name:             "tileset.json from directory",
+			url:              "http://localhost:8080/collections/buildings/3dtiles",
+			containerID:      "buildings",
+			wantStatusCode:   http.StatusOK,
+			wantContentType:  engine.MediaTypeJSON,
+			wantBodyContains: `"uri": "0/0/0.b3dm"`,
+		},
+		{
+			name:             "b3dm tile from directory",
+			url:              "http://localhost:8080/collections/buildings/3dtiles/0/0/0.b3dm",
+			containerID:      "buildings",
+			tilePath:         "0/0/0.b3dm",
+			wantStatusCode:   http.StatusOK,
+			wantContentType:  "application/octet-stream",
+			wantBodyContains: "b3dm",
+		},
+		{
+			name:           "missing tile in directory",
+			url:            "http://localhost:8080/collections/buildings/3dtiles/1/0/0.b3dm",
+			containerID:    "buildings",
+			tilePath:       "1/0/0.b3dm",
+			wantStatusCode: http.StatusNoContent,
+		},
+		{
+			name:           "no escape from directory",
+			url:            "http://localhost:8080/collections/buildings/3dtiles/../../local_test.go",
+			containerID:    "buildings",
+			tilePath:       "../../../local_test.go",
+			wantStatusCode: http.StatusNoContent,
+		},
+		{
+			name:             "implicit tileset.json from archive",
+			url:              "http://localhost:8080/collections/city/3dtiles",
+			containerID:      "city",
+			wantStatusCode:   http.StatusOK,
+			wantContentType:  engine.MediaTypeJSON,
+			wantBodyContains: `"subdivisionScheme": "QUADTREE"`,
+		},
+		{
+			name:             "explicit tileset from archive",
+			url:              "http://localhost:8080/collections/:3dContainerId/:explicitTileSet.json",
+			containerID:      "city",
+			explicitTileSet:  "tileset-1-0-0",
+			wantStatusCode:   http.StatusOK,
+			wantContentType:  engine.MediaTypeJSON,
+			wantBodyContains: `"uri": "content/1/0/0.glb"`,
+		},
+		{
+			name:             "subtree from archive",
+			url:              "http://localhost:8080/collections/city/3dtiles/subtrees/0/0/0.subtree",
+			containerID:      "city",
+			tilePath:         "subtrees/0/0/0.subtree",
+			wantStatusCode:   http.StatusOK,
+			wantContentType:  "application/octet-stream",
+			wantBodyContains: "subt",
+		},
+		{
+			name:             "compressed glb from archive",
+			url:              "http://localhost:8080/collections/city/3dtiles/content/0/0/0.glb",
+			containerID:      "city",
+			tilePath:         "content/0/0/0.glb",
+			wantStatusCode:   http.StatusOK,
+			wantContentType:  "model/gltf-binary",
+			wantBodyContains: "glTF",
+		},
+		{
+			name:             "layer.json from archive",
+			url:              "http://localhost:8080/collections/terrain/quantized-mesh",
+			containerID:      "terrain",
+			wantStatusCode:   http.StatusOK,
+			wantContentType:  engine.MediaTypeJSON,
+			wantBodyContains: `"format": "quantized-mesh-1.0"`,
+		},
+		{
+			name:                "gzipped terrain from archive, client accepts gzip",
+			url:                 "http://localhost:8080/collections/terrain/quantized-mesh/0/0/0.terrain",
+			containerID:         "terrain",
+			tilePath:            "0/0/0.terrain",
+			acceptEncoding:      "gzip, deflate",
+			wantStatusCode:      http.StatusOK,
+			wantContentType:     engine.MediaTypeQuantizedMesh,
+			wantContentEncoding: engine.FormatGzip,
+			wantVary:            engine.HeaderAcceptEncoding,
+		},
+		{
+			name:             "gzipped terrain from archive, client doesn't accept gzip",
+			url:              "http://localhost:8080/collections/terrain/quantized-mesh/0/0/0.terrain",
+			containerID:      "terrain",
+			tilePath:         "0/0/0.terrain",
+			wantStatusCode:   http.StatusOK,
+			wantContentType:  engine.MediaTypeQuantizedMesh,
+			wantVary:         engine.HeaderAcceptEncoding,
+			wantBodyContains: "quantized-mesh-tile",
+		},
+		{
+			name:             "gzipped terrain from archive, client refuses gzip",
+			url:              "http://localhost:8080/collections/terrain/quantized-mesh/0/0/0.terrain",
+			containerID:      "terrain",
+			tilePath:         "0/0/0.terrain",
+			acceptEncoding:   "gzip;q=0, deflate",
+			wantStatusCode:   http.StatusOK,
+			wantContentType:  engine.MediaTypeQuantizedMesh,
+			wantVary:         engine.HeaderAcceptEncoding,
+			wantBodyContains: "quantized-mesh-tile",
+		},
+	}
+	newEngine, err := engine.NewEngine("internal/ogc/geovolumes/testdata/config_local_3d.yaml",
+		"internal/engine/testdata/test_theme.yaml", "", false, true)
+	require.NoError(t, err)
+	geoVolumes := NewThreeDimensionalGeoVolumes(newEngine)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, tt.url, nil)
+			require.NoError(t, err)
+			if tt.acceptEncoding != "" {
+				req.Header.Set(engine.HeaderAcceptEncoding, tt.acceptEncoding)
+			}
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("3dContainerId", tt.containerID)
+			rctx.URLParams.Add("*", tt.tilePath)
+			rctx.URLParams.Add("explicitTileSet", tt.explicitTileSet)
+			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+
+			var handler http.HandlerFunc
+			switch {
+			case tt.explicitTileSet != "":
+				handler = geoVolumes.ExplicitTileset()
+			case tt.tilePath != "":
+				handler = geoVolumes.Tile()
+			case tt.containerID == "terrain":
+				handler = geoVolumes.Tileset("layer.json")
+			default:
+				handler = geoVolumes.Tileset("tileset.json")
+			}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.wantStatusCode, rr.Code)
+			if tt.wantContentType != "" {
+				assert.Equal(t, tt.wantContentType, rr.Header().Get(engine.HeaderContentType))
+			}
+			assert.Equal(t, tt.wantContentEncoding, rr.Header().Get(engine.HeaderContentEncoding))
+			assert.Equal(t, tt.wantVary, rr.Header().Get(engine.HeaderVary))
+			assert.Contains(t, rr.Body.String(), tt.wantBodyContains)
+		})
+	}
+
+	// bounding volumes are read from local disk too
+	volumes := geoVolumes.GetGeoVolumes()
+	assert.Equal(t, []float64{0.0872, 0.9075, 0.0873, 0.9076, -5, 120}, volumes.Get("buildings").Region)
+	assert.Equal(t, []float64{0.0872, 0.9075, 0.0873, 0.9076, -5, 120}, volumes.Get("city").Region)
+	assert.Equal(t, []float64{4.9, 51.9, 5.1, 52.1}, volumes.Get("terrain").Bbox)
+}
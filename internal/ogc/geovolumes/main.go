@@ -1,7 +1,9 @@
 package geovolumes
 
 import (
+	"context"
 	"errors"
+	"io/fs"
 	"log"
 	"net/http"
 	"net/url"
@@ -18,18 +20,38 @@ import (
 type ThreeDimensionalGeoVolumes struct {
 	engine           *engine.Engine
 	validateResponse bool
+	geoVolumes       geospatial.GeoVolumes
+
+	// 3D tiles served from local disk instead of the tileserver, by collection ID
+	localTiles map[string]*localTiles
 }
 
 func NewThreeDimensionalGeoVolumes(e *engine.Engine) *ThreeDimensionalGeoVolumes {
-	_, err := url.ParseRequestURI(e.Config.OgcAPI.GeoVolumes.TileServer.String())
-	if err != nil {
-		log.Fatalf("invalid tileserver url provided: %v", err)
-	}
-
 	geoVolumes := &ThreeDimensionalGeoVolumes{
 		engine:           e,
 		validateResponse: *e.Config.OgcAPI.GeoVolumes.ValidateResponses,
+		localTiles:       make(map[string]*localTiles),
+	}
+	for _, collection := range e.Config.OgcAPI.GeoVolumes.Collections {
+		if collection.LocalPath == nil {
+			continue
+		}
+		tiles, err := openLocalTiles(collection)
+		if err != nil {
+			log.Fatalf("failed to open 3D tiles of collection %s: %v", collection.ID, err)
+		}
+		geoVolumes.localTiles[collection.ID] = tiles
+		e.RegisterShutdownHook(func() {
+			_ = tiles.Close()
+		})
+	}
+	if len(geoVolumes.localTiles) < len(e.Config.OgcAPI.GeoVolumes.Collections) {
+		_, err := url.ParseRequestURI(e.Config.OgcAPI.GeoVolumes.TileServer.String())
+		if err != nil {
+			log.Fatalf("invalid tileserver url provided: %v", err)
+		}
 	}
+	geoVolumes.geoVolumes = geoVolumes.discoverGeoVolumes()
 
 	// 3D Tiles
 	e.Router.Get(geospatial.CollectionsPath+"/{3dContainerId}/3dtiles", geoVolumes.Tileset("tileset.json"))
@@ -45,9 +67,52 @@ func NewThreeDimensionalGeoVolumes(e *engine.Engine) *ThreeDimensionalGeoVolumes
 	e.Router.Get(geospatial.CollectionsPath+"/{3dContainerId}/quantized-mesh", geoVolumes.Tileset("layer.json"))
 	e.Router.Get(geospatial.CollectionsPath+"/{3dContainerId}/quantized-mesh/*", geoVolumes.Tile())
 
+	// Readiness checks of the 3D tile server (or local disk), per collection
+	for _, collection := range e.Config.OgcAPI.GeoVolumes.Collections {
+		if tiles, ok := geoVolumes.localTiles[collection.ID]; ok {
+			manifest := collectionManifestPath(collection, true)
+			e.RegisterReadinessCheck("3d", collection.ID, func(_ context.Context) error {
+				_, err := fs.Stat(tiles, manifest)
+				return err
+			})
+			continue
+		}
+		target, err := url.Parse(e.Config.OgcAPI.GeoVolumes.TileServer.String() + collectionManifestPath(collection, false))
+		if err != nil {
+			log.Fatalf("invalid tileserver url provided: %v", err)
+		}
+		e.RegisterReadinessCheck("3d", collection.ID, func(ctx context.Context) error {
+			return engine.CheckURLFound(ctx, target)
+		})
+	}
+
 	return geoVolumes
 }
 
+// collectionManifestPath path on the tile server (or on local disk) to the tileset.json of
+// the 3D tiles in the given collection, or to the layer.json in case of a DTM.
+func collectionManifestPath(collection config.GeoVolumesCollection, local bool) string {
+	manifest := "tileset.json"
+	if collection.IsDtm {
+		manifest = "layer.json"
+	}
+	if local {
+		return manifest
+	}
+	path, _ := url.JoinPath("/", tileServerPath(&collection), manifest)
+
+	return path
+}
+
+// tileServerPath basepath to the 3D tiles in the given collection on the tile server.
+func tileServerPath(collection *config.GeoVolumesCollection) string {
+	if collection.TileServerPath != nil {
+		return *collection.TileServerPath
+	}
+
+	return collection.ID
+}
+
 // Tileset serves tileset.json manifest in case of OGC 3D Tiles (= separate spec from OGC 3D GeoVolumes) requests or
 // layer.json manifest in case of quantized mesh requests. Both requests will be proxied to the configured tileserver.
 func (t *ThreeDimensionalGeoVolumes) Tileset(fileName string) http.HandlerFunc {
@@ -86,11 +151,6 @@ func (t *ThreeDimensionalGeoVolumes) Tile() http.HandlerFunc {
 			return
 		}
 
-		tileServerPath := collectionID
-		if collection.TileServerPath != nil {
-			tileServerPath = *collection.TileServerPath
-		}
-
 		tilePath := chi.URLParam(r, "*")
 
 		contentType := ""
@@ -99,7 +159,12 @@ func (t *ThreeDimensionalGeoVolumes) Tile() http.HandlerFunc {
 			contentType = engine.MediaTypeQuantizedMesh
 		}
 
-		path, _ := url.JoinPath("/", tileServerPath, tilePath)
+		if tiles, ok := t.localTiles[collectionID]; ok {
+			t.serveLocalFile(w, r, tiles, tilePath, true, contentType)
+
+			return
+		}
+		path, _ := url.JoinPath("/", tileServerPath(collection), tilePath)
 		t.reverseProxy(w, r, path, true, contentType)
 	}
 }
@@ -113,12 +178,12 @@ func (t *ThreeDimensionalGeoVolumes) tileSet(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	tileServerPath := collectionID
-	if collection.TileServerPath != nil {
-		tileServerPath = *collection.TileServerPath
-	}
+	if tiles, ok := t.localTiles[collectionID]; ok {
+		t.serveLocalFile(w, r, tiles, tileSet, false, "")
 
-	path, _ := url.JoinPath("/", tileServerPath, tileSet)
+		return
+	}
+	path, _ := url.JoinPath("/", tileServerPath(collection), tileSet)
 	t.reverseProxy(w, r, path, false, "")
 }
 
@@ -0,0 +1,249 @@
+package geovolumes
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/PDOK/gokoala/config"
+	"github.com/PDOK/gokoala/internal/engine/util"
+	"github.com/PDOK/gokoala/internal/ogc/common/geospatial"
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	// max time spent retrieving the bounding volume of a single 3D container
+	boundingVolumeTimeout = 10 * time.Second
+
+	// max number of bounding volumes retrieved concurrently
+	boundingVolumeConcurrency = 4
+
+	// WGS84 ellipsoid
+	semiMajorAxis    = 6378137.0
+	flattening       = 1 / 298.257223563
+	eccentricitySq   = flattening * (2 - flattening)
+	minEarthDistance = 6_000_000 // coordinates closer to the center of the earth aren't ECEF coordinates
+)
+
+// tilesetManifest the parts of a 3D Tiles tileset.json (or Quantized Mesh layer.json) describing its bounding volume.
+type tilesetManifest struct {
+	Root *struct {
+		BoundingVolume struct {
+			Region []float64 `json:"region"`
+			Box    []float64 `json:"box"`
+			Sphere []float64 `json:"sphere"`
+		} `json:"boundingVolume"`
+		Transform []float64 `json:"transform"`
+	} `json:"root"`
+
+	// bounds of a Quantized Mesh (layer.json) in degrees: west, south, east, north
+	Bounds []float64 `json:"bounds"`
+}
+
+// GetGeoVolumes the 3D containers offered by this API, with their bounding volumes.
+func (t *ThreeDimensionalGeoVolumes) GetGeoVolumes() geospatial.GeoVolumes {
+	return t.geoVolumes
+}
+
+// discoverGeoVolumes determines the bounding volume of each 3D container. The extent in the config is
+// used when available in CRS84, otherwise the root bounding volume of the tileset.json (on the tile server or local disk).
+// Failing to retrieve the latter isn't fatal, in that case the 3D container just lacks a bounding volume.
+func (t *ThreeDimensionalGeoVolumes) discoverGeoVolumes() geospatial.GeoVolumes {
+	collections := t.engine.Config.OgcAPI.GeoVolumes.Collections
+	volumes := make([]geospatial.GeoVolume, len(collections))
+
+	g := errgroup.Group{}
+	g.SetLimit(boundingVolumeConcurrency)
+	for i, collection := range collections {
+		g.Go(func() error {
+			volume, err := t.boundingVolume(collection)
+			if err != nil {
+				log.Printf("Warning: failed to determine bounding volume of 3D collection '%s': %v", collection.ID, err)
+			}
+			if bbox := configuredBbox(collection); bbox != nil {
+				volume.Bbox = bbox
+			}
+			volume.Children = collection.Children
+			volumes[i] = volume
+			return nil
+		})
+	}
+	_ = g.Wait()
+
+	result := make(geospatial.GeoVolumes, len(collections))
+	for i, collection := range collections {
+		result[collection.ID] = volumes[i]
+	}
+
+	return result
+}
+
+// configuredBbox the extent of the given collection from the config, nil when absent or not in CRS84.
+func configuredBbox(collection config.GeoVolumesCollection) []float64 {
+	if collection.Metadata == nil || collection.Metadata.Extent == nil || collection.Metadata.Extent.Srs != "" {
+		return nil
+	}
+	bbox := make([]float64, 0, len(collection.Metadata.Extent.Bbox))
+	for _, value := range collection.Metadata.Extent.Bbox {
+		v, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil
+		}
+		bbox = append(bbox, v)
+	}
+	if len(bbox) != 4 && len(bbox) != 6 {
+		return nil
+	}
+
+	return bbox
+}
+
+// boundingVolume reads the root bounding volume of the given collection from the tile server or local disk.
+func (t *ThreeDimensionalGeoVolumes) boundingVolume(collection config.GeoVolumesCollection) (geospatial.GeoVolume, error) {
+	var data []byte
+	var err error
+	if tiles, ok := t.localTiles[collection.ID]; ok {
+		data, err = tiles.readFile(collectionManifestPath(collection, true))
+		if err == nil && data == nil {
+			err = fmt.Errorf("%s not found in %s", collectionManifestPath(collection, true), *collection.LocalPath)
+		}
+	} else {
+		data, err = t.fetchManifest(collection)
+	}
+	if err != nil {
+		return geospatial.GeoVolume{}, err
+	}
+	if util.IsGzipped(data) {
+		if data, err = util.Gunzip(data); err != nil {
+			return geospatial.GeoVolume{}, err
+		}
+	}
+	var manifest tilesetManifest
+	if err = json.Unmarshal(data, &manifest); err != nil {
+		return geospatial.GeoVolume{}, fmt.Errorf("invalid manifest: %w", err)
+	}
+
+	return manifestToGeoVolume(manifest)
+}
+
+func (t *ThreeDimensionalGeoVolumes) fetchManifest(collection config.GeoVolumesCollection) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), boundingVolumeTimeout)
+	defer cancel()
+
+	target, err := url.Parse(t.engine.Config.OgcAPI.GeoVolumes.TileServer.String() + collectionManifestPath(collection, false))
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to retrieve %s, status code: %d", target, resp.StatusCode)
+	}
+
+	return io.ReadAll(resp.Body)
+}
+
+func manifestToGeoVolume(manifest tilesetManifest) (geospatial.GeoVolume, error) {
+	if len(manifest.Bounds) == 4 {
+		return geospatial.GeoVolume{Bbox: manifest.Bounds}, nil
+	}
+	if manifest.Root == nil {
+		return geospatial.GeoVolume{}, errors.New("manifest lacks a root tile")
+	}
+	bv := manifest.Root.BoundingVolume
+	switch {
+	case len(bv.Region) == 6:
+		region := bv.Region
+		bbox := []float64{toDegrees(region[0]), toDegrees(region[1]), region[4],
+			toDegrees(region[2]), toDegrees(region[3]), region[5]}
+
+		return geospatial.GeoVolume{Region: region, Bbox: bbox}, nil
+	case len(bv.Box) == 12:
+		box := transformBox(bv.Box, manifest.Root.Transform)
+
+		return geospatial.GeoVolume{Box: box, Bbox: boxToBbox(box)}, nil
+	case len(bv.Sphere) == 4:
+		// treat sphere as the box enclosing it
+		r := bv.Sphere[3]
+		box := transformBox([]float64{bv.Sphere[0], bv.Sphere[1], bv.Sphere[2], r, 0, 0, 0, r, 0, 0, 0, r}, manifest.Root.Transform)
+
+		return geospatial.GeoVolume{Box: box, Bbox: boxToBbox(box)}, nil
+	default:
+		return geospatial.GeoVolume{}, errors.New("root tile lacks a supported bounding volume (region, box or sphere)")
+	}
+}
+
+// transformBox applies the given (column-major 4x4) transform of the root tile to the given box.
+func transformBox(box []float64, transform []float64) []float64 {
+	if len(transform) != 16 {
+		return box
+	}
+	result := make([]float64, 12)
+	for i := range 3 {
+		// center is a point (translated), the half-axes are vectors (only rotated/scaled)
+		result[i] = transform[i]*box[0] + transform[4+i]*box[1] + transform[8+i]*box[2] + transform[12+i]
+		for axis := 1; axis <= 3; axis++ {
+			result[axis*3+i] = transform[i]*box[axis*3] + transform[4+i]*box[axis*3+1] + transform[8+i]*box[axis*3+2]
+		}
+	}
+
+	return result
+}
+
+// boxToBbox converts a box in ECEF coordinates to a bbox in CRS84h, nil when the box isn't in ECEF coordinates.
+func boxToBbox(box []float64) []float64 {
+	bbox := []float64{math.Inf(1), math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1), math.Inf(-1)}
+	for _, signs := range [][3]float64{{-1, -1, -1}, {-1, -1, 1}, {-1, 1, -1}, {-1, 1, 1}, {1, -1, -1}, {1, -1, 1}, {1, 1, -1}, {1, 1, 1}} {
+		var corner [3]float64
+		for i := range 3 {
+			corner[i] = box[i] + signs[0]*box[3+i] + signs[1]*box[6+i] + signs[2]*box[9+i]
+		}
+		if math.Sqrt(corner[0]*corner[0]+corner[1]*corner[1]+corner[2]*corner[2]) < minEarthDistance {
+			return nil
+		}
+		lon, lat, height := ecefToGeodetic(corner[0], corner[1], corner[2])
+		bbox[0], bbox[1], bbox[2] = min(bbox[0], lon), min(bbox[1], lat), min(bbox[2], height)
+		bbox[3], bbox[4], bbox[5] = max(bbox[3], lon), max(bbox[4], lat), max(bbox[5], height)
+	}
+	if slices.ContainsFunc(bbox, math.IsNaN) {
+		return nil
+	}
+
+	return bbox
+}
+
+// ecefToGeodetic converts earth-centered, earth-fixed coordinates to longitude and latitude
+// (in degrees) and ellipsoidal height (in meters) on the WGS84 ellipsoid.
+func ecefToGeodetic(x, y, z float64) (lon float64, lat float64, height float64) {
+	p := math.Hypot(x, y)
+	lat = math.Atan2(z, p*(1-eccentricitySq))
+	for range 5 {
+		n := semiMajorAxis / math.Sqrt(1-eccentricitySq*math.Pow(math.Sin(lat), 2))
+		height = p/math.Cos(lat) - n
+		lat = math.Atan2(z, p*(1-eccentricitySq*n/(n+height)))
+	}
+	n := semiMajorAxis / math.Sqrt(1-eccentricitySq*math.Pow(math.Sin(lat), 2))
+	height = p/math.Cos(lat) - n
+
+	return toDegrees(math.Atan2(y, x)), toDegrees(lat), height
+}
+
+func toDegrees(radians float64) float64 {
+	return radians * 180 / math.Pi
+}
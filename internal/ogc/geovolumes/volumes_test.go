@@ -0,0 +1,78 @@
+package geovolumes
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/PDOK/gokoala/config"
+	"github.com/PDOK/gokoala/internal/engine"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestThreeDimensionalGeoVolumes_GetGeoVolumes(t *testing.T) {
+	tileServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/container_1/"):
+			engine.SafeWrite(w.Write, []byte(`{"asset": {"version": "1.1"}, "root": {
+				"boundingVolume": {"region": [0.0872, 0.9075, 0.0873, 0.9076, -5, 120]}
+			}}`))
+		case strings.HasPrefix(r.URL.Path, "/container_2/"):
+			// box of 200x200x200 meters, positioned at lon 5, lat 52 by transform
+			engine.SafeWrite(w.Write, []byte(`{"asset": {"version": "1.1"}, "root": {
+				"boundingVolume": {"box": [0, 0, 0, 100, 0, 0, 0, 100, 0, 0, 0, 100]},
+				"transform": [1, 0, 0, 0, 0, 1, 0, 0, 0, 0, 1, 0, 3919986.7541, 342954.4022, 5002803.3455, 1]
+			}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer tileServer.Close()
+	tileServerURL, err := url.Parse(tileServer.URL)
+	require.NoError(t, err)
+
+	newEngine, err := engine.NewEngine("internal/ogc/geovolumes/testdata/config_minimal_3d.yaml",
+		"internal/engine/testdata/test_theme.yaml", "", false, true)
+	require.NoError(t, err)
+	newEngine.Config.OgcAPI.GeoVolumes.TileServer = config.URL{URL: tileServerURL}
+	newEngine.Config.OgcAPI.GeoVolumes.Collections = append(newEngine.Config.OgcAPI.GeoVolumes.Collections,
+		config.GeoVolumesCollection{
+			ID: "container_3",
+			Metadata: &config.GeoSpatialCollectionMetadata{
+				Extent: &config.Extent{Bbox: []string{"4.1", "51.2", "4.3", "51.4"}},
+			},
+		})
+	newEngine.Config.OgcAPI.GeoVolumes.Collections[0].Children = []string{"container_2"}
+
+	geoVolumes := NewThreeDimensionalGeoVolumes(newEngine).GetGeoVolumes()
+	require.Len(t, geoVolumes, 3)
+
+	// region
+	volume := geoVolumes.Get("container_1")
+	require.NotNil(t, volume)
+	assert.Equal(t, []float64{0.0872, 0.9075, 0.0873, 0.9076, -5, 120}, volume.Region)
+	assert.Nil(t, volume.Box)
+	assert.InDeltaSlice(t, []float64{4.9962, 51.9959, -5, 5.0019, 52.0016, 120}, volume.Bbox, 0.0001)
+	assert.Equal(t, []string{"container_2"}, volume.Children)
+
+	// box with transform
+	volume = geoVolumes.Get("container_2")
+	require.NotNil(t, volume)
+	assert.Nil(t, volume.Region)
+	assert.InDeltaSlice(t, []float64{3919986.7541, 342954.4022, 5002803.3455, 100, 0, 0, 0, 100, 0, 0, 0, 100}, volume.Box, 0.0001)
+	require.Len(t, volume.Bbox, 6)
+	assert.InDelta(t, 5, (volume.Bbox[0]+volume.Bbox[3])/2, 0.001)
+	assert.InDelta(t, 52, (volume.Bbox[1]+volume.Bbox[4])/2, 0.001)
+	assert.Less(t, volume.Bbox[2], 0.0)
+	assert.Greater(t, volume.Bbox[5], 0.0)
+
+	// unavailable on tile server, extent from config
+	volume = geoVolumes.Get("container_3")
+	require.NotNil(t, volume)
+	assert.Nil(t, volume.Region)
+	assert.Nil(t, volume.Box)
+	assert.Equal(t, []float64{4.1, 51.2, 4.3, 51.4}, volume.Bbox)
+}
@@ -0,0 +1,309 @@
+package processes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"slices"
+
+	"github.com/PDOK/gokoala/config"
+	ds "github.com/PDOK/gokoala/internal/ogc/features/datasources"
+	"github.com/PDOK/gokoala/internal/ogc/features/domain"
+	"github.com/twpayne/go-geom"
+	"github.com/twpayne/go-geom/encoding/geojson"
+)
+
+const (
+	// number of features retrieved from a datasource at once
+	scanPageSize = 1000
+
+	// max number of features returned by a process, to bound memory usage
+	maxOutputFeatures = 10000
+
+	inputCollection = "collection"
+	inputGeometry   = "geometry"
+	inputDistance   = "distance"
+
+	outputCount    = "count"
+	outputFeatures = "features"
+)
+
+// FeatureDatasources gives access to the features of the collections offered through OGC API Features.
+type FeatureDatasources interface {
+
+	// GetDatasource returns the datasource of the given collection, serving features in WGS84.
+	// Returns nil when the collection doesn't exist.
+	GetDatasource(collectionID string) ds.Datasource
+}
+
+// builtInProcesses processes operating on the features of the given collections.
+func builtInProcesses(datasources FeatureDatasources, collections config.FeaturesCollections, baseURL config.URL) []process {
+	ids := make([]string, 0, len(collections))
+	for _, collection := range collections {
+		ids = append(ids, collection.ID)
+	}
+	scanner := &featureScanner{datasources: datasources, collectionIDs: ids, baseURL: baseURL}
+
+	return []process{
+		&countFeatures{scanner},
+		&intersectFeatures{scanner},
+	}
+}
+
+// countFeatures counts the features of a collection located in a polygon.
+type countFeatures struct {
+	scanner *featureScanner
+}
+
+func (c *countFeatures) description() processDescription {
+	return processDescription{
+		ID:          "count-features",
+		Title:       "Count features",
+		Description: "Counts the features of a collection that intersect the given polygon.",
+		Version:     "1.0.0",
+		Keywords:    []string{"count", "polygon", "features"},
+		Inputs: map[string]inputDescription{
+			inputCollection: c.scanner.collectionInput(),
+			inputGeometry: {
+				Title:       "Polygon",
+				Description: "GeoJSON (Multi)Polygon in WGS84 (CRS84) in which to count the features.",
+				MinOccurs:   1,
+				Schema:      geometrySchema("Polygon", "MultiPolygon"),
+			},
+		},
+		Outputs: map[string]outputDescription{
+			outputCount: {
+				Title:       "Number of features",
+				Description: "Number of features in the collection that intersect the polygon.",
+				Schema:      map[string]any{"type": "integer", "minimum": 0},
+			},
+		},
+	}
+}
+
+func (c *countFeatures) prepare(inputs map[string]json.RawMessage) (execution, error) {
+	collectionID, err := c.scanner.parseCollection(inputs)
+	if err != nil {
+		return nil, err
+	}
+	polygon, err := parseGeometry(inputs, "Polygon", "MultiPolygon")
+	if err != nil {
+		return nil, err
+	}
+
+	return func(ctx context.Context) (map[string]any, error) {
+		count := 0
+		err := c.scanner.scan(ctx, collectionID, polygon, 0, func(_ *domain.Feature) error {
+			count++
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		return map[string]any{outputCount: count}, nil
+	}, nil
+}
+
+// intersectFeatures selects the features of a collection that intersect a geometry, optionally buffered.
+type intersectFeatures struct {
+	scanner *featureScanner
+}
+
+func (i *intersectFeatures) description() processDescription {
+	return processDescription{
+		ID:    "intersect-features",
+		Title: "Intersect features",
+		Description: "Selects the features of a collection that intersect the given geometry. Optionally the " +
+			"geometry is buffered with the given distance, selecting the features within that distance of the geometry.",
+		Version:  "1.0.0",
+		Keywords: []string{"intersect", "buffer", "features"},
+		Inputs: map[string]inputDescription{
+			inputCollection: i.scanner.collectionInput(),
+			inputGeometry: {
+				Title:       "Geometry",
+				Description: "GeoJSON geometry in WGS84 (CRS84) to intersect the features with.",
+				MinOccurs:   1,
+				Schema:      geometrySchema(),
+			},
+			inputDistance: {
+				Title:       "Buffer distance",
+				Description: "Distance in meters to buffer the geometry with. Defaults to 0 (no buffer).",
+				MinOccurs:   0,
+				Schema:      map[string]any{"type": "number", "minimum": 0, "default": 0},
+			},
+		},
+		Outputs: map[string]outputDescription{
+			outputFeatures: {
+				Title: "Features",
+				Description: fmt.Sprintf("GeoJSON FeatureCollection (in WGS84) with the features that intersect "+
+					"the (buffered) geometry, at most %d features.", maxOutputFeatures),
+				Schema: map[string]any{"type": "object", "format": "geojson-feature-collection"},
+			},
+		},
+	}
+}
+
+func (i *intersectFeatures) prepare(inputs map[string]json.RawMessage) (execution, error) {
+	collectionID, err := i.scanner.parseCollection(inputs)
+	if err != nil {
+		return nil, err
+	}
+	geometry, err := parseGeometry(inputs)
+	if err != nil {
+		return nil, err
+	}
+	var buffer float64
+	if raw, ok := inputs[inputDistance]; ok {
+		if err = json.Unmarshal(raw, &buffer); err != nil || buffer < 0 || math.IsInf(buffer, 0) {
+			return nil, inputError{"input '" + inputDistance + "' should be a positive number (meters)"}
+		}
+	}
+
+	return func(ctx context.Context) (map[string]any, error) {
+		fc := &domain.FeatureCollection{Features: make([]*domain.Feature, 0)}
+		err := i.scanner.scan(ctx, collectionID, geometry, buffer, func(f *domain.Feature) error {
+			if len(fc.Features) == maxOutputFeatures {
+				return inputError{fmt.Sprintf("more than %d features intersect the geometry, "+
+					"use a smaller geometry or distance", maxOutputFeatures)}
+			}
+			f.Links = nil
+			fc.Features = append(fc.Features, f)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		fc.NumberReturned = len(fc.Features)
+
+		return map[string]any{outputFeatures: fc}, nil
+	}, nil
+}
+
+// featureScanner scans the features of a collection for features near a geometry.
+type featureScanner struct {
+	datasources   FeatureDatasources
+	collectionIDs []string
+	baseURL       config.URL
+}
+
+func (s *featureScanner) collectionInput() inputDescription {
+	return inputDescription{
+		Title:       "Collection",
+		Description: "ID of the collection containing the features.",
+		MinOccurs:   1,
+		Schema:      map[string]any{"type": "string", "enum": s.collectionIDs},
+	}
+}
+
+func (s *featureScanner) parseCollection(inputs map[string]json.RawMessage) (string, error) {
+	var collectionID string
+	raw, ok := inputs[inputCollection]
+	if !ok {
+		return "", inputError{"input '" + inputCollection + "' is required"}
+	}
+	if err := json.Unmarshal(raw, &collectionID); err != nil || !slices.Contains(s.collectionIDs, collectionID) ||
+		s.datasources.GetDatasource(collectionID) == nil {
+		return "", inputError{"input '" + inputCollection + "' should be the ID of one of the feature collections"}
+	}
+
+	return collectionID, nil
+}
+
+// scan calls the given function for each feature in the collection within the given distance (in meters) of the geometry.
+func (s *featureScanner) scan(ctx context.Context, collectionID string, geometry geom.T, bufferDistance float64,
+	fn func(f *domain.Feature) error) error {
+
+	datasource := s.datasources.GetDatasource(collectionID)
+	schema, _, err := datasource.GetSchema(collectionID)
+	if err != nil {
+		return err
+	}
+	profile := domain.NewProfile(domain.RelAsLink, *s.baseURL.URL, *schema)
+
+	bounds := geometry.Bounds()
+	proj := newProjection((bounds.Min(1) + bounds.Max(1)) / 2)
+	target, err := proj.toPlanar(geometry)
+	if err != nil {
+		return inputError{err.Error()}
+	}
+	criteria := ds.FeaturesCriteria{
+		Limit:      scanPageSize,
+		InputSRID:  domain.SRID(domain.WGS84SRID),
+		OutputSRID: domain.SRID(domain.WGS84SRID),
+		Bbox:       proj.expand(bounds, bufferDistance),
+	}
+	for {
+		fc, cursors, err := datasource.GetFeatures(ctx, collectionID, criteria, profile)
+		if err != nil {
+			return err
+		}
+		if fc == nil {
+			return nil
+		}
+		for _, f := range fc.Features {
+			if f.Geometry == nil {
+				continue
+			}
+			featureGeom, err := f.Geometry.Decode()
+			if err != nil {
+				return fmt.Errorf("failed to decode geometry of feature %s: %w", f.ID, err)
+			}
+			planar, err := proj.toPlanar(featureGeom)
+			if err != nil {
+				return err
+			}
+			if distance(target, planar) > bufferDistance {
+				continue
+			}
+			if err = fn(f); err != nil {
+				return err
+			}
+		}
+		if !cursors.HasNext {
+			return nil
+		}
+		criteria.Cursor = cursors.Next.Decode(nil)
+		if err = ctx.Err(); err != nil {
+			return err
+		}
+	}
+}
+
+func geometrySchema(types ...string) map[string]any {
+	schema := map[string]any{
+		"type":     "object",
+		"format":   "geojson-geometry",
+		"required": []string{"type"},
+	}
+	if len(types) > 0 {
+		schema["properties"] = map[string]any{"type": map[string]any{"type": "string", "enum": types}}
+	}
+
+	return schema
+}
+
+// parseGeometry parses the GeoJSON geometry input, optionally restricted to the given layouts.
+func parseGeometry(inputs map[string]json.RawMessage, types ...string) (geom.T, error) {
+	raw, ok := inputs[inputGeometry]
+	if !ok {
+		return nil, inputError{"input '" + inputGeometry + "' is required"}
+	}
+	var geometry geojson.Geometry
+	if err := json.Unmarshal(raw, &geometry); err != nil {
+		return nil, inputError{"input '" + inputGeometry + "' should be a GeoJSON geometry: " + err.Error()}
+	}
+	if len(types) > 0 && !slices.Contains(types, geometry.Type) {
+		return nil, inputError{fmt.Sprintf("input '%s' should be a GeoJSON geometry of type %v", inputGeometry, types)}
+	}
+	result, err := geometry.Decode()
+	if err != nil {
+		return nil, inputError{"input '" + inputGeometry + "' should be a GeoJSON geometry: " + err.Error()}
+	}
+	if result.Empty() {
+		return nil, inputError{"input '" + inputGeometry + "' should not be empty"}
+	}
+
+	return result, nil
+}
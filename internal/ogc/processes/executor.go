@@ -0,0 +1,359 @@
+package processes
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/netip"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/PDOK/gokoala/config"
+	"github.com/PDOK/gokoala/internal/engine"
+	"github.com/google/uuid"
+)
+
+const (
+	// max time spent delivering a single callback to a subscriber
+	callbackTimeout = 10 * time.Second
+
+	// max time spent on job store operations
+	storeTimeout = 10 * time.Second
+
+	// message of failed jobs caused by server-side errors, details are logged instead
+	messageServerError = "failed to execute process, an unexpected server error occurred"
+)
+
+var (
+	now = func() time.Time { return time.Now().UTC() } // allow mocking
+
+	isCallbackAllowed = isPublicAddress // allow mocking
+
+	errCallbackAddress = errors.New("callbacks to internal addresses aren't allowed")
+
+	errQueueFull = errors.New("too many jobs are waiting for execution")
+)
+
+// subscriber URLs to be notified about the progress of a job, see https://docs.ogc.org/is/18-062r2/18-062r2.html#toc45
+type subscriber struct {
+	SuccessURI    string `json:"successUri"`
+	InProgressURI string `json:"inProgressUri,omitempty"`
+	FailedURI     string `json:"failedUri,omitempty"`
+}
+
+// executor executes processes (sync or async) as jobs, while keeping track of these jobs in the job store.
+type executor struct {
+	store      jobStore
+	slots      chan struct{} // limits the number of jobs running concurrently
+	queue      chan struct{} // limits the number of jobs running or waiting for a slot
+	timeout    time.Duration
+	retention  time.Duration
+	callbacks  bool
+	statusInfo func(j job) statusInfo
+
+	ctx      context.Context // canceled on shutdown
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+	mu       sync.Mutex
+	inFlight map[string]context.CancelFunc // cancels unfinished jobs, by job ID
+	client   *http.Client
+}
+
+func newExecutor(store jobStore, cfg config.ProcessesJobs, callbacks bool, statusInfo func(j job) statusInfo) *executor {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &executor{
+		store:      store,
+		slots:      make(chan struct{}, cfg.MaxConcurrent),
+		queue:      make(chan struct{}, cfg.MaxConcurrent+cfg.MaxQueued),
+		timeout:    cfg.Timeout.Duration,
+		retention:  cfg.Retention.Duration,
+		callbacks:  callbacks,
+		statusInfo: statusInfo,
+		ctx:        ctx,
+		cancel:     cancel,
+		inFlight:   make(map[string]context.CancelFunc),
+		client:     newCallbackClient(),
+	}
+}
+
+// newCallbackClient creates an HTTP client for callbacks to subscribers. Since subscriber URIs are provided
+// by clients, connections to internal addresses are refused to prevent server-side request forgery (SSRF).
+// This is checked when connecting, so after DNS resolution and for each redirect.
+func newCallbackClient() *http.Client {
+	dialer := &net.Dialer{
+		Timeout: callbackTimeout,
+		Control: func(_, address string, _ syscall.RawConn) error {
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return err
+			}
+			if !isCallbackAllowed(addrPort.Addr()) {
+				return fmt.Errorf("%w: %s", errCallbackAddress, addrPort.Addr())
+			}
+
+			return nil
+		},
+	}
+
+	return &http.Client{
+		Timeout: callbackTimeout,
+		Transport: &http.Transport{
+			Proxy:               nil, // connect directly, otherwise the address of the subscriber can't be checked
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: callbackTimeout,
+			MaxIdleConns:        10,
+			IdleConnTimeout:     90 * time.Second,
+		},
+	}
+}
+
+// checkCallbackHost resolves the host of a subscriber URI, to reject callbacks to internal addresses upfront.
+func checkCallbackHost(ctx context.Context, host string) error {
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return err
+	}
+	for _, addr := range addrs {
+		if !isCallbackAllowed(addr) {
+			return fmt.Errorf("%w: %s", errCallbackAddress, addr)
+		}
+	}
+
+	return nil
+}
+
+// isPublicAddress whether the given IP address is publicly routable. Global unicast excludes loopback,
+// link-local, multicast and unspecified addresses, but includes private addresses.
+func isPublicAddress(addr netip.Addr) bool {
+	addr = addr.Unmap()
+
+	return addr.IsGlobalUnicast() && !addr.IsPrivate()
+}
+
+// submit creates a new job for the given execution of a process. In case of sync execution the job
+// is finished on return, in case of async execution the job is executed in the background.
+// Returns errQueueFull when too many jobs are waiting for execution already.
+func (x *executor) submit(ctx context.Context, processID string, exec execution, sub *subscriber, async bool) (job, error) {
+	x.cleanup(ctx)
+
+	select {
+	case x.queue <- struct{}{}:
+	default:
+		return job{}, errQueueFull
+	}
+	created := now()
+	j := job{
+		ID:        uuid.New().String(),
+		ProcessID: processID,
+		Status:    statusAccepted,
+		Created:   created,
+		Updated:   created,
+	}
+	if err := x.store.save(ctx, j); err != nil {
+		<-x.queue
+		return j, err
+	}
+	parent := x.ctx
+	if !async {
+		parent = ctx // sync execution is canceled when the client goes away
+	}
+	jobCtx, cancel := context.WithCancel(parent)
+	x.mu.Lock()
+	x.inFlight[j.ID] = cancel
+	x.mu.Unlock()
+
+	if async {
+		x.wg.Go(func() {
+			x.run(jobCtx, j, exec, sub)
+		})
+
+		return j, nil
+	}
+
+	return x.run(jobCtx, j, exec, sub), nil
+}
+
+// run executes the given job and records its progress in the job store.
+func (x *executor) run(ctx context.Context, j job, exec execution, sub *subscriber) job {
+	defer x.release(j.ID)
+	defer func() { <-x.queue }()
+
+	select {
+	case x.slots <- struct{}{}:
+		defer func() { <-x.slots }()
+	case <-ctx.Done():
+		return x.finish(j, nil, ctx.Err(), sub)
+	}
+	// only the execution itself is limited in time, not the time spent waiting for a slot
+	ctx, cancel := context.WithTimeout(ctx, x.timeout)
+	defer cancel()
+
+	started := now()
+	j.Status = statusRunning
+	j.Started = &started
+	j.Updated = started
+	if !x.update(j) {
+		return j
+	}
+	x.notify(sub, j, nil)
+
+	outputs, err := exec(ctx)
+	if err == nil && ctx.Err() != nil {
+		err = ctx.Err()
+	}
+
+	return x.finish(j, outputs, err, sub)
+}
+
+func (x *executor) finish(j job, outputs map[string]any, err error, sub *subscriber) job {
+	finished := now()
+	j.Finished = &finished
+	j.Updated = finished
+	if err == nil {
+		j.Results, err = json.Marshal(outputs)
+	}
+	var inputErr inputError
+	switch {
+	case err == nil:
+		j.Status = statusSuccessful
+		j.Progress = 100
+		j.Message = "job finished successfully"
+	case errors.As(err, &inputErr):
+		j.Status = statusFailed
+		j.Message = inputErr.Error()
+	case errors.Is(err, context.Canceled):
+		j.Status = statusFailed
+		j.Message = "job canceled"
+	case errors.Is(err, context.DeadlineExceeded):
+		j.Status = statusFailed
+		j.Message = "job timed out"
+	default:
+		log.Printf("job %s of process %s failed: %v", j.ID, j.ProcessID, err)
+		j.Status = statusFailed
+		j.Message = messageServerError
+	}
+	if x.update(j) {
+		x.notify(sub, j, j.Results)
+	}
+
+	return j
+}
+
+// update saves the given job, unless it has been dismissed in the meantime. Returns false when dismissed.
+func (x *executor) update(j job) bool {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if _, ok := x.inFlight[j.ID]; !ok {
+		return false
+	}
+	// use a new context, the job context may be canceled when the job failed for that reason
+	ctx, cancel := context.WithTimeout(context.Background(), storeTimeout)
+	defer cancel()
+	if err := x.store.save(ctx, j); err != nil {
+		log.Printf("%v", err)
+	}
+
+	return true
+}
+
+func (x *executor) release(jobID string) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if cancel, ok := x.inFlight[jobID]; ok {
+		cancel()
+		delete(x.inFlight, jobID)
+	}
+}
+
+// dismiss cancels the given job, when still running, and removes it (including its results).
+func (x *executor) dismiss(ctx context.Context, j job) (job, error) {
+	x.mu.Lock()
+	if cancel, ok := x.inFlight[j.ID]; ok {
+		cancel()
+		delete(x.inFlight, j.ID)
+	}
+	x.mu.Unlock()
+
+	if err := x.store.delete(ctx, j.ID); err != nil {
+		return j, err
+	}
+	j.Status = statusDismissed
+	j.Message = "job dismissed"
+	j.Updated = now()
+	j.Results = nil
+
+	return j, nil
+}
+
+// cleanup removes jobs which finished longer ago than the retention period.
+func (x *executor) cleanup(ctx context.Context) {
+	if err := x.store.deleteFinishedBefore(ctx, now().Add(-x.retention)); err != nil {
+		log.Printf("%v", err)
+	}
+}
+
+// notify sends the status (or the results) of the given job to the subscriber, when requested.
+func (x *executor) notify(sub *subscriber, j job, results []byte) {
+	if sub == nil || !x.callbacks {
+		return
+	}
+	var uri string
+	var body []byte
+	switch j.Status {
+	case statusRunning:
+		uri = sub.InProgressURI
+	case statusSuccessful:
+		uri, body = sub.SuccessURI, results
+	case statusFailed:
+		uri = sub.FailedURI
+	default:
+		return
+	}
+	if uri == "" {
+		return
+	}
+	if body == nil {
+		var err error
+		if body, err = json.Marshal(x.statusInfo(j)); err != nil {
+			log.Printf("failed to encode status of job %s for callback: %v", j.ID, err)
+			return
+		}
+	}
+	if err := x.post(uri, body); err != nil {
+		log.Printf("failed to notify subscriber of job %s: %v", j.ID, err)
+	}
+}
+
+func (x *executor) post(uri string, body []byte) error {
+	req, err := http.NewRequestWithContext(x.ctx, http.MethodPost, uri, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set(engine.HeaderContentType, engine.MediaTypeJSON)
+	resp, err := x.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("callback to %s responded with status code: %d", uri, resp.StatusCode)
+	}
+
+	return nil
+}
+
+// shutdown cancels all running jobs, waits for these to be recorded as failed and closes the job store.
+func (x *executor) shutdown() {
+	x.cancel()
+	x.wg.Wait()
+	if err := x.store.close(); err != nil {
+		log.Printf("failed to close job store: %v", err)
+	}
+}
@@ -0,0 +1,213 @@
+package processes
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/twpayne/go-geom"
+	"github.com/twpayne/go-geom/xy"
+	"github.com/twpayne/go-geom/xy/location"
+)
+
+const (
+	earthRadius     = 6371008.8 // mean radius in meters
+	metersPerDegree = earthRadius * math.Pi / 180
+)
+
+// planarGeometry a geometry decomposed in points, line segments and polygons, with coordinates in meters.
+type planarGeometry struct {
+	// first coordinate of each component (point, linestring or polygon)
+	anchors []geom.Coord
+
+	// line segments of all linestrings and polygon rings
+	segments [][2]geom.Coord
+
+	// polygons as flat XY coordinates of their rings, the first ring is the exterior ring
+	polygons [][][]float64
+}
+
+// projection a local equirectangular projection from WGS84 (CRS84) to meters. Distances are
+// accurate enough near the given latitude of origin, which suffices for simple geoprocessing.
+type projection struct {
+	cosLat float64
+}
+
+func newProjection(originLat float64) projection {
+	return projection{cosLat: math.Cos(originLat * math.Pi / 180)}
+}
+
+func (p projection) project(lon, lat float64) geom.Coord {
+	return geom.Coord{lon * metersPerDegree * p.cosLat, lat * metersPerDegree}
+}
+
+// expand the given bounds (in CRS84) with the given distance (in meters).
+func (p projection) expand(bounds *geom.Bounds, distance float64) *geom.Bounds {
+	if distance <= 0 {
+		return bounds
+	}
+	maxLat := max(math.Abs(bounds.Min(1)), math.Abs(bounds.Max(1)))
+	cosLat := max(math.Cos(math.Min(maxLat+distance/metersPerDegree, 89.9)*math.Pi/180), 1e-6)
+	dLon := distance / (metersPerDegree * cosLat)
+	dLat := distance / metersPerDegree
+
+	return geom.NewBounds(geom.XY).Set(
+		bounds.Min(0)-dLon, bounds.Min(1)-dLat,
+		bounds.Max(0)+dLon, bounds.Max(1)+dLat)
+}
+
+// toPlanar decomposes the given geometry (in CRS84) while projecting it to meters.
+func (p projection) toPlanar(g geom.T) (*planarGeometry, error) {
+	result := &planarGeometry{}
+	if err := p.addGeometry(result, g); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (p projection) addGeometry(result *planarGeometry, g geom.T) error {
+	switch t := g.(type) {
+	case *geom.Point:
+		if !t.Empty() {
+			result.anchors = append(result.anchors, p.project(t.X(), t.Y()))
+		}
+	case *geom.MultiPoint:
+		for i := range t.NumPoints() {
+			if err := p.addGeometry(result, t.Point(i)); err != nil {
+				return err
+			}
+		}
+	case *geom.LineString:
+		p.addLine(result, t.FlatCoords(), t.Stride())
+	case *geom.MultiLineString:
+		for i := range t.NumLineStrings() {
+			p.addLine(result, t.LineString(i).FlatCoords(), t.Stride())
+		}
+	case *geom.Polygon:
+		p.addPolygon(result, t)
+	case *geom.MultiPolygon:
+		for i := range t.NumPolygons() {
+			p.addPolygon(result, t.Polygon(i))
+		}
+	case *geom.GeometryCollection:
+		for _, child := range t.Geoms() {
+			if err := p.addGeometry(result, child); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("unsupported geometry type %T", g)
+	}
+
+	return nil
+}
+
+func (p projection) addLine(result *planarGeometry, flatCoords []float64, stride int) {
+	projected := p.projectCoords(flatCoords, stride)
+	if len(projected) == 0 {
+		return
+	}
+	result.anchors = append(result.anchors, geom.Coord{projected[0], projected[1]})
+	result.addSegments(projected)
+}
+
+func (p projection) addPolygon(result *planarGeometry, polygon *geom.Polygon) {
+	if polygon.Empty() {
+		return
+	}
+	rings := make([][]float64, 0, polygon.NumLinearRings())
+	for i := range polygon.NumLinearRings() {
+		ring := polygon.LinearRing(i)
+		projected := p.projectCoords(ring.FlatCoords(), ring.Stride())
+		result.addSegments(projected)
+		rings = append(rings, projected)
+	}
+	result.anchors = append(result.anchors, geom.Coord{rings[0][0], rings[0][1]})
+	result.polygons = append(result.polygons, rings)
+}
+
+func (p projection) projectCoords(flatCoords []float64, stride int) []float64 {
+	projected := make([]float64, 0, len(flatCoords)/stride*2)
+	for i := 0; i < len(flatCoords); i += stride {
+		c := p.project(flatCoords[i], flatCoords[i+1])
+		projected = append(projected, c[0], c[1])
+	}
+
+	return projected
+}
+
+func (g *planarGeometry) addSegments(flatCoords []float64) {
+	for i := 2; i < len(flatCoords); i += 2 {
+		g.segments = append(g.segments, [2]geom.Coord{
+			{flatCoords[i-2], flatCoords[i-1]},
+			{flatCoords[i], flatCoords[i+1]},
+		})
+	}
+}
+
+// distance the minimum distance (in meters) between the given geometries, 0 when these intersect.
+func distance(a, b *planarGeometry) float64 {
+	if a.isEmpty() || b.isEmpty() {
+		return math.Inf(1)
+	}
+	// a component located completely inside a polygon of the other geometry
+	if b.contains(a.anchors) || a.contains(b.anchors) {
+		return 0
+	}
+	result := math.Inf(1)
+	for _, anchor := range a.anchors {
+		result = min(result, distanceToPoint(b, anchor))
+	}
+	for _, anchor := range b.anchors {
+		result = min(result, distanceToPoint(a, anchor))
+	}
+	for _, s1 := range a.segments {
+		for _, s2 := range b.segments {
+			result = min(result, xy.DistanceFromLineToLine(s1[0], s1[1], s2[0], s2[1]))
+			if result == 0 {
+				return 0
+			}
+		}
+	}
+
+	return result
+}
+
+func distanceToPoint(g *planarGeometry, c geom.Coord) float64 {
+	result := math.Inf(1)
+	for _, anchor := range g.anchors {
+		result = min(result, xy.Distance(anchor, c))
+	}
+	for _, s := range g.segments {
+		result = min(result, xy.DistanceFromPointToLine(c, s[0], s[1]))
+	}
+
+	return result
+}
+
+func (g *planarGeometry) isEmpty() bool {
+	return len(g.anchors) == 0
+}
+
+// contains whether one of the given coordinates is located inside (or on the boundary of) one of the polygons.
+func (g *planarGeometry) contains(coords []geom.Coord) bool {
+	for _, polygon := range g.polygons {
+		for _, c := range coords {
+			if xy.LocatePointInRing(geom.XY, c, polygon[0]) == location.Exterior {
+				continue
+			}
+			inHole := false
+			for _, hole := range polygon[1:] {
+				if xy.LocatePointInRing(geom.XY, c, hole) == location.Interior {
+					inHole = true
+					break
+				}
+			}
+			if !inHole {
+				return true
+			}
+		}
+	}
+
+	return false
+}
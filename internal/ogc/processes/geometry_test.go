@@ -0,0 +1,87 @@
+package processes
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/twpayne/go-geom"
+)
+
+func TestDistance(t *testing.T) {
+	square := geom.NewPolygonFlat(geom.XY, []float64{5, 52, 5.01, 52, 5.01, 52.01, 5, 52.01, 5, 52}, []int{10})
+	squareWithHole := geom.NewPolygonFlat(geom.XY, []float64{
+		5, 52, 5.01, 52, 5.01, 52.01, 5, 52.01, 5, 52,
+		5.004, 52.004, 5.006, 52.004, 5.006, 52.006, 5.004, 52.006, 5.004, 52.004,
+	}, []int{10, 20})
+
+	tests := []struct {
+		name  string
+		a     geom.T
+		b     geom.T
+		want  float64
+		delta float64
+	}{
+		{
+			name: "point inside polygon",
+			a:    square,
+			b:    geom.NewPointFlat(geom.XY, []float64{5.005, 52.005}),
+			want: 0,
+		},
+		{
+			name:  "point in hole of polygon",
+			a:     squareWithHole,
+			b:     geom.NewPointFlat(geom.XY, []float64{5.005, 52.005}),
+			want:  0.001 * metersPerDegree * newProjection(52).cosLat, // nearest edge of the hole is east/west
+			delta: 1,
+		},
+		{
+			name:  "point north of polygon",
+			a:     square,
+			b:     geom.NewPointFlat(geom.XY, []float64{5.005, 52.02}),
+			want:  0.01 * metersPerDegree,
+			delta: 1,
+		},
+		{
+			name: "line crossing polygon",
+			a:    square,
+			b:    geom.NewLineStringFlat(geom.XY, []float64{4.9, 52.005, 5.1, 52.005}),
+			want: 0,
+		},
+		{
+			name: "polygon inside polygon",
+			a:    geom.NewPolygonFlat(geom.XY, []float64{5.001, 52.001, 5.002, 52.001, 5.002, 52.002, 5.001, 52.001}, []int{8}),
+			b:    square,
+			want: 0,
+		},
+		{
+			name:  "points",
+			a:     geom.NewPointFlat(geom.XY, []float64{5, 52}),
+			b:     geom.NewMultiPointFlat(geom.XY, []float64{5, 52.1, 5, 52.001}),
+			want:  0.001 * metersPerDegree,
+			delta: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			proj := newProjection(52)
+			a, err := proj.toPlanar(tt.a)
+			require.NoError(t, err)
+			b, err := proj.toPlanar(tt.b)
+			require.NoError(t, err)
+
+			assert.InDelta(t, tt.want, distance(a, b), tt.delta)
+			assert.InDelta(t, tt.want, distance(b, a), tt.delta)
+		})
+	}
+}
+
+func TestProjection_Expand(t *testing.T) {
+	bounds := geom.NewBounds(geom.XY).Set(5, 52, 5.01, 52.01)
+	proj := newProjection(52)
+
+	assert.Equal(t, bounds, proj.expand(bounds, 0))
+	expanded := proj.expand(bounds, 1000)
+	assert.InDelta(t, 52-1000/metersPerDegree, expanded.Min(1), 1e-9)
+	assert.Less(t, expanded.Min(0), 5-1000/metersPerDegree, "longitude degrees are shorter than latitude degrees")
+}
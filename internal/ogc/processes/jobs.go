@@ -0,0 +1,152 @@
+package processes
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/PDOK/gokoala/config"
+)
+
+const (
+	jobStoreMemory = "memory"
+	jobStoreSQLite = "sqlite"
+)
+
+type jobStatus string
+
+// job statuses, see https://docs.ogc.org/is/18-062r2/18-062r2.html#toc44
+const (
+	statusAccepted   jobStatus = "accepted"
+	statusRunning    jobStatus = "running"
+	statusSuccessful jobStatus = "successful"
+	statusFailed     jobStatus = "failed"
+	statusDismissed  jobStatus = "dismissed"
+)
+
+// job a single execution of a process.
+type job struct {
+	ID        string     `db:"id"`
+	ProcessID string     `db:"process_id"`
+	Status    jobStatus  `db:"status"`
+	Message   string     `db:"message"`
+	Progress  int        `db:"progress"`
+	Created   time.Time  `db:"created"`
+	Started   *time.Time `db:"started"`
+	Finished  *time.Time `db:"finished"`
+	Updated   time.Time  `db:"updated"`
+
+	// Results document (outputs by output ID) in JSON, only available when the job is successful
+	Results []byte `db:"results"`
+}
+
+func (j *job) isFinished() bool {
+	return j.Status == statusSuccessful || j.Status == statusFailed || j.Status == statusDismissed
+}
+
+// jobStore keeps track of jobs and their results.
+type jobStore interface {
+	// save inserts or updates the given job
+	save(ctx context.Context, j job) error
+
+	// get returns the job with the given ID, nil when the job doesn't exist
+	get(ctx context.Context, id string) (*job, error)
+
+	// list returns all jobs, most recently created first
+	list(ctx context.Context) ([]job, error)
+
+	// delete removes the job with the given ID (including its results)
+	delete(ctx context.Context, id string) error
+
+	// deleteFinishedBefore removes jobs (including their results) which finished before the given moment
+	deleteFinishedBefore(ctx context.Context, moment time.Time) error
+
+	// ping checks whether the store is available
+	ping(ctx context.Context) error
+
+	// close closes the store gracefully
+	close() error
+}
+
+func newJobStore(cfg config.ProcessesJobs) (jobStore, error) {
+	switch cfg.Store {
+	case jobStoreMemory:
+		return newMemoryJobStore(), nil
+	case jobStoreSQLite:
+		return newSQLiteJobStore(cfg.SQLitePath)
+	default:
+		return nil, fmt.Errorf("unsupported job store: %s", cfg.Store)
+	}
+}
+
+// memoryJobStore keeps jobs in memory, jobs are lost on restart.
+type memoryJobStore struct {
+	mu   sync.RWMutex
+	jobs map[string]job
+}
+
+func newMemoryJobStore() *memoryJobStore {
+	return &memoryJobStore{jobs: make(map[string]job)}
+}
+
+func (m *memoryJobStore) save(_ context.Context, j job) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.jobs[j.ID] = j
+
+	return nil
+}
+
+func (m *memoryJobStore) get(_ context.Context, id string) (*job, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if j, ok := m.jobs[id]; ok {
+		return &j, nil
+	}
+
+	return nil, nil //nolint:nilnil
+}
+
+func (m *memoryJobStore) list(_ context.Context) ([]job, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	result := make([]job, 0, len(m.jobs))
+	for _, j := range m.jobs {
+		result = append(result, j)
+	}
+	slices.SortFunc(result, func(a, b job) int {
+		return b.Created.Compare(a.Created)
+	})
+
+	return result, nil
+}
+
+func (m *memoryJobStore) delete(_ context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.jobs, id)
+
+	return nil
+}
+
+func (m *memoryJobStore) deleteFinishedBefore(_ context.Context, moment time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for id, j := range m.jobs {
+		if j.Finished != nil && j.Finished.Before(moment) {
+			delete(m.jobs, id)
+		}
+	}
+
+	return nil
+}
+
+func (m *memoryJobStore) ping(_ context.Context) error {
+	return nil
+}
+
+func (m *memoryJobStore) close() error {
+	return nil
+}
@@ -0,0 +1,108 @@
+package processes
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/PDOK/gokoala/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJobStore(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  func(t *testing.T) config.ProcessesJobs
+	}{
+		{
+			name: "memory",
+			cfg: func(_ *testing.T) config.ProcessesJobs {
+				return config.ProcessesJobs{Store: jobStoreMemory}
+			},
+		},
+		{
+			name: "sqlite",
+			cfg: func(t *testing.T) config.ProcessesJobs {
+				return config.ProcessesJobs{Store: jobStoreSQLite, SQLitePath: filepath.Join(t.TempDir(), "jobs.sqlite")}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			store, err := newJobStore(tt.cfg(t))
+			require.NoError(t, err)
+			defer store.close()
+			require.NoError(t, store.ping(ctx))
+
+			created := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+			finished := created.Add(time.Minute)
+			older := job{ID: "1", ProcessID: "p", Status: statusSuccessful, Created: created, Updated: finished,
+				Finished: &finished, Progress: 100, Results: []byte(`{"count":1}`)}
+			newer := job{ID: "2", ProcessID: "p", Status: statusRunning, Created: created.Add(time.Hour), Updated: created.Add(time.Hour)}
+			require.NoError(t, store.save(ctx, older))
+			require.NoError(t, store.save(ctx, newer))
+
+			got, err := store.get(ctx, "1")
+			require.NoError(t, err)
+			require.NotNil(t, got)
+			assert.Equal(t, statusSuccessful, got.Status)
+			assert.JSONEq(t, `{"count":1}`, string(got.Results))
+			assert.True(t, got.Finished.Equal(finished))
+
+			missing, err := store.get(ctx, "3")
+			require.NoError(t, err)
+			assert.Nil(t, missing)
+
+			jobs, err := store.list(ctx)
+			require.NoError(t, err)
+			require.Len(t, jobs, 2)
+			assert.Equal(t, "2", jobs[0].ID, "newest first")
+
+			newer.Status = statusFailed
+			newer.Message = "failed"
+			newer.Finished = &finished
+			require.NoError(t, store.save(ctx, newer))
+			got, err = store.get(ctx, "2")
+			require.NoError(t, err)
+			assert.Equal(t, statusFailed, got.Status)
+
+			require.NoError(t, store.deleteFinishedBefore(ctx, finished.Add(time.Second)))
+			jobs, err = store.list(ctx)
+			require.NoError(t, err)
+			assert.Empty(t, jobs)
+		})
+	}
+}
+
+func TestSQLiteJobStore_InterruptedJobs(t *testing.T) {
+	ctx := context.Background()
+	file := filepath.Join(t.TempDir(), "jobs.sqlite")
+	store, err := newSQLiteJobStore(file)
+	require.NoError(t, err)
+	created := now()
+	finished := created.Add(time.Second)
+	require.NoError(t, store.save(ctx, job{ID: "1", ProcessID: "p", Status: statusRunning, Created: created, Updated: created}))
+	require.NoError(t, store.save(ctx, job{ID: "2", ProcessID: "p", Status: statusAccepted, Created: created, Updated: created}))
+	require.NoError(t, store.save(ctx, job{ID: "3", ProcessID: "p", Status: statusSuccessful, Created: created,
+		Updated: finished, Finished: &finished}))
+	require.NoError(t, store.close()) // mimic a crash, jobs are left running/accepted
+
+	store, err = newSQLiteJobStore(file)
+	require.NoError(t, err)
+	defer store.close()
+	for _, id := range []string{"1", "2"} {
+		got, err := store.get(ctx, id)
+		require.NoError(t, err)
+		require.NotNil(t, got)
+		assert.Equal(t, statusFailed, got.Status)
+		assert.Equal(t, "job interrupted by restart", got.Message)
+		assert.NotNil(t, got.Finished)
+	}
+	got, err := store.get(ctx, "3")
+	require.NoError(t, err)
+	require.NotNil(t, got)
+	assert.Equal(t, statusSuccessful, got.Status)
+}
@@ -1,30 +1,498 @@
 package processes
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"mime"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
 
-	"github.com/PDOK/gokoala/config"
 	"github.com/PDOK/gokoala/internal/engine"
+	"github.com/PDOK/gokoala/internal/ogc/common/core"
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	processesPath = "/processes"
+	jobsPath      = "/jobs"
+
+	// max size of an execute request
+	maxExecuteRequestSize = 10 << 20 // 10 MiB
+
+	headerPrefer            = "Prefer"
+	headerPreferenceApplied = "Preference-Applied"
+	preferRespondAsync      = "respond-async"
+	headerRetryAfter        = "Retry-After"
+
+	// seconds after which clients should retry when too many jobs are waiting for execution
+	retryAfterQueueFull = 30
+
+	responseRaw      = "raw"
+	responseDocument = "document"
+
+	relExecute = "http://www.opengis.net/def/rel/ogc/1.0/execute"
+	relResults = "http://www.opengis.net/def/rel/ogc/1.0/results"
 )
 
 type Processes struct {
 	engine *engine.Engine
+
+	// built-in processes and their execution, nil when acting as a proxy for an external processes server
+	registry *registry
+	executor *executor
+
+	// summaries of the processes offered, built-in or by the external processes server
+	processes []core.Process
 }
 
-func NewProcesses(e *engine.Engine) *Processes {
+// NewProcesses Bootstraps OGC API Processes logic. Either acts as a proxy for an external processes
+// server or executes the built-in processes, which operate on the given feature datasources.
+func NewProcesses(e *engine.Engine, datasources FeatureDatasources) *Processes {
+	cfg := e.Config.OgcAPI.Processes
 	processes := &Processes{engine: e}
-	e.Router.Handle("/jobs*", processes.forwarder(e.Config.OgcAPI.Processes.ProcessesServer))
-	e.Router.Handle("/processes*", processes.forwarder(e.Config.OgcAPI.Processes.ProcessesServer))
-	e.Router.Handle("/api*", processes.forwarder(e.Config.OgcAPI.Processes.ProcessesServer))
+	if cfg.IsProxy() {
+		var err error
+		if processes.processes, err = processes.fetchProcesses(cfg); err != nil {
+			log.Printf("WARNING: failed to retrieve processes from processes server, "+
+				"processes won't be listed on landing page: %v", err)
+		}
+		e.Router.Handle(jobsPath+"*", processes.forwarder(cfg.ProcessesServer))
+		e.Router.Handle(processesPath+"*", processes.forwarder(cfg.ProcessesServer))
+		e.RegisterReadinessCheck("processes", "server", func(ctx context.Context) error {
+			return engine.CheckURL(ctx, cfg.ProcessesServer.URL)
+		})
+
+		return processes
+	}
+
+	store, err := newJobStore(cfg.Jobs)
+	if err != nil {
+		log.Fatalf("failed to open job store: %v", err)
+	}
+	processes.registry = newRegistry(builtInProcesses(datasources, e.Config.OgcAPI.Features.Collections, e.Config.BaseURL)...)
+	processes.executor = newExecutor(store, cfg.Jobs, cfg.SupportsCallback, processes.statusInfo)
+	for _, proc := range processes.registry.all() {
+		desc := proc.description()
+		inputs, _ := json.Marshal(desc.Inputs)
+		processes.processes = append(processes.processes, core.Process{
+			ID:          desc.ID,
+			Title:       desc.Title,
+			Description: desc.Description,
+			Collections: processes.referencedCollections(inputs),
+		})
+	}
+	e.RegisterShutdownHook(processes.executor.shutdown)
+	e.RegisterReadinessCheck("processes", "jobs", store.ping)
+
+	e.Router.Get(processesPath, processes.ProcessList())
+	e.Router.Get(processesPath+"/{processId}", processes.ProcessDescription())
+	e.Router.Post(processesPath+"/{processId}/execution", processes.Execute())
+	e.Router.Get(jobsPath, processes.JobList())
+	e.Router.Get(jobsPath+"/{jobId}", processes.JobStatus())
+	e.Router.Get(jobsPath+"/{jobId}/results", processes.JobResults())
+	if cfg.SupportsDismiss {
+		e.Router.Delete(jobsPath+"/{jobId}", processes.Dismiss())
+	}
 
 	return processes
 }
 
-func (p *Processes) forwarder(processServer config.URL) http.HandlerFunc {
+// GetProcesses returns summaries of the processes offered, to be listed on the landing page.
+func (p *Processes) GetProcesses() []core.Process {
+	return p.processes
+}
+
+type link struct {
+	Rel   string `json:"rel"`
+	Type  string `json:"type,omitempty"`
+	Title string `json:"title,omitempty"`
+	Href  string `json:"href"`
+}
+
+// processDocument summary or full description of a process, see https://docs.ogc.org/is/18-062r2/18-062r2.html#toc36
+type processDocument struct {
+	ID                 string                       `json:"id"`
+	Title              string                       `json:"title"`
+	Description        string                       `json:"description"`
+	Version            string                       `json:"version"`
+	Keywords           []string                     `json:"keywords,omitempty"`
+	JobControlOptions  []string                     `json:"jobControlOptions"`
+	OutputTransmission []string                     `json:"outputTransmission"`
+	Inputs             map[string]inputDescription  `json:"inputs,omitempty"`
+	Outputs            map[string]outputDescription `json:"outputs,omitempty"`
+	Links              []link                       `json:"links"`
+}
+
+type processList struct {
+	Processes []processDocument `json:"processes"`
+	Links     []link            `json:"links"`
+}
+
+// statusInfo status of a job, see https://docs.ogc.org/is/18-062r2/18-062r2.html#toc44
+type statusInfo struct {
+	ProcessID string     `json:"processID"`
+	Type      string     `json:"type"`
+	JobID     string     `json:"jobID"`
+	Status    jobStatus  `json:"status"`
+	Message   string     `json:"message,omitempty"`
+	Created   time.Time  `json:"created"`
+	Started   *time.Time `json:"started,omitempty"`
+	Finished  *time.Time `json:"finished,omitempty"`
+	Updated   time.Time  `json:"updated"`
+	Progress  int        `json:"progress"`
+	Links     []link     `json:"links"`
+}
+
+type jobList struct {
+	Jobs  []statusInfo `json:"jobs"`
+	Links []link       `json:"links"`
+}
+
+// executeRequest see https://docs.ogc.org/is/18-062r2/18-062r2.html#toc33
+type executeRequest struct {
+	Inputs     map[string]json.RawMessage `json:"inputs"`
+	Response   string                     `json:"response"`
+	Subscriber *subscriber                `json:"subscriber"`
+}
+
+// ProcessList serves the list of built-in processes.
+func (p *Processes) ProcessList() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		all := p.registry.all()
+		list := processList{
+			Processes: make([]processDocument, 0, len(all)),
+			Links:     []link{p.link("self", "This document", processesPath)},
+		}
+		for _, proc := range all {
+			list.Processes = append(list.Processes, p.processDocument(proc, false))
+		}
+		p.engine.Serve(w, r, engine.ServeJSON(list), engine.ServeContentType(engine.MediaTypeJSON))
+	}
+}
+
+// ProcessDescription serves the description of a single process, including its inputs and outputs.
+func (p *Processes) ProcessDescription() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		processID := chi.URLParam(r, "processId")
+		proc, ok := p.registry.get(processID)
+		if !ok {
+			engine.RenderProblem(engine.ProblemNotFound, w, "unknown process "+processID)
+
+			return
+		}
+		p.engine.Serve(w, r, engine.ServeJSON(p.processDocument(proc, true)), engine.ServeContentType(engine.MediaTypeJSON))
+	}
+}
+
+// Execute executes a process, synchronously or - when preferred by the client - asynchronously.
+func (p *Processes) Execute() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		processID := chi.URLParam(r, "processId")
+		proc, ok := p.registry.get(processID)
+		if !ok {
+			engine.RenderProblem(engine.ProblemNotFound, w, "unknown process "+processID)
+
+			return
+		}
+		req, ok := readExecuteRequest(w, r)
+		if !ok {
+			return
+		}
+		inputs := proc.description().Inputs
+		for inputID := range req.Inputs {
+			if _, known := inputs[inputID]; !known {
+				engine.RenderProblem(engine.ProblemBadRequest, w, "unknown input '"+inputID+"' for process "+processID)
+
+				return
+			}
+		}
+		exec, err := proc.prepare(req.Inputs)
+		if err != nil {
+			engine.RenderProblem(engine.ProblemBadRequest, w, err.Error())
+
+			return
+		}
+
+		async := isRespondAsyncPreferred(r)
+		j, err := p.executor.submit(r.Context(), processID, exec, req.Subscriber, async)
+		if errors.Is(err, errQueueFull) {
+			w.Header().Set(headerRetryAfter, strconv.Itoa(retryAfterQueueFull))
+			engine.RenderProblem(engine.ProblemServiceUnavailable, w, "too many jobs are waiting for execution, try again later")
+
+			return
+		} else if err != nil {
+			engine.RenderProblemAndLog(engine.ProblemServerError, w, err)
+
+			return
+		}
+		if async {
+			w.Header().Set("Location", p.engine.Config.BaseURL.String()+jobsPath+"/"+j.ID)
+			w.Header().Set(headerPreferenceApplied, preferRespondAsync)
+			writeJSON(w, http.StatusCreated, p.statusInfo(j))
+
+			return
+		}
+		p.serveResults(w, r, j, req.Response == responseRaw || req.Response == "")
+	}
+}
+
+// JobList serves the list of jobs, optionally filtered by process ID and/or status.
+func (p *Processes) JobList() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		jobs, err := p.executor.store.list(r.Context())
+		if err != nil {
+			engine.RenderProblemAndLog(engine.ProblemServerError, w, err)
+
+			return
+		}
+		processIDs := r.URL.Query()["processID"]
+		statuses := r.URL.Query()["status"]
+		list := jobList{
+			Jobs:  make([]statusInfo, 0, len(jobs)),
+			Links: []link{p.link("self", "This document", jobsPath)},
+		}
+		for _, j := range jobs {
+			if (len(processIDs) > 0 && !contains(processIDs, j.ProcessID)) ||
+				(len(statuses) > 0 && !contains(statuses, string(j.Status))) {
+				continue
+			}
+			list.Jobs = append(list.Jobs, p.statusInfo(j))
+		}
+		p.engine.Serve(w, r, engine.ServeJSON(list), engine.ServeContentType(engine.MediaTypeJSON))
+	}
+}
+
+// JobStatus serves the status of a single job.
+func (p *Processes) JobStatus() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		j, ok := p.getJob(w, r)
+		if !ok {
+			return
+		}
+		p.engine.Serve(w, r, engine.ServeJSON(p.statusInfo(*j)), engine.ServeContentType(engine.MediaTypeJSON))
+	}
+}
+
+// JobResults serves the results (outputs) of a successful job.
+func (p *Processes) JobResults() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		targetURL := *processServer.URL
-		targetURL.Path = processServer.Path + r.URL.Path
-		targetURL.RawQuery = r.URL.RawQuery
-		p.engine.ReverseProxy(w, r, &targetURL, false, "")
+		j, ok := p.getJob(w, r)
+		if !ok {
+			return
+		}
+		p.serveResults(w, r, *j, false)
 	}
 }
+
+// Dismiss cancels a job, when still running, and removes the job including its results.
+func (p *Processes) Dismiss() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		j, ok := p.getJob(w, r)
+		if !ok {
+			return
+		}
+		dismissed, err := p.executor.dismiss(r.Context(), *j)
+		if err != nil {
+			engine.RenderProblemAndLog(engine.ProblemServerError, w, err)
+
+			return
+		}
+		p.engine.Serve(w, r, engine.ServeJSON(p.statusInfo(dismissed)), engine.ServeContentType(engine.MediaTypeJSON))
+	}
+}
+
+func (p *Processes) getJob(w http.ResponseWriter, r *http.Request) (*job, bool) {
+	jobID := chi.URLParam(r, "jobId")
+	j, err := p.executor.store.get(r.Context(), jobID)
+	if err != nil {
+		engine.RenderProblemAndLog(engine.ProblemServerError, w, err)
+
+		return nil, false
+	}
+	if j == nil {
+		engine.RenderProblem(engine.ProblemNotFound, w, "unknown job "+jobID)
+
+		return nil, false
+	}
+
+	return j, true
+}
+
+// serveResults serves the results of the given job as a document (outputs by output ID)
+// or - when requested and the process has a single output - the raw output value.
+func (p *Processes) serveResults(w http.ResponseWriter, r *http.Request, j job, raw bool) {
+	switch j.Status {
+	case statusSuccessful:
+		// handled below
+	case statusFailed:
+		engine.RenderProblem(engine.ProblemServerError, w, "job "+j.ID+" failed: "+j.Message)
+
+		return
+	default:
+		engine.RenderProblem(engine.ProblemNotFound, w, "results of job "+j.ID+" not ready yet, job is "+string(j.Status))
+
+		return
+	}
+	output := j.Results
+	if raw {
+		var outputs map[string]json.RawMessage
+		if err := json.Unmarshal(j.Results, &outputs); err != nil {
+			engine.RenderProblemAndLog(engine.ProblemServerError, w, err)
+
+			return
+		}
+		if len(outputs) == 1 {
+			for _, value := range outputs {
+				output = value
+			}
+		}
+	}
+	p.engine.Serve(w, r,
+		engine.ServeValidation(false /* body already consumed */, true),
+		engine.ServePreRenderedOutput(output),
+		engine.ServeContentType(engine.MediaTypeJSON))
+}
+
+func (p *Processes) processDocument(proc process, full bool) processDocument {
+	desc := proc.description()
+	jobControl := []string{jobControlSync, jobControlAsync}
+	if p.engine.Config.OgcAPI.Processes.SupportsDismiss {
+		jobControl = append(jobControl, jobControlDismiss)
+	}
+	doc := processDocument{
+		ID:                 desc.ID,
+		Title:              desc.Title,
+		Description:        desc.Description,
+		Version:            desc.Version,
+		Keywords:           desc.Keywords,
+		JobControlOptions:  jobControl,
+		OutputTransmission: []string{transmissionValue},
+		Links: []link{
+			p.link("self", "Description of process "+desc.ID, processesPath+"/"+desc.ID),
+			p.link(relExecute, "Execute process "+desc.ID, processesPath+"/"+desc.ID+"/execution"),
+		},
+	}
+	if full {
+		doc.Inputs = desc.Inputs
+		doc.Outputs = desc.Outputs
+	}
+
+	return doc
+}
+
+func (p *Processes) statusInfo(j job) statusInfo {
+	info := statusInfo{
+		ProcessID: j.ProcessID,
+		Type:      "process",
+		JobID:     j.ID,
+		Status:    j.Status,
+		Message:   j.Message,
+		Created:   j.Created,
+		Started:   j.Started,
+		Finished:  j.Finished,
+		Updated:   j.Updated,
+		Progress:  j.Progress,
+		Links:     []link{p.link("self", "Status of job "+j.ID, jobsPath+"/"+j.ID)},
+	}
+	if j.Status == statusSuccessful {
+		info.Links = append(info.Links, p.link(relResults, "Results of job "+j.ID, jobsPath+"/"+j.ID+"/results"))
+	}
+
+	return info
+}
+
+func (p *Processes) link(rel, title, path string) link {
+	return link{Rel: rel, Type: engine.MediaTypeJSON, Title: title, Href: p.engine.Config.BaseURL.String() + path}
+}
+
+// readExecuteRequest reads and validates the execute request in the request body. Renders a problem
+// and returns false when the request can't be processed any further.
+func readExecuteRequest(w http.ResponseWriter, r *http.Request) (*executeRequest, bool) {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get(engine.HeaderContentType))
+	if err != nil || mediaType != engine.MediaTypeJSON {
+		engine.RenderProblem(engine.ProblemUnsupportedMediaType, w, "execute request should be "+engine.MediaTypeJSON)
+
+		return nil, false
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, maxExecuteRequestSize)
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		engine.RenderProblem(engine.ProblemBadRequest, w, "failed to read execute request: "+err.Error())
+
+		return nil, false
+	}
+	var req executeRequest
+	if err = json.Unmarshal(body, &req); err != nil {
+		engine.RenderProblem(engine.ProblemBadRequest, w, "invalid execute request: "+err.Error())
+
+		return nil, false
+	}
+	if req.Response != "" && req.Response != responseRaw && req.Response != responseDocument {
+		engine.RenderProblem(engine.ProblemBadRequest, w, "invalid value for response, expected one of: raw, document")
+
+		return nil, false
+	}
+	if req.Subscriber != nil {
+		for _, uri := range []string{req.Subscriber.SuccessURI, req.Subscriber.InProgressURI, req.Subscriber.FailedURI} {
+			if uri == "" {
+				continue
+			}
+			u, err := url.ParseRequestURI(uri)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+				engine.RenderProblem(engine.ProblemBadRequest, w, "invalid subscriber URI "+uri)
+
+				return nil, false
+			}
+			if err = checkCallbackHost(r.Context(), u.Hostname()); err != nil {
+				engine.RenderProblem(engine.ProblemBadRequest, w, "invalid subscriber URI "+uri+": "+err.Error())
+
+				return nil, false
+			}
+		}
+	}
+
+	return &req, true
+}
+
+// isRespondAsyncPreferred whether the client prefers async execution, see RFC 7240.
+func isRespondAsyncPreferred(r *http.Request) bool {
+	for _, header := range r.Header.Values(headerPrefer) {
+		for _, preference := range strings.Split(header, ",") {
+			if strings.EqualFold(strings.TrimSpace(preference), preferRespondAsync) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func writeJSON(w http.ResponseWriter, statusCode int, value any) {
+	body, err := json.Marshal(value)
+	if err != nil {
+		engine.RenderProblemAndLog(engine.ProblemServerError, w, err)
+
+		return
+	}
+	w.Header().Set(engine.HeaderContentType, engine.MediaTypeJSON)
+	w.WriteHeader(statusCode)
+	engine.SafeWrite(w.Write, body)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		for _, part := range strings.Split(v, ",") {
+			if part == value {
+				return true
+			}
+		}
+	}
+
+	return false
+}
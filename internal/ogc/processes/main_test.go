@@ -0,0 +1,422 @@
+package processes
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"os"
+	"path"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/PDOK/gokoala/config"
+	"github.com/PDOK/gokoala/internal/engine"
+	ds "github.com/PDOK/gokoala/internal/ogc/features/datasources"
+	"github.com/PDOK/gokoala/internal/ogc/features/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/twpayne/go-geom"
+	"github.com/twpayne/go-geom/encoding/geojson"
+)
+
+func init() {
+	// change working dir to root, to mimic behavior of 'go run' in order to resolve template files.
+	_, filename, _, _ := runtime.Caller(0)
+	dir := path.Join(path.Dir(filename), "../../../")
+	err := os.Chdir(dir)
+	if err != nil {
+		panic(err)
+	}
+}
+
+// fakeDatasource serves a fixed set of point features, regardless of the criteria.
+type fakeDatasource struct {
+	ds.Datasource
+
+	points [][2]float64
+}
+
+func (f *fakeDatasource) GetFeatures(_ context.Context, _ string, _ ds.FeaturesCriteria,
+	_ domain.Profile) (*domain.FeatureCollection, domain.Cursors, error) {
+
+	fc := &domain.FeatureCollection{}
+	for i, p := range f.points {
+		geometry, err := geojson.Encode(geom.NewPointFlat(geom.XY, []float64{p[0], p[1]}))
+		if err != nil {
+			return nil, domain.Cursors{}, err
+		}
+		fc.Features = append(fc.Features, &domain.Feature{ID: string(rune('1' + i)), Geometry: geometry})
+	}
+	fc.NumberReturned = len(fc.Features)
+
+	return fc, domain.Cursors{}, nil
+}
+
+func (f *fakeDatasource) GetSchema(_ string) (*domain.Schema, domain.Queryables, error) {
+	return &domain.Schema{}, nil, nil
+}
+
+type fakeDatasources map[string]ds.Datasource
+
+func (f fakeDatasources) GetDatasource(collectionID string) ds.Datasource {
+	return f[collectionID]
+}
+
+func newTestProcesses(t *testing.T) *engine.Engine {
+	t.Helper()
+	newEngine, err := engine.NewEngine("internal/ogc/processes/testdata/config_processes_builtin.yaml",
+		"internal/engine/testdata/test_theme.yaml", "", false, true)
+	require.NoError(t, err)
+	datasource := &fakeDatasource{points: [][2]float64{{5.0, 52.0}, {5.001, 52.0}, {5.1, 52.1}}}
+	processes := NewProcesses(newEngine, fakeDatasources{"ligplaatsen": datasource})
+	t.Cleanup(processes.executor.shutdown)
+
+	return newEngine
+}
+
+func doRequest(t *testing.T, e *engine.Engine, method, url, body string, headers map[string]string) *httptest.ResponseRecorder {
+	t.Helper()
+	var reader io.Reader
+	if body != "" {
+		reader = strings.NewReader(body)
+	}
+	req, err := http.NewRequest(method, url, reader)
+	require.NoError(t, err)
+	if body != "" {
+		req.Header.Set(engine.HeaderContentType, engine.MediaTypeJSON)
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	rr := httptest.NewRecorder()
+	e.Router.ServeHTTP(rr, req)
+
+	return rr
+}
+
+func TestProcesses_ProcessList(t *testing.T) {
+	e := newTestProcesses(t)
+
+	rr := doRequest(t, e, http.MethodGet, "http://localhost:8080/processes", "", nil)
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	var list processList
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &list))
+	require.Len(t, list.Processes, 2)
+	assert.Equal(t, "count-features", list.Processes[0].ID)
+	assert.Equal(t, "intersect-features", list.Processes[1].ID)
+	assert.Equal(t, []string{jobControlSync, jobControlAsync, jobControlDismiss}, list.Processes[0].JobControlOptions)
+	assert.Empty(t, list.Processes[0].Inputs)
+	assert.Equal(t, "http://localhost:8080/processes/count-features/execution", list.Processes[0].Links[1].Href)
+}
+
+func TestProcesses_ProcessDescription(t *testing.T) {
+	e := newTestProcesses(t)
+
+	rr := doRequest(t, e, http.MethodGet, "http://localhost:8080/processes/intersect-features", "", nil)
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), `"enum":["ligplaatsen"]`)
+	assert.Contains(t, rr.Body.String(), `"distance"`)
+
+	rr = doRequest(t, e, http.MethodGet, "http://localhost:8080/processes/unknown", "", nil)
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
+
+func TestProcesses_ExecuteSync(t *testing.T) {
+	tests := []struct {
+		name           string
+		processID      string
+		body           string
+		contentType    string
+		wantStatusCode int
+		wantBody       string
+	}{
+		{
+			name:      "count features in polygon",
+			processID: "count-features",
+			body: `{"inputs": {"collection": "ligplaatsen", "geometry": {"type": "Polygon",
+				"coordinates": [[[4.99, 51.99], [5.01, 51.99], [5.01, 52.01], [4.99, 52.01], [4.99, 51.99]]]}}}`,
+			wantStatusCode: http.StatusOK,
+			wantBody:       `2`,
+		},
+		{
+			name:      "count features in polygon as document",
+			processID: "count-features",
+			body: `{"response": "document", "inputs": {"collection": "ligplaatsen", "geometry": {"type": "Polygon",
+				"coordinates": [[[4.99, 51.99], [5.0005, 51.99], [5.0005, 52.01], [4.99, 52.01], [4.99, 51.99]]]}}}`,
+			wantStatusCode: http.StatusOK,
+			wantBody:       `{"count":1}`,
+		},
+		{
+			name:           "intersect features with buffered point",
+			processID:      "intersect-features",
+			body:           `{"inputs": {"collection": "ligplaatsen", "geometry": {"type": "Point", "coordinates": [5.0, 52.0]}, "distance": 100}}`,
+			wantStatusCode: http.StatusOK,
+			wantBody:       `"numberReturned":2`,
+		},
+		{
+			name:           "intersect features with smaller buffer",
+			processID:      "intersect-features",
+			body:           `{"inputs": {"collection": "ligplaatsen", "geometry": {"type": "Point", "coordinates": [5.0, 52.0]}, "distance": 10}}`,
+			wantStatusCode: http.StatusOK,
+			wantBody:       `"numberReturned":1`,
+		},
+		{
+			name:           "point is not a polygon",
+			processID:      "count-features",
+			body:           `{"inputs": {"collection": "ligplaatsen", "geometry": {"type": "Point", "coordinates": [5.0, 52.0]}}}`,
+			wantStatusCode: http.StatusBadRequest,
+			wantBody:       `should be a GeoJSON geometry of type [Polygon MultiPolygon]`,
+		},
+		{
+			name:           "unknown collection",
+			processID:      "count-features",
+			body:           `{"inputs": {"collection": "foo", "geometry": {"type": "Point", "coordinates": [5.0, 52.0]}}}`,
+			wantStatusCode: http.StatusBadRequest,
+			wantBody:       `should be the ID of one of the feature collections`,
+		},
+		{
+			name:           "unknown input",
+			processID:      "count-features",
+			body:           `{"inputs": {"foo": "bar"}}`,
+			wantStatusCode: http.StatusBadRequest,
+			wantBody:       `unknown input 'foo'`,
+		},
+		{
+			name:      "subscriber on internal address",
+			processID: "count-features",
+			body: `{"inputs": {"collection": "ligplaatsen", "geometry": {"type": "Point", "coordinates": [5.0, 52.0]}},
+				"subscriber": {"successUri": "http://169.254.169.254/latest/meta-data"}}`,
+			wantStatusCode: http.StatusBadRequest,
+			wantBody:       `callbacks to internal addresses aren't allowed`,
+		},
+		{
+			name:           "unsupported content type",
+			processID:      "count-features",
+			body:           `{"inputs": {}}`,
+			contentType:    "text/plain",
+			wantStatusCode: http.StatusUnsupportedMediaType,
+		},
+		{
+			name:           "unknown process",
+			processID:      "foo",
+			body:           `{"inputs": {}}`,
+			wantStatusCode: http.StatusNotFound,
+		},
+	}
+	e := newTestProcesses(t)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			headers := map[string]string{}
+			if tt.contentType != "" {
+				headers[engine.HeaderContentType] = tt.contentType
+			}
+			rr := doRequest(t, e, http.MethodPost, "http://localhost:8080/processes/"+tt.processID+"/execution", tt.body, headers)
+
+			assert.Equal(t, tt.wantStatusCode, rr.Code, rr.Body.String())
+			assert.Contains(t, rr.Body.String(), tt.wantBody)
+		})
+	}
+}
+
+func TestProcesses_ExecuteAsync(t *testing.T) {
+	// subscriber runs on localhost
+	isCallbackAllowed = func(_ netip.Addr) bool { return true }
+	defer func() { isCallbackAllowed = isPublicAddress }()
+
+	callbacks := make(chan string, 10)
+	subscriber := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		callbacks <- r.URL.Path + " " + string(body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer subscriber.Close()
+	e := newTestProcesses(t)
+
+	body := `{"inputs": {"collection": "ligplaatsen", "geometry": {"type": "Polygon",
+		"coordinates": [[[4.99, 51.99], [5.01, 51.99], [5.01, 52.01], [4.99, 52.01], [4.99, 51.99]]]}},
+		"subscriber": {"successUri": "` + subscriber.URL + `/success"}}`
+	rr := doRequest(t, e, http.MethodPost, "http://localhost:8080/processes/count-features/execution", body,
+		map[string]string{headerPrefer: preferRespondAsync})
+	require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	assert.Equal(t, preferRespondAsync, rr.Header().Get(headerPreferenceApplied))
+
+	var status statusInfo
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &status))
+	assert.Equal(t, "http://localhost:8080/jobs/"+status.JobID, rr.Header().Get("Location"))
+
+	require.Eventually(t, func() bool {
+		rr = doRequest(t, e, http.MethodGet, "http://localhost:8080/jobs/"+status.JobID, "", nil)
+		require.Equal(t, http.StatusOK, rr.Code)
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &status))
+		return status.Status == statusSuccessful
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, 100, status.Progress)
+
+	rr = doRequest(t, e, http.MethodGet, "http://localhost:8080/jobs/"+status.JobID+"/results", "", nil)
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, `{"count":2}`, rr.Body.String())
+
+	select {
+	case callback := <-callbacks:
+		assert.Equal(t, `/success {"count":2}`, callback)
+	case <-time.After(5 * time.Second):
+		assert.Fail(t, "subscriber not notified")
+	}
+
+	rr = doRequest(t, e, http.MethodGet, "http://localhost:8080/jobs?processID=count-features&status=successful", "", nil)
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), status.JobID)
+
+	rr = doRequest(t, e, http.MethodGet, "http://localhost:8080/jobs?processID=intersect-features", "", nil)
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.NotContains(t, rr.Body.String(), status.JobID)
+
+	rr = doRequest(t, e, http.MethodDelete, "http://localhost:8080/jobs/"+status.JobID, "", nil)
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), `"status":"dismissed"`)
+
+	rr = doRequest(t, e, http.MethodGet, "http://localhost:8080/jobs/"+status.JobID, "", nil)
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
+
+func TestExecutor_CallbackToInternalAddress(t *testing.T) {
+	var called atomic.Bool
+	subscriber := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		called.Store(true)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer subscriber.Close()
+	redirect := httptest.NewServer(http.RedirectHandler(subscriber.URL, http.StatusTemporaryRedirect))
+	defer redirect.Close()
+	x := newExecutor(newMemoryJobStore(), testJobsConfig(1, 0, time.Minute), true, nil)
+	defer x.shutdown()
+
+	err := x.post(subscriber.URL, []byte(`{}`))
+	require.ErrorIs(t, err, errCallbackAddress)
+
+	// only allow the first connection (to the redirect), the redirect itself should still be refused
+	var dials atomic.Int32
+	isCallbackAllowed = func(_ netip.Addr) bool { return dials.Add(1) == 1 }
+	defer func() { isCallbackAllowed = isPublicAddress }()
+	err = x.post(redirect.URL, []byte(`{}`))
+	require.ErrorIs(t, err, errCallbackAddress)
+	assert.False(t, called.Load())
+}
+
+func TestProcesses_ExecuteQueueFull(t *testing.T) {
+	e, err := engine.NewEngine("internal/ogc/processes/testdata/config_processes_builtin.yaml",
+		"internal/engine/testdata/test_theme.yaml", "", false, true)
+	require.NoError(t, err)
+	processes := NewProcesses(e, fakeDatasources{"ligplaatsen": &fakeDatasource{}})
+	t.Cleanup(processes.executor.shutdown)
+
+	// occupy the whole queue
+	for range cap(processes.executor.queue) {
+		processes.executor.queue <- struct{}{}
+	}
+	rr := doRequest(t, e, http.MethodPost, "http://localhost:8080/processes/count-features/execution",
+		`{"inputs": {"collection": "ligplaatsen", "geometry": {"type": "Polygon",
+			"coordinates": [[[4.99, 51.99], [5.01, 51.99], [5.01, 52.01], [4.99, 52.01], [4.99, 51.99]]]}}}`, nil)
+	assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+	assert.Equal(t, "30", rr.Header().Get("Retry-After"))
+	assert.Contains(t, rr.Body.String(), "too many jobs are waiting for execution")
+
+	for range cap(processes.executor.queue) {
+		<-processes.executor.queue
+	}
+}
+
+func TestExecutor_QueueFull(t *testing.T) {
+	x := newExecutor(newMemoryJobStore(), testJobsConfig(1, 1, time.Minute), false, nil)
+	defer x.shutdown()
+	ctx := context.Background()
+	block := make(chan struct{})
+	blocking := func(_ context.Context) (map[string]any, error) {
+		<-block
+
+		return nil, nil
+	}
+
+	running, err := x.submit(ctx, "test", blocking, nil, true)
+	require.NoError(t, err)
+	queued, err := x.submit(ctx, "test", blocking, nil, true)
+	require.NoError(t, err)
+
+	// one job running and one waiting for a slot, so the queue is full
+	_, err = x.submit(ctx, "test", blocking, nil, true)
+	require.ErrorIs(t, err, errQueueFull)
+	_, err = x.submit(ctx, "test", blocking, nil, false)
+	require.ErrorIs(t, err, errQueueFull)
+
+	// finished jobs make room in the queue again
+	close(block)
+	for _, id := range []string{running.ID, queued.ID} {
+		require.Eventually(t, func() bool {
+			j, err := x.store.get(ctx, id)
+			return err == nil && j.Status == statusSuccessful
+		}, 5*time.Second, 10*time.Millisecond)
+	}
+	require.Eventually(t, func() bool { return len(x.queue) == 0 }, 5*time.Second, 10*time.Millisecond)
+	j, err := x.submit(ctx, "test", blocking, nil, false)
+	require.NoError(t, err)
+	assert.Equal(t, statusSuccessful, j.Status)
+}
+
+func TestExecutor_Timeout(t *testing.T) {
+	x := newExecutor(newMemoryJobStore(), testJobsConfig(1, 0, 50*time.Millisecond), false, nil)
+	defer x.shutdown()
+	slow := func(ctx context.Context) (map[string]any, error) {
+		<-ctx.Done()
+
+		return nil, ctx.Err()
+	}
+
+	j, err := x.submit(context.Background(), "test", slow, nil, false)
+	require.NoError(t, err)
+	assert.Equal(t, statusFailed, j.Status)
+	assert.Equal(t, "job timed out", j.Message)
+}
+
+func testJobsConfig(maxConcurrent, maxQueued int, timeout time.Duration) config.ProcessesJobs {
+	return config.ProcessesJobs{
+		Store:         jobStoreMemory,
+		MaxConcurrent: maxConcurrent,
+		MaxQueued:     maxQueued,
+		Timeout:       config.Duration{Duration: timeout},
+		Retention:     config.Duration{Duration: time.Hour},
+	}
+}
+
+func TestIsPublicAddress(t *testing.T) {
+	tests := []struct {
+		addr string
+		want bool
+	}{
+		{addr: "145.12.34.56", want: true},
+		{addr: "2001:db8::1", want: true},
+		{addr: "127.0.0.1", want: false},
+		{addr: "::1", want: false},
+		{addr: "10.1.2.3", want: false},
+		{addr: "172.16.0.1", want: false},
+		{addr: "192.168.1.1", want: false},
+		{addr: "169.254.169.254", want: false},
+		{addr: "fe80::1", want: false},
+		{addr: "fd00::1", want: false},
+		{addr: "0.0.0.0", want: false},
+		{addr: "224.0.0.1", want: false},
+		{addr: "::ffff:127.0.0.1", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.addr, func(t *testing.T) {
+			assert.Equal(t, tt.want, isPublicAddress(netip.MustParseAddr(tt.addr)))
+		})
+	}
+}
@@ -0,0 +1,111 @@
+package processes
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/PDOK/gokoala/config"
+	"github.com/PDOK/gokoala/internal/engine"
+	"github.com/PDOK/gokoala/internal/ogc/common/core"
+)
+
+// upstreamProcess description of a process offered by an external processes server, only the parts of interest.
+type upstreamProcess struct {
+	ID          string          `json:"id"`
+	Title       string          `json:"title"`
+	Description string          `json:"description"`
+	Inputs      json.RawMessage `json:"inputs"`
+}
+
+func (p *Processes) forwarder(processServer config.URL) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		targetURL := *processServer.URL
+		targetURL.Path = processServer.Path + r.URL.Path
+		targetURL.RawQuery = r.URL.RawQuery
+		p.engine.ReverseProxy(w, r, &targetURL, false, "")
+	}
+}
+
+// fetchProcesses retrieves the descriptions of the processes offered by the external processes server.
+func (p *Processes) fetchProcesses(cfg *config.OgcAPIProcesses) ([]core.Process, error) {
+	var list struct {
+		Processes []upstreamProcess `json:"processes"`
+	}
+	if err := p.fetch(cfg, processesPath, &list); err != nil {
+		return nil, err
+	}
+	result := make([]core.Process, 0, len(list.Processes))
+	for _, proc := range list.Processes {
+		// the list contains summaries, the inputs are only part of the full description
+		var description upstreamProcess
+		if err := p.fetch(cfg, processesPath+"/"+url.PathEscape(proc.ID), &description); err != nil {
+			log.Printf("WARNING: failed to retrieve description of process %s, "+
+				"skipping references to collections: %v", proc.ID, err)
+			description = proc
+		}
+		result = append(result, core.Process{
+			ID:          proc.ID,
+			Title:       proc.Title,
+			Description: proc.Description,
+			Collections: p.referencedCollections(description.Inputs),
+		})
+	}
+
+	return result, nil
+}
+
+func (p *Processes) fetch(cfg *config.OgcAPIProcesses, path string, result any) error {
+	target := *cfg.ProcessesServer.URL
+	target.Path = cfg.ProcessesServer.Path + path
+	body, err := engine.Fetch(target, engine.MediaTypeJSON, cfg.ProcessesServerRetries)
+	if err != nil {
+		return err
+	}
+	if err = json.Unmarshal(body, result); err != nil {
+		return fmt.Errorf("invalid response from %s: %w", target.String(), err)
+	}
+
+	return nil
+}
+
+// referencedCollections returns the IDs of the collections of this API referred to by the given (JSON)
+// process inputs, either by collection ID (e.g. as allowed or default value) or by collection URL.
+func (p *Processes) referencedCollections(inputsJSON []byte) []string {
+	var inputs any
+	if err := json.Unmarshal(inputsJSON, &inputs); err != nil {
+		return nil
+	}
+	collections := p.engine.Config.AllCollections()
+	collectionsURL := p.engine.Config.BaseURL.String() + "/collections/"
+	var result []string
+	var walk func(value any)
+	walk = func(value any) {
+		switch v := value.(type) {
+		case map[string]any:
+			for _, child := range v {
+				walk(child)
+			}
+		case []any:
+			for _, child := range v {
+				walk(child)
+			}
+		case string:
+			id := v
+			if strings.HasPrefix(v, collectionsURL) {
+				id = strings.Split(strings.TrimPrefix(v, collectionsURL), "/")[0]
+			}
+			if collections.ContainsID(id) && !slices.Contains(result, id) {
+				result = append(result, id)
+			}
+		}
+	}
+	walk(inputs)
+	slices.Sort(result)
+
+	return result
+}
@@ -0,0 +1,74 @@
+package processes
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/PDOK/gokoala/config"
+	"github.com/PDOK/gokoala/internal/engine"
+	"github.com/PDOK/gokoala/internal/ogc/common/core"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProcesses_Proxy(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(engine.HeaderContentType, engine.MediaTypeJSON)
+		switch r.URL.Path {
+		case "/ogcapi/processes":
+			_, _ = w.Write([]byte(`{"processes": [
+				{"id": "buffer", "title": "Buffer", "description": "Buffers features"},
+				{"id": "echo", "title": "Echo", "description": "Echoes input"}
+			]}`))
+		case "/ogcapi/processes/buffer":
+			_, _ = w.Write([]byte(`{"id": "buffer", "title": "Buffer", "inputs": {
+				"features": {"schema": {"type": "string", "format": "uri",
+					"default": "http://localhost:8080/collections/ligplaatsen/items"}},
+				"layer": {"schema": {"type": "string", "enum": ["ligplaatsen", "foo"]}},
+				"distance": {"schema": {"type": "number"}}
+			}}`))
+		case "/ogcapi/processes/echo":
+			_, _ = w.Write([]byte(`{"id": "echo", "title": "Echo", "inputs": {"text": {"schema": {"type": "string"}}}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer upstream.Close()
+
+	cfg, err := config.NewConfig("internal/ogc/processes/testdata/config_processes_builtin.yaml")
+	require.NoError(t, err)
+	processesServer, err := url.Parse(upstream.URL + "/ogcapi")
+	require.NoError(t, err)
+	cfg.OgcAPI.Processes.ProcessesServer = config.URL{URL: processesServer}
+	cfg.OgcAPI.Processes.ProcessesServerRetries = 1
+	theme, err := config.NewTheme("internal/engine/testdata/test_theme.yaml")
+	require.NoError(t, err)
+	newEngine := engine.NewEngineWithConfig(cfg, theme, "", false, true)
+
+	processes := NewProcesses(newEngine, nil)
+
+	assert.Equal(t, []core.Process{
+		{ID: "buffer", Title: "Buffer", Description: "Buffers features", Collections: []string{"ligplaatsen"}},
+		{ID: "echo", Title: "Echo", Description: "Echoes input"},
+	}, processes.GetProcesses())
+
+	rr := doRequest(t, newEngine, http.MethodGet, "http://localhost:8080/processes/echo", "", nil)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), `"title": "Echo"`)
+
+	// OpenAPI spec is served by GoKoala itself, not by the processes server
+	rr = doRequest(t, newEngine, http.MethodGet, "http://localhost:8080/api", "", nil)
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
+
+func TestProcesses_ReferencedCollections(t *testing.T) {
+	e := newTestProcesses(t)
+	p := &Processes{engine: e}
+
+	assert.Equal(t, []string{"ligplaatsen"}, p.referencedCollections([]byte(`{"a": {"schema": {"enum": ["ligplaatsen"]}}}`)))
+	assert.Equal(t, []string{"ligplaatsen"}, p.referencedCollections([]byte(`["http://localhost:8080/collections/ligplaatsen"]`)))
+	assert.Empty(t, p.referencedCollections([]byte(`{"a": "ligplaatsen/items", "b": "http://example.com/collections/foo"}`)))
+	assert.Empty(t, p.referencedCollections([]byte(`invalid`)))
+}
@@ -0,0 +1,96 @@
+package processes
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"strings"
+)
+
+const (
+	jobControlSync    = "sync-execute"
+	jobControlAsync   = "async-execute"
+	jobControlDismiss = "dismiss"
+
+	transmissionValue = "value"
+)
+
+// process a built-in process, executable through OGC API Processes.
+type process interface {
+	// description of the process, its inputs and outputs
+	description() processDescription
+
+	// prepare validates the given inputs (by input ID) and returns the execution of the process with these
+	// inputs. Returns an inputError when the inputs are invalid.
+	prepare(inputs map[string]json.RawMessage) (execution, error)
+}
+
+// execution of a process with certain inputs, returns the outputs by output ID.
+type execution func(ctx context.Context) (map[string]any, error)
+
+// inputError invalid or missing inputs provided by the client.
+type inputError struct {
+	message string
+}
+
+func (e inputError) Error() string {
+	return e.message
+}
+
+// processDescription see https://docs.ogc.org/is/18-062r2/18-062r2.html#toc37
+type processDescription struct {
+	ID          string                       `json:"id"`
+	Title       string                       `json:"title"`
+	Description string                       `json:"description"`
+	Version     string                       `json:"version"`
+	Keywords    []string                     `json:"keywords,omitempty"`
+	Inputs      map[string]inputDescription  `json:"inputs"`
+	Outputs     map[string]outputDescription `json:"outputs"`
+}
+
+type inputDescription struct {
+	Title       string         `json:"title"`
+	Description string         `json:"description"`
+	MinOccurs   int            `json:"minOccurs"`
+	Schema      map[string]any `json:"schema"`
+}
+
+type outputDescription struct {
+	Title       string         `json:"title"`
+	Description string         `json:"description"`
+	Schema      map[string]any `json:"schema"`
+}
+
+// registry of built-in processes, by process ID.
+type registry struct {
+	processes map[string]process
+}
+
+func newRegistry(processes ...process) *registry {
+	r := &registry{processes: make(map[string]process, len(processes))}
+	for _, p := range processes {
+		r.processes[p.description().ID] = p
+	}
+
+	return r
+}
+
+// get returns the process with the given ID, false when no such process exists.
+func (r *registry) get(processID string) (process, bool) {
+	p, ok := r.processes[processID]
+
+	return p, ok
+}
+
+// all returns all processes, ordered by ID.
+func (r *registry) all() []process {
+	result := make([]process, 0, len(r.processes))
+	for _, p := range r.processes {
+		result = append(result, p)
+	}
+	slices.SortFunc(result, func(a, b process) int {
+		return strings.Compare(a.description().ID, b.description().ID)
+	})
+
+	return result
+}
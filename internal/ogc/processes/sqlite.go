@@ -0,0 +1,124 @@
+package processes
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	_ "github.com/mattn/go-sqlite3" // import for side effect (= sqlite3 driver) only
+)
+
+const createJobsTable = `
+create table if not exists jobs (
+	id         text primary key,
+	process_id text not null,
+	status     text not null,
+	message    text not null default '',
+	progress   integer not null default 0,
+	created    datetime not null,
+	started    datetime,
+	finished   datetime,
+	updated    datetime not null,
+	results    blob
+);
+create index if not exists jobs_created on jobs (created);`
+
+// sqliteJobStore keeps jobs in a SQLite database on local disk, jobs survive restarts.
+type sqliteJobStore struct {
+	db *sqlx.DB
+}
+
+func newSQLiteJobStore(file string) (*sqliteJobStore, error) {
+	db, err := sqlx.Open("sqlite3", fmt.Sprintf("file:%s?_journal_mode=WAL&_busy_timeout=5000", file))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open job store %s: %w", file, err)
+	}
+	db.SetMaxOpenConns(1) // single writer, prevents 'database is locked' errors
+	if _, err = db.Exec(createJobsTable); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("failed to create jobs table in job store %s: %w", file, err)
+	}
+
+	// jobs that didn't finish before the previous shutdown will never finish
+	interruptedAt := now()
+	result, err := db.Exec("update jobs set status = ?, message = ?, finished = ?, updated = ? where status in (?, ?)",
+		statusFailed, "job interrupted by restart", interruptedAt, interruptedAt, statusAccepted, statusRunning)
+	if err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("failed to update interrupted jobs in job store %s: %w", file, err)
+	}
+	if interrupted, _ := result.RowsAffected(); interrupted > 0 {
+		log.Printf("marked %d interrupted job(s) as failed in job store %s", interrupted, file)
+	}
+	log.Printf("opened job store: %s", file)
+
+	return &sqliteJobStore{db}, nil
+}
+
+func (s *sqliteJobStore) save(ctx context.Context, j job) error {
+	_, err := s.db.NamedExecContext(ctx, `
+		insert into jobs (id, process_id, status, message, progress, created, started, finished, updated, results)
+		values (:id, :process_id, :status, :message, :progress, :created, :started, :finished, :updated, :results)
+		on conflict (id) do update set
+			status = excluded.status, message = excluded.message, progress = excluded.progress,
+			started = excluded.started, finished = excluded.finished, updated = excluded.updated,
+			results = excluded.results`, j)
+	if err != nil {
+		return fmt.Errorf("failed to save job %s: %w", j.ID, err)
+	}
+
+	return nil
+}
+
+func (s *sqliteJobStore) get(ctx context.Context, id string) (*job, error) {
+	var j job
+	err := s.db.GetContext(ctx, &j, "select * from jobs where id = ?", id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil //nolint:nilnil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get job %s: %w", id, err)
+	}
+
+	return &j, nil
+}
+
+func (s *sqliteJobStore) list(ctx context.Context) ([]job, error) {
+	// results aren't needed in the list of jobs, skip these since they can be large
+	var jobs []job
+	err := s.db.SelectContext(ctx, &jobs, `
+		select id, process_id, status, message, progress, created, started, finished, updated
+		from jobs order by created desc`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list jobs: %w", err)
+	}
+
+	return jobs, nil
+}
+
+func (s *sqliteJobStore) delete(ctx context.Context, id string) error {
+	if _, err := s.db.ExecContext(ctx, "delete from jobs where id = ?", id); err != nil {
+		return fmt.Errorf("failed to delete job %s: %w", id, err)
+	}
+
+	return nil
+}
+
+func (s *sqliteJobStore) deleteFinishedBefore(ctx context.Context, moment time.Time) error {
+	if _, err := s.db.ExecContext(ctx, "delete from jobs where finished < ?", moment); err != nil {
+		return fmt.Errorf("failed to delete finished jobs: %w", err)
+	}
+
+	return nil
+}
+
+func (s *sqliteJobStore) ping(ctx context.Context) error {
+	return s.db.PingContext(ctx)
+}
+
+func (s *sqliteJobStore) close() error {
+	return s.db.Close()
+}
@@ -15,27 +15,33 @@ import (
 
 func SetupBuildingBlocks(engine *engine.Engine, rewritesFile, synonymsFile string) error {
 	// OGC 3D GeoVolumes API
+	var geoVolumes geospatial.GeoVolumes
 	if engine.Config.OgcAPI.GeoVolumes != nil {
-		geovolumes.NewThreeDimensionalGeoVolumes(engine)
-	}
-	// OGC Tiles API
-	if engine.Config.OgcAPI.Tiles != nil {
-		tiles.NewTiles(engine)
+		gv := geovolumes.NewThreeDimensionalGeoVolumes(engine)
+		geoVolumes = gv.GetGeoVolumes()
 	}
 	// OGC Styles API
+	var supportedStyles tiles.SupportedStyles
 	if engine.Config.OgcAPI.Styles != nil {
-		styles.NewStyles(engine)
+		supportedStyles = styles.NewStyles(engine)
+	}
+	// OGC Tiles API
+	if engine.Config.OgcAPI.Tiles != nil {
+		tiles.NewTiles(engine, supportedStyles)
 	}
 	// OGC Features API
 	collectionTypes := geospatial.NewCollectionTypes(nil, nil)
+	var featureDatasources processes.FeatureDatasources
 	if engine.Config.OgcAPI.Features != nil {
 		f := features.NewFeatures(engine)
 		collectionTypes = f.GetCollectionTypes()
+		featureDatasources = f
 	}
 	// Features Search API, build on top of the OGC Features API
 	if engine.Config.OgcAPI.FeaturesSearch != nil {
 		fs := engine.Config.OgcAPI.FeaturesSearch
 		ds := features.CreateDatasources(config.NewSearchConfig(fs), engine.RegisterShutdownHook)
+		features.RegisterReadinessChecks(engine, "search", ds)
 		projInfoMap := features.GetProjJSONBySRID(ds)
 		_, err := features_search.NewSearch(engine, ds, projInfoMap, rewritesFile, synonymsFile, fs.SearchSettings.MaxSynonyms)
 		if err != nil {
@@ -43,15 +49,17 @@ func SetupBuildingBlocks(engine *engine.Engine, rewritesFile, synonymsFile strin
 		}
 	}
 	// OGC Processes API
+	var landingPage core.LandingPage
 	if engine.Config.OgcAPI.Processes != nil {
-		processes.NewProcesses(engine)
+		p := processes.NewProcesses(engine, featureDatasources)
+		landingPage.Processes = p.GetProcesses()
 	}
 
 	// OGC Common Part 1, this will always be started
-	core.NewCommonCore(engine, core.ExtraConformanceClasses{})
+	core.NewCommonCore(engine, core.ExtraConformanceClasses{}, landingPage)
 	// OGC Common part 2
 	if engine.Config.HasCollections() {
-		geospatial.NewCollections(engine, collectionTypes)
+		geospatial.NewCollections(engine, collectionTypes, geoVolumes)
 	}
 	return nil
 }
@@ -0,0 +1,897 @@
+package styles
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/PDOK/gokoala/config"
+	"github.com/PDOK/gokoala/internal/engine"
+)
+
+const (
+	ogcNamespace = "http://www.opengis.net/ogc"
+
+	mapboxSourceDataset = "dataset"
+)
+
+// mapboxToSLDFilterOperators comparison operators supported in both Mapbox filters and SLD (OGC filter encoding)
+var mapboxToSLDFilterOperators = map[string]string{
+	"==": "PropertyIsEqualTo",
+	"!=": "PropertyIsNotEqualTo",
+	"<":  "PropertyIsLessThan",
+	"<=": "PropertyIsLessThanOrEqualTo",
+	">":  "PropertyIsGreaterThan",
+	">=": "PropertyIsGreaterThanOrEqualTo",
+}
+
+// sldDocument SLD 1.0 stylesheet, only the parts which can be converted to/from Mapbox styles
+type sldDocument struct {
+	XMLName     xml.Name        `xml:"StyledLayerDescriptor"`
+	Version     string          `xml:"version,attr"`
+	Namespace   string          `xml:"xmlns,attr,omitempty"`
+	OgcPrefix   string          `xml:"xmlns:ogc,attr,omitempty"`
+	NamedLayers []sldNamedLayer `xml:"NamedLayer"`
+	UserLayers  []sldNamedLayer `xml:"UserLayer"`
+}
+
+type sldNamedLayer struct {
+	Name       string         `xml:"Name"`
+	UserStyles []sldUserStyle `xml:"UserStyle"`
+}
+
+type sldUserStyle struct {
+	Name              string                `xml:"Name,omitempty"`
+	Title             string                `xml:"Title,omitempty"`
+	FeatureTypeStyles []sldFeatureTypeStyle `xml:"FeatureTypeStyle"`
+}
+
+type sldFeatureTypeStyle struct {
+	Rules []sldRule `xml:"Rule"`
+}
+
+type sldRule struct {
+	Name                string                 `xml:"Name,omitempty"`
+	Filter              *ogcExpression         `xml:"Filter"`
+	ElseFilter          *struct{}              `xml:"ElseFilter"`
+	MinScaleDenominator *float64               `xml:"MinScaleDenominator"`
+	MaxScaleDenominator *float64               `xml:"MaxScaleDenominator"`
+	PolygonSymbolizers  []sldPolygonSymbolizer `xml:"PolygonSymbolizer"`
+	LineSymbolizers     []sldLineSymbolizer    `xml:"LineSymbolizer"`
+	PointSymbolizers    []sldPointSymbolizer   `xml:"PointSymbolizer"`
+	TextSymbolizers     []sldTextSymbolizer    `xml:"TextSymbolizer"`
+	RasterSymbolizers   []struct{}             `xml:"RasterSymbolizer"`
+}
+
+type sldPolygonSymbolizer struct {
+	Fill   *sldParameters `xml:"Fill"`
+	Stroke *sldParameters `xml:"Stroke"`
+}
+
+type sldLineSymbolizer struct {
+	Stroke *sldParameters `xml:"Stroke"`
+}
+
+type sldPointSymbolizer struct {
+	Graphic sldGraphic `xml:"Graphic"`
+}
+
+type sldGraphic struct {
+	Mark            *sldMark  `xml:"Mark"`
+	ExternalGraphic *struct{} `xml:"ExternalGraphic"`
+	Size            string    `xml:"Size,omitempty"`
+}
+
+type sldMark struct {
+	WellKnownName string         `xml:"WellKnownName,omitempty"`
+	Fill          *sldParameters `xml:"Fill"`
+	Stroke        *sldParameters `xml:"Stroke"`
+}
+
+type sldTextSymbolizer struct {
+	Label *ogcExpression `xml:"Label"`
+	Fill  *sldParameters `xml:"Fill"`
+}
+
+type sldParameters struct {
+	Parameters []sldParameter `xml:"CssParameter"`
+}
+
+type sldParameter struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:",chardata"`
+}
+
+// ogcExpression element of an OGC filter or expression, e.g. <ogc:PropertyIsEqualTo> or <ogc:Literal>
+type ogcExpression struct {
+	XMLName  xml.Name
+	Children []ogcExpression `xml:",any"`
+	Text     string          `xml:",chardata"`
+}
+
+// convertStylesheet converts the given (rendered) stylesheet to another format. Constructs which
+// can't be converted are omitted (or approximated) and reported as warnings.
+func convertStylesheet(cfg *config.Config, supportedSrs config.SupportedSrs, zoom zoomScale, style config.Style,
+	from string, to string, stylesheet []byte) ([]byte, []string, error) {
+
+	switch {
+	case from == engine.FormatMapboxStyle && to == engine.FormatSLD:
+		return mapboxToSLD(style, zoom, stylesheet)
+	case from == engine.FormatSLD && to == engine.FormatMapboxStyle:
+		return sldToMapbox(cfg, supportedSrs, zoom, style, stylesheet)
+	default:
+		return nil, nil, fmt.Errorf("conversion from %s to %s isn't supported", from, to)
+	}
+}
+
+// mapboxToSLD converts a Mapbox style to SLD 1.0. Each (visible) Mapbox layer results in
+// a NamedLayer (to retain the drawing order) named after the source layer.
+func mapboxToSLD(style config.Style, zoom zoomScale, stylesheet []byte) ([]byte, []string, error) {
+	var mapbox struct {
+		Layers []mapboxLayer `json:"layers"`
+	}
+	if err := json.Unmarshal(stylesheet, &mapbox); err != nil {
+		return nil, nil, fmt.Errorf("invalid mapbox style: %w", err)
+	}
+
+	var warnings []string
+	warn := func(format string, args ...any) {
+		warnings = append(warnings, fmt.Sprintf(format, args...))
+	}
+	sld := sldDocument{Version: "1.0.0", Namespace: sldNamespace, OgcPrefix: ogcNamespace}
+	for _, layer := range mapbox.Layers {
+		if visibility, ok := layer.Layout["visibility"].(string); ok && visibility == "none" {
+			continue
+		}
+		if !slices.Contains([]string{legendTypeFill, legendTypeLine, legendTypeCircle, "symbol"}, layer.Type) {
+			warn("layer '%s' of type '%s' can't be converted to SLD", layer.ID, layer.Type)
+
+			continue
+		}
+		filter, err := mapboxFilterToSLD(layer.Filter)
+		if err != nil {
+			warn("layer '%s' is omitted, its filter can't be converted to SLD: %v", layer.ID, err)
+
+			continue
+		}
+		rules := mapboxLayerToSLDRules(layer, filter, zoom, warn)
+		if len(rules) == 0 {
+			continue
+		}
+		name := layer.SourceLayer
+		if name == "" {
+			name = layer.ID
+		}
+		sld.NamedLayers = append(sld.NamedLayers, sldNamedLayer{
+			Name: name,
+			UserStyles: []sldUserStyle{{
+				Name:              layer.ID,
+				Title:             style.Title,
+				FeatureTypeStyles: []sldFeatureTypeStyle{{Rules: rules}},
+			}},
+		})
+	}
+	if len(sld.NamedLayers) == 0 {
+		return nil, warnings, errors.New("style doesn't contain layers which can be converted to SLD")
+	}
+
+	result, err := xml.MarshalIndent(sld, "", "  ")
+	if err != nil {
+		return nil, warnings, err
+	}
+
+	return append([]byte(xml.Header), result...), warnings, nil
+}
+
+// mapboxLayerToSLDRules converts the paint/layout properties of a Mapbox layer to SLD rules.
+// A color depending on a feature property results in a rule per class.
+func mapboxLayerToSLDRules(layer mapboxLayer, filter *ogcExpression, zoom zoomScale, warn func(string, ...any)) []sldRule {
+	colorProperty := layer.Type + "-color"
+	if layer.Type == "symbol" {
+		colorProperty = "text-color"
+	}
+	property, classes := colorClasses(layer.Paint[colorProperty])
+	if property == "" {
+		classes = nil
+	}
+	for _, key := range slices.Sorted(maps.Keys(layer.Paint)) {
+		if (key == colorProperty && classes != nil) || (key == "line-dasharray" && isLiteralArray(layer.Paint[key])) {
+			continue
+		}
+		switch layer.Paint[key].(type) {
+		case map[string]any, []any:
+			warn("data driven or zoom dependent value of '%s' in layer '%s' is approximated", key, layer.ID)
+		}
+	}
+	if classes == nil {
+		classes = []legendClass{{color: evaluateColor(layer.Paint[colorProperty], defaultColor)}}
+	}
+
+	var rules []sldRule
+	var otherValues []any
+	for _, class := range classes {
+		rule := sldRule{Name: layer.ID}
+		classFilter := filter
+		switch {
+		case property != "" && class.values != nil:
+			rule.Name += " " + class.label
+			otherValues = append(otherValues, class.values...)
+			classFilter = ogcAnd(filter, ogcIn(property, class.values))
+		case property != "":
+			rule.Name += " " + legendOtherLabel
+			classFilter = ogcAnd(filter, ogcNot(ogcIn(property, otherValues)))
+		}
+		rule.Filter = ogcFilter(classFilter)
+		if layer.MinZoom != nil {
+			scale := zoom.scaleDenominator(*layer.MinZoom)
+			rule.MaxScaleDenominator = &scale
+		}
+		if layer.MaxZoom != nil {
+			scale := zoom.scaleDenominator(*layer.MaxZoom)
+			rule.MinScaleDenominator = &scale
+		}
+		if !addSLDSymbolizer(&rule, layer, class.color, warn) {
+			return nil
+		}
+		rules = append(rules, rule)
+	}
+
+	return rules
+}
+
+// addSLDSymbolizer adds the symbolizer for the given Mapbox layer to the rule. Returns false when
+// the layer has nothing to symbolize.
+func addSLDSymbolizer(rule *sldRule, layer mapboxLayer, c legendColor, warn func(string, ...any)) bool {
+	switch layer.Type {
+	case legendTypeFill:
+		if _, ok := layer.Paint["fill-pattern"]; ok {
+			warn("fill-pattern of layer '%s' can't be converted to SLD", layer.ID)
+		}
+		symbolizer := sldPolygonSymbolizer{Fill: sldFill(c, evaluateNumber(layer.Paint["fill-opacity"], 1))}
+		if outline, ok := layer.Paint["fill-outline-color"]; ok {
+			symbolizer.Stroke = sldStroke(evaluateColor(outline, c), 1, 1)
+		}
+		rule.PolygonSymbolizers = append(rule.PolygonSymbolizers, symbolizer)
+	case legendTypeLine:
+		if _, ok := layer.Paint["line-pattern"]; ok {
+			warn("line-pattern of layer '%s' can't be converted to SLD", layer.ID)
+		}
+		width := evaluateNumber(layer.Paint["line-width"], defaultLineWidth)
+		stroke := sldStroke(c, width, evaluateNumber(layer.Paint["line-opacity"], 1))
+		if lineCap, ok := evaluate(layer.Layout["line-cap"]).(string); ok {
+			stroke.add("stroke-linecap", lineCap)
+		}
+		if lineJoin, ok := evaluate(layer.Layout["line-join"]).(string); ok {
+			stroke.add("stroke-linejoin", lineJoin)
+		}
+		dashArray := layer.Paint["line-dasharray"]
+		if !isLiteralArray(dashArray) {
+			dashArray = evaluate(dashArray) // e.g. ["literal", [2, 1]]
+		}
+		if dashes, ok := dashArray.([]any); ok && isLiteralArray(dashes) {
+			// dashes in Mapbox are in line widths, in SLD in pixels
+			values := make([]string, 0, len(dashes))
+			for _, dash := range dashes {
+				if d, ok := dash.(float64); ok {
+					values = append(values, formatValue(d*width))
+				}
+			}
+			stroke.add("stroke-dasharray", strings.Join(values, " "))
+		}
+		rule.LineSymbolizers = append(rule.LineSymbolizers, sldLineSymbolizer{Stroke: stroke})
+	case legendTypeCircle:
+		mark := &sldMark{WellKnownName: "circle", Fill: sldFill(c, evaluateNumber(layer.Paint["circle-opacity"], 1))}
+		if width := evaluateNumber(layer.Paint["circle-stroke-width"], 0); width > 0 {
+			mark.Stroke = sldStroke(evaluateColor(layer.Paint["circle-stroke-color"], defaultColor), width,
+				evaluateNumber(layer.Paint["circle-stroke-opacity"], 1))
+		}
+		radius := evaluateNumber(layer.Paint["circle-radius"], defaultCircleRadius)
+		rule.PointSymbolizers = append(rule.PointSymbolizers, sldPointSymbolizer{
+			Graphic: sldGraphic{Mark: mark, Size: formatValue(2 * radius)},
+		})
+	default: // symbol
+		if _, ok := layer.Layout["icon-image"]; ok {
+			warn("icons of layer '%s' can't be converted to SLD", layer.ID)
+		}
+		textField, ok := layer.Layout["text-field"]
+		if !ok {
+			return false
+		}
+		label, err := mapboxTextFieldToSLD(textField)
+		if err != nil {
+			warn("labels of layer '%s' are omitted: %v", layer.ID, err)
+
+			return false
+		}
+		rule.TextSymbolizers = append(rule.TextSymbolizers, sldTextSymbolizer{
+			Label: label,
+			Fill:  sldFill(c, evaluateNumber(layer.Paint["text-opacity"], 1)),
+		})
+	}
+
+	return true
+}
+
+// mapboxFilterToSLD converts (legacy or expression) Mapbox filters to OGC filters
+func mapboxFilterToSLD(filter any) (*ogcExpression, error) {
+	if filter == nil {
+		return nil, nil //nolint:nilnil // no filter
+	}
+	expr, ok := filter.([]any)
+	if !ok || len(expr) == 0 {
+		return nil, fmt.Errorf("unsupported filter %v", filter)
+	}
+	op, _ := expr[0].(string)
+	switch op {
+	case "all", "any", "none":
+		var operands []ogcExpression
+		for _, sub := range expr[1:] {
+			operand, err := mapboxFilterToSLD(sub)
+			if err != nil {
+				return nil, err
+			}
+			if operand != nil {
+				operands = append(operands, *operand)
+			}
+		}
+		if len(operands) == 0 {
+			return nil, nil //nolint:nilnil // no filter
+		}
+		result := &operands[0]
+		if len(operands) > 1 {
+			name := "ogc:And"
+			if op != "all" {
+				name = "ogc:Or"
+			}
+			result = &ogcExpression{XMLName: xml.Name{Local: name}, Children: operands}
+		}
+		if op == "none" {
+			result = ogcNot(result)
+		}
+
+		return result, nil
+	case "!":
+		if len(expr) != 2 {
+			break
+		}
+		operand, err := mapboxFilterToSLD(expr[1])
+		if err != nil || operand == nil {
+			return nil, err
+		}
+
+		return ogcNot(operand), nil
+	case "in", "!in":
+		property, ok := filterProperty(expr)
+		if !ok || len(expr) < 3 {
+			break
+		}
+		values := expr[2:]
+		if literal, ok := expr[2].([]any); ok && len(literal) == 2 && literal[0] == "literal" {
+			values, _ = literal[1].([]any)
+		}
+		result := ogcIn(property, values)
+		if op == "!in" {
+			result = ogcNot(result)
+		}
+
+		return result, nil
+	case "has", "!has":
+		property, ok := expr[1].(string)
+		if !ok || len(expr) != 2 {
+			break
+		}
+		isNull := &ogcExpression{XMLName: xml.Name{Local: "ogc:PropertyIsNull"},
+			Children: []ogcExpression{ogcPropertyName(property)}}
+		if op == "!has" {
+			return isNull, nil
+		}
+
+		return ogcNot(isNull), nil
+	default:
+		comparison, ok := mapboxToSLDFilterOperators[op]
+		if !ok || len(expr) != 3 {
+			break
+		}
+		property, ok := filterProperty(expr)
+		if !ok {
+			break
+		}
+		if _, ok := expr[2].([]any); ok {
+			break // value is an expression
+		}
+
+		return &ogcExpression{XMLName: xml.Name{Local: "ogc:" + comparison},
+			Children: []ogcExpression{ogcPropertyName(property), ogcLiteral(expr[2])}}, nil
+	}
+
+	return nil, fmt.Errorf("unsupported filter %v", filter)
+}
+
+// filterProperty returns the property of a (legacy or expression) comparison filter,
+// filters on geometry type or feature ID aren't supported.
+func filterProperty(expr []any) (string, bool) {
+	switch key := expr[1].(type) {
+	case string:
+		return key, !strings.HasPrefix(key, "$")
+	case []any:
+		if len(key) == 2 && key[0] == "get" {
+			property, ok := key[1].(string)
+
+			return property, ok
+		}
+	}
+
+	return "", false
+}
+
+// mapboxTextFieldToSLD converts a text-field ("{property}", ["get", "property"] or a constant) to an SLD label
+func mapboxTextFieldToSLD(textField any) (*ogcExpression, error) {
+	switch v := textField.(type) {
+	case string:
+		if strings.HasPrefix(v, "{") && strings.HasSuffix(v, "}") && strings.Count(v, "{") == 1 {
+			return &ogcExpression{Children: []ogcExpression{ogcPropertyName(v[1 : len(v)-1])}}, nil
+		}
+		if !strings.Contains(v, "{") {
+			return &ogcExpression{Text: v}, nil
+		}
+	case []any:
+		if len(v) == 2 && v[0] == "get" {
+			if property, ok := v[1].(string); ok {
+				return &ogcExpression{Children: []ogcExpression{ogcPropertyName(property)}}, nil
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("unsupported text-field %v", textField)
+}
+
+func ogcFilter(expr *ogcExpression) *ogcExpression {
+	if expr == nil {
+		return nil
+	}
+
+	return &ogcExpression{XMLName: xml.Name{Local: "ogc:Filter"}, Children: []ogcExpression{*expr}}
+}
+
+func ogcAnd(a *ogcExpression, b *ogcExpression) *ogcExpression {
+	if a == nil {
+		return b
+	}
+
+	return &ogcExpression{XMLName: xml.Name{Local: "ogc:And"}, Children: []ogcExpression{*a, *b}}
+}
+
+func ogcNot(expr *ogcExpression) *ogcExpression {
+	return &ogcExpression{XMLName: xml.Name{Local: "ogc:Not"}, Children: []ogcExpression{*expr}}
+}
+
+// ogcIn property equals one of the given values (OGC filters don't have an "in" operator)
+func ogcIn(property string, values []any) *ogcExpression {
+	comparisons := make([]ogcExpression, 0, len(values))
+	for _, value := range values {
+		comparisons = append(comparisons, ogcExpression{XMLName: xml.Name{Local: "ogc:PropertyIsEqualTo"},
+			Children: []ogcExpression{ogcPropertyName(property), ogcLiteral(value)}})
+	}
+	if len(comparisons) == 1 {
+		return &comparisons[0]
+	}
+
+	return &ogcExpression{XMLName: xml.Name{Local: "ogc:Or"}, Children: comparisons}
+}
+
+func ogcPropertyName(property string) ogcExpression {
+	return ogcExpression{XMLName: xml.Name{Local: "ogc:PropertyName"}, Text: property}
+}
+
+func ogcLiteral(value any) ogcExpression {
+	return ogcExpression{XMLName: xml.Name{Local: "ogc:Literal"}, Text: formatValue(value)}
+}
+
+func sldFill(c legendColor, opacity float64) *sldParameters {
+	fill := &sldParameters{}
+	fill.add("fill", c.hex())
+	if alpha := withOpacity(c, opacity).A; alpha < 255 {
+		fill.add("fill-opacity", formatValue(roundOpacity(alpha)))
+	}
+
+	return fill
+}
+
+func sldStroke(c legendColor, width float64, opacity float64) *sldParameters {
+	stroke := &sldParameters{}
+	stroke.add("stroke", c.hex())
+	stroke.add("stroke-width", formatValue(width))
+	if alpha := withOpacity(c, opacity).A; alpha < 255 {
+		stroke.add("stroke-opacity", formatValue(roundOpacity(alpha)))
+	}
+
+	return stroke
+}
+
+func (p *sldParameters) add(name string, value string) {
+	p.Parameters = append(p.Parameters, sldParameter{Name: name, Value: value})
+}
+
+func (p *sldParameters) get(name string) (string, bool) {
+	if p == nil {
+		return "", false
+	}
+	for _, parameter := range p.Parameters {
+		if parameter.Name == name && strings.TrimSpace(parameter.Value) != "" {
+			return strings.TrimSpace(parameter.Value), true
+		}
+	}
+
+	return "", false
+}
+
+func (p *sldParameters) getNumber(name string, fallback float64) float64 {
+	if value, ok := p.get(name); ok {
+		if n, err := strconv.ParseFloat(value, 64); err == nil {
+			return n
+		}
+	}
+
+	return fallback
+}
+
+// sldToMapbox converts an SLD 1.0 stylesheet to a Mapbox style. Each symbolizer in a rule results
+// in a Mapbox layer. The (vector) tiles of this API are used as source, the name of the NamedLayer
+// should match the layer in these tiles.
+func sldToMapbox(cfg *config.Config, supportedSrs config.SupportedSrs, zoom zoomScale, style config.Style,
+	stylesheet []byte) ([]byte, []string, error) {
+
+	var sld sldDocument
+	if err := xml.Unmarshal(stylesheet, &sld); err != nil {
+		return nil, nil, fmt.Errorf("invalid SLD: %w", err)
+	}
+	tiles := cfg.OgcAPI.Tiles
+	if tiles == nil || tiles.DatasetTiles == nil || !tiles.DatasetTiles.HasType(config.TilesTypeVector) {
+		return nil, nil, errors.New("conversion to Mapbox style requires (dataset) vector tiles to use as source")
+	}
+
+	var warnings []string
+	warn := func(format string, args ...any) {
+		warnings = append(warnings, fmt.Sprintf(format, args...))
+	}
+	layers := make([]map[string]any, 0)
+	for _, namedLayer := range append(sld.NamedLayers, sld.UserLayers...) {
+		for _, userStyle := range namedLayer.UserStyles {
+			for i, featureTypeStyle := range userStyle.FeatureTypeStyles {
+				prefix := toStyleID(namedLayer.Name)
+				if len(userStyle.FeatureTypeStyles) > 1 {
+					prefix += "-" + strconv.Itoa(i)
+				}
+				layers = append(layers, sldRulesToMapboxLayers(namedLayer.Name, prefix, featureTypeStyle.Rules, zoom, warn)...)
+			}
+		}
+	}
+	if len(layers) == 0 {
+		return nil, warnings, errors.New("SLD doesn't contain rules which can be converted to Mapbox style")
+	}
+	if slices.ContainsFunc(layers, func(layer map[string]any) bool { return layer["type"] == "symbol" }) {
+		warn("labels are converted, but fonts aren't: add a 'glyphs' URL to render these labels")
+	}
+
+	mapbox := map[string]any{
+		"version": 8,
+		"id":      style.ID,
+		"name":    style.Title,
+		"sources": map[string]any{
+			mapboxSourceDataset: map[string]any{
+				"type": "vector",
+				"tiles": []string{cfg.BaseURL.String() + "/tiles/" + supportedSrs.GetTileMatrixSetID() +
+					"/{z}/{y}/{x}?f=" + engine.FormatMVT},
+				"minzoom": supportedSrs.ZoomLevelRange.Start,
+				"maxzoom": supportedSrs.ZoomLevelRange.End,
+			},
+		},
+		"layers": layers,
+	}
+	result, err := json.MarshalIndent(mapbox, "", "  ")
+
+	return result, warnings, err
+}
+
+// sldRulesToMapboxLayers converts the rules of an SLD FeatureTypeStyle to Mapbox layers
+func sldRulesToMapboxLayers(sourceLayer string, prefix string, rules []sldRule, zoom zoomScale,
+	warn func(string, ...any)) []map[string]any {
+
+	// filters of the rules, to derive the filter of an ElseFilter rule
+	var filters []any
+	elseApplies := true
+	for _, rule := range rules {
+		if rule.ElseFilter != nil {
+			continue
+		}
+		if rule.Filter == nil {
+			elseApplies = false
+
+			continue
+		}
+		if filter, err := sldFilterToMapbox(*rule.Filter); err == nil {
+			filters = append(filters, filter)
+		}
+	}
+
+	var layers []map[string]any
+	for i, rule := range rules {
+		var filter any
+		switch {
+		case rule.ElseFilter != nil:
+			if !elseApplies {
+				continue // all features are already covered by another rule
+			}
+			if len(filters) > 0 {
+				filter = []any{"!", append([]any{"any"}, filters...)}
+			}
+		case rule.Filter != nil:
+			var err error
+			if filter, err = sldFilterToMapbox(*rule.Filter); err != nil {
+				warn("rule %d of layer '%s' is omitted, its filter can't be converted to Mapbox style: %v", i, sourceLayer, err)
+
+				continue
+			}
+		}
+		if len(rule.RasterSymbolizers) > 0 {
+			warn("RasterSymbolizer in rule %d of layer '%s' can't be converted to Mapbox style", i, sourceLayer)
+		}
+
+		newLayer := func(layerType string, suffix string) map[string]any {
+			layer := map[string]any{
+				"id":           fmt.Sprintf("%s-%d-%s", prefix, i, suffix),
+				"type":         layerType,
+				"source":       mapboxSourceDataset,
+				"source-layer": sourceLayer,
+				"paint":        map[string]any{},
+			}
+			if filter != nil {
+				layer["filter"] = filter
+			}
+			if rule.MaxScaleDenominator != nil {
+				layer["minzoom"] = zoom.zoomLevel(*rule.MaxScaleDenominator)
+			}
+			if rule.MinScaleDenominator != nil {
+				layer["maxzoom"] = zoom.zoomLevel(*rule.MinScaleDenominator)
+			}
+			layers = append(layers, layer)
+
+			return layer
+		}
+
+		for j, symbolizer := range rule.PolygonSymbolizers {
+			if symbolizer.Fill != nil {
+				layer := newLayer(legendTypeFill, "fill"+suffixIndex(j))
+				paint := layer["paint"].(map[string]any)
+				paint["fill-color"] = mapboxColor(symbolizer.Fill, "fill", "#808080")
+				paint["fill-opacity"] = symbolizer.Fill.getNumber("fill-opacity", 1)
+			}
+			if symbolizer.Stroke != nil {
+				layer := newLayer(legendTypeLine, "outline"+suffixIndex(j))
+				addMapboxStroke(layer, symbolizer.Stroke)
+			}
+		}
+		for j, symbolizer := range rule.LineSymbolizers {
+			layer := newLayer(legendTypeLine, "line"+suffixIndex(j))
+			addMapboxStroke(layer, symbolizer.Stroke)
+		}
+		for j, symbolizer := range rule.PointSymbolizers {
+			if symbolizer.Graphic.ExternalGraphic != nil && symbolizer.Graphic.Mark == nil {
+				warn("ExternalGraphic in rule %d of layer '%s' can't be converted to Mapbox style", i, sourceLayer)
+
+				continue
+			}
+			mark := symbolizer.Graphic.Mark
+			if mark == nil {
+				mark = &sldMark{}
+			}
+			if wellKnownName := strings.TrimSpace(mark.WellKnownName); wellKnownName != "circle" {
+				warn("mark '%s' in rule %d of layer '%s' is converted to a circle", wellKnownName, i, sourceLayer)
+			}
+			layer := newLayer(legendTypeCircle, "point"+suffixIndex(j))
+			paint := layer["paint"].(map[string]any)
+			size := 6.0 // default size in SLD
+			if n, err := strconv.ParseFloat(strings.TrimSpace(symbolizer.Graphic.Size), 64); err == nil {
+				size = n
+			}
+			paint["circle-radius"] = size / 2
+			if mark.Fill != nil || mark.Stroke == nil {
+				paint["circle-color"] = mapboxColor(mark.Fill, "fill", "#808080")
+				paint["circle-opacity"] = mark.Fill.getNumber("fill-opacity", 1)
+			} else {
+				paint["circle-opacity"] = 0
+			}
+			if mark.Stroke != nil {
+				paint["circle-stroke-color"] = mapboxColor(mark.Stroke, "stroke", "#000000")
+				paint["circle-stroke-width"] = mark.Stroke.getNumber("stroke-width", 1)
+				paint["circle-stroke-opacity"] = mark.Stroke.getNumber("stroke-opacity", 1)
+			}
+		}
+		for j, symbolizer := range rule.TextSymbolizers {
+			textField, err := sldLabelToMapbox(symbolizer.Label)
+			if err != nil {
+				warn("labels in rule %d of layer '%s' are omitted: %v", i, sourceLayer, err)
+
+				continue
+			}
+			layer := newLayer("symbol", "text"+suffixIndex(j))
+			layer["layout"] = map[string]any{"text-field": textField}
+			paint := layer["paint"].(map[string]any)
+			paint["text-color"] = mapboxColor(symbolizer.Fill, "fill", "#000000")
+		}
+	}
+
+	return layers
+}
+
+func addMapboxStroke(layer map[string]any, stroke *sldParameters) {
+	paint := layer["paint"].(map[string]any)
+	width := stroke.getNumber("stroke-width", 1)
+	paint["line-color"] = mapboxColor(stroke, "stroke", "#000000")
+	paint["line-width"] = width
+	paint["line-opacity"] = stroke.getNumber("stroke-opacity", 1)
+	if dashArray, ok := stroke.get("stroke-dasharray"); ok && width > 0 {
+		// dashes in SLD are in pixels, in Mapbox in line widths
+		var dashes []float64
+		for _, dash := range strings.Fields(dashArray) {
+			if d, err := strconv.ParseFloat(dash, 64); err == nil {
+				dashes = append(dashes, d/width)
+			}
+		}
+		paint["line-dasharray"] = dashes
+	}
+	layout := map[string]any{}
+	if lineCap, ok := stroke.get("stroke-linecap"); ok {
+		layout["line-cap"] = lineCap
+	}
+	if lineJoin, ok := stroke.get("stroke-linejoin"); ok {
+		if lineJoin == "mitre" {
+			lineJoin = "miter"
+		}
+		layout["line-join"] = lineJoin
+	}
+	if len(layout) > 0 {
+		layer["layout"] = layout
+	}
+}
+
+// sldFilterToMapbox converts an OGC filter to a Mapbox filter (expression)
+func sldFilterToMapbox(filter ogcExpression) (any, error) {
+	switch filter.XMLName.Local {
+	case "Filter":
+		if len(filter.Children) != 1 {
+			return nil, errors.New("filter should contain a single operator")
+		}
+
+		return sldFilterToMapbox(filter.Children[0])
+	case "And", "Or":
+		op := "all"
+		if filter.XMLName.Local == "Or" {
+			op = "any"
+		}
+		result := []any{op}
+		for _, child := range filter.Children {
+			operand, err := sldFilterToMapbox(child)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, operand)
+		}
+
+		return result, nil
+	case "Not":
+		if len(filter.Children) != 1 {
+			return nil, errors.New("Not should contain a single operator")
+		}
+		operand, err := sldFilterToMapbox(filter.Children[0])
+		if err != nil {
+			return nil, err
+		}
+
+		return []any{"!", operand}, nil
+	case "PropertyIsNull":
+		if len(filter.Children) != 1 || filter.Children[0].XMLName.Local != "PropertyName" {
+			return nil, errors.New("PropertyIsNull should contain a PropertyName")
+		}
+
+		return []any{"!", []any{"has", strings.TrimSpace(filter.Children[0].Text)}}, nil
+	}
+	for op, comparison := range mapboxToSLDFilterOperators {
+		if filter.XMLName.Local != comparison {
+			continue
+		}
+		var property string
+		var value any
+		for _, child := range filter.Children {
+			switch child.XMLName.Local {
+			case "PropertyName":
+				property = strings.TrimSpace(child.Text)
+			case "Literal":
+				value = parseLiteral(child.Text)
+			}
+		}
+		if property == "" || value == nil {
+			return nil, fmt.Errorf("%s should contain a PropertyName and a Literal", comparison)
+		}
+
+		return []any{op, []any{"get", property}, value}, nil
+	}
+
+	return nil, fmt.Errorf("unsupported operator %s", filter.XMLName.Local)
+}
+
+// sldLabelToMapbox converts an SLD label (property or constant) to a Mapbox text-field
+func sldLabelToMapbox(label *ogcExpression) (any, error) {
+	if label == nil {
+		return nil, errors.New("TextSymbolizer without Label")
+	}
+	switch {
+	case len(label.Children) == 0 && strings.TrimSpace(label.Text) != "":
+		return strings.TrimSpace(label.Text), nil
+	case len(label.Children) == 1 && label.Children[0].XMLName.Local == "PropertyName" && strings.TrimSpace(label.Text) == "":
+		return []any{"get", strings.TrimSpace(label.Children[0].Text)}, nil
+	case len(label.Children) == 1 && label.Children[0].XMLName.Local == "Literal" && strings.TrimSpace(label.Text) == "":
+		return strings.TrimSpace(label.Children[0].Text), nil
+	}
+
+	return nil, errors.New("only a PropertyName or constant is supported as Label")
+}
+
+// mapboxColor returns the color of the given SLD parameter as Mapbox color
+func mapboxColor(parameters *sldParameters, name string, fallback string) string {
+	if value, ok := parameters.get(name); ok {
+		if c, ok := parseColor(value); ok {
+			return c.hex()
+		}
+	}
+
+	return fallback
+}
+
+// parseLiteral parses an OGC literal, numeric values are converted to numbers
+func parseLiteral(literal string) any {
+	literal = strings.TrimSpace(literal)
+	if n, err := strconv.ParseFloat(literal, 64); err == nil {
+		return n
+	}
+
+	return literal
+}
+
+// isLiteralArray whether the given value is an array of numbers (rather than an expression)
+func isLiteralArray(value any) bool {
+	values, ok := value.([]any)
+
+	return ok && !slices.ContainsFunc(values, func(v any) bool { _, isNumber := v.(float64); return !isNumber })
+}
+
+func suffixIndex(i int) string {
+	if i == 0 {
+		return ""
+	}
+
+	return "-" + strconv.Itoa(i)
+}
+
+// hex notation of this color (without alpha)
+func (c legendColor) hex() string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
+
+// zoomScale relates zoom levels (in Mapbox styles) to scale denominators (in SLD), as the scale denominator of
+// zoom level 0 in the TileMatrixSet of the style. Each zoom level halves the scale denominator.
+type zoomScale float64
+
+func (z zoomScale) scaleDenominator(zoom float64) float64 {
+	return math.Round(float64(z)/math.Pow(2, zoom)*1000) / 1000
+}
+
+func (z zoomScale) zoomLevel(scale float64) float64 {
+	return math.Round(math.Log2(float64(z)/scale)*100) / 100
+}
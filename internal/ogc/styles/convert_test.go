@@ -0,0 +1,189 @@
+package styles
+
+import (
+	"encoding/json"
+	"net/url"
+	"testing"
+
+	"github.com/PDOK/gokoala/config"
+	"github.com/PDOK/gokoala/internal/engine"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// scale denominator of zoom level 0 in WebMercatorQuad
+const webMercatorZoomScale = zoomScale(559082264.0287178)
+
+func TestConvertStylesheet_MapboxToSLD(t *testing.T) {
+	stylesheet := `{
+  "version": 8,
+  "sources": {"bgt": {"type": "vector"}},
+  "layers": [
+    {"id": "background", "type": "background"},
+    {"id": "water", "type": "fill", "source": "bgt", "source-layer": "waterdeel",
+      "filter": ["all", ["==", "class", "water"], [">=", ["get", "area"], 100]],
+      "paint": {"fill-color": "#0000ff", "fill-opacity": 0.5, "fill-outline-color": "navy"}},
+    {"id": "roads", "type": "line", "source": "bgt", "source-layer": "wegdeel", "minzoom": 10,
+      "layout": {"line-cap": "round"},
+      "paint": {
+        "line-color": ["match", ["get", "type"], "highway", "#ff0000", ["local", "street"], "#808080", "#000000"],
+        "line-width": ["interpolate", ["linear"], ["zoom"], 10, 1, 15, 3],
+        "line-dasharray": [2, 1]
+      }},
+    {"id": "labels", "type": "symbol", "source": "bgt", "source-layer": "wegdeel",
+      "layout": {"text-field": "{name}", "icon-image": "marker"}},
+    {"id": "unsupported", "type": "fill", "source": "bgt", "source-layer": "pand", "filter": ["==", "$type", "Polygon"]}
+  ]
+}`
+	result, warnings, err := convertStylesheet(nil, config.SupportedSrs{}, webMercatorZoomScale, config.Style{ID: "test", Title: "Test"},
+		engine.FormatMapboxStyle, engine.FormatSLD, []byte(stylesheet))
+	require.NoError(t, err)
+
+	sld := string(result)
+	assert.Contains(t, sld, `<StyledLayerDescriptor version="1.0.0" xmlns="http://www.opengis.net/sld" xmlns:ogc="http://www.opengis.net/ogc">`)
+	assert.Contains(t, sld, `<ogc:PropertyIsGreaterThanOrEqualTo>
+                <ogc:PropertyName>area</ogc:PropertyName>
+                <ogc:Literal>100</ogc:Literal>
+              </ogc:PropertyIsGreaterThanOrEqualTo>`)
+	assert.Contains(t, sld, `<CssParameter name="fill">#0000ff</CssParameter>`)
+	assert.Contains(t, sld, `<CssParameter name="fill-opacity">0.5</CssParameter>`)
+	assert.Contains(t, sld, `<CssParameter name="stroke">#000080</CssParameter>`)
+	assert.Contains(t, sld, `<Name>roads local, street</Name>`)
+	assert.Contains(t, sld, `<Name>roads Other</Name>`)
+	assert.Contains(t, sld, `<MaxScaleDenominator>545978.773</MaxScaleDenominator>`)
+	assert.Contains(t, sld, `<CssParameter name="stroke-dasharray">6 3</CssParameter>`)
+	assert.Contains(t, sld, `<CssParameter name="stroke-linecap">round</CssParameter>`)
+	assert.Contains(t, sld, `<Label>
+              <ogc:PropertyName>name</ogc:PropertyName>
+            </Label>`)
+	assert.NotContains(t, sld, "pand")
+
+	assert.Equal(t, []string{
+		"layer 'background' of type 'background' can't be converted to SLD",
+		"data driven or zoom dependent value of 'line-width' in layer 'roads' is approximated",
+		"icons of layer 'labels' can't be converted to SLD",
+		"layer 'unsupported' is omitted, its filter can't be converted to SLD: unsupported filter [== $type Polygon]",
+	}, warnings)
+
+	info, err := validateSLD(result)
+	require.NoError(t, err)
+	assert.Equal(t, "water", info.ID)
+}
+
+func TestConvertStylesheet_SLDToMapbox(t *testing.T) {
+	stylesheet := `<?xml version="1.0" encoding="UTF-8"?>
+<StyledLayerDescriptor version="1.0.0" xmlns="http://www.opengis.net/sld" xmlns:ogc="http://www.opengis.net/ogc">
+  <NamedLayer>
+    <Name>wegdeel</Name>
+    <UserStyle>
+      <FeatureTypeStyle>
+        <Rule>
+          <ogc:Filter>
+            <ogc:Or>
+              <ogc:PropertyIsEqualTo><ogc:PropertyName>type</ogc:PropertyName><ogc:Literal>highway</ogc:Literal></ogc:PropertyIsEqualTo>
+              <ogc:PropertyIsLessThan><ogc:PropertyName>lanes</ogc:PropertyName><ogc:Literal>4</ogc:Literal></ogc:PropertyIsLessThan>
+            </ogc:Or>
+          </ogc:Filter>
+          <MaxScaleDenominator>545978.773</MaxScaleDenominator>
+          <LineSymbolizer>
+            <Stroke>
+              <CssParameter name="stroke">#FF0000</CssParameter>
+              <CssParameter name="stroke-width">2</CssParameter>
+              <CssParameter name="stroke-dasharray">4 2</CssParameter>
+            </Stroke>
+          </LineSymbolizer>
+          <TextSymbolizer>
+            <Label><ogc:PropertyName>name</ogc:PropertyName></Label>
+          </TextSymbolizer>
+        </Rule>
+        <Rule>
+          <ElseFilter/>
+          <PolygonSymbolizer>
+            <Fill><CssParameter name="fill">#00ff00</CssParameter><CssParameter name="fill-opacity">0.5</CssParameter></Fill>
+            <Stroke><CssParameter name="stroke">#000000</CssParameter></Stroke>
+          </PolygonSymbolizer>
+        </Rule>
+        <Rule>
+          <ogc:Filter><ogc:PropertyIsLike><ogc:PropertyName>name</ogc:PropertyName><ogc:Literal>A*</ogc:Literal></ogc:PropertyIsLike></ogc:Filter>
+          <LineSymbolizer/>
+        </Rule>
+        <Rule>
+          <ogc:Filter><ogc:PropertyIsNotEqualTo><ogc:PropertyName>kind</ogc:PropertyName><ogc:Literal>poi</ogc:Literal></ogc:PropertyIsNotEqualTo></ogc:Filter>
+          <PointSymbolizer>
+            <Graphic>
+              <Mark><WellKnownName>square</WellKnownName><Fill><CssParameter name="fill">#0000ff</CssParameter></Fill></Mark>
+              <Size>8</Size>
+            </Graphic>
+          </PointSymbolizer>
+        </Rule>
+      </FeatureTypeStyle>
+    </UserStyle>
+  </NamedLayer>
+</StyledLayerDescriptor>`
+	baseURL, err := url.Parse("http://localhost:8080")
+	require.NoError(t, err)
+	cfg := &config.Config{
+		BaseURL: config.URL{URL: baseURL},
+		OgcAPI: config.OgcAPI{Tiles: &config.OgcAPITiles{
+			DatasetTiles: &config.Tiles{Types: []config.TilesType{config.TilesTypeVector}},
+		}},
+	}
+	supportedSrs := config.SupportedSrs{Srs: "EPSG:3857", ZoomLevelRange: config.ZoomLevelRange{Start: 0, End: 20}}
+	result, warnings, err := convertStylesheet(cfg, supportedSrs, webMercatorZoomScale, config.Style{ID: "test", Title: "Test"},
+		engine.FormatSLD, engine.FormatMapboxStyle, []byte(stylesheet))
+	require.NoError(t, err)
+
+	info, err := validateMapboxStyle(result)
+	require.NoError(t, err)
+	assert.Equal(t, "test", info.ID)
+
+	var mapbox struct {
+		Sources map[string]struct {
+			Tiles []string `json:"tiles"`
+		} `json:"sources"`
+		Layers []map[string]any `json:"layers"`
+	}
+	require.NoError(t, json.Unmarshal(result, &mapbox))
+	assert.Equal(t, []string{"http://localhost:8080/tiles/WebMercatorQuad/{z}/{y}/{x}?f=mvt"}, mapbox.Sources[mapboxSourceDataset].Tiles)
+
+	ids := make([]string, 0, len(mapbox.Layers))
+	for _, layer := range mapbox.Layers {
+		ids = append(ids, layer["id"].(string))
+		assert.Equal(t, "wegdeel", layer["source-layer"])
+	}
+	assert.Equal(t, []string{"wegdeel-0-line", "wegdeel-0-text", "wegdeel-1-fill", "wegdeel-1-outline", "wegdeel-3-point"}, ids)
+
+	filter := []any{"any", []any{"==", []any{"get", "type"}, "highway"}, []any{"<", []any{"get", "lanes"}, 4.0}}
+	assert.Equal(t, filter, mapbox.Layers[0]["filter"])
+	assert.InDelta(t, 10.0, mapbox.Layers[0]["minzoom"], 0.01)
+	assert.Equal(t, map[string]any{"line-color": "#ff0000", "line-width": 2.0, "line-opacity": 1.0,
+		"line-dasharray": []any{2.0, 1.0}}, mapbox.Layers[0]["paint"])
+	assert.Equal(t, map[string]any{"text-field": []any{"get", "name"}}, mapbox.Layers[1]["layout"])
+	assert.Equal(t, []any{"!", []any{"any", filter, []any{"!=", []any{"get", "kind"}, "poi"}}}, mapbox.Layers[2]["filter"])
+	assert.Equal(t, map[string]any{"fill-color": "#00ff00", "fill-opacity": 0.5}, mapbox.Layers[2]["paint"])
+	assert.Equal(t, map[string]any{"circle-radius": 4.0, "circle-color": "#0000ff", "circle-opacity": 1.0}, mapbox.Layers[4]["paint"])
+
+	assert.Equal(t, []string{
+		"rule 2 of layer 'wegdeel' is omitted, its filter can't be converted to Mapbox style: unsupported operator PropertyIsLike",
+		"mark 'square' in rule 3 of layer 'wegdeel' is converted to a circle",
+		"labels are converted, but fonts aren't: add a 'glyphs' URL to render these labels",
+	}, warnings)
+
+	_, _, err = convertStylesheet(&config.Config{}, supportedSrs, webMercatorZoomScale, config.Style{ID: "test"},
+		engine.FormatSLD, engine.FormatMapboxStyle, []byte(stylesheet))
+	assert.ErrorContains(t, err, "requires (dataset) vector tiles")
+}
+
+func TestNewZoomScales(t *testing.T) {
+	zoomScales, err := newZoomScales(&config.OgcAPITiles{DatasetTiles: &config.Tiles{SupportedSrs: []config.SupportedSrs{
+		{Srs: "EPSG:3857"}, {Srs: "EPSG:28992"},
+	}}})
+	require.NoError(t, err)
+	assert.InDelta(t, float64(webMercatorZoomScale), float64(zoomScales["WebMercatorQuad"]), 0.001)
+	assert.InDelta(t, 12288000.0, float64(zoomScales["NetherlandsRDNewQuad"]), 0.001)
+
+	// same scale results in another zoom level, depending on the tileMatrixSet
+	assert.InDelta(t, 5.0, zoomScales["NetherlandsRDNewQuad"].zoomLevel(384000), 0.01)
+	assert.InDelta(t, 10.51, zoomScales["WebMercatorQuad"].zoomLevel(384000), 0.01)
+	assert.InDelta(t, 384000.0, zoomScales["NetherlandsRDNewQuad"].scaleDenominator(5), 0.001)
+}
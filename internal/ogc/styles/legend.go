@@ -0,0 +1,541 @@
+package styles
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image/color"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	legendTypeFill   = "fill"
+	legendTypeLine   = "line"
+	legendTypeCircle = "circle"
+
+	// label of the class of features not matching any of the other classes
+	legendOtherLabel = "Other"
+)
+
+var (
+	// default values of paint properties, see https://docs.mapbox.com/style-spec/reference/layers/
+	defaultColor        = legendColor{A: 255}
+	defaultLineWidth    = 1.0
+	defaultCircleRadius = 5.0
+
+	namedColors = map[string]legendColor{
+		"black":       {0, 0, 0, 255},
+		"white":       {255, 255, 255, 255},
+		"red":         {255, 0, 0, 255},
+		"green":       {0, 128, 0, 255},
+		"blue":        {0, 0, 255, 255},
+		"yellow":      {255, 255, 0, 255},
+		"orange":      {255, 165, 0, 255},
+		"purple":      {128, 0, 128, 255},
+		"gray":        {128, 128, 128, 255},
+		"grey":        {128, 128, 128, 255},
+		"silver":      {192, 192, 192, 255},
+		"maroon":      {128, 0, 0, 255},
+		"navy":        {0, 0, 128, 255},
+		"teal":        {0, 128, 128, 255},
+		"olive":       {128, 128, 0, 255},
+		"lime":        {0, 255, 0, 255},
+		"aqua":        {0, 255, 255, 255},
+		"cyan":        {0, 255, 255, 255},
+		"fuchsia":     {255, 0, 255, 255},
+		"magenta":     {255, 0, 255, 255},
+		"brown":       {165, 42, 42, 255},
+		"pink":        {255, 192, 203, 255},
+		"transparent": {0, 0, 0, 0},
+	}
+)
+
+// legendItem single entry in a legend
+type legendItem struct {
+	Label  string
+	Symbol legendSymbol
+}
+
+// legendSymbol symbol of a legend entry, a simplified representation of a Mapbox layer
+type legendSymbol struct {
+	// one of fill, line or circle
+	Type string
+
+	Fill        *legendColor
+	Stroke      *legendColor
+	StrokeWidth float64
+	Radius      float64
+}
+
+// legendColor color of a legend symbol
+type legendColor color.NRGBA
+
+// CSS color notation, for use in HTML/JSON legends
+func (c legendColor) CSS() string {
+	return fmt.Sprintf("rgba(%d, %d, %d, %s)", c.R, c.G, c.B, formatValue(roundOpacity(c.A)))
+}
+
+// roundOpacity converts alpha to opacity (0-1), rounded to 2 decimals
+func roundOpacity(alpha uint8) float64 {
+	return math.Round(float64(alpha)/255*100) / 100
+}
+
+type mapboxLayer struct {
+	ID          string         `json:"id"`
+	Type        string         `json:"type"`
+	SourceLayer string         `json:"source-layer"`
+	MinZoom     *float64       `json:"minzoom"`
+	MaxZoom     *float64       `json:"maxzoom"`
+	Filter      any            `json:"filter"`
+	Layout      map[string]any `json:"layout"`
+	Paint       map[string]any `json:"paint"`
+}
+
+// legendClass part of the features in a layer, with a specific color
+type legendClass struct {
+	label string
+	color legendColor
+
+	// values of the feature property in this class, nil for the class of other features
+	values []any
+}
+
+// generateLegend derives a legend from the given (rendered) Mapbox style. Each visible fill, line
+// and circle layer results in a legend item. Colors depending on feature properties (match expressions
+// or categorical functions) result in a legend item per class. Since a legend isn't zoom dependent,
+// zoom dependent values are evaluated halfway their zoom levels. Other data driven values evaluate
+// to their default. Symbol layers (icons/text), raster and background layers are omitted.
+func generateLegend(stylesheet []byte) ([]legendItem, error) {
+	var style struct {
+		Layers []mapboxLayer `json:"layers"`
+	}
+	if err := json.Unmarshal(stylesheet, &style); err != nil {
+		return nil, fmt.Errorf("invalid mapbox style: %w", err)
+	}
+
+	var items []legendItem
+	for _, layer := range style.Layers {
+		if visibility, ok := layer.Layout["visibility"].(string); ok && visibility == "none" {
+			continue
+		}
+		var colorProperty, opacityProperty string
+		switch layer.Type {
+		case legendTypeFill, legendTypeLine, legendTypeCircle:
+			colorProperty, opacityProperty = layer.Type+"-color", layer.Type+"-opacity"
+		case "fill-extrusion":
+			colorProperty, opacityProperty = "fill-extrusion-color", "fill-extrusion-opacity"
+		default:
+			continue
+		}
+
+		label := layerLabel(layer)
+		opacity := evaluateNumber(layer.Paint[opacityProperty], 1)
+		_, classes := colorClasses(layer.Paint[colorProperty])
+		if classes == nil {
+			classes = []legendClass{{label: label, color: evaluateColor(layer.Paint[colorProperty], defaultColor)}}
+		} else {
+			for i := range classes {
+				classes[i].label = label + ": " + classes[i].label
+			}
+		}
+		for _, class := range classes {
+			items = append(items, legendItem{
+				Label:  class.label,
+				Symbol: layerSymbol(layer, withOpacity(class.color, opacity)),
+			})
+		}
+	}
+
+	return items, nil
+}
+
+func layerSymbol(layer mapboxLayer, c legendColor) legendSymbol {
+	switch layer.Type {
+	case legendTypeLine:
+		return legendSymbol{
+			Type:        legendTypeLine,
+			Stroke:      &c,
+			StrokeWidth: evaluateNumber(layer.Paint["line-width"], defaultLineWidth),
+		}
+	case legendTypeCircle:
+		symbol := legendSymbol{
+			Type:   legendTypeCircle,
+			Fill:   &c,
+			Radius: evaluateNumber(layer.Paint["circle-radius"], defaultCircleRadius),
+		}
+		if width := evaluateNumber(layer.Paint["circle-stroke-width"], 0); width > 0 {
+			stroke := withOpacity(evaluateColor(layer.Paint["circle-stroke-color"], defaultColor),
+				evaluateNumber(layer.Paint["circle-stroke-opacity"], 1))
+			symbol.Stroke = &stroke
+			symbol.StrokeWidth = width
+		}
+
+		return symbol
+	default:
+		symbol := legendSymbol{Type: legendTypeFill, Fill: &c}
+		if outline, ok := layer.Paint["fill-outline-color"]; ok {
+			stroke := evaluateColor(outline, c)
+			symbol.Stroke = &stroke
+			symbol.StrokeWidth = 1
+		}
+
+		return symbol
+	}
+}
+
+// layerLabel derives a human-friendly label from the layer ID and (when present) its filter
+func layerLabel(layer mapboxLayer) string {
+	label := strings.TrimSpace(strings.NewReplacer("_", " ", "-", " ").Replace(layer.ID))
+	if first, size := utf8.DecodeRuneInString(label); first != utf8.RuneError {
+		label = string(unicode.ToUpper(first)) + label[size:]
+	}
+	if values := filterValues(layer.Filter); len(values) > 0 {
+		label += " (" + strings.Join(values, ", ") + ")"
+	}
+
+	return label
+}
+
+// filterValues returns the values a layer is filtered on when the filter is a simple
+// comparison (==, in, match) or a combination (all) of those.
+func filterValues(filter any) []string {
+	expr, ok := filter.([]any)
+	if !ok || len(expr) < 2 {
+		return nil
+	}
+	op, _ := expr[0].(string)
+	if op != "all" && len(expr) < 3 {
+		return nil
+	}
+	switch op {
+	case "all":
+		var values []string
+		for _, sub := range expr[1:] {
+			values = append(values, filterValues(sub)...)
+		}
+
+		return values
+	case "==":
+		if key, ok := expr[1].(string); ok && strings.HasPrefix(key, "$") {
+			return nil // filter on geometry type or id, not a class
+		}
+
+		return []string{formatValue(expr[2])}
+	case "in":
+		// legacy syntax: ["in", key, value1, value2, ...], expression syntax: ["in", ["get", key], ["literal", [values]]]
+		if literal, ok := expr[2].([]any); ok && len(literal) == 2 && literal[0] == "literal" {
+			return formatValues(literal[1])
+		}
+		values := make([]string, 0, len(expr)-2)
+		for _, v := range expr[2:] {
+			values = append(values, formatValue(v))
+		}
+
+		return values
+	case "match":
+		// ["match", ["get", key], [values], true, false]
+		if len(expr) == 5 && expr[3] == true {
+			return formatValues(expr[2])
+		}
+	}
+
+	return nil
+}
+
+// colorClasses returns the feature property and the classes of a color depending on this property,
+// or nil when the color doesn't depend on a feature property. The property is empty when the
+// color depends on an expression (other than a plain property lookup).
+func colorClasses(value any) (string, []legendClass) {
+	var property string
+	var classes []legendClass
+	switch v := value.(type) {
+	case map[string]any:
+		// legacy categorical function: {"property": key, "type": "categorical", "stops": [[value, color], ...]}
+		if _, ok := v["property"]; !ok || v["type"] != "categorical" {
+			return "", nil
+		}
+		property, _ = v["property"].(string)
+		stops, _ := v["stops"].([]any)
+		for _, stop := range stops {
+			if s, ok := stop.([]any); ok && len(s) == 2 {
+				classes = append(classes, legendClass{formatValue(s[0]), evaluateColor(s[1], defaultColor), []any{s[0]}})
+			}
+		}
+		if fallback, ok := v["default"]; ok {
+			classes = append(classes, legendClass{legendOtherLabel, evaluateColor(fallback, defaultColor), nil})
+		}
+	case []any:
+		// ["match", input, value1, color1, value2, color2, ..., fallback]
+		if len(v) < 5 || v[0] != "match" {
+			return "", nil
+		}
+		if input, ok := v[1].([]any); ok && len(input) == 2 && input[0] == "get" {
+			property, _ = input[1].(string)
+		}
+		for i := 2; i+1 < len(v); i += 2 {
+			values, ok := v[i].([]any)
+			if !ok {
+				values = []any{v[i]}
+			}
+			classes = append(classes, legendClass{strings.Join(formatValues(values), ", "), evaluateColor(v[i+1], defaultColor), values})
+		}
+		classes = append(classes, legendClass{legendOtherLabel, evaluateColor(v[len(v)-1], defaultColor), nil})
+	}
+
+	return property, classes
+}
+
+// evaluate returns the value of a paint property. Zoom dependent values are evaluated
+// halfway their zoom levels, other data driven values evaluate to their default (fallback).
+func evaluate(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		// legacy function: {"stops": [[zoom, value], ...]}
+		if stops, ok := v["stops"].([]any); ok && len(stops) > 0 && v["property"] == nil {
+			if stop, ok := stops[len(stops)/2].([]any); ok && len(stop) == 2 {
+				return evaluate(stop[1])
+			}
+		}
+
+		return evaluate(v["default"])
+	case []any:
+		return evaluateExpression(v)
+	default:
+		return v
+	}
+}
+
+func evaluateExpression(expr []any) any {
+	if len(expr) < 2 {
+		return nil
+	}
+	op, _ := expr[0].(string)
+	switch op {
+	case "literal", "to-color", "to-number":
+		return evaluate(expr[1])
+	case "rgb", "rgba":
+		return expressionColor(expr[1:])
+	case "coalesce":
+		for _, sub := range expr[1:] {
+			if result := evaluate(sub); result != nil {
+				return result
+			}
+		}
+	case "interpolate", "interpolate-hcl", "interpolate-lab":
+		// ["interpolate", interpolation, input, stop1, output1, stop2, output2, ...]
+		if outputs := everyOther(expr, 4); len(outputs) > 0 {
+			return evaluate(outputs[len(outputs)/2])
+		}
+	case "step":
+		// ["step", input, output0, stop1, output1, stop2, output2, ...]
+		if outputs := everyOther(expr, 2); len(outputs) > 0 {
+			return evaluate(outputs[len(outputs)/2])
+		}
+	case "match", "case":
+		return evaluate(expr[len(expr)-1])
+	}
+
+	return nil
+}
+
+func evaluateColor(value any, fallback legendColor) legendColor {
+	switch v := evaluate(value).(type) {
+	case string:
+		if c, ok := parseColor(v); ok {
+			return c
+		}
+	case legendColor:
+		return v
+	}
+
+	return fallback
+}
+
+func evaluateNumber(value any, fallback float64) float64 {
+	if v, ok := evaluate(value).(float64); ok {
+		return v
+	}
+
+	return fallback
+}
+
+func expressionColor(args []any) any {
+	if len(args) < 3 {
+		return nil
+	}
+	var components [4]float64
+	components[3] = 1
+	for i := 0; i < len(args) && i < 4; i++ {
+		n, ok := evaluate(args[i]).(float64)
+		if !ok {
+			return nil
+		}
+		components[i] = n
+	}
+
+	return legendColor{clampByte(components[0]), clampByte(components[1]), clampByte(components[2]),
+		clampByte(components[3] * 255)}
+}
+
+// parseColor parses CSS colors (hex, rgb(a), hsl(a) or named) as supported by Mapbox styles
+func parseColor(value string) (legendColor, bool) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if c, ok := namedColors[value]; ok {
+		return c, true
+	}
+	if strings.HasPrefix(value, "#") {
+		return parseHexColor(value[1:])
+	}
+	open, closing := strings.Index(value, "("), strings.LastIndex(value, ")")
+	if open < 0 || closing < open {
+		return legendColor{}, false
+	}
+	fn := value[:open]
+	args := strings.Split(value[open+1:closing], ",")
+	if len(args) < 3 || len(args) > 4 {
+		return legendColor{}, false
+	}
+	var components [4]float64
+	components[3] = 1
+	for i, arg := range args {
+		arg = strings.TrimSpace(arg)
+		percentage := strings.HasSuffix(arg, "%")
+		n, err := strconv.ParseFloat(strings.TrimSuffix(arg, "%"), 64)
+		if err != nil {
+			return legendColor{}, false
+		}
+		if percentage {
+			n /= 100
+			if i < 3 && (fn == "rgb" || fn == "rgba") {
+				n *= 255
+			}
+		}
+		components[i] = n
+	}
+	alpha := clampByte(components[3] * 255)
+	switch fn {
+	case "rgb", "rgba":
+		return legendColor{clampByte(components[0]), clampByte(components[1]), clampByte(components[2]), alpha}, true
+	case "hsl", "hsla":
+		r, g, b := hslToRGB(components[0], components[1], components[2])
+		return legendColor{clampByte(r * 255), clampByte(g * 255), clampByte(b * 255), alpha}, true
+	}
+
+	return legendColor{}, false
+}
+
+func parseHexColor(hex string) (legendColor, bool) {
+	if len(hex) == 3 || len(hex) == 4 {
+		// expand short notation
+		var expanded strings.Builder
+		for _, r := range hex {
+			expanded.WriteRune(r)
+			expanded.WriteRune(r)
+		}
+		hex = expanded.String()
+	}
+	if len(hex) == 6 {
+		hex += "ff"
+	}
+	if len(hex) != 8 {
+		return legendColor{}, false
+	}
+	n, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return legendColor{}, false
+	}
+
+	return legendColor{uint8(n >> 24), uint8(n >> 16), uint8(n >> 8), uint8(n)}, true
+}
+
+// hslToRGB converts hue (degrees), saturation and lightness (0-1) to RGB (0-1)
+func hslToRGB(h, s, l float64) (float64, float64, float64) {
+	h = math.Mod(math.Mod(h, 360)+360, 360) / 360
+	if s == 0 {
+		return l, l, l
+	}
+	var q float64
+	if l < 0.5 {
+		q = l * (1 + s)
+	} else {
+		q = l + s - l*s
+	}
+	p := 2*l - q
+	hueToRGB := func(t float64) float64 {
+		t = math.Mod(t+1, 1)
+		switch {
+		case t < 1.0/6:
+			return p + (q-p)*6*t
+		case t < 1.0/2:
+			return q
+		case t < 2.0/3:
+			return p + (q-p)*(2.0/3-t)*6
+		default:
+			return p
+		}
+	}
+
+	return hueToRGB(h + 1.0/3), hueToRGB(h), hueToRGB(h - 1.0/3)
+}
+
+func withOpacity(c legendColor, opacity float64) legendColor {
+	c.A = clampByte(float64(c.A) * opacity)
+
+	return c
+}
+
+func clampByte(v float64) uint8 {
+	return uint8(math.Round(math.Max(0, math.Min(255, v))))
+}
+
+// everyOther returns every other element of the given slice, starting at the given index
+func everyOther(values []any, start int) []any {
+	var result []any
+	for i := start; i < len(values); i += 2 {
+		result = append(result, values[i])
+	}
+
+	return result
+}
+
+func formatValues(value any) []string {
+	if values, ok := value.([]any); ok {
+		result := make([]string, 0, len(values))
+		for _, v := range values {
+			result = append(result, formatValue(v))
+		}
+
+		return result
+	}
+
+	return []string{formatValue(value)}
+}
+
+func formatValue(value any) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// generateLegendImage generates a legend (items and PNG) from the given (rendered) Mapbox style
+func generateLegendImage(stylesheet []byte) ([]legendItem, []byte, error) {
+	items, err := generateLegend(stylesheet)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(items) == 0 {
+		return nil, nil, errors.New("style doesn't contain layers which can be shown in a legend")
+	}
+	legend, err := renderLegendImage(items)
+
+	return items, legend, err
+}
@@ -0,0 +1,128 @@
+package styles
+
+import (
+	"bytes"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateLegend(t *testing.T) {
+	stylesheet := `{
+  "version": 8,
+  "sources": {"bgt": {"type": "vector"}},
+  "layers": [
+    {"id": "background", "type": "background", "paint": {"background-color": "#fff"}},
+    {"id": "hidden", "type": "fill", "source": "bgt", "layout": {"visibility": "none"}},
+    {"id": "water_areas", "type": "fill", "source": "bgt", "filter": ["==", "class", "water"],
+      "paint": {"fill-color": "#0000ff", "fill-opacity": 0.5, "fill-outline-color": "navy"}},
+    {"id": "roads", "type": "line", "source": "bgt",
+      "paint": {
+        "line-color": ["match", ["get", "type"], "highway", "rgb(255, 0, 0)", ["local", "street"], "hsl(0, 0%, 50%)", "black"],
+        "line-width": ["interpolate", ["linear"], ["zoom"], 5, 1, 10, 3, 15, 6]
+      }},
+    {"id": "buildings", "type": "fill", "source": "bgt",
+      "paint": {"fill-color": {"property": "use", "type": "categorical", "stops": [["living", "#f00"], ["office", "#00f"]]}}},
+    {"id": "poi", "type": "circle", "source": "bgt",
+      "paint": {"circle-radius": {"stops": [[5, 2], [10, 4]]}, "circle-color": "rgba(0, 128, 0, 0.5)", "circle-stroke-width": 1}},
+    {"id": "labels", "type": "symbol", "source": "bgt"}
+  ]
+}`
+	items, err := generateLegend([]byte(stylesheet))
+	require.NoError(t, err)
+
+	labels := make([]string, 0, len(items))
+	for _, item := range items {
+		labels = append(labels, item.Label)
+	}
+	assert.Equal(t, []string{
+		"Water areas (water)",
+		"Roads: highway",
+		"Roads: local, street",
+		"Roads: Other",
+		"Buildings: living",
+		"Buildings: office",
+		"Poi",
+	}, labels)
+
+	water := items[0].Symbol
+	assert.Equal(t, legendTypeFill, water.Type)
+	assert.Equal(t, "rgba(0, 0, 255, 0.5)", water.Fill.CSS())
+	assert.Equal(t, "rgba(0, 0, 128, 1)", water.Stroke.CSS())
+
+	roads := items[2].Symbol
+	assert.Equal(t, legendTypeLine, roads.Type)
+	assert.Equal(t, "rgba(128, 128, 128, 1)", roads.Stroke.CSS())
+	assert.InDelta(t, 3.0, roads.StrokeWidth, 0)
+
+	assert.Equal(t, "rgba(0, 0, 255, 1)", items[5].Symbol.Fill.CSS())
+
+	poi := items[6].Symbol
+	assert.Equal(t, legendTypeCircle, poi.Type)
+	assert.InDelta(t, 4.0, poi.Radius, 0)
+	assert.Equal(t, "rgba(0, 128, 0, 0.5)", poi.Fill.CSS())
+	assert.Equal(t, "rgba(0, 0, 0, 1)", poi.Stroke.CSS())
+
+	_, err = generateLegend([]byte("not a mapbox style"))
+	assert.Error(t, err)
+}
+
+func TestParseColor(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+		ok    bool
+	}{
+		{value: "#f00", want: "rgba(255, 0, 0, 1)", ok: true},
+		{value: "#00ff0080", want: "rgba(0, 255, 0, 0.5)", ok: true},
+		{value: "rgb(10, 20, 30)", want: "rgba(10, 20, 30, 1)", ok: true},
+		{value: "rgba(10, 20, 30, 0.25)", want: "rgba(10, 20, 30, 0.25)", ok: true},
+		{value: "hsl(120, 100%, 25%)", want: "rgba(0, 128, 0, 1)", ok: true},
+		{value: "Navy", want: "rgba(0, 0, 128, 1)", ok: true},
+		{value: "#12", ok: false},
+		{value: "rgb(1, 2)", ok: false},
+		{value: "unknown", ok: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			c, ok := parseColor(tt.value)
+			assert.Equal(t, tt.ok, ok)
+			if tt.ok {
+				assert.Equal(t, tt.want, c.CSS())
+			}
+		})
+	}
+}
+
+func TestRenderLegendImage(t *testing.T) {
+	red := legendColor{255, 0, 0, 255}
+	items := []legendItem{
+		{Label: "Gebäude", Symbol: legendSymbol{Type: legendTypeFill, Fill: &red}},
+		{Label: "Roads", Symbol: legendSymbol{Type: legendTypeLine, Stroke: &red, StrokeWidth: 2}},
+		{Label: "Points of interest", Symbol: legendSymbol{Type: legendTypeCircle, Fill: &red, Radius: 5}},
+	}
+	result, err := renderLegendImage(items)
+	require.NoError(t, err)
+
+	img, err := png.Decode(bytes.NewReader(result))
+	require.NoError(t, err)
+	assert.Equal(t, 2*legendPadding+len(items)*legendRowHeight, img.Bounds().Dy())
+	assert.Equal(t, 3*legendPadding+legendSymbolWidth+len("Points of interest")*(glyphWidth+glyphSpacing), img.Bounds().Dx())
+
+	// center of the fill symbol in the first row
+	r, g, b, _ := img.At(legendPadding+legendSymbolWidth/2, legendPadding+legendRowHeight/2).RGBA()
+	assert.Equal(t, []uint32{0xffff, 0, 0}, []uint32{r, g, b})
+}
+
+func TestToASCII(t *testing.T) {
+	assert.Equal(t, "Gebaude cafe ?", toASCII("Gebäude café €"))
+}
+
+func TestLayerLabel(t *testing.T) {
+	assert.Equal(t, "Water areas", layerLabel(mapboxLayer{ID: "water_areas"}))
+	assert.Equal(t, "Éénrichtingsverkeer", layerLabel(mapboxLayer{ID: "éénrichtingsverkeer"}))
+	assert.Equal(t, "Ölpipeline", layerLabel(mapboxLayer{ID: "ölpipeline"}))
+	assert.Empty(t, layerLabel(mapboxLayer{ID: "_"}))
+}
@@ -0,0 +1,154 @@
+package styles
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"math"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+const (
+	legendPadding      = 6
+	legendRowHeight    = 20
+	legendSymbolWidth  = 24
+	legendSymbolHeight = 12
+	legendMaxLabel     = 60 // max number of characters of a label
+
+	glyphWidth   = 5
+	glyphHeight  = 8
+	glyphSpacing = 1
+)
+
+var (
+	legendBackground = color.NRGBA{R: 255, G: 255, B: 255, A: 255}
+	legendText       = color.NRGBA{R: 33, G: 37, B: 41, A: 255}
+)
+
+// renderLegendImage renders the given legend as PNG, an entry per row with the symbol on
+// the left and the label on the right. Text is rendered using a built-in bitmap font, since
+// only ASCII characters are supported, diacritics are removed from labels.
+func renderLegendImage(items []legendItem) ([]byte, error) {
+	labels := make([]string, 0, len(items))
+	maxLabel := 0
+	for _, item := range items {
+		label := toASCII(item.Label)
+		if len(label) > legendMaxLabel {
+			label = label[:legendMaxLabel-3] + "..."
+		}
+		labels = append(labels, label)
+		maxLabel = max(maxLabel, len(label))
+	}
+
+	width := 3*legendPadding + legendSymbolWidth + maxLabel*(glyphWidth+glyphSpacing)
+	height := 2*legendPadding + len(items)*legendRowHeight
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(img, img.Bounds(), image.NewUniform(legendBackground), image.Point{}, draw.Src)
+
+	for i, item := range items {
+		top := legendPadding + i*legendRowHeight
+		drawSymbol(img, image.Rect(legendPadding, top, legendPadding+legendSymbolWidth, top+legendRowHeight), item.Symbol)
+		drawText(img, 2*legendPadding+legendSymbolWidth, top+(legendRowHeight-glyphHeight)/2, labels[i], legendText)
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func drawSymbol(img *image.NRGBA, cell image.Rectangle, symbol legendSymbol) {
+	center := image.Pt((cell.Min.X+cell.Max.X)/2, (cell.Min.Y+cell.Max.Y)/2)
+	switch symbol.Type {
+	case legendTypeLine:
+		if symbol.Stroke == nil {
+			return
+		}
+		lineWidth := clampInt(symbol.StrokeWidth, 1, legendRowHeight-4)
+		top := center.Y - lineWidth/2
+		fillRect(img, image.Rect(cell.Min.X, top, cell.Max.X, top+lineWidth), *symbol.Stroke)
+	case legendTypeCircle:
+		radius := float64(clampInt(symbol.Radius, 2, legendSymbolHeight/2+2))
+		strokeWidth := 0.0
+		if symbol.Stroke != nil {
+			strokeWidth = float64(clampInt(symbol.StrokeWidth, 1, 3))
+		}
+		for y := center.Y - int(radius) - 1; y <= center.Y+int(radius)+1; y++ {
+			for x := center.X - int(radius) - 1; x <= center.X+int(radius)+1; x++ {
+				d := math.Hypot(float64(x-center.X)+0.5, float64(y-center.Y)+0.5)
+				switch {
+				case d > radius:
+					continue
+				case d > radius-strokeWidth:
+					blend(img, x, y, *symbol.Stroke)
+				case symbol.Fill != nil:
+					blend(img, x, y, *symbol.Fill)
+				}
+			}
+		}
+	default:
+		rect := image.Rect(cell.Min.X, center.Y-legendSymbolHeight/2, cell.Max.X, center.Y+legendSymbolHeight/2)
+		if symbol.Fill != nil {
+			fillRect(img, rect, *symbol.Fill)
+		}
+		if symbol.Stroke != nil {
+			w := clampInt(symbol.StrokeWidth, 1, 3)
+			fillRect(img, image.Rect(rect.Min.X, rect.Min.Y, rect.Max.X, rect.Min.Y+w), *symbol.Stroke)
+			fillRect(img, image.Rect(rect.Min.X, rect.Max.Y-w, rect.Max.X, rect.Max.Y), *symbol.Stroke)
+			fillRect(img, image.Rect(rect.Min.X, rect.Min.Y+w, rect.Min.X+w, rect.Max.Y-w), *symbol.Stroke)
+			fillRect(img, image.Rect(rect.Max.X-w, rect.Min.Y+w, rect.Max.X, rect.Max.Y-w), *symbol.Stroke)
+		}
+	}
+}
+
+func drawText(img *image.NRGBA, x, y int, text string, c color.NRGBA) {
+	for _, r := range text {
+		if r >= firstGlyph && int(r-firstGlyph) < len(glyphs) {
+			glyph := glyphs[r-firstGlyph]
+			for col := range glyphWidth {
+				for row := range glyphHeight {
+					if glyph[col]&(1<<row) != 0 {
+						img.SetNRGBA(x+col, y+row, c)
+					}
+				}
+			}
+		}
+		x += glyphWidth + glyphSpacing
+	}
+}
+
+func fillRect(img *image.NRGBA, rect image.Rectangle, c legendColor) {
+	draw.Draw(img, rect, image.NewUniform(color.NRGBA(c)), image.Point{}, draw.Over)
+}
+
+func blend(img *image.NRGBA, x, y int, c legendColor) {
+	fillRect(img, image.Rect(x, y, x+1, y+1), c)
+}
+
+func clampInt(v float64, minimum, maximum int) int {
+	return max(minimum, min(maximum, int(math.Round(v))))
+}
+
+// toASCII removes diacritics and replaces other non-ASCII characters with '?'
+func toASCII(s string) string {
+	var result strings.Builder
+	for _, r := range norm.NFD.String(s) {
+		switch {
+		case unicode.Is(unicode.Mn, r):
+			continue
+		case r < firstGlyph || int(r-firstGlyph) >= len(glyphs):
+			result.WriteRune('?')
+		default:
+			result.WriteRune(r)
+		}
+	}
+
+	return result.String()
+}
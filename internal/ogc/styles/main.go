@@ -1,15 +1,18 @@
 package styles
 
 import (
+	"fmt"
 	"log"
 	"net/http"
 	"net/url"
+	"path/filepath"
 	"slices"
 	"strings"
+	"sync"
 
 	"github.com/PDOK/gokoala/config"
 	"github.com/PDOK/gokoala/internal/engine"
-	"github.com/PDOK/gokoala/internal/engine/util"
+	"github.com/PDOK/gokoala/internal/ogc/tiles"
 	"github.com/go-chi/chi/v5"
 )
 
@@ -38,8 +41,14 @@ type stylesTemplateData struct {
 	// All supported projections for this dataset
 	SupportedProjections []config.SupportedSrs
 
-	// All supported projections by GoKoala (for tiles)
-	AllProjections map[string]any
+	// Styles created through the API, in addition to the styles in the config
+	ManagedStyles []config.Style
+
+	// IDs of styles with a generated legend
+	GeneratedLegends map[string]bool
+
+	// Formats (native or converted) in which each style is available, by style ID
+	Stylesheets map[string][]stylesheetFormat
 }
 
 type stylesMetadataTemplateData struct {
@@ -48,42 +57,115 @@ type stylesMetadataTemplateData struct {
 
 	// Projection used by this style
 	Projection string
+
+	// Whether a legend is generated for this style
+	GeneratedLegend bool
+
+	// Formats (native or converted) in which this style is available
+	Stylesheets []stylesheetFormat
+}
+
+// stylesheetFormat format in which a style is available
+type stylesheetFormat struct {
+	Format string
+
+	// false when the stylesheet is converted from another format
+	Native bool
+}
+
+type legendTemplateData struct {
+	// Metadata about this style
+	Metadata config.Style
+
+	// Projection used by this style
+	Projection string
+
+	// Entries in the legend
+	Items []legendItem
+}
+
+type stylePerFormatTemplateData struct {
+	// Projection used by this style
+	Projection string
+
+	// Zoom levels supported in this projection
+	ZoomLevelRange config.ZoomLevelRange
 }
 
 type Styles struct {
 	engine                *engine.Engine
 	localResourcesHandler http.Handler
+	supportedProjections  []config.SupportedSrs
+
+	// only set when styles can be managed through the API
+	storage Storage
+
+	// styles created through the API, the config itself is never changed at runtime
+	managed []config.Style
+
+	// zoom levels relative to scale denominators, per TileMatrixSet ID
+	zoomScales map[string]zoomScale
+
+	// IDs of styles defined in the config, these can't be changed through the API
+	readOnly map[string]bool
+
+	// legends (PNG) generated from the Mapbox styles, per style instance (style and projection)
+	legends map[string][]byte
+
+	// stylesheets converted from another format, per style instance and format (e.g. "default__webmercatorquad.sld10")
+	converted map[string][]byte
+
+	// guards the managed styles, the legends and converted stylesheets, since these may change at runtime
+	mu sync.RWMutex
 }
 
 func NewStyles(e *engine.Engine) *Styles {
-	// default style must be the first entry in supported styles
-	if e.Config.OgcAPI.Styles.Default != e.Config.OgcAPI.Styles.SupportedStyles[0].ID {
-		log.Fatalf("default style must be first entry in supported styles. '%s' does not match '%s'",
-			e.Config.OgcAPI.Styles.SupportedStyles[0].ID, e.Config.OgcAPI.Styles.Default)
+	readOnly := make(map[string]bool)
+	for _, style := range e.Config.OgcAPI.Styles.SupportedStyles {
+		readOnly[style.ID] = true
+	}
+	var storage Storage
+	var managed []config.Style
+	if e.Config.OgcAPI.Styles.Manage != nil {
+		var err error
+		if storage, managed, err = loadManagedStyles(e); err != nil {
+			log.Fatalf("failed to load styles managed through the API: %v", err)
+		}
 	}
 
-	allProjections := util.Cast(config.AllTileProjections)
 	supportedProjections := e.Config.OgcAPI.Tiles.GetProjections()
 	if len(supportedProjections) == 0 {
 		log.Fatalf("failed to setup OGC API Styles, no supported projections (SRS) found in OGC API Tiles")
 	}
-	defaultProjection = strings.ToLower(config.AllTileProjections[supportedProjections[0].Srs])
-
-	e.RenderTemplatesWithParams(stylesPath,
-		&stylesTemplateData{defaultProjection, supportedProjections, allProjections},
-		stylesBreadcrumbs,
-		engine.NewTemplateKey(templatesDir+"styles.go.json"),
-		engine.NewTemplateKey(templatesDir+"styles.go.html"))
-
-	renderStylesPerProjection(e, supportedProjections)
+	defaultProjection = strings.ToLower(supportedProjections[0].GetTileMatrixSetID())
+	zoomScales, err := newZoomScales(e.Config.OgcAPI.Tiles)
+	if err != nil {
+		log.Fatalf("failed to setup OGC API Styles: %v", err)
+	}
 
 	styles := &Styles{
-		engine: e,
+		engine:               e,
+		supportedProjections: supportedProjections,
+		zoomScales:           zoomScales,
+		storage:              storage,
+		managed:              managed,
+		readOnly:             readOnly,
+		legends:              make(map[string][]byte),
+		converted:            make(map[string][]byte),
 	}
+	for _, style := range styles.SupportedStyles() {
+		styles.renderStylePerProjection(e.RenderTemplatesWithParams, style)
+	}
+	styles.renderStyles(e.RenderTemplatesWithParams)
 	e.Router.Get(stylesPath, styles.Styles())
 	e.Router.Get(stylesPath+"/{style}", styles.Style())
 	e.Router.Get(stylesPath+"/{style}/metadata", styles.Metadata())
 	e.Router.Get(stylesPath+"/{style}/legend", styles.Legend())
+	if storage != nil {
+		e.Router.Post(stylesPath, styles.CreateStyle())
+		e.Router.Put(stylesPath+"/{style}", styles.ReplaceStyle())
+		e.Router.Delete(stylesPath+"/{style}", styles.DeleteStyle())
+	}
 
 	if res := e.Config.Resources; e.Config.Resources != nil {
 		if res != nil && res.Directory != nil && *res.Directory != "" {
@@ -106,6 +188,12 @@ func (s *Styles) Styles() http.HandlerFunc {
 func (s *Styles) Style() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		style, styleID := parseStyleParam(r)
+		supportedStyle, ok := s.findStyle(styleID)
+		if !ok {
+			engine.RenderProblem(engine.ProblemNotFound, w, "unknown style "+styleID)
+
+			return
+		}
 		styleFormat := s.engine.CN.NegotiateFormat(r)
 		var key engine.TemplateKey
 		if styleFormat == engine.FormatHTML {
@@ -119,6 +207,11 @@ func (s *Styles) Style() http.HandlerFunc {
 				styleFormat = engine.FormatMapboxStyle
 				instanceName = style + "." + engine.FormatMapboxStyle
 			}
+			if !slices.ContainsFunc(supportedStyle.Formats, func(f config.StyleFormat) bool { return f.Format == styleFormat }) {
+				s.serveConvertedStylesheet(w, r, instanceName, styleID, styleFormat)
+
+				return
+			}
 			key = engine.TemplateKey{
 				Name:         styleID + s.engine.CN.GetStyleFormatExtension(styleFormat),
 				Directory:    s.engine.Config.OgcAPI.Styles.StylesDir,
@@ -133,7 +226,12 @@ func (s *Styles) Style() http.HandlerFunc {
 
 func (s *Styles) Metadata() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		style, _ := parseStyleParam(r)
+		style, styleID := parseStyleParam(r)
+		if _, ok := s.findStyle(styleID); !ok {
+			engine.RenderProblem(engine.ProblemNotFound, w, "unknown style "+styleID)
+
+			return
+		}
 		key := engine.NewTemplateKey(
 			templatesDir+"styleMetadata.go."+s.engine.CN.NegotiateFormat(r),
 			engine.WithInstanceName(style),
@@ -142,26 +240,40 @@ func (s *Styles) Metadata() http.HandlerFunc {
 	}
 }
 
+// Legend serves the legend of a style. By default as PNG: the configured legend or otherwise the legend generated
+// from the Mapbox style. Generated legends are also available as JSON and HTML (using ?f=json or ?f=html).
 func (s *Styles) Legend() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		_, styleID := parseStyleParam(r)
+		style, styleID := parseStyleParam(r)
+		supportedStyle, ok := s.findStyle(styleID)
+		if !ok {
+			engine.RenderProblem(engine.ProblemNotFound, w, "unknown style "+styleID)
 
-		// search matching legend when configured
-		var legend string
-		for _, supportedStyle := range s.engine.Config.OgcAPI.Styles.SupportedStyles {
-			if supportedStyle.ID == styleID && supportedStyle.Legend != nil {
-				legend = *supportedStyle.Legend
+			return
+		}
 
-				break
+		// explicitly check format param, since legends are served as PNG by default
+		if format := r.URL.Query().Get(engine.FormatParam); format == engine.FormatJSON || format == engine.FormatHTML {
+			if _, ok = s.generatedLegend(style); !ok {
+				engine.RenderProblem(engine.ProblemNotFound, w, "no legend generated for style "+styleID)
+
+				return
 			}
+			key := engine.NewTemplateKey(templatesDir+"legend.go."+s.engine.CN.NegotiateFormat(r),
+				engine.WithInstanceName(style),
+				s.engine.WithNegotiatedLanguage(w, r))
+			s.engine.Serve(w, r, engine.ServeTemplate(key))
+
+			return
 		}
-		if s.engine.Config.Resources == nil {
-			engine.RenderProblem(engine.ProblemNotFound, w, "no legends configured")
+		if supportedStyle.Legend == nil {
+			s.serveGeneratedLegend(w, r, style, styleID)
 
 			return
 		}
-		if legend == "" {
-			engine.RenderProblem(engine.ProblemNotFound, w, "no legend configured for style "+styleID)
+		legend := *supportedStyle.Legend
+		if s.engine.Config.Resources == nil {
+			engine.RenderProblem(engine.ProblemNotFound, w, "no legends configured")
 
 			return
 		}
@@ -181,6 +293,82 @@ func (s *Styles) Legend() http.HandlerFunc {
 	}
 }
 
+func (s *Styles) serveGeneratedLegend(w http.ResponseWriter, r *http.Request, style string, styleID string) {
+	legend, ok := s.generatedLegend(style)
+	if !ok {
+		engine.RenderProblem(engine.ProblemNotFound, w, "no legend configured or generated for style "+styleID)
+
+		return
+	}
+	s.engine.Serve(w, r, engine.ServePreRenderedOutput(legend), engine.ServeContentType(engine.MediaTypePNG),
+		engine.ServeValidation(true, false /* binary */))
+}
+
+// serveConvertedStylesheet serves a stylesheet converted from another format, since the style isn't natively available in the requested format
+func (s *Styles) serveConvertedStylesheet(w http.ResponseWriter, r *http.Request, instanceName string, styleID string, styleFormat string) {
+	s.mu.RLock()
+	stylesheet, ok := s.converted[instanceName]
+	s.mu.RUnlock()
+	if !ok {
+		engine.RenderProblem(engine.ProblemNotFound, w, "style "+styleID+" isn't available in format "+styleFormat)
+
+		return
+	}
+	s.engine.Serve(w, r, engine.ServePreRenderedOutput(stylesheet),
+		engine.ServeContentType(s.engine.CN.GetStyleFormatMediaType(styleFormat)))
+}
+
+// generatedLegend returns the legend (PNG) generated for the given style instance, if any
+func (s *Styles) generatedLegend(style string) ([]byte, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	legend, ok := s.legends[style]
+
+	return legend, ok
+}
+
+// SupportedStyles returns all styles: the styles in the config and the styles created through the API
+func (s *Styles) SupportedStyles() []config.Style {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return slices.Concat(s.engine.Config.OgcAPI.Styles.SupportedStyles, s.managed)
+}
+
+// HasStyle true when a style with the given ID exists
+func (s *Styles) HasStyle(styleID string) bool {
+	_, ok := s.findStyle(styleID)
+
+	return ok
+}
+
+// findStyle returns the supported style with the given ID
+func (s *Styles) findStyle(styleID string) (config.Style, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.getStyle(styleID)
+}
+
+// newZoomScales derives the scale denominator of zoom level 0 from the TileMatrixSet of each projection
+func newZoomScales(tilesConfig *config.OgcAPITiles) (map[string]zoomScale, error) {
+	tileMatrixSets, err := tiles.LoadTileMatrixSets(tilesConfig)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]zoomScale)
+	for _, projection := range tilesConfig.GetProjections() {
+		id := projection.GetTileMatrixSetID()
+		scale, ok := tileMatrixSets[id].ScaleDenominator(0)
+		if !ok {
+			return nil, fmt.Errorf("tileMatrixSet '%s' has no zoom level 0", id)
+		}
+		result[id] = zoomScale(scale)
+	}
+
+	return result, nil
+}
+
 func parseStyleParam(r *http.Request) (style string, styleID string) {
 	style = chi.URLParam(r, "style")
 	styleID = strings.Split(style, projectionDelimiter)[0]
@@ -196,42 +384,179 @@ func parseStyleParam(r *http.Request) (style string, styleID string) {
 	return style, styleID
 }
 
-func renderStylesPerProjection(e *engine.Engine, supportedProjections []config.SupportedSrs) {
-	for _, style := range e.Config.OgcAPI.Styles.SupportedStyles {
-		for _, supportedSrs := range supportedProjections {
-			projection := config.AllTileProjections[supportedSrs.Srs]
-			zoomLevelRange := supportedSrs.ZoomLevelRange
-			styleInstanceID := style.ID + projectionDelimiter + strings.ToLower(projection)
-			styleProjectionBreadcrumb := engine.Breadcrumb{
-				Name: style.Title + " (" + projection + ")",
-				Path: stylesCrumb + styleInstanceID,
+// renderFunc renders templates, either during startup or at runtime (see Engine.RerenderTemplatesWithParams)
+type renderFunc func(urlPath string, params any, breadcrumbs []engine.Breadcrumb, keys ...engine.TemplateKey)
+
+func (s *Styles) renderStyles(render renderFunc) {
+	generatedLegends := make(map[string]bool)
+	for style := range s.legends {
+		generatedLegends[strings.Split(style, projectionDelimiter)[0]] = true
+	}
+	stylesheets := make(map[string][]stylesheetFormat)
+	for _, styles := range [][]config.Style{s.engine.Config.OgcAPI.Styles.SupportedStyles, s.managed} {
+		for _, style := range styles {
+			stylesheets[style.ID] = s.stylesheetFormats(style)
+		}
+	}
+	render(stylesPath,
+		&stylesTemplateData{defaultProjection, s.supportedProjections, s.managed, generatedLegends, stylesheets},
+		stylesBreadcrumbs,
+		engine.NewTemplateKey(templatesDir+"styles.go.json"),
+		engine.NewTemplateKey(templatesDir+"styles.go.html"))
+}
+
+func (s *Styles) renderStylePerProjection(render renderFunc, style config.Style) {
+	e := s.engine
+	var conversionWarnings []string
+	for _, supportedSrs := range s.supportedProjections {
+		projection := supportedSrs.GetTileMatrixSetID()
+		zoomLevelRange := supportedSrs.ZoomLevelRange
+		styleInstanceID := style.ID + projectionDelimiter + strings.ToLower(projection)
+		styleProjectionBreadcrumb := engine.Breadcrumb{
+			Name: style.Title + " (" + projection + ")",
+			Path: stylesCrumb + styleInstanceID,
+		}
+
+		// Convert stylesheets to the formats in which the style isn't natively available
+		conversionWarnings = append(conversionWarnings, s.convertStylesheets(style, styleInstanceID, supportedSrs)...)
+
+		// Generate legend, when possible
+		generatedLegend := s.renderLegend(render, style, styleInstanceID, supportedSrs, styleProjectionBreadcrumb)
+
+		data := &stylesMetadataTemplateData{style, projection, generatedLegend, s.stylesheetFormats(style)}
+
+		// Render metadata template (JSON)
+		path := stylesPath + "/" + styleInstanceID + "/metadata"
+		render(path, data, nil,
+			engine.NewTemplateKey(templatesDir+"styleMetadata.go.json", engine.WithInstanceName(styleInstanceID)))
+
+		// Render metadata template (HTML)
+		styleMetadataBreadcrumbs := stylesBreadcrumbs
+		styleMetadataBreadcrumbs = append(styleMetadataBreadcrumbs, []engine.Breadcrumb{
+			styleProjectionBreadcrumb,
+			{
+				Name: "Metadata",
+				Path: stylesCrumb + styleInstanceID + "/metadata",
+			},
+		}...)
+		render(path, data, styleMetadataBreadcrumbs,
+			engine.NewTemplateKey(templatesDir+"styleMetadata.go.html", engine.WithInstanceName(styleInstanceID)))
+
+		// Add existing style definitions to rendered templates
+		renderStylePerFormat(e, render, style, styleInstanceID, projection, zoomLevelRange, styleProjectionBreadcrumb)
+	}
+	slices.Sort(conversionWarnings)
+	for _, warning := range slices.Compact(conversionWarnings) {
+		log.Printf("Warning: style %s %s", style.ID, warning)
+	}
+}
+
+// convertStylesheets converts the (native) stylesheet of the given style to the other supported style
+// formats. Returns warnings about constructs that can't be (fully) converted.
+func (s *Styles) convertStylesheets(style config.Style, styleInstanceID string, supportedSrs config.SupportedSrs) []string {
+	cfg := s.engine.Config
+	var warnings []string
+	for _, format := range s.engine.CN.GetSupportedStyleFormats() {
+		delete(s.converted, styleInstanceID+"."+format)
+	}
+	for _, format := range s.engine.CN.GetSupportedStyleFormats() {
+		if len(style.Formats) == 0 || slices.ContainsFunc(style.Formats, func(f config.StyleFormat) bool { return f.Format == format }) {
+			continue
+		}
+		from := style.Formats[0].Format
+		stylesheet, err := renderStylesheetFile(cfg, supportedSrs,
+			filepath.Join(cfg.OgcAPI.Styles.StylesDir, style.ID+s.engine.CN.GetStyleFormatExtension(from)))
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("can't be converted to %s: %v", format, err))
+
+			continue
+		}
+		converted, conversionWarnings, err := convertStylesheet(cfg, supportedSrs,
+			s.zoomScales[supportedSrs.GetTileMatrixSetID()], style, from, format, stylesheet)
+		for _, warning := range conversionWarnings {
+			warnings = append(warnings, fmt.Sprintf("can't be fully converted to %s: %s", format, warning))
+		}
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("can't be converted to %s: %v", format, err))
+
+			continue
+		}
+		s.converted[styleInstanceID+"."+format] = converted
+	}
+
+	return warnings
+}
+
+// stylesheetFormats returns the formats in which the given style is available, natively or converted
+func (s *Styles) stylesheetFormats(style config.Style) []stylesheetFormat {
+	result := make([]stylesheetFormat, 0, len(style.Formats))
+	for _, format := range style.Formats {
+		result = append(result, stylesheetFormat{Format: format.Format, Native: true})
+	}
+	for _, format := range s.engine.CN.GetSupportedStyleFormats() {
+		if slices.ContainsFunc(result, func(f stylesheetFormat) bool { return f.Format == format }) {
+			continue
+		}
+		for converted := range s.converted {
+			if strings.HasPrefix(converted, style.ID+projectionDelimiter) && strings.HasSuffix(converted, "."+format) {
+				result = append(result, stylesheetFormat{Format: format, Native: false})
+
+				break
 			}
-			data := &stylesMetadataTemplateData{style, projection}
-
-			// Render metadata template (JSON)
-			path := stylesPath + "/" + styleInstanceID + "/metadata"
-			e.RenderTemplatesWithParams(path, data, nil,
-				engine.NewTemplateKey(templatesDir+"styleMetadata.go.json", engine.WithInstanceName(styleInstanceID)))
-
-			// Render metadata template (HTML)
-			styleMetadataBreadcrumbs := stylesBreadcrumbs
-			styleMetadataBreadcrumbs = append(styleMetadataBreadcrumbs, []engine.Breadcrumb{
-				styleProjectionBreadcrumb,
-				{
-					Name: "Metadata",
-					Path: stylesCrumb + styleInstanceID + "/metadata",
-				},
-			}...)
-			e.RenderTemplatesWithParams(path, data, styleMetadataBreadcrumbs,
-				engine.NewTemplateKey(templatesDir+"styleMetadata.go.html", engine.WithInstanceName(styleInstanceID)))
-
-			// Add existing style definitions to rendered templates
-			renderStylePerFormat(e, style, styleInstanceID, projection, zoomLevelRange, styleProjectionBreadcrumb)
 		}
 	}
+
+	return result
+}
+
+// renderLegend generates a legend (PNG, JSON and HTML) from the Mapbox style (native or converted). Returns
+// false when the style isn't available as Mapbox style or the legend can't be generated.
+func (s *Styles) renderLegend(render renderFunc, style config.Style, styleInstanceID string,
+	supportedSrs config.SupportedSrs, styleProjectionBreadcrumb engine.Breadcrumb) bool {
+
+	delete(s.legends, styleInstanceID)
+	var stylesheet []byte
+	var err error
+	if slices.ContainsFunc(style.Formats, func(f config.StyleFormat) bool { return f.Format == engine.FormatMapboxStyle }) {
+		stylesheet, err = renderStylesheetFile(s.engine.Config, supportedSrs, filepath.Join(
+			s.engine.Config.OgcAPI.Styles.StylesDir, style.ID+s.engine.CN.GetStyleFormatExtension(engine.FormatMapboxStyle)))
+	} else if converted, ok := s.converted[styleInstanceID+"."+engine.FormatMapboxStyle]; ok {
+		stylesheet = converted
+	} else {
+		return false
+	}
+	var items []legendItem
+	var legend []byte
+	if err == nil {
+		items, legend, err = generateLegendImage(stylesheet)
+	}
+	if err != nil {
+		log.Printf("failed to generate legend for style %s: %v", styleInstanceID, err)
+
+		return false
+	}
+	s.legends[styleInstanceID] = legend
+
+	path := stylesPath + "/" + styleInstanceID + "/legend"
+	data := &legendTemplateData{style, supportedSrs.GetTileMatrixSetID(), items}
+	render(path, data, nil,
+		engine.NewTemplateKey(templatesDir+"legend.go.json", engine.WithInstanceName(styleInstanceID)))
+
+	legendBreadcrumbs := stylesBreadcrumbs
+	legendBreadcrumbs = append(legendBreadcrumbs, []engine.Breadcrumb{
+		styleProjectionBreadcrumb,
+		{
+			Name: "Legend",
+			Path: stylesCrumb + styleInstanceID + "/legend",
+		},
+	}...)
+	render(path, data, legendBreadcrumbs,
+		engine.NewTemplateKey(templatesDir+"legend.go.html", engine.WithInstanceName(styleInstanceID)))
+
+	return true
 }
 
-func renderStylePerFormat(e *engine.Engine, style config.Style, styleInstanceID string,
+func renderStylePerFormat(e *engine.Engine, render renderFunc, style config.Style, styleInstanceID string,
 	projection string, zoomLevelRange config.ZoomLevelRange, styleProjectionBreadcrumb engine.Breadcrumb) {
 
 	for _, styleFormat := range style.Formats {
@@ -245,15 +570,12 @@ func renderStylePerFormat(e *engine.Engine, style config.Style, styleInstanceID
 		path := stylesPath + "/" + styleInstanceID
 
 		// Render template (JSON)
-		e.RenderTemplatesWithParams(path, struct {
-			Projection     string
-			ZoomLevelRange config.ZoomLevelRange
-		}{Projection: projection, ZoomLevelRange: zoomLevelRange}, nil, styleKey)
+		render(path, stylePerFormatTemplateData{Projection: projection, ZoomLevelRange: zoomLevelRange}, nil, styleKey)
 
 		// Render template (HTML)
 		styleBreadCrumbs := stylesBreadcrumbs
 		styleBreadCrumbs = append(styleBreadCrumbs, styleProjectionBreadcrumb)
-		e.RenderTemplatesWithParams(path, style, styleBreadCrumbs,
+		render(path, style, styleBreadCrumbs,
 			engine.NewTemplateKey(templatesDir+"style.go.html", engine.WithInstanceName(styleInstanceID)))
 	}
 }
@@ -275,15 +275,54 @@ func TestStyles_Legend(t *testing.T) {
 			},
 		},
 		{
-			name: "without legend",
+			name: "without legend, generated from Mapbox style",
 			fields: fields{
 				configFile: "internal/ogc/styles/testdata/config_legend.yaml",
 				url:        "http://localhost:8080/styles/alternative__webmercatorquad/legend",
 				style:      "alternative__webmercatorquad",
 			},
+			want: want{
+				statusCode:   http.StatusOK,
+				bodyContains: []string{"\x89PNG"},
+			},
+		},
+		{
+			name: "generated legend as JSON",
+			fields: fields{
+				configFile: "internal/ogc/styles/testdata/config_legend.yaml",
+				url:        "http://localhost:8080/styles/alternative__webmercatorquad/legend?f=json",
+				style:      "alternative__webmercatorquad",
+			},
+			want: want{
+				statusCode: http.StatusOK,
+				bodyContains: []string{
+					"\"label\": \"Testing (Testing)\"",
+					"\"stroke\": \"rgba(170, 170, 170, 1)\"",
+				},
+			},
+		},
+		{
+			name: "generated legend as HTML",
+			fields: fields{
+				configFile: "internal/ogc/styles/testdata/config_legend.yaml",
+				url:        "http://localhost:8080/styles/alternative__webmercatorquad/legend?f=html",
+				style:      "alternative__webmercatorquad",
+			},
+			want: want{
+				statusCode:   http.StatusOK,
+				bodyContains: []string{"<svg", "stroke=\"rgba(170, 170, 170, 1)\""},
+			},
+		},
+		{
+			name: "unknown style",
+			fields: fields{
+				configFile: "internal/ogc/styles/testdata/config_legend.yaml",
+				url:        "http://localhost:8080/styles/unknown__webmercatorquad/legend",
+				style:      "unknown__webmercatorquad",
+			},
 			want: want{
 				statusCode:   http.StatusNotFound,
-				bodyContains: []string{"no legend configured for style alternative"},
+				bodyContains: []string{"unknown style unknown"},
 			},
 		},
 	}
@@ -0,0 +1,289 @@
+package styles
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/PDOK/gokoala/config"
+	"github.com/PDOK/gokoala/internal/engine"
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	// max size of a stylesheet, in bytes
+	maxStylesheetSize = 5 * 1024 * 1024
+
+	validateParam = "validate"
+	validateYes   = "yes"
+	validateNo    = "no"
+	validateOnly  = "only"
+)
+
+// loadManagedStyles loads the styles previously created through the API
+func loadManagedStyles(e *engine.Engine) (Storage, []config.Style, error) {
+	storage, err := newStorage(e.Config.OgcAPI.Styles, e.CN.GetStyleFormatExtension)
+	if err != nil {
+		return nil, nil, err
+	}
+	managedStyles, err := storage.Load()
+	if err != nil {
+		return nil, nil, err
+	}
+	stylesConfig := e.Config.OgcAPI.Styles
+	result := make([]config.Style, 0, len(managedStyles))
+	for _, style := range managedStyles {
+		if slices.ContainsFunc(stylesConfig.SupportedStyles, func(s config.Style) bool { return s.ID == style.ID }) {
+			log.Printf("skipping style '%s' stored in %s, since a style with the same ID is configured",
+				style.ID, stylesConfig.StylesDir)
+			continue
+		}
+		result = append(result, style)
+	}
+
+	return storage, result, nil
+}
+
+// CreateStyle adds a new style, based on the stylesheet in the request body.
+// The ID of the style is derived from the stylesheet.
+func (s *Styles) CreateStyle() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		format, stylesheet, info, ok := s.readStylesheet(w, r)
+		if !ok {
+			return
+		}
+		styleID := toStyleID(info.ID)
+		if styleID == "" {
+			engine.RenderProblem(engine.ProblemBadRequest, w,
+				"failed to derive style ID from stylesheet, provide an id or name in the stylesheet")
+
+			return
+		}
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if _, exists := s.getStyle(styleID); exists {
+			engine.RenderProblem(engine.ProblemConflict, w, "style "+styleID+" already exists")
+
+			return
+		}
+		style := newManagedStyle(styleID, format, info)
+		if err := s.saveStyle(style, format, stylesheet); err != nil {
+			engine.RenderProblemAndLog(engine.ProblemServerError, w, err)
+
+			return
+		}
+		w.Header().Set("Location", s.engine.Config.BaseURL.String()+stylesPath+"/"+styleID)
+		w.WriteHeader(http.StatusCreated)
+	}
+}
+
+// ReplaceStyle creates or updates the style with the ID in the path, based on the stylesheet
+// in the request body. Stylesheets in other formats of an existing style remain untouched.
+func (s *Styles) ReplaceStyle() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		styleID := chi.URLParam(r, "style")
+		if styleID != toStyleID(styleID) {
+			engine.RenderProblem(engine.ProblemBadRequest, w, "invalid style ID "+styleID+
+				", only lowercase characters, numbers, hyphens and (single) underscores are allowed")
+
+			return
+		}
+		format, stylesheet, info, ok := s.readStylesheet(w, r)
+		if !ok {
+			return
+		}
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		style, exists := s.getStyle(styleID)
+		if exists && s.readOnly[styleID] {
+			engine.RenderProblem(engine.ProblemConflict, w, "style "+styleID+" is read-only")
+
+			return
+		}
+		if exists {
+			style.LastUpdated = lastUpdated()
+			if title := sanitizeTitle(info.Title); title != "" {
+				style.Title = title
+			}
+			if !slices.ContainsFunc(style.Formats, func(f config.StyleFormat) bool { return f.Format == format }) {
+				style.Formats = append(slices.Clone(style.Formats), config.StyleFormat{Format: format})
+			}
+		} else {
+			style = newManagedStyle(styleID, format, info)
+		}
+		if err := s.saveStyle(style, format, stylesheet); err != nil {
+			engine.RenderProblemAndLog(engine.ProblemServerError, w, err)
+
+			return
+		}
+		if exists {
+			w.WriteHeader(http.StatusNoContent)
+		} else {
+			w.Header().Set("Location", s.engine.Config.BaseURL.String()+stylesPath+"/"+styleID)
+			w.WriteHeader(http.StatusCreated)
+		}
+	}
+}
+
+// DeleteStyle removes the style with the ID in the path.
+func (s *Styles) DeleteStyle() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		styleID := chi.URLParam(r, "style")
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		style, exists := s.getStyle(styleID)
+		if !exists {
+			engine.RenderProblem(engine.ProblemNotFound, w, "unknown style "+styleID)
+
+			return
+		}
+		if s.readOnly[styleID] {
+			engine.RenderProblem(engine.ProblemConflict, w, "style "+styleID+" is read-only")
+
+			return
+		}
+		if err := s.storage.Delete(style); err != nil {
+			engine.RenderProblemAndLog(engine.ProblemServerError, w, err)
+
+			return
+		}
+		s.managed = slices.DeleteFunc(slices.Clone(s.managed), func(st config.Style) bool { return st.ID == styleID })
+		for styleInstanceID := range s.legends {
+			if strings.Split(styleInstanceID, projectionDelimiter)[0] == styleID {
+				delete(s.legends, styleInstanceID)
+			}
+		}
+		for instanceName := range s.converted {
+			if strings.Split(instanceName, projectionDelimiter)[0] == styleID {
+				delete(s.converted, instanceName)
+			}
+		}
+		s.renderStyles(s.rerender)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// readStylesheet reads and validates the stylesheet in the request body. Renders a problem
+// and returns false when the request can't be processed any further.
+func (s *Styles) readStylesheet(w http.ResponseWriter, r *http.Request) (string, []byte, *stylesheetInfo, bool) {
+	validate := r.URL.Query().Get(validateParam)
+	if validate != "" && validate != validateYes && validate != validateNo && validate != validateOnly {
+		engine.RenderProblem(engine.ProblemBadRequest, w, "invalid value for parameter validate, "+
+			"expected one of: yes, no, only")
+
+		return "", nil, nil, false
+	}
+	format, err := styleFormatFromMediaType(r.Header.Get(engine.HeaderContentType))
+	if err != nil {
+		engine.RenderProblem(engine.ProblemUnsupportedMediaType, w, err.Error())
+
+		return "", nil, nil, false
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, maxStylesheetSize)
+	stylesheet, err := io.ReadAll(r.Body)
+	if err != nil {
+		engine.RenderProblem(engine.ProblemBadRequest, w, "failed to read stylesheet: "+err.Error())
+
+		return "", nil, nil, false
+	}
+
+	// Stylesheets are always validated (also when validate=no) since an invalid
+	// stylesheet can't be rendered and served.
+	info, err := validateStylesheet(s.supportedProjections, format, stylesheet)
+	if err != nil {
+		engine.RenderProblem(engine.ProblemBadRequest, w, err.Error())
+
+		return "", nil, nil, false
+	}
+	if validate == validateOnly {
+		w.WriteHeader(http.StatusNoContent)
+
+		return "", nil, nil, false
+	}
+
+	return format, stylesheet, info, true
+}
+
+// saveStyle persists the given style and makes it available in the API. Caller should hold the lock.
+func (s *Styles) saveStyle(style config.Style, format string, stylesheet []byte) error {
+	if err := s.storage.Save(style, format, stylesheet); err != nil {
+		return fmt.Errorf("failed to save style %s: %w", style.ID, err)
+	}
+	// replace (instead of modify in-place) since rendered templates may still refer to the previous styles
+	managed := slices.Clone(s.managed)
+	if i := slices.IndexFunc(managed, func(st config.Style) bool { return st.ID == style.ID }); i >= 0 {
+		managed[i] = style
+	} else {
+		managed = append(managed, style)
+	}
+	s.managed = managed
+
+	s.renderStylePerProjection(s.rerender, style)
+	s.renderStyles(s.rerender)
+
+	return nil
+}
+
+// getStyle same as findStyle, but for callers already holding the lock.
+func (s *Styles) getStyle(styleID string) (config.Style, bool) {
+	for _, styles := range [][]config.Style{s.engine.Config.OgcAPI.Styles.SupportedStyles, s.managed} {
+		for _, style := range styles {
+			if style.ID == styleID {
+				return style, true
+			}
+		}
+	}
+
+	return config.Style{}, false
+}
+
+func (s *Styles) rerender(_ string, params any, breadcrumbs []engine.Breadcrumb, keys ...engine.TemplateKey) {
+	s.engine.RerenderTemplatesWithParams(params, breadcrumbs, keys...)
+}
+
+func newManagedStyle(styleID string, format string, info *stylesheetInfo) config.Style {
+	title := sanitizeTitle(info.Title)
+	if title == "" {
+		title = styleID
+	}
+
+	return config.Style{
+		ID:          styleID,
+		Title:       title,
+		LastUpdated: lastUpdated(),
+		Formats:     []config.StyleFormat{{Format: format}},
+	}
+}
+
+func lastUpdated() *string {
+	now := engine.Now().UTC().Format(time.RFC3339)
+
+	return &now
+}
+
+func styleFormatFromMediaType(contentType string) (string, error) {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return "", errors.New("missing or invalid Content-Type header")
+	}
+	switch mediaType {
+	case engine.MediaTypeMapboxStyle:
+		return engine.FormatMapboxStyle, nil
+	case strings.Split(engine.MediaTypeSLD, ";")[0]:
+		if version, ok := params["version"]; !ok || version == "1.0" || version == "1.0.0" {
+			return engine.FormatSLD, nil
+		}
+	}
+
+	return "", fmt.Errorf("unsupported Content-Type %s, expected %s or %s",
+		contentType, engine.MediaTypeMapboxStyle, engine.MediaTypeSLD)
+}
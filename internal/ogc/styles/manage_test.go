@@ -0,0 +1,264 @@
+package styles
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/PDOK/gokoala/config"
+	"github.com/PDOK/gokoala/internal/engine"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testMapboxStyle = `{
+  "version": 8,
+  "name": "Road Map",
+  "sources": {
+    "roads": {
+      "type": "vector",
+      "tiles": ["http://localhost:8080/tiles/{{ .Params.Projection }}/{z}/{y}/{x}?f=mvt"]
+    }
+  },
+  "layers": [
+    {"id": "background", "type": "background"},
+    {"id": "roads", "type": "line", "source": "roads", "source-layer": "roads"}
+  ]
+}`
+	testSLD = `<?xml version="1.0" encoding="UTF-8"?>
+<StyledLayerDescriptor version="1.0.0" xmlns="http://www.opengis.net/sld" xmlns:ogc="http://www.opengis.net/ogc">
+  <NamedLayer>
+    <Name>roads</Name>
+    <UserStyle>
+      <Name>road_map</Name>
+      <Title>Road Map</Title>
+      <FeatureTypeStyle>
+        <Rule>
+          <LineSymbolizer/>
+        </Rule>
+      </FeatureTypeStyle>
+    </UserStyle>
+  </NamedLayer>
+</StyledLayerDescriptor>`
+)
+
+func newManagedStyles(t *testing.T, stylesDir string) *Styles {
+	t.Helper()
+	newEngine, err := engine.NewEngine("internal/ogc/styles/testdata/config_manage_styles.yaml",
+		"internal/engine/testdata/test_theme.yaml", "", false, true)
+	require.NoError(t, err)
+
+	// use a copy of the styles dir, since styles will be created/deleted
+	if _, err = os.Stat(filepath.Join(stylesDir, "default.json")); err != nil {
+		defaultStyle, err := os.ReadFile(filepath.Join(newEngine.Config.OgcAPI.Styles.StylesDir, "default.json"))
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(filepath.Join(stylesDir, "default.json"), defaultStyle, 0o600))
+	}
+	newEngine.Config.OgcAPI.Styles.StylesDir = stylesDir
+
+	return NewStyles(newEngine)
+}
+
+func TestStyles_ManageStyles(t *testing.T) {
+	stylesDir := t.TempDir()
+	styles := newManagedStyles(t, stylesDir)
+
+	serve := func(handler http.HandlerFunc, method string, url string, style string, contentType string, body string) *httptest.ResponseRecorder {
+		req, err := createManageStyleRequest(method, url, style, contentType, body)
+		require.NoError(t, err)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		return rr
+	}
+	getStyle := func(style string, format string) *httptest.ResponseRecorder {
+		return serve(styles.Style(), http.MethodGet, "http://localhost:8080/styles/:style?f="+format, style, "", "")
+	}
+
+	t.Run("validate only", func(t *testing.T) {
+		rr := serve(styles.CreateStyle(), http.MethodPost, "http://localhost:8080/styles?validate=only", "",
+			engine.MediaTypeMapboxStyle, testMapboxStyle)
+		assert.Equal(t, http.StatusNoContent, rr.Code, rr.Body.String())
+		_, err := os.Stat(filepath.Join(stylesDir, "road-map.json"))
+		assert.ErrorIs(t, err, os.ErrNotExist)
+	})
+
+	t.Run("reject invalid style", func(t *testing.T) {
+		rr := serve(styles.CreateStyle(), http.MethodPost, "http://localhost:8080/styles", "",
+			engine.MediaTypeMapboxStyle, `{"version": 8, "sources": {}, "layers": [{"id": "x", "type": "line", "source": "unknown"}]}`)
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		assert.Contains(t, rr.Body.String(), "refers to unknown source")
+
+		rr = serve(styles.CreateStyle(), http.MethodPost, "http://localhost:8080/styles", "",
+			engine.MediaTypeMapboxStyle, `{"version": 8, "name": "{{ .Params.Unknown }}"}`)
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		assert.Contains(t, rr.Body.String(), "isn't allowed")
+	})
+
+	t.Run("reject templates other than placeholders", func(t *testing.T) {
+		t.Setenv("TEST_STYLES_SECRET", "s3cr3t")
+		for _, name := range []string{
+			`{{ env "TEST_STYLES_SECRET" }}`,
+			`{{ .Config.OgcAPI.Features.Datasources.DefaultWGS84.Postgres.Pass.Value }}`,
+			`{{ .Config.Title }}`,
+			`{{ .Params.Projection | lower }}`,
+			`{{ if .Params.Projection }}x{{ end }}`,
+			`{{ $x := .Params.Projection }}`,
+		} {
+			rr := serve(styles.CreateStyle(), http.MethodPost, "http://localhost:8080/styles", "",
+				engine.MediaTypeMapboxStyle, `{"version": 8, "name": "leak", "sources": {}, "layers": [], "x": "`+name+`"}`)
+			assert.Equal(t, http.StatusBadRequest, rr.Code, name)
+			assert.Contains(t, rr.Body.String(), "invalid template", name)
+			assert.NotContains(t, rr.Body.String(), "s3cr3t", name)
+		}
+		assert.NoFileExists(t, filepath.Join(stylesDir, "leak.json"))
+	})
+
+	t.Run("reject unsupported media type", func(t *testing.T) {
+		rr := serve(styles.CreateStyle(), http.MethodPost, "http://localhost:8080/styles", "",
+			"text/plain", testMapboxStyle)
+		assert.Equal(t, http.StatusUnsupportedMediaType, rr.Code)
+	})
+
+	t.Run("create style", func(t *testing.T) {
+		rr := serve(styles.CreateStyle(), http.MethodPost, "http://localhost:8080/styles", "",
+			engine.MediaTypeMapboxStyle, testMapboxStyle)
+		require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+		assert.Equal(t, "http://localhost:8080/styles/road-map", rr.Header().Get("Location"))
+		assert.FileExists(t, filepath.Join(stylesDir, "road-map.json"))
+		assert.FileExists(t, filepath.Join(stylesDir, "road-map.metadata.json"))
+
+		rr = getStyle("road-map__webmercatorquad", engine.FormatMapboxStyle)
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Contains(t, rr.Body.String(), "tiles/WebMercatorQuad/{z}/{y}/{x}?f=mvt")
+
+		rr = serve(styles.Styles(), http.MethodGet, "http://localhost:8080/styles?f=json", "", "", "")
+		assert.Contains(t, rr.Body.String(), "\"id\": \"road-map__netherlandsrdnewquad\"")
+		assert.Contains(t, rr.Body.String(), "\"title\": \"Road Map (NetherlandsRDNewQuad)\"")
+		assert.Contains(t, rr.Body.String(), "\"href\": \"http://localhost:8080/styles/road-map__webmercatorquad?f=sld10\"")
+
+		rr = serve(styles.Metadata(), http.MethodGet, "http://localhost:8080/styles/:style/metadata?f=json",
+			"road-map__webmercatorquad", "", "")
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Regexp(t, `"title": "Mapbox Style",[^}]+"native": true`, rr.Body.String())
+		assert.Regexp(t, `"title": "OpenGIS Styled Layer Descriptor 1.0 Style",[^}]+"native": false`, rr.Body.String())
+
+		rr = serve(styles.CreateStyle(), http.MethodPost, "http://localhost:8080/styles", "",
+			engine.MediaTypeMapboxStyle, testMapboxStyle)
+		assert.Equal(t, http.StatusConflict, rr.Code)
+	})
+
+	t.Run("add format to style", func(t *testing.T) {
+		// converted from the Mapbox style, as long as the style isn't natively available as SLD
+		rr := getStyle("road-map", engine.FormatSLD)
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Contains(t, rr.Body.String(), "<NamedLayer>\n    <Name>roads</Name>")
+		assert.NotContains(t, rr.Body.String(), "<Name>road_map</Name>")
+
+		rr = serve(styles.ReplaceStyle(), http.MethodPut, "http://localhost:8080/styles/:style", "road-map",
+			engine.MediaTypeSLD, testSLD)
+		require.Equal(t, http.StatusNoContent, rr.Code, rr.Body.String())
+
+		rr = getStyle("road-map", engine.FormatSLD)
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Contains(t, rr.Body.String(), "<Name>road_map</Name>")
+		rr = getStyle("road-map", engine.FormatMapboxStyle)
+		assert.Equal(t, http.StatusOK, rr.Code)
+	})
+
+	t.Run("create style with PUT", func(t *testing.T) {
+		rr := serve(styles.ReplaceStyle(), http.MethodPut, "http://localhost:8080/styles/:style", "roads_sld",
+			"application/vnd.ogc.sld+xml", testSLD)
+		require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+		assert.FileExists(t, filepath.Join(stylesDir, "roads_sld.sld"))
+
+		// converted from SLD
+		rr = getStyle("roads_sld__webmercatorquad", engine.FormatMapboxStyle)
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Contains(t, rr.Body.String(), "\"source-layer\": \"roads\"")
+		assert.Contains(t, rr.Body.String(), "http://localhost:8080/tiles/WebMercatorQuad/{z}/{y}/{x}?f=mvt")
+
+		rr = serve(styles.ReplaceStyle(), http.MethodPut, "http://localhost:8080/styles/:style", "Roads__SLD",
+			engine.MediaTypeSLD, testSLD)
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("configured styles are read-only", func(t *testing.T) {
+		rr := serve(styles.ReplaceStyle(), http.MethodPut, "http://localhost:8080/styles/:style", "default",
+			engine.MediaTypeMapboxStyle, testMapboxStyle)
+		assert.Equal(t, http.StatusConflict, rr.Code)
+
+		rr = serve(styles.DeleteStyle(), http.MethodDelete, "http://localhost:8080/styles/:style", "default", "", "")
+		assert.Equal(t, http.StatusConflict, rr.Code)
+	})
+
+	t.Run("managed styles are loaded on startup", func(t *testing.T) {
+		reloaded := newManagedStyles(t, stylesDir)
+		style, ok := reloaded.findStyle("road-map")
+		require.True(t, ok)
+		assert.Equal(t, "Road Map", style.Title)
+		assert.Len(t, style.Formats, 2)
+		assert.Equal(t, "default", reloaded.SupportedStyles()[0].ID)
+		assert.True(t, reloaded.HasStyle("road-map"))
+		// managed styles aren't added to the config
+		assert.False(t, slices.ContainsFunc(reloaded.engine.Config.OgcAPI.Styles.SupportedStyles,
+			func(s config.Style) bool { return s.ID == "road-map" }))
+	})
+
+	t.Run("delete style", func(t *testing.T) {
+		rr := serve(styles.DeleteStyle(), http.MethodDelete, "http://localhost:8080/styles/:style", "road-map", "", "")
+		assert.Equal(t, http.StatusNoContent, rr.Code)
+		assert.NoFileExists(t, filepath.Join(stylesDir, "road-map.json"))
+		assert.NoFileExists(t, filepath.Join(stylesDir, "road-map.sld"))
+		assert.NoFileExists(t, filepath.Join(stylesDir, "road-map.metadata.json"))
+
+		rr = getStyle("road-map", engine.FormatMapboxStyle)
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+		rr = serve(styles.Metadata(), http.MethodGet, "http://localhost:8080/styles/:style/metadata", "road-map", "", "")
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+		rr = serve(styles.Styles(), http.MethodGet, "http://localhost:8080/styles?f=json", "", "", "")
+		assert.NotContains(t, rr.Body.String(), "road-map")
+
+		rr = serve(styles.DeleteStyle(), http.MethodDelete, "http://localhost:8080/styles/:style", "road-map", "", "")
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+	})
+}
+
+func TestToStyleID(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{name: "Road Map", want: "road-map"},
+		{name: "road_map", want: "road_map"},
+		{name: " __Roads___v2.1__ ", want: "roads_v2-1"},
+		{name: "!!!", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, toStyleID(tt.name))
+		})
+	}
+}
+
+func createManageStyleRequest(method string, url string, style string, contentType string, body string) (*http.Request, error) {
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	if contentType != "" {
+		req.Header.Set(engine.HeaderContentType, contentType)
+	}
+	rctx := chi.NewRouteContext()
+	if style != "" {
+		rctx.URLParams.Add("style", style)
+	}
+
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+
+	return req, err
+}
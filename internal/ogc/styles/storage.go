@@ -0,0 +1,121 @@
+package styles
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/PDOK/gokoala/config"
+)
+
+const (
+	storageLocal = "local"
+
+	metadataExtension = ".metadata.json"
+)
+
+// Storage backend to persist styles created/updated through the API (manage-styles).
+type Storage interface {
+	// Load returns the metadata of all stored styles
+	Load() ([]config.Style, error)
+
+	// Save stores the stylesheet of a style in the given format along with the style metadata.
+	// Stylesheets of the style in other formats are left untouched.
+	Save(style config.Style, format string, stylesheet []byte) error
+
+	// Delete removes the stylesheets (in all formats) and metadata of a style
+	Delete(style config.Style) error
+}
+
+func newStorage(cfg *config.OgcAPIStyles, extension func(format string) string) (Storage, error) {
+	switch cfg.Manage.Storage {
+	case storageLocal:
+		return &localStorage{dir: cfg.StylesDir, extension: extension}, nil
+	default:
+		return nil, fmt.Errorf("unsupported styles storage '%s'", cfg.Manage.Storage)
+	}
+}
+
+// localStorage stores styles on local disk in the stylesDir, in the same way as styles
+// that are configured in the config file. Metadata is stored next to the stylesheets.
+type localStorage struct {
+	dir       string
+	extension func(format string) string
+}
+
+func (l *localStorage) Load() ([]config.Style, error) {
+	files, err := filepath.Glob(filepath.Join(l.dir, "*"+metadataExtension))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+
+	styles := make([]config.Style, 0, len(files))
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read style metadata %s: %w", file, err)
+		}
+		var style config.Style
+		if err = json.Unmarshal(data, &style); err != nil {
+			return nil, fmt.Errorf("failed to parse style metadata %s: %w", file, err)
+		}
+		if style.ID != strings.TrimSuffix(filepath.Base(file), metadataExtension) {
+			return nil, fmt.Errorf("style metadata %s doesn't match style ID '%s'", file, style.ID)
+		}
+		styles = append(styles, style)
+	}
+
+	return styles, nil
+}
+
+func (l *localStorage) Save(style config.Style, format string, stylesheet []byte) error {
+	if err := writeFileAtomic(filepath.Join(l.dir, style.ID+l.extension(format)), stylesheet); err != nil {
+		return err
+	}
+	metadata, err := json.MarshalIndent(style, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(filepath.Join(l.dir, style.ID+metadataExtension), metadata)
+}
+
+func (l *localStorage) Delete(style config.Style) error {
+	// remove metadata first, so a partially removed style isn't loaded again on startup
+	files := []string{filepath.Join(l.dir, style.ID+metadataExtension)}
+	for _, format := range style.Formats {
+		files = append(files, filepath.Join(l.dir, style.ID+l.extension(format.Format)))
+	}
+	for _, file := range files {
+		if err := os.Remove(file); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writeFileAtomic writes to a temporary file first, so readers never see partially written files.
+func writeFileAtomic(name string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(name), "."+filepath.Base(name)+"-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err = tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), name)
+}
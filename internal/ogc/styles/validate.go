@@ -0,0 +1,295 @@
+package styles
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"maps"
+	"slices"
+	"strings"
+	texttemplate "text/template"
+	"text/template/parse"
+	"unicode"
+
+	"github.com/PDOK/gokoala/config"
+	"github.com/PDOK/gokoala/internal/engine"
+	"github.com/PDOK/gokoala/internal/engine/util"
+)
+
+const sldNamespace = "http://www.opengis.net/sld"
+
+var (
+	// layer types as defined in https://docs.mapbox.com/style-spec/reference/layers/#type
+	mapboxLayerTypes = []string{"background", "fill", "line", "symbol", "raster", "circle",
+		"fill-extrusion", "heatmap", "hillshade", "sky"}
+
+	// layer types which don't require a source
+	mapboxLayerTypesWithoutSource = []string{"background", "sky"}
+
+	// Placeholders allowed in stylesheets uploaded through the API. In contrast to stylesheets
+	// in the config these aren't trusted, so other template constructs (functions, config, etc.) aren't allowed.
+	allowedPlaceholders = []string{".Params.Projection", ".Params.ZoomLevelRange.Start", ".Params.ZoomLevelRange.End"}
+)
+
+// stylesheetInfo info about a style, derived from its stylesheet
+type stylesheetInfo struct {
+	ID    string
+	Title string
+}
+
+type mapboxStyle struct {
+	Version int                        `json:"version"`
+	ID      string                     `json:"id"`
+	Name    string                     `json:"name"`
+	Sources map[string]json.RawMessage `json:"sources"`
+	Layers  []struct {
+		ID     string `json:"id"`
+		Type   string `json:"type"`
+		Source string `json:"source"`
+	} `json:"layers"`
+}
+
+type sldStyle struct {
+	XMLName     xml.Name   `xml:"StyledLayerDescriptor"`
+	Version     string     `xml:"version,attr"`
+	NamedLayers []sldLayer `xml:"NamedLayer"`
+	UserLayers  []sldLayer `xml:"UserLayer"`
+}
+
+type sldLayer struct {
+	Name       string `xml:"Name"`
+	UserStyles []struct {
+		Name              string `xml:"Name"`
+		Title             string `xml:"Title"`
+		FeatureTypeStyles []struct {
+			Rules []struct{} `xml:"Rule"`
+		} `xml:"FeatureTypeStyle"`
+	} `xml:"UserStyle"`
+}
+
+// validateStylesheet validates the given (uploaded) stylesheet in the given format. Since stylesheets are
+// templates (see renderStylePerFormat) the stylesheet is rendered for each supported projection
+// before validation, this also guarantees the stylesheet can be rendered without failure.
+func validateStylesheet(supportedProjections []config.SupportedSrs, format string, stylesheet []byte) (*stylesheetInfo, error) {
+	parsed, err := parseUploadedStylesheet(stylesheet)
+	if err != nil {
+		return nil, err
+	}
+
+	var info *stylesheetInfo
+	for _, supportedSrs := range supportedProjections {
+		// config isn't available to uploaded stylesheets
+		rendered, err := renderStylesheet(parsed, nil, supportedSrs)
+		if err != nil {
+			return nil, err
+		}
+		switch format {
+		case engine.FormatMapboxStyle:
+			info, err = validateMapboxStyle(rendered)
+		case engine.FormatSLD:
+			info, err = validateSLD(rendered)
+		default:
+			err = fmt.Errorf("unsupported style format %s", format)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid stylesheet for %s: %w", supportedSrs.GetTileMatrixSetID(), err)
+		}
+	}
+
+	return info, nil
+}
+
+// parseStylesheet parses the given stylesheet as template, just like the engine does
+func parseStylesheet(stylesheet []byte) (*texttemplate.Template, error) {
+	funcs := maps.Clone(engine.GlobalTemplateFuncs)
+	funcs["i18n"] = func(messageID string) htmltemplate.HTML {
+		return htmltemplate.HTML(messageID) //nolint:gosec // stylesheets aren't translated
+	}
+	parsed, err := texttemplate.New("stylesheet").Funcs(funcs).Parse(string(stylesheet))
+	if err != nil {
+		return nil, fmt.Errorf("invalid template: %w", err)
+	}
+
+	return parsed, nil
+}
+
+// parseUploadedStylesheet parses the given stylesheet as template, without any functions.
+// Only the allowedPlaceholders are accepted, see validatePlaceholders.
+func parseUploadedStylesheet(stylesheet []byte) (*texttemplate.Template, error) {
+	parsed, err := texttemplate.New("stylesheet").Parse(string(stylesheet))
+	if err != nil {
+		return nil, fmt.Errorf("invalid template: %w", err)
+	}
+	if err = validatePlaceholders(parsed.Root); err != nil {
+		return nil, err
+	}
+
+	return parsed, nil
+}
+
+// validatePlaceholders asserts the given parsed template only consists of text and allowedPlaceholders
+func validatePlaceholders(root *parse.ListNode) error {
+	if root == nil {
+		return nil
+	}
+	for _, node := range root.Nodes {
+		switch n := node.(type) {
+		case *parse.TextNode:
+			continue
+		case *parse.ActionNode:
+			if n.Pipe != nil && len(n.Pipe.Decl) == 0 && len(n.Pipe.Cmds) == 1 && len(n.Pipe.Cmds[0].Args) == 1 {
+				if field, ok := n.Pipe.Cmds[0].Args[0].(*parse.FieldNode); ok && slices.Contains(allowedPlaceholders, field.String()) {
+					continue
+				}
+			}
+		}
+
+		return fmt.Errorf("invalid template: '%s' isn't allowed, stylesheets may only contain the placeholders {{ %s }}",
+			node.String(), strings.Join(allowedPlaceholders, " }}, {{ "))
+	}
+
+	return nil
+}
+
+// renderStylesheet renders the given parsed stylesheet for the given projection
+func renderStylesheet(parsed *texttemplate.Template, cfg *config.Config, supportedSrs config.SupportedSrs) ([]byte, error) {
+	var rendered bytes.Buffer
+	if err := parsed.Execute(&rendered, &engine.TemplateData{
+		Config: cfg,
+		Params: stylePerFormatTemplateData{
+			Projection:     supportedSrs.GetTileMatrixSetID(),
+			ZoomLevelRange: supportedSrs.ZoomLevelRange,
+		},
+	}); err != nil {
+		return nil, fmt.Errorf("failed to render template: %w", err)
+	}
+
+	return rendered.Bytes(), nil
+}
+
+// renderStylesheetFile renders the given stylesheet (template) on disk for the given projection
+func renderStylesheetFile(cfg *config.Config, supportedSrs config.SupportedSrs, stylesheetFile string) ([]byte, error) {
+	parsed, err := parseStylesheet([]byte(util.ReadFile(stylesheetFile)))
+	if err != nil {
+		return nil, err
+	}
+
+	return renderStylesheet(parsed, cfg, supportedSrs)
+}
+
+func validateMapboxStyle(stylesheet []byte) (*stylesheetInfo, error) {
+	var style mapboxStyle
+	if err := json.Unmarshal(stylesheet, &style); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+	if style.Version != 8 {
+		return nil, errors.New("mapbox style should have version 8")
+	}
+	if style.Sources == nil {
+		return nil, errors.New("mapbox style should have sources")
+	}
+	if style.Layers == nil {
+		return nil, errors.New("mapbox style should have layers")
+	}
+	layerIDs := make(map[string]bool, len(style.Layers))
+	for i, layer := range style.Layers {
+		if layer.ID == "" {
+			return nil, fmt.Errorf("layer %d should have an id", i)
+		}
+		if layerIDs[layer.ID] {
+			return nil, fmt.Errorf("duplicate layer id '%s'", layer.ID)
+		}
+		layerIDs[layer.ID] = true
+
+		if !slices.Contains(mapboxLayerTypes, layer.Type) {
+			return nil, fmt.Errorf("layer '%s' has invalid type '%s'", layer.ID, layer.Type)
+		}
+		if slices.Contains(mapboxLayerTypesWithoutSource, layer.Type) {
+			continue
+		}
+		if _, ok := style.Sources[layer.Source]; !ok {
+			return nil, fmt.Errorf("layer '%s' refers to unknown source '%s'", layer.ID, layer.Source)
+		}
+	}
+
+	id := style.ID
+	if id == "" {
+		id = style.Name
+	}
+
+	return &stylesheetInfo{ID: id, Title: style.Name}, nil
+}
+
+func validateSLD(stylesheet []byte) (*stylesheetInfo, error) {
+	var style sldStyle
+	if err := xml.Unmarshal(stylesheet, &style); err != nil {
+		return nil, fmt.Errorf("invalid XML: %w", err)
+	}
+	if style.XMLName.Space != sldNamespace {
+		return nil, fmt.Errorf("SLD should be in namespace %s", sldNamespace)
+	}
+	if style.Version != "1.0.0" {
+		return nil, errors.New("SLD should have version 1.0.0")
+	}
+
+	var info *stylesheetInfo
+	for _, layer := range append(style.NamedLayers, style.UserLayers...) {
+		if len(layer.UserStyles) == 0 {
+			return nil, fmt.Errorf("layer '%s' should have a UserStyle", layer.Name)
+		}
+		for _, userStyle := range layer.UserStyles {
+			if len(userStyle.FeatureTypeStyles) == 0 || len(userStyle.FeatureTypeStyles[0].Rules) == 0 {
+				return nil, fmt.Errorf("UserStyle of layer '%s' should have a FeatureTypeStyle with at least one Rule", layer.Name)
+			}
+			if info == nil {
+				info = &stylesheetInfo{ID: userStyle.Name, Title: userStyle.Title}
+				if info.ID == "" {
+					info.ID = layer.Name
+				}
+				if info.Title == "" {
+					info.Title = info.ID
+				}
+			}
+		}
+	}
+	if info == nil {
+		return nil, errors.New("SLD should have at least one NamedLayer or UserLayer")
+	}
+
+	return info, nil
+}
+
+// toStyleID converts the given name to a valid style ID (lowercase, numbers, hyphens and underscores)
+func toStyleID(name string) string {
+	var id strings.Builder
+	for _, r := range strings.ToLower(strings.TrimSpace(name)) {
+		switch {
+		case (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '_':
+			id.WriteRune(r)
+		default:
+			id.WriteRune('-')
+		}
+	}
+
+	result := id.String()
+	for strings.Contains(result, projectionDelimiter) {
+		// reserved to separate style and projection
+		result = strings.ReplaceAll(result, projectionDelimiter, "_")
+	}
+
+	return strings.Trim(result, "-_")
+}
+
+// sanitizeTitle removes characters from the title that aren't allowed in (JSON) templates
+func sanitizeTitle(title string) string {
+	return strings.TrimSpace(strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) || strings.ContainsRune(`"\<>`, r) {
+			return -1
+		}
+
+		return r
+	}, title))
+}
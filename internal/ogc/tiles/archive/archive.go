@@ -0,0 +1,60 @@
+package archive
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path/filepath"
+	"time"
+
+	"github.com/PDOK/gokoala/config"
+	"github.com/PDOK/gokoala/internal/engine"
+)
+
+// Archive single-file container of (vector) tiles, like MBTiles or PMTiles.
+type Archive interface {
+	// Tile returns the tile at the given tile matrix (zoom level), row and column.
+	// Rows and columns follow the OGC TileMatrixSet convention, meaning the origin is top-left.
+	// Returns nil without error when the archive doesn't contain the requested tile.
+	Tile(ctx context.Context, tileMatrix, tileRow, tileCol int) ([]byte, error)
+
+	// Close closes the archive
+	Close() error
+}
+
+// Open opens the given archive, downloads it first when configured to do so. The given matrix
+// heights (number of tile rows by tile matrix) of the TileMatrixSet are used to convert rows
+// for archives with their origin at the bottom-left.
+func Open(cfg config.TilesArchive, matrixHeights map[int]int) (Archive, error) {
+	if cfg.Download != nil {
+		if err := download(cfg); err != nil {
+			return nil, err
+		}
+	}
+	switch filepath.Ext(cfg.File) {
+	case ".mbtiles":
+		return newMBTiles(cfg.File, matrixHeights)
+	case ".pmtiles":
+		return newPMTiles(cfg.File)
+	default:
+		return nil, fmt.Errorf("unsupported tile archive %s, only MBTiles and PMTiles are supported", cfg.File)
+	}
+}
+
+func download(cfg config.TilesArchive) error {
+	url := *cfg.Download.From.URL
+	log.Printf("start download of tile archive: %s", url.String())
+
+	tlsSkipVerify := false
+	if cfg.Download.TLSSkipVerify != nil {
+		tlsSkipVerify = *cfg.Download.TLSSkipVerify
+	}
+
+	downloadTime, err := engine.Download(url, cfg.File, cfg.Download.Parallelism, tlsSkipVerify,
+		cfg.Download.Timeout.Duration, cfg.Download.RetryDelay.Duration, cfg.Download.RetryMaxDelay.Duration, cfg.Download.MaxRetries)
+	if err != nil {
+		return fmt.Errorf("failed to download tile archive: %w", err)
+	}
+	log.Printf("successfully downloaded tile archive to %s in %s", cfg.File, downloadTime.Round(time.Second))
+	return nil
+}
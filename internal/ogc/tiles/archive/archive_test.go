@@ -0,0 +1,223 @@
+package archive
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/binary"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/PDOK/gokoala/config"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestZxyToTileID(t *testing.T) {
+	tests := []struct {
+		z    uint8
+		x, y uint32
+		want uint64
+	}{
+		{0, 0, 0, 0},
+		{1, 0, 0, 1},
+		{1, 0, 1, 2},
+		{1, 1, 1, 3},
+		{1, 1, 0, 4},
+		{2, 0, 0, 5},
+		{12, 3423, 1763, 19078479},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, zxyToTileID(tt.z, tt.x, tt.y), "z=%d x=%d y=%d", tt.z, tt.x, tt.y)
+	}
+}
+
+func TestMBTiles(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "test.mbtiles")
+	db, err := sqlx.Open("sqlite3", file)
+	require.NoError(t, err)
+	db.MustExec(`create table metadata (name text, value text)`)
+	db.MustExec(`create table tiles (zoom_level integer, tile_column integer, tile_row integer, tile_data blob)`)
+	db.MustExec(`insert into metadata values ('format', 'pbf')`)
+	db.MustExec(`insert into tiles values (2, 1, 0, ?)`, []byte("tile-2-1-0")) // row 0 in TMS is row 3 in OGC
+	require.NoError(t, db.Close())
+
+	archive, err := Open(config.TilesArchive{Srs: "EPSG:3857", File: file}, map[int]int{2: 4})
+	require.NoError(t, err)
+	defer archive.Close()
+
+	tile, err := archive.Tile(context.Background(), 2, 3, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("tile-2-1-0"), tile)
+
+	tile, err = archive.Tile(context.Background(), 2, 0, 1)
+	require.NoError(t, err)
+	assert.Nil(t, tile)
+
+	// unknown tile matrix
+	tile, err = archive.Tile(context.Background(), 3, 0, 1)
+	require.NoError(t, err)
+	assert.Nil(t, tile)
+}
+
+func TestMBTilesNonSquareTileMatrix(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "test.mbtiles")
+	db, err := sqlx.Open("sqlite3", file)
+	require.NoError(t, err)
+	db.MustExec(`create table metadata (name text, value text)`)
+	db.MustExec(`create table tiles (zoom_level integer, tile_column integer, tile_row integer, tile_data blob)`)
+	db.MustExec(`insert into tiles values (2, 1, 0, ?)`, []byte("tile-2-1-0")) // row 0 in TMS is row 2 in OGC, with 3 rows
+	require.NoError(t, db.Close())
+
+	archive, err := Open(config.TilesArchive{Srs: "EPSG:28992", File: file}, map[int]int{2: 3})
+	require.NoError(t, err)
+	defer archive.Close()
+
+	tile, err := archive.Tile(context.Background(), 2, 2, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("tile-2-1-0"), tile)
+}
+
+func TestMBTilesRasterNotSupported(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "test.mbtiles")
+	db, err := sqlx.Open("sqlite3", file)
+	require.NoError(t, err)
+	db.MustExec(`create table metadata (name text, value text)`)
+	db.MustExec(`insert into metadata values ('format', 'png')`)
+	require.NoError(t, db.Close())
+
+	_, err = Open(config.TilesArchive{Srs: "EPSG:3857", File: file}, nil)
+	require.ErrorContains(t, err, "only vector tiles (pbf) are supported")
+}
+
+func TestPMTiles(t *testing.T) {
+	tiles := map[uint64][]byte{
+		zxyToTileID(0, 0, 0): []byte("tile-0-0-0"),
+		zxyToTileID(1, 1, 0): []byte("tile-1-1-0"),
+		zxyToTileID(1, 1, 1): []byte("tile-1-1-1"),
+		zxyToTileID(2, 3, 2): []byte("tile-2-3-2"),
+	}
+	for _, compression := range []uint8{pmTilesCompressionNone, pmTilesCompressionGzip} {
+		for _, withLeafDir := range []bool{false, true} {
+			file := writePMTiles(t, tiles, compression, withLeafDir)
+
+			archive, err := Open(config.TilesArchive{Srs: "EPSG:3857", File: file}, nil)
+			require.NoError(t, err)
+
+			tile, err := archive.Tile(context.Background(), 0, 0, 0)
+			require.NoError(t, err)
+			assert.Equal(t, []byte("tile-0-0-0"), tile)
+
+			// row (y) before column (x) in OGC API tiles
+			tile, err = archive.Tile(context.Background(), 1, 0, 1)
+			require.NoError(t, err)
+			assert.Equal(t, []byte("tile-1-1-0"), tile)
+
+			tile, err = archive.Tile(context.Background(), 2, 2, 3)
+			require.NoError(t, err)
+			assert.Equal(t, []byte("tile-2-3-2"), tile)
+
+			tile, err = archive.Tile(context.Background(), 1, 0, 0)
+			require.NoError(t, err)
+			assert.Nil(t, tile)
+
+			tile, err = archive.Tile(context.Background(), 14, 100, 100)
+			require.NoError(t, err)
+			assert.Nil(t, tile)
+
+			// leaf directories are cached
+			assert.Equal(t, withLeafDir, archive.(*pmTiles).leafDirs.Len() > 0)
+
+			require.NoError(t, archive.Close())
+		}
+	}
+}
+
+func TestPMTilesInvalid(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "invalid.pmtiles")
+	require.NoError(t, os.WriteFile(file, make([]byte, pmTilesHeaderLength), 0o600))
+
+	_, err := Open(config.TilesArchive{Srs: "EPSG:3857", File: file}, nil)
+	require.ErrorContains(t, err, "not a PMTiles archive")
+}
+
+// writePMTiles writes a minimal PMTiles v3 archive containing the given tiles
+func writePMTiles(t *testing.T, tiles map[uint64][]byte, compression uint8, withLeafDir bool) string {
+	t.Helper()
+
+	ids := slices.Sorted(maps.Keys(tiles))
+	var tileData []byte
+	entries := make([]pmTilesEntry, 0, len(ids))
+	for _, id := range ids {
+		entries = append(entries, pmTilesEntry{tileID: id, offset: uint64(len(tileData)), length: uint32(len(tiles[id])), runLength: 1})
+		tileData = append(tileData, tiles[id]...)
+	}
+
+	rootDir := serializeDirectory(t, entries, compression)
+	var leafDirs []byte
+	if withLeafDir {
+		leafDirs = rootDir
+		rootDir = serializeDirectory(t, []pmTilesEntry{{tileID: 0, offset: 0, length: uint32(len(leafDirs)), runLength: 0}}, compression)
+	}
+
+	header := make([]byte, pmTilesHeaderLength)
+	copy(header, pmTilesMagic)
+	header[7] = pmTilesVersion
+	binary.LittleEndian.PutUint64(header[8:16], pmTilesHeaderLength)
+	binary.LittleEndian.PutUint64(header[16:24], uint64(len(rootDir)))
+	binary.LittleEndian.PutUint64(header[40:48], uint64(pmTilesHeaderLength+len(rootDir)))
+	binary.LittleEndian.PutUint64(header[48:56], uint64(len(leafDirs)))
+	binary.LittleEndian.PutUint64(header[56:64], uint64(pmTilesHeaderLength+len(rootDir)+len(leafDirs)))
+	binary.LittleEndian.PutUint64(header[64:72], uint64(len(tileData)))
+	header[97] = compression
+	header[98] = pmTilesCompressionNone
+	header[99] = pmTilesTileTypeMVT
+
+	var archive []byte
+	archive = append(archive, header...)
+	archive = append(archive, rootDir...)
+	archive = append(archive, leafDirs...)
+	archive = append(archive, tileData...)
+
+	file := filepath.Join(t.TempDir(), "test.pmtiles")
+	require.NoError(t, os.WriteFile(file, archive, 0o600))
+	return file
+}
+
+func serializeDirectory(t *testing.T, entries []pmTilesEntry, compression uint8) []byte {
+	t.Helper()
+
+	var dir []byte
+	dir = binary.AppendUvarint(dir, uint64(len(entries)))
+	var lastID uint64
+	for _, e := range entries {
+		dir = binary.AppendUvarint(dir, e.tileID-lastID)
+		lastID = e.tileID
+	}
+	for _, e := range entries {
+		dir = binary.AppendUvarint(dir, uint64(e.runLength))
+	}
+	for _, e := range entries {
+		dir = binary.AppendUvarint(dir, uint64(e.length))
+	}
+	for i, e := range entries {
+		if i > 0 && e.offset == entries[i-1].offset+uint64(entries[i-1].length) {
+			dir = binary.AppendUvarint(dir, 0)
+		} else {
+			dir = binary.AppendUvarint(dir, e.offset+1)
+		}
+	}
+	if compression != pmTilesCompressionGzip {
+		return dir
+	}
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	_, err := gz.Write(dir)
+	require.NoError(t, err)
+	require.NoError(t, gz.Close())
+	return buf.Bytes()
+}
@@ -0,0 +1,61 @@
+package archive
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/jmoiron/sqlx"
+	_ "github.com/mattn/go-sqlite3" // import for side effect (= sqlite3 driver) only
+)
+
+// MBTiles archive, see https://github.com/mapbox/mbtiles-spec.
+type mbTiles struct {
+	db            *sqlx.DB
+	matrixHeights map[int]int
+}
+
+func newMBTiles(file string, matrixHeights map[int]int) (*mbTiles, error) {
+	db, err := sqlx.Open("sqlite3", fmt.Sprintf("file:%s?mode=ro&immutable=1", file))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open MBTiles archive %s: %w", file, err)
+	}
+	var format string
+	if err = db.Get(&format, "select value from metadata where name = 'format'"); err != nil && !errors.Is(err, sql.ErrNoRows) {
+		_ = db.Close()
+		return nil, fmt.Errorf("failed to read metadata of MBTiles archive %s: %w", file, err)
+	}
+	if format != "" && format != "pbf" {
+		_ = db.Close()
+		return nil, fmt.Errorf("MBTiles archive %s contains tiles in format '%s', only vector tiles (pbf) are supported", file, format)
+	}
+	log.Printf("opened MBTiles archive: %s", file)
+
+	return &mbTiles{db, matrixHeights}, nil
+}
+
+func (m *mbTiles) Tile(ctx context.Context, tileMatrix, tileRow, tileCol int) ([]byte, error) {
+	// MBTiles uses the TMS tiling scheme, which has its origin at the bottom-left, so flip the row to convert.
+	matrixHeight, ok := m.matrixHeights[tileMatrix]
+	if !ok {
+		return nil, nil
+	}
+	tmsRow := matrixHeight - 1 - tileRow
+
+	var data []byte
+	err := m.db.GetContext(ctx, &data,
+		"select tile_data from tiles where zoom_level = ? and tile_column = ? and tile_row = ?",
+		tileMatrix, tileCol, tmsRow)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read tile %d/%d/%d from MBTiles archive: %w", tileMatrix, tileRow, tileCol, err)
+	}
+	return data, nil
+}
+
+func (m *mbTiles) Close() error {
+	return m.db.Close()
+}
@@ -0,0 +1,280 @@
+package archive
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+
+	lru "github.com/hashicorp/golang-lru/v2"
+)
+
+// PMTiles v3 constants, see https://github.com/protomaps/PMTiles/blob/main/spec/v3/spec.md
+const (
+	pmTilesMagic         = "PMTiles"
+	pmTilesVersion       = 3
+	pmTilesHeaderLength  = 127
+	pmTilesMaxDirDepth   = 3
+	pmTilesMaxTileMatrix = 31
+
+	pmTilesCompressionNone = 1
+	pmTilesCompressionGzip = 2
+
+	pmTilesTileTypeMVT = 1
+
+	// number of (decompressed) leaf directories to keep in memory
+	pmTilesLeafDirCacheSize = 64
+)
+
+type pmTilesHeader struct {
+	rootDirOffset       uint64
+	rootDirLength       uint64
+	leafDirsOffset      uint64
+	tileDataOffset      uint64
+	internalCompression uint8
+	tileCompression     uint8
+	tileType            uint8
+}
+
+type pmTilesEntry struct {
+	tileID    uint64
+	offset    uint64
+	length    uint32
+	runLength uint32
+}
+
+// PMTiles archive, see https://github.com/protomaps/PMTiles.
+type pmTiles struct {
+	file    *os.File
+	header  pmTilesHeader
+	rootDir []pmTilesEntry
+
+	// leaf directories by offset, to avoid reading and decompressing these on every request
+	leafDirs *lru.Cache[uint64, []pmTilesEntry]
+}
+
+func newPMTiles(file string) (*pmTiles, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open PMTiles archive %s: %w", file, err)
+	}
+	leafDirs, _ := lru.New[uint64, []pmTilesEntry](pmTilesLeafDirCacheSize)
+	p := &pmTiles{file: f, leafDirs: leafDirs}
+	if err = p.init(); err != nil {
+		_ = f.Close()
+		return nil, fmt.Errorf("failed to open PMTiles archive %s: %w", file, err)
+	}
+	log.Printf("opened PMTiles archive: %s", file)
+
+	return p, nil
+}
+
+func (p *pmTiles) init() error {
+	buf := make([]byte, pmTilesHeaderLength)
+	if _, err := p.file.ReadAt(buf, 0); err != nil {
+		return fmt.Errorf("failed to read header: %w", err)
+	}
+	header, err := parsePMTilesHeader(buf)
+	if err != nil {
+		return err
+	}
+	p.header = header
+	p.rootDir, err = p.readDirectory(header.rootDirOffset, header.rootDirLength)
+	return err
+}
+
+func parsePMTilesHeader(buf []byte) (pmTilesHeader, error) {
+	var header pmTilesHeader
+	if string(buf[0:7]) != pmTilesMagic {
+		return header, errors.New("not a PMTiles archive")
+	}
+	if buf[7] != pmTilesVersion {
+		return header, fmt.Errorf("unsupported PMTiles version %d, only version %d is supported", buf[7], pmTilesVersion)
+	}
+	header.rootDirOffset = binary.LittleEndian.Uint64(buf[8:16])
+	header.rootDirLength = binary.LittleEndian.Uint64(buf[16:24])
+	header.leafDirsOffset = binary.LittleEndian.Uint64(buf[40:48])
+	header.tileDataOffset = binary.LittleEndian.Uint64(buf[56:64])
+	header.internalCompression = buf[97]
+	header.tileCompression = buf[98]
+	header.tileType = buf[99]
+
+	if header.tileType != pmTilesTileTypeMVT {
+		return header, errors.New("only vector tiles (MVT) are supported")
+	}
+	for _, c := range []uint8{header.internalCompression, header.tileCompression} {
+		if c != pmTilesCompressionNone && c != pmTilesCompressionGzip {
+			return header, fmt.Errorf("unsupported compression type %d, only none or gzip is supported", c)
+		}
+	}
+	return header, nil
+}
+
+func (p *pmTiles) Tile(_ context.Context, tileMatrix, tileRow, tileCol int) ([]byte, error) {
+	if tileMatrix < 0 || tileMatrix > pmTilesMaxTileMatrix || tileRow < 0 || tileCol < 0 {
+		return nil, nil
+	}
+	// PMTiles uses the XYZ tiling scheme, which - like OGC TileMatrixSets - has its origin at the top-left.
+	tileID := zxyToTileID(uint8(tileMatrix), uint32(tileCol), uint32(tileRow))
+
+	dirOffset, dirLength := p.header.rootDirOffset, p.header.rootDirLength
+	dir := p.rootDir
+	for depth := 0; depth <= pmTilesMaxDirDepth; depth++ {
+		if depth > 0 {
+			var err error
+			if dir, err = p.readLeafDirectory(dirOffset, dirLength); err != nil {
+				return nil, err
+			}
+		}
+		entry, ok := findTile(dir, tileID)
+		if !ok {
+			return nil, nil
+		}
+		if entry.runLength > 0 {
+			data := make([]byte, entry.length)
+			if _, err := p.file.ReadAt(data, int64(p.header.tileDataOffset+entry.offset)); err != nil { //nolint:gosec
+				return nil, fmt.Errorf("failed to read tile %d/%d/%d from PMTiles archive: %w", tileMatrix, tileRow, tileCol, err)
+			}
+			return data, nil
+		}
+		// entry points to a leaf directory
+		dirOffset = p.header.leafDirsOffset + entry.offset
+		dirLength = uint64(entry.length)
+	}
+	return nil, errors.New("maximum directory depth exceeded in PMTiles archive")
+}
+
+func (p *pmTiles) Close() error {
+	return p.file.Close()
+}
+
+func (p *pmTiles) readLeafDirectory(offset, length uint64) ([]pmTilesEntry, error) {
+	if dir, ok := p.leafDirs.Get(offset); ok {
+		return dir, nil
+	}
+	dir, err := p.readDirectory(offset, length)
+	if err != nil {
+		return nil, err
+	}
+	p.leafDirs.Add(offset, dir)
+	return dir, nil
+}
+
+func (p *pmTiles) readDirectory(offset, length uint64) ([]pmTilesEntry, error) {
+	buf := make([]byte, length)
+	if _, err := p.file.ReadAt(buf, int64(offset)); err != nil { //nolint:gosec
+		return nil, fmt.Errorf("failed to read directory: %w", err)
+	}
+	var reader io.Reader = bytes.NewReader(buf)
+	if p.header.internalCompression == pmTilesCompressionGzip {
+		gz, err := gzip.NewReader(reader)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress directory: %w", err)
+		}
+		defer gz.Close()
+		reader = gz
+	}
+	dir, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress directory: %w", err)
+	}
+	return deserializeDirectory(dir)
+}
+
+// deserializeDirectory decodes a directory, which is a list of varint encoded
+// columns: tile IDs (delta encoded), run lengths, lengths and offsets.
+func deserializeDirectory(dir []byte) ([]pmTilesEntry, error) {
+	reader := bytes.NewReader(dir)
+	numEntries, err := binary.ReadUvarint(reader)
+	if err != nil {
+		return nil, fmt.Errorf("invalid directory: %w", err)
+	}
+	if numEntries > uint64(len(dir)) {
+		return nil, errors.New("invalid directory: too many entries")
+	}
+	entries := make([]pmTilesEntry, numEntries)
+
+	var lastID uint64
+	for i := range entries {
+		delta, err := binary.ReadUvarint(reader)
+		if err != nil {
+			return nil, fmt.Errorf("invalid directory: %w", err)
+		}
+		entries[i].tileID = lastID + delta
+		lastID = entries[i].tileID
+	}
+	for i := range entries {
+		runLength, err := binary.ReadUvarint(reader)
+		if err != nil {
+			return nil, fmt.Errorf("invalid directory: %w", err)
+		}
+		entries[i].runLength = uint32(runLength) //nolint:gosec
+	}
+	for i := range entries {
+		length, err := binary.ReadUvarint(reader)
+		if err != nil {
+			return nil, fmt.Errorf("invalid directory: %w", err)
+		}
+		entries[i].length = uint32(length) //nolint:gosec
+	}
+	for i := range entries {
+		offset, err := binary.ReadUvarint(reader)
+		if err != nil {
+			return nil, fmt.Errorf("invalid directory: %w", err)
+		}
+		if offset == 0 && i > 0 {
+			// tile data is contiguous with the previous entry
+			entries[i].offset = entries[i-1].offset + uint64(entries[i-1].length)
+		} else {
+			entries[i].offset = offset - 1
+		}
+	}
+	return entries, nil
+}
+
+// findTile returns the directory entry containing the given tile ID, which is
+// either a (run of) tile(s) or - when the run length is 0 - a leaf directory.
+func findTile(entries []pmTilesEntry, tileID uint64) (pmTilesEntry, bool) {
+	// index of the last entry with a tile ID <= the requested tile ID
+	i := sort.Search(len(entries), func(i int) bool { return entries[i].tileID > tileID }) - 1
+	if i < 0 {
+		return pmTilesEntry{}, false
+	}
+	entry := entries[i]
+	if entry.runLength == 0 || tileID-entry.tileID < uint64(entry.runLength) {
+		return entry, true
+	}
+	return pmTilesEntry{}, false
+}
+
+// zxyToTileID converts tile coordinates to a PMTiles tile ID, which is the position
+// of the tile on a Hilbert curve, offset by the number of tiles in lower zoom levels.
+func zxyToTileID(z uint8, x, y uint32) uint64 {
+	var tileID uint64 = ((1 << (2 * uint64(z))) - 1) / 3
+	n := uint32(1) << z
+	for s := n / 2; s > 0; s /= 2 {
+		var rx, ry uint32
+		if x&s > 0 {
+			rx = 1
+		}
+		if y&s > 0 {
+			ry = 1
+		}
+		tileID += uint64(s) * uint64(s) * uint64((3*rx)^ry)
+		// rotate quadrant
+		if ry == 0 {
+			if rx == 1 {
+				x = n - 1 - x
+				y = n - 1 - y
+			}
+			x, y = y, x
+		}
+	}
+	return tileID
+}
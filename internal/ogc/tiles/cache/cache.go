@@ -0,0 +1,189 @@
+package cache
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PDOK/gokoala/config"
+	"github.com/PDOK/gokoala/internal/engine"
+)
+
+const (
+	// rough estimate of the memory used by a cache entry besides the tile itself (key, headers, etc.)
+	entryOverhead = 512
+)
+
+// headers of the tile server response which are stored along with the tile
+var cachedHeaders = []string{
+	engine.HeaderContentType,
+	engine.HeaderContentEncoding,
+	engine.HeaderCacheControl,
+	engine.HeaderETag,
+	engine.HeaderLastModified,
+}
+
+// Tile cached response of the tile server.
+type Tile struct {
+	// StatusCode of the response, either 200 or 204 (empty tile)
+	StatusCode int
+
+	// Header relevant headers of the response
+	Header http.Header
+
+	// Body the tile itself, empty for 204
+	Body []byte
+
+	// Expires when the tile should be revalidated with the tile server
+	Expires time.Time
+}
+
+// Fresh true when the tile doesn't need to be revalidated with the tile server.
+func (t *Tile) Fresh() bool {
+	return time.Now().Before(t.Expires)
+}
+
+// ETag validator of the tile, empty when the tile server didn't provide one.
+func (t *Tile) ETag() string {
+	return t.Header.Get(engine.HeaderETag)
+}
+
+// LastModified validator of the tile, empty when the tile server didn't provide one.
+func (t *Tile) LastModified() string {
+	return t.Header.Get(engine.HeaderLastModified)
+}
+
+func (t *Tile) size() int64 {
+	return int64(len(t.Body) + entryOverhead)
+}
+
+// Cache two-tier (memory and optionally disk) cache of tiles. Tiles are stored in both
+// tiers, each tier evicts tiles independently on a least-recently used (LRU) basis.
+// Tiles evicted from memory are read back from disk when requested again.
+type Cache struct {
+	memory        *sizedLRU[*Tile]
+	disk          *diskCache
+	defaultMaxAge time.Duration
+}
+
+// New creates a new tile cache based on the given config.
+func New(cfg config.TilesCache) (*Cache, error) {
+	maxSize, err := cfg.MaxSizeAsBytes()
+	if err != nil {
+		return nil, fmt.Errorf("invalid max size of tile cache: %w", err)
+	}
+	c := &Cache{defaultMaxAge: cfg.DefaultMaxAge.Duration}
+	if cfg.Disk != nil {
+		maxDiskSize, err := cfg.Disk.MaxSizeAsBytes()
+		if err != nil {
+			return nil, fmt.Errorf("invalid max size of tile disk cache: %w", err)
+		}
+		if c.disk, err = newDiskCache(cfg.Disk.Path, maxDiskSize); err != nil {
+			return nil, err
+		}
+	}
+	c.memory = newSizedLRU[*Tile](cfg.MaxEntries, maxSize, (*Tile).size, nil)
+
+	return c, nil
+}
+
+// Get returns the cached tile, or nil when the tile isn't cached. Note: the tile may be stale.
+func (c *Cache) Get(key string) *Tile {
+	if tile, ok := c.memory.Get(key); ok {
+		return tile
+	}
+	if c.disk != nil {
+		if tile := c.disk.get(key); tile != nil {
+			c.memory.Add(key, tile)
+
+			return tile
+		}
+	}
+
+	return nil
+}
+
+// Put stores the given response of the tile server in the cache. Returns the
+// cached tile, or nil when the response isn't cacheable.
+func (c *Cache) Put(key string, statusCode int, header http.Header, body []byte) *Tile {
+	if statusCode != http.StatusOK && statusCode != http.StatusNoContent {
+		return nil
+	}
+	maxAge, cacheable := c.maxAge(header)
+	if !cacheable {
+		return nil
+	}
+	tile := &Tile{
+		StatusCode: statusCode,
+		Header:     make(http.Header),
+		Body:       body,
+		Expires:    time.Now().Add(maxAge),
+	}
+	for _, name := range cachedHeaders {
+		if value := header.Get(name); value != "" {
+			tile.Header.Set(name, value)
+		}
+	}
+	if maxAge <= 0 && tile.ETag() == "" && tile.LastModified() == "" {
+		// would need to be fetched again on each request anyway
+		return nil
+	}
+	c.store(key, tile)
+
+	return tile
+}
+
+// Refresh marks the given stale tile as fresh again, after the tile server confirmed
+// (using a 304 Not Modified response with the given headers) that the tile is still valid.
+func (c *Cache) Refresh(key string, tile *Tile, header http.Header) *Tile {
+	maxAge, cacheable := c.maxAge(header)
+	if !cacheable {
+		return tile
+	}
+	refreshed := *tile
+	refreshed.Header = tile.Header.Clone()
+	if cacheControl := header.Get(engine.HeaderCacheControl); cacheControl != "" {
+		refreshed.Header.Set(engine.HeaderCacheControl, cacheControl)
+	}
+	refreshed.Expires = time.Now().Add(maxAge)
+	c.store(key, &refreshed)
+
+	return &refreshed
+}
+
+func (c *Cache) store(key string, tile *Tile) {
+	c.memory.Add(key, tile)
+	if c.disk != nil {
+		c.disk.put(key, tile)
+	}
+}
+
+// maxAge determines how long a response may be cached, based on the Cache-Control header.
+// Returns false when the response may not be cached at all.
+func (c *Cache) maxAge(header http.Header) (time.Duration, bool) {
+	maxAge := c.defaultMaxAge
+	sharedMaxAge := false
+	for _, directive := range strings.Split(header.Get(engine.HeaderCacheControl), ",") {
+		name, value, _ := strings.Cut(strings.ToLower(strings.TrimSpace(directive)), "=")
+		switch name {
+		case "no-store", "private":
+			return 0, false
+		case "no-cache":
+			return 0, true
+		case "s-maxage":
+			if seconds, err := strconv.Atoi(value); err == nil {
+				maxAge = time.Duration(seconds) * time.Second
+				sharedMaxAge = true
+			}
+		case "max-age":
+			// s-maxage takes precedence over max-age for shared caches like this one
+			if seconds, err := strconv.Atoi(value); err == nil && !sharedMaxAge {
+				maxAge = time.Duration(seconds) * time.Second
+			}
+		}
+	}
+
+	return maxAge, true
+}
@@ -0,0 +1,179 @@
+package cache
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/PDOK/gokoala/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestCache(t *testing.T, maxEntries int, maxSize string, disk *config.TilesDiskCache) *Cache {
+	t.Helper()
+	c, err := New(config.TilesCache{
+		MaxEntries:    maxEntries,
+		MaxSize:       maxSize,
+		DefaultMaxAge: config.Duration{Duration: time.Hour},
+		Disk:          disk,
+	})
+	require.NoError(t, err)
+
+	return c
+}
+
+func TestCache_maxAge(t *testing.T) {
+	c := newTestCache(t, 10, "1Mb", nil)
+	tests := []struct {
+		cacheControl  string
+		wantMaxAge    time.Duration
+		wantCacheable bool
+	}{
+		{"", time.Hour, true},
+		{"public", time.Hour, true},
+		{"max-age=60", time.Minute, true},
+		{"public, max-age=60, s-maxage=120", 2 * time.Minute, true},
+		{"s-maxage=120, max-age=60", 2 * time.Minute, true},
+		{"no-cache", 0, true},
+		{"no-store", 0, false},
+		{"private, max-age=60", 0, false},
+		{"max-age=foo", time.Hour, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.cacheControl, func(t *testing.T) {
+			header := http.Header{}
+			header.Set("Cache-Control", tt.cacheControl)
+			maxAge, cacheable := c.maxAge(header)
+			assert.Equal(t, tt.wantMaxAge, maxAge)
+			assert.Equal(t, tt.wantCacheable, cacheable)
+		})
+	}
+}
+
+func TestCache_PutAndGet(t *testing.T) {
+	c := newTestCache(t, 10, "1Mb", nil)
+
+	header := http.Header{}
+	header.Set("Content-Type", "application/vnd.mapbox-vector-tile")
+	header.Set("ETag", `"abc"`)
+	header.Set("X-Something-Else", "foo")
+	tile := c.Put("a", http.StatusOK, header, []byte("tile"))
+	require.NotNil(t, tile)
+	assert.True(t, tile.Fresh())
+	assert.Equal(t, `"abc"`, tile.ETag())
+	assert.Empty(t, tile.Header.Get("X-Something-Else"))
+
+	cached := c.Get("a")
+	require.NotNil(t, cached)
+	assert.Equal(t, []byte("tile"), cached.Body)
+	assert.Nil(t, c.Get("b"))
+
+	// empty tiles are cached too
+	assert.NotNil(t, c.Put("empty", http.StatusNoContent, http.Header{}, nil))
+	assert.NotNil(t, c.Get("empty"))
+
+	// errors and uncacheable responses aren't
+	assert.Nil(t, c.Put("error", http.StatusBadGateway, http.Header{}, []byte("error")))
+	assert.Nil(t, c.Get("error"))
+	noStore := http.Header{}
+	noStore.Set("Cache-Control", "no-store")
+	assert.Nil(t, c.Put("nostore", http.StatusOK, noStore, []byte("tile")))
+	assert.Nil(t, c.Get("nostore"))
+
+	// no-cache without a validator is useless to cache
+	noCache := http.Header{}
+	noCache.Set("Cache-Control", "no-cache")
+	assert.Nil(t, c.Put("nocache", http.StatusOK, noCache, []byte("tile")))
+	noCache.Set("ETag", `"def"`)
+	stale := c.Put("nocache", http.StatusOK, noCache, []byte("tile"))
+	require.NotNil(t, stale)
+	assert.False(t, stale.Fresh())
+}
+
+func TestCache_Refresh(t *testing.T) {
+	c := newTestCache(t, 10, "1Mb", nil)
+
+	header := http.Header{}
+	header.Set("Cache-Control", "max-age=0")
+	header.Set("ETag", `"abc"`)
+	tile := c.Put("a", http.StatusOK, header, []byte("tile"))
+	require.NotNil(t, tile)
+	assert.False(t, tile.Fresh())
+
+	notModified := http.Header{}
+	notModified.Set("Cache-Control", "max-age=60")
+	refreshed := c.Refresh("a", tile, notModified)
+	assert.True(t, refreshed.Fresh())
+	assert.Equal(t, "max-age=60", refreshed.Header.Get("Cache-Control"))
+	assert.Equal(t, []byte("tile"), refreshed.Body)
+	assert.True(t, c.Get("a").Fresh())
+}
+
+func TestCache_Eviction(t *testing.T) {
+	// by number of entries
+	c := newTestCache(t, 2, "1Mb", nil)
+	for i := range 3 {
+		c.Put(fmt.Sprintf("%d", i), http.StatusOK, http.Header{}, []byte("tile"))
+	}
+	assert.Nil(t, c.Get("0"))
+	assert.NotNil(t, c.Get("1"))
+	assert.NotNil(t, c.Get("2"))
+
+	// by size
+	c = newTestCache(t, 100, "4kb", nil) // 1000 bytes + overhead per tile, so only 2 fit
+	for i := range 3 {
+		c.Put(fmt.Sprintf("%d", i), http.StatusOK, http.Header{}, make([]byte, 1000))
+	}
+	assert.Nil(t, c.Get("0"))
+	assert.NotNil(t, c.Get("1"))
+	assert.NotNil(t, c.Get("2"))
+	assert.LessOrEqual(t, c.memory.Size(), int64(4000))
+
+	// tiles larger than the cache itself aren't cached
+	c.Put("huge", http.StatusOK, http.Header{}, make([]byte, 5000))
+	assert.Nil(t, c.Get("huge"))
+	assert.NotNil(t, c.Get("2"))
+}
+
+func TestCache_Disk(t *testing.T) {
+	dir := t.TempDir()
+	leftover := filepath.Join(dir, "leftover"+diskCacheFileExt)
+	unrelated := filepath.Join(dir, "unrelated.txt")
+	require.NoError(t, os.WriteFile(leftover, []byte("old"), 0o600))
+	require.NoError(t, os.WriteFile(unrelated, []byte("keep"), 0o600))
+
+	c := newTestCache(t, 1, "1Mb", &config.TilesDiskCache{Path: dir, MaxSize: "1Mb"})
+	assert.NoFileExists(t, leftover, "previous cached tiles should be removed on startup")
+	assert.FileExists(t, unrelated)
+
+	header := http.Header{}
+	header.Set("Content-Type", "image/png")
+	c.Put("a", http.StatusOK, header, []byte("tile a"))
+	c.Put("b", http.StatusOK, header, []byte("tile b"))
+	assert.Equal(t, 1, c.memory.Len())
+
+	// "a" is evicted from memory, but still on disk
+	tile := c.Get("a")
+	require.NotNil(t, tile)
+	assert.Equal(t, []byte("tile a"), tile.Body)
+	assert.Equal(t, "image/png", tile.Header.Get("Content-Type"))
+	assert.True(t, tile.Fresh())
+
+	// evicted from disk
+	c = newTestCache(t, 1, "1Mb", &config.TilesDiskCache{Path: dir, MaxSize: "1kb"})
+	c.Put("a", http.StatusOK, header, make([]byte, 700))
+	c.Put("b", http.StatusOK, header, make([]byte, 700))
+	assert.NoFileExists(t, c.disk.path("a"))
+	assert.FileExists(t, c.disk.path("b"))
+	assert.Nil(t, c.Get("a"))
+	assert.NotNil(t, c.Get("b"))
+}
+
+func TestNew_InvalidSize(t *testing.T) {
+	_, err := New(config.TilesCache{MaxEntries: 1, MaxSize: "lots"})
+	require.Error(t, err)
+}
@@ -0,0 +1,105 @@
+package cache
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+)
+
+const diskCacheFileExt = ".tile"
+
+// diskCache second tier of the tile cache, stores each tile in a separate file.
+type diskCache struct {
+	dir   string
+	index *sizedLRU[int64] // size of each file on disk by cache key
+}
+
+func newDiskCache(dir string, maxSize int64) (*diskCache, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create tile cache dir %s: %w", dir, err)
+	}
+	// the index isn't persisted, so start with an empty cache. Only remove
+	// our own files in case the dir is (accidentally) shared with other files.
+	existing, err := filepath.Glob(filepath.Join(dir, "*"+diskCacheFileExt))
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range existing {
+		if err = os.Remove(file); err != nil {
+			return nil, fmt.Errorf("failed to clean up tile cache dir %s: %w", dir, err)
+		}
+	}
+
+	d := &diskCache{dir: dir}
+	d.index = newSizedLRU[int64](0, maxSize, func(size int64) int64 { return size }, func(key string, _ int64) {
+		if err := os.Remove(d.path(key)); err != nil && !os.IsNotExist(err) {
+			log.Printf("failed to remove tile from disk cache: %v", err)
+		}
+	})
+
+	return d, nil
+}
+
+func (d *diskCache) get(key string) *Tile {
+	if _, ok := d.index.Get(key); !ok {
+		return nil
+	}
+	contents, err := os.ReadFile(d.path(key))
+	if err != nil {
+		d.index.Remove(key)
+
+		return nil
+	}
+	var tile Tile
+	if err = gob.NewDecoder(bytes.NewReader(contents)).Decode(&tile); err != nil {
+		log.Printf("failed to decode tile from disk cache, removing it: %v", err)
+		d.index.Remove(key)
+
+		return nil
+	}
+
+	return &tile
+}
+
+func (d *diskCache) put(key string, tile *Tile) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(tile); err != nil {
+		log.Printf("failed to encode tile for disk cache: %v", err)
+
+		return
+	}
+	// write to temp file first, so readers never see partially written tiles
+	tmp, err := os.CreateTemp(d.dir, "tile-*.tmp")
+	if err != nil {
+		log.Printf("failed to write tile to disk cache: %v", err)
+
+		return
+	}
+	_, err = tmp.Write(buf.Bytes())
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), d.path(key))
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+		log.Printf("failed to write tile to disk cache: %v", err)
+
+		return
+	}
+	if !d.index.Add(key, int64(buf.Len())) {
+		_ = os.Remove(d.path(key))
+	}
+}
+
+func (d *diskCache) path(key string) string {
+	hash := sha256.Sum256([]byte(key))
+
+	return filepath.Join(d.dir, hex.EncodeToString(hash[:])+diskCacheFileExt)
+}
@@ -0,0 +1,89 @@
+package cache
+
+import (
+	"math"
+	"sync"
+
+	"github.com/hashicorp/golang-lru/v2/simplelru"
+)
+
+// sizedLRU least-recently used (LRU) cache bounded by both the number of entries and the total size of the entries.
+type sizedLRU[V any] struct {
+	mu      sync.Mutex
+	lru     *simplelru.LRU[string, V]
+	size    int64
+	maxSize int64
+	sizeOf  func(V) int64
+}
+
+func newSizedLRU[V any](maxEntries int, maxSize int64, sizeOf func(V) int64, onEvict func(string, V)) *sizedLRU[V] {
+	if maxEntries <= 0 {
+		maxEntries = math.MaxInt32
+	}
+	c := &sizedLRU[V]{maxSize: maxSize, sizeOf: sizeOf}
+	// callback is invoked while holding the lock, so no need to lock again
+	c.lru, _ = simplelru.NewLRU[string, V](maxEntries, func(key string, value V) {
+		c.size -= c.sizeOf(value)
+		if onEvict != nil {
+			onEvict(key, value)
+		}
+	})
+
+	return c
+}
+
+// Get returns the value for the given key and marks it as recently used.
+func (c *sizedLRU[V]) Get(key string) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.lru.Get(key)
+}
+
+// Add adds or replaces the value for the given key, evicting the least-recently used
+// entries when the cache grows beyond its limits. Returns false when the value doesn't fit in the cache at all.
+func (c *sizedLRU[V]) Add(key string, value V) bool {
+	size := c.sizeOf(value)
+	if size > c.maxSize {
+		return false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if old, ok := c.lru.Peek(key); ok {
+		// replacing an entry doesn't trigger eviction callback
+		c.size -= c.sizeOf(old)
+	}
+	c.lru.Add(key, value)
+	c.size += size
+	for c.size > c.maxSize {
+		c.lru.RemoveOldest()
+	}
+
+	return true
+}
+
+// Remove removes the given key from the cache.
+func (c *sizedLRU[V]) Remove(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.lru.Remove(key)
+}
+
+// Len number of entries in the cache.
+func (c *sizedLRU[V]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.lru.Len()
+}
+
+// Size total size of the entries in the cache.
+func (c *sizedLRU[V]) Size() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.size
+}
@@ -0,0 +1,253 @@
+package tiles
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/PDOK/gokoala/config"
+	"github.com/PDOK/gokoala/internal/engine"
+	"github.com/PDOK/gokoala/internal/engine/util"
+	"github.com/PDOK/gokoala/internal/ogc/tiles/cache"
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	cacheHit         = "HIT"
+	cacheMiss        = "MISS"
+	cacheRevalidated = "REVALIDATED"
+
+	// max duration of a (shared) request to the tile server on a cache miss
+	fetchTileTimeout = 30 * time.Second
+)
+
+// serveTileFromCache serves the tile from cache when available, otherwise the
+// tile is retrieved from the tile server and stored in the cache.
+func (t *Tiles) serveTileFromCache(w http.ResponseWriter, r *http.Request, key string, target *url.URL, mediaType string) {
+	if tile := t.cache.Get(key); tile != nil && tile.Fresh() {
+		writeCachedTile(w, r, tile, cacheHit)
+
+		return
+	}
+	tile, response, cacheStatus := t.fetchTile(r, key, target, mediaType)
+	if tile != nil {
+		writeCachedTile(w, r, tile, cacheStatus)
+
+		return
+	}
+	// not cacheable (e.g. an error from the tile server), pass response as-is
+	for name, values := range response.header {
+		w.Header()[name] = values
+	}
+	w.Header().Set(engine.HeaderCache, cacheMiss)
+	w.WriteHeader(response.statusCode)
+	engine.SafeWrite(w.Write, response.body.Bytes())
+}
+
+// fetchTile retrieves tile from the tile server (revalidating stale tiles when possible) and stores it in the cache.
+// Concurrent requests for the same tile result in a single request to the tile server. This
+// request doesn't depend on the context of the first requester, since it serves all requesters.
+// Returns the cached tile, or the raw response of the tile server when the tile isn't cacheable.
+func (t *Tiles) fetchTile(r *http.Request, key string, target *url.URL, mediaType string) (*cache.Tile, *tileResponse, string) {
+	type result struct {
+		tile        *cache.Tile
+		response    *tileResponse
+		cacheStatus string
+	}
+	value, _, _ := t.inflight.Do(key, func() (any, error) {
+		stale := t.cache.Get(key)
+
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), fetchTileTimeout)
+		defer cancel()
+		proxyReq := r.Clone(ctx)
+		proxyReq.Method = http.MethodGet
+		// we need the complete tile, regardless of what the client already has
+		for _, name := range []string{engine.HeaderIfNoneMatch, engine.HeaderIfModifiedSince, engine.HeaderRange} {
+			proxyReq.Header.Del(name)
+		}
+		// tiles are cached (and served) gzipped when the tile server supports it, clients
+		// not supporting gzip receive a decompressed tile
+		proxyReq.Header.Set(engine.HeaderAcceptEncoding, engine.FormatGzip)
+		if stale != nil {
+			if etag := stale.ETag(); etag != "" {
+				proxyReq.Header.Set(engine.HeaderIfNoneMatch, etag)
+			} else if lastModified := stale.LastModified(); lastModified != "" {
+				proxyReq.Header.Set(engine.HeaderIfModifiedSince, lastModified)
+			}
+		}
+
+		response := newTileResponse()
+		t.engine.ReverseProxy(response, proxyReq, target, true, mediaType)
+
+		if response.statusCode == http.StatusNotModified && stale != nil {
+			return result{t.cache.Refresh(key, stale, response.header), nil, cacheRevalidated}, nil
+		}
+		if tile := t.cache.Put(key, response.statusCode, response.header, response.body.Bytes()); tile != nil {
+			return result{tile, nil, cacheMiss}, nil
+		}
+
+		return result{nil, response, cacheMiss}, nil
+	})
+	res := value.(result)
+
+	return res.tile, res.response, res.cacheStatus
+}
+
+// seedCache fills the cache with the tiles in the configured zoom levels, within the limits of each tileset.
+func (t *Tiles) seedCache(ctx context.Context, seed config.TilesCacheSeed) error {
+	start := time.Now()
+	var seeded atomic.Int64
+
+	g, ctx := errgroup.WithContext(ctx)
+	g.SetLimit(seed.Concurrency)
+	for collectionID, tilesConfig := range tilesByCollection(t.engine) {
+		formats := getTileFormats(tilesConfig, false)
+		if !tilesConfig.HasTileServer() || len(formats) == 0 {
+			continue
+		}
+		format := formats[0]
+		for tileMatrixSetID, ts := range t.tilesets[collectionID] {
+			if len(seed.Srs) > 0 && !slices.Contains(seed.Srs, ts.Srs) {
+				continue
+			}
+			if _, ok := t.archives[collectionID][tileMatrixSetID]; ok && format == engine.FormatMVT {
+				continue // served from archive, no need to cache
+			}
+			if ts.derived != nil {
+				continue // not available on the tile server, derived tiles are cached on request
+			}
+			for zoomLevel := seed.ZoomLevelRange.Start; zoomLevel <= seed.ZoomLevelRange.End; zoomLevel++ {
+				limits, ok := ts.limits[zoomLevel]
+				if !ok {
+					continue
+				}
+				for row := limits.MinTileRow; row <= limits.MaxTileRow; row++ {
+					for col := limits.MinTileCol; col <= limits.MaxTileCol; col++ {
+						g.Go(func() error {
+							if err := ctx.Err(); err != nil {
+								return err
+							}
+							key := tileCacheKey(collectionID, tileMatrixSetID, zoomLevel, row, col, format, t.defaultStyle)
+							if tile := t.cache.Get(key); tile != nil && tile.Fresh() {
+								return nil
+							}
+							target, err := createTilesURL(tileMatrixSetID, strconv.Itoa(zoomLevel), strconv.Itoa(col),
+								strconv.Itoa(row), format, t.defaultStyle, tilesConfig)
+							if err != nil {
+								return err
+							}
+							req, err := http.NewRequestWithContext(ctx, http.MethodGet, target.String(), nil)
+							if err != nil {
+								return err
+							}
+							if tile, _, _ := t.fetchTile(req, key, target, tileFormats[format].MediaType); tile != nil {
+								seeded.Add(1)
+							}
+							return nil
+						})
+					}
+				}
+			}
+		}
+	}
+	if err := g.Wait(); err != nil {
+		return fmt.Errorf("failed to seed tile cache: %w", err)
+	}
+	log.Printf("seeded tile cache with %d tiles in %s", seeded.Load(), time.Since(start).Round(time.Second))
+
+	return nil
+}
+
+func writeCachedTile(w http.ResponseWriter, r *http.Request, tile *cache.Tile, cacheStatus string) {
+	for name, values := range tile.Header {
+		w.Header()[name] = values
+	}
+	w.Header().Set(engine.HeaderCache, cacheStatus)
+	gzipped := tile.Header.Get(engine.HeaderContentEncoding) == engine.FormatGzip
+	if gzipped {
+		// the body depends on the Accept-Encoding of the client, let downstream caches know
+		addVary(w.Header(), engine.HeaderAcceptEncoding)
+	}
+	if etag := tile.ETag(); etag != "" && matchesETag(r.Header.Get(engine.HeaderIfNoneMatch), etag) {
+		w.WriteHeader(http.StatusNotModified)
+
+		return
+	}
+	body := tile.Body
+	if gzipped && !engine.AcceptsEncoding(r, engine.FormatGzip) {
+		var err error
+		if body, err = util.Gunzip(body); err != nil {
+			engine.RenderProblemAndLog(engine.ProblemServerError, w, err)
+
+			return
+		}
+		w.Header().Del(engine.HeaderContentEncoding)
+	}
+	if tile.StatusCode == http.StatusOK {
+		w.Header().Set(engine.HeaderContentLength, strconv.Itoa(len(body)))
+	}
+	w.WriteHeader(tile.StatusCode)
+	if len(body) > 0 {
+		engine.SafeWrite(w.Write, body)
+	}
+}
+
+// addVary adds the given header name to the Vary header, unless it's already listed.
+func addVary(header http.Header, name string) {
+	values := header.Values(engine.HeaderVary)
+	for _, value := range values {
+		for _, candidate := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(candidate), name) {
+				return
+			}
+		}
+	}
+	// clip, since the values may be shared with the header of the cached tile
+	header[engine.HeaderVary] = append(slices.Clip(values), name)
+}
+
+func matchesETag(ifNoneMatch string, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+
+	return false
+}
+
+func tileCacheKey(collectionID string, tileMatrixSetID string, tileMatrix, tileRow, tileCol int, format string, style string) string {
+	return fmt.Sprintf("%s/%s/%d/%d/%d/%s/%s", collectionID, tileMatrixSetID, tileMatrix, tileRow, tileCol, format, style)
+}
+
+// tileResponse records the response of the tile server, so it can be cached.
+type tileResponse struct {
+	statusCode int
+	header     http.Header
+	body       bytes.Buffer
+}
+
+func newTileResponse() *tileResponse {
+	return &tileResponse{statusCode: http.StatusOK, header: make(http.Header)}
+}
+
+func (tr *tileResponse) Header() http.Header {
+	return tr.header
+}
+
+func (tr *tileResponse) Write(b []byte) (int, error) {
+	return tr.body.Write(b)
+}
+
+func (tr *tileResponse) WriteHeader(statusCode int) {
+	tr.statusCode = statusCode
+}
@@ -0,0 +1,203 @@
+package tiles
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/PDOK/gokoala/config"
+	"github.com/PDOK/gokoala/internal/engine"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeTileServer tile server which counts requests per path. Tiles at zoom level 12 are
+// empty (404), tiles at zoom level 11 may not be cached, all other tiles have an ETag.
+type fakeTileServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	requests map[string]int
+}
+
+func newFakeTileServer(t *testing.T) *fakeTileServer {
+	t.Helper()
+	l, err := net.Listen("tcp", "localhost:9091")
+	if err != nil {
+		log.Fatal(err)
+	}
+	fts := &fakeTileServer{requests: make(map[string]int)}
+	fts.Server = httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fts.mu.Lock()
+		fts.requests[r.URL.Path]++
+		fts.mu.Unlock()
+
+		switch {
+		case strings.Contains(r.URL.Path, "/12/"):
+			w.WriteHeader(http.StatusNotFound)
+		case strings.Contains(r.URL.Path, "/11/"):
+			w.Header().Set(engine.HeaderCacheControl, "no-store")
+			engine.SafeWrite(w.Write, []byte(r.URL.Path))
+		case r.Header.Get(engine.HeaderIfNoneMatch) == `"v1"`:
+			w.Header().Set(engine.HeaderCacheControl, "max-age=60")
+			w.WriteHeader(http.StatusNotModified)
+		default:
+			var buf bytes.Buffer
+			gz := gzip.NewWriter(&buf)
+			_, _ = gz.Write([]byte(r.URL.Path))
+			_ = gz.Close()
+			w.Header().Set(engine.HeaderETag, `"v1"`)
+			w.Header().Set(engine.HeaderCacheControl, "max-age=60")
+			w.Header().Set(engine.HeaderContentEncoding, engine.FormatGzip)
+			engine.SafeWrite(w.Write, buf.Bytes())
+		}
+	}))
+	fts.Listener.Close()
+	fts.Listener = l
+	fts.Start()
+	t.Cleanup(fts.Close)
+
+	return fts
+}
+
+func (fts *fakeTileServer) count(path string) int {
+	fts.mu.Lock()
+	defer fts.mu.Unlock()
+
+	return fts.requests[path]
+}
+
+func TestTiles_Cache(t *testing.T) {
+	tileServer := newFakeTileServer(t)
+	newEngine, err := engine.NewEngine("internal/ogc/tiles/testdata/config_tiles_cache.yaml",
+		"internal/engine/testdata/test_theme.yaml", "", false, true)
+	require.NoError(t, err)
+	tiles := NewTiles(newEngine, nil)
+	require.NotNil(t, tiles.cache)
+	handler := tiles.Tile(*newEngine.Config.OgcAPI.Tiles.DatasetTiles)
+
+	getTile := func(tileMatrix, tileRow, tileCol string, header http.Header) *httptest.ResponseRecorder {
+		req, err := createTileRequest("http://localhost:8080/tiles/:tileMatrixSetId/:tileMatrix/:tileRow/:tileCol?f=mvt",
+			"NetherlandsRDNewQuad", tileMatrix, tileRow, tileCol)
+		require.NoError(t, err)
+		for name, values := range header {
+			req.Header[name] = values
+		}
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		return rr
+	}
+	gzipHeader := http.Header{engine.HeaderAcceptEncoding: []string{engine.FormatGzip}}
+
+	t.Run("cache miss, then hit", func(t *testing.T) {
+		rr := getTile("5", "10", "15", nil)
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, cacheMiss, rr.Header().Get(engine.HeaderCache))
+		assert.Equal(t, engine.MediaTypeMVT, rr.Header().Get(engine.HeaderContentType))
+		// client doesn't support gzip, so tile is decompressed
+		assert.Empty(t, rr.Header().Get(engine.HeaderContentEncoding))
+		assert.Equal(t, "/NetherlandsRDNewQuad/5/15/10.pbf", rr.Body.String())
+		assert.Equal(t, []string{engine.HeaderAcceptEncoding}, rr.Header().Values(engine.HeaderVary))
+
+		rr = getTile("5", "10", "15", gzipHeader)
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, cacheHit, rr.Header().Get(engine.HeaderCache))
+		assert.Equal(t, engine.FormatGzip, rr.Header().Get(engine.HeaderContentEncoding))
+		assert.Equal(t, `"v1"`, rr.Header().Get(engine.HeaderETag))
+		assert.Equal(t, "max-age=60", rr.Header().Get(engine.HeaderCacheControl))
+		assert.Equal(t, []string{engine.HeaderAcceptEncoding}, rr.Header().Values(engine.HeaderVary))
+		assert.Equal(t, 1, tileServer.count("/NetherlandsRDNewQuad/5/15/10.pbf"))
+
+		// repeated hits don't pile up the Vary header
+		rr = getTile("5", "10", "15", nil)
+		assert.Equal(t, cacheHit, rr.Header().Get(engine.HeaderCache))
+		assert.Empty(t, rr.Header().Get(engine.HeaderContentEncoding))
+		assert.Equal(t, []string{engine.HeaderAcceptEncoding}, rr.Header().Values(engine.HeaderVary))
+	})
+
+	t.Run("conditional request of client", func(t *testing.T) {
+		rr := getTile("5", "10", "15", http.Header{engine.HeaderIfNoneMatch: []string{`"v1"`}})
+		assert.Equal(t, http.StatusNotModified, rr.Code)
+		assert.Empty(t, rr.Body.String())
+		assert.Equal(t, []string{engine.HeaderAcceptEncoding}, rr.Header().Values(engine.HeaderVary))
+	})
+
+	t.Run("stale tile is revalidated", func(t *testing.T) {
+		key := tileCacheKey("", "NetherlandsRDNewQuad", 5, 10, 15, engine.FormatMVT, "")
+		stale := *tiles.cache.Get(key)
+		stale.Expires = stale.Expires.AddDate(-1, 0, 0)
+		tiles.cache.Refresh(key, &stale, http.Header{engine.HeaderCacheControl: []string{"max-age=0"}})
+		require.False(t, tiles.cache.Get(key).Fresh())
+
+		rr := getTile("5", "10", "15", gzipHeader)
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, cacheRevalidated, rr.Header().Get(engine.HeaderCache))
+		assert.NotEmpty(t, rr.Body.Bytes())
+		assert.Equal(t, 2, tileServer.count("/NetherlandsRDNewQuad/5/15/10.pbf"))
+		assert.True(t, tiles.cache.Get(key).Fresh())
+	})
+
+	t.Run("empty tiles are cached", func(t *testing.T) {
+		for range 2 {
+			rr := getTile("12", "10", "15", nil)
+			assert.Equal(t, http.StatusNoContent, rr.Code)
+		}
+		assert.Equal(t, 1, tileServer.count("/NetherlandsRDNewQuad/12/15/10.pbf"))
+	})
+
+	t.Run("no-store is respected", func(t *testing.T) {
+		for range 2 {
+			rr := getTile("11", "10", "15", nil)
+			assert.Equal(t, http.StatusOK, rr.Code)
+			assert.Equal(t, cacheMiss, rr.Header().Get(engine.HeaderCache))
+			assert.Equal(t, "/NetherlandsRDNewQuad/11/15/10.pbf", rr.Body.String())
+		}
+		assert.Equal(t, 2, tileServer.count("/NetherlandsRDNewQuad/11/15/10.pbf"))
+	})
+
+	t.Run("tile is fetched regardless of cancelled request", func(t *testing.T) {
+		req, err := createTileRequest("http://localhost:8080/tiles/:tileMatrixSetId/:tileMatrix/:tileRow/:tileCol?f=mvt",
+			"NetherlandsRDNewQuad", "6", "10", "15")
+		require.NoError(t, err)
+		ctx, cancel := context.WithCancel(req.Context())
+		cancel()
+		target, err := createTilesURL("NetherlandsRDNewQuad", "6", "15", "10", engine.FormatMVT, "",
+			*newEngine.Config.OgcAPI.Tiles.DatasetTiles)
+		require.NoError(t, err)
+
+		tile, _, cacheStatus := tiles.fetchTile(req.WithContext(ctx), "cancelled", target, engine.MediaTypeMVT)
+		require.NotNil(t, tile)
+		assert.Equal(t, cacheMiss, cacheStatus)
+		assert.Equal(t, http.StatusOK, tile.StatusCode)
+	})
+
+	t.Run("seed", func(t *testing.T) {
+		err := tiles.seedCache(context.Background(), config.TilesCacheSeed{
+			ZoomLevelRange: config.ZoomLevelRange{Start: 0, End: 1},
+			Concurrency:    2,
+		})
+		require.NoError(t, err)
+		for _, path := range []string{"/0/0/0", "/1/0/0", "/1/0/1", "/1/1/0", "/1/1/1"} {
+			assert.Equal(t, 1, tileServer.count("/NetherlandsRDNewQuad"+path+".pbf"), path)
+		}
+
+		rr := getTile("1", "1", "0", nil)
+		assert.Equal(t, cacheHit, rr.Header().Get(engine.HeaderCache))
+		assert.Equal(t, "/NetherlandsRDNewQuad/1/0/1.pbf", rr.Body.String())
+
+		// already seeded tiles aren't requested again
+		require.NoError(t, tiles.seedCache(context.Background(), config.TilesCacheSeed{
+			ZoomLevelRange: config.ZoomLevelRange{Start: 0, End: 1},
+			Srs:            []string{"EPSG:28992"},
+			Concurrency:    2,
+		}))
+		assert.Equal(t, 1, tileServer.count("/NetherlandsRDNewQuad/0/0/0.pbf"))
+	})
+}
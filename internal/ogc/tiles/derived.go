@@ -0,0 +1,467 @@
+package tiles
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/PDOK/gokoala/config"
+	"github.com/PDOK/gokoala/internal/engine"
+	"github.com/PDOK/gokoala/internal/engine/util"
+	"github.com/PDOK/gokoala/internal/ogc/features/proj"
+	"github.com/PDOK/gokoala/internal/ogc/tiles/mvt"
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	// maximum number of source tiles used to derive a single tile
+	maxSourceTiles = 16
+
+	// number of points per edge used when transforming the bounds of a tile
+	densifyPoints = 8
+
+	// buffer around derived vector tiles, as fraction of the tile extent
+	vectorTileBuffer = 1.0 / 64
+
+	// distance (in pixels) between the points which are transformed exactly when
+	// warping raster tiles, the coordinates of the pixels in between are interpolated
+	warpGridSize = 16
+
+	jpegQuality = 90
+)
+
+// derivation tiles derived (reprojected on the fly) from the tiles in another TileMatrixSet.
+type derivation struct {
+	// source tileset from which tiles are derived
+	source tileset
+
+	toSource proj.Transformer
+	toTarget proj.Transformer
+}
+
+// sourceTile tile from which (part of) a derived tile is derived.
+type sourceTile struct {
+	// bounds of the tile in the CRS of the source TileMatrixSet
+	bounds [4]float64
+
+	// data uncompressed tile
+	data []byte
+}
+
+// addDerivations links tilesets which are derived from tiles in another TileMatrixSet to their source.
+func (t *Tiles) addDerivations(collectionID string, tilesConfig config.Tiles) error {
+	for _, supportedSrs := range tilesConfig.SupportedSrs {
+		if !supportedSrs.IsDerived() {
+			continue
+		}
+		ts := t.tilesets[collectionID][supportedSrs.GetTileMatrixSetID()]
+		source, ok := t.tilesets[collectionID][supportedSrs.DerivedFrom]
+		if !ok {
+			return fmt.Errorf("tileMatrixSet '%s' to derive tiles in srs '%s' from is not offered", supportedSrs.DerivedFrom, ts.Srs)
+		}
+		toSource, err := proj.NewTransformer(ts.Srs, source.Srs)
+		if err != nil {
+			return fmt.Errorf("can't derive tiles in srs '%s': %w", ts.Srs, err)
+		}
+		toTarget, err := proj.NewTransformer(source.Srs, ts.Srs)
+		if err != nil {
+			return fmt.Errorf("can't derive tiles in srs '%s': %w", ts.Srs, err)
+		}
+		ts.derived = &derivation{source: source, toSource: toSource, toTarget: toTarget}
+		t.tilesets[collectionID][ts.TileMatrixSet.ID] = ts
+	}
+
+	return nil
+}
+
+// serveDerivedTile serves a tile derived from the tiles in another TileMatrixSet. Derived tiles are cached when
+// the tile cache is enabled, since deriving a tile is relatively expensive.
+func (t *Tiles) serveDerivedTile(w http.ResponseWriter, r *http.Request, tilesConfig config.Tiles, collectionID string,
+	ts tileset, tileMatrix, tileRow, tileCol int, format string, style string) {
+
+	var key string
+	if t.cache != nil {
+		key = tileCacheKey(collectionID, ts.TileMatrixSet.ID, tileMatrix, tileRow, tileCol, format, style)
+		if tile := t.cache.Get(key); tile != nil && tile.Fresh() {
+			writeCachedTile(w, r, tile, cacheHit)
+
+			return
+		}
+	}
+	data, err := t.deriveTile(r, tilesConfig, collectionID, ts, tileMatrix, tileRow, tileCol, format, style)
+	if err != nil {
+		engine.RenderProblemAndLog(engine.ProblemServerError, w, err)
+
+		return
+	}
+	statusCode := http.StatusOK
+	if data == nil {
+		statusCode = http.StatusNoContent
+	}
+	header := http.Header{}
+	header.Set(engine.HeaderContentType, tileFormats[format].MediaType)
+	if t.cache != nil {
+		if tile := t.cache.Put(key, statusCode, header, data); tile != nil {
+			writeCachedTile(w, r, tile, cacheMiss)
+
+			return
+		}
+	}
+	if data == nil {
+		w.WriteHeader(http.StatusNoContent)
+
+		return
+	}
+	w.Header().Set(engine.HeaderContentType, tileFormats[format].MediaType)
+	w.Header().Set(engine.HeaderContentLength, strconv.Itoa(len(data)))
+	engine.SafeWrite(w.Write, data)
+}
+
+// deriveTile derives the requested tile from the overlapping tiles in the source TileMatrixSet.
+// Returns nil without error when the derived tile is empty.
+func (t *Tiles) deriveTile(r *http.Request, tilesConfig config.Tiles, collectionID string, ts tileset,
+	tileMatrix, tileRow, tileCol int, format string, style string) ([]byte, error) {
+
+	tm, ok := ts.TileMatrixSet.tileMatrix(tileMatrix)
+	if !ok {
+		return nil, fmt.Errorf("tileMatrix %d not found in tileMatrixSet '%s'", tileMatrix, ts.TileMatrixSet.ID)
+	}
+	bounds := ts.TileMatrixSet.tileBounds(tm, tileRow, tileCol)
+	sourceTM, limits, ok := ts.derived.sourceTileMatrix(tm, transformBounds(bounds, ts.derived.toSource))
+	if !ok {
+		return nil, nil // tile is outside the source tiles
+	}
+	sources, err := t.fetchSourceTiles(r, tilesConfig, collectionID, ts.derived.source, sourceTM, limits, format, style)
+	if err != nil || len(sources) == 0 {
+		return nil, err
+	}
+	if format == engine.FormatMVT {
+		return deriveVectorTile(ts.derived, bounds, sources)
+	}
+
+	return deriveRasterTile(ts.derived, tm, bounds, sources, format)
+}
+
+// sourceTileMatrix selects the TileMatrix of the source tiles with a resolution matching the target TileMatrix,
+// as long as the number of source tiles covering the given bounds doesn't exceed the maximum.
+func (d *derivation) sourceTileMatrix(target TileMatrix, bounds [4]float64) (TileMatrix, TileMatrixSetLimits, bool) {
+	for _, v := range bounds {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return TileMatrix{}, TileMatrixSetLimits{}, false
+		}
+	}
+	candidates := d.source.TileMatrices()
+	if len(candidates) == 0 {
+		return TileMatrix{}, TileMatrixSetLimits{}, false
+	}
+
+	// least detailed tile matrix with at least the resolution of the target tile matrix, otherwise the most detailed
+	cellSize := (bounds[2] - bounds[0]) / float64(target.TileWidth)
+	selected := len(candidates) - 1
+	for i, candidate := range candidates {
+		if candidate.CellSize <= cellSize {
+			selected = i
+			break
+		}
+	}
+	for ; selected >= 0; selected-- {
+		tm := candidates[selected]
+		limits, ok := d.sourceLimits(tm, bounds)
+		if !ok {
+			return TileMatrix{}, TileMatrixSetLimits{}, false
+		}
+		count := (limits.MaxTileRow - limits.MinTileRow + 1) * (limits.MaxTileCol - limits.MinTileCol + 1)
+		if count <= maxSourceTiles || selected == 0 {
+			return tm, limits, true
+		}
+	}
+
+	return TileMatrix{}, TileMatrixSetLimits{}, false
+}
+
+// sourceLimits range of source tiles in the given TileMatrix covering the given bounds,
+// restricted to the source tiles which are available.
+func (d *derivation) sourceLimits(tm TileMatrix, bounds [4]float64) (TileMatrixSetLimits, bool) {
+	tms := d.source.TileMatrixSet
+	first := tms.tileBounds(tm, 0, 0)
+	last := tms.tileBounds(tm, tm.MatrixHeight-1, tm.MatrixWidth-1)
+	if bounds[2] <= min(first[0], last[0]) || bounds[0] >= max(first[2], last[2]) ||
+		bounds[3] <= min(first[1], last[1]) || bounds[1] >= max(first[3], last[3]) {
+		return TileMatrixSetLimits{}, false // outside tile matrix
+	}
+
+	zoomLevel, _ := strconv.Atoi(tm.ID)
+	available := d.source.limits[zoomLevel]
+	limits := tms.limitsWithin(tm, bounds[:])
+	limits.MinTileRow = max(limits.MinTileRow, available.MinTileRow)
+	limits.MaxTileRow = min(limits.MaxTileRow, available.MaxTileRow)
+	limits.MinTileCol = max(limits.MinTileCol, available.MinTileCol)
+	limits.MaxTileCol = min(limits.MaxTileCol, available.MaxTileCol)
+
+	return limits, limits.MinTileRow <= limits.MaxTileRow && limits.MinTileCol <= limits.MaxTileCol
+}
+
+// transformBounds transforms the given bounds (minx, miny, maxx, maxy) using a densified
+// outline of the bounds, since straight lines may be curved after transformation.
+func transformBounds(bounds [4]float64, transform proj.Transformer) [4]float64 {
+	result := [4]float64{math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)}
+	for i := 0; i <= densifyPoints; i++ {
+		f := float64(i) / densifyPoints
+		x := bounds[0] + f*(bounds[2]-bounds[0])
+		y := bounds[1] + f*(bounds[3]-bounds[1])
+		for _, p := range [4][2]float64{{x, bounds[1]}, {x, bounds[3]}, {bounds[0], y}, {bounds[2], y}} {
+			tx, ty := transform(p[0], p[1])
+			result = [4]float64{math.Min(result[0], tx), math.Min(result[1], ty), math.Max(result[2], tx), math.Max(result[3], ty)}
+		}
+	}
+
+	return result
+}
+
+// fetchSourceTiles retrieves the non-empty source tiles within the given limits concurrently.
+func (t *Tiles) fetchSourceTiles(r *http.Request, tilesConfig config.Tiles, collectionID string, source tileset,
+	tm TileMatrix, limits TileMatrixSetLimits, format string, style string) ([]sourceTile, error) {
+
+	zoomLevel, _ := strconv.Atoi(tm.ID)
+	columns := limits.MaxTileCol - limits.MinTileCol + 1
+	tiles := make([]sourceTile, (limits.MaxTileRow-limits.MinTileRow+1)*columns)
+
+	g, ctx := errgroup.WithContext(r.Context())
+	for row := limits.MinTileRow; row <= limits.MaxTileRow; row++ {
+		for col := limits.MinTileCol; col <= limits.MaxTileCol; col++ {
+			g.Go(func() error {
+				data, err := t.fetchUncompressedTile(ctx, tilesConfig, collectionID, source.TileMatrixSet.ID,
+					zoomLevel, row, col, format, style)
+				if err != nil {
+					return err
+				}
+				tiles[(row-limits.MinTileRow)*columns+col-limits.MinTileCol] = sourceTile{
+					bounds: source.TileMatrixSet.tileBounds(tm, row, col),
+					data:   data,
+				}
+				return nil
+			})
+		}
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+
+	result := make([]sourceTile, 0, len(tiles))
+	for _, tile := range tiles {
+		if len(tile.data) > 0 {
+			result = append(result, tile)
+		}
+	}
+
+	return result, nil
+}
+
+// fetchUncompressedTile retrieves a single (uncompressed) tile from archive, cache or tile server.
+// Returns nil without error when the tile is empty.
+func (t *Tiles) fetchUncompressedTile(ctx context.Context, tilesConfig config.Tiles, collectionID string, tileMatrixSetID string,
+	tileMatrix, tileRow, tileCol int, format string, style string) ([]byte, error) {
+
+	if tileArchive, ok := t.archives[collectionID][tileMatrixSetID]; ok && format == engine.FormatMVT {
+		tile, err := tileArchive.Tile(ctx, tileMatrix, tileRow, tileCol)
+		if err != nil || tile == nil {
+			return nil, err
+		}
+		if util.IsGzipped(tile) {
+			return util.Gunzip(tile)
+		}
+		return tile, nil
+	}
+
+	target, err := createTilesURL(tileMatrixSetID, strconv.Itoa(tileMatrix), strconv.Itoa(tileCol),
+		strconv.Itoa(tileRow), format, style, tilesConfig)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	mediaType := tileFormats[format].MediaType
+
+	var statusCode int
+	var header http.Header
+	var body []byte
+	if t.cache != nil {
+		key := tileCacheKey(collectionID, tileMatrixSetID, tileMatrix, tileRow, tileCol, format, style)
+		tile := t.cache.Get(key)
+		if tile == nil || !tile.Fresh() {
+			var response *tileResponse
+			if tile, response, _ = t.fetchTile(req, key, target, mediaType); tile == nil {
+				statusCode, header, body = response.statusCode, response.header, response.body.Bytes()
+			}
+		}
+		if tile != nil {
+			statusCode, header, body = tile.StatusCode, tile.Header, tile.Body
+		}
+	} else {
+		req.Header.Set(engine.HeaderAcceptEncoding, engine.FormatGzip)
+		response := newTileResponse()
+		t.engine.ReverseProxy(response, req, target, true, mediaType)
+		statusCode, header, body = response.statusCode, response.header, response.body.Bytes()
+	}
+
+	switch statusCode {
+	case http.StatusOK:
+		if header.Get(engine.HeaderContentEncoding) == engine.FormatGzip || util.IsGzipped(body) {
+			return util.Gunzip(body)
+		}
+		return body, nil
+	case http.StatusNoContent:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("failed to retrieve tile %s, status code: %d", target, statusCode)
+	}
+}
+
+// deriveVectorTile reprojects the features in the given source tiles to a vector tile with the given bounds.
+// Features occurring in multiple (overlapping) source tiles are merged into a single feature, based on feature id.
+func deriveVectorTile(d *derivation, bounds [4]float64, sources []sourceTile) ([]byte, error) {
+	result := &mvt.Tile{}
+	// per layer: feature id -> index of the feature in the derived layer
+	featureIndex := make(map[string]map[uint64]int)
+	for _, source := range sources {
+		tile, err := mvt.Decode(source.data)
+		if err != nil {
+			return nil, err
+		}
+		for _, layer := range tile.Layers {
+			extent := float64(layer.Extent)
+			buffer := extent * vectorTileBuffer
+			target := result.Layer(layer.Name, layer.Version, layer.Extent)
+			if featureIndex[layer.Name] == nil {
+				featureIndex[layer.Name] = make(map[uint64]int)
+			}
+			index := featureIndex[layer.Name]
+
+			// source tile coordinates -> source CRS -> target CRS -> target tile coordinates
+			transform := func(x, y float64) (float64, float64) {
+				x, y = d.toTarget(
+					source.bounds[0]+x/extent*(source.bounds[2]-source.bounds[0]),
+					source.bounds[3]-y/extent*(source.bounds[3]-source.bounds[1]))
+				return (x - bounds[0]) / (bounds[2] - bounds[0]) * extent, (bounds[3] - y) / (bounds[3] - bounds[1]) * extent
+			}
+			for _, feature := range layer.Features {
+				feature.Transform(transform)
+				if !feature.Clip(-buffer, -buffer, extent+buffer, extent+buffer) {
+					continue
+				}
+				if feature.ID != nil {
+					if i, ok := index[*feature.ID]; ok && target.Features[i].Type == feature.Type {
+						target.Features[i].Merge(feature)
+
+						continue
+					}
+					index[*feature.ID] = len(target.Features)
+				}
+				target.Features = append(target.Features, feature)
+			}
+		}
+	}
+	if data := mvt.Encode(result); len(data) > 0 {
+		return data, nil
+	}
+
+	return nil, nil
+}
+
+// deriveRasterTile warps the given source images to an image with the given bounds, using nearest neighbour resampling.
+func deriveRasterTile(d *derivation, tm TileMatrix, bounds [4]float64, sources []sourceTile, format string) ([]byte, error) {
+	images := make([]image.Image, 0, len(sources))
+	for _, source := range sources {
+		img, _, err := image.Decode(bytes.NewReader(source.data))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode tile to derive tile from: %w", err)
+		}
+		images = append(images, img)
+	}
+
+	width, height := tm.TileWidth, tm.TileHeight
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	if format == engine.FormatJPEG {
+		draw.Draw(dst, dst.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	}
+	grid := newWarpGrid(d, bounds, width, height)
+	drawn := false
+	for py := range height {
+		for px := range width {
+			x, y := grid.at(float64(px)+0.5, float64(py)+0.5)
+			for i, source := range sources {
+				b := source.bounds
+				if x < b[0] || x >= b[2] || y <= b[1] || y > b[3] {
+					continue
+				}
+				src := images[i].Bounds()
+				sx := src.Min.X + int((x-b[0])/(b[2]-b[0])*float64(src.Dx()))
+				sy := src.Min.Y + int((b[3]-y)/(b[3]-b[1])*float64(src.Dy()))
+				dst.Set(px, py, images[i].At(sx, sy))
+				drawn = true
+				break
+			}
+		}
+	}
+	if !drawn {
+		return nil, nil
+	}
+
+	var buf bytes.Buffer
+	var err error
+	if format == engine.FormatJPEG {
+		err = jpeg.Encode(&buf, dst, &jpeg.Options{Quality: jpegQuality})
+	} else {
+		err = png.Encode(&buf, dst)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode derived tile: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// warpGrid pixel coordinates transformed to the source CRS. Only the points of a coarse
+// grid are transformed exactly, coordinates in between are interpolated.
+type warpGrid struct {
+	points     [][][2]float64
+	cols, rows int
+}
+
+func newWarpGrid(d *derivation, bounds [4]float64, width, height int) warpGrid {
+	g := warpGrid{cols: width/warpGridSize + 2, rows: height/warpGridSize + 2}
+	g.points = make([][][2]float64, g.rows)
+	for j := range g.rows {
+		g.points[j] = make([][2]float64, g.cols)
+		for i := range g.cols {
+			x := bounds[0] + float64(i*warpGridSize)/float64(width)*(bounds[2]-bounds[0])
+			y := bounds[3] - float64(j*warpGridSize)/float64(height)*(bounds[3]-bounds[1])
+			g.points[j][i][0], g.points[j][i][1] = d.toSource(x, y)
+		}
+	}
+
+	return g
+}
+
+// at bilinear interpolated source coordinate of the given pixel.
+func (g warpGrid) at(px, py float64) (float64, float64) {
+	gx, gy := px/warpGridSize, py/warpGridSize
+	i, j := min(int(gx), g.cols-2), min(int(gy), g.rows-2)
+	fx, fy := gx-float64(i), gy-float64(j)
+
+	p00, p10, p01, p11 := g.points[j][i], g.points[j][i+1], g.points[j+1][i], g.points[j+1][i+1]
+	x := (1-fy)*((1-fx)*p00[0]+fx*p10[0]) + fy*((1-fx)*p01[0]+fx*p11[0])
+	y := (1-fy)*((1-fx)*p00[1]+fx*p10[1]) + fy*((1-fx)*p01[1]+fx*p11[1])
+
+	return x, y
+}
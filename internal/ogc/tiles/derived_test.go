@@ -0,0 +1,153 @@
+package tiles
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/PDOK/gokoala/internal/engine"
+	"github.com/PDOK/gokoala/internal/ogc/tiles/mvt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newFakeSourceTileServer tile server offering vector tiles with a polygon (the same feature in
+// every tile) covering the whole tile and raster tiles completely filled in red. Returns the requested paths.
+func newFakeSourceTileServer(t *testing.T) func() []string {
+	t.Helper()
+	l, err := net.Listen("tcp", "localhost:9092")
+	if err != nil {
+		log.Fatal(err)
+	}
+	var mu sync.Mutex
+	var requested []string
+
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requested = append(requested, r.URL.Path)
+		mu.Unlock()
+
+		if strings.HasSuffix(r.URL.Path, ".png") {
+			img := image.NewRGBA(image.Rect(0, 0, 256, 256))
+			for i := 0; i < len(img.Pix); i += 4 {
+				img.Pix[i], img.Pix[i+3] = 255, 255
+			}
+			var buf bytes.Buffer
+			require.NoError(t, png.Encode(&buf, img))
+			w.Header().Set(engine.HeaderContentType, engine.MediaTypePNG)
+			engine.SafeWrite(w.Write, buf.Bytes())
+
+			return
+		}
+		tile := &mvt.Tile{}
+		layer := tile.Layer("area", 2, mvt.DefaultExtent)
+		id := uint64(1)
+		layer.Features = append(layer.Features, mvt.Feature{
+			ID:       &id,
+			Type:     mvt.Polygon,
+			Tags:     []mvt.Tag{{Key: "name", Value: append([]byte{0x0a, 4}, "test"...)}},
+			Geometry: [][][2]float64{{{-64, -64}, {4160, -64}, {4160, 4160}, {-64, 4160}}},
+		})
+		w.Header().Set(engine.HeaderContentType, engine.MediaTypeMVT)
+		engine.SafeWrite(w.Write, mvt.Encode(tile))
+	}))
+	ts.Listener.Close()
+	ts.Listener = l
+	ts.Start()
+	t.Cleanup(ts.Close)
+
+	return func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		result := requested
+		requested = nil
+
+		return result
+	}
+}
+
+func TestTiles_DerivedTiles(t *testing.T) {
+	requested := newFakeSourceTileServer(t)
+	newEngine, err := engine.NewEngine("internal/ogc/tiles/testdata/config_tiles_derived.yaml",
+		"internal/engine/testdata/test_theme.yaml", "", false, true)
+	require.NoError(t, err)
+	tiles := NewTiles(newEngine, nil)
+	require.NotNil(t, tiles.tilesets[""]["WebMercatorQuad"].derived)
+	require.Nil(t, tiles.tilesets[""]["NetherlandsRDNewQuad"].derived)
+	handler := tiles.Tile(*newEngine.Config.OgcAPI.Tiles.DatasetTiles)
+
+	getTile := func(tileMatrix, tileRow, tileCol, format string) *httptest.ResponseRecorder {
+		req, err := createTileRequest("http://localhost:8080/tiles/:tileMatrixSetId/:tileMatrix/:tileRow/:tileCol?f="+format,
+			"WebMercatorQuad", tileMatrix, tileRow, tileCol)
+		require.NoError(t, err)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		return rr
+	}
+
+	t.Run("vector tile around Amersfoort", func(t *testing.T) {
+		rr := getTile("10", "337", "527", engine.FormatMVT)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		assert.Equal(t, engine.MediaTypeMVT, rr.Header().Get(engine.HeaderContentType))
+
+		tile, err := mvt.Decode(rr.Body.Bytes())
+		require.NoError(t, err)
+		require.Len(t, tile.Layers, 1)
+		assert.Equal(t, "area", tile.Layers[0].Name)
+		// same feature in all source tiles, merged into one
+		require.Len(t, tile.Layers[0].Features, 1)
+		feature := tile.Layers[0].Features[0]
+		assert.Equal(t, "name", feature.Tags[0].Key)
+		for _, part := range feature.Geometry {
+			for _, p := range part {
+				// clipped to the tile, including buffer
+				assert.InDelta(t, 2048, p[0], 2048+64)
+				assert.InDelta(t, 2048, p[1], 2048+64)
+			}
+		}
+		paths := requested()
+		assert.Greater(t, len(paths), 1)
+		for _, path := range paths {
+			assert.True(t, strings.HasPrefix(path, "/NetherlandsRDNewQuad/"), path)
+		}
+	})
+
+	t.Run("raster tile around Amersfoort", func(t *testing.T) {
+		rr := getTile("10", "337", "527", engine.FormatPNG)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		assert.Equal(t, engine.MediaTypePNG, rr.Header().Get(engine.HeaderContentType))
+
+		img, err := png.Decode(rr.Body)
+		require.NoError(t, err)
+		assert.Equal(t, image.Rect(0, 0, 256, 256), img.Bounds())
+		assert.Equal(t, color.RGBAModel.Convert(color.RGBA{R: 255, A: 255}), color.RGBAModel.Convert(img.At(128, 128)))
+	})
+
+	t.Run("tile outside source tiles is empty", func(t *testing.T) {
+		requested()
+		rr := getTile("10", "0", "0", engine.FormatMVT)
+		assert.Equal(t, http.StatusNoContent, rr.Code)
+		assert.Empty(t, requested())
+	})
+
+	t.Run("source tile resolution matches derived tile resolution", func(t *testing.T) {
+		requested()
+		rr := getTile("14", "5399", "8437", engine.FormatMVT)
+		assert.Equal(t, http.StatusOK, rr.Code)
+		paths := requested()
+		require.NotEmpty(t, paths)
+		assert.LessOrEqual(t, len(paths), maxSourceTiles)
+		for _, path := range paths {
+			assert.True(t, strings.HasPrefix(path, "/NetherlandsRDNewQuad/10/"), path)
+		}
+	})
+}
@@ -1,12 +1,15 @@
 package tiles
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"log"
+	"maps"
+	"math"
 	"net/http"
 	"net/url"
-	"os"
+	"slices"
 	"strconv"
 	"strings"
 
@@ -14,8 +17,11 @@ import (
 	"github.com/PDOK/gokoala/internal/engine"
 	"github.com/PDOK/gokoala/internal/engine/util"
 	g "github.com/PDOK/gokoala/internal/ogc/common/geospatial"
+	"github.com/PDOK/gokoala/internal/ogc/features/proj"
+	"github.com/PDOK/gokoala/internal/ogc/tiles/archive"
+	"github.com/PDOK/gokoala/internal/ogc/tiles/cache"
 	"github.com/go-chi/chi/v5"
-	"gopkg.in/yaml.v3"
+	"golang.org/x/sync/singleflight"
 )
 
 const (
@@ -27,7 +33,9 @@ const (
 	defaultTilesTmpl        = "{tms}/{z}/{x}/{y}." + engine.FormatMVTAlternative
 	collectionsCrumb        = "collections/"
 	tilesCrumbTitle         = "Tiles"
-	tmsLimitsDir            = "internal/ogc/tiles/tileMatrixSetLimits/"
+	stylesPath              = "/styles"
+	mapTilesPath            = "/map/tiles"
+	wgs84Srs                = "EPSG:4326"
 )
 
 var (
@@ -49,8 +57,38 @@ var (
 			Path: "collections",
 		},
 	}
+
+	// tile formats by extension of the requested tile, e.g. 15.pbf or 15.png
+	tileFormatsByExtension = map[string]string{
+		engine.FormatMVTAlternative: engine.FormatMVT,
+		engine.FormatPNG:            engine.FormatPNG,
+		"jpg":                       engine.FormatJPEG,
+		engine.FormatJPEG:           engine.FormatJPEG,
+		engine.FormatWebP:           engine.FormatWebP,
+	}
+
+	tileFormats = map[string]tileFormat{
+		engine.FormatMVT:  {engine.FormatMVT, engine.MediaTypeMVT, "Mapbox vector tiles", "Mapbox Vector Tiles"},
+		engine.FormatPNG:  {engine.FormatPNG, engine.MediaTypePNG, "PNG map tiles", "PNG"},
+		engine.FormatJPEG: {engine.FormatJPEG, engine.MediaTypeJPEG, "JPEG map tiles", "JPEG"},
+		engine.FormatWebP: {engine.FormatWebP, engine.MediaTypeWebP, "WebP map tiles", "WebP"},
+	}
 )
 
+type tileFormat struct {
+	// Format as used in ?f= param
+	Format string
+
+	// MediaType of tiles in this format
+	MediaType string
+
+	// Title human-friendly name of this format
+	Title string
+
+	// Name short name of this format, used in error messages
+	Name string
+}
+
 type templateData struct {
 	// Tiles top-level or collection-level tiles config
 	config.Tiles
@@ -58,55 +96,278 @@ type templateData struct {
 	// BaseURL part of the url prefixing "/tiles"
 	BaseURL string
 
-	// All supported projections by GoKoala (for tiles)
-	AllProjections map[string]any
+	// Tilesets offered, one per supported projection
+	Tilesets []tileset
 }
 
-type Tiles struct {
-	engine              *engine.Engine
-	tileMatrixSetLimits map[string]map[int]TileMatrixSetLimits
+// tileset tiles in a specific projection/TileMatrixSet.
+type tileset struct {
+	// Srs projection of the tiles
+	Srs string
+
+	// ZoomLevelRange available zoom levels
+	ZoomLevelRange config.ZoomLevelRange
+
+	// TileMatrixSet definition of the tiling scheme
+	TileMatrixSet TileMatrixSet
+
+	// TileMatrixSetLimits by zoom level
+	limits map[int]TileMatrixSetLimits
+
+	// VectorLayers layers in the vector tiles, only available when configured to discover these
+	VectorLayers []vectorLayer
+
+	// derived when tiles are derived (reprojected) from tiles in another TileMatrixSet, nil otherwise
+	derived *derivation
+}
+
+// Limits TileMatrixSetLimits ordered by zoom level.
+func (ts tileset) Limits() []TileMatrixSetLimits {
+	result := make([]TileMatrixSetLimits, 0, len(ts.limits))
+	for _, zoomLevel := range util.Keys(ts.limits) {
+		result = append(result, ts.limits[zoomLevel])
+	}
+	slices.SortFunc(result, func(a, b TileMatrixSetLimits) int {
+		za, _ := strconv.Atoi(a.TileMatrix)
+		zb, _ := strconv.Atoi(b.TileMatrix)
+		return za - zb
+	})
+
+	return result
+}
+
+// TileMatrices tile matrices (zoom levels) of the TileMatrixSet offered in this tileset.
+func (ts tileset) TileMatrices() []TileMatrix {
+	result := make([]TileMatrix, 0, len(ts.limits))
+	for _, tm := range ts.TileMatrixSet.TileMatrices {
+		if zoomLevel, _ := strconv.Atoi(tm.ID); zoomLevel >= ts.ZoomLevelRange.Start && zoomLevel <= ts.ZoomLevelRange.End {
+			result = append(result, tm)
+		}
+	}
+
+	return result
+}
+
+type tilesetTemplateData struct {
+	templateData
+
+	// Tileset the tileset to render
+	Tileset tileset
+}
+
+type tileMatrixSetsTemplateData struct {
+	// TileMatrixSets used by this dataset
+	TileMatrixSets []TileMatrixSet
+}
+
+// newTemplateData creates template data for the given tiles. The TileMatrixSetLimits of each tileset
+// are restricted to the union of the given extents, or cover the whole TileMatrixSet when no extents are given.
+func newTemplateData(tilesConfig config.Tiles, baseURL string,
+	tileMatrixSets map[string]TileMatrixSet, extents []*config.Extent) templateData {
+
+	tilesets := make([]tileset, 0, len(tilesConfig.SupportedSrs))
+	for _, supportedSrs := range tilesConfig.SupportedSrs {
+		tms := tileMatrixSets[supportedSrs.GetTileMatrixSetID()]
+		tilesets = append(tilesets, tileset{
+			Srs:            supportedSrs.Srs,
+			ZoomLevelRange: supportedSrs.ZoomLevelRange,
+			TileMatrixSet:  tms,
+			limits:         tms.Limits(supportedSrs.ZoomLevelRange, extentInSrs(extents, supportedSrs.Srs)),
+		})
+	}
+
+	return templateData{tilesConfig, baseURL, tilesets}
+}
+
+// datasetExtents extents of all collections in the dataset, used for top-level (dataset) tiles.
+// Returns nil when the extent of one or more collections is unknown.
+func datasetExtents(cfg *config.Config) []*config.Extent {
+	collections := cfg.AllCollections()
+	extents := make([]*config.Extent, 0, len(collections))
+	for _, coll := range collections {
+		metadata := coll.GetMetadata()
+		if metadata == nil || metadata.Extent == nil {
+			return nil
+		}
+		extents = append(extents, metadata.Extent)
+	}
+
+	return extents
+}
+
+// extentInSrs bbox of the union of the given extents in the given projection, reprojected when needed.
+// Returns nil when there are no extents or when one of the extents can't be reprojected.
+func extentInSrs(extents []*config.Extent, srs string) []float64 {
+	var result []float64
+	for _, extent := range extents {
+		bbox := extentBboxInSrs(extent, srs)
+		if bbox == nil {
+			return nil
+		}
+		if result == nil {
+			result = bbox
+			continue
+		}
+		result = []float64{min(result[0], bbox[0]), min(result[1], bbox[1]), max(result[2], bbox[2]), max(result[3], bbox[3])}
+	}
+
+	return result
+}
+
+func extentBboxInSrs(extent *config.Extent, srs string) []float64 {
+	if extent == nil || len(extent.Bbox) != 4 {
+		return nil
+	}
+	bbox := make([]float64, 0, len(extent.Bbox))
+	for _, value := range extent.Bbox {
+		f, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil {
+			return nil
+		}
+		bbox = append(bbox, f)
+	}
+	extentSrs := extent.Srs
+	if extentSrs == "" {
+		extentSrs = wgs84Srs // extents default to CRS84, which has the same (lon/lat) axis order as the transformer
+	}
+	if extentSrs == srs {
+		return bbox
+	}
+	transform, err := proj.NewTransformer(extentSrs, srs)
+	if err != nil {
+		log.Printf("WARNING: can't derive tileMatrixSetLimits in %s from extent in %s: %v", srs, extentSrs, err)
+		return nil
+	}
+
+	return transformBbox(transform, bbox)
+}
+
+// transformBbox reprojects the given bbox, using points along its edges since
+// the edges of a bbox are usually curved in another projection.
+func transformBbox(transform proj.Transformer, bbox []float64) []float64 {
+	const pointsPerEdge = 10
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	for i := range pointsPerEdge + 1 {
+		fraction := float64(i) / pointsPerEdge
+		x := bbox[0] + fraction*(bbox[2]-bbox[0])
+		y := bbox[1] + fraction*(bbox[3]-bbox[1])
+		for _, point := range [][2]float64{{x, bbox[1]}, {x, bbox[3]}, {bbox[0], y}, {bbox[2], y}} {
+			px, py := transform(point[0], point[1])
+			minX, minY = math.Min(minX, px), math.Min(minY, py)
+			maxX, maxY = math.Max(maxX, px), math.Max(maxY, py)
+		}
+	}
+
+	return []float64{minX, minY, maxX, maxY}
+}
+
+// DataType type of tiles as advertised in tileset metadata: 'vector' or - for raster tiles - 'map'.
+func (d templateData) DataType() string {
+	if d.HasType(config.TilesTypeVector) {
+		return string(config.TilesTypeVector)
+	}
+
+	return "map"
+}
+
+// TileFormats formats in which tiles are offered.
+func (d templateData) TileFormats() []tileFormat {
+	result := make([]tileFormat, 0)
+	for _, format := range getTileFormats(d.Tiles, false) {
+		result = append(result, tileFormats[format])
+	}
+
+	return result
+}
+
+// DefaultTileFormat format used when requesting a tile without explicit format.
+func (d templateData) DefaultTileFormat() string {
+	if formats := getTileFormats(d.Tiles, false); len(formats) > 0 {
+		return formats[0]
+	}
+
+	return engine.FormatMVT
+}
+
+// SupportedStyles gives access to the styles offered through OGC API Styles, which may change at runtime.
+type SupportedStyles interface {
+
+	// HasStyle true when a style with the given ID exists
+	HasStyle(styleID string) bool
 }
 
-type TileMatrixSetLimits struct {
-	MinCol int `yaml:"minCol" json:"minCol"`
-	MaxCol int `yaml:"maxCol" json:"maxCol"`
-	MinRow int `yaml:"minRow" json:"minRow"`
-	MaxRow int `yaml:"maxRow" json:"maxRow"`
+type Tiles struct {
+	engine *engine.Engine
+
+	// styles in which raster tiles are offered, nil when OGC API Styles isn't enabled
+	styles SupportedStyles
+
+	// tilesets by collection ID (empty for dataset tiles) and tileMatrixSet ID
+	tilesets map[string]map[string]tileset
+
+	// tile archives by collection ID (empty for dataset tiles) and tileMatrixSet ID
+	archives map[string]map[string]archive.Archive
+
+	// style used for raster tiles when no style is requested explicitly
+	defaultStyle string
+
+	// optional cache of tiles retrieved from the tile server
+	cache    *cache.Cache
+	inflight singleflight.Group
 }
 
-func NewTiles(e *engine.Engine) *Tiles {
-	tiles := &Tiles{engine: e}
+func NewTiles(e *engine.Engine, styles SupportedStyles) *Tiles {
+	tiles := &Tiles{engine: e, styles: styles, tilesets: make(map[string]map[string]tileset)}
 
-	// TileMatrixSetLimits
-	supportedProjections := e.Config.OgcAPI.Tiles.GetProjections()
-	tiles.tileMatrixSetLimits = readTileMatrixSetLimits(supportedProjections)
+	// TileMatrixSets, both built-in and custom
+	tileMatrixSets, err := LoadTileMatrixSets(e.Config.OgcAPI.Tiles)
+	if err != nil {
+		log.Fatalf("failed to load tileMatrixSets: %v", err)
+	}
+
+	// Tile archives (MBTiles/PMTiles), when tiles aren't served from a tile server
+	tiles.archives = openArchives(e, tileMatrixSets)
 
 	// TileMatrixSets
-	renderTileMatrixTemplates(e)
+	renderTileMatrixTemplates(e, tileMatrixSets)
 	e.Router.Get(tileMatrixSetsPath, tiles.TileMatrixSets())
 	e.Router.Get(tileMatrixSetsPath+"/{tileMatrixSetId}", tiles.TileMatrixSet())
 
+	// Bounds the total time spent on discovering vector layers during startup
+	discoveryCtx, cancelDiscovery := context.WithTimeout(context.Background(), vectorLayersTimeout)
+	defer cancelDiscovery()
+
 	// Top-level tiles (dataset tiles in OGC spec)
 	if e.Config.OgcAPI.Tiles.DatasetTiles != nil {
-		renderTilesTemplates(e, nil, templateData{
-			*e.Config.OgcAPI.Tiles.DatasetTiles,
-			e.Config.BaseURL.String(),
-			util.Cast(config.AllTileProjections),
-		})
+		data := newTemplateData(*e.Config.OgcAPI.Tiles.DatasetTiles, e.Config.BaseURL.String(), tileMatrixSets, datasetExtents(e.Config))
+		tiles.discoverVectorLayers(discoveryCtx, "", *e.Config.OgcAPI.Tiles.DatasetTiles, data.Tilesets)
+		tiles.addTilesets("", data.Tilesets)
+		renderTilesTemplates(e, nil, data, tileMatrixSets)
 		e.Router.Get(tilesPath, tiles.TilesetsList())
 		e.Router.Get(tilesPath+"/{tileMatrixSetId}", tiles.Tileset())
 		e.Router.Head(tilesPath+"/{tileMatrixSetId}/{tileMatrix}/{tileRow}/{tileCol}", tiles.Tile(*e.Config.OgcAPI.Tiles.DatasetTiles))
 		e.Router.Get(tilesPath+"/{tileMatrixSetId}/{tileMatrix}/{tileRow}/{tileCol}", tiles.Tile(*e.Config.OgcAPI.Tiles.DatasetTiles))
+
+		// Styled raster tiles (map tiles in OGC spec)
+		if e.Config.OgcAPI.Styles != nil && e.Config.OgcAPI.Tiles.DatasetTiles.HasType(config.TilesTypeRaster) {
+			tiles.defaultStyle = e.Config.OgcAPI.Styles.Default
+			e.Router.Head(stylesPath+"/{style}"+mapTilesPath+"/{tileMatrixSetId}/{tileMatrix}/{tileRow}/{tileCol}", tiles.StyledTile(*e.Config.OgcAPI.Tiles.DatasetTiles))
+			e.Router.Get(stylesPath+"/{style}"+mapTilesPath+"/{tileMatrixSetId}/{tileMatrix}/{tileRow}/{tileCol}", tiles.StyledTile(*e.Config.OgcAPI.Tiles.DatasetTiles))
+		}
 	}
 
 	// Collection-level tiles (geodata tiles in OGC spec)
 	geoDataTiles := map[string]config.Tiles{}
 	for _, coll := range e.Config.OgcAPI.Tiles.Collections {
-		renderTilesTemplates(e, &coll, templateData{
-			coll.GeoDataTiles,
-			e.Config.BaseURL.String() + g.CollectionsPath + "/" + coll.ID,
-			util.Cast(config.AllTileProjections),
-		})
+		var extents []*config.Extent
+		if coll.Metadata != nil && coll.Metadata.Extent != nil {
+			extents = []*config.Extent{coll.Metadata.Extent}
+		}
+		data := newTemplateData(coll.GeoDataTiles, e.Config.BaseURL.String()+g.CollectionsPath+"/"+coll.ID, tileMatrixSets, extents)
+		tiles.discoverVectorLayers(discoveryCtx, coll.ID, coll.GeoDataTiles, data.Tilesets)
+		tiles.addTilesets(coll.ID, data.Tilesets)
+		renderTilesTemplates(e, &coll, data, tileMatrixSets)
 		geoDataTiles[coll.ID] = coll.GeoDataTiles
 	}
 	if len(geoDataTiles) != 0 {
@@ -116,9 +377,45 @@ func NewTiles(e *engine.Engine) *Tiles {
 		e.Router.Get(g.CollectionsPath+"/{collectionId}"+tilesPath+"/{tileMatrixSetId}/{tileMatrix}/{tileRow}/{tileCol}", tiles.TileForCollection(geoDataTiles))
 	}
 
+	// Tiles derived (reprojected on the fly) from tiles in another TileMatrixSet
+	for collectionID, tilesConfig := range tilesByCollection(e) {
+		if err = tiles.addDerivations(collectionID, tilesConfig); err != nil {
+			log.Fatalf("failed to setup derived tiles: %v", err)
+		}
+	}
+
+	// Readiness checks of the archives and tile servers backing the tiles
+	byCollection := tilesByCollection(e)
+	for _, collectionID := range slices.Sorted(maps.Keys(byCollection)) {
+		tiles.registerReadinessChecks(collectionID, byCollection[collectionID])
+	}
+
+	// Tile cache, optionally seeded in the background
+	if cacheConfig := e.Config.OgcAPI.Tiles.Cache; cacheConfig != nil {
+		if tiles.cache, err = cache.New(*cacheConfig); err != nil {
+			log.Fatalf("failed to create tile cache: %v", err)
+		}
+		if cacheConfig.Seed != nil {
+			ctx, cancel := context.WithCancel(context.Background())
+			e.RegisterShutdownHook(cancel)
+			go func() {
+				if err := tiles.seedCache(ctx, *cacheConfig.Seed); err != nil {
+					log.Println(err)
+				}
+			}()
+		}
+	}
+
 	return tiles
 }
 
+func (t *Tiles) addTilesets(collectionID string, tilesets []tileset) {
+	t.tilesets[collectionID] = make(map[string]tileset)
+	for _, ts := range tilesets {
+		t.tilesets[collectionID][ts.TileMatrixSet.ID] = ts
+	}
+}
+
 func (t *Tiles) TileMatrixSets() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		key := engine.NewTemplateKey(templatesDir+"tileMatrixSets.go."+t.engine.CN.NegotiateFormat(r),
@@ -130,7 +427,8 @@ func (t *Tiles) TileMatrixSets() http.HandlerFunc {
 func (t *Tiles) TileMatrixSet() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		tileMatrixSetID := chi.URLParam(r, "tileMatrixSetId")
-		key := engine.NewTemplateKey(templatesDir+tileMatrixSetsLocalPath+tileMatrixSetID+".go."+t.engine.CN.NegotiateFormat(r),
+		key := engine.NewTemplateKey(templatesDir+"tileMatrixSet.go."+t.engine.CN.NegotiateFormat(r),
+			engine.WithInstanceName(tileMatrixSetID),
 			t.engine.WithNegotiatedLanguage(w, r))
 		t.engine.Serve(w, r, engine.ServeTemplate(key))
 	}
@@ -157,7 +455,8 @@ func (t *Tiles) TilesetsListForCollection() http.HandlerFunc {
 func (t *Tiles) Tileset() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		tileMatrixSetID := chi.URLParam(r, "tileMatrixSetId")
-		key := engine.NewTemplateKey(templatesDir+tilesLocalPath+tileMatrixSetID+".go."+t.engine.CN.NegotiateFormat(r),
+		key := engine.NewTemplateKey(templatesDir+"tileset.go."+t.engine.CN.NegotiateFormat(r),
+			engine.WithInstanceName(tilesetInstanceName("", tileMatrixSetID)),
 			t.engine.WithNegotiatedLanguage(w, r))
 		t.engine.Serve(w, r, engine.ServeTemplate(key))
 	}
@@ -167,134 +466,298 @@ func (t *Tiles) TilesetForCollection() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		collectionID := chi.URLParam(r, "collectionId")
 		tileMatrixSetID := chi.URLParam(r, "tileMatrixSetId")
-		key := engine.NewTemplateKey(templatesDir+tilesLocalPath+tileMatrixSetID+".go."+t.engine.CN.NegotiateFormat(r),
-			engine.WithInstanceName(collectionID),
+		key := engine.NewTemplateKey(templatesDir+"tileset.go."+t.engine.CN.NegotiateFormat(r),
+			engine.WithInstanceName(tilesetInstanceName(collectionID, tileMatrixSetID)),
 			t.engine.WithNegotiatedLanguage(w, r))
 		t.engine.Serve(w, r, engine.ServeTemplate(key))
 	}
 }
 
-// Tile reverse proxy to configured tileserver/object storage. Assumes the backing resource is publicly accessible.
+// Tile reverse proxy to configured tileserver/object storage or read tile from archive.
+// Assumes the backing resource is publicly accessible.
 func (t *Tiles) Tile(tilesConfig config.Tiles) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		tileMatrixSetID := chi.URLParam(r, "tileMatrixSetId")
-		tileMatrix := chi.URLParam(r, "tileMatrix")
-		tileRow := chi.URLParam(r, "tileRow")
-		tileCol, err := getTileColumn(r, t.engine.CN.NegotiateFormat(r))
-		if err != nil {
-			engine.RenderProblemAndLog(engine.ProblemBadRequest, w, err, err.Error())
+		t.serveTile(w, r, tilesConfig, "", t.defaultStyle, false)
+	}
+}
 
-			return
-		}
-		tm, tr, tc, err := parseTileParams(tileMatrix, tileRow, tileCol)
-		if err != nil {
-			engine.RenderProblemAndLog(engine.ProblemBadRequest, w, err, strings.ReplaceAll(err.Error(), "strconv.Atoi: ", ""))
+// TileForCollection reverse proxy to configured tileserver/object storage or read tile from archive
+// for tiles within a given collection.
+// Assumes the backing resource is publicly accessible.
+func (t *Tiles) TileForCollection(tilesConfigByCollection map[string]config.Tiles) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		collectionID := chi.URLParam(r, "collectionId")
+		tilesConfig, ok := tilesConfigByCollection[collectionID]
+		if !ok {
+			err := fmt.Errorf("no tiles available for collection: %s", collectionID)
+			engine.RenderProblemAndLog(engine.ProblemNotFound, w, err, err.Error())
 
 			return
 		}
+		t.serveTile(w, r, tilesConfig, collectionID, t.defaultStyle, false)
+	}
+}
 
-		if _, ok := t.tileMatrixSetLimits[tileMatrixSetID]; !ok {
-			// unknown tileMatrixSet
-			err = fmt.Errorf("unknown tileMatrixSet '%s'", tileMatrixSetID)
-			engine.RenderProblemAndLog(engine.ProblemBadRequest, w, err, err.Error())
+// StyledTile reverse proxy to configured tileserver/object storage for raster tiles
+// rendered in the requested style (map tiles in OGC spec).
+// Assumes the backing resource is publicly accessible.
+func (t *Tiles) StyledTile(tilesConfig config.Tiles) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		style := chi.URLParam(r, "style")
+		if t.styles == nil || !t.styles.HasStyle(style) {
+			err := fmt.Errorf("unknown style '%s'", style)
+			engine.RenderProblemAndLog(engine.ProblemNotFound, w, err, err.Error())
 
 			return
 		}
-		err = checkTileMatrixSetLimits(t.tileMatrixSetLimits, tileMatrixSetID, tm, tr, tc)
-		if err != nil {
-			engine.RenderProblem(engine.ProblemNotFound, w, err.Error())
+		t.serveTile(w, r, tilesConfig, "", style, true)
+	}
+}
 
-			return
-		}
+func (t *Tiles) serveTile(w http.ResponseWriter, r *http.Request, tilesConfig config.Tiles,
+	collectionID string, style string, rasterOnly bool) {
 
-		target, err := createTilesURL(tileMatrixSetID, tileMatrix, tileCol, tileRow, tilesConfig)
-		if err != nil {
-			engine.RenderProblemAndLog(engine.ProblemServerError, w, err)
+	tileMatrixSetID := chi.URLParam(r, "tileMatrixSetId")
+	tileMatrix := chi.URLParam(r, "tileMatrix")
+	tileRow := chi.URLParam(r, "tileRow")
+	tileCol, format, err := getTileColumn(r, t.engine.CN.NegotiateFormat(r), getTileFormats(tilesConfig, rasterOnly))
+	if err != nil {
+		engine.RenderProblemAndLog(engine.ProblemBadRequest, w, err, err.Error())
 
-			return
-		}
-		t.engine.ReverseProxy(w, r, target, true, engine.MediaTypeMVT)
+		return
+	}
+	tm, tr, tc, err := parseTileParams(tileMatrix, tileRow, tileCol)
+	if err != nil {
+		engine.RenderProblemAndLog(engine.ProblemBadRequest, w, err, strings.ReplaceAll(err.Error(), "strconv.Atoi: ", ""))
+
+		return
+	}
+
+	ts, ok := t.tilesets[collectionID][tileMatrixSetID]
+	if !ok {
+		// unknown tileMatrixSet
+		err = fmt.Errorf("unknown tileMatrixSet '%s'", tileMatrixSetID)
+		engine.RenderProblemAndLog(engine.ProblemBadRequest, w, err, err.Error())
+
+		return
+	}
+	err = checkTileMatrixSetLimits(ts.limits, tm, tr, tc)
+	if err != nil {
+		engine.RenderProblem(engine.ProblemNotFound, w, err.Error())
+
+		return
+	}
+
+	if ts.derived != nil {
+		t.serveDerivedTile(w, r, tilesConfig, collectionID, ts, tm, tr, tc, format, style)
+
+		return
+	}
+	// archives only contain vector tiles
+	if tileArchive, ok := t.archives[collectionID][tileMatrixSetID]; ok && format == engine.FormatMVT {
+		serveTileFromArchive(w, r, tileArchive, tm, tr, tc)
+
+		return
 	}
+	target, err := createTilesURL(tileMatrixSetID, tileMatrix, tileCol, tileRow, format, style, tilesConfig)
+	if err != nil {
+		engine.RenderProblemAndLog(engine.ProblemServerError, w, err)
+
+		return
+	}
+	if t.cache != nil {
+		key := tileCacheKey(collectionID, tileMatrixSetID, tm, tr, tc, format, style)
+		t.serveTileFromCache(w, r, key, target, tileFormats[format].MediaType)
+
+		return
+	}
+	t.engine.ReverseProxy(w, r, target, true, tileFormats[format].MediaType)
 }
 
-// TileForCollection reverse proxy to configured tileserver/object storage for tiles within a given collection.
-// Assumes the backing resource is publicly accessible.
-func (t *Tiles) TileForCollection(tilesConfigByCollection map[string]config.Tiles) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		collectionID := chi.URLParam(r, "collectionId")
-		tileMatrixSetID := chi.URLParam(r, "tileMatrixSetId")
-		tileMatrix := chi.URLParam(r, "tileMatrix")
-		tileRow := chi.URLParam(r, "tileRow")
-		tileCol, err := getTileColumn(r, t.engine.CN.NegotiateFormat(r))
-		if err != nil {
-			engine.RenderProblemAndLog(engine.ProblemBadRequest, w, err, err.Error())
+func serveTileFromArchive(w http.ResponseWriter, r *http.Request, tileArchive archive.Archive, tileMatrix, tileRow, tileCol int) {
+	tile, err := tileArchive.Tile(r.Context(), tileMatrix, tileRow, tileCol)
+	if err != nil {
+		engine.RenderProblemAndLog(engine.ProblemServerError, w, err)
 
-			return
-		}
-		tm, tr, tc, err := parseTileParams(tileMatrix, tileRow, tileCol)
-		if err != nil {
-			engine.RenderProblemAndLog(engine.ProblemBadRequest, w, err, strings.ReplaceAll(err.Error(), "strconv.Atoi: ", ""))
+		return
+	}
+	if tile == nil {
+		// OGC spec: If the tile has no content due to lack of data in the area, but is within the data
+		// resource its tile matrix sets and tile matrix sets limits, the HTTP response will use the status
+		// code either 204 (indicating an empty tile with no content) or a 200
+		w.WriteHeader(http.StatusNoContent)
+
+		return
+	}
+	w.Header().Set(engine.HeaderContentType, engine.MediaTypeMVT)
+	if util.IsGzipped(tile) {
+		// vector tiles are usually stored gzipped in archives, pass as-is when client supports it.
+		// Since the response depends on the client, let caches know.
+		w.Header().Add(engine.HeaderVary, engine.HeaderAcceptEncoding)
+		if engine.AcceptsEncoding(r, engine.FormatGzip) {
+			w.Header().Set(engine.HeaderContentEncoding, engine.FormatGzip)
+		} else if tile, err = util.Gunzip(tile); err != nil {
+			engine.RenderProblemAndLog(engine.ProblemServerError, w, err)
 
 			return
 		}
+	}
+	w.Header().Set(engine.HeaderContentLength, strconv.Itoa(len(tile)))
+	engine.SafeWrite(w.Write, tile)
+}
 
-		if _, ok := t.tileMatrixSetLimits[tileMatrixSetID]; !ok {
-			// unknown tileMatrixSet
-			err = fmt.Errorf("unknown tileMatrixSet '%s'", tileMatrixSetID)
-			engine.RenderProblemAndLog(engine.ProblemBadRequest, w, err, err.Error())
+// tilesByCollection tiles config by collection ID (empty for dataset tiles).
+func tilesByCollection(e *engine.Engine) map[string]config.Tiles {
+	result := map[string]config.Tiles{}
+	if e.Config.OgcAPI.Tiles.DatasetTiles != nil {
+		result[""] = *e.Config.OgcAPI.Tiles.DatasetTiles
+	}
+	for _, coll := range e.Config.OgcAPI.Tiles.Collections {
+		result[coll.ID] = coll.GeoDataTiles
+	}
 
-			return
-		}
-		err = checkTileMatrixSetLimits(t.tileMatrixSetLimits, tileMatrixSetID, tm, tr, tc)
-		if err != nil {
-			engine.RenderProblem(engine.ProblemNotFound, w, err.Error())
+	return result
+}
 
-			return
+func openArchives(e *engine.Engine, tileMatrixSets map[string]TileMatrixSet) map[string]map[string]archive.Archive {
+	result := make(map[string]map[string]archive.Archive)
+	for collectionID, tilesConfig := range tilesByCollection(e) {
+		for _, archiveConfig := range tilesConfig.Archives {
+			tileMatrixSetID := tileMatrixSetIDBySrs(tilesConfig, archiveConfig.Srs)
+			tileArchive, err := archive.Open(archiveConfig, tileMatrixSets[tileMatrixSetID].matrixHeights())
+			if err != nil {
+				log.Fatalf("failed to open tile archive: %v", err)
+			}
+			if _, ok := result[collectionID]; !ok {
+				result[collectionID] = make(map[string]archive.Archive)
+			}
+			result[collectionID][tileMatrixSetID] = tileArchive
+			e.RegisterShutdownHook(func() {
+				if err := tileArchive.Close(); err != nil {
+					log.Printf("failed to close tile archive %s: %v", archiveConfig.File, err)
+				}
+			})
 		}
+	}
 
-		tilesConfig, ok := tilesConfigByCollection[collectionID]
-		if !ok {
-			err = fmt.Errorf("no tiles available for collection: %s", collectionID)
-			engine.RenderProblemAndLog(engine.ProblemNotFound, w, err, err.Error())
+	return result
+}
 
-			return
+// registerReadinessChecks registers a readiness check per projection of the given tiles, checking the availability
+// of the archive or tile server. Derived tiles are skipped, since these depend on tiles which are checked already.
+func (t *Tiles) registerReadinessChecks(collectionID string, tilesConfig config.Tiles) {
+	if enabled := tilesConfig.HealthCheck.Enabled; enabled != nil && !*enabled {
+		return
+	}
+	formats := getTileFormats(tilesConfig, false)
+	for _, supportedSrs := range tilesConfig.SupportedSrs {
+		ts, ok := t.tilesets[collectionID][supportedSrs.GetTileMatrixSetID()]
+		if !ok || ts.derived != nil || len(formats) == 0 {
+			continue
+		}
+		limits, ok := ts.limits[ts.ZoomLevelRange.Start]
+		if !ok {
+			continue
 		}
-		target, err := createTilesURL(tileMatrixSetID, tileMatrix, tileCol, tileRow, tilesConfig)
+		name := strings.TrimPrefix(tilesetInstanceName(collectionID, ts.TileMatrixSet.ID), "/")
+		tileMatrix := ts.ZoomLevelRange.Start
+		tileRow := (limits.MinTileRow + limits.MaxTileRow) / 2
+		tileCol := (limits.MinTileCol + limits.MaxTileCol) / 2
+
+		if tileArchive, ok := t.archives[collectionID][ts.TileMatrixSet.ID]; ok {
+			t.engine.RegisterReadinessCheck("tiles", name, func(ctx context.Context) error {
+				_, err := tileArchive.Tile(ctx, tileMatrix, tileRow, tileCol)
+				return err
+			})
+			continue
+		}
+		if !tilesConfig.HasTileServer() {
+			continue
+		}
+		if tilesConfig.HealthCheck.Srs == supportedSrs.Srs && tilesConfig.HealthCheck.TilePath != nil {
+			// specific tile known to exist
+			target, err := url.Parse(tilesConfig.TileServer.String() + *tilesConfig.HealthCheck.TilePath)
+			if err != nil {
+				log.Fatalf("invalid health check tilepath: %v", err)
+			}
+			t.engine.RegisterReadinessCheck("tiles", name, func(ctx context.Context) error {
+				return engine.CheckURLFound(ctx, target)
+			})
+			continue
+		}
+		// tile may be empty, so only check whether the tile server responds
+		target, err := createTilesURL(ts.TileMatrixSet.ID, strconv.Itoa(tileMatrix), strconv.Itoa(tileCol),
+			strconv.Itoa(tileRow), formats[0], t.defaultStyle, tilesConfig)
 		if err != nil {
-			engine.RenderProblemAndLog(engine.ProblemServerError, w, err)
-
-			return
+			log.Fatalf("failed to setup readiness check of tiles: %v", err)
 		}
-		t.engine.ReverseProxy(w, r, target, true, engine.MediaTypeMVT)
+		t.engine.RegisterReadinessCheck("tiles", name, func(ctx context.Context) error {
+			return engine.CheckURL(ctx, target)
+		})
+	}
+}
+
+// getTileFormats formats in which the given tiles are offered, the first one being the default format.
+func getTileFormats(tilesConfig config.Tiles, rasterOnly bool) []string {
+	var result []string
+	if tilesConfig.HasType(config.TilesTypeVector) && !rasterOnly {
+		result = append(result, engine.FormatMVT)
+	}
+	for _, format := range tilesConfig.GetRasterFormats() {
+		result = append(result, string(format))
 	}
+
+	return result
 }
 
-func getTileColumn(r *http.Request, format string) (string, error) {
+func getTileColumn(r *http.Request, format string, supportedFormats []string) (string, string, error) {
 	tileCol := chi.URLParam(r, "tileCol")
 
 	// We support content negotiation using Accept header and ?f= param, but also
-	// using the .pbf extension. This is for backwards compatibility.
-	if !strings.HasSuffix(tileCol, "."+engine.FormatMVTAlternative) {
-		// if no format is specified, default to mvt
-		if f := strings.Replace(format, engine.FormatJSON, engine.FormatMVT, 1); f != engine.FormatMVT && f != engine.FormatMVTAlternative {
-			return "", errors.New("specify tile format. Currently only Mapbox Vector Tiles (?f=mvt) tiles are supported")
+	// using an extension like .png. The .pbf extension is for backwards compatibility.
+	if col, ext, found := strings.Cut(tileCol, "."); found {
+		if extFormat, ok := tileFormatsByExtension[ext]; ok {
+			tileCol = col
+			format = extFormat
 		}
-	} else {
-		tileCol = tileCol[:len(tileCol)-4] // remove .pbf extension
+	}
+	switch format {
+	case engine.FormatJSON:
+		// if no format is specified, use the default format
+		if len(supportedFormats) > 0 {
+			format = supportedFormats[0]
+		}
+	case engine.FormatMVTAlternative:
+		format = engine.FormatMVT
+	}
+	if !slices.Contains(supportedFormats, format) {
+		formats := make([]string, 0, len(supportedFormats))
+		for _, f := range supportedFormats {
+			formats = append(formats, fmt.Sprintf("%s (?f=%s)", tileFormats[f].Name, f))
+		}
+
+		return "", "", fmt.Errorf("specify tile format. Currently only %s tiles are supported", strings.Join(formats, ", "))
 	}
 
-	return tileCol, nil
+	return tileCol, format, nil
 }
 
 func createTilesURL(tileMatrixSetID string, tileMatrix string, tileCol string,
-	tileRow string, tilesCfg config.Tiles) (*url.URL, error) {
+	tileRow string, format string, style string, tilesCfg config.Tiles) (*url.URL, error) {
 
 	tilesTmpl := defaultTilesTmpl
-	if tilesCfg.URITemplateTiles != nil {
-		tilesTmpl = *tilesCfg.URITemplateTiles
+	if format == engine.FormatMVT {
+		if tilesCfg.URITemplateTiles != nil {
+			tilesTmpl = *tilesCfg.URITemplateTiles
+		}
+	} else {
+		tilesTmpl = "{tms}/{z}/{x}/{y}." + format
+		if tmpl, ok := tilesCfg.URITemplateRasterTiles[config.RasterTilesFormat(format)]; ok {
+			tilesTmpl = tmpl
+		}
 	}
 	// OGC spec is (default) z/row/col but tileserver is z/col/row (z/x/y)
-	replacer := strings.NewReplacer("{tms}", tileMatrixSetID, "{z}", tileMatrix, "{x}", tileCol, "{y}", tileRow)
+	replacer := strings.NewReplacer("{tms}", tileMatrixSetID, "{z}", tileMatrix, "{x}", tileCol, "{y}", tileRow, "{style}", style)
 	path, _ := url.JoinPath("/", replacer.Replace(tilesTmpl))
 
 	target, err := url.Parse(tilesCfg.TileServer.String() + path)
@@ -305,29 +768,40 @@ func createTilesURL(tileMatrixSetID string, tileMatrix string, tileCol string,
 	return target, nil
 }
 
-func renderTileMatrixTemplates(e *engine.Engine) {
-	e.RenderTemplates(tileMatrixSetsPath,
+func renderTileMatrixTemplates(e *engine.Engine, tileMatrixSets map[string]TileMatrixSet) {
+	used := make([]TileMatrixSet, 0)
+	for _, projection := range e.Config.OgcAPI.Tiles.GetProjections() {
+		tms := tileMatrixSets[projection.GetTileMatrixSetID()]
+		if !slices.ContainsFunc(used, func(u TileMatrixSet) bool { return u.ID == tms.ID }) {
+			used = append(used, tms)
+		}
+	}
+	slices.SortFunc(used, func(a, b TileMatrixSet) int { return strings.Compare(a.ID, b.ID) })
+
+	e.RenderTemplatesWithParams(tileMatrixSetsPath,
+		tileMatrixSetsTemplateData{used},
 		tileMatrixSetsBreadcrumbs,
 		engine.NewTemplateKey(templatesDir+"tileMatrixSets.go.json"),
 		engine.NewTemplateKey(templatesDir+"tileMatrixSets.go.html"))
 
-	for _, projection := range config.AllTileProjections {
+	for id, tms := range tileMatrixSets {
 		breadcrumbs := tileMatrixSetsBreadcrumbs
 		breadcrumbs = append(breadcrumbs, []engine.Breadcrumb{
 			{
-				Name: projection,
-				Path: tileMatrixSetsLocalPath + projection,
+				Name: id,
+				Path: tileMatrixSetsLocalPath + id,
 			},
 		}...)
 
-		e.RenderTemplates(tileMatrixSetsPath+"/"+projection,
+		e.RenderTemplatesWithParams(tileMatrixSetsPath+"/"+id,
+			tms,
 			breadcrumbs,
-			engine.NewTemplateKey(templatesDir+tileMatrixSetsLocalPath+projection+".go.json"),
-			engine.NewTemplateKey(templatesDir+tileMatrixSetsLocalPath+projection+".go.html"))
+			engine.NewTemplateKey(templatesDir+"tileMatrixSet.go.json", engine.WithInstanceName(id)),
+			engine.NewTemplateKey(templatesDir+"tileMatrixSet.go.html", engine.WithInstanceName(id)))
 	}
 }
 
-func renderTilesTemplates(e *engine.Engine, collection *config.TilesCollection, data templateData) {
+func renderTilesTemplates(e *engine.Engine, collection *config.TilesCollection, data templateData, tileMatrixSets map[string]TileMatrixSet) {
 	var breadcrumbs []engine.Breadcrumb
 	path := tilesPath
 	collectionID := ""
@@ -357,7 +831,8 @@ func renderTilesTemplates(e *engine.Engine, collection *config.TilesCollection,
 		engine.NewTemplateKey(templatesDir+"tiles.go.html", engine.WithInstanceName(collectionID)))
 
 	// Now render metadata about tiles per projection/SRS.
-	for _, projection := range config.AllTileProjections {
+	for _, ts := range slices.Concat(data.Tilesets, builtInTilesetsNotOffered(data.Tiles, tileMatrixSets)) {
+		projection := ts.TileMatrixSet.ID
 		path = tilesPath + "/" + projection
 		projectionBreadcrumbs := breadcrumbs
 
@@ -377,49 +852,57 @@ func renderTilesTemplates(e *engine.Engine, collection *config.TilesCollection,
 				},
 			}...)
 		}
+		tilesetData := tilesetTemplateData{data, ts}
+		instanceName := tilesetInstanceName(collectionID, projection)
 		e.RenderTemplatesWithParams(path,
-			data,
+			tilesetData,
 			projectionBreadcrumbs,
-			engine.NewTemplateKey(templatesDir+tilesLocalPath+projection+".go.json", engine.WithInstanceName(collectionID)),
-			engine.NewTemplateKey(templatesDir+tilesLocalPath+projection+".go.html", engine.WithInstanceName(collectionID)))
+			engine.NewTemplateKey(templatesDir+"tileset.go.json", engine.WithInstanceName(instanceName)),
+			engine.NewTemplateKey(templatesDir+"tileset.go.html", engine.WithInstanceName(instanceName)))
 		e.RenderTemplatesWithParams(path,
-			data,
+			tilesetData,
 			projectionBreadcrumbs,
-			engine.NewTemplateKey(templatesDir+tilesLocalPath+projection+".go.tilejson", engine.WithInstanceName(collectionID)))
+			engine.NewTemplateKey(templatesDir+"tileset.go.tilejson", engine.WithInstanceName(instanceName)))
 	}
 }
 
-func getCollectionTitle(collectionID string, metadata *config.GeoSpatialCollectionMetadata) string {
-	if metadata != nil && metadata.Title != nil {
-		return *metadata.Title
+// builtInTilesetsNotOffered tilesets (without limits) in the built-in TileMatrixSets which aren't offered by the
+// given tiles. Metadata of these tilesets has always been available, tiles however can't be requested.
+func builtInTilesetsNotOffered(tilesConfig config.Tiles, tileMatrixSets map[string]TileMatrixSet) []tileset {
+	var result []tileset
+	for _, srs := range slices.Sorted(maps.Keys(config.AllTileProjections)) {
+		tileMatrixSetID := config.AllTileProjections[srs]
+		if tilesConfig.SupportedSrsByTileMatrixSetID(tileMatrixSetID) != nil {
+			continue
+		}
+		result = append(result, tileset{Srs: srs, TileMatrixSet: tileMatrixSets[tileMatrixSetID]})
 	}
 
-	return collectionID
+	return result
 }
 
-func readTileMatrixSetLimits(supportedProjections []config.SupportedSrs) map[string]map[int]TileMatrixSetLimits {
-	tileMatrixSetLimits := make(map[string]map[int]TileMatrixSetLimits)
-	for _, supportedSrs := range supportedProjections {
-		tileMatrixSetID := config.AllTileProjections[supportedSrs.Srs]
-		yamlFile, err := os.ReadFile(tmsLimitsDir + tileMatrixSetID + ".yaml")
-		if err != nil {
-			log.Fatalf("unable to read file %s", tileMatrixSetID+".yaml")
-		}
-		tmsLimits := make(map[int]TileMatrixSetLimits)
-		err = yaml.Unmarshal(yamlFile, &tmsLimits)
-		if err != nil {
-			log.Fatalf("cannot unmarshal yaml: %v", err)
-		}
-		// keep only the zoomlevels supported
-		for tm := range tmsLimits {
-			if tm < supportedSrs.ZoomLevelRange.Start || tm > supportedSrs.ZoomLevelRange.End {
-				delete(tmsLimits, tm)
-			}
+// tilesetInstanceName unique name of a tileset, used to render and lookup tileset templates.
+func tilesetInstanceName(collectionID string, tileMatrixSetID string) string {
+	return collectionID + "/" + tileMatrixSetID
+}
+
+// tileMatrixSetIDBySrs ID of the TileMatrixSet used for the given projection.
+func tileMatrixSetIDBySrs(tilesConfig config.Tiles, srs string) string {
+	for _, supportedSrs := range tilesConfig.SupportedSrs {
+		if supportedSrs.Srs == srs {
+			return supportedSrs.GetTileMatrixSetID()
 		}
-		tileMatrixSetLimits[tileMatrixSetID] = tmsLimits
 	}
 
-	return tileMatrixSetLimits
+	return config.AllTileProjections[srs]
+}
+
+func getCollectionTitle(collectionID string, metadata *config.GeoSpatialCollectionMetadata) string {
+	if metadata != nil && metadata.Title != nil {
+		return *metadata.Title
+	}
+
+	return collectionID
 }
 
 func parseTileParams(tileMatrix, tileRow, tileCol string) (int, int, int, error) {
@@ -430,13 +913,11 @@ func parseTileParams(tileMatrix, tileRow, tileCol string) (int, int, int, error)
 	return tm, tr, tc, errors.Join(tmErr, trErr, tcErr)
 }
 
-func checkTileMatrixSetLimits(tileMatrixSetLimits map[string]map[int]TileMatrixSetLimits,
-	tileMatrixSetID string, tileMatrix, tileRow, tileCol int) error {
-
-	if limits, ok := tileMatrixSetLimits[tileMatrixSetID][tileMatrix]; !ok {
+func checkTileMatrixSetLimits(tileMatrixSetLimits map[int]TileMatrixSetLimits, tileMatrix, tileRow, tileCol int) error {
+	if limits, ok := tileMatrixSetLimits[tileMatrix]; !ok {
 		// tileMatrix out of supported range
 		return fmt.Errorf("tileMatrix %d is out of range", tileMatrix)
-	} else if tileRow < limits.MinRow || tileRow > limits.MaxRow || tileCol < limits.MinCol || tileCol > limits.MaxCol {
+	} else if tileRow < limits.MinTileRow || tileRow > limits.MaxTileRow || tileCol < limits.MinTileCol || tileCol > limits.MaxTileCol {
 		// tileRow and/or tileCol out of supported range
 		return fmt.Errorf("tileRow/tileCol %d/%d is out of range", tileRow, tileCol)
 	}
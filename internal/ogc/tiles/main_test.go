@@ -1,7 +1,10 @@
 package tiles
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
+	"encoding/json"
 	"log"
 	"net"
 	"net/http"
@@ -9,7 +12,10 @@ import (
 	"net/url"
 	"os"
 	"path"
+	"path/filepath"
 	"runtime"
+	"slices"
+	"strings"
 	"testing"
 
 	"github.com/PDOK/gokoala/config"
@@ -20,6 +26,7 @@ import (
 	"golang.org/x/text/language"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/jmoiron/sqlx"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -119,7 +126,7 @@ func TestNewTiles(t *testing.T) {
 	}
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			tiles := NewTiles(test.args.e)
+			tiles := NewTiles(test.args.e, nil)
 			assert.NotEmpty(t, tiles.engine.Templates.RenderedTemplates)
 		})
 	}
@@ -320,7 +327,7 @@ func TestTiles_Tile(t *testing.T) {
 
 			newEngine, err := engine.NewEngine(tt.fields.configFile, "internal/engine/testdata/test_theme.yaml", "", false, true)
 			require.NoError(t, err)
-			tiles := NewTiles(newEngine)
+			tiles := NewTiles(newEngine, nil)
 			handler := tiles.Tile(*newEngine.Config.OgcAPI.Tiles.DatasetTiles)
 			handler.ServeHTTP(rr, req)
 
@@ -330,6 +337,244 @@ func TestTiles_Tile(t *testing.T) {
 	}
 }
 
+func TestTiles_TileFromArchive(t *testing.T) {
+	// given MBTiles archive with a single (gzipped) tile
+	var tile bytes.Buffer
+	gz := gzip.NewWriter(&tile)
+	_, err := gz.Write([]byte("fake-mvt"))
+	require.NoError(t, err)
+	require.NoError(t, gz.Close())
+
+	archiveFile := filepath.Join(t.TempDir(), "tiles.mbtiles")
+	db, err := sqlx.Open("sqlite3", archiveFile)
+	require.NoError(t, err)
+	db.MustExec(`create table tiles (zoom_level integer, tile_column integer, tile_row integer, tile_data blob)`)
+	db.MustExec(`create table metadata (name text, value text)`)
+	db.MustExec(`insert into tiles values (1, 0, 1, ?)`, tile.Bytes()) // row 1 in TMS is row 0 in OGC
+	require.NoError(t, db.Close())
+
+	tests := []struct {
+		name                string
+		tileMatrix          string
+		tileRow             string
+		tileCol             string
+		acceptEncoding      string
+		wantStatusCode      int
+		wantBody            []byte
+		wantContentEncoding string
+		wantVary            string
+	}{
+		{
+			name:           "tile from archive",
+			tileMatrix:     "1",
+			tileRow:        "0",
+			tileCol:        "0.pbf",
+			wantStatusCode: http.StatusOK,
+			wantBody:       []byte("fake-mvt"),
+			wantVary:       "Accept-Encoding",
+		},
+		{
+			name:                "gzipped tile from archive",
+			tileMatrix:          "1",
+			tileRow:             "0",
+			tileCol:             "0.pbf",
+			acceptEncoding:      "gzip, deflate",
+			wantStatusCode:      http.StatusOK,
+			wantBody:            tile.Bytes(),
+			wantContentEncoding: "gzip",
+			wantVary:            "Accept-Encoding",
+		},
+		{
+			name:           "tile from archive, client refuses gzip",
+			tileMatrix:     "1",
+			tileRow:        "0",
+			tileCol:        "0.pbf",
+			acceptEncoding: "gzip;q=0, deflate",
+			wantStatusCode: http.StatusOK,
+			wantBody:       []byte("fake-mvt"),
+			wantVary:       "Accept-Encoding",
+		},
+		{
+			name:           "empty tile",
+			tileMatrix:     "1",
+			tileRow:        "1",
+			tileCol:        "0.pbf",
+			wantStatusCode: http.StatusNoContent,
+			wantBody:       []byte{},
+		},
+		{
+			name:           "out of range",
+			tileMatrix:     "1",
+			tileRow:        "5",
+			tileCol:        "5.pbf",
+			wantStatusCode: http.StatusNotFound,
+			wantBody:       []byte("tileRow/tileCol 5/5 is out of range"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := createTileRequest("http://localhost:8080/tiles/:tileMatrixSetId/:tileMatrix/:tileRow/:tileCol",
+				"NetherlandsRDNewQuad", tt.tileMatrix, tt.tileRow, tt.tileCol)
+			require.NoError(t, err)
+			if tt.acceptEncoding != "" {
+				req.Header.Set("Accept-Encoding", tt.acceptEncoding)
+			}
+			rr := httptest.NewRecorder()
+
+			newEngine, err := engine.NewEngine("internal/ogc/tiles/testdata/config_tiles_toplevel.yaml", "internal/engine/testdata/test_theme.yaml", "", false, true)
+			require.NoError(t, err)
+			newEngine.Config.OgcAPI.Tiles.DatasetTiles.Archives = []config.TilesArchive{{Srs: "EPSG:28992", File: archiveFile}}
+			tiles := NewTiles(newEngine, nil)
+			handler := tiles.Tile(*newEngine.Config.OgcAPI.Tiles.DatasetTiles)
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.wantStatusCode, rr.Code)
+			assert.Contains(t, rr.Body.String(), string(tt.wantBody))
+			assert.Equal(t, tt.wantContentEncoding, rr.Header().Get("Content-Encoding"))
+			assert.Equal(t, tt.wantVary, rr.Header().Get("Vary"))
+			if tt.wantStatusCode == http.StatusOK {
+				assert.Equal(t, engine.MediaTypeMVT, rr.Header().Get("Content-Type"))
+			}
+		})
+	}
+}
+
+func TestTiles_RasterTile(t *testing.T) {
+	tests := []struct {
+		name            string
+		url             string
+		tileCol         string
+		wantBody        string
+		wantContentType string
+		wantStatusCode  int
+	}{
+		{
+			name:            "vector tile is default",
+			url:             "http://localhost:8080/tiles/:tileMatrixSetId/:tileMatrix/:tileRow/:tileCol",
+			tileCol:         "15",
+			wantBody:        "/NetherlandsRDNewQuad/5/15/10.pbf",
+			wantContentType: engine.MediaTypeMVT,
+			wantStatusCode:  http.StatusOK,
+		},
+		{
+			name:            "png tile using ?f=png",
+			url:             "http://localhost:8080/tiles/:tileMatrixSetId/:tileMatrix/:tileRow/:tileCol?f=png",
+			tileCol:         "15",
+			wantBody:        "/NetherlandsRDNewQuad/5/15/10.png",
+			wantContentType: engine.MediaTypePNG,
+			wantStatusCode:  http.StatusOK,
+		},
+		{
+			name:            "png tile using extension",
+			url:             "http://localhost:8080/tiles/:tileMatrixSetId/:tileMatrix/:tileRow/:tileCol",
+			tileCol:         "15.png",
+			wantBody:        "/NetherlandsRDNewQuad/5/15/10.png",
+			wantContentType: engine.MediaTypePNG,
+			wantStatusCode:  http.StatusOK,
+		},
+		{
+			name:            "webp tile using uri template with default style",
+			url:             "http://localhost:8080/tiles/:tileMatrixSetId/:tileMatrix/:tileRow/:tileCol?f=webp",
+			tileCol:         "15",
+			wantBody:        "/some-default/NetherlandsRDNewQuad/5/15/10.webp",
+			wantContentType: engine.MediaTypeWebP,
+			wantStatusCode:  http.StatusOK,
+		},
+		{
+			name:           "unsupported raster format",
+			url:            "http://localhost:8080/tiles/:tileMatrixSetId/:tileMatrix/:tileRow/:tileCol",
+			tileCol:        "15.jpg",
+			wantBody:       "Currently only Mapbox Vector Tiles (?f=mvt), PNG (?f=png), WebP (?f=webp) tiles are supported",
+			wantStatusCode: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := createTileRequest(tt.url, "NetherlandsRDNewQuad", "5", "10", tt.tileCol)
+			require.NoError(t, err)
+			rr, ts := createMockServer()
+			defer ts.Close()
+
+			newEngine, err := engine.NewEngine("internal/ogc/tiles/testdata/config_tiles_raster.yaml", "internal/engine/testdata/test_theme.yaml", "", false, true)
+			require.NoError(t, err)
+			tiles := NewTiles(newEngine, nil)
+			handler := tiles.Tile(*newEngine.Config.OgcAPI.Tiles.DatasetTiles)
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.wantStatusCode, rr.Code)
+			assert.Contains(t, rr.Body.String(), tt.wantBody)
+			if tt.wantContentType != "" {
+				assert.Equal(t, tt.wantContentType, rr.Header().Get("Content-Type"))
+			}
+		})
+	}
+}
+
+func TestTiles_StyledTile(t *testing.T) {
+	tests := []struct {
+		name           string
+		style          string
+		tileCol        string
+		wantBody       string
+		wantStatusCode int
+	}{
+		{
+			name:           "webp tile in style",
+			style:          "dark",
+			tileCol:        "15.webp",
+			wantBody:       "/dark/NetherlandsRDNewQuad/5/15/10.webp",
+			wantStatusCode: http.StatusOK,
+		},
+		{
+			name:           "default to first raster format",
+			style:          "dark",
+			tileCol:        "15",
+			wantBody:       "/NetherlandsRDNewQuad/5/15/10.png",
+			wantStatusCode: http.StatusOK,
+		},
+		{
+			name:           "vector tiles aren't styled",
+			style:          "dark",
+			tileCol:        "15.pbf",
+			wantBody:       "Currently only PNG (?f=png), WebP (?f=webp) tiles are supported",
+			wantStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:           "unknown style",
+			style:          "foo",
+			tileCol:        "15.png",
+			wantBody:       "unknown style 'foo'",
+			wantStatusCode: http.StatusNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := createTileRequest("http://localhost:8080/styles/:style/map/tiles/:tileMatrixSetId/:tileMatrix/:tileRow/:tileCol",
+				"NetherlandsRDNewQuad", "5", "10", tt.tileCol)
+			require.NoError(t, err)
+			chi.RouteContext(req.Context()).URLParams.Add("style", tt.style)
+			rr, ts := createMockServer()
+			defer ts.Close()
+
+			newEngine, err := engine.NewEngine("internal/ogc/tiles/testdata/config_tiles_raster.yaml", "internal/engine/testdata/test_theme.yaml", "", false, true)
+			require.NoError(t, err)
+			tiles := NewTiles(newEngine, fakeStyles{"dark"})
+			handler := tiles.StyledTile(*newEngine.Config.OgcAPI.Tiles.DatasetTiles)
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.wantStatusCode, rr.Code)
+			assert.Contains(t, rr.Body.String(), tt.wantBody)
+		})
+	}
+}
+
+// fakeStyles styles offered through OGC API Styles, by ID.
+type fakeStyles []string
+
+func (f fakeStyles) HasStyle(styleID string) bool {
+	return slices.Contains(f, styleID)
+}
+
 func TestTiles_TileForCollection(t *testing.T) {
 	type fields struct {
 		configFile      string
@@ -473,7 +718,7 @@ func TestTiles_TileForCollection(t *testing.T) {
 
 			newEngine, err := engine.NewEngine(tt.fields.configFile, "internal/engine/testdata/test_theme.yaml", "", false, true)
 			require.NoError(t, err)
-			tiles := NewTiles(newEngine)
+			tiles := NewTiles(newEngine, nil)
 			geoDataTiles := map[string]config.Tiles{newEngine.Config.OgcAPI.Tiles.Collections[0].ID: newEngine.Config.OgcAPI.Tiles.Collections[0].GeoDataTiles}
 			handler := tiles.TileForCollection(geoDataTiles)
 			handler.ServeHTTP(rr, req)
@@ -532,7 +777,7 @@ func TestTile_TilesetsList(t *testing.T) {
 
 			newEngine, err := engine.NewEngine(tt.fields.configFile, "internal/engine/testdata/test_theme.yaml", "", false, true)
 			require.NoError(t, err)
-			tiles := NewTiles(newEngine)
+			tiles := NewTiles(newEngine, nil)
 			handler := tiles.TilesetsList()
 			handler.ServeHTTP(rr, req)
 
@@ -581,7 +826,7 @@ func TestTile_TilesetsListForCollection(t *testing.T) {
 
 			newEngine, err := engine.NewEngine(tt.fields.configFile, "internal/engine/testdata/test_theme.yaml", "", false, true)
 			require.NoError(t, err)
-			tiles := NewTiles(newEngine)
+			tiles := NewTiles(newEngine, nil)
 			handler := tiles.TilesetsListForCollection()
 			handler.ServeHTTP(rr, req)
 
@@ -591,6 +836,47 @@ func TestTile_TilesetsListForCollection(t *testing.T) {
 	}
 }
 
+// Collection-level tilesets should link to the tiles of the collection, not to the dataset-level tiles
+func TestTile_TilesetsListForCollectionLinks(t *testing.T) {
+	req, err := createTilesetsListRequest("http://localhost:8080/collections/example/tiles?f=json", "example")
+	require.NoError(t, err)
+	rr, ts := createMockServer()
+	defer ts.Close()
+
+	newEngine, err := engine.NewEngine("internal/ogc/tiles/testdata/config_tiles_collectionlevel.yaml",
+		"internal/engine/testdata/test_theme.yaml", "", false, true)
+	require.NoError(t, err)
+	tiles := NewTiles(newEngine, nil)
+	tiles.TilesetsListForCollection().ServeHTTP(rr, req)
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	var list struct {
+		Tilesets []struct {
+			TileMatrixSetID string `json:"tileMatrixSetId"`
+			Links           []struct {
+				Rel  string `json:"rel"`
+				Href string `json:"href"`
+			} `json:"links"`
+		} `json:"tilesets"`
+	}
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &list))
+	require.NotEmpty(t, list.Tilesets)
+	for _, tileset := range list.Tilesets {
+		var items int
+		for _, link := range tileset.Links {
+			switch link.Rel {
+			case "self":
+				assert.Equal(t, "http://localhost:8080/collections/example/tiles/"+tileset.TileMatrixSetID, link.Href)
+			case "item":
+				items++
+				assert.True(t, strings.HasPrefix(link.Href, "http://localhost:8080/collections/example/tiles/"+
+					tileset.TileMatrixSetID+"/{tileMatrix}/{tileRow}/{tileCol}?f="), link.Href)
+			}
+		}
+		assert.Positive(t, items, "tileset %s has no tile links", tileset.TileMatrixSetID)
+	}
+}
+
 func TestTile_Tileset(t *testing.T) {
 	type fields struct {
 		configFile      string
@@ -666,7 +952,7 @@ func TestTile_Tileset(t *testing.T) {
 
 			newEngine, err := engine.NewEngine(tt.fields.configFile, "internal/engine/testdata/test_theme.yaml", "", false, true)
 			require.NoError(t, err)
-			tiles := NewTiles(newEngine)
+			tiles := NewTiles(newEngine, nil)
 			handler := tiles.Tileset()
 			handler.ServeHTTP(rr, req)
 
@@ -756,7 +1042,7 @@ func TestTile_TilesetForCollection(t *testing.T) {
 
 			newEngine, err := engine.NewEngine(tt.fields.configFile, "internal/engine/testdata/test_theme.yaml", "", false, true)
 			require.NoError(t, err)
-			tiles := NewTiles(newEngine)
+			tiles := NewTiles(newEngine, nil)
 			handler := tiles.TilesetForCollection()
 			handler.ServeHTTP(rr, req)
 
@@ -841,7 +1127,7 @@ func TestTile_TilematrixSet(t *testing.T) {
 
 			newEngine, err := engine.NewEngine(tt.fields.configFile, "internal/engine/testdata/test_theme.yaml", "", false, true)
 			require.NoError(t, err)
-			tiles := NewTiles(newEngine)
+			tiles := NewTiles(newEngine, nil)
 			handler := tiles.TileMatrixSet()
 			handler.ServeHTTP(rr, req)
 
@@ -910,7 +1196,7 @@ func TestTile_TilematrixSets(t *testing.T) {
 
 			newEngine, err := engine.NewEngine(tt.fields.configFile, "internal/engine/testdata/test_theme.yaml", "", false, true)
 			require.NoError(t, err)
-			tiles := NewTiles(newEngine)
+			tiles := NewTiles(newEngine, nil)
 			handler := tiles.TileMatrixSets()
 			handler.ServeHTTP(rr, req)
 
@@ -996,3 +1282,91 @@ func createTilematrixSetsRequest(url string) (*http.Request, error) {
 
 	return req, err
 }
+
+func TestTiles_Readiness(t *testing.T) {
+	// tile server without any tiles
+	tileServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer tileServer.Close()
+	tileServerURL, err := url.Parse(tileServer.URL)
+	require.NoError(t, err)
+
+	newEngine, err := engine.NewEngine("internal/ogc/tiles/testdata/config_tiles_toplevel.yaml", "internal/engine/testdata/test_theme.yaml", "", false, true)
+	require.NoError(t, err)
+	newEngine.Config.OgcAPI.Tiles.DatasetTiles.TileServer = config.URL{URL: tileServerURL}
+	NewTiles(newEngine, nil)
+
+	rr := httptest.NewRecorder()
+	newEngine.Router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "http://localhost:8080/health/ready", nil))
+
+	// health check tile (in RD) should exist, for other projections it suffices that the tile server responds
+	assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+	assert.Contains(t, rr.Body.String(), `"name": "NetherlandsRDNewQuad",
+      "status": "down"`)
+	assert.Contains(t, rr.Body.String(), `"name": "WebMercatorQuad",
+      "status": "up"`)
+}
+
+func TestExtentInSrs(t *testing.T) {
+	netherlands := &config.Extent{Srs: "EPSG:4326", Bbox: []string{"3.2", "50.75", "7.22", "53.7"}}
+	amsterdam := &config.Extent{Srs: "EPSG:28992", Bbox: []string{"110000", "470000", "135000", "500000"}}
+	tests := []struct {
+		name    string
+		extents []*config.Extent
+		srs     string
+		want    []float64
+	}{
+		{
+			name: "no extents",
+			srs:  "EPSG:28992",
+		},
+		{
+			name:    "extent in same srs",
+			extents: []*config.Extent{amsterdam},
+			srs:     "EPSG:28992",
+			want:    []float64{110000, 470000, 135000, 500000},
+		},
+		{
+			name:    "extent defaults to wgs84 and is reprojected",
+			extents: []*config.Extent{{Bbox: netherlands.Bbox}},
+			srs:     "EPSG:3857",
+			want:    []float64{356222, 6577190, 803727, 7113543},
+		},
+		{
+			name:    "union of extents in different srs",
+			extents: []*config.Extent{amsterdam, {Srs: "EPSG:28992", Bbox: []string{"150000", "400000", "160000", "410000"}}},
+			srs:     "EPSG:28992",
+			want:    []float64{110000, 400000, 160000, 500000},
+		},
+		{
+			name:    "extent in unsupported srs",
+			extents: []*config.Extent{{Srs: "EPSG:25831", Bbox: []string{"600000", "5800000", "700000", "5900000"}}},
+			srs:     "EPSG:28992",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := extentInSrs(tt.extents, tt.srs)
+			if tt.want == nil {
+				assert.Nil(t, got)
+				return
+			}
+			require.Len(t, got, 4)
+			for i := range tt.want {
+				assert.InDelta(t, tt.want[i], got[i], 1)
+			}
+		})
+	}
+}
+
+func TestDatasetExtents(t *testing.T) {
+	cfg := &config.Config{OgcAPI: config.OgcAPI{Tiles: &config.OgcAPITiles{Collections: config.TilesCollections{
+		{ID: "a", Metadata: &config.GeoSpatialCollectionMetadata{Extent: &config.Extent{Bbox: []string{"1", "2", "3", "4"}}}},
+		{ID: "b", Metadata: &config.GeoSpatialCollectionMetadata{Extent: &config.Extent{Bbox: []string{"5", "6", "7", "8"}}}},
+	}}}}
+	assert.Len(t, datasetExtents(cfg), 2)
+
+	cfg.OgcAPI.Tiles.Collections = append(cfg.OgcAPI.Tiles.Collections, config.TilesCollection{ID: "c"})
+	assert.Nil(t, datasetExtents(cfg))
+}
@@ -0,0 +1,183 @@
+package mvt
+
+// Transform applies the given function to all coordinates of the feature geometry.
+func (f *Feature) Transform(fn func(x, y float64) (float64, float64)) {
+	for _, part := range f.Geometry {
+		for i, p := range part {
+			part[i][0], part[i][1] = fn(p[0], p[1])
+		}
+	}
+}
+
+// Clip clips the feature geometry to the given bounds (in tile coordinates).
+// Returns false when nothing of the geometry remains.
+func (f *Feature) Clip(minX, minY, maxX, maxY float64) bool {
+	b := bounds{minX, minY, maxX, maxY}
+	var result [][][2]float64
+	switch f.Type {
+	case Point:
+		for _, part := range f.Geometry {
+			var points [][2]float64
+			for _, p := range part {
+				if b.contains(p) {
+					points = append(points, p)
+				}
+			}
+			if len(points) > 0 {
+				result = append(result, points)
+			}
+		}
+	case LineString:
+		for _, part := range f.Geometry {
+			result = append(result, b.clipLine(part)...)
+		}
+	case Polygon:
+		// interior rings are within their exterior ring, so when an exterior
+		// ring is clipped away entirely its interior rings are as well
+		for _, part := range f.Geometry {
+			if ring := b.clipRing(part); len(ring) > 0 {
+				result = append(result, ring)
+			}
+		}
+	}
+	f.Geometry = result
+
+	return len(result) > 0
+}
+
+// Merge adds the geometry of the given feature (of the same type) to this feature. Used
+// to combine parts of a feature which are spread over multiple tiles. Points already
+// present (at the resolution of the tile) are skipped.
+func (f *Feature) Merge(other Feature) {
+	if f.Type != Point || len(f.Geometry) == 0 {
+		f.Geometry = append(f.Geometry, other.Geometry...)
+
+		return
+	}
+	seen := make(map[[2]int32]bool, len(f.Geometry[0]))
+	for _, p := range f.Geometry[0] {
+		seen[[2]int32{roundInt32(p[0]), roundInt32(p[1])}] = true
+	}
+	for _, part := range other.Geometry {
+		for _, p := range part {
+			key := [2]int32{roundInt32(p[0]), roundInt32(p[1])}
+			if !seen[key] {
+				seen[key] = true
+				f.Geometry[0] = append(f.Geometry[0], p)
+			}
+		}
+	}
+}
+
+type bounds struct {
+	minX, minY, maxX, maxY float64
+}
+
+func (b bounds) contains(p [2]float64) bool {
+	return p[0] >= b.minX && p[0] <= b.maxX && p[1] >= b.minY && p[1] <= b.maxY
+}
+
+// clipLine clips the given line using the Liang-Barsky algorithm for each segment,
+// the result may consist of multiple lines.
+func (b bounds) clipLine(line [][2]float64) [][][2]float64 {
+	var result [][][2]float64
+	var current [][2]float64
+	for i := 0; i+1 < len(line); i++ {
+		p0, p1, ok := b.clipSegment(line[i], line[i+1])
+		if !ok {
+			continue
+		}
+		if len(current) == 0 || current[len(current)-1] != p0 {
+			if len(current) > 1 {
+				result = append(result, current)
+			}
+			current = [][2]float64{p0}
+		}
+		current = append(current, p1)
+	}
+	if len(current) > 1 {
+		result = append(result, current)
+	}
+
+	return result
+}
+
+func (b bounds) clipSegment(p0, p1 [2]float64) ([2]float64, [2]float64, bool) {
+	dx, dy := p1[0]-p0[0], p1[1]-p0[1]
+	t0, t1 := 0.0, 1.0
+	for _, edge := range [4][2]float64{
+		{-dx, p0[0] - b.minX},
+		{dx, b.maxX - p0[0]},
+		{-dy, p0[1] - b.minY},
+		{dy, b.maxY - p0[1]},
+	} {
+		p, q := edge[0], edge[1]
+		if p == 0 {
+			if q < 0 {
+				return p0, p1, false // parallel and outside
+			}
+			continue
+		}
+		t := q / p
+		if p < 0 {
+			if t > t1 {
+				return p0, p1, false
+			}
+			t0 = max(t0, t)
+		} else {
+			if t < t0 {
+				return p0, p1, false
+			}
+			t1 = min(t1, t)
+		}
+	}
+
+	return [2]float64{p0[0] + t0*dx, p0[1] + t0*dy}, [2]float64{p0[0] + t1*dx, p0[1] + t1*dy}, true
+}
+
+// clipRing clips the given polygon ring using the Sutherland-Hodgman algorithm.
+// The orientation of the ring is preserved.
+func (b bounds) clipRing(ring [][2]float64) [][2]float64 {
+	edges := []struct {
+		inside    func(p [2]float64) bool
+		intersect func(a, c [2]float64) [2]float64
+	}{
+		{func(p [2]float64) bool { return p[0] >= b.minX }, func(a, c [2]float64) [2]float64 { return intersectX(a, c, b.minX) }},
+		{func(p [2]float64) bool { return p[0] <= b.maxX }, func(a, c [2]float64) [2]float64 { return intersectX(a, c, b.maxX) }},
+		{func(p [2]float64) bool { return p[1] >= b.minY }, func(a, c [2]float64) [2]float64 { return intersectY(a, c, b.minY) }},
+		{func(p [2]float64) bool { return p[1] <= b.maxY }, func(a, c [2]float64) [2]float64 { return intersectY(a, c, b.maxY) }},
+	}
+	result := ring
+	for _, edge := range edges {
+		if len(result) == 0 {
+			break
+		}
+		input := result
+		result = make([][2]float64, 0, len(input)+4)
+		prev := input[len(input)-1]
+		for _, p := range input {
+			switch {
+			case edge.inside(p) && !edge.inside(prev):
+				result = append(result, edge.intersect(prev, p), p)
+			case edge.inside(p):
+				result = append(result, p)
+			case edge.inside(prev):
+				result = append(result, edge.intersect(prev, p))
+			}
+			prev = p
+		}
+	}
+	if len(result) < 3 {
+		return nil
+	}
+
+	return result
+}
+
+func intersectX(a, c [2]float64, x float64) [2]float64 {
+	return [2]float64{x, a[1] + (c[1]-a[1])*(x-a[0])/(c[0]-a[0])}
+}
+
+func intersectY(a, c [2]float64, y float64) [2]float64 {
+	return [2]float64{a[0] + (c[0]-a[0])*(y-a[1])/(c[1]-a[1]), y}
+}
@@ -0,0 +1,483 @@
+// Package mvt decodes and encodes Mapbox Vector Tiles (MVT), see https://github.com/mapbox/vector-tile-spec.
+// Geometries are decoded to (float) tile coordinates, so they can be transformed and clipped before being
+// encoded again. Attribute values are kept in their encoded form, since they're never modified.
+package mvt
+
+import (
+	"errors"
+	"fmt"
+)
+
+// GeometryType type of geometry of a feature.
+type GeometryType uint32
+
+const (
+	Unknown GeometryType = iota
+	Point
+	LineString
+	Polygon
+)
+
+// ValueType type of attribute value.
+type ValueType uint32
+
+const (
+	UnknownValue ValueType = iota
+	StringValue
+	FloatValue
+	IntValue
+	BoolValue
+)
+
+// DefaultExtent default width/height of a tile in tile coordinates.
+const DefaultExtent = 4096
+
+// field numbers as defined in vector_tile.proto
+const (
+	tileLayers = 3
+
+	layerName     = 1
+	layerFeatures = 2
+	layerKeys     = 3
+	layerValues   = 4
+	layerExtent   = 5
+	layerVersion  = 15
+
+	featureID       = 1
+	featureTags     = 2
+	featureType     = 3
+	featureGeometry = 4
+
+	valueString = 1
+	valueFloat  = 2
+	valueDouble = 3
+	valueInt    = 4
+	valueUint   = 5
+	valueSint   = 6
+	valueBool   = 7
+)
+
+// geometry commands
+const (
+	cmdMoveTo    = 1
+	cmdLineTo    = 2
+	cmdClosePath = 7
+)
+
+// Tile vector tile, consisting of layers.
+type Tile struct {
+	Layers []*Layer
+}
+
+// Layer named collection of features.
+type Layer struct {
+	Name     string
+	Version  uint32
+	Extent   uint32
+	Features []Feature
+}
+
+// Feature single feature in a layer.
+type Feature struct {
+	ID   *uint64
+	Type GeometryType
+	Tags []Tag
+
+	// Geometry in tile coordinates (origin top-left). For points a single part with all
+	// points, for linestrings a part per line and for polygons a part per ring (without repeating
+	// the first point). Exterior rings are clockwise, interior rings counterclockwise.
+	Geometry [][][2]float64
+}
+
+// Tag attribute of a feature. The value is kept protobuf encoded (as Value message of vector_tile.proto).
+type Tag struct {
+	Key   string
+	Value []byte
+}
+
+// Type type of the (encoded) attribute value.
+func (t Tag) Type() ValueType {
+	r := reader{buf: t.Value}
+	field, _, err := r.field()
+	if err != nil {
+		return UnknownValue
+	}
+	switch field {
+	case valueString:
+		return StringValue
+	case valueFloat, valueDouble:
+		return FloatValue
+	case valueInt, valueUint, valueSint:
+		return IntValue
+	case valueBool:
+		return BoolValue
+	default:
+		return UnknownValue
+	}
+}
+
+// Layer returns the layer with the given name, creating it when it doesn't exist yet.
+func (t *Tile) Layer(name string, version uint32, extent uint32) *Layer {
+	for _, layer := range t.Layers {
+		if layer.Name == name {
+			return layer
+		}
+	}
+	layer := &Layer{Name: name, Version: version, Extent: extent}
+	t.Layers = append(t.Layers, layer)
+
+	return layer
+}
+
+// Decode parses the given (uncompressed) vector tile.
+func Decode(data []byte) (*Tile, error) {
+	tile := &Tile{}
+	r := reader{buf: data}
+	for !r.done() {
+		field, wireType, err := r.field()
+		if err != nil {
+			return nil, err
+		}
+		if field != tileLayers || wireType != wireBytes {
+			if err = r.skip(wireType); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		buf, err := r.bytes()
+		if err != nil {
+			return nil, err
+		}
+		layer, err := decodeLayer(buf)
+		if err != nil {
+			return nil, fmt.Errorf("invalid vector tile: %w", err)
+		}
+		tile.Layers = append(tile.Layers, layer)
+	}
+
+	return tile, nil
+}
+
+func decodeLayer(data []byte) (*Layer, error) {
+	layer := &Layer{Version: 1, Extent: DefaultExtent}
+	var keys []string
+	var values [][]byte
+	var features [][]byte
+
+	r := reader{buf: data}
+	for !r.done() {
+		field, wireType, err := r.field()
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case field == layerName && wireType == wireBytes:
+			name, err := r.bytes()
+			if err != nil {
+				return nil, err
+			}
+			layer.Name = string(name)
+		case field == layerFeatures && wireType == wireBytes:
+			feature, err := r.bytes()
+			if err != nil {
+				return nil, err
+			}
+			features = append(features, feature)
+		case field == layerKeys && wireType == wireBytes:
+			key, err := r.bytes()
+			if err != nil {
+				return nil, err
+			}
+			keys = append(keys, string(key))
+		case field == layerValues && wireType == wireBytes:
+			value, err := r.bytes()
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, value)
+		case field == layerExtent && wireType == wireVarint:
+			extent, err := r.varint()
+			if err != nil {
+				return nil, err
+			}
+			layer.Extent = uint32(extent)
+		case field == layerVersion && wireType == wireVarint:
+			version, err := r.varint()
+			if err != nil {
+				return nil, err
+			}
+			layer.Version = uint32(version)
+		default:
+			if err = r.skip(wireType); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// keys and values may appear after the features, so decode features last
+	for _, buf := range features {
+		feature, err := decodeFeature(buf, keys, values)
+		if err != nil {
+			return nil, fmt.Errorf("layer %s: %w", layer.Name, err)
+		}
+		layer.Features = append(layer.Features, feature)
+	}
+
+	return layer, nil
+}
+
+func decodeFeature(data []byte, keys []string, values [][]byte) (Feature, error) {
+	var feature Feature
+	var tags, geometry []uint32
+
+	r := reader{buf: data}
+	for !r.done() {
+		field, wireType, err := r.field()
+		if err != nil {
+			return feature, err
+		}
+		switch {
+		case field == featureID && wireType == wireVarint:
+			id, err := r.varint()
+			if err != nil {
+				return feature, err
+			}
+			feature.ID = &id
+		case field == featureTags && wireType == wireBytes:
+			if tags, err = r.packed(); err != nil {
+				return feature, err
+			}
+		case field == featureType && wireType == wireVarint:
+			geomType, err := r.varint()
+			if err != nil {
+				return feature, err
+			}
+			feature.Type = GeometryType(geomType)
+		case field == featureGeometry && wireType == wireBytes:
+			if geometry, err = r.packed(); err != nil {
+				return feature, err
+			}
+		default:
+			if err = r.skip(wireType); err != nil {
+				return feature, err
+			}
+		}
+	}
+
+	if len(tags)%2 != 0 {
+		return feature, errors.New("odd number of tags")
+	}
+	for i := 0; i < len(tags); i += 2 {
+		if int(tags[i]) >= len(keys) || int(tags[i+1]) >= len(values) {
+			return feature, errors.New("tag refers to unknown key or value")
+		}
+		feature.Tags = append(feature.Tags, Tag{Key: keys[tags[i]], Value: values[tags[i+1]]})
+	}
+	var err error
+	feature.Geometry, err = decodeGeometry(feature.Type, geometry)
+
+	return feature, err
+}
+
+func decodeGeometry(geomType GeometryType, commands []uint32) ([][][2]float64, error) {
+	var parts [][][2]float64
+	var x, y int32
+	for i := 0; i < len(commands); {
+		cmd, count := commands[i]&0x7, int(commands[i]>>3)
+		i++
+		switch cmd {
+		case cmdMoveTo, cmdLineTo:
+			if i+2*count > len(commands) {
+				return nil, errors.New("truncated geometry")
+			}
+			if cmd == cmdMoveTo && (geomType != Point || len(parts) == 0) {
+				parts = append(parts, nil)
+			}
+			if len(parts) == 0 {
+				return nil, errors.New("geometry should start with a MoveTo command")
+			}
+			for range count {
+				x += unzigzag(commands[i])
+				y += unzigzag(commands[i+1])
+				i += 2
+				parts[len(parts)-1] = append(parts[len(parts)-1], [2]float64{float64(x), float64(y)})
+			}
+		case cmdClosePath:
+			// rings are implicitly closed
+		default:
+			return nil, fmt.Errorf("unknown geometry command %d", cmd)
+		}
+	}
+
+	return parts, nil
+}
+
+// Encode serializes the given tile. Coordinates are rounded to integers, features
+// without (valid) geometry after rounding are omitted, as are layers without features.
+func Encode(tile *Tile) []byte {
+	w := writer{}
+	for _, layer := range tile.Layers {
+		if buf := encodeLayer(layer); buf != nil {
+			w.bytes(tileLayers, buf)
+		}
+	}
+
+	return w.buf
+}
+
+func encodeLayer(layer *Layer) []byte {
+	keys := make(map[string]uint32)
+	values := make(map[string]uint32)
+	var keyList []string
+	var valueList [][]byte
+
+	features := writer{}
+	for _, feature := range layer.Features {
+		geometry := encodeGeometry(feature.Type, feature.Geometry)
+		if len(geometry) == 0 {
+			continue
+		}
+		tags := make([]uint32, 0, 2*len(feature.Tags))
+		for _, tag := range feature.Tags {
+			keyIndex, ok := keys[tag.Key]
+			if !ok {
+				keyIndex = uint32(len(keyList))
+				keys[tag.Key] = keyIndex
+				keyList = append(keyList, tag.Key)
+			}
+			valueIndex, ok := values[string(tag.Value)]
+			if !ok {
+				valueIndex = uint32(len(valueList))
+				values[string(tag.Value)] = valueIndex
+				valueList = append(valueList, tag.Value)
+			}
+			tags = append(tags, keyIndex, valueIndex)
+		}
+
+		f := writer{}
+		if feature.ID != nil {
+			f.varint(featureID, *feature.ID)
+		}
+		f.packed(featureTags, tags)
+		f.varint(featureType, uint64(feature.Type))
+		f.packed(featureGeometry, geometry)
+		features.bytes(layerFeatures, f.buf)
+	}
+	if len(features.buf) == 0 {
+		return nil
+	}
+
+	w := writer{}
+	w.varint(layerVersion, uint64(layer.Version))
+	w.string(layerName, layer.Name)
+	w.buf = append(w.buf, features.buf...)
+	for _, key := range keyList {
+		w.string(layerKeys, key)
+	}
+	for _, value := range valueList {
+		w.bytes(layerValues, value)
+	}
+	w.varint(layerExtent, uint64(layer.Extent))
+
+	return w.buf
+}
+
+func encodeGeometry(geomType GeometryType, parts [][][2]float64) []uint32 {
+	var commands []uint32
+	var x, y int32
+	appendPoints := func(points [][2]int32) {
+		for _, p := range points {
+			commands = append(commands, zigzag(p[0]-x), zigzag(p[1]-y))
+			x, y = p[0], p[1]
+		}
+	}
+
+	switch geomType {
+	case Point:
+		var points [][2]int32
+		for _, part := range parts {
+			for _, p := range part {
+				points = append(points, [2]int32{roundInt32(p[0]), roundInt32(p[1])})
+			}
+		}
+		if len(points) > 0 {
+			commands = append(commands, command(cmdMoveTo, len(points)))
+			appendPoints(points)
+		}
+	case LineString:
+		for _, part := range parts {
+			line := round(part)
+			if len(line) < 2 {
+				continue
+			}
+			commands = append(commands, command(cmdMoveTo, 1))
+			appendPoints(line[:1])
+			commands = append(commands, command(cmdLineTo, len(line)-1))
+			appendPoints(line[1:])
+		}
+	case Polygon:
+		skipInteriors := true
+		for _, part := range parts {
+			exterior := ringAreaFloat(part) > 0
+			ring := round(part)
+			if len(ring) > 1 && ring[0] == ring[len(ring)-1] {
+				ring = ring[:len(ring)-1]
+			}
+			// rings may become degenerate due to rounding, when this happens to an
+			// exterior ring the interior rings belonging to it are omitted as well.
+			degenerate := len(ring) < 3 || ringArea(ring) == 0
+			if exterior {
+				skipInteriors = degenerate
+			}
+			if degenerate || (!exterior && skipInteriors) {
+				continue
+			}
+			commands = append(commands, command(cmdMoveTo, 1))
+			appendPoints(ring[:1])
+			commands = append(commands, command(cmdLineTo, len(ring)-1))
+			appendPoints(ring[1:])
+			commands = append(commands, command(cmdClosePath, 1))
+		}
+	}
+
+	return commands
+}
+
+func command(id int, count int) uint32 {
+	return uint32(id&0x7) | uint32(count)<<3
+}
+
+// round rounds the given points to integer tile coordinates, dropping consecutive duplicate points.
+func round(points [][2]float64) [][2]int32 {
+	result := make([][2]int32, 0, len(points))
+	for _, p := range points {
+		rounded := [2]int32{roundInt32(p[0]), roundInt32(p[1])}
+		if len(result) > 0 && result[len(result)-1] == rounded {
+			continue
+		}
+		result = append(result, rounded)
+	}
+
+	return result
+}
+
+// ringArea signed area (times 2) of the given ring in tile coordinates, positive for exterior rings.
+func ringArea(ring [][2]int32) int64 {
+	var area int64
+	for i := range ring {
+		j := (i + 1) % len(ring)
+		area += int64(ring[i][0])*int64(ring[j][1]) - int64(ring[j][0])*int64(ring[i][1])
+	}
+
+	return area
+}
+
+func ringAreaFloat(ring [][2]float64) float64 {
+	var area float64
+	for i := range ring {
+		j := (i + 1) % len(ring)
+		area += ring[i][0]*ring[j][1] - ring[j][0]*ring[i][1]
+	}
+
+	return area
+}
@@ -0,0 +1,177 @@
+package mvt
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// protobuf encoded string value (field 1 of Value message)
+func stringValue(s string) []byte {
+	w := writer{}
+	w.string(1, s)
+	return w.buf
+}
+
+func TestEncodeGeometry(t *testing.T) {
+	// examples from the vector tile specification
+	tests := []struct {
+		name     string
+		geomType GeometryType
+		geometry [][][2]float64
+		want     []uint32
+	}{
+		{
+			name:     "point",
+			geomType: Point,
+			geometry: [][][2]float64{{{25, 17}}},
+			want:     []uint32{9, 50, 34},
+		},
+		{
+			name:     "multi point",
+			geomType: Point,
+			geometry: [][][2]float64{{{5, 7}, {3, 2}}},
+			want:     []uint32{17, 10, 14, 3, 9},
+		},
+		{
+			name:     "linestring",
+			geomType: LineString,
+			geometry: [][][2]float64{{{2, 2}, {2, 10}, {10, 10}}},
+			want:     []uint32{9, 4, 4, 18, 0, 16, 16, 0},
+		},
+		{
+			name:     "polygon",
+			geomType: Polygon,
+			geometry: [][][2]float64{{{3, 6}, {8, 12}, {20, 34}}},
+			want:     []uint32{9, 6, 12, 18, 10, 12, 24, 44, 15},
+		},
+		{
+			name:     "polygon with degenerate exterior ring and its interior ring",
+			geomType: Polygon,
+			geometry: [][][2]float64{{{0, 0}, {0.2, 0}, {0.2, 0.2}}, {{0, 0}, {0, 10}, {10, 10}}},
+			want:     nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := encodeGeometry(tt.geomType, tt.geometry)
+			assert.Equal(t, tt.want, got)
+
+			if tt.want != nil {
+				decoded, err := decodeGeometry(tt.geomType, got)
+				require.NoError(t, err)
+				assert.Equal(t, tt.geometry, decoded)
+			}
+		})
+	}
+}
+
+func TestEncodeDecode(t *testing.T) {
+	id := uint64(42)
+	tile := &Tile{}
+	buildings := tile.Layer("buildings", 2, DefaultExtent)
+	buildings.Features = append(buildings.Features,
+		Feature{
+			ID:       &id,
+			Type:     Polygon,
+			Tags:     []Tag{{Key: "name", Value: stringValue("town hall")}, {Key: "kind", Value: stringValue("public")}},
+			Geometry: [][][2]float64{{{0, 0}, {100, 0}, {100, 100}, {0, 100}}, {{10, 10}, {10, 20}, {20, 20}, {20, 10}}},
+		},
+		Feature{
+			Type:     Polygon,
+			Tags:     []Tag{{Key: "kind", Value: stringValue("public")}},
+			Geometry: [][][2]float64{{{200, 200}, {300, 200}, {300, 300}}},
+		},
+	)
+	roads := tile.Layer("roads", 2, DefaultExtent)
+	roads.Features = append(roads.Features, Feature{
+		Type:     LineString,
+		Geometry: [][][2]float64{{{0, 0}, {4096, 4096}}, {{10, 0}, {0, 10}}},
+	})
+	tile.Layer("empty", 2, DefaultExtent)
+	assert.Same(t, buildings, tile.Layer("buildings", 2, DefaultExtent))
+
+	decoded, err := Decode(Encode(tile))
+	require.NoError(t, err)
+	require.Len(t, decoded.Layers, 2, "empty layer should be omitted")
+	assert.Equal(t, *buildings, *decoded.Layers[0])
+	assert.Equal(t, *roads, *decoded.Layers[1])
+}
+
+func TestDecode_Invalid(t *testing.T) {
+	_, err := Decode([]byte{0x1a, 0x10, 0x01})
+	require.Error(t, err)
+
+	tile, err := Decode(nil)
+	require.NoError(t, err)
+	assert.Empty(t, tile.Layers)
+}
+
+func TestTag_Type(t *testing.T) {
+	intValue := writer{}
+	intValue.varint(valueInt, 42)
+	boolValue := writer{}
+	boolValue.varint(valueBool, 1)
+	doubleValue := writer{}
+	doubleValue.key(valueDouble, wire64Bit)
+	doubleValue.buf = append(doubleValue.buf, make([]byte, 8)...)
+
+	assert.Equal(t, StringValue, Tag{Key: "name", Value: stringValue("town hall")}.Type())
+	assert.Equal(t, IntValue, Tag{Key: "floors", Value: intValue.buf}.Type())
+	assert.Equal(t, BoolValue, Tag{Key: "public", Value: boolValue.buf}.Type())
+	assert.Equal(t, FloatValue, Tag{Key: "height", Value: doubleValue.buf}.Type())
+	assert.Equal(t, UnknownValue, Tag{Key: "invalid"}.Type())
+}
+
+func TestFeature_Clip(t *testing.T) {
+	tests := []struct {
+		name    string
+		feature Feature
+		want    [][][2]float64
+	}{
+		{
+			name:    "points",
+			feature: Feature{Type: Point, Geometry: [][][2]float64{{{5, 5}, {15, 5}}}},
+			want:    [][][2]float64{{{5, 5}}},
+		},
+		{
+			name:    "line crossing the bounds twice",
+			feature: Feature{Type: LineString, Geometry: [][][2]float64{{{-5, 5}, {5, 5}, {5, 15}, {8, 15}, {8, 5}}}},
+			want:    [][][2]float64{{{0, 5}, {5, 5}, {5, 10}}, {{8, 10}, {8, 5}}},
+		},
+		{
+			name:    "polygon partially outside",
+			feature: Feature{Type: Polygon, Geometry: [][][2]float64{{{5, 5}, {15, 5}, {15, 15}, {5, 15}}}},
+			want:    [][][2]float64{{{5, 10}, {5, 5}, {10, 5}, {10, 10}}},
+		},
+		{
+			name:    "polygon outside",
+			feature: Feature{Type: Polygon, Geometry: [][][2]float64{{{20, 20}, {30, 20}, {30, 30}}}},
+			want:    nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok := tt.feature.Clip(0, 0, 10, 10)
+			assert.Equal(t, tt.want != nil, ok)
+			assert.Equal(t, tt.want, tt.feature.Geometry)
+		})
+	}
+}
+
+func TestFeature_Transform(t *testing.T) {
+	feature := Feature{Type: LineString, Geometry: [][][2]float64{{{1, 2}, {3, 4}}}}
+	feature.Transform(func(x, y float64) (float64, float64) { return x * 2, y + 1 })
+	assert.Equal(t, [][][2]float64{{{2, 3}, {6, 5}}}, feature.Geometry)
+}
+
+func TestFeature_Merge(t *testing.T) {
+	points := Feature{Type: Point, Geometry: [][][2]float64{{{1, 2}}}}
+	points.Merge(Feature{Type: Point, Geometry: [][][2]float64{{{1.2, 1.9}, {5, 6}}}})
+	assert.Equal(t, [][][2]float64{{{1, 2}, {5, 6}}}, points.Geometry)
+
+	line := Feature{Type: LineString, Geometry: [][][2]float64{{{1, 2}, {3, 4}}}}
+	line.Merge(Feature{Type: LineString, Geometry: [][][2]float64{{{3, 4}, {5, 6}}}})
+	assert.Equal(t, [][][2]float64{{{1, 2}, {3, 4}}, {{3, 4}, {5, 6}}}, line.Geometry)
+}
@@ -0,0 +1,158 @@
+package mvt
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// protobuf wire types, see https://protobuf.dev/programming-guides/encoding/
+const (
+	wireVarint  = 0
+	wire64Bit   = 1
+	wireBytes   = 2
+	wire32Bit   = 5
+	maxFieldNum = 1<<29 - 1
+)
+
+var errTruncated = errors.New("truncated protobuf message")
+
+// reader minimal protobuf decoder, just enough to decode vector tiles.
+type reader struct {
+	buf []byte
+	pos int
+}
+
+func (r *reader) done() bool {
+	return r.pos >= len(r.buf)
+}
+
+func (r *reader) varint() (uint64, error) {
+	value, n := binary.Uvarint(r.buf[r.pos:])
+	if n <= 0 {
+		return 0, errTruncated
+	}
+	r.pos += n
+
+	return value, nil
+}
+
+// field returns the next field number and wire type.
+func (r *reader) field() (int, int, error) {
+	key, err := r.varint()
+	if err != nil {
+		return 0, 0, err
+	}
+	if key>>3 == 0 || key>>3 > maxFieldNum {
+		return 0, 0, fmt.Errorf("invalid protobuf field number %d", key>>3)
+	}
+
+	return int(key >> 3), int(key & 7), nil
+}
+
+func (r *reader) bytes() ([]byte, error) {
+	length, err := r.varint()
+	if err != nil {
+		return nil, err
+	}
+	if length > uint64(len(r.buf)-r.pos) {
+		return nil, errTruncated
+	}
+	result := r.buf[r.pos : r.pos+int(length)]
+	r.pos += int(length)
+
+	return result, nil
+}
+
+// packed reads a packed repeated varint field.
+func (r *reader) packed() ([]uint32, error) {
+	buf, err := r.bytes()
+	if err != nil {
+		return nil, err
+	}
+	result := make([]uint32, 0, len(buf))
+	packed := reader{buf: buf}
+	for !packed.done() {
+		value, err := packed.varint()
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, uint32(value))
+	}
+
+	return result, nil
+}
+
+func (r *reader) skip(wireType int) error {
+	var size int
+	switch wireType {
+	case wireVarint:
+		_, err := r.varint()
+		return err
+	case wireBytes:
+		_, err := r.bytes()
+		return err
+	case wire64Bit:
+		size = 8
+	case wire32Bit:
+		size = 4
+	default:
+		return fmt.Errorf("unsupported protobuf wire type %d", wireType)
+	}
+	if r.pos+size > len(r.buf) {
+		return errTruncated
+	}
+	r.pos += size
+
+	return nil
+}
+
+// writer minimal protobuf encoder, just enough to encode vector tiles.
+type writer struct {
+	buf []byte
+}
+
+func (w *writer) key(field int, wireType int) {
+	w.buf = binary.AppendUvarint(w.buf, uint64(field)<<3|uint64(wireType))
+}
+
+func (w *writer) varint(field int, value uint64) {
+	w.key(field, wireVarint)
+	w.buf = binary.AppendUvarint(w.buf, value)
+}
+
+func (w *writer) bytes(field int, value []byte) {
+	w.key(field, wireBytes)
+	w.buf = binary.AppendUvarint(w.buf, uint64(len(value)))
+	w.buf = append(w.buf, value...)
+}
+
+func (w *writer) string(field int, value string) {
+	w.bytes(field, []byte(value))
+}
+
+func (w *writer) packed(field int, values []uint32) {
+	if len(values) == 0 {
+		return
+	}
+	packed := make([]byte, 0, len(values)*2)
+	for _, value := range values {
+		packed = binary.AppendUvarint(packed, uint64(value))
+	}
+	w.bytes(field, packed)
+}
+
+// zigzag encoding of signed integers, as used for geometry parameters.
+func zigzag(value int32) uint32 {
+	return uint32((value << 1) ^ (value >> 31))
+}
+
+func unzigzag(value uint32) int32 {
+	return int32(value>>1) ^ -int32(value&1)
+}
+
+// roundInt32 rounds the given coordinate, clamping it to the range of int32.
+func roundInt32(value float64) int32 {
+	return int32(math.Max(math.MinInt32, math.Min(math.MaxInt32, math.Round(value))))
+}
@@ -0,0 +1,291 @@
+package tiles
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/PDOK/gokoala/config"
+)
+
+const (
+	tileMatrixSetsDir        = "internal/ogc/tiles/tileMatrixSets/"
+	cornerOfOriginBottomLeft = "bottomLeft"
+)
+
+// TileMatrixSet definition according to the OGC Two Dimensional Tile Matrix Set standard (2D-TMS).
+// See https://docs.ogc.org/is/17-083r4/17-083r4.html
+type TileMatrixSet struct {
+	ID                string       `json:"id"`
+	Title             string       `json:"title,omitempty"`
+	Description       string       `json:"description,omitempty"`
+	URI               string       `json:"uri,omitempty"`
+	CRS               CRS          `json:"crs"`
+	OrderedAxes       []string     `json:"orderedAxes,omitempty"`
+	WellKnownScaleSet string       `json:"wellKnownScaleSet,omitempty"`
+	TileMatrices      []TileMatrix `json:"tileMatrices"`
+}
+
+// TileMatrix a single zoom level in a TileMatrixSet. The ID should equal the zoom level.
+type TileMatrix struct {
+	ID               string     `json:"id"`
+	ScaleDenominator float64    `json:"scaleDenominator"`
+	CellSize         float64    `json:"cellSize"`
+	CornerOfOrigin   string     `json:"cornerOfOrigin,omitempty"`
+	PointOfOrigin    [2]float64 `json:"pointOfOrigin"`
+	TileWidth        int        `json:"tileWidth"`
+	TileHeight       int        `json:"tileHeight"`
+	MatrixWidth      int        `json:"matrixWidth"`
+	MatrixHeight     int        `json:"matrixHeight"`
+}
+
+// CRS reference to a coordinate reference system. In 2D-TMS this is either
+// a URI or an object containing the URI.
+type CRS string
+
+// UnmarshalJSON parses a CRS as URI string or as {"uri": "..."} object.
+func (c *CRS) UnmarshalJSON(b []byte) error {
+	var uri string
+	if err := json.Unmarshal(b, &uri); err == nil {
+		*c = CRS(uri)
+		return nil
+	}
+	var ref struct {
+		URI string `json:"uri"`
+	}
+	if err := json.Unmarshal(b, &ref); err != nil {
+		return fmt.Errorf("crs should be a URI or an object with an uri: %w", err)
+	}
+	*c = CRS(ref.URI)
+	return nil
+}
+
+// HTTPS the CRS URI using the https scheme, as advertised in the list of tilesets.
+func (c CRS) HTTPS() string {
+	if uri, ok := strings.CutPrefix(string(c), "http://"); ok {
+		return "https://" + uri
+	}
+
+	return string(c)
+}
+
+// Srs the CRS as SRS (e.g. EPSG:28992), empty when this isn't an EPSG CRS.
+func (c CRS) Srs() string {
+	uri := string(c)
+	if !strings.Contains(uri, "EPSG") {
+		return ""
+	}
+	code := uri[strings.LastIndexAny(uri, "/:")+1:]
+	if _, err := strconv.Atoi(code); err != nil {
+		return ""
+	}
+
+	return "EPSG:" + code
+}
+
+// TileMatrixSetLimits the range of tiles available in a TileMatrix.
+type TileMatrixSetLimits struct {
+	TileMatrix string `json:"tileMatrix"`
+	MinTileRow int    `json:"minTileRow"`
+	MaxTileRow int    `json:"maxTileRow"`
+	MinTileCol int    `json:"minTileCol"`
+	MaxTileCol int    `json:"maxTileCol"`
+}
+
+// LoadTileMatrixSets reads the built-in TileMatrixSets and the custom TileMatrixSets used in the given config.
+func LoadTileMatrixSets(cfg *config.OgcAPITiles) (map[string]TileMatrixSet, error) {
+	files := make([]string, 0)
+	for _, id := range config.AllTileProjections {
+		files = append(files, tileMatrixSetsDir+id+".json")
+	}
+	if cfg.CustomTileMatrixSets != nil {
+		if cfg.CustomTileMatrixSets.Dir != nil {
+			matches, err := filepath.Glob(filepath.Join(*cfg.CustomTileMatrixSets.Dir, "*.json"))
+			if err != nil {
+				return nil, err
+			}
+			files = append(files, matches...)
+		}
+		files = append(files, cfg.CustomTileMatrixSets.Files...)
+	}
+
+	customIDs := cfg.GetCustomTileMatrixSetIDs()
+	result := make(map[string]TileMatrixSet)
+	for _, file := range files {
+		tms, err := readTileMatrixSet(file)
+		if err != nil {
+			return nil, err
+		}
+		if !config.IsBuiltInTileMatrixSet(tms.ID) && !slices.Contains(customIDs, tms.ID) {
+			continue // not used in this dataset
+		}
+		if _, ok := result[tms.ID]; ok {
+			return nil, fmt.Errorf("duplicate definition of tileMatrixSet '%s' in %s", tms.ID, file)
+		}
+		result[tms.ID] = *tms
+	}
+
+	for _, projection := range cfg.GetProjections() {
+		tms, ok := result[projection.GetTileMatrixSetID()]
+		if !ok {
+			return nil, fmt.Errorf("no definition found of tileMatrixSet '%s' used for srs '%s'",
+				projection.GetTileMatrixSetID(), projection.Srs)
+		}
+		if tms.CRS.Srs() != projection.Srs {
+			return nil, fmt.Errorf("crs '%s' of tileMatrixSet '%s' doesn't match srs '%s'",
+				tms.CRS, tms.ID, projection.Srs)
+		}
+	}
+
+	return result, nil
+}
+
+func readTileMatrixSet(file string) (*TileMatrixSet, error) {
+	contents, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read tileMatrixSet %s: %w", file, err)
+	}
+	var tms TileMatrixSet
+	if err = json.Unmarshal(contents, &tms); err != nil {
+		return nil, fmt.Errorf("invalid tileMatrixSet %s: %w", file, err)
+	}
+	if err = tms.validate(); err != nil {
+		return nil, fmt.Errorf("invalid tileMatrixSet %s: %w", file, err)
+	}
+
+	return &tms, nil
+}
+
+func (tms TileMatrixSet) validate() error {
+	var errs []error
+	if tms.ID == "" {
+		errs = append(errs, errors.New("id is required"))
+	}
+	if tms.CRS.Srs() == "" {
+		errs = append(errs, fmt.Errorf("crs '%s' is not an EPSG crs", tms.CRS))
+	}
+	if len(tms.TileMatrices) == 0 {
+		errs = append(errs, errors.New("at least one tileMatrix is required"))
+	}
+	for _, tm := range tms.TileMatrices {
+		if zoomLevel, err := strconv.Atoi(tm.ID); err != nil || zoomLevel < 0 {
+			errs = append(errs, fmt.Errorf("id of tileMatrix '%s' should be a zoom level (integer)", tm.ID))
+		}
+		if tm.CellSize <= 0 || tm.TileWidth <= 0 || tm.TileHeight <= 0 || tm.MatrixWidth <= 0 || tm.MatrixHeight <= 0 {
+			errs = append(errs, fmt.Errorf("tileMatrix '%s' should have a positive cellSize, "+
+				"tileWidth, tileHeight, matrixWidth and matrixHeight", tm.ID))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Limits computes the TileMatrixSetLimits for the given zoom levels. When an extent is given (minx, miny,
+// maxx, maxy in the CRS of this TileMatrixSet) the limits are restricted to the tiles covering this extent.
+func (tms TileMatrixSet) Limits(zoomLevelRange config.ZoomLevelRange, extent []float64) map[int]TileMatrixSetLimits {
+	result := make(map[int]TileMatrixSetLimits)
+	for _, tm := range tms.TileMatrices {
+		zoomLevel, _ := strconv.Atoi(tm.ID)
+		if zoomLevel < zoomLevelRange.Start || zoomLevel > zoomLevelRange.End {
+			continue
+		}
+		result[zoomLevel] = tms.limitsWithin(tm, extent)
+	}
+
+	return result
+}
+
+// limitsWithin the TileMatrixSetLimits of the given TileMatrix, restricted to the given extent when provided.
+func (tms TileMatrixSet) limitsWithin(tm TileMatrix, extent []float64) TileMatrixSetLimits {
+	limits := TileMatrixSetLimits{
+		TileMatrix: tm.ID,
+		MaxTileRow: tm.MatrixHeight - 1,
+		MaxTileCol: tm.MatrixWidth - 1,
+	}
+	if len(extent) == 4 {
+		originX, originY := tms.origin(tm)
+		tileSpanX := tm.CellSize * float64(tm.TileWidth)
+		tileSpanY := tm.CellSize * float64(tm.TileHeight)
+
+		limits.MinTileCol = clamp(math.Floor((extent[0]-originX)/tileSpanX), limits.MaxTileCol)
+		limits.MaxTileCol = clamp(math.Ceil((extent[2]-originX)/tileSpanX)-1, limits.MaxTileCol)
+		if tm.CornerOfOrigin == cornerOfOriginBottomLeft {
+			limits.MinTileRow = clamp(math.Floor((extent[1]-originY)/tileSpanY), limits.MaxTileRow)
+			limits.MaxTileRow = clamp(math.Ceil((extent[3]-originY)/tileSpanY)-1, limits.MaxTileRow)
+		} else {
+			limits.MinTileRow = clamp(math.Floor((originY-extent[3])/tileSpanY), limits.MaxTileRow)
+			limits.MaxTileRow = clamp(math.Ceil((originY-extent[1])/tileSpanY)-1, limits.MaxTileRow)
+		}
+	}
+
+	return limits
+}
+
+// origin point of origin of the given TileMatrix as x/y (easting/northing), regardless of the axis order of the CRS.
+func (tms TileMatrixSet) origin(tm TileMatrix) (float64, float64) {
+	if len(tms.OrderedAxes) > 0 {
+		switch strings.ToUpper(tms.OrderedAxes[0]) {
+		case "Y", "N", "LAT", "NORTHING":
+			return tm.PointOfOrigin[1], tm.PointOfOrigin[0]
+		}
+	}
+
+	return tm.PointOfOrigin[0], tm.PointOfOrigin[1]
+}
+
+// tileMatrix the TileMatrix of the given zoom level.
+func (tms TileMatrixSet) tileMatrix(zoomLevel int) (TileMatrix, bool) {
+	id := strconv.Itoa(zoomLevel)
+	for _, tm := range tms.TileMatrices {
+		if tm.ID == id {
+			return tm, true
+		}
+	}
+
+	return TileMatrix{}, false
+}
+
+// ScaleDenominator scale denominator of the given zoom level, false when the zoom level doesn't exist.
+func (tms TileMatrixSet) ScaleDenominator(zoomLevel int) (float64, bool) {
+	tm, ok := tms.tileMatrix(zoomLevel)
+
+	return tm.ScaleDenominator, ok
+}
+
+// matrixHeights number of tile rows by zoom level.
+func (tms TileMatrixSet) matrixHeights() map[int]int {
+	result := make(map[int]int, len(tms.TileMatrices))
+	for _, tm := range tms.TileMatrices {
+		if zoomLevel, err := strconv.Atoi(tm.ID); err == nil {
+			result[zoomLevel] = tm.MatrixHeight
+		}
+	}
+
+	return result
+}
+
+// tileBounds bbox (minx, miny, maxx, maxy) of the given tile in the CRS of this TileMatrixSet.
+func (tms TileMatrixSet) tileBounds(tm TileMatrix, tileRow, tileCol int) [4]float64 {
+	originX, originY := tms.origin(tm)
+	tileSpanX := tm.CellSize * float64(tm.TileWidth)
+	tileSpanY := tm.CellSize * float64(tm.TileHeight)
+
+	minX := originX + float64(tileCol)*tileSpanX
+	if tm.CornerOfOrigin == cornerOfOriginBottomLeft {
+		minY := originY + float64(tileRow)*tileSpanY
+		return [4]float64{minX, minY, minX + tileSpanX, minY + tileSpanY}
+	}
+	maxY := originY - float64(tileRow)*tileSpanY
+
+	return [4]float64{minX, maxY - tileSpanY, minX + tileSpanX, maxY}
+}
+
+func clamp(value float64, maxValue int) int {
+	return int(math.Max(0, math.Min(value, float64(maxValue))))
+}
@@ -0,0 +1,237 @@
+package tiles
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/PDOK/gokoala/config"
+	"github.com/PDOK/gokoala/internal/engine"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCRS_Srs(t *testing.T) {
+	tests := []struct {
+		crs  CRS
+		want string
+	}{
+		{"http://www.opengis.net/def/crs/EPSG/0/28992", "EPSG:28992"},
+		{"https://www.opengis.net/def/crs/EPSG/0/3857", "EPSG:3857"},
+		{"urn:ogc:def:crs:EPSG::3035", "EPSG:3035"},
+		{"EPSG:25831", "EPSG:25831"},
+		{"http://www.opengis.net/def/crs/OGC/1.3/CRS84", ""},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, tt.crs.Srs(), string(tt.crs))
+	}
+}
+
+func TestCRS_UnmarshalJSON(t *testing.T) {
+	var tms TileMatrixSet
+	require.NoError(t, json.Unmarshal([]byte(`{"id": "a", "crs": "http://www.opengis.net/def/crs/EPSG/0/28992"}`), &tms))
+	assert.Equal(t, CRS("http://www.opengis.net/def/crs/EPSG/0/28992"), tms.CRS)
+
+	require.NoError(t, json.Unmarshal([]byte(`{"id": "b", "crs": {"uri": "http://www.opengis.net/def/crs/EPSG/0/25831"}}`), &tms))
+	assert.Equal(t, CRS("http://www.opengis.net/def/crs/EPSG/0/25831"), tms.CRS)
+}
+
+func TestLoadTileMatrixSets(t *testing.T) {
+	customDir := "internal/ogc/tiles/testdata/tileMatrixSets"
+	tests := []struct {
+		name    string
+		tiles   *config.OgcAPITiles
+		wantIDs []string
+		wantErr string
+	}{
+		{
+			name: "built-in only",
+			tiles: &config.OgcAPITiles{DatasetTiles: &config.Tiles{
+				SupportedSrs: []config.SupportedSrs{{Srs: "EPSG:28992"}},
+			}},
+			wantIDs: []string{"EuropeanETRS89_LAEAQuad", "NetherlandsRDNewQuad", "WebMercatorQuad"},
+		},
+		{
+			name: "custom tileMatrixSet from dir",
+			tiles: &config.OgcAPITiles{
+				DatasetTiles: &config.Tiles{
+					SupportedSrs: []config.SupportedSrs{{Srs: "EPSG:25831", TileMatrixSet: "NetherlandsUTM31Quad"}},
+				},
+				CustomTileMatrixSets: &config.CustomTileMatrixSets{Dir: &customDir},
+			},
+			wantIDs: []string{"EuropeanETRS89_LAEAQuad", "NetherlandsRDNewQuad", "NetherlandsUTM31Quad", "WebMercatorQuad"},
+		},
+		{
+			name: "custom tileMatrixSet from file",
+			tiles: &config.OgcAPITiles{
+				DatasetTiles: &config.Tiles{
+					SupportedSrs: []config.SupportedSrs{{Srs: "EPSG:25831", TileMatrixSet: "NetherlandsUTM31Quad"}},
+				},
+				CustomTileMatrixSets: &config.CustomTileMatrixSets{Files: []string{customDir + "/NetherlandsUTM31Quad.json"}},
+			},
+			wantIDs: []string{"EuropeanETRS89_LAEAQuad", "NetherlandsRDNewQuad", "NetherlandsUTM31Quad", "WebMercatorQuad"},
+		},
+		{
+			name: "unused custom tileMatrixSet is ignored",
+			tiles: &config.OgcAPITiles{
+				DatasetTiles: &config.Tiles{
+					SupportedSrs: []config.SupportedSrs{{Srs: "EPSG:3857"}},
+				},
+				CustomTileMatrixSets: &config.CustomTileMatrixSets{Dir: &customDir},
+			},
+			wantIDs: []string{"EuropeanETRS89_LAEAQuad", "NetherlandsRDNewQuad", "WebMercatorQuad"},
+		},
+		{
+			name: "missing definition",
+			tiles: &config.OgcAPITiles{
+				DatasetTiles: &config.Tiles{
+					SupportedSrs: []config.SupportedSrs{{Srs: "EPSG:25831", TileMatrixSet: "Unknown"}},
+				},
+				CustomTileMatrixSets: &config.CustomTileMatrixSets{Dir: &customDir},
+			},
+			wantErr: "no definition found of tileMatrixSet 'Unknown' used for srs 'EPSG:25831'",
+		},
+		{
+			name: "srs doesn't match crs of tileMatrixSet",
+			tiles: &config.OgcAPITiles{
+				DatasetTiles: &config.Tiles{
+					SupportedSrs: []config.SupportedSrs{{Srs: "EPSG:28992", TileMatrixSet: "NetherlandsUTM31Quad"}},
+				},
+				CustomTileMatrixSets: &config.CustomTileMatrixSets{Dir: &customDir},
+			},
+			wantErr: "crs 'http://www.opengis.net/def/crs/EPSG/0/25831' of tileMatrixSet 'NetherlandsUTM31Quad' doesn't match srs 'EPSG:28992'",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := LoadTileMatrixSets(tt.tiles)
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.ElementsMatch(t, tt.wantIDs, func() []string {
+				ids := make([]string, 0, len(result))
+				for id := range result {
+					ids = append(ids, id)
+				}
+				return ids
+			}())
+		})
+	}
+}
+
+func TestTileMatrixSet_Limits(t *testing.T) {
+	tileMatrixSets, err := LoadTileMatrixSets(&config.OgcAPITiles{})
+	require.NoError(t, err)
+	custom, err := readTileMatrixSet("internal/ogc/tiles/testdata/tileMatrixSets/NetherlandsUTM31Quad.json")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name           string
+		tms            TileMatrixSet
+		zoomLevelRange config.ZoomLevelRange
+		extent         []float64
+		want           map[int]TileMatrixSetLimits
+	}{
+		{
+			name:           "full tile matrices without extent",
+			tms:            tileMatrixSets["NetherlandsRDNewQuad"],
+			zoomLevelRange: config.ZoomLevelRange{Start: 1, End: 3},
+			want: map[int]TileMatrixSetLimits{
+				1: {TileMatrix: "1", MaxTileRow: 1, MaxTileCol: 1},
+				2: {TileMatrix: "2", MaxTileRow: 3, MaxTileCol: 3},
+				3: {TileMatrix: "3", MaxTileRow: 7, MaxTileCol: 7},
+			},
+		},
+		{
+			name:           "zoom levels beyond tileMatrixSet are ignored",
+			tms:            *custom,
+			zoomLevelRange: config.ZoomLevelRange{Start: 3, End: 20},
+			want: map[int]TileMatrixSetLimits{
+				3: {TileMatrix: "3", MaxTileRow: 7, MaxTileCol: 7},
+			},
+		},
+		{
+			name:           "restricted by extent",
+			tms:            *custom,
+			zoomLevelRange: config.ZoomLevelRange{Start: 0, End: 3},
+			extent:         []float64{600000, 5800000, 700000, 5900000},
+			want: map[int]TileMatrixSetLimits{
+				0: {TileMatrix: "0", MinTileRow: 0, MaxTileRow: 0, MinTileCol: 0, MaxTileCol: 0},
+				1: {TileMatrix: "1", MinTileRow: 0, MaxTileRow: 1, MinTileCol: 0, MaxTileCol: 1},
+				2: {TileMatrix: "2", MinTileRow: 1, MaxTileRow: 3, MinTileCol: 1, MaxTileCol: 3},
+				3: {TileMatrix: "3", MinTileRow: 3, MaxTileRow: 6, MinTileCol: 3, MaxTileCol: 6},
+			},
+		},
+		{
+			name:           "restricted by extent with northing/easting axis order",
+			tms:            tileMatrixSets["EuropeanETRS89_LAEAQuad"],
+			zoomLevelRange: config.ZoomLevelRange{Start: 2, End: 2},
+			// south-east quarter of the tile matrix set
+			extent: []float64{4250000, 1000000, 6500000, 3250000},
+			want: map[int]TileMatrixSetLimits{
+				2: {TileMatrix: "2", MinTileRow: 2, MaxTileRow: 3, MinTileCol: 2, MaxTileCol: 3},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.tms.Limits(tt.zoomLevelRange, tt.extent))
+		})
+	}
+}
+
+func TestTiles_CustomTileMatrixSet(t *testing.T) {
+	newEngine, err := engine.NewEngine("internal/ogc/tiles/testdata/config_tiles_custom_tms.yaml",
+		"internal/engine/testdata/test_theme.yaml", "", false, true)
+	require.NoError(t, err)
+	tiles := NewTiles(newEngine, nil)
+
+	// tileset metadata with limits based on extent of collection
+	req, err := createTilesetRequest("http://localhost:8080/collections/example/tiles/NetherlandsUTM31Quad?f=json", "NetherlandsUTM31Quad", "example")
+	require.NoError(t, err)
+	rr, ts := createMockServer()
+	tiles.TilesetForCollection().ServeHTTP(rr, req)
+	ts.Close()
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var tileset struct {
+		CRS                 string                `json:"crs"`
+		TileMatrixSetLimits []TileMatrixSetLimits `json:"tileMatrixSetLimits"`
+	}
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &tileset))
+	assert.Equal(t, "http://www.opengis.net/def/crs/EPSG/0/25831", tileset.CRS)
+	assert.Equal(t, TileMatrixSetLimits{TileMatrix: "3", MinTileRow: 3, MaxTileRow: 6, MinTileCol: 3, MaxTileCol: 6},
+		tileset.TileMatrixSetLimits[3])
+
+	// tileMatrixSet definition
+	req, err = createTilematrixSetRequest("http://localhost:8080/tileMatrixSets/NetherlandsUTM31Quad?f=json", "NetherlandsUTM31Quad")
+	require.NoError(t, err)
+	rr, ts = createMockServer()
+	tiles.TileMatrixSet().ServeHTTP(rr, req)
+	ts.Close()
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), `"NetherlandsUTM31Quad"`)
+
+	// tiles
+	geoDataTiles := map[string]config.Tiles{"example": newEngine.Config.OgcAPI.Tiles.Collections[0].GeoDataTiles}
+	tests := []struct {
+		tileMatrix, tileRow, tileCol string
+		wantStatusCode               int
+		wantBody                     string
+	}{
+		{"3", "4", "5", http.StatusOK, "/NetherlandsUTM31Quad/3/5/4.pbf"},
+		{"3", "0", "0", http.StatusNotFound, "tileRow/tileCol 0/0 is out of range"},
+		{"4", "4", "5", http.StatusNotFound, "tileMatrix 4 is out of range"},
+	}
+	for _, tt := range tests {
+		req, err = createTileRequest("http://localhost:8080/collections/example/tiles/:tileMatrixSetId/:tileMatrix/:tileRow/:tileCol?f=mvt",
+			"NetherlandsUTM31Quad", tt.tileMatrix, tt.tileRow, tt.tileCol, "example")
+		require.NoError(t, err)
+		rr, ts = createMockServer()
+		tiles.TileForCollection(geoDataTiles).ServeHTTP(rr, req)
+		ts.Close()
+		assert.Equal(t, tt.wantStatusCode, rr.Code)
+		assert.Contains(t, rr.Body.String(), tt.wantBody)
+	}
+}
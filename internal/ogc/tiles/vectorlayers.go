@@ -0,0 +1,332 @@
+package tiles
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/PDOK/gokoala/config"
+	"github.com/PDOK/gokoala/internal/engine"
+	"github.com/PDOK/gokoala/internal/engine/util"
+	"github.com/PDOK/gokoala/internal/ogc/tiles/mvt"
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	// max time spent discovering the vector layers of all tilesets, during startup
+	vectorLayersTimeout = 30 * time.Second
+
+	// max number of tilesets inspected concurrently
+	vectorLayersConcurrency = 4
+)
+
+// vectorLayer layer in vector tiles, advertised in TileJSON (as vector_layers) and tileset metadata (as layers).
+type vectorLayer struct {
+	// ID name of the layer in the vector tiles
+	ID string
+
+	// Fields attributes of the features in this layer by name, with their JSON type (string, number,
+	// integer or boolean). The type is empty when mixed or unknown.
+	Fields map[string]string
+
+	// MinZoom lowest zoom level in which this layer is present
+	MinZoom int
+
+	// MaxZoom highest zoom level in which this layer is present
+	MaxZoom int
+
+	// GeometryDimension of the features in this layer (0: points, 1: curves, 2: surfaces), nil when mixed or unknown
+	GeometryDimension *int
+
+	geometryTypes []mvt.GeometryType
+}
+
+// FieldNames names of the fields in this layer, sorted alphabetically.
+func (l vectorLayer) FieldNames() []string {
+	names := util.Keys(l.Fields)
+	slices.Sort(names)
+
+	return names
+}
+
+// TileJSONFields fields in this layer with their type as commonly used in TileJSON (String, Number, Boolean or Mixed).
+func (l vectorLayer) TileJSONFields() map[string]string {
+	result := make(map[string]string, len(l.Fields))
+	for name, fieldType := range l.Fields {
+		switch fieldType {
+		case "string":
+			result[name] = "String"
+		case "number", "integer":
+			result[name] = "Number"
+		case "boolean":
+			result[name] = "Boolean"
+		default:
+			result[name] = "Mixed"
+		}
+	}
+
+	return result
+}
+
+// addFeatures merges the fields and geometry type of the given features (found in the given zoom level) into this layer.
+func (l *vectorLayer) addFeatures(zoomLevel int, features []mvt.Feature) {
+	l.MinZoom = min(l.MinZoom, zoomLevel)
+	l.MaxZoom = max(l.MaxZoom, zoomLevel)
+	for _, feature := range features {
+		if !slices.Contains(l.geometryTypes, feature.Type) {
+			l.geometryTypes = append(l.geometryTypes, feature.Type)
+		}
+		for _, tag := range feature.Tags {
+			fieldType := valueTypeToJSONType(tag.Type())
+			if existing, ok := l.Fields[tag.Key]; ok && existing != fieldType {
+				if (existing == "number" && fieldType == "integer") || (existing == "integer" && fieldType == "number") {
+					fieldType = "number"
+				} else {
+					fieldType = "" // mixed
+				}
+			}
+			l.Fields[tag.Key] = fieldType
+		}
+	}
+	l.GeometryDimension = nil
+	if len(l.geometryTypes) == 1 && l.geometryTypes[0] != mvt.Unknown {
+		dimension := int(l.geometryTypes[0]) - 1
+		l.GeometryDimension = &dimension
+	}
+}
+
+func valueTypeToJSONType(valueType mvt.ValueType) string {
+	switch valueType {
+	case mvt.StringValue:
+		return "string"
+	case mvt.FloatValue:
+		return "number"
+	case mvt.IntValue:
+		return "integer"
+	case mvt.BoolValue:
+		return "boolean"
+	default:
+		return ""
+	}
+}
+
+// discoverVectorLayers determines the vector layers in the given tilesets, when configured to do so. Failing
+// to do so isn't fatal, in that case the tileset metadata just lacks a description of the vector layers.
+// The given context bounds the time spent on discovery.
+func (t *Tiles) discoverVectorLayers(ctx context.Context, collectionID string, tilesConfig config.Tiles, tilesets []tileset) {
+	if tilesConfig.VectorLayers == nil || !tilesConfig.HasType(config.TilesTypeVector) {
+		return
+	}
+	var g errgroup.Group
+	g.SetLimit(vectorLayersConcurrency)
+	for i, supportedSrs := range tilesConfig.SupportedSrs {
+		if supportedSrs.IsDerived() {
+			continue
+		}
+		g.Go(func() error {
+			layers, err := t.vectorLayers(ctx, collectionID, tilesConfig, tilesets[i])
+			if err != nil {
+				log.Printf("Warning: failed to discover vector layers of tiles in tileMatrixSet '%s': %v",
+					tilesets[i].TileMatrixSet.ID, err)
+				return nil
+			}
+			tilesets[i].VectorLayers = layers
+			return nil
+		})
+	}
+	_ = g.Wait()
+
+	// derived tiles contain the same layers as their source, but in the zoom levels of the derived tileset
+	for i, supportedSrs := range tilesConfig.SupportedSrs {
+		if !supportedSrs.IsDerived() {
+			continue
+		}
+		for _, source := range tilesets {
+			if source.TileMatrixSet.ID != supportedSrs.DerivedFrom {
+				continue
+			}
+			layers := make([]vectorLayer, 0, len(source.VectorLayers))
+			for _, layer := range source.VectorLayers {
+				layer.MinZoom = supportedSrs.ZoomLevelRange.Start
+				layer.MaxZoom = supportedSrs.ZoomLevelRange.End
+				layers = append(layers, layer)
+			}
+			tilesets[i].VectorLayers = layers
+		}
+	}
+}
+
+// vectorLayers reads the vector layers of the given tileset from the TileJSON document on the tile server
+// when configured, otherwise the vector layers are derived from a representative tile per zoom level.
+func (t *Tiles) vectorLayers(ctx context.Context, collectionID string, tilesConfig config.Tiles, ts tileset) ([]vectorLayer, error) {
+	if tilesConfig.VectorLayers.URITemplateTileJSON != nil {
+		return t.readVectorLayers(ctx, tilesConfig, ts)
+	}
+
+	return t.inspectVectorLayers(ctx, collectionID, tilesConfig, ts)
+}
+
+// inspectVectorLayers derives the vector layers from a tile per zoom level. Starting at the lowest zoom level, the
+// data is followed: the next tile inspected is the tile containing a feature of the previous tile. When there's no
+// such tile (e.g. the previous tile was empty) the tile in the center of the zoom level is inspected.
+func (t *Tiles) inspectVectorLayers(ctx context.Context, collectionID string, tilesConfig config.Tiles, ts tileset) ([]vectorLayer, error) {
+	zoomLevels := util.Keys(ts.limits)
+	slices.Sort(zoomLevels)
+
+	tiles := make([]*mvt.Tile, len(zoomLevels))
+	var errs []error
+	var previous *inspectedTile
+	for i, zoomLevel := range zoomLevels {
+		if ctx.Err() != nil {
+			errs = append(errs, ctx.Err())
+			break
+		}
+		row, col := ts.inspectionTile(zoomLevel, previous)
+		data, err := t.fetchUncompressedTile(ctx, tilesConfig, collectionID, ts.TileMatrixSet.ID,
+			zoomLevel, row, col, engine.FormatMVT, t.defaultStyle)
+		if err == nil {
+			tiles[i], err = mvt.Decode(data)
+		}
+		if err != nil {
+			errs = append(errs, err) // try the other zoom levels anyway
+			previous = nil
+			continue
+		}
+		previous = newInspectedTile(zoomLevel, row, col, tiles[i])
+	}
+	if len(errs) > 0 && !slices.ContainsFunc(tiles, func(tile *mvt.Tile) bool { return tile != nil }) {
+		return nil, fmt.Errorf("none of the zoom levels could be inspected: %w", errs[0])
+	}
+
+	var layers []*vectorLayer
+	for i, tile := range tiles {
+		if tile == nil {
+			continue
+		}
+		for _, l := range tile.Layers {
+			index := slices.IndexFunc(layers, func(layer *vectorLayer) bool { return layer.ID == l.Name })
+			if index < 0 {
+				index = len(layers)
+				layers = append(layers, &vectorLayer{ID: l.Name, Fields: make(map[string]string), MinZoom: zoomLevels[i], MaxZoom: zoomLevels[i]})
+			}
+			layers[index].addFeatures(zoomLevels[i], l.Features)
+		}
+	}
+	result := make([]vectorLayer, 0, len(layers))
+	for _, layer := range layers {
+		result = append(result, *layer)
+	}
+
+	return result, nil
+}
+
+// inspectedTile tile inspected to discover vector layers, with the location of a feature in this tile (if any).
+type inspectedTile struct {
+	zoomLevel, row, col int
+
+	// location of a feature, as fraction of the tile width and height (from the top-left)
+	x, y float64
+}
+
+// newInspectedTile returns the given tile when it contains a feature, nil otherwise.
+func newInspectedTile(zoomLevel int, row int, col int, tile *mvt.Tile) *inspectedTile {
+	for _, layer := range tile.Layers {
+		extent := float64(layer.Extent)
+		for _, feature := range layer.Features {
+			for _, part := range feature.Geometry {
+				for _, p := range part {
+					if p[0] >= 0 && p[0] < extent && p[1] >= 0 && p[1] < extent {
+						return &inspectedTile{zoomLevel, row, col, p[0] / extent, p[1] / extent}
+					}
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// inspectionTile tile (row and column) to inspect in the given zoom level: the tile containing the feature found
+// in the previous zoom level when the tile matrices are a quadtree, otherwise the tile in the center of the limits.
+func (ts tileset) inspectionTile(zoomLevel int, previous *inspectedTile) (int, int) {
+	limits := ts.limits[zoomLevel]
+	row := (limits.MinTileRow + limits.MaxTileRow) / 2
+	col := (limits.MinTileCol + limits.MaxTileCol) / 2
+	if previous == nil || previous.zoomLevel != zoomLevel-1 {
+		return row, col
+	}
+	tm, ok := ts.TileMatrixSet.tileMatrix(zoomLevel)
+	parent, parentOk := ts.TileMatrixSet.tileMatrix(previous.zoomLevel)
+	if !ok || !parentOk || tm.MatrixWidth != 2*parent.MatrixWidth || tm.MatrixHeight != 2*parent.MatrixHeight {
+		return row, col
+	}
+	childRow := 2*previous.row + int(previous.y*2)
+	childCol := 2*previous.col + int(previous.x*2)
+	if childRow < limits.MinTileRow || childRow > limits.MaxTileRow || childCol < limits.MinTileCol || childCol > limits.MaxTileCol {
+		return row, col
+	}
+
+	return childRow, childCol
+}
+
+// readVectorLayers reads the vector layers from the TileJSON document on the tile server.
+func (t *Tiles) readVectorLayers(ctx context.Context, tilesConfig config.Tiles, ts tileset) ([]vectorLayer, error) {
+	if !tilesConfig.HasTileServer() {
+		return nil, errors.New("TileJSON can only be read from a tileServer, none is configured")
+	}
+	path, _ := url.JoinPath("/", strings.ReplaceAll(*tilesConfig.VectorLayers.URITemplateTileJSON, "{tms}", ts.TileMatrixSet.ID))
+	target, err := url.Parse(tilesConfig.TileServer.String() + path)
+	if err != nil {
+		return nil, fmt.Errorf("invalid TileJSON url: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	response := newTileResponse()
+	t.engine.ReverseProxy(response, req, target, true, engine.MediaTypeTileJSON)
+	if response.statusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to retrieve TileJSON %s, status code: %d", target, response.statusCode)
+	}
+
+	var tileJSON struct {
+		VectorLayers []struct {
+			ID      string            `json:"id"`
+			Fields  map[string]string `json:"fields"`
+			MinZoom *int              `json:"minzoom"`
+			MaxZoom *int              `json:"maxzoom"`
+		} `json:"vector_layers"` //nolint:tagliatelle // TileJSON spec
+	}
+	if err = json.Unmarshal(response.body.Bytes(), &tileJSON); err != nil {
+		return nil, fmt.Errorf("invalid TileJSON %s: %w", target, err)
+	}
+	result := make([]vectorLayer, 0, len(tileJSON.VectorLayers))
+	for _, l := range tileJSON.VectorLayers {
+		layer := vectorLayer{ID: l.ID, Fields: make(map[string]string, len(l.Fields)),
+			MinZoom: ts.ZoomLevelRange.Start, MaxZoom: ts.ZoomLevelRange.End}
+		if l.MinZoom != nil {
+			layer.MinZoom = max(*l.MinZoom, layer.MinZoom)
+		}
+		if l.MaxZoom != nil {
+			layer.MaxZoom = min(*l.MaxZoom, layer.MaxZoom)
+		}
+		for name, description := range l.Fields {
+			switch strings.ToLower(description) {
+			case "string", "number", "boolean":
+				layer.Fields[name] = strings.ToLower(description)
+			default:
+				layer.Fields[name] = ""
+			}
+		}
+		result = append(result, layer)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,205 @@
+package tiles
+
+import (
+	"encoding/binary"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/PDOK/gokoala/config"
+	"github.com/PDOK/gokoala/internal/engine"
+	"github.com/PDOK/gokoala/internal/engine/types"
+	"github.com/PDOK/gokoala/internal/ogc/tiles/mvt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// protobuf encoded string value (field 1 of Value message)
+func mvtStringValue(s string) []byte {
+	return append([]byte{0x0a, byte(len(s))}, s...)
+}
+
+// protobuf encoded double value (field 3 of Value message)
+func mvtDoubleValue(f float64) []byte {
+	return binary.LittleEndian.AppendUint64([]byte{0x19}, math.Float64bits(f))
+}
+
+// newVectorTileServer tile server with a 'water' layer in all zoom levels and
+// a 'buildings' layer from zoom level 10, also offers a TileJSON document.
+func newVectorTileServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "tiles.json") {
+			engine.SafeWrite(w.Write, []byte(`{"tilejson": "3.0.0", "vector_layers": [
+				{"id": "roads", "fields": {"name": "String", "lanes": "Number", "remarks": "Some remarks"}, "minzoom": 5, "maxzoom": 40}
+			]}`))
+			return
+		}
+		parts := strings.Split(r.URL.Path, "/") // /{tms}/{z}/{x}/{y}.pbf
+		square := [][][2]float64{{{0, 0}, {100, 0}, {100, 100}, {0, 100}}}
+		tile := &mvt.Tile{Layers: []*mvt.Layer{{Name: "water", Version: 2, Extent: mvt.DefaultExtent, Features: []mvt.Feature{
+			{Type: mvt.Polygon, Tags: []mvt.Tag{{Key: "name", Value: mvtStringValue("IJsselmeer")}}, Geometry: square},
+		}}}}
+		if len(parts) > 2 && len(parts[2]) > 1 {
+			tile.Layers = append(tile.Layers, &mvt.Layer{Name: "buildings", Version: 2, Extent: mvt.DefaultExtent, Features: []mvt.Feature{
+				{Type: mvt.Polygon, Tags: []mvt.Tag{{Key: "height", Value: mvtDoubleValue(12.5)}}, Geometry: square},
+				{Type: mvt.Point, Tags: []mvt.Tag{{Key: "height", Value: mvtStringValue("unknown")}}, Geometry: [][][2]float64{{{5, 5}}}},
+			}})
+		}
+		engine.SafeWrite(w.Write, mvt.Encode(tile))
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestTiles_VectorLayers(t *testing.T) {
+	server := newVectorTileServer(t)
+	serverURL, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name             string
+		vectorLayers     *config.TilesVectorLayers
+		format           string
+		wantBodyContains []string
+		wantNotContains  []string
+	}{
+		{
+			name:         "no vector layers when not configured",
+			vectorLayers: nil,
+			format:       engine.FormatTileJSON,
+			wantBodyContains: []string{
+				`"tilejson": "3.0.0"`,
+			},
+			wantNotContains: []string{
+				`vector_layers`,
+			},
+		},
+		{
+			name:         "vector layers in TileJSON by inspecting tiles",
+			vectorLayers: &config.TilesVectorLayers{},
+			format:       engine.FormatTileJSON,
+			wantBodyContains: []string{
+				`"id": "water",
+      "fields": {
+        "name": "String"
+      },
+      "minzoom": 0,
+      "maxzoom": 12`,
+				`"id": "buildings",
+      "fields": {
+        "height": "Mixed"
+      },
+      "minzoom": 10,
+      "maxzoom": 12`,
+			},
+		},
+		{
+			name:         "layers in tileset metadata by inspecting tiles",
+			vectorLayers: &config.TilesVectorLayers{},
+			format:       engine.FormatJSON,
+			wantBodyContains: []string{
+				`"id": "water",
+      "dataType": "vector",
+      "geometryDimension": 2,
+      "minTileMatrix": "0",
+      "maxTileMatrix": "12",
+      "propertiesSchema": {
+        "type": "object",
+        "properties": {
+          "name": {
+            "type": "string"
+          }
+        }
+      }`,
+				`"id": "buildings",
+      "dataType": "vector",
+      "minTileMatrix": "10",
+      "maxTileMatrix": "12",
+      "propertiesSchema": {
+        "type": "object",
+        "properties": {
+          "height": {}
+        }
+      }`,
+			},
+		},
+		{
+			name:         "vector layers from TileJSON on tile server",
+			vectorLayers: &config.TilesVectorLayers{URITemplateTileJSON: types.PtrTo("{tms}/tiles.json")},
+			format:       engine.FormatJSON,
+			wantBodyContains: []string{
+				`"id": "roads",
+      "dataType": "vector",
+      "minTileMatrix": "5",
+      "maxTileMatrix": "12",
+      "propertiesSchema": {
+        "type": "object",
+        "properties": {
+          "lanes": {
+            "type": "number"
+          },
+          "name": {
+            "type": "string"
+          },
+          "remarks": {}
+        }
+      }`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			newEngine, err := engine.NewEngine("internal/ogc/tiles/testdata/config_tiles_toplevel.yaml",
+				"internal/engine/testdata/test_theme.yaml", "", false, true)
+			require.NoError(t, err)
+			newEngine.Config.OgcAPI.Tiles.DatasetTiles.TileServer = config.URL{URL: serverURL}
+			newEngine.Config.OgcAPI.Tiles.DatasetTiles.VectorLayers = tt.vectorLayers
+			tiles := NewTiles(newEngine, nil)
+
+			req, err := createTilesetRequest("http://localhost:8080/tiles/NetherlandsRDNewQuad?f="+tt.format, "NetherlandsRDNewQuad")
+			require.NoError(t, err)
+			rr := httptest.NewRecorder()
+			tiles.Tileset().ServeHTTP(rr, req)
+
+			assert.Equal(t, http.StatusOK, rr.Code)
+			body := withoutWhitespace(rr.Body.String())
+			for _, want := range tt.wantBodyContains {
+				assert.Contains(t, body, withoutWhitespace(want))
+			}
+			for _, notWant := range tt.wantNotContains {
+				assert.NotContains(t, rr.Body.String(), notWant)
+			}
+		})
+	}
+}
+
+func withoutWhitespace(s string) string {
+	return strings.Join(strings.Fields(s), "")
+}
+
+func TestTileset_InspectionTile(t *testing.T) {
+	tileMatrixSets, err := LoadTileMatrixSets(&config.OgcAPITiles{})
+	require.NoError(t, err)
+	tms := tileMatrixSets["WebMercatorQuad"]
+	ts := tileset{TileMatrixSet: tms, limits: tms.Limits(config.ZoomLevelRange{Start: 0, End: 3}, nil)}
+
+	// center of the zoom level when there's no data to follow
+	row, col := ts.inspectionTile(3, nil)
+	assert.Equal(t, [2]int{3, 3}, [2]int{row, col})
+
+	// follow the data, feature in the north-east of tile 0/0/0
+	previous := newInspectedTile(0, 0, 0, &mvt.Tile{Layers: []*mvt.Layer{{Extent: mvt.DefaultExtent, Features: []mvt.Feature{
+		{Type: mvt.Point, Geometry: [][][2]float64{{{-10, -10}, {3000, 1000}}}},
+	}}}})
+	require.NotNil(t, previous)
+	row, col = ts.inspectionTile(1, previous)
+	assert.Equal(t, [2]int{0, 1}, [2]int{row, col})
+
+	// empty tiles aren't followed
+	assert.Nil(t, newInspectedTile(0, 0, 0, &mvt.Tile{}))
+}